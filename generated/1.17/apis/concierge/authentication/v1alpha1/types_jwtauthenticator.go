@@ -27,11 +27,21 @@ type JWTAuthenticatorSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	Audience string `json:"audience"`
 
+	// Audiences is a list of additional acceptable values of the "aud" JWT claim. A JWT is accepted when its
+	// "aud" claim contains the value of Audience or any of these values.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+
 	// Claims allows customization of the claims that will be mapped to user identity
 	// for Kubernetes access.
 	// +optional
 	Claims JWTTokenClaims `json:"claims"`
 
+	// RequiredClaims is a list of constraints on the claims of the JWT. A JWT which does not satisfy all of
+	// these constraints is rejected, even when its signature and other claims are valid.
+	// +optional
+	RequiredClaims []JWTRequiredClaim `json:"requiredClaims,omitempty"`
+
 	// TLS configuration for communicating with the OIDC provider.
 	// +optional
 	TLS *TLSSpec `json:"tls,omitempty"`
@@ -49,6 +59,25 @@ type JWTTokenClaims struct {
 	// username from the JWT token. When not specified, it will default to "username".
 	// +optional
 	Username string `json:"username"`
+
+	// UsernamePrefix is an optional string which will be prepended to the value of the username claim,
+	// e.g. "oidc:". This can be used to prevent clashes with usernames from other identity sources.
+	// When not specified, the value of the username claim will be used as-is.
+	// +optional
+	UsernamePrefix string `json:"usernamePrefix,omitempty"`
+}
+
+// JWTRequiredClaim is a constraint on a single claim of a JWT.
+type JWTRequiredClaim struct {
+	// Claim is the name of the required claim.
+	// +kubebuilder:validation:MinLength=1
+	Claim string `json:"claim"`
+
+	// Value is the optional required value of the claim. When specified, a string claim must equal Value,
+	// a boolean or number claim must have Value as its JSON representation (e.g. "true"), and a list claim
+	// must contain Value. When not specified, the claim must be present and must not be null or empty.
+	// +optional
+	Value string `json:"value,omitempty"`
 }
 
 // JWTAuthenticator describes the configuration of a JWT authenticator.
@@ -33,6 +33,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*ImpersonationProxyInfo)(nil), (*login.ImpersonationProxyInfo)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_ImpersonationProxyInfo_To_login_ImpersonationProxyInfo(a.(*ImpersonationProxyInfo), b.(*login.ImpersonationProxyInfo), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*login.ImpersonationProxyInfo)(nil), (*ImpersonationProxyInfo)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_login_ImpersonationProxyInfo_To_v1alpha1_ImpersonationProxyInfo(a.(*login.ImpersonationProxyInfo), b.(*ImpersonationProxyInfo), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*TokenCredentialRequest)(nil), (*login.TokenCredentialRequest)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_TokenCredentialRequest_To_login_TokenCredentialRequest(a.(*TokenCredentialRequest), b.(*login.TokenCredentialRequest), scope)
 	}); err != nil {
@@ -102,6 +112,28 @@ func Convert_login_ClusterCredential_To_v1alpha1_ClusterCredential(in *login.Clu
 	return autoConvert_login_ClusterCredential_To_v1alpha1_ClusterCredential(in, out, s)
 }
 
+func autoConvert_v1alpha1_ImpersonationProxyInfo_To_login_ImpersonationProxyInfo(in *ImpersonationProxyInfo, out *login.ImpersonationProxyInfo, s conversion.Scope) error {
+	out.Endpoint = in.Endpoint
+	out.CertificateAuthorityData = in.CertificateAuthorityData
+	return nil
+}
+
+// Convert_v1alpha1_ImpersonationProxyInfo_To_login_ImpersonationProxyInfo is an autogenerated conversion function.
+func Convert_v1alpha1_ImpersonationProxyInfo_To_login_ImpersonationProxyInfo(in *ImpersonationProxyInfo, out *login.ImpersonationProxyInfo, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ImpersonationProxyInfo_To_login_ImpersonationProxyInfo(in, out, s)
+}
+
+func autoConvert_login_ImpersonationProxyInfo_To_v1alpha1_ImpersonationProxyInfo(in *login.ImpersonationProxyInfo, out *ImpersonationProxyInfo, s conversion.Scope) error {
+	out.Endpoint = in.Endpoint
+	out.CertificateAuthorityData = in.CertificateAuthorityData
+	return nil
+}
+
+// Convert_login_ImpersonationProxyInfo_To_v1alpha1_ImpersonationProxyInfo is an autogenerated conversion function.
+func Convert_login_ImpersonationProxyInfo_To_v1alpha1_ImpersonationProxyInfo(in *login.ImpersonationProxyInfo, out *ImpersonationProxyInfo, s conversion.Scope) error {
+	return autoConvert_login_ImpersonationProxyInfo_To_v1alpha1_ImpersonationProxyInfo(in, out, s)
+}
+
 func autoConvert_v1alpha1_TokenCredentialRequest_To_login_TokenCredentialRequest(in *TokenCredentialRequest, out *login.TokenCredentialRequest, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha1_TokenCredentialRequestSpec_To_login_TokenCredentialRequestSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -181,6 +213,7 @@ func Convert_login_TokenCredentialRequestSpec_To_v1alpha1_TokenCredentialRequest
 func autoConvert_v1alpha1_TokenCredentialRequestStatus_To_login_TokenCredentialRequestStatus(in *TokenCredentialRequestStatus, out *login.TokenCredentialRequestStatus, s conversion.Scope) error {
 	out.Credential = (*login.ClusterCredential)(unsafe.Pointer(in.Credential))
 	out.Message = (*string)(unsafe.Pointer(in.Message))
+	out.ImpersonationProxyInfo = (*login.ImpersonationProxyInfo)(unsafe.Pointer(in.ImpersonationProxyInfo))
 	return nil
 }
 
@@ -192,6 +225,7 @@ func Convert_v1alpha1_TokenCredentialRequestStatus_To_login_TokenCredentialReque
 func autoConvert_login_TokenCredentialRequestStatus_To_v1alpha1_TokenCredentialRequestStatus(in *login.TokenCredentialRequestStatus, out *TokenCredentialRequestStatus, s conversion.Scope) error {
 	out.Credential = (*ClusterCredential)(unsafe.Pointer(in.Credential))
 	out.Message = (*string)(unsafe.Pointer(in.Message))
+	out.ImpersonationProxyInfo = (*ImpersonationProxyInfo)(unsafe.Pointer(in.ImpersonationProxyInfo))
 	return nil
 }
 
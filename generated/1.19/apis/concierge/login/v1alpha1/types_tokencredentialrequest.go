@@ -26,6 +26,20 @@ type TokenCredentialRequestStatus struct {
 	// An error message will be returned for an unsuccessful credential request.
 	// +optional
 	Message *string `json:"message,omitempty"`
+
+	// ImpersonationProxyInfo will be returned for a successful credential request while the impersonation proxy
+	// of this Concierge is enabled and ready to accept client connections.
+	// +optional
+	ImpersonationProxyInfo *ImpersonationProxyInfo `json:"impersonationProxyInfo,omitempty"`
+}
+
+// ImpersonationProxyInfo describes how to connect to the impersonation proxy of this Concierge.
+type ImpersonationProxyInfo struct {
+	// Endpoint is the HTTPS endpoint of the impersonation proxy.
+	Endpoint string `json:"endpoint"`
+
+	// CertificateAuthorityData is the base64-encoded PEM CA bundle of the impersonation proxy.
+	CertificateAuthorityData string `json:"certificateAuthorityData"`
 }
 
 // TokenCredentialRequest submits an IDP-specific credential to Pinniped in exchange for a cluster-specific credential.
@@ -25,6 +25,7 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"go.pinniped.dev/generated/1.20/apis/concierge/identity/v1alpha1.WhoAmIRequestSpec":         schema_apis_concierge_identity_v1alpha1_WhoAmIRequestSpec(ref),
 		"go.pinniped.dev/generated/1.20/apis/concierge/identity/v1alpha1.WhoAmIRequestStatus":       schema_apis_concierge_identity_v1alpha1_WhoAmIRequestStatus(ref),
 		"go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.ClusterCredential":            schema_apis_concierge_login_v1alpha1_ClusterCredential(ref),
+		"go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.ImpersonationProxyInfo":       schema_apis_concierge_login_v1alpha1_ImpersonationProxyInfo(ref),
 		"go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.TokenCredentialRequest":       schema_apis_concierge_login_v1alpha1_TokenCredentialRequest(ref),
 		"go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.TokenCredentialRequestList":   schema_apis_concierge_login_v1alpha1_TokenCredentialRequestList(ref),
 		"go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.TokenCredentialRequestSpec":   schema_apis_concierge_login_v1alpha1_TokenCredentialRequestSpec(ref),
@@ -570,6 +571,36 @@ func schema_apis_concierge_login_v1alpha1_ClusterCredential(ref common.Reference
 	}
 }
 
+func schema_apis_concierge_login_v1alpha1_ImpersonationProxyInfo(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ImpersonationProxyInfo describes how to connect to the impersonation proxy of this Concierge.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"endpoint": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Endpoint is the HTTPS endpoint of the impersonation proxy.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"certificateAuthorityData": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CertificateAuthorityData is the base64-encoded PEM CA bundle of the impersonation proxy.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"endpoint", "certificateAuthorityData"},
+			},
+		},
+	}
+}
+
 func schema_apis_concierge_login_v1alpha1_TokenCredentialRequest(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -717,11 +748,17 @@ func schema_apis_concierge_login_v1alpha1_TokenCredentialRequestStatus(ref commo
 							Format:      "",
 						},
 					},
+					"impersonationProxyInfo": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImpersonationProxyInfo will be returned for a successful credential request while the impersonation proxy of this Concierge is enabled and ready to accept client connections.",
+							Ref:         ref("go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.ImpersonationProxyInfo"),
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.ClusterCredential"},
+			"go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.ClusterCredential", "go.pinniped.dev/generated/1.20/apis/concierge/login/v1alpha1.ImpersonationProxyInfo"},
 	}
 }
 
@@ -66,18 +66,25 @@ const (
 
 // ImpersonationProxyServiceType enumerates the types of service that can be provisioned for the impersonation proxy.
 //
-// +kubebuilder:validation:Enum=LoadBalancer;ClusterIP;None
+// +kubebuilder:validation:Enum=LoadBalancer;NodePort;ClusterIP;None;External
 type ImpersonationProxyServiceType string
 
 const (
 	// ImpersonationProxyServiceTypeLoadBalancer provisions a service of type LoadBalancer.
 	ImpersonationProxyServiceTypeLoadBalancer = ImpersonationProxyServiceType("LoadBalancer")
 
+	// ImpersonationProxyServiceTypeNodePort provisions a service of type NodePort.
+	ImpersonationProxyServiceTypeNodePort = ImpersonationProxyServiceType("NodePort")
+
 	// ImpersonationProxyServiceTypeClusterIP provisions a service of type ClusterIP.
 	ImpersonationProxyServiceTypeClusterIP = ImpersonationProxyServiceType("ClusterIP")
 
 	// ImpersonationProxyServiceTypeNone does not automatically provision any service.
 	ImpersonationProxyServiceTypeNone = ImpersonationProxyServiceType("None")
+
+	// ImpersonationProxyServiceTypeExternal does not provision any service, and also never deletes any existing
+	// service, because the impersonation proxy is exposed by an ingress or proxy which is managed outside the Concierge.
+	ImpersonationProxyServiceTypeExternal = ImpersonationProxyServiceType("External")
 )
 
 // ImpersonationProxySpec describes the intended configuration of the Concierge impersonation proxy.
@@ -96,10 +103,36 @@ type ImpersonationProxySpec struct {
 	// ExternalEndpoint describes the HTTPS endpoint where the proxy will be exposed. If not set, the proxy will
 	// be served using the external name of the LoadBalancer service or the cluster service DNS name.
 	//
-	// This field must be non-empty when spec.impersonationProxy.service.type is "None".
+	// This field must be non-empty when spec.impersonationProxy.service.type is "None" or "External".
 	//
 	// +optional
 	ExternalEndpoint string `json:"externalEndpoint,omitempty"`
+
+	// BindAddress is the IP address of the network interface on which the impersonation proxy should listen.
+	// If not set, the impersonation proxy will listen on all network interfaces.
+	//
+	// +optional
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// AutoMode configures how the impersonation proxy behaves when it is enabled by "auto" mode.
+	// This is ignored unless spec.impersonationProxy.mode is "auto".
+	//
+	// +optional
+	AutoMode *ImpersonationProxyAutoModeSpec `json:"autoMode,omitempty"`
+}
+
+// ImpersonationProxyAutoModeSpec describes how the Concierge should behave when "auto" mode enables the impersonation proxy.
+type ImpersonationProxyAutoModeSpec struct {
+	// CreateLoadBalancer decides whether the Concierge creates a LoadBalancer Service for the impersonation proxy
+	// when "auto" mode enables it, and when spec.impersonationProxy.service.type is "LoadBalancer".
+	// When false, the Concierge starts the impersonation proxy but it will never create, update, or delete
+	// any Service for the impersonation proxy, so that networking can be managed by someone else. The serving
+	// certificate is issued for spec.impersonationProxy.externalEndpoint when it is set, and otherwise the
+	// Concierge waits for the load balancer to be assigned an IP or hostname.
+	// Optional. When not specified, the default is true.
+	// +kubebuilder:default=true
+	// +optional
+	CreateLoadBalancer *bool `json:"createLoadBalancer,omitempty"`
 }
 
 // ImpersonationProxyServiceSpec describes how the Concierge should provision a Service to expose the impersonation proxy.
@@ -109,6 +142,13 @@ type ImpersonationProxyServiceSpec struct {
 	// If the type is "None", then the "spec.impersonationProxy.externalEndpoint" field must be set to a non-empty
 	// value so that the Concierge can properly advertise the endpoint in the CredentialIssuer's status.
 	//
+	// If the type is "NodePort", then the Concierge will advertise the endpoint using the IP address of one of the
+	// cluster's nodes and the node port assigned to the Service, unless "spec.impersonationProxy.externalEndpoint" is set.
+	//
+	// If the type is "External", then the "spec.impersonationProxy.externalEndpoint" field must be set to a non-empty
+	// value. The Concierge will start the impersonation proxy and issue its serving certificate for that endpoint only,
+	// but it will never create, update, or delete any Service for the impersonation proxy.
+	//
 	// +kubebuilder:default:="LoadBalancer"
 	Type ImpersonationProxyServiceType `json:"type,omitempty"`
 
@@ -120,10 +160,37 @@ type ImpersonationProxyServiceSpec struct {
 	// +optional
 	LoadBalancerIP string `json:"loadBalancerIP,omitempty"`
 
+	// LoadBalancerSourceRanges specifies the CIDRs to set in the spec.loadBalancerSourceRanges field of the
+	// provisioned Service, which restrict which client IP addresses may connect to the load balancer.
+	// This is only used when the type is "LoadBalancer", and it is not supported on all cloud providers.
+	//
+	// +optional
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+
 	// Annotations specifies zero or more key/value pairs to set as annotations on the provisioned Service.
 	//
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// AdditionalClusterIP specifies whether to also provision a ClusterIP Service for the impersonation proxy when
+	// the type is "LoadBalancer", so that clients inside the cluster can reach it without going through the load
+	// balancer. The serving certificate will also be valid for the IP addresses of the ClusterIP Service, but the
+	// Concierge will still advertise the endpoint of the load balancer in the CredentialIssuer's status.
+	// The annotations are only set on the load balancer Service.
+	// This field must not be set to true when the type is not "LoadBalancer".
+	//
+	// +optional
+	AdditionalClusterIP bool `json:"additionalClusterIP,omitempty"`
+
+	// ResolveEndpointHostname specifies whether to resolve the hostname which the load balancer was assigned, such
+	// as the hostname of an AWS ELB, to its IP addresses when the type is "LoadBalancer". The serving certificate will
+	// then be valid for the hostname and for each of those IP addresses, for clients which connect by IP address.
+	// When the hostname cannot be resolved, the serving certificate is only valid for the hostname until a later
+	// attempt to resolve it succeeds. The serving certificate is issued again whenever the resolved IP addresses change.
+	// This field must not be set to true when the type is not "LoadBalancer".
+	//
+	// +optional
+	ResolveEndpointHostname bool `json:"resolveEndpointHostname,omitempty"`
 }
 
 // CredentialIssuerStatus describes the status of the Concierge.
@@ -38,7 +38,8 @@ type ActiveDirectoryIdentityProviderStatus struct {
 type ActiveDirectoryIdentityProviderBind struct {
 	// SecretName contains the name of a namespace-local Secret object that provides the username and
 	// password for an Active Directory bind user. This account will be used to perform LDAP searches. The Secret should be
-	// of type "kubernetes.io/basic-auth" which includes "username" and "password" keys. The username value
+	// of type "kubernetes.io/basic-auth" which includes "username" and "password" keys. A Secret of type "Opaque"
+	// which includes the same keys is also accepted. The username value
 	// should be the full dn (distinguished name) of your bind account, e.g. "cn=bind-account,ou=users,dc=example,dc=com".
 	// The password must be non-empty.
 	// +kubebuilder:validation:MinLength=1
@@ -132,6 +133,23 @@ type ActiveDirectoryIdentityProviderGroupSearch struct {
 	// +optional
 	Attributes ActiveDirectoryIdentityProviderGroupSearchAttributes `json:"attributes,omitempty"`
 
+	// ResolveNestedGroups, when true, also gives the user the groups which contain the user's groups, and the
+	// groups which contain those, up to NestedGroupsMaxDepth levels, by searching again using Filter with each
+	// group's dn in place of the user's dn. Each group is only searched once, so cycles in the group memberships
+	// are allowed. When Filter uses the LDAP_MATCHING_RULE_IN_CHAIN matching rule (1.2.840.113556.1.4.1941),
+	// as the default Filter does, ActiveDirectory already returns the nested groups, so this has no effect.
+	// Optional. When not specified, only the groups which are found by Filter are used.
+	// +optional
+	ResolveNestedGroups bool `json:"resolveNestedGroups,omitempty"`
+
+	// NestedGroupsMaxDepth is the number of levels of groups above the user's own groups which are resolved when
+	// ResolveNestedGroups is true. It is ignored when ResolveNestedGroups is false.
+	// Optional. When not specified, the default will act as if the NestedGroupsMaxDepth were specified as 10.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	NestedGroupsMaxDepth int32 `json:"nestedGroupsMaxDepth,omitempty"`
+
 	// The user's group membership is refreshed as they interact with the supervisor
 	// to obtain new credentials (as their old credentials expire).  This allows group
 	// membership changes to be quickly reflected into Kubernetes clusters.  Since
@@ -161,12 +179,23 @@ type ActiveDirectoryIdentityProviderGroupSearch struct {
 // Spec for configuring an ActiveDirectory identity provider.
 type ActiveDirectoryIdentityProviderSpec struct {
 	// Host is the hostname of this Active Directory identity provider, i.e., where to connect. For example: ldap.example.com:636.
+	// Multiple hosts may be given as a comma-separated list, e.g. ldap1.example.com:636,ldap2.example.com:636,
+	// in which case they will be tried in order until one accepts a connection.
 	// +kubebuilder:validation:MinLength=1
 	Host string `json:"host"`
 
 	// TLS contains the connection settings for how to establish the connection to the Host.
 	TLS *TLSSpec `json:"tls,omitempty"`
 
+	// ConnectionProtocol chooses how to establish a secure connection to the Host:
+	// - "TLS" connects using implicit TLS (LDAPS). When the Host does not include a port, port 636 is used.
+	// - "StartTLS" connects without TLS and then upgrades the connection using the StartTLS extended operation.
+	//   When the Host does not include a port, port 389 is used.
+	// In both cases the TLS settings are used to verify the server's certificate. When not specified, TLS is
+	// tried first and StartTLS is used if connecting using TLS fails.
+	// +optional
+	ConnectionProtocol LDAPConnectionProtocol `json:"connectionProtocol,omitempty"`
+
 	// Bind contains the configuration for how to provide access credentials during an initial bind to the ActiveDirectory server
 	// to be allowed to perform searches and binds to validate a user's credentials during a user's authentication attempt.
 	Bind ActiveDirectoryIdentityProviderBind `json:"bind,omitempty"`
@@ -20,6 +20,95 @@ const (
 	LDAPPhaseError LDAPIdentityProviderPhase = "Error"
 )
 
+// LDAPConnectionProtocol enumerates the protocols which can be used to establish a secure connection to an LDAP server.
+//
+// +kubebuilder:validation:Enum=TLS;StartTLS
+type LDAPConnectionProtocol string
+
+const (
+	// LDAPConnectionProtocolTLS connects to the LDAP server using implicit TLS, i.e. LDAPS.
+	LDAPConnectionProtocolTLS = LDAPConnectionProtocol("TLS")
+
+	// LDAPConnectionProtocolStartTLS connects to the LDAP server without TLS and then upgrades the connection
+	// using the StartTLS extended operation.
+	LDAPConnectionProtocolStartTLS = LDAPConnectionProtocol("StartTLS")
+)
+
+// LDAPUIDEncoding enumerates the ways in which the value of the UID attribute can be encoded to become the user's UID.
+//
+// +kubebuilder:validation:Enum=Base64URL;Hex
+type LDAPUIDEncoding string
+
+const (
+	// LDAPUIDEncodingBase64URL encodes the value of the UID attribute using unpadded base64url encoding.
+	LDAPUIDEncodingBase64URL = LDAPUIDEncoding("Base64URL")
+
+	// LDAPUIDEncodingHex encodes the value of the UID attribute using lower-case hexadecimal encoding.
+	LDAPUIDEncodingHex = LDAPUIDEncoding("Hex")
+)
+
+// LDAPMultiValuePolicy enumerates the ways in which the value of an attribute can be chosen when the attribute
+// has more than one value.
+//
+// +kubebuilder:validation:Enum=Error;First;Last
+type LDAPMultiValuePolicy string
+
+const (
+	// LDAPMultiValuePolicyError rejects the login when the attribute has more than one value.
+	LDAPMultiValuePolicyError = LDAPMultiValuePolicy("Error")
+
+	// LDAPMultiValuePolicyFirst chooses the first value, in the order returned by the LDAP server.
+	LDAPMultiValuePolicyFirst = LDAPMultiValuePolicy("First")
+
+	// LDAPMultiValuePolicyLast chooses the last value, in the order returned by the LDAP server.
+	LDAPMultiValuePolicyLast = LDAPMultiValuePolicy("Last")
+)
+
+// LDAPSearchScope enumerates the scopes of an LDAP search, relative to the search base.
+//
+// +kubebuilder:validation:Enum=base;one;sub
+type LDAPSearchScope string
+
+const (
+	// LDAPSearchScopeBase searches only the entry at the search base itself.
+	LDAPSearchScopeBase = LDAPSearchScope("base")
+
+	// LDAPSearchScopeOne searches only the immediate children of the search base.
+	LDAPSearchScopeOne = LDAPSearchScope("one")
+
+	// LDAPSearchScopeSub searches the search base and its entire subtree.
+	LDAPSearchScopeSub = LDAPSearchScope("sub")
+)
+
+// LDAPGroupSearchFailurePolicy enumerates what happens to a login when the user authenticates successfully
+// but the search for their groups fails.
+//
+// +kubebuilder:validation:Enum=FailOpen;FailClosed
+type LDAPGroupSearchFailurePolicy string
+
+const (
+	// LDAPGroupSearchFailurePolicyFailOpen lets the user log in without any groups.
+	LDAPGroupSearchFailurePolicyFailOpen = LDAPGroupSearchFailurePolicy("FailOpen")
+
+	// LDAPGroupSearchFailurePolicyFailClosed rejects the login.
+	LDAPGroupSearchFailurePolicyFailClosed = LDAPGroupSearchFailurePolicy("FailClosed")
+)
+
+// LDAPGroupSearchMode enumerates the ways in which the groups of a user can be found.
+//
+// +kubebuilder:validation:Enum=filter;userAttribute
+type LDAPGroupSearchMode string
+
+const (
+	// LDAPGroupSearchModeFilter searches for the group entries under the group search base which match the
+	// group search filter.
+	LDAPGroupSearchModeFilter = LDAPGroupSearchMode("filter")
+
+	// LDAPGroupSearchModeUserAttribute reads the dns (distinguished names) of the user's groups from an
+	// attribute of the user's entry, such as "memberOf".
+	LDAPGroupSearchModeUserAttribute = LDAPGroupSearchMode("userAttribute")
+)
+
 // Status of an LDAP identity provider.
 type LDAPIdentityProviderStatus struct {
 	// Phase summarizes the overall status of the LDAPIdentityProvider.
@@ -33,16 +122,59 @@ type LDAPIdentityProviderStatus struct {
 	// +listType=map
 	// +listMapKey=type
 	Conditions []Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// Validation describes the most recent successful validation of the connection to the LDAP server in a
+	// machine-readable form. It describes the same validation as the message of the LDAPConnectionValid condition,
+	// and it is only present while that condition is True.
+	// +optional
+	Validation *LDAPIdentityProviderValidation `json:"validation,omitempty"`
+}
+
+// LDAPIdentityProviderValidation describes a successful validation of the connection to the LDAP server.
+type LDAPIdentityProviderValidation struct {
+	// ObservedGeneration is the generation of the LDAPIdentityProvider spec which was validated.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ObservedSecretVersion is the resourceVersion of the bind Secret which was used to validate the connection.
+	// It is empty when the connection was validated using anonymous bind without a bind Secret.
+	// +optional
+	ObservedSecretVersion string `json:"observedSecretVersion,omitempty"`
+
+	// ConnectionHost is the host, and optionally the port, of the LDAP server which was reached.
+	// +optional
+	ConnectionHost string `json:"connectionHost,omitempty"`
+
+	// ResolvedBindDN is the username of the bind account which was used to bind, after bind.bindDNTemplate was
+	// applied to it. It is empty when the connection was validated using anonymous bind.
+	// +optional
+	ResolvedBindDN string `json:"resolvedBindDN,omitempty"`
 }
 
 type LDAPIdentityProviderBind struct {
 	// SecretName contains the name of a namespace-local Secret object that provides the username and
 	// password for an LDAP bind user. This account will be used to perform LDAP searches. The Secret should be
-	// of type "kubernetes.io/basic-auth" which includes "username" and "password" keys. The username value
-	// should be the full dn (distinguished name) of your bind account, e.g. "cn=bind-account,ou=users,dc=example,dc=com".
-	// The password must be non-empty.
+	// of type "kubernetes.io/basic-auth" which includes "username" and "password" keys. A Secret of type "Opaque"
+	// which includes the same keys is also accepted. The username value
+	// should be the full dn (distinguished name) of your bind account, e.g. "cn=bind-account,ou=users,dc=example,dc=com",
+	// unless bindDNTemplate is specified. The password must be non-empty. Required unless allowAnonymousBind is true.
 	// +kubebuilder:validation:MinLength=1
-	SecretName string `json:"secretName"`
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// BindDNTemplate is a template for the dn (distinguished name) of the bind account, for when the username
+	// in the bind Secret is a short name instead of a full dn. The pattern "{}" must occur in the template and will
+	// be dynamically replaced by the username from the Secret, after escaping it for use in a dn,
+	// e.g. "cn={},ou=service-accounts,dc=example,dc=com". When not specified, the username from the Secret is used
+	// as the bind dn.
+	// +optional
+	BindDNTemplate string `json:"bindDNTemplate,omitempty"`
+
+	// AllowAnonymousBind, when true, causes the Supervisor to perform an anonymous (unauthenticated) bind
+	// instead of binding as a bind user when performing LDAP searches. This should only be used with LDAP servers
+	// which allow anonymous searches of the user and group search bases. When true, secretName may be omitted.
+	// +optional
+	AllowAnonymousBind bool `json:"allowAnonymousBind,omitempty"`
 }
 
 type LDAPIdentityProviderUserSearchAttributes struct {
@@ -56,12 +188,35 @@ type LDAPIdentityProviderUserSearchAttributes struct {
 	// +kubebuilder:validation:MinLength=1
 	Username string `json:"username,omitempty"`
 
+	// UsernameMultiValuePolicy chooses what happens when the Username attribute has more than one value in the
+	// user's entry. Allowed values are "Error", which rejects the login, and "First" or "Last", which choose that
+	// value in the order returned by the LDAP server. When not specified, "Error" is used. The UID attribute must
+	// always have exactly one value, since choosing one of several values could give two users the same UID.
+	// +optional
+	UsernameMultiValuePolicy LDAPMultiValuePolicy `json:"usernameMultiValuePolicy,omitempty"`
+
 	// UID specifies the name of the attribute in the LDAP entry which whose value shall be used to uniquely
 	// identify the user within this LDAP provider after a successful authentication. E.g. "uidNumber" or "objectGUID".
 	// The value of this field is case-sensitive and must match the case of the attribute name returned by the LDAP
 	// server in the user's entry. Distinguished names can be used by specifying lower-case "dn".
 	// +kubebuilder:validation:MinLength=1
 	UID string `json:"uid,omitempty"`
+
+	// UIDEncoding chooses how the raw bytes of the UID attribute's value are encoded to become the user's UID,
+	// which allows binary attributes such as "objectGUID" to be used as the UID. Allowed values are "Base64URL"
+	// and "Hex". When not specified, "Base64URL" is used.
+	// +optional
+	UIDEncoding LDAPUIDEncoding `json:"uidEncoding,omitempty"`
+
+	// Extra maps keys of the user's extra information, which Kubernetes makes available to audit logs and
+	// authorization webhooks, to the names of the attributes in the LDAP entry whose values shall become the
+	// values of those keys after a successful authentication. E.g. {"example.com/department": "departmentNumber"}.
+	// A multi-valued attribute results in multiple values for its key, and an attribute which is missing from the
+	// user's entry results in no value for its key. The attribute names are case-sensitive and must match the case
+	// of the attribute names returned by the LDAP server in the user's entry. Distinguished names can be used by
+	// specifying lower-case "dn".
+	// +optional
+	Extra map[string]string `json:"extra,omitempty"`
 }
 
 type LDAPIdentityProviderGroupSearchAttributes struct {
@@ -72,6 +227,15 @@ type LDAPIdentityProviderGroupSearchAttributes struct {
 	// Optional. When not specified, the default will act as if the GroupName were specified as "dn" (distinguished name).
 	// +optional
 	GroupName string `json:"groupName,omitempty"`
+
+	// UseDNWhenGroupNameIsMissing, when true, makes the distinguished name of a group entry become its group name
+	// when the entry has no value for the GroupName attribute. Group entries which have neither a value for the
+	// GroupName attribute nor a distinguished name are then left out of the user's list of groups, instead of causing
+	// the authentication to fail.
+	// Optional. When not specified, a group entry without a value for the GroupName attribute causes the
+	// authentication to fail.
+	// +optional
+	UseDNWhenGroupNameIsMissing bool `json:"useDNWhenGroupNameIsMissing,omitempty"`
 }
 
 type LDAPIdentityProviderUserSearch struct {
@@ -91,17 +255,104 @@ type LDAPIdentityProviderUserSearch struct {
 	// +optional
 	Filter string `json:"filter,omitempty"`
 
+	// UserDNTemplate is a template for the dn (distinguished name) of each user's entry, for directories where
+	// the dn can be derived from the username. The pattern "{}" must occur in the template at least once and will
+	// be dynamically replaced by the username, escaped for use in a dn. E.g. "uid={},ou=people,dc=example,dc=com".
+	// When specified, the user's entry is read directly from that dn instead of searching for the user, so
+	// Filter is ignored.
+	// Optional. When not specified, the user search is performed using Base and Filter.
+	// +optional
+	UserDNTemplate string `json:"userDNTemplate,omitempty"`
+
+	// Scope is the scope of the user search, relative to Base. "base" searches only the Base entry itself,
+	// "one" searches only the immediate children of Base, and "sub" searches Base and its entire subtree.
+	// Scope is ignored when UserDNTemplate is specified.
+	// Optional. When not specified, the default will act as if the Scope were specified as "sub".
+	// +optional
+	Scope LDAPSearchScope `json:"scope,omitempty"`
+
 	// Attributes specifies how the user's information should be read from the LDAP entry which was found as
 	// the result of the user search.
 	// +optional
 	Attributes LDAPIdentityProviderUserSearchAttributes `json:"attributes,omitempty"`
+
+	// UsernameCaseSensitive decides whether the username typed by the user must match the case of the username
+	// in the LDAP entry. When false, each equality assertion of the form "attribute={}" in the Filter (or in the
+	// default Filter) is changed to use the caseIgnoreMatch extensible matching rule, so the LDAP server must
+	// support extensible matching. In either case, the user's username is always read from the LDAP entry
+	// using Attributes.Username, not copied from what the user typed.
+	// Optional. When not specified, the default is true.
+	// +kubebuilder:default=true
+	// +optional
+	UsernameCaseSensitive *bool `json:"usernameCaseSensitive,omitempty"`
+
+	// UsernameNormalization specifies changes which are made to the username typed by the user before it
+	// replaces the "{}" placeholder in the Filter or in the UserDNTemplate, for when users type their username
+	// differently than it is stored in the LDAP entry, e.g. "EXAMPLE\jdoe" or "jdoe@example.com" instead of "jdoe".
+	// The user's username is still read from the LDAP entry using Attributes.Username.
+	// Optional. When not specified, the username is used as it was typed.
+	// +optional
+	UsernameNormalization LDAPIdentityProviderUsernameNormalization `json:"usernameNormalization,omitempty"`
+
+	// AllowMultipleMatches decides what happens when the user search finds more than one entry for a username.
+	// When false, the login fails, so that a Filter which is too loose cannot cause a user to be authenticated
+	// as someone else. When true, the first entry returned by the LDAP server is used, which should only be
+	// enabled when every entry found for a username is known to belong to the same person.
+	// Optional. When not specified, the default is false.
+	// +optional
+	AllowMultipleMatches bool `json:"allowMultipleMatches,omitempty"`
+
+	// DryRunUsername is a username which is searched for each time the settings of this provider are validated,
+	// to check that the user search finds exactly one entry for it, e.g. the username of a test account. The
+	// outcome is reported in the UserSearchValid condition. The group search is also tried for the entry which is
+	// found, and its outcome is reported in the GroupSearchValid condition. The LDAP server is never asked to
+	// authenticate as this user, so no password is needed.
+	// Optional. When not specified, the user search is not tried until a user logs in.
+	// +optional
+	DryRunUsername string `json:"dryRunUsername,omitempty"`
+}
+
+// LDAPIdentityProviderUsernameNormalization describes the changes which are made to the username typed by the
+// user before the user search. They are made in the order of the fields below.
+type LDAPIdentityProviderUsernameNormalization struct {
+	// TrimWhitespace, when true, removes any leading and trailing whitespace from the username.
+	// +optional
+	TrimWhitespace bool `json:"trimWhitespace,omitempty"`
+
+	// StripNetBIOSPrefix, when true, removes a NetBIOS domain prefix from the username, which is everything up to
+	// and including the first backslash. E.g. "EXAMPLE\jdoe" becomes "jdoe".
+	// +optional
+	StripNetBIOSPrefix bool `json:"stripNetBIOSPrefix,omitempty"`
+
+	// StripDomainSuffix is a domain which is removed from the end of the username, along with the "@" before it,
+	// when the username ends with "@" followed by this domain. The domain is compared case-insensitively.
+	// E.g. "example.com" changes "jdoe@example.com" to "jdoe", but leaves "jdoe@other.example.com" unchanged.
+	// It must not contain "@" or whitespace.
+	// Optional. When not specified, no domain suffix is removed.
+	// +optional
+	StripDomainSuffix string `json:"stripDomainSuffix,omitempty"`
 }
 
 type LDAPIdentityProviderGroupSearch struct {
+	// Mode chooses how the user's groups are found. "filter" searches for the group entries under Base which
+	// match Filter. "userAttribute" reads the dns (distinguished names) of the user's groups from the
+	// UserAttributeForGroups attribute of the user's entry, which was already read by the user search, so no
+	// group search base is needed and Base, Filter, PageSize, and Scope are ignored.
+	// Optional. When not specified, the default will act as if the Mode were specified as "filter".
+	// +optional
+	Mode LDAPGroupSearchMode `json:"mode,omitempty"`
+
+	// UserAttributeForGroups is the name of the attribute of the user's entry whose values are the dns of the
+	// user's groups, when Mode is "userAttribute". When Attributes.GroupName is not specified or is "dn", the dns
+	// become the group names. Otherwise, each group's entry is read to find the value of its GroupName attribute.
+	// Optional. When not specified, the default will act as if the UserAttributeForGroups were specified as "memberOf".
+	// +optional
+	UserAttributeForGroups string `json:"userAttributeForGroups,omitempty"`
+
 	// Base is the dn (distinguished name) that should be used as the search base when searching for groups. E.g.
-	// "ou=groups,dc=example,dc=com". When not specified, no group search will be performed and
-	// authenticated users will not belong to any groups from the LDAP provider. Also, when not specified,
-	// the values of Filter and Attributes are ignored.
+	// "ou=groups,dc=example,dc=com". When not specified, and Mode is not "userAttribute", no group search will be
+	// performed and authenticated users will not belong to any groups from the LDAP provider. Also, when not
+	// specified, the values of Filter and Attributes are ignored.
 	// +optional
 	Base string `json:"base,omitempty"`
 
@@ -120,6 +371,47 @@ type LDAPIdentityProviderGroupSearch struct {
 	// +optional
 	Attributes LDAPIdentityProviderGroupSearchAttributes `json:"attributes,omitempty"`
 
+	// AllowedGroups is an optional list of group DNs, e.g. "cn=admins,ou=groups,dc=example,dc=com". When not empty,
+	// only the groups found by the group search whose DNs are in this list are given to the user, so that only a
+	// curated subset of the user's group memberships is visible to Kubernetes RBAC. DNs are compared without regard
+	// to case or insignificant whitespace. When empty, all groups found by the group search are given to the user.
+	// +optional
+	AllowedGroups []string `json:"allowedGroups,omitempty"`
+
+	// PageSize is the maximum number of group entries which should be requested from the LDAP server per page
+	// of results when searching for groups for a user. The group search uses the simple paged results control
+	// (RFC 2696), and all pages are read to find all of the user's groups. This should not be larger than the
+	// maximum number of results which the LDAP server allows per search.
+	// Optional. When not specified, the default will act as if the PageSize were specified as 1000.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PageSize int32 `json:"pageSize,omitempty"`
+
+	// Scope is the scope of the group search, relative to Base. "base" searches only the Base entry itself,
+	// "one" searches only the immediate children of Base, and "sub" searches Base and its entire subtree.
+	// Optional. When not specified, the default will act as if the Scope were specified as "sub".
+	// +optional
+	Scope LDAPSearchScope `json:"scope,omitempty"`
+
+	// ResolveNestedGroups, when true, also gives the user the groups which contain the user's groups, and the
+	// groups which contain those, up to NestedGroupsMaxDepth levels. When Mode is "filter", the groups which
+	// contain a group are found by searching again using Filter with the group's dn in place of the user's dn.
+	// When Mode is "userAttribute", they are read from the UserAttributeForGroups attribute of the group's entry.
+	// Each group is only searched once, so cycles in the group memberships are allowed. AllowedGroups is applied
+	// to the resulting groups, after the nested groups have been resolved. Note that this makes at least one more
+	// query to the LDAP server per group at each level during every login and refresh.
+	// Optional. When not specified, only the groups which directly contain the user are used.
+	// +optional
+	ResolveNestedGroups bool `json:"resolveNestedGroups,omitempty"`
+
+	// NestedGroupsMaxDepth is the number of levels of groups above the user's own groups which are resolved when
+	// ResolveNestedGroups is true. It is ignored when ResolveNestedGroups is false.
+	// Optional. When not specified, the default will act as if the NestedGroupsMaxDepth were specified as 10.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	NestedGroupsMaxDepth int32 `json:"nestedGroupsMaxDepth,omitempty"`
+
 	// The user's group membership is refreshed as they interact with the supervisor
 	// to obtain new credentials (as their old credentials expire).  This allows group
 	// membership changes to be quickly reflected into Kubernetes clusters.  Since
@@ -149,12 +441,51 @@ type LDAPIdentityProviderGroupSearch struct {
 // Spec for configuring an LDAP identity provider.
 type LDAPIdentityProviderSpec struct {
 	// Host is the hostname of this LDAP identity provider, i.e., where to connect. For example: ldap.example.com:636.
+	// Multiple hosts may be given as a comma-separated list, e.g. ldap1.example.com:636,ldap2.example.com:636,
+	// in which case they will be tried in order until one accepts a connection.
 	// +kubebuilder:validation:MinLength=1
 	Host string `json:"host"`
 
 	// TLS contains the connection settings for how to establish the connection to the Host.
 	TLS *TLSSpec `json:"tls,omitempty"`
 
+	// ConnectionProtocol chooses how to establish a secure connection to the Host:
+	// - "TLS" connects using implicit TLS (LDAPS). When the Host does not include a port, port 636 is used.
+	// - "StartTLS" connects without TLS and then upgrades the connection using the StartTLS extended operation.
+	//   When the Host does not include a port, port 389 is used.
+	// In both cases the TLS settings are used to verify the server's certificate. When not specified, TLS is
+	// tried first and StartTLS is used if connecting using TLS fails.
+	// +optional
+	ConnectionProtocol LDAPConnectionProtocol `json:"connectionProtocol,omitempty"`
+
+	// ConnectionTimeout bounds how long it may take to connect to the Host, and how long each of the checks which
+	// are made against the server while validating this identity provider may take. It is a duration such as "30s"
+	// or "2m", which must be greater than zero and at most ten minutes. When not specified, 90 seconds is used.
+	// +optional
+	ConnectionTimeout *metav1.Duration `json:"connectionTimeout,omitempty"`
+
+	// ProxyURL is the URL of a proxy through which to connect to the Host, for when the LDAP server is only
+	// reachable through an egress proxy. SOCKS5 proxies (e.g. "socks5://proxy.example.com:1080") and HTTP proxies
+	// which support the CONNECT method (e.g. "http://proxy.example.com:3128") are supported. Credentials for the
+	// proxy may be included in the URL. When not specified, the Supervisor connects to the Host directly.
+	// +kubebuilder:validation:Pattern=`^(socks5|socks5h|http)://`
+	// +optional
+	ProxyURL string `json:"proxyURL,omitempty"`
+
+	// SearchSizeLimit is the maximum number of entries which the LDAP server should return for each group search,
+	// to bound the server-side resources used by the search. A group search which would find more entries than
+	// this fails the authentication. The user search always asks for at most two entries, which is enough to tell
+	// that it is ambiguous. When not specified, the LDAP server's own limit is used.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SearchSizeLimit int32 `json:"searchSizeLimit,omitempty"`
+
+	// SearchTimeLimit is the maximum number of seconds which the LDAP server should spend on each search, to bound
+	// the server-side resources used by the search. When not specified, 90 seconds is used.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SearchTimeLimit int32 `json:"searchTimeLimit,omitempty"`
+
 	// Bind contains the configuration for how to provide access credentials during an initial bind to the LDAP server
 	// to be allowed to perform searches and binds to validate a user's credentials during a user's authentication attempt.
 	Bind LDAPIdentityProviderBind `json:"bind,omitempty"`
@@ -164,6 +495,15 @@ type LDAPIdentityProviderSpec struct {
 
 	// GroupSearch contains the configuration for searching for a user's group membership in the LDAP provider.
 	GroupSearch LDAPIdentityProviderGroupSearch `json:"groupSearch,omitempty"`
+
+	// GroupSearchFailurePolicy determines what happens when a user's password is accepted by the LDAP server but the
+	// search for their groups fails, e.g. because of a transient server error. "FailClosed" rejects the login.
+	// "FailOpen" lets the user log in without any groups from the LDAP provider, which may give them less access
+	// than they should have until their groups are found again by the next refresh of their session.
+	// Optional. When not specified, the default will act as if the GroupSearchFailurePolicy were specified as
+	// "FailClosed", since a user without their groups could otherwise be given a different level of access than intended.
+	// +optional
+	GroupSearchFailurePolicy LDAPGroupSearchFailurePolicy `json:"groupSearchFailurePolicy,omitempty"`
 }
 
 // LDAPIdentityProvider describes the configuration of an upstream Lightweight Directory Access
@@ -26,6 +26,19 @@ type TokenCredentialRequestStatus struct {
 	// An error message will be returned for an unsuccessful credential request.
 	// +optional
 	Message *string
+
+	// ImpersonationProxyInfo will be returned for a successful credential request while the impersonation proxy
+	// of this Concierge is enabled and ready to accept client connections.
+	// +optional
+	ImpersonationProxyInfo *ImpersonationProxyInfo
+}
+
+type ImpersonationProxyInfo struct {
+	// Endpoint is the HTTPS endpoint of the impersonation proxy.
+	Endpoint string
+
+	// CertificateAuthorityData is the base64-encoded PEM CA bundle of the impersonation proxy.
+	CertificateAuthorityData string
 }
 
 // TokenCredentialRequest submits an IDP-specific credential to Pinniped in exchange for a cluster-specific credential.
@@ -9,6 +9,7 @@
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -267,6 +268,11 @@ func (in *LDAPIdentityProviderBind) DeepCopy() *LDAPIdentityProviderBind {
 func (in *LDAPIdentityProviderGroupSearch) DeepCopyInto(out *LDAPIdentityProviderGroupSearch) {
 	*out = *in
 	out.Attributes = in.Attributes
+	if in.AllowedGroups != nil {
+		in, out := &in.AllowedGroups, &out.AllowedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -337,9 +343,14 @@ func (in *LDAPIdentityProviderSpec) DeepCopyInto(out *LDAPIdentityProviderSpec)
 		*out = new(TLSSpec)
 		**out = **in
 	}
+	if in.ConnectionTimeout != nil {
+		in, out := &in.ConnectionTimeout, &out.ConnectionTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	out.Bind = in.Bind
-	out.UserSearch = in.UserSearch
-	out.GroupSearch = in.GroupSearch
+	in.UserSearch.DeepCopyInto(&out.UserSearch)
+	in.GroupSearch.DeepCopyInto(&out.GroupSearch)
 	return
 }
 
@@ -363,6 +374,11 @@ func (in *LDAPIdentityProviderStatus) DeepCopyInto(out *LDAPIdentityProviderStat
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(LDAPIdentityProviderValidation)
+		**out = **in
+	}
 	return
 }
 
@@ -379,7 +395,13 @@ func (in *LDAPIdentityProviderStatus) DeepCopy() *LDAPIdentityProviderStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LDAPIdentityProviderUserSearch) DeepCopyInto(out *LDAPIdentityProviderUserSearch) {
 	*out = *in
-	out.Attributes = in.Attributes
+	in.Attributes.DeepCopyInto(&out.Attributes)
+	if in.UsernameCaseSensitive != nil {
+		in, out := &in.UsernameCaseSensitive, &out.UsernameCaseSensitive
+		*out = new(bool)
+		**out = **in
+	}
+	out.UsernameNormalization = in.UsernameNormalization
 	return
 }
 
@@ -396,6 +418,13 @@ func (in *LDAPIdentityProviderUserSearch) DeepCopy() *LDAPIdentityProviderUserSe
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LDAPIdentityProviderUserSearchAttributes) DeepCopyInto(out *LDAPIdentityProviderUserSearchAttributes) {
 	*out = *in
+	if in.Extra != nil {
+		in, out := &in.Extra, &out.Extra
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -409,6 +438,38 @@ func (in *LDAPIdentityProviderUserSearchAttributes) DeepCopy() *LDAPIdentityProv
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAPIdentityProviderUsernameNormalization) DeepCopyInto(out *LDAPIdentityProviderUsernameNormalization) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPIdentityProviderUsernameNormalization.
+func (in *LDAPIdentityProviderUsernameNormalization) DeepCopy() *LDAPIdentityProviderUsernameNormalization {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAPIdentityProviderUsernameNormalization)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAPIdentityProviderValidation) DeepCopyInto(out *LDAPIdentityProviderValidation) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAPIdentityProviderValidation.
+func (in *LDAPIdentityProviderValidation) DeepCopy() *LDAPIdentityProviderValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAPIdentityProviderValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OIDCAuthorizationConfig) DeepCopyInto(out *OIDCAuthorizationConfig) {
 	*out = *in
@@ -1,4 +1,4 @@
-// Copyright 2020-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -25,6 +25,13 @@ type WebhookAuthenticatorSpec struct {
 	// TLS configuration.
 	// +optional
 	TLS *TLSSpec `json:"tls,omitempty"`
+
+	// CacheTTL is how long a successful token review from the webhook is cached, so that repeated
+	// TokenCredentialRequests with the same token within this period do not each call the webhook.
+	// Denied token reviews are cached for no longer than 2s and never longer than CacheTTL, and failed
+	// calls to the webhook are not cached. Set to 0s to disable caching. Defaults to 10s.
+	// +optional
+	CacheTTL *metav1.Duration `json:"cacheTTL,omitempty"`
 }
 
 // WebhookAuthenticator describes the configuration of a webhook authenticator.
@@ -186,6 +186,27 @@ func (in *CredentialIssuerStrategy) DeepCopy() *CredentialIssuerStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImpersonationProxyAutoModeSpec) DeepCopyInto(out *ImpersonationProxyAutoModeSpec) {
+	*out = *in
+	if in.CreateLoadBalancer != nil {
+		in, out := &in.CreateLoadBalancer, &out.CreateLoadBalancer
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImpersonationProxyAutoModeSpec.
+func (in *ImpersonationProxyAutoModeSpec) DeepCopy() *ImpersonationProxyAutoModeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImpersonationProxyAutoModeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImpersonationProxyInfo) DeepCopyInto(out *ImpersonationProxyInfo) {
 	*out = *in
@@ -205,6 +226,11 @@ func (in *ImpersonationProxyInfo) DeepCopy() *ImpersonationProxyInfo {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImpersonationProxyServiceSpec) DeepCopyInto(out *ImpersonationProxyServiceSpec) {
 	*out = *in
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Annotations != nil {
 		in, out := &in.Annotations, &out.Annotations
 		*out = make(map[string]string, len(*in))
@@ -229,6 +255,11 @@ func (in *ImpersonationProxyServiceSpec) DeepCopy() *ImpersonationProxyServiceSp
 func (in *ImpersonationProxySpec) DeepCopyInto(out *ImpersonationProxySpec) {
 	*out = *in
 	in.Service.DeepCopyInto(&out.Service)
+	if in.AutoMode != nil {
+		in, out := &in.AutoMode, &out.AutoMode
+		*out = new(ImpersonationProxyAutoModeSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
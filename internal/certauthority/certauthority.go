@@ -1,4 +1,4 @@
-// Copyright 2020-2021 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package certauthority implements a simple x509 certificate authority suitable for use in an aggregated API service.
@@ -10,6 +10,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -29,6 +30,75 @@ import (
 // https://github.com/kubernetes/kubernetes/blob/68d646a101005e95379d84160adf01d146bdd149/pkg/controller/certificates/signer/signer.go#L199
 const certBackdate = 5 * time.Minute
 
+// KeyAlgorithm is the algorithm and size of the private keys which are generated for a CA and for the
+// certificates which it issues.
+type KeyAlgorithm string
+
+const (
+	// KeyAlgorithmECDSAP256 is the default key algorithm, which is used when the key algorithm is empty.
+	KeyAlgorithmECDSAP256 = KeyAlgorithm("ECDSA-P256")
+	KeyAlgorithmECDSAP384 = KeyAlgorithm("ECDSA-P384")
+	KeyAlgorithmRSA2048   = KeyAlgorithm("RSA-2048")
+	KeyAlgorithmRSA3072   = KeyAlgorithm("RSA-3072")
+)
+
+// KeyAlgorithms returns all supported key algorithms.
+func KeyAlgorithms() []KeyAlgorithm {
+	return []KeyAlgorithm{KeyAlgorithmECDSAP256, KeyAlgorithmECDSAP384, KeyAlgorithmRSA2048, KeyAlgorithmRSA3072}
+}
+
+// Validate returns an error when the key algorithm is not supported. The empty key algorithm is valid.
+func (a KeyAlgorithm) Validate() error {
+	if a == "" {
+		return nil
+	}
+	for _, supported := range KeyAlgorithms() {
+		if a == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported key algorithm %q", string(a))
+}
+
+// KeyAlgorithmOf returns the key algorithm of the public key, or an empty string when it is not one of the
+// supported key algorithms.
+func KeyAlgorithmOf(publicKey crypto.PublicKey) KeyAlgorithm {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return KeyAlgorithmECDSAP256
+		case elliptic.P384():
+			return KeyAlgorithmECDSAP384
+		}
+	case *rsa.PublicKey:
+		switch key.N.BitLen() {
+		case 2048:
+			return KeyAlgorithmRSA2048
+		case 3072:
+			return KeyAlgorithmRSA3072
+		}
+	}
+	return ""
+}
+
+// generateKey generates a new private key using the key algorithm, where the empty key algorithm means
+// KeyAlgorithmECDSAP256.
+func (a KeyAlgorithm) generateKey(rng io.Reader) (crypto.Signer, error) {
+	switch a {
+	case "", KeyAlgorithmECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rng)
+	case KeyAlgorithmECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rng)
+	case KeyAlgorithmRSA2048:
+		return rsa.GenerateKey(rng, 2048)
+	case KeyAlgorithmRSA3072:
+		return rsa.GenerateKey(rng, 3072)
+	default:
+		return nil, a.Validate()
+	}
+}
+
 type env struct {
 	// secure random number generators for various steps (usually crypto/rand.Reader, but broken out here for tests).
 	serialRNG  io.Reader
@@ -52,8 +122,11 @@ type CA struct {
 
 	// privateKey is the same private key represented by signer, but in a format which allows export.
 	// It is only set by New, not by Load, since Load can handle various types of PrivateKey but New
-	// only needs to create keys of type ecdsa.PrivateKey.
-	privateKey *ecdsa.PrivateKey
+	// only needs to create keys of type *ecdsa.PrivateKey or *rsa.PrivateKey.
+	privateKey crypto.PrivateKey
+
+	// keyAlgorithm is the key algorithm of the private keys of the issued certificates.
+	keyAlgorithm KeyAlgorithm
 
 	// env is our reference to the outside world (clocks and random number generation).
 	env env
@@ -75,6 +148,15 @@ const ErrInvalidCACertificate = constable.Error("invalid CA certificate")
 
 // Load a certificate authority from an existing certificate and private key (in PEM format).
 func Load(certPEM string, keyPEM string) (*CA, error) {
+	return LoadWithKeyAlgorithm(certPEM, keyPEM, KeyAlgorithmECDSAP256)
+}
+
+// LoadWithKeyAlgorithm is like Load, but the certificates which are issued by the loaded certificate authority
+// have private keys of the given key algorithm.
+func LoadWithKeyAlgorithm(certPEM string, keyPEM string, algorithm KeyAlgorithm) (*CA, error) {
+	if err := algorithm.Validate(); err != nil {
+		return nil, fmt.Errorf("could not load CA: %w", err)
+	}
 	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
 	if err != nil {
 		return nil, fmt.Errorf("could not load CA: %w", err)
@@ -90,32 +172,47 @@ func Load(certPEM string, keyPEM string) (*CA, error) {
 		return nil, fmt.Errorf("%w: passed in key pair is not a CA", ErrInvalidCACertificate)
 	}
 	return &CA{
-		caCertBytes: cert.Certificate[0],
-		signer:      cert.PrivateKey.(crypto.Signer),
-		env:         secureEnv(),
+		caCertBytes:  cert.Certificate[0],
+		signer:       cert.PrivateKey.(crypto.Signer),
+		keyAlgorithm: algorithm,
+		env:          secureEnv(),
 	}, nil
 }
 
 // New generates a fresh certificate authority with the given Common Name and TTL.
 func New(commonName string, ttl time.Duration) (*CA, error) {
-	return newInternal(commonName, ttl, secureEnv())
+	return NewWithSubject(pkix.Name{CommonName: commonName}, ttl)
 }
 
-// newInternal is the internal guts of New, broken out for easier testing.
-func newInternal(commonName string, ttl time.Duration, env env) (*CA, error) {
-	ca := CA{env: env}
+// NewWithSubject generates a fresh certificate authority with the given subject and TTL.
+func NewWithSubject(subject pkix.Name, ttl time.Duration) (*CA, error) {
+	return NewWithKeyAlgorithm(subject, ttl, KeyAlgorithmECDSAP256)
+}
+
+// NewWithKeyAlgorithm generates a fresh certificate authority with the given subject and TTL, whose private key
+// and the private keys of the certificates which it issues are of the given key algorithm.
+func NewWithKeyAlgorithm(subject pkix.Name, ttl time.Duration, algorithm KeyAlgorithm) (*CA, error) {
+	return newInternal(subject, ttl, algorithm, secureEnv())
+}
+
+// newInternal is the internal guts of NewWithKeyAlgorithm, broken out for easier testing.
+func newInternal(subject pkix.Name, ttl time.Duration, algorithm KeyAlgorithm, env env) (*CA, error) {
+	if err := algorithm.Validate(); err != nil {
+		return nil, fmt.Errorf("could not generate CA: %w", err)
+	}
+	ca := CA{keyAlgorithm: algorithm, env: env}
 	// Generate a random serial for the CA
 	serialNumber, err := randomSerial(env.serialRNG)
 	if err != nil {
 		return nil, fmt.Errorf("could not generate CA serial: %w", err)
 	}
 
-	// Generate a new P256 keypair.
-	ca.privateKey, err = ecdsa.GenerateKey(elliptic.P256(), env.keygenRNG)
+	// Generate a new keypair.
+	ca.signer, err = algorithm.generateKey(env.keygenRNG)
 	if err != nil {
 		return nil, fmt.Errorf("could not generate CA private key: %w", err)
 	}
-	ca.signer = ca.privateKey
+	ca.privateKey = ca.signer
 
 	// Make a CA certificate valid for some ttl and backdated by some amount.
 	now := env.clock()
@@ -125,7 +222,7 @@ func newInternal(commonName string, ttl time.Duration, env env) (*CA, error) {
 	// Create CA cert template
 	caTemplate := x509.Certificate{
 		SerialNumber:          serialNumber,
-		Subject:               pkix.Name{CommonName: commonName},
+		Subject:               subject,
 		NotBefore:             notBefore,
 		NotAfter:              notAfter,
 		IsCA:                  true,
@@ -135,7 +232,7 @@ func newInternal(commonName string, ttl time.Duration, env env) (*CA, error) {
 	}
 
 	// Self-sign the CA to get the DER certificate.
-	caCertBytes, err := x509.CreateCertificate(env.signingRNG, &caTemplate, &caTemplate, &ca.privateKey.PublicKey, ca.privateKey)
+	caCertBytes, err := x509.CreateCertificate(env.signingRNG, &caTemplate, &caTemplate, ca.signer.Public(), ca.signer)
 	if err != nil {
 		return nil, fmt.Errorf("could not issue CA certificate: %w", err)
 	}
@@ -153,11 +250,18 @@ func (c *CA) PrivateKeyToPEM() ([]byte, error) {
 	if c.privateKey == nil {
 		return nil, fmt.Errorf("no private key data (did you try to use this after Load?)")
 	}
-	derKey, err := x509.MarshalECPrivateKey(c.privateKey)
-	if err != nil {
-		return nil, err
+	switch privateKey := c.privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		derKey, err := x509.MarshalECPrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: derKey}), nil
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", c.privateKey)
 	}
-	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: derKey}), nil
 }
 
 // Pool returns the current CA signing bundle as a *x509.CertPool.
@@ -198,8 +302,8 @@ func (c *CA) issueCert(extKeyUsage x509.ExtKeyUsage, subject pkix.Name, dnsNames
 		return nil, fmt.Errorf("could not generate serial number for certificate: %w", err)
 	}
 
-	// Generate a new P256 keypair.
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), c.env.keygenRNG)
+	// Generate a new keypair.
+	privateKey, err := c.keyAlgorithm.generateKey(c.env.keygenRNG)
 	if err != nil {
 		return nil, fmt.Errorf("could not generate private key: %w", err)
 	}
@@ -227,7 +331,7 @@ func (c *CA) issueCert(extKeyUsage x509.ExtKeyUsage, subject pkix.Name, dnsNames
 		DNSNames:              dnsNames,
 		IPAddresses:           ips,
 	}
-	certBytes, err := x509.CreateCertificate(rand.Reader, &template, caCert, &privateKey.PublicKey, c.signer)
+	certBytes, err := x509.CreateCertificate(rand.Reader, &template, caCert, privateKey.Public(), c.signer)
 	if err != nil {
 		return nil, fmt.Errorf("could not sign certificate: %w", err)
 	}
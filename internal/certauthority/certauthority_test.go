@@ -7,6 +7,7 @@ import (
 	"crypto"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"io"
 	"net"
@@ -159,7 +160,7 @@ func TestNewInternal(t *testing.T) {
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := newInternal("Test CA", tt.ttl, tt.env)
+			got, err := newInternal(pkix.Name{CommonName: "Test CA"}, tt.ttl, KeyAlgorithmECDSAP256, tt.env)
 			if tt.wantErr != "" {
 				require.EqualError(t, err, tt.wantErr)
 				require.Nil(t, got)
@@ -178,6 +179,127 @@ func TestNewInternal(t *testing.T) {
 	}
 }
 
+func TestNewWithSubject(t *testing.T) {
+	ca, err := NewWithSubject(pkix.Name{CommonName: "Test CA", Organization: []string{"Test Org"}}, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, ca)
+
+	caCert, err := x509.ParseCertificate(ca.caCertBytes)
+	require.NoError(t, err)
+	require.Equal(t, "Test CA", caCert.Subject.CommonName)
+	require.Equal(t, []string{"Test Org"}, caCert.Subject.Organization)
+	require.True(t, caCert.IsCA)
+}
+
+func TestNewWithKeyAlgorithm(t *testing.T) {
+	tests := []struct {
+		name          string
+		algorithm     KeyAlgorithm
+		wantAlgorithm KeyAlgorithm
+		wantKeyPEM    string
+		wantErr       string
+	}{
+		{
+			name:          "default",
+			wantAlgorithm: KeyAlgorithmECDSAP256,
+			wantKeyPEM:    "EC PRIVATE KEY",
+		},
+		{
+			name:          "ECDSA P-256",
+			algorithm:     KeyAlgorithmECDSAP256,
+			wantAlgorithm: KeyAlgorithmECDSAP256,
+			wantKeyPEM:    "EC PRIVATE KEY",
+		},
+		{
+			name:          "ECDSA P-384",
+			algorithm:     KeyAlgorithmECDSAP384,
+			wantAlgorithm: KeyAlgorithmECDSAP384,
+			wantKeyPEM:    "EC PRIVATE KEY",
+		},
+		{
+			name:          "RSA 2048",
+			algorithm:     KeyAlgorithmRSA2048,
+			wantAlgorithm: KeyAlgorithmRSA2048,
+			wantKeyPEM:    "RSA PRIVATE KEY",
+		},
+		{
+			name:          "RSA 3072",
+			algorithm:     KeyAlgorithmRSA3072,
+			wantAlgorithm: KeyAlgorithmRSA3072,
+			wantKeyPEM:    "RSA PRIVATE KEY",
+		},
+		{
+			name:      "unsupported",
+			algorithm: KeyAlgorithm("RSA-1024"),
+			wantErr:   `could not generate CA: unsupported key algorithm "RSA-1024"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ca, err := NewWithKeyAlgorithm(pkix.Name{CommonName: "Test CA"}, time.Hour, tt.algorithm)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, ca)
+				return
+			}
+			require.NoError(t, err)
+
+			caCert, err := x509.ParseCertificate(ca.caCertBytes)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAlgorithm, KeyAlgorithmOf(caCert.PublicKey))
+
+			// The private key can be exported and loaded again.
+			keyPEM, err := ca.PrivateKeyToPEM()
+			require.NoError(t, err)
+			require.Contains(t, string(keyPEM), "-----BEGIN "+tt.wantKeyPEM+"-----\n")
+			reloaded, err := LoadWithKeyAlgorithm(string(ca.Bundle()), string(keyPEM), tt.algorithm)
+			require.NoError(t, err)
+
+			// Both the new and the reloaded CA issue certificates which have keys of the same algorithm.
+			for _, issuer := range []*CA{ca, reloaded} {
+				clientCert, err := issuer.IssueClientCert("test-username", []string{"group1"}, time.Hour)
+				require.NoError(t, err)
+				require.Equal(t, tt.wantAlgorithm, KeyAlgorithmOf(clientCert.Leaf.PublicKey))
+				certPEM, clientKeyPEM, err := ToPEM(clientCert)
+				require.NoError(t, err)
+				validateClientCert(t, ca.Bundle(), certPEM, clientKeyPEM, "test-username", []string{"group1"}, time.Hour)
+
+				serverCert, err := issuer.IssueServerCert([]string{"example.com"}, nil, time.Hour)
+				require.NoError(t, err)
+				require.Equal(t, tt.wantAlgorithm, KeyAlgorithmOf(serverCert.Leaf.PublicKey))
+				certPEM, serverKeyPEM, err := ToPEM(serverCert)
+				require.NoError(t, err)
+				validateServerCert(t, ca.Bundle(), certPEM, serverKeyPEM, []string{"example.com"}, nil, time.Hour)
+
+				_, err = serverCert.Leaf.Verify(x509.VerifyOptions{
+					DNSName:   "example.com",
+					Roots:     ca.Pool(),
+					KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+				})
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadWithKeyAlgorithm(t *testing.T) {
+	certPEM, err := os.ReadFile("./testdata/test.crt")
+	require.NoError(t, err)
+	keyPEM, err := os.ReadFile("./testdata/test.key")
+	require.NoError(t, err)
+
+	ca, err := LoadWithKeyAlgorithm(string(certPEM), string(keyPEM), KeyAlgorithmECDSAP384)
+	require.NoError(t, err)
+	cert, err := ca.IssueServerCert([]string{"example.com"}, nil, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, KeyAlgorithmECDSAP384, KeyAlgorithmOf(cert.Leaf.PublicKey))
+
+	ca, err = LoadWithKeyAlgorithm(string(certPEM), string(keyPEM), KeyAlgorithm("RSA-1024"))
+	require.EqualError(t, err, `could not load CA: unsupported key algorithm "RSA-1024"`)
+	require.Nil(t, ca)
+}
+
 func TestBundle(t *testing.T) {
 	ca := CA{caCertBytes: []byte{1, 2, 3, 4, 5, 6, 7, 8}}
 	certPEM := ca.Bundle()
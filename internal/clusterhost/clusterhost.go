@@ -1,4 +1,4 @@
-// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package clusterhost
@@ -6,8 +6,12 @@ package clusterhost
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -20,11 +24,56 @@ const (
 )
 
 type ClusterHost struct {
-	client kubernetes.Interface
+	client                    kubernetes.Interface
+	controlPlaneNodeSelectors []labels.Selector
+	excludedNodeRoles         []string
 }
 
-func New(client kubernetes.Interface) *ClusterHost {
-	return &ClusterHost{client: client}
+// New returns a ClusterHost which considers a node to be a control plane node when its labels match any of
+// the controlPlaneNodeSelectors. When no selectors are given, the well-known node role labels are used.
+func New(client kubernetes.Interface, controlPlaneNodeSelectors ...labels.Selector) *ClusterHost {
+	if len(controlPlaneNodeSelectors) == 0 {
+		controlPlaneNodeSelectors = defaultControlPlaneNodeSelectors()
+	}
+	return &ClusterHost{client: client, controlPlaneNodeSelectors: controlPlaneNodeSelectors}
+}
+
+// WithExcludedNodeRoles causes HasControlPlaneNodes to ignore any node which has one of the given roles in either
+// the node-role.kubernetes.io/<role> or the kubernetes.io/node-role=<role> label format, even when that node also
+// matches a control plane node selector.
+func (c *ClusterHost) WithExcludedNodeRoles(excludedNodeRoles ...string) *ClusterHost {
+	c.excludedNodeRoles = excludedNodeRoles
+	return c
+}
+
+// ParseControlPlaneNodeSelectors parses label selectors which identify control plane nodes, in the same
+// format as the --selector flag of kubectl, e.g. "node-role.kubernetes.io/master" or "example.com/role=control".
+func ParseControlPlaneNodeSelectors(selectors []string) ([]labels.Selector, error) {
+	parsed := make([]labels.Selector, 0, len(selectors))
+	for i, s := range selectors {
+		selector, err := labels.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("selector %d %q is invalid: %w", i, s, err)
+		}
+		if selector.Empty() {
+			return nil, fmt.Errorf("selector %d must not be empty", i)
+		}
+		parsed = append(parsed, selector)
+	}
+	return parsed, nil
+}
+
+// ValidateExcludedNodeRoles checks that each of the roles could be used in the node-role.kubernetes.io/<role> label.
+func ValidateExcludedNodeRoles(roles []string) error {
+	for i, role := range roles {
+		if role == "" {
+			return fmt.Errorf("role %d must not be empty", i)
+		}
+		if errs := validation.IsQualifiedName(labelNodeRolePrefix + role); len(errs) > 0 {
+			return fmt.Errorf("role %d %q is invalid: %s", i, role, strings.Join(errs, "; "))
+		}
+	}
+	return nil
 }
 
 func (c *ClusterHost) HasControlPlaneNodes(ctx context.Context) (bool, error) {
@@ -36,8 +85,11 @@ func (c *ClusterHost) HasControlPlaneNodes(ctx context.Context) (bool, error) {
 		return false, fmt.Errorf("no nodes found")
 	}
 	for _, node := range nodes.Items {
-		for k, v := range node.Labels {
-			if isControlPlaneNodeRole(k, v) {
+		if c.hasExcludedNodeRole(node.Labels) {
+			continue
+		}
+		for _, selector := range c.controlPlaneNodeSelectors {
+			if selector.Matches(labels.Set(node.Labels)) {
 				return true, nil
 			}
 		}
@@ -46,18 +98,31 @@ func (c *ClusterHost) HasControlPlaneNodes(ctx context.Context) (bool, error) {
 	return false, nil
 }
 
-func isControlPlaneNodeRole(k string, v string) bool {
-	if k == labelNodeRolePrefix+controlPlaneNodeRole {
-		return true
-	}
-	if k == labelNodeRolePrefix+masterNodeRole {
-		return true
+func (c *ClusterHost) hasExcludedNodeRole(nodeLabels map[string]string) bool {
+	for _, role := range c.excludedNodeRoles {
+		if _, ok := nodeLabels[labelNodeRolePrefix+role]; ok {
+			return true
+		}
+		if nodeLabels[nodeLabelRole] == role {
+			return true
+		}
 	}
-	if k == nodeLabelRole && v == controlPlaneNodeRole {
-		return true
+	return false
+}
+
+func defaultControlPlaneNodeSelectors() []labels.Selector {
+	return []labels.Selector{
+		labelExistsSelector(labelNodeRolePrefix + controlPlaneNodeRole),
+		labelExistsSelector(labelNodeRolePrefix + masterNodeRole),
+		labels.SelectorFromSet(labels.Set{nodeLabelRole: controlPlaneNodeRole}),
+		labels.SelectorFromSet(labels.Set{nodeLabelRole: masterNodeRole}),
 	}
-	if k == nodeLabelRole && v == masterNodeRole {
-		return true
+}
+
+func labelExistsSelector(key string) labels.Selector {
+	requirement, err := labels.NewRequirement(key, selection.Exists, nil)
+	if err != nil {
+		panic(err) // the keys of the default selectors are constants, so this should never happen
 	}
-	return false
+	return labels.NewSelector().Add(*requirement)
 }
@@ -1,4 +1,4 @@
-// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package clusterhost
@@ -20,11 +20,13 @@ import (
 
 func TestHasControlPlaneNodes(t *testing.T) {
 	tests := []struct {
-		name            string
-		nodes           []*v1.Node
-		listNodesErr    error
-		wantErr         error
-		wantReturnValue bool
+		name                      string
+		nodes                     []*v1.Node
+		controlPlaneNodeSelectors []string
+		excludedNodeRoles         []string
+		listNodesErr              error
+		wantErr                   error
+		wantReturnValue           bool
 	}{
 		{
 			name:         "Fetching nodes returns an error",
@@ -36,6 +38,12 @@ func TestHasControlPlaneNodes(t *testing.T) {
 			nodes:   []*v1.Node{},
 			wantErr: errors.New("no nodes found"),
 		},
+		{
+			name:                      "Fetching nodes returns an empty array when using custom control plane node selectors",
+			nodes:                     []*v1.Node{},
+			controlPlaneNodeSelectors: []string{"example.com/role=control"},
+			wantErr:                   errors.New("no nodes found"),
+		},
 		{
 			name: "Nodes found, but not control plane nodes",
 			nodes: []*v1.Node{
@@ -141,6 +149,106 @@ func TestHasControlPlaneNodes(t *testing.T) {
 			},
 			wantReturnValue: true,
 		},
+		{
+			name: "Nodes found, including a node which matches a custom control plane node selector",
+			nodes: []*v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node-1",
+						Labels: map[string]string{"example.com/role": "worker"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node-2",
+						Labels: map[string]string{"example.com/role": "control"},
+					},
+				},
+			},
+			controlPlaneNodeSelectors: []string{"some-other-label", "example.com/role in (control, ctrl)"},
+			wantReturnValue:           true,
+		},
+		{
+			name: "Nodes found with the well-known control plane labels, but they do not match the custom control plane node selectors",
+			nodes: []*v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "node-1",
+						Labels: map[string]string{
+							"node-role.kubernetes.io/control-plane": "",
+							"kubernetes.io/node-role":               "master",
+							"example.com/role":                      "worker",
+						},
+					},
+				},
+			},
+			controlPlaneNodeSelectors: []string{"example.com/role=control"},
+			wantReturnValue:           false,
+		},
+		{
+			name: "Nodes found with the well-known control plane labels, but they also have an excluded role",
+			nodes: []*v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "node-1",
+						Labels: map[string]string{
+							"node-role.kubernetes.io/control-plane": "",
+							"node-role.kubernetes.io/edge":          "",
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "node-2",
+						Labels: map[string]string{
+							"node-role.kubernetes.io/master": "",
+							"kubernetes.io/node-role":        "virtual",
+						},
+					},
+				},
+			},
+			excludedNodeRoles: []string{"edge", "virtual"},
+			wantReturnValue:   false,
+		},
+		{
+			name: "Nodes found, including a control plane node which does not have an excluded role",
+			nodes: []*v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "node-1",
+						Labels: map[string]string{
+							"node-role.kubernetes.io/control-plane": "",
+							"node-role.kubernetes.io/edge":          "",
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node-2",
+						Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+					},
+				},
+			},
+			excludedNodeRoles: []string{"edge"},
+			wantReturnValue:   true,
+		},
+		{
+			name: "Nodes found which match a custom control plane node selector, but they have an excluded role",
+			nodes: []*v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "node-1",
+						Labels: map[string]string{
+							"example.com/role":             "control",
+							"node-role.kubernetes.io/edge": "",
+						},
+					},
+				},
+			},
+			controlPlaneNodeSelectors: []string{"example.com/role=control"},
+			excludedNodeRoles:         []string{"edge"},
+			wantReturnValue:           false,
+		},
 	}
 	for _, tt := range tests {
 		test := tt
@@ -160,10 +268,92 @@ func TestHasControlPlaneNodes(t *testing.T) {
 				err := kubeClient.Tracker().Add(node)
 				require.NoError(t, err)
 			}
-			clusterHost := New(kubeClient)
+			controlPlaneNodeSelectors, err := ParseControlPlaneNodeSelectors(test.controlPlaneNodeSelectors)
+			require.NoError(t, err)
+			clusterHost := New(kubeClient, controlPlaneNodeSelectors...).WithExcludedNodeRoles(test.excludedNodeRoles...)
 			hasControlPlaneNodes, err := clusterHost.HasControlPlaneNodes(context.Background())
 			require.Equal(t, test.wantErr, err)
 			require.Equal(t, test.wantReturnValue, hasControlPlaneNodes)
 		})
 	}
 }
+
+func TestParseControlPlaneNodeSelectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []string
+		wantErr   string
+		wantLen   int
+	}{
+		{
+			name:      "no selectors",
+			selectors: nil,
+			wantLen:   0,
+		},
+		{
+			name:      "valid selectors",
+			selectors: []string{"node-role.kubernetes.io/master", "example.com/role=control,!example.com/worker"},
+			wantLen:   2,
+		},
+		{
+			name:      "invalid selector",
+			selectors: []string{"node-role.kubernetes.io/master", "example.com/role in control"},
+			wantErr:   `selector 1 "example.com/role in control" is invalid: `,
+		},
+		{
+			name:      "empty selector",
+			selectors: []string{""},
+			wantErr:   "selector 0 must not be empty",
+		},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			selectors, err := ParseControlPlaneNodeSelectors(test.selectors)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, selectors, test.wantLen)
+		})
+	}
+}
+
+func TestValidateExcludedNodeRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   []string
+		wantErr string
+	}{
+		{
+			name:  "no roles",
+			roles: nil,
+		},
+		{
+			name:  "valid roles",
+			roles: []string{"edge", "virtual-kubelet", "infra.example"},
+		},
+		{
+			name:    "invalid role",
+			roles:   []string{"edge", "not a role"},
+			wantErr: `role 1 "not a role" is invalid: `,
+		},
+		{
+			name:    "empty role",
+			roles:   []string{""},
+			wantErr: "role 0 must not be empty",
+		},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateExcludedNodeRoles(test.roles)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+)
+
+const (
+	// These match the settings of the generic API server's own secure serving, so that requiring client certificates
+	// does not change how the impersonation proxy serves requests otherwise.
+	clientCertServerMaxHeaderBytes      = 1 << 20
+	clientCertServerIdleTimeout         = 90 * time.Second
+	clientCertServerReadHeaderTimeout   = 32 * time.Second
+	clientCertServerMaxStreamsPerConn   = 250
+	clientCertServerMaxBufferPerStream  = 256 * 1024
+	clientCertServerDefaultNextProtocol = "http/1.1"
+)
+
+// serveRequiringClientCerts serves handler on the listener of servingInfo like the generic API server would, except
+// that the TLS handshake requires a client certificate which is signed by one of the CAs of clientCA. The generic
+// API server only ever requests client certificates during the handshake, because they are optional for
+// authentication, so it cannot be used to serve when they are required. Connections without a valid client
+// certificate are therefore closed before any request is read from them.
+//
+// It returns a channel which is closed when the server has been shut down after stopCh was closed.
+func serveRequiringClientCerts(
+	servingInfo *genericapiserver.SecureServingInfo,
+	handler http.Handler,
+	clientCA dynamiccertificates.CAContentProvider,
+	shutdownTimeout time.Duration,
+	stopCh <-chan struct{},
+) (<-chan struct{}, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:   servingInfo.MinTLSVersion,
+		CipherSuites: servingInfo.CipherSuites,
+		NextProtos:   []string{http2.NextProtoTLS, clientCertServerDefaultNextProtocol},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	if servingInfo.DisableHTTP2 {
+		tlsConfig.NextProtos = []string{clientCertServerDefaultNextProtocol}
+	}
+
+	// Keep the serving certificate up to date and use the client CA, like the generic API server does.
+	certController := dynamiccertificates.NewDynamicServingCertificateController(
+		tlsConfig, clientCA, servingInfo.Cert, servingInfo.SNICerts, nil,
+	)
+	if servingInfo.Cert != nil {
+		servingInfo.Cert.AddListener(certController)
+	}
+	if err := certController.RunOnce(); err != nil {
+		return nil, fmt.Errorf("could not load the impersonation proxy serving certificate: %w", err)
+	}
+	go certController.Run(1, stopCh)
+	tlsConfig.GetConfigForClient = certController.GetConfigForClient
+
+	server := &http.Server{
+		Addr:              servingInfo.Listener.Addr().String(),
+		Handler:           handler,
+		MaxHeaderBytes:    clientCertServerMaxHeaderBytes,
+		TLSConfig:         tlsConfig,
+		IdleTimeout:       clientCertServerIdleTimeout,
+		ReadHeaderTimeout: clientCertServerReadHeaderTimeout,
+	}
+	if !servingInfo.DisableHTTP2 {
+		maxStreams := uint32(clientCertServerMaxStreamsPerConn)
+		if servingInfo.HTTP2MaxStreamsPerConnection > 0 {
+			maxStreams = uint32(servingInfo.HTTP2MaxStreamsPerConnection)
+		}
+		if err := http2.ConfigureServer(server, &http2.Server{
+			IdleTimeout:                  clientCertServerIdleTimeout,
+			MaxConcurrentStreams:         maxStreams,
+			MaxReadFrameSize:             clientCertServerMaxBufferPerStream,
+			MaxUploadBufferPerStream:     clientCertServerMaxBufferPerStream,
+			MaxUploadBufferPerConnection: clientCertServerMaxBufferPerStream * int32(maxStreams),
+		}); err != nil {
+			return nil, fmt.Errorf("could not configure http2 for the impersonation proxy: %w", err)
+		}
+	}
+
+	stoppedCh, _, err := genericapiserver.RunServer(server, servingInfo.Listener, shutdownTimeout, stopCh)
+	return stoppedCh, err
+}
+
+// nonBlockingRunner is implemented by a prepared generic API server.
+type nonBlockingRunner interface {
+	NonBlockingRun(stopCh <-chan struct{}, shutdownTimeout time.Duration) (<-chan struct{}, <-chan struct{}, error)
+}
+
+// runRequiringClientCerts is like the Run method of a prepared generic API server which does not have any secure
+// serving info of its own, so that its handler can be served by serveRequiringClientCerts instead. It blocks until
+// stopCh is closed and the server has been shut down.
+func runRequiringClientCerts(
+	server nonBlockingRunner,
+	servingInfo *genericapiserver.SecureServingInfo,
+	handler http.Handler,
+	clientCA dynamiccertificates.CAContentProvider,
+	shutdownTimeout time.Duration,
+	stopCh <-chan struct{},
+) error {
+	// Use an internal stop channel to stop serving when the server fails to start.
+	internalStopCh := make(chan struct{})
+	stoppedCh, err := serveRequiringClientCerts(servingInfo, handler, clientCA, shutdownTimeout, internalStopCh)
+	if err != nil {
+		close(internalStopCh)
+		return err
+	}
+
+	// Without secure serving info, this only runs the post start hooks of the server.
+	if _, _, err := server.NonBlockingRun(stopCh, shutdownTimeout); err != nil {
+		close(internalStopCh)
+		<-stoppedCh
+		return err
+	}
+
+	<-stopCh
+	close(internalStopCh)
+	<-stoppedCh
+	return nil
+}
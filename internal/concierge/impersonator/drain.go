@@ -0,0 +1,121 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"go.pinniped.dev/internal/constable"
+)
+
+// ErrDrainTimeout is returned by the start function of an impersonator server when some client connections were
+// still open once the shutdown drain timeout had elapsed, so they were closed without waiting for them to finish.
+const ErrDrainTimeout = constable.Error("timed out waiting for impersonation proxy connections to drain")
+
+// The same keep-alive period which the generic API server sets on the connections that it accepts. It cannot do
+// that for these connections because it can only set it on the *net.TCPConn type.
+const keepAlivePeriod = 3 * time.Minute
+
+// connTrackingListener is a net.Listener which keeps track of the connections which it accepted until they are
+// closed. Unlike http.Server.Shutdown, it also tracks connections which were hijacked from the http.Server,
+// such as those used for exec and port-forward streams, so that they can be drained too.
+type connTrackingListener struct {
+	net.Listener
+
+	mutex  sync.Mutex
+	conns  map[*trackedConn]struct{}
+	idleCh chan struct{} // closed once there are no open connections, when somebody is waiting for that
+}
+
+func newConnTrackingListener(listener net.Listener) *connTrackingListener {
+	return &connTrackingListener{Listener: listener, conns: map[*trackedConn]struct{}{}}
+}
+
+func (l *connTrackingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetKeepAlive(true)
+		_ = tcpConn.SetKeepAlivePeriod(keepAlivePeriod)
+	}
+
+	tracked := &trackedConn{Conn: conn, listener: l}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.conns[tracked] = struct{}{}
+	return tracked, nil
+}
+
+func (l *connTrackingListener) remove(conn *trackedConn) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.conns, conn)
+	if len(l.conns) == 0 && l.idleCh != nil {
+		close(l.idleCh)
+		l.idleCh = nil
+	}
+}
+
+// idle returns a channel which is closed once there are no open connections.
+func (l *connTrackingListener) idle() <-chan struct{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.conns) == 0 {
+		idleCh := make(chan struct{})
+		close(idleCh)
+		return idleCh
+	}
+	if l.idleCh == nil {
+		l.idleCh = make(chan struct{})
+	}
+	return l.idleCh
+}
+
+// drain waits up to timeout for all open connections to be closed, and then closes any connections which are
+// still open. The returned error wraps ErrDrainTimeout when any connection had to be closed, along with any errors
+// from closing them.
+func (l *connTrackingListener) drain(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-l.idle():
+		return nil
+	case <-timer.C:
+	}
+
+	l.mutex.Lock()
+	conns := make([]*trackedConn, 0, len(l.conns))
+	for conn := range l.conns {
+		conns = append(conns, conn)
+	}
+	l.mutex.Unlock()
+
+	errs := []error{fmt.Errorf("%w: closing %d connections which were still open after %s", ErrDrainTimeout, len(conns), timeout)}
+	for _, conn := range conns {
+		if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, fmt.Errorf("could not close connection from %s: %w", conn.RemoteAddr(), err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// trackedConn is a net.Conn which removes itself from its connTrackingListener when it is closed.
+type trackedConn struct {
+	net.Conn
+	listener  *connTrackingListener
+	closeOnce sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.closeOnce.Do(func() { c.listener.remove(c) })
+	return c.Conn.Close()
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnTrackingListenerDrain(t *testing.T) {
+	t.Parallel()
+
+	newListener := func(t *testing.T) *connTrackingListener {
+		t.Helper()
+		tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		listener := newConnTrackingListener(tcpListener)
+		t.Cleanup(func() { _ = listener.Close() })
+		return listener
+	}
+
+	// accept dials the listener and returns the server side of the new connection.
+	accept := func(t *testing.T, listener *connTrackingListener) net.Conn {
+		t.Helper()
+		clientConn, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = clientConn.Close() })
+		serverConn, err := listener.Accept()
+		require.NoError(t, err)
+		return serverConn
+	}
+
+	t.Run("returns immediately when there are no open connections", func(t *testing.T) {
+		t.Parallel()
+		listener := newListener(t)
+		require.NoError(t, listener.drain(time.Hour))
+
+		accept(t, listener).Close()
+		require.NoError(t, listener.drain(time.Hour))
+	})
+
+	t.Run("waits for the open connections to be closed", func(t *testing.T) {
+		t.Parallel()
+		listener := newListener(t)
+		conn1, conn2 := accept(t, listener), accept(t, listener)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = conn1.Close()
+			_ = conn1.Close() // closing twice is harmless
+			_ = conn2.Close()
+		}()
+
+		require.NoError(t, listener.drain(time.Hour))
+	})
+
+	t.Run("closes the connections which are still open after the timeout", func(t *testing.T) {
+		t.Parallel()
+		listener := newListener(t)
+		conn1, conn2 := accept(t, listener), accept(t, listener)
+		require.NoError(t, conn1.Close())
+
+		err := listener.drain(50 * time.Millisecond)
+		require.EqualError(t, err, "timed out waiting for impersonation proxy connections to drain: closing 1 connections which were still open after 50ms")
+		require.True(t, errors.Is(err, ErrDrainTimeout))
+
+		_, err = conn2.Write([]byte("hello"))
+		require.ErrorIs(t, err, net.ErrClosed)
+		require.NoError(t, listener.drain(time.Hour))
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// forwardedForHeader is removed from requests by default, so that clients cannot cause log confusion by spoofing
+// the addresses which appear in the Kube API server's audit logs.
+const forwardedForHeader = "X-Forwarded-For"
+
+// requestHeaderPolicy decides which of the headers of a client's request are removed before the request is proxied
+// to the Kube API server. The header names are canonical.
+type requestHeaderPolicy struct {
+	strip       sets.String
+	passThrough sets.String
+}
+
+// newRequestHeaderPolicy returns the policy for the StripRequestHeaders and PassThroughRequestHeaders of the config.
+// The impersonation and authorization headers are reserved, since the proxy always rejects requests which still have
+// them after authentication and impersonation, so they cannot be configured to be stripped or passed through.
+func newRequestHeaderPolicy(config Config) (requestHeaderPolicy, error) {
+	policy := requestHeaderPolicy{strip: sets.NewString(), passThrough: sets.NewString()}
+
+	for _, header := range config.StripRequestHeaders {
+		key, err := validRequestHeaderName(header)
+		if err != nil {
+			return requestHeaderPolicy{}, err
+		}
+		policy.strip.Insert(key)
+	}
+
+	for _, header := range config.PassThroughRequestHeaders {
+		key, err := validRequestHeaderName(header)
+		if err != nil {
+			return requestHeaderPolicy{}, err
+		}
+		if policy.strip.Has(key) {
+			return requestHeaderPolicy{}, fmt.Errorf("invalid impersonation proxy request header %q: cannot be both stripped and passed through", header)
+		}
+		policy.passThrough.Insert(key)
+	}
+
+	if !policy.passThrough.Has(forwardedForHeader) {
+		policy.strip.Insert(forwardedForHeader)
+	}
+
+	return policy, nil
+}
+
+func validRequestHeaderName(header string) (string, error) {
+	if !httpguts.ValidHeaderFieldName(header) {
+		return "", fmt.Errorf("invalid impersonation proxy request header %q: not a valid header name", header)
+	}
+	key := http.CanonicalHeaderKey(header)
+	if isReservedRequestHeader(key) {
+		return "", fmt.Errorf("invalid impersonation proxy request header %q: impersonation and authorization headers are reserved", header)
+	}
+	return key, nil
+}
+
+// isReservedRequestHeader matches the canonical names of the headers which ensureNoImpersonationHeaders and the
+// authorization header check of the proxy reject.
+func isReservedRequestHeader(key string) bool {
+	return key == "Authorization" || strings.HasPrefix(key, "Impersonate")
+}
+
+// stripHeaders returns the request without the headers which the policy strips, or the same request when it does not
+// have any of them.
+func (p requestHeaderPolicy) stripHeaders(r *http.Request) *http.Request {
+	var keys []string
+	for key := range r.Header {
+		// the keys are usually already canonical, but canonicalize them in case a client managed to send other keys
+		if p.strip.Has(http.CanonicalHeaderKey(key)) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return r
+	}
+
+	r = utilnet.CloneRequest(r)
+	for _, key := range keys {
+		delete(r.Header, key)
+	}
+	return r
+}
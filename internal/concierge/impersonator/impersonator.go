@@ -4,7 +4,10 @@
 package impersonator
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,10 +17,13 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	goruntime "runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -58,37 +64,220 @@ import (
 	"go.pinniped.dev/internal/valuelesscontext"
 )
 
+// healthzPath is served directly by the impersonator, without requiring authentication, so that it can be
+// used as a readiness probe. Note that requests for /healthz itself are proxied to the Kube API server.
+const healthzPath = "/healthz/impersonation-proxy"
+
 // FactoryFunc is a function which can create an impersonator server.
 // It returns a function which will start the impersonator server.
-// That start function takes a stopCh which can be used to stop the server.
+// That start function takes a stopCh which can be used to stop the server. When stopped, the server waits up to
+// shutdownDrainTimeout for its open connections to finish, and then closes any which remain open, in which case
+// the start function returns an error which wraps ErrDrainTimeout.
 // Once a server has been stopped, don't start it again using the start function.
 // Instead, call the factory function again to get a new start function.
 type FactoryFunc func(
-	port int,
+	address string,
+	requestTimeout time.Duration,
+	shutdownDrainTimeout time.Duration,
 	dynamicCertProvider dynamiccert.Private,
 	impersonationProxySignerCA dynamiccert.Public,
 ) (func(stopCh <-chan struct{}) error, error)
 
+// Config holds the optional settings of an impersonator server. The zero value of each field is its default.
+type Config struct {
+	// RequestLogLevel is the level at which each request is logged. Defaults to plog.LevelDebug.
+	RequestLogLevel plog.LogLevel
+
+	// MaxResponseBodyBytes is the largest response body which is relayed from the Kube API server for a request
+	// which does not stream its response. Larger responses fail with a 502 Bad Gateway. Defaults to unlimited.
+	MaxResponseBodyBytes int64
+
+	// MinTLSVersion is the minimum TLS version which clients must use, either "VersionTLS12" or "VersionTLS13".
+	// Defaults to the minimum version of the default TLS profile, which is TLS 1.2.
+	MinTLSVersion string
+
+	// CipherSuites are the names of the TLS 1.2 cipher suites which clients may use, as named by crypto/tls,
+	// e.g. "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384". Only secure cipher suites are allowed. They cannot be set
+	// when MinTLSVersion is "VersionTLS13", because TLS 1.3 cipher suites are not configurable.
+	// Defaults to the cipher suites of the default TLS profile.
+	CipherSuites []string
+
+	// ClientCABundle is an optional PEM-encoded bundle of CA certificates. When it is set, the TLS handshake of every
+	// connection requires a client certificate signed by one of these CAs, so connections without one are closed
+	// before any request is read from them. This is in addition to the usual authentication of the request, so the
+	// client certificate must still authenticate the user unless the request carries another credential. Health
+	// checks, which have no client certificate, can then only use the metrics listener (see MetricsAddress).
+	ClientCABundle []byte
+
+	// PermitPortSharing binds the listener with SO_REUSEPORT, so that a new listener can bind to the port before
+	// the listener of a previous server has been closed, which avoids briefly refusing connections when the proxy
+	// is restarted. It is ignored on platforms which do not support SO_REUSEPORT.
+	PermitPortSharing bool
+
+	// MetricsAddress is the host:port of an optional plain HTTP listener, separate from the TLS listener of the proxy,
+	// on which the Prometheus metrics of the process are served at /metrics, along with the impersonator's own health
+	// check. An empty host means 127.0.0.1, so the metrics are only reachable from within the pod unless a host is
+	// chosen. The listener is started and stopped along with the proxy. Defaults to empty, which means that there is
+	// no metrics listener.
+	MetricsAddress string
+
+	// StripRequestHeaders are the names of client request headers which are removed from requests before they are
+	// proxied to the Kube API server, e.g. custom authentication headers which are only meant for a proxy in front of
+	// the impersonation proxy. The impersonation headers and the Authorization header are reserved and cannot be
+	// listed, since requests which still have them after authentication and impersonation are always rejected.
+	StripRequestHeaders []string
+
+	// PassThroughRequestHeaders are the names of client request headers which are proxied to the Kube API server even
+	// though they would be removed by default. Only X-Forwarded-For is removed by default, so that clients cannot spoof
+	// the addresses in the Kube API server's audit logs. The reserved headers cannot be listed, and a header cannot
+	// be both stripped and passed through.
+	PassThroughRequestHeaders []string
+}
+
+const (
+	minTLSVersionTLS12 = "VersionTLS12"
+	minTLSVersionTLS13 = "VersionTLS13"
+)
+
+// validateTLSConfig checks that the optional TLS settings of the config name a known TLS version
+// and secure TLS 1.2 cipher suites.
+func validateTLSConfig(config Config) error {
+	switch config.MinTLSVersion {
+	case "", minTLSVersionTLS12, minTLSVersionTLS13:
+	default:
+		return fmt.Errorf("invalid impersonation proxy minimum TLS version %q: must be %s or %s",
+			config.MinTLSVersion, minTLSVersionTLS12, minTLSVersionTLS13)
+	}
+
+	if len(config.CipherSuites) == 0 {
+		return nil
+	}
+	if config.MinTLSVersion == minTLSVersionTLS13 {
+		return fmt.Errorf("invalid impersonation proxy cipher suites: cannot be configured when the minimum TLS version is %s",
+			minTLSVersionTLS13)
+	}
+	allowedCipherSuites := sets.NewString()
+	for _, suite := range tls.CipherSuites() {
+		for _, version := range suite.SupportedVersions {
+			if version == tls.VersionTLS12 {
+				allowedCipherSuites.Insert(suite.Name)
+			}
+		}
+	}
+	for _, name := range config.CipherSuites {
+		if !allowedCipherSuites.Has(name) {
+			return fmt.Errorf("invalid impersonation proxy cipher suite %q: must be one of %s",
+				name, strings.Join(allowedCipherSuites.List(), ", "))
+		}
+	}
+	return nil
+}
+
+// applyTLSConfig overrides the default TLS settings of the serving options with the optional settings of the config,
+// which must already be valid.
+func applyTLSConfig(opts *genericoptions.SecureServingOptionsWithLoopback, config Config) {
+	if config.MinTLSVersion != "" {
+		opts.MinTLSVersion = config.MinTLSVersion
+	}
+	switch {
+	case len(config.CipherSuites) > 0:
+		opts.CipherSuites = config.CipherSuites
+	case config.MinTLSVersion == minTLSVersionTLS13:
+		// TLS 1.3 cipher suites are not configurable, so the default TLS 1.2 cipher suites would only be ignored.
+		opts.CipherSuites = nil
+	}
+}
+
+// portSharingSupported is false on the platforms for which the generic API server cannot set SO_REUSEPORT.
+const portSharingSupported = goruntime.GOOS != "windows"
+
+// applyListenConfig sets the options for how the serving options create their listener from the config.
+func applyListenConfig(opts *genericoptions.SecureServingOptionsWithLoopback, config Config) {
+	if config.PermitPortSharing && portSharingSupported {
+		opts.PermitPortSharing = true
+	}
+}
+
 func New(
-	port int,
+	address string,
+	requestTimeout time.Duration,
+	shutdownDrainTimeout time.Duration,
 	dynamicCertProvider dynamiccert.Private,
 	impersonationProxySignerCA dynamiccert.Public,
 ) (func(stopCh <-chan struct{}) error, error) {
-	return newInternal(port, dynamicCertProvider, impersonationProxySignerCA, kubeclient.Secure, nil, nil, nil)
+	return newInternal(address, requestTimeout, shutdownDrainTimeout, dynamicCertProvider, impersonationProxySignerCA, Config{}, kubeclient.Secure, nil, nil, nil)
+}
+
+// NewWithConfig returns a FactoryFunc which is the same as New, except that the servers which it creates
+// use the optional settings from config.
+func NewWithConfig(config Config) FactoryFunc {
+	return func(
+		address string,
+		requestTimeout time.Duration,
+		shutdownDrainTimeout time.Duration,
+		dynamicCertProvider dynamiccert.Private,
+		impersonationProxySignerCA dynamiccert.Public,
+	) (func(stopCh <-chan struct{}) error, error) {
+		return newInternal(address, requestTimeout, shutdownDrainTimeout, dynamicCertProvider, impersonationProxySignerCA, config, kubeclient.Secure, nil, nil, nil)
+	}
 }
 
 func newInternal( //nolint:funlen // yeah, it's kind of long.
-	port int,
+	address string, // host:port, where an empty host means all network interfaces
+	requestTimeout time.Duration,
+	shutdownDrainTimeout time.Duration,
 	dynamicCertProvider dynamiccert.Private,
 	impersonationProxySignerCA dynamiccert.Public,
+	config Config,
 	restConfigFunc ptls.RestConfigFunc, // for unit testing, should always be kubeclient.Secure in production
 	clientOpts []kubeclient.Option, // for unit testing, should always be nil in production
 	recOpts func(*genericoptions.RecommendedOptions), // for unit testing, should always be nil in production
 	recConfig func(*genericapiserver.RecommendedConfig), // for unit testing, should always be nil in production
 ) (func(stopCh <-chan struct{}) error, error) {
-	var listener net.Listener
+	var listener, metricsListener net.Listener
+
+	requestLogLevel := config.RequestLogLevel
+	if requestLogLevel == "" {
+		requestLogLevel = plog.LevelDebug
+	}
 
 	constructServer := func() (func(stopCh <-chan struct{}) error, error) {
+		if err := validateTLSConfig(config); err != nil {
+			return nil, err
+		}
+
+		requestHeaders, err := newRequestHeaderPolicy(config)
+		if err != nil {
+			return nil, err
+		}
+
+		var clientCA dynamiccertificates.CAContentProvider
+		if len(config.ClientCABundle) > 0 {
+			if !x509.NewCertPool().AppendCertsFromPEM(config.ClientCABundle) {
+				return nil, constable.Error("invalid impersonation proxy client CA bundle: no certificates found")
+			}
+			clientCA, err = dynamiccertificates.NewStaticCAContent("impersonation-proxy-client-ca", config.ClientCABundle)
+			if err != nil {
+				return nil, fmt.Errorf("invalid impersonation proxy client CA bundle: %w", err)
+			}
+		}
+
+		bindHost, bindPortString, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid impersonation proxy address %q: %w", address, err)
+		}
+		port, err := strconv.Atoi(bindPortString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid impersonation proxy address %q: %w", address, err)
+		}
+
+		if config.MetricsAddress != "" {
+			metricsListener, err = newMetricsListener(config.MetricsAddress)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// Bare minimum server side scheme to allow for status messages to be encoded.
 		scheme := runtime.NewScheme()
 		metav1.AddToGroupVersion(scheme, metav1.Unversioned)
@@ -104,6 +293,13 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 		recommendedOptions.Etcd = nil                                                   // turn off etcd storage because we don't need it yet
 		recommendedOptions.SecureServing.ServerCert.GeneratedCert = dynamicCertProvider // serving certs (end user facing)
 		recommendedOptions.SecureServing.BindPort = port
+		if bindHost != "" {
+			bindIP := net.ParseIP(bindHost)
+			if bindIP == nil {
+				return nil, fmt.Errorf("invalid impersonation proxy address %q: host must be an IP address", address)
+			}
+			recommendedOptions.SecureServing.BindAddress = bindIP
+		}
 
 		// secure TLS for connections coming from external clients and going to the Kube API server
 		// this is best effort because not all options provide the right hooks to override TLS config
@@ -111,6 +307,8 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 		if err := ptls.DefaultRecommendedOptions(recommendedOptions, restConfigFunc); err != nil {
 			return nil, fmt.Errorf("failed to secure recommended options: %w", err)
 		}
+		applyTLSConfig(recommendedOptions.SecureServing, config)
+		applyListenConfig(recommendedOptions.SecureServing, config)
 
 		// Wire up the impersonation proxy signer CA as another valid authenticator for client cert auth,
 		// along with the Kube API server's CA.
@@ -148,6 +346,11 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 			return nil, err
 		}
 
+		// Track the connections, including hijacked connections for long-running requests, so that they can be
+		// drained when the server is stopped.
+		trackingListener := newConnTrackingListener(serverConfig.SecureServing.Listener)
+		serverConfig.SecureServing.Listener = trackingListener
+
 		// Loopback authentication to this server does not really make sense since we just proxy everything to
 		// the Kube API server, thus we replace loopback connection config with one that does direct connections
 		// the Kube API server. Loopback config is mainly used by post start hooks, so this is mostly future proofing.
@@ -166,6 +369,10 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 			sets.NewString("attach", "exec", "proxy", "log", "portforward"),
 		)
 
+		// Bound how long a request may take, so that clients do not hang forever when the Kube API server stalls.
+		// Long-running requests (as decided by the LongRunningFunc above), such as watches and exec, are exempt.
+		serverConfig.RequestTimeout = requestTimeout
+
 		// use the custom impersonation proxy service account credentials when reverse proxying to the API server
 		kubeClientForProxy, err := getReverseProxyClient(clientOpts)
 		if err != nil {
@@ -174,7 +381,7 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 
 		// Assume proto config is safe because transport level configs do not use rest.ContentConfig.
 		// Thus if we are interacting with actual APIs, they should be using pre-built clients.
-		impersonationProxyFunc, err := newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig))
+		impersonationProxyFunc, err := newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig), config.MaxResponseBodyBytes, requestHeaders)
 		if err != nil {
 			return nil, err
 		}
@@ -194,6 +401,12 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 			}))
 			handler = filterlatency.TrackStarted(handler, c.TracerProvider, "impersonationproxy")
 
+			// Log each request. This runs after the standard Kube handler chain below, so the user who the
+			// request will be proxied as has already been authenticated (and impersonated, if requested).
+			handler = filterlatency.TrackCompleted(handler)
+			handler = withRequestLogging(handler, plog.New(), requestLogLevel)
+			handler = filterlatency.TrackStarted(handler, c.TracerProvider, "requestlogging")
+
 			// The standard Kube handler chain (authn, authz, impersonation, audit, etc).
 			// See the genericapiserver.DefaultBuildHandlerChain func for details.
 			handler = defaultBuildHandlerChainFunc(handler, c)
@@ -203,6 +416,9 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 			handler = withBearerTokenPreservation(handler)
 			handler = filterlatency.TrackStarted(handler, c.TracerProvider, "bearertokenpreservation")
 
+			// Answer health checks before authentication, since probes will not have client certs.
+			handler = withHealthz(handler)
+
 			// Always set security headers so browsers do the right thing.
 			handler = filterlatency.TrackCompleted(handler)
 			handler = securityheader.Wrap(handler)
@@ -307,6 +523,16 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 		if err != nil {
 			return nil, err
 		}
+		// Bound how long the graceful shutdown of the http.Server waits for its connections to become idle.
+		impersonationProxyServer.ShutdownTimeout = shutdownDrainTimeout
+
+		// When client certs are required, the handler chain is served by serveRequiringClientCerts instead of by
+		// the generic API server, which would only request them.
+		var clientCertServingInfo *genericapiserver.SecureServingInfo
+		if clientCA != nil {
+			clientCertServingInfo = impersonationProxyServer.SecureServingInfo
+			impersonationProxyServer.SecureServingInfo = nil
+		}
 
 		preparedRun := impersonationProxyServer.PrepareRun()
 
@@ -325,7 +551,31 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 			return nil, constable.Error("invalid impersonator loopback rest config has wrong bearer token semantics")
 		}
 
-		return preparedRun.Run, nil
+		return func(stopCh <-chan struct{}) error {
+			if metricsListener != nil {
+				stopServingMetrics := serveMetrics(metricsListener)
+				defer stopServingMetrics()
+			}
+
+			drainErrCh := make(chan error, 1)
+			go func() {
+				<-stopCh
+				drainErrCh <- trackingListener.drain(shutdownDrainTimeout)
+			}()
+
+			var runErr error
+			if clientCertServingInfo != nil {
+				runErr = runRequiringClientCerts(preparedRun, clientCertServingInfo, preparedRun.Handler, clientCA, shutdownDrainTimeout, stopCh)
+			} else {
+				runErr = preparedRun.Run(stopCh)
+			}
+			select {
+			case <-stopCh:
+			default:
+				return runErr // the server stopped without being asked to stop, so there is nothing to drain
+			}
+			return errors.NewAggregate([]error{runErr, <-drainErrCh})
+		}, nil
 	}
 
 	result, err := constructServer()
@@ -335,6 +585,9 @@ func newInternal( //nolint:funlen // yeah, it's kind of long.
 		if listener != nil {
 			errs = append(errs, listener.Close())
 		}
+		if metricsListener != nil {
+			errs = append(errs, metricsListener.Close())
+		}
 		return nil, errors.NewAggregate(errs)
 	}
 	return result, nil
@@ -447,17 +700,93 @@ func withBearerTokenPreservation(delegate http.Handler) http.Handler {
 	})
 }
 
+func withHealthz(delegate http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != healthzPath {
+			delegate.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// If this handler is being called, then the server is up and ready to proxy requests.
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write([]byte(`{"status":"ok"}` + "\n"))
+	})
+}
+
+// redactedHeaders are never logged because their values are credentials.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// withRequestLogging logs the method, path, headers, and user of each request at the given level, along with
+// a newly generated request ID. The request ID is also added to the request's context so that any later
+// log statements about the same request can include it.
+func withRequestLogging(delegate http.Handler, log plog.Logger, level plog.LogLevel) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := uuid.NewString()
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey, requestID))
+
+		var username string
+		if userInfo, ok := request.UserFrom(r.Context()); ok {
+			username = userInfo.GetName()
+		}
+
+		headers := r.Header.Clone()
+		for _, header := range redactedHeaders {
+			if len(headers.Values(header)) > 0 {
+				headers.Set(header, "redacted")
+			}
+		}
+
+		logAtLevel(log.WithValues("requestID", requestID), level, "impersonation proxy received request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"username", username,
+			"headers", headers,
+		)
+
+		delegate.ServeHTTP(w, r)
+	})
+}
+
+func logAtLevel(log plog.Logger, level plog.LogLevel, msg string, keysAndValues ...interface{}) {
+	switch level {
+	case plog.LevelWarning:
+		log.Warning(msg, keysAndValues...)
+	case plog.LevelInfo:
+		log.Info(msg, keysAndValues...)
+	case plog.LevelTrace:
+		log.Trace(msg, keysAndValues...)
+	case plog.LevelAll:
+		log.All(msg, keysAndValues...)
+	default:
+		log.Debug(msg, keysAndValues...)
+	}
+}
+
 func tokenFrom(ctx context.Context) string {
 	token, _ := ctx.Value(tokenKey).(string)
 	return token
 }
 
+func requestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
 // contextKey type is unexported to prevent collisions.
 type contextKey int
 
-const tokenKey contextKey = iota
+const (
+	tokenKey contextKey = iota
+	requestIDKey
+)
 
-func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapiserver.Config) http.Handler, error) {
+func newImpersonationReverseProxyFunc(restConfig *rest.Config, maxResponseBodyBytes int64, requestHeaders requestHeaderPolicy) (func(*genericapiserver.Config) http.Handler, error) {
 	serverURL, err := url.Parse(restConfig.Host)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse host URL from in-cluster config: %w", err)
@@ -485,6 +814,7 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if len(r.Header.Values("Authorization")) != 0 {
 				plog.Warning("aggregated API server logic did not delete authorization header but it is always supposed to do so",
+					"requestID", requestIDFrom(r.Context()),
 					"url", r.URL.String(),
 					"method", r.Method,
 				)
@@ -495,6 +825,7 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 			if err := ensureNoImpersonationHeaders(r); err != nil {
 				plog.Error("unknown impersonation header seen",
 					err,
+					"requestID", requestIDFrom(r.Context()),
 					"url", r.URL.String(),
 					"method", r.Method,
 				)
@@ -505,6 +836,7 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 			userInfo, ok := request.UserFrom(r.Context())
 			if !ok {
 				plog.Warning("aggregated API server logic did not set user info but it is always supposed to do so",
+					"requestID", requestIDFrom(r.Context()),
 					"url", r.URL.String(),
 					"method", r.Method,
 				)
@@ -515,6 +847,7 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 			ae := audit.AuditEventFrom(r.Context())
 			if ae == nil {
 				plog.Warning("aggregated API server logic did not set audit event but it is always supposed to do so",
+					"requestID", requestIDFrom(r.Context()),
 					"url", r.URL.String(),
 					"method", r.Method,
 				)
@@ -536,6 +869,7 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 			rt, err := getTransportForUser(r.Context(), userInfo, baseRT, baseRTAnonymous, ae, token, c.Authentication.Authenticator)
 			if err != nil {
 				plog.WarningErr("rejecting request as we cannot act as the current user", err,
+					"requestID", requestIDFrom(r.Context()),
 					"url", r.URL.String(),
 					"method", r.Method,
 					"isUpgradeRequest", isUpgradeRequest,
@@ -545,11 +879,13 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 			}
 
 			plog.Debug("impersonation proxy servicing request",
+				"requestID", requestIDFrom(r.Context()),
 				"url", r.URL.String(),
 				"method", r.Method,
 				"isUpgradeRequest", isUpgradeRequest,
 			)
 			plog.Trace("impersonation proxy servicing request was for user",
+				"requestID", requestIDFrom(r.Context()),
 				"url", r.URL.String(),
 				"method", r.Method,
 				"isUpgradeRequest", isUpgradeRequest,
@@ -559,16 +895,15 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 			// The proxy library used below will panic when the client disconnects abruptly, so in order to
 			// assure that this log message is always printed at the end of this func, it must be deferred.
 			defer plog.Debug("impersonation proxy finished servicing request",
+				"requestID", requestIDFrom(r.Context()),
 				"url", r.URL.String(),
 				"method", r.Method,
 				"isUpgradeRequest", isUpgradeRequest,
 			)
 
-			// do not allow the client to cause log confusion by spoofing this header
-			if len(r.Header.Values("X-Forwarded-For")) > 0 {
-				r = utilnet.CloneRequest(r)
-				r.Header.Del("X-Forwarded-For")
-			}
+			// do not allow the client to cause log confusion by spoofing X-Forwarded-For, unless it was configured
+			// to be passed through, and remove any other headers which should not reach the Kube API server
+			r = requestHeaders.stripHeaders(r)
 
 			// the http2 code seems to call Close concurrently which can lead to data races
 			if r.Body != nil {
@@ -579,11 +914,71 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 			reverseProxy := httputil.NewSingleHostReverseProxy(serverURL)
 			reverseProxy.Transport = rt
 			reverseProxy.FlushInterval = 200 * time.Millisecond // the "watch" verb will not work without this line
+			if maxResponseBodyBytes > 0 && !isUpgradeRequest && !isStreamingRequest(r) {
+				reverseProxy.ModifyResponse = limitResponseBody(r, maxResponseBodyBytes)
+			}
 			reverseProxy.ServeHTTP(w, r)
 		})
 	}, nil
 }
 
+// isStreamingRequest returns true for requests whose responses are streamed to the client for as long as the
+// request is open, so that the size of their response bodies cannot be limited. Exec, attach, and port-forward
+// requests are usually upgrade requests, which the caller also exempts, but they are detected here too.
+func isStreamingRequest(r *http.Request) bool {
+	requestInfo, ok := request.RequestInfoFrom(r.Context())
+	if !ok || !requestInfo.IsResourceRequest {
+		return false
+	}
+	if requestInfo.Verb == "watch" {
+		return true
+	}
+	switch requestInfo.Subresource {
+	case "exec", "attach", "portforward":
+		return true
+	case "log":
+		follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+		return follow
+	default:
+		return false
+	}
+}
+
+// limitResponseBody returns a ModifyResponse function for a httputil.ReverseProxy which fails any response whose
+// body is larger than maxBytes, which causes the reverse proxy to respond with a 502 Bad Gateway. The body is read
+// into memory before it is relayed, because the status code cannot be changed once the client has started to
+// receive the body.
+func limitResponseBody(r *http.Request, maxBytes int64) func(*http.Response) error {
+	tooLarge := func() error {
+		plog.Warning("impersonation proxy response body from the Kube API server was too large",
+			"requestID", requestIDFrom(r.Context()),
+			"url", r.URL.String(),
+			"method", r.Method,
+			"maxResponseBodyBytes", maxBytes,
+		)
+		return fmt.Errorf("response body is larger than the maximum of %d bytes", maxBytes)
+	}
+
+	return func(resp *http.Response) error {
+		if resp.ContentLength > maxBytes {
+			_ = resp.Body.Close()
+			return tooLarge()
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+		_ = resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("could not read response body: %w", err)
+		}
+		if int64(len(body)) > maxBytes {
+			return tooLarge()
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return nil
+	}
+}
+
 var _ io.ReadWriteCloser = &safeReadWriteCloser{}
 
 type safeReadWriteCloser struct {
@@ -8,12 +8,14 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -54,6 +56,7 @@ import (
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/httputil/roundtripper"
 	"go.pinniped.dev/internal/kubeclient"
+	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/testutil/tlsserver"
 )
 
@@ -93,10 +96,12 @@ func TestImpersonator(t *testing.T) {
 		kubeAPIServerStatusCode            int
 		kubeAPIServerHealthz               http.Handler
 		anonymousAuthDisabled              bool
+		impersonatorConfig                 Config
 		wantKubeAPIServerRequestHeaders    http.Header
 		wantError                          string
 		wantConstructionError              string
 		wantAuthorizerAttributes           []authorizer.AttributesRecord
+		wantMetricFamilies                 []string
 	}{
 		{
 			name:                               "happy path",
@@ -118,6 +123,48 @@ func TestImpersonator(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:                               "happy path when TLS 1.3 is required",
+			clientCert:                         newClientCert(t, ca, "test-username", []string{"test-group1", "test-group2"}),
+			kubeAPIServerClientBearerTokenFile: "required-to-be-set",
+			impersonatorConfig:                 Config{MinTLSVersion: "VersionTLS13"},
+			wantKubeAPIServerRequestHeaders: http.Header{
+				"Impersonate-User":  {"test-username"},
+				"Impersonate-Group": {"test-group1", "test-group2", "system:authenticated"},
+				"Authorization":     {"Bearer some-service-account-token"},
+				"User-Agent":        {"test-agent"},
+				"Accept":            {"application/vnd.kubernetes.protobuf,application/json"},
+				"Accept-Encoding":   {"gzip"},
+				"X-Forwarded-For":   {"127.0.0.1"},
+			},
+			wantAuthorizerAttributes: []authorizer.AttributesRecord{
+				{
+					User: &user.DefaultInfo{Name: "test-username", UID: "", Groups: []string{"test-group1", "test-group2", "system:authenticated"}, Extra: nil},
+					Verb: "list", Namespace: "", APIGroup: "", APIVersion: "v1", Resource: "namespaces", Subresource: "", Name: "", ResourceRequest: true, Path: "/api/v1/namespaces",
+				},
+			},
+		},
+		{
+			name:                               "happy path with a metrics listener",
+			clientCert:                         newClientCert(t, ca, "test-username", []string{"test-group1", "test-group2"}),
+			kubeAPIServerClientBearerTokenFile: "required-to-be-set",
+			wantKubeAPIServerRequestHeaders: http.Header{
+				"Impersonate-User":  {"test-username"},
+				"Impersonate-Group": {"test-group1", "test-group2", "system:authenticated"},
+				"Authorization":     {"Bearer some-service-account-token"},
+				"User-Agent":        {"test-agent"},
+				"Accept":            {"application/vnd.kubernetes.protobuf,application/json"},
+				"Accept-Encoding":   {"gzip"},
+				"X-Forwarded-For":   {"127.0.0.1"},
+			},
+			wantAuthorizerAttributes: []authorizer.AttributesRecord{
+				{
+					User: &user.DefaultInfo{Name: "test-username", UID: "", Groups: []string{"test-group1", "test-group2", "system:authenticated"}, Extra: nil},
+					Verb: "list", Namespace: "", APIGroup: "", APIVersion: "v1", Resource: "namespaces", Subresource: "", Name: "", ResourceRequest: true, Path: "/api/v1/namespaces",
+				},
+			},
+			wantMetricFamilies: []string{"go_goroutines", "apiserver_request_filter_duration_seconds"},
+		},
 		{
 			name:                               "happy path with forbidden healthz",
 			clientCert:                         newClientCert(t, ca, "test-username", []string{"test-group1", "test-group2"}),
@@ -244,6 +291,53 @@ func TestImpersonator(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:                               "happy path strips configured headers and passes through the forwarded header",
+			clientCert:                         newClientCert(t, ca, "test-username2", []string{"test-group3", "test-group4"}),
+			kubeAPIServerClientBearerTokenFile: "required-to-be-set",
+			impersonatorConfig: Config{
+				StripRequestHeaders:       []string{"x-custom-auth"},
+				PassThroughRequestHeaders: []string{"X-Forwarded-For"},
+			},
+			clientMutateHeaders: func(header http.Header) {
+				header.Add("X-Custom-Auth", "some-secret")
+				header.Add("X-Forwarded-For", "example.com")
+				header.Add("X-Other", "kept")
+			},
+			wantKubeAPIServerRequestHeaders: http.Header{
+				"Impersonate-User":  {"test-username2"},
+				"Impersonate-Group": {"test-group3", "test-group4", "system:authenticated"},
+				"Authorization":     {"Bearer some-service-account-token"},
+				"User-Agent":        {"test-agent"},
+				"Accept":            {"application/vnd.kubernetes.protobuf,application/json"},
+				"Accept-Encoding":   {"gzip"},
+				"X-Forwarded-For":   {"example.com, 127.0.0.1"},
+				"X-Other":           {"kept"},
+			},
+			wantAuthorizerAttributes: []authorizer.AttributesRecord{
+				{
+					User: &user.DefaultInfo{Name: "test-username2", UID: "", Groups: []string{"test-group3", "test-group4", "system:authenticated"}, Extra: nil},
+					Verb: "list", Namespace: "", APIGroup: "", APIVersion: "v1", Resource: "namespaces", Subresource: "", Name: "", ResourceRequest: true, Path: "/api/v1/namespaces",
+				},
+			},
+		},
+		{
+			name:                               "reserved impersonation headers set by the client are rejected even when other headers are stripped",
+			clientCert:                         newClientCert(t, ca, "test-username2", []string{"test-group3", "test-group4"}),
+			kubeAPIServerClientBearerTokenFile: "required-to-be-set",
+			impersonatorConfig:                 Config{StripRequestHeaders: []string{"X-Custom-Auth"}},
+			clientMutateHeaders: func(header http.Header) {
+				header.Add("X-Custom-Auth", "some-secret")
+				header.Add("Impersonate-Something", "some-newfangled-impersonate-header")
+			},
+			wantError: "Internal error occurred: invalid impersonation",
+			wantAuthorizerAttributes: []authorizer.AttributesRecord{
+				{
+					User: &user.DefaultInfo{Name: "test-username2", UID: "", Groups: []string{"test-group3", "test-group4", "system:authenticated"}, Extra: nil},
+					Verb: "list", Namespace: "", APIGroup: "", APIVersion: "v1", Resource: "namespaces", Subresource: "", Name: "", ResourceRequest: true, Path: "/api/v1/namespaces",
+				},
+			},
+		},
 		{
 			name:                               "user is authenticated but the kube API request returns an error",
 			kubeAPIServerStatusCode:            http.StatusNotFound,
@@ -872,8 +966,21 @@ func TestImpersonator(t *testing.T) {
 				return kubeclient.Secure(config)
 			}
 
+			var metricsPort int
+			if len(tt.wantMetricFamilies) > 0 {
+				// Serve the metrics on a port which is not in use, without a host to use the default host.
+				metricsListener, unusedPort, err := genericoptions.CreateListener("", "127.0.0.1:0", net.ListenConfig{})
+				require.NoError(t, err)
+				require.NoError(t, metricsListener.Close())
+				metricsPort = unusedPort
+				tt.impersonatorConfig.MetricsAddress = ":" + strconv.Itoa(metricsPort)
+
+				// After shutdown, the metrics port should be available again.
+				defer requireCanBindToPort(t, metricsPort)
+			}
+
 			// Create an impersonator.  Use an invalid port number to make sure our listener override works.
-			runner, constructionErr := newInternal(-1000, certKeyContent, caContent, restConfigFunc, clientOpts, recOpts, recConfig)
+			runner, constructionErr := newInternal(":-1000", time.Minute, time.Minute, certKeyContent, caContent, tt.impersonatorConfig, restConfigFunc, clientOpts, recOpts, recConfig)
 			if len(tt.wantConstructionError) > 0 {
 				require.EqualError(t, constructionErr, tt.wantConstructionError)
 				require.Nil(t, runner)
@@ -938,6 +1045,20 @@ func TestImpersonator(t *testing.T) {
 				}, listResponse)
 			}
 
+			// The server honors the configured minimum TLS version, so a client which only supports TLS 1.2 can connect
+			// only when TLS 1.3 is not required.
+			tls12Conn, err := tls.Dial("tcp", "127.0.0.1:"+strconv.Itoa(port), &tls.Config{
+				MaxVersion: tls.VersionTLS12,
+				RootCAs:    ca.Pool(),
+			})
+			if tt.impersonatorConfig.MinTLSVersion == "VersionTLS13" {
+				require.ErrorContains(t, err, "tls: protocol version not supported")
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, uint16(tls.VersionTLS12), tls12Conn.ConnectionState().Version)
+				require.NoError(t, tls12Conn.Close())
+			}
+
 			// If we expect to see some headers, then the fake KAS should have been called.
 			require.Equal(t, len(tt.wantKubeAPIServerRequestHeaders) != 0, testKubeAPIServerWasCalled)
 			// If the impersonator proxied the request to the fake Kube API server, we should see the headers
@@ -1047,7 +1168,17 @@ func TestImpersonator(t *testing.T) {
 			require.True(t, errors.IsUnauthorized(errBadCert), errBadCert)
 			require.EqualError(t, errBadCert, "Unauthorized")
 
+			// the impersonator's own health check should always work without any credentials, and should not be proxied
+
+			healthzBody, errHealthz := rc.Get().AbsPath("/healthz/impersonation-proxy").DoRaw(ctx)
+			require.NoError(t, errHealthz)
+			require.Equal(t, `{"status":"ok"}`+"\n", string(healthzBody))
+
 			// Stop the impersonator server.
+			if len(tt.wantMetricFamilies) > 0 {
+				requireMetricFamilies(ctx, t, metricsPort, tt.wantMetricFamilies)
+			}
+
 			close(stopCh)
 			exitErr := <-errCh
 			require.NoError(t, exitErr)
@@ -1055,6 +1186,337 @@ func TestImpersonator(t *testing.T) {
 	}
 }
 
+func TestImpersonatorInvalidAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr string
+	}{
+		{
+			name:    "missing port",
+			address: "10.0.0.5",
+			wantErr: `invalid impersonation proxy address "10.0.0.5": address 10.0.0.5: missing port in address`,
+		},
+		{
+			name:    "port is not a number",
+			address: "10.0.0.5:https",
+			wantErr: `invalid impersonation proxy address "10.0.0.5:https": strconv.Atoi: parsing "https": invalid syntax`,
+		},
+		{
+			name:    "host is not an IP address",
+			address: "example.com:8444",
+			wantErr: `invalid impersonation proxy address "example.com:8444": host must be an IP address`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := New(tt.address, time.Minute, time.Minute, nil, nil)
+			require.EqualError(t, err, tt.wantErr)
+			require.Nil(t, runner)
+		})
+	}
+}
+
+func TestImpersonatorInvalidMetricsAddress(t *testing.T) {
+	tests := []struct {
+		name           string
+		metricsAddress string
+		wantErr        string
+	}{
+		{
+			name:           "missing port",
+			metricsAddress: "127.0.0.1",
+			wantErr:        `invalid impersonation proxy metrics address "127.0.0.1": address 127.0.0.1: missing port in address`,
+		},
+		{
+			name:           "port is not a number",
+			metricsAddress: ":metrics",
+			wantErr:        `invalid impersonation proxy metrics address ":metrics": strconv.Atoi: parsing "metrics": invalid syntax`,
+		},
+		{
+			name:           "host is not an IP address",
+			metricsAddress: "localhost:9090",
+			wantErr:        `invalid impersonation proxy metrics address "localhost:9090": host must be an IP address`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := NewWithConfig(Config{MetricsAddress: tt.metricsAddress})("127.0.0.1:8444", time.Minute, time.Minute, nil, nil)
+			require.EqualError(t, err, tt.wantErr)
+			require.Nil(t, runner)
+		})
+	}
+}
+
+func TestImpersonatorInvalidTLSConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{
+			name:    "unknown minimum TLS version",
+			config:  Config{MinTLSVersion: "VersionTLS14"},
+			wantErr: `invalid impersonation proxy minimum TLS version "VersionTLS14": must be VersionTLS12 or VersionTLS13`,
+		},
+		{
+			name:    "insecure minimum TLS version",
+			config:  Config{MinTLSVersion: "VersionTLS11"},
+			wantErr: `invalid impersonation proxy minimum TLS version "VersionTLS11": must be VersionTLS12 or VersionTLS13`,
+		},
+		{
+			name:    "cipher suites with TLS 1.3 required",
+			config:  Config{MinTLSVersion: "VersionTLS13", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}},
+			wantErr: `invalid impersonation proxy cipher suites: cannot be configured when the minimum TLS version is VersionTLS13`,
+		},
+		{
+			name:    "unknown cipher suite",
+			config:  Config{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_NOT_A_CIPHER_SUITE"}},
+			wantErr: `invalid impersonation proxy cipher suite "TLS_NOT_A_CIPHER_SUITE": must be one of `,
+		},
+		{
+			name:    "insecure cipher suite",
+			config:  Config{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+			wantErr: `invalid impersonation proxy cipher suite "TLS_RSA_WITH_RC4_128_SHA": must be one of `,
+		},
+		{
+			name:    "TLS 1.3 cipher suite",
+			config:  Config{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}},
+			wantErr: `invalid impersonation proxy cipher suite "TLS_AES_128_GCM_SHA256": must be one of `,
+		},
+		{
+			name:    "client CA bundle without any certificates",
+			config:  Config{ClientCABundle: []byte("not a PEM bundle")},
+			wantErr: `invalid impersonation proxy client CA bundle: no certificates found`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := NewWithConfig(tt.config)("127.0.0.1:0", time.Minute, time.Minute, nil, nil)
+			require.Error(t, err)
+			// The list of allowed cipher suites depends on the version of Go, so only compare the start of the error.
+			require.True(t, strings.HasPrefix(err.Error(), tt.wantErr), err.Error())
+			require.Nil(t, runner)
+		})
+	}
+}
+
+func TestImpersonatorInvalidRequestHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{
+			name:    "stripping an impersonation header",
+			config:  Config{StripRequestHeaders: []string{"X-Custom-Auth", "impersonate-user"}},
+			wantErr: `invalid impersonation proxy request header "impersonate-user": impersonation and authorization headers are reserved`,
+		},
+		{
+			name:    "passing through an impersonation header",
+			config:  Config{PassThroughRequestHeaders: []string{"Impersonate-Extra-foo"}},
+			wantErr: `invalid impersonation proxy request header "Impersonate-Extra-foo": impersonation and authorization headers are reserved`,
+		},
+		{
+			name:    "passing through the authorization header",
+			config:  Config{PassThroughRequestHeaders: []string{"Authorization"}},
+			wantErr: `invalid impersonation proxy request header "Authorization": impersonation and authorization headers are reserved`,
+		},
+		{
+			name:    "invalid header name",
+			config:  Config{StripRequestHeaders: []string{"X-Custom Auth"}},
+			wantErr: `invalid impersonation proxy request header "X-Custom Auth": not a valid header name`,
+		},
+		{
+			name:    "header which is both stripped and passed through",
+			config:  Config{StripRequestHeaders: []string{"X-Custom-Auth"}, PassThroughRequestHeaders: []string{"x-custom-auth"}},
+			wantErr: `invalid impersonation proxy request header "x-custom-auth": cannot be both stripped and passed through`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := NewWithConfig(tt.config)("127.0.0.1:0", time.Minute, time.Minute, nil, nil)
+			require.EqualError(t, err, tt.wantErr)
+			require.Nil(t, runner)
+		})
+	}
+}
+
+func TestImpersonatorListenConfig(t *testing.T) {
+	// newListener creates the listener for the port the same way as the serving options of the impersonator.
+	newListener := func(t *testing.T, config Config, port int) (net.Listener, error) {
+		t.Helper()
+		opts := genericoptions.NewSecureServingOptions().WithLoopback()
+		opts.BindAddress = net.ParseIP("127.0.0.1")
+		opts.BindPort = port
+		applyListenConfig(opts, config)
+		var servingInfo *genericapiserver.SecureServingInfo
+		if err := opts.SecureServingOptions.ApplyTo(&servingInfo); err != nil {
+			return nil, err
+		}
+		t.Cleanup(func() { _ = servingInfo.Listener.Close() })
+		return servingInfo.Listener, nil
+	}
+
+	unusedPort := func(t *testing.T) int {
+		t.Helper()
+		listener, port, err := genericoptions.CreateListener("", "127.0.0.1:0", net.ListenConfig{})
+		require.NoError(t, err)
+		require.NoError(t, listener.Close())
+		return port
+	}
+
+	t.Run("without port sharing a second listener cannot bind to the port", func(t *testing.T) {
+		port := unusedPort(t)
+		_, err := newListener(t, Config{}, port)
+		require.NoError(t, err)
+
+		_, err = newListener(t, Config{}, port)
+		require.ErrorContains(t, err, "address already in use")
+	})
+
+	t.Run("with port sharing a second listener can bind to the port before the first listener is closed", func(t *testing.T) {
+		if !portSharingSupported {
+			t.Skip("SO_REUSEPORT is not supported on this platform")
+		}
+		port := unusedPort(t)
+		_, err := newListener(t, Config{PermitPortSharing: true}, port)
+		require.NoError(t, err)
+
+		second, err := newListener(t, Config{PermitPortSharing: true}, port)
+		require.NoError(t, err)
+		require.Equal(t, port, second.Addr().(*net.TCPAddr).Port)
+	})
+}
+
+func TestImpersonatorRequiredClientCerts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+
+	ca, err := certauthority.New("ca", time.Hour)
+	require.NoError(t, err)
+	caKey, err := ca.PrivateKeyToPEM()
+	require.NoError(t, err)
+	caContent := dynamiccert.NewCA("ca")
+	require.NoError(t, caContent.SetCertKeyContent(ca.Bundle(), caKey))
+
+	cert, key, err := ca.IssueServerCertPEM(nil, []net.IP{net.ParseIP("127.0.0.1")}, time.Hour)
+	require.NoError(t, err)
+	certKeyContent := dynamiccert.NewServingCert("cert-key")
+	require.NoError(t, certKeyContent.SetCertKeyContent(cert, key))
+
+	clientCA, err := certauthority.New("client-ca", time.Hour)
+	require.NoError(t, err)
+	unrelatedCA, err := certauthority.New("ca", time.Hour)
+	require.NoError(t, err)
+
+	// turn off this code path because it does not handle the config we remove correctly
+	defer featuregatetesting.SetFeatureGateDuringTest(t, utilfeature.DefaultFeatureGate, features.APIPriorityAndFairness, false)()
+
+	listener, port, err := genericoptions.CreateListener("", "127.0.0.1:0", net.ListenConfig{})
+	require.NoError(t, err)
+	metricsListener, metricsPort, err := genericoptions.CreateListener("", "127.0.0.1:0", net.ListenConfig{})
+	require.NoError(t, err)
+	require.NoError(t, metricsListener.Close())
+
+	// After shutdown, both ports should be available again.
+	defer requireCanBindToPort(t, port)
+	defer requireCanBindToPort(t, metricsPort)
+
+	// The fake Kube API server only needs to answer the requests which are made while starting the impersonator.
+	testKubeAPIServer := tlsserver.TLSTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/namespaces/kube-system/configmaps":
+			http.NotFound(w, r)
+		case "/healthz":
+			_, _ = fmt.Fprint(w, "ok")
+		default:
+			require.Fail(t, "fake Kube API server got an unexpected request", "path: %s", r.URL.Path)
+		}
+	}), nil)
+	testKubeAPIServerKubeconfig := rest.Config{
+		Host:            testKubeAPIServer.URL,
+		BearerToken:     "some-service-account-token",
+		TLSClientConfig: rest.TLSClientConfig{CAData: tlsserver.TLSTestServerCA(testKubeAPIServer)},
+		BearerTokenFile: "required-to-be-set",
+	}
+	clientOpts := []kubeclient.Option{kubeclient.WithConfig(&testKubeAPIServerKubeconfig)}
+	recOpts := func(options *genericoptions.RecommendedOptions) {
+		options.Authentication.RemoteKubeConfigFileOptional = true
+		options.Authorization.RemoteKubeConfigFileOptional = true
+		options.Admission = nil
+		options.SecureServing.Listener = listener // use our listener with the dynamic port
+	}
+	restConfigFunc := func(config *rest.Config) (kubernetes.Interface, *rest.Config, error) {
+		if config == nil {
+			config = &testKubeAPIServerKubeconfig
+		}
+		return kubeclient.Secure(config)
+	}
+
+	config := Config{ClientCABundle: clientCA.Bundle(), MetricsAddress: ":" + strconv.Itoa(metricsPort)}
+	runner, err := newInternal(":-1000", time.Minute, time.Minute, certKeyContent, caContent, config, restConfigFunc, clientOpts, recOpts, nil)
+	require.NoError(t, err)
+
+	stopCh := make(chan struct{})
+	errCh := make(chan error)
+	go func() {
+		errCh <- runner(stopCh)
+	}()
+
+	getHealthz := func(t *testing.T, clientCert *clientCert) (*http.Response, error) {
+		t.Helper()
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: ca.Pool()}
+		if clientCert != nil {
+			cert, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+			require.NoError(t, err)
+			// Always present the cert, even when it is not signed by one of the CAs which the server asks for.
+			tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return &cert, nil }
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		t.Cleanup(client.CloseIdleConnections)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://127.0.0.1:"+strconv.Itoa(port)+healthzPath, nil)
+		require.NoError(t, err)
+		return client.Do(req) //nolint:bodyclose // the caller closes the body
+	}
+
+	// Wait until the impersonator is serving, using a client cert which is signed by the client CA.
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = getHealthz(t, newClientCert(t, clientCA, "test-username", []string{"test-group"}))
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"status":"ok"}`+"\n", string(body))
+
+	// Without a client cert, or with one which is not signed by the client CA, the TLS handshake is rejected.
+	_, err = getHealthz(t, nil)
+	require.ErrorContains(t, err, "remote error: tls: certificate required")
+	_, err = getHealthz(t, newClientCert(t, unrelatedCA, "test-username", []string{"test-group"}))
+	require.ErrorContains(t, err, "remote error: tls: unknown certificate authority")
+
+	// The health check is still reachable without a client cert on the metrics listener.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(metricsPort)+healthzPath, nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, `{"status":"ok"}`+"\n", string(body))
+
+	close(stopCh)
+	require.NoError(t, <-errCh)
+}
+
 func TestImpersonatorHTTPHandler(t *testing.T) {
 	const (
 		testUser                           = "test-user"
@@ -1071,6 +1533,7 @@ func TestImpersonatorHTTPHandler(t *testing.T) {
 	tests := []struct {
 		name                            string
 		restConfig                      *rest.Config
+		impersonatorConfig              Config
 		wantCreationErr                 string
 		request                         *http.Request
 		authenticator                   authenticator.Request
@@ -1411,6 +1874,33 @@ func TestImpersonatorHTTPHandler(t *testing.T) {
 			wantHTTPBody:   "successful proxied response",
 			wantHTTPStatus: http.StatusOK,
 		},
+		{
+			name:               "authenticated user with stripped headers",
+			impersonatorConfig: Config{StripRequestHeaders: []string{"X-Custom-Auth", "other-header"}},
+			request: newRequest(t, map[string][]string{
+				"User-Agent":      {"test-user-agent"},
+				"Accept":          {"some-accepted-format"},
+				"Accept-Encoding": {"some-accepted-encoding"},
+				"X-Custom-Auth":   {"some-secret"},
+				"Other-Header":    {"test-header-value-1"},
+				"Kept-Header":     {"test-header-value-2"},
+				"X-Forwarded-For": {"example.com"},
+			}, &user.DefaultInfo{
+				Name:   testUser,
+				Groups: testGroups,
+			}, nil, ""),
+			wantKubeAPIServerRequestHeaders: map[string][]string{
+				"Authorization":     {"Bearer some-service-account-token"},
+				"Impersonate-Group": {"test-group-1", "test-group-2"},
+				"Impersonate-User":  {"test-user"},
+				"User-Agent":        {"test-user-agent"},
+				"Accept":            {"some-accepted-format"},
+				"Accept-Encoding":   {"some-accepted-encoding"},
+				"Kept-Header":       {"test-header-value-2"},
+			},
+			wantHTTPBody:   "successful proxied response",
+			wantHTTPStatus: http.StatusOK,
+		},
 		{
 			name: "authenticated user with UID and bearer token",
 			request: newRequest(t, map[string][]string{
@@ -1861,7 +2351,11 @@ func TestImpersonatorHTTPHandler(t *testing.T) {
 				if err != nil {
 					return nil, err
 				}
-				return newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig))
+				requestHeaders, err := newRequestHeaderPolicy(tt.impersonatorConfig)
+				if err != nil {
+					return nil, err
+				}
+				return newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig), 0, requestHeaders)
 			}()
 
 			if tt.wantCreationErr != "" {
@@ -1920,6 +2414,173 @@ func TestImpersonatorHTTPHandler(t *testing.T) {
 	}
 }
 
+func TestImpersonatorHTTPHandlerMaxResponseBodyBytes(t *testing.T) {
+	const responseBody = "successful proxied response" // 27 bytes
+
+	resourceRequestInfo := func(verb, subresource string) *request.RequestInfo {
+		return &request.RequestInfo{
+			IsResourceRequest: true,
+			Verb:              verb,
+			APIVersion:        "v1",
+			Namespace:         "some-namespace",
+			Resource:          "pods",
+			Subresource:       subresource,
+			Name:              "some-pod",
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		maxResponseBodyBytes int64
+		requestInfo          *request.RequestInfo
+		query                string
+		upgrade              bool
+		chunkedResponse      bool
+		wantHTTPStatus       int
+		wantHTTPBody         string
+	}{
+		{
+			name:                 "unlimited",
+			maxResponseBodyBytes: 0,
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "response body is exactly the maximum size",
+			maxResponseBodyBytes: 27,
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "response body with a content length is larger than the maximum size",
+			maxResponseBodyBytes: 26,
+			wantHTTPStatus:       http.StatusBadGateway,
+		},
+		{
+			name:                 "chunked response body is exactly the maximum size",
+			maxResponseBodyBytes: 27,
+			chunkedResponse:      true,
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "chunked response body is larger than the maximum size",
+			maxResponseBodyBytes: 26,
+			chunkedResponse:      true,
+			wantHTTPStatus:       http.StatusBadGateway,
+		},
+		{
+			name:                 "get pod is limited",
+			maxResponseBodyBytes: 1,
+			requestInfo:          resourceRequestInfo("get", ""),
+			wantHTTPStatus:       http.StatusBadGateway,
+		},
+		{
+			name:                 "watch is exempt",
+			maxResponseBodyBytes: 1,
+			requestInfo:          resourceRequestInfo("watch", ""),
+			chunkedResponse:      true,
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "exec is exempt",
+			maxResponseBodyBytes: 1,
+			requestInfo:          resourceRequestInfo("create", "exec"),
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "attach is exempt",
+			maxResponseBodyBytes: 1,
+			requestInfo:          resourceRequestInfo("create", "attach"),
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "portforward is exempt",
+			maxResponseBodyBytes: 1,
+			requestInfo:          resourceRequestInfo("create", "portforward"),
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "upgrade requests are exempt",
+			maxResponseBodyBytes: 1,
+			upgrade:              true,
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "logs with follow are exempt",
+			maxResponseBodyBytes: 1,
+			requestInfo:          resourceRequestInfo("get", "log"),
+			query:                "follow=true",
+			chunkedResponse:      true,
+			wantHTTPStatus:       http.StatusOK,
+			wantHTTPBody:         responseBody,
+		},
+		{
+			name:                 "logs without follow are limited",
+			maxResponseBodyBytes: 1,
+			requestInfo:          resourceRequestInfo("get", "log"),
+			query:                "follow=false",
+			wantHTTPStatus:       http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			testKubeAPIServer := tlsserver.TLSTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.chunkedResponse {
+					for _, part := range strings.SplitAfter(responseBody, " ") {
+						_, _ = w.Write([]byte(part))
+						w.(http.Flusher).Flush()
+					}
+					return
+				}
+				_, _ = w.Write([]byte(responseBody))
+			}), nil)
+
+			kubeClientForProxy, err := kubeclient.New(kubeclient.WithConfig(&rest.Config{
+				Host:            testKubeAPIServer.URL,
+				BearerToken:     "some-service-account-token",
+				TLSClientConfig: rest.TLSClientConfig{CAData: tlsserver.TLSTestServerCA(testKubeAPIServer)},
+			}))
+			require.NoError(t, err)
+			requestHeaders, err := newRequestHeaderPolicy(Config{})
+			require.NoError(t, err)
+			impersonatorHTTPHandlerFunc, err := newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig), tt.maxResponseBodyBytes, requestHeaders)
+			require.NoError(t, err)
+
+			// this is not a valid way to get a server config, but it is good enough for a unit test
+			scheme := runtime.NewScheme()
+			metav1.AddToGroupVersion(scheme, metav1.Unversioned)
+			serverConfig := genericapiserver.NewRecommendedConfig(serializer.NewCodecFactory(scheme))
+
+			header := http.Header{}
+			if tt.upgrade {
+				header.Add("Connection", "Upgrade")
+				header.Add("Upgrade", "spdy/3.1")
+			}
+			r := newRequest(t, header, &user.DefaultInfo{Name: "test-user"}, nil, "")
+			if tt.requestInfo != nil {
+				r = r.WithContext(request.WithRequestInfo(r.Context(), tt.requestInfo))
+			}
+			r.URL.RawQuery = tt.query
+
+			w := httptest.NewRecorder()
+			impersonatorHTTPHandlerFunc(&serverConfig.Config).ServeHTTP(w, r)
+
+			require.Equalf(t, tt.wantHTTPStatus, w.Code, "fyi, response body was %q", w.Body.String())
+			require.Equal(t, tt.wantHTTPBody, w.Body.String())
+		})
+	}
+}
+
 func newRequest(t *testing.T, h http.Header, userInfo user.Info, event *auditinternal.Event, token string) *http.Request {
 	t.Helper()
 
@@ -2013,6 +2674,23 @@ func newClientCert(t *testing.T, ca *certauthority.CA, username string, groups [
 	}
 }
 
+// requireMetricFamilies scrapes the plain HTTP metrics listener on the port of 127.0.0.1 and requires that it served
+// each of the metric families.
+func requireMetricFamilies(ctx context.Context, t *testing.T, port int, wantMetricFamilies []string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(port)+"/metrics", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, resp.Body.Close()) }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	for _, family := range wantMetricFamilies {
+		require.Contains(t, string(body), "\n# TYPE "+family+" ")
+	}
+}
+
 func requireCanBindToPort(t *testing.T, port int) {
 	t.Helper()
 	ln, _, listenErr := genericoptions.CreateListener("", "0.0.0.0:"+strconv.Itoa(port), net.ListenConfig{})
@@ -2098,6 +2776,177 @@ func Test_withBearerTokenPreservation(t *testing.T) {
 	}
 }
 
+func Test_withRequestLogging(t *testing.T) {
+	tests := []struct {
+		name         string
+		level        plog.LogLevel
+		user         user.Info
+		headers      http.Header
+		wantMessage  string
+		wantContains []string
+		wantOmits    []string
+	}{
+		{
+			name:        "logs the request and the user",
+			level:       plog.LevelDebug,
+			user:        &user.DefaultInfo{Name: "some-user", Groups: []string{"some-group"}},
+			headers:     http.Header{"Accept": {"application/json"}},
+			wantMessage: `"level":"debug","timestamp":"2099-08-08T13:57:36.123456Z","caller":`,
+			wantContains: []string{
+				`"message":"impersonation proxy received request"`,
+				`"method":"GET","path":"/api/v1/namespaces","username":"some-user"`,
+				`"Accept":["application/json"]`,
+			},
+		},
+		{
+			name:        "logs at the configured level",
+			level:       plog.LevelInfo,
+			user:        &user.DefaultInfo{Name: "some-user"},
+			wantMessage: `"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","caller":`,
+			wantContains: []string{
+				`"message":"impersonation proxy received request"`,
+			},
+		},
+		{
+			name:        "logs a request without user info",
+			level:       plog.LevelTrace,
+			wantMessage: `"level":"trace","timestamp":"2099-08-08T13:57:36.123456Z","caller":`,
+			wantContains: []string{
+				`"username":""`,
+			},
+		},
+		{
+			name:  "redacts sensitive headers",
+			level: plog.LevelDebug,
+			user:  &user.DefaultInfo{Name: "some-user"},
+			headers: http.Header{
+				"Authorization":       {"Bearer some-token"},
+				"Proxy-Authorization": {"Basic some-credentials", "Basic other-credentials"},
+				"Cookie":              {"some-cookie"},
+				"User-Agent":          {"some-agent"},
+			},
+			wantMessage: `"level":"debug","timestamp":"2099-08-08T13:57:36.123456Z","caller":`,
+			wantContains: []string{
+				`"Authorization":["redacted"]`,
+				`"Proxy-Authorization":["redacted"]`,
+				`"Cookie":["redacted"]`,
+				`"User-Agent":["some-agent"]`,
+			},
+			wantOmits: []string{"some-token", "credentials", "some-cookie"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var log strings.Builder
+			inputReq, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://example.com/api/v1/namespaces", nil)
+			require.NoError(t, err)
+			inputReq.Header = tt.headers
+			if tt.user != nil {
+				inputReq = inputReq.WithContext(request.WithUser(inputReq.Context(), tt.user))
+			}
+			inputReqCopy := inputReq.Clone(inputReq.Context())
+
+			var requestID string
+			delegate := http.HandlerFunc(func(w http.ResponseWriter, outputReq *http.Request) {
+				requestID = requestIDFrom(outputReq.Context())
+				require.Equal(t, inputReqCopy.Header, outputReq.Header) // the headers are only redacted in the logs
+			})
+
+			withRequestLogging(delegate, plog.TestLogger(t, &log), tt.level).ServeHTTP(nil, inputReq)
+			require.Equal(t, inputReqCopy, inputReq) // assert no mutation occurred
+
+			require.Len(t, requestID, 36)
+			require.Equal(t, 1, strings.Count(log.String(), "\n"))
+			require.Contains(t, log.String(), tt.wantMessage)
+			require.Contains(t, log.String(), `"requestID":"`+requestID+`"`)
+			for _, want := range tt.wantContains {
+				require.Contains(t, log.String(), want)
+			}
+			for _, omit := range tt.wantOmits {
+				require.NotContains(t, log.String(), omit)
+			}
+		})
+	}
+}
+
+func Test_withHealthz(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		wantDelegated  bool
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "GET of the health check path",
+			method:         http.MethodGet,
+			path:           "/healthz/impersonation-proxy",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"status":"ok"}` + "\n",
+		},
+		{
+			name:           "HEAD of the health check path",
+			method:         http.MethodHead,
+			path:           "/healthz/impersonation-proxy",
+			wantStatusCode: http.StatusOK,
+			wantBody:       "",
+		},
+		{
+			name:           "POST of the health check path",
+			method:         http.MethodPost,
+			path:           "/healthz/impersonation-proxy",
+			wantStatusCode: http.StatusMethodNotAllowed,
+			wantBody:       "method not allowed\n",
+		},
+		{
+			name:          "the Kube API server's healthz is passed through",
+			method:        http.MethodGet,
+			path:          "/healthz",
+			wantDelegated: true,
+		},
+		{
+			name:          "other paths are passed through",
+			method:        http.MethodGet,
+			path:          "/api/v1/namespaces",
+			wantDelegated: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			delegate := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			server := httptest.NewServer(withHealthz(delegate))
+			t.Cleanup(server.Close)
+
+			req, err := http.NewRequestWithContext(context.Background(), tt.method, server.URL+tt.path, nil)
+			require.NoError(t, err)
+			resp, err := server.Client().Do(req)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = resp.Body.Close() })
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.wantDelegated, called)
+			if tt.wantDelegated {
+				require.Equal(t, http.StatusTeapot, resp.StatusCode)
+				return
+			}
+			require.Equal(t, tt.wantStatusCode, resp.StatusCode)
+			require.Equal(t, tt.wantBody, string(body))
+			if tt.wantStatusCode == http.StatusOK {
+				require.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
 type attributeRecorder struct {
 	lock       sync.Mutex
 	attributes []authorizer.AttributesRecord
@@ -0,0 +1,82 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	metricsPath = "/metrics"
+
+	// defaultMetricsHost is used when the metrics address does not have a host, so that the metrics are not
+	// reachable from outside the pod unless they were explicitly configured to be.
+	defaultMetricsHost = "127.0.0.1"
+
+	// metricsReadHeaderTimeout bounds how long the metrics listener waits for the headers of a request.
+	metricsReadHeaderTimeout = 10 * time.Second
+
+	// metricsShutdownTimeout bounds how long the metrics listener waits for scrapes in progress when it is stopped.
+	metricsShutdownTimeout = 5 * time.Second
+)
+
+// newMetricsListener binds the plain HTTP listener for the metrics at address, which is a host:port where an empty
+// host means defaultMetricsHost. The host must be an IP address, like the host of the impersonation proxy address.
+func newMetricsListener(address string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid impersonation proxy metrics address %q: %w", address, err)
+	}
+	if _, err = strconv.Atoi(port); err != nil {
+		return nil, fmt.Errorf("invalid impersonation proxy metrics address %q: %w", address, err)
+	}
+	if host == "" {
+		host = defaultMetricsHost
+	} else if net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("invalid impersonation proxy metrics address %q: host must be an IP address", address)
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on impersonation proxy metrics address %q: %w", address, err)
+	}
+	return listener, nil
+}
+
+// newMetricsServer returns a plain HTTP server which only serves the Prometheus metrics of the process at /metrics,
+// and the impersonator's own health check, which cannot be reached on the TLS listener without a client certificate
+// when client certificates are required.
+func newMetricsServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, legacyregistry.Handler())
+	mux.Handle(healthzPath, withHealthz(http.NotFoundHandler()))
+	return &http.Server{Handler: mux, ReadHeaderTimeout: metricsReadHeaderTimeout}
+}
+
+// serveMetrics serves the metrics on listener in the background, and returns a function which stops serving them
+// and closes the listener.
+func serveMetrics(listener net.Listener) func() {
+	server := newMetricsServer()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			plog.Error("impersonation proxy metrics server failed", err, "address", listener.Addr().String())
+		}
+	}()
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			_ = server.Close()
+		}
+	}
+}
@@ -6,6 +6,7 @@ package server
 
 import (
 	"context"
+	"crypto/x509/pkix"
 	"fmt"
 	"io"
 	"os"
@@ -23,11 +24,13 @@ import (
 	"k8s.io/client-go/rest"
 
 	conciergeopenapi "go.pinniped.dev/generated/latest/client/concierge/openapi"
+	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/certauthority/dynamiccertauthority"
 	"go.pinniped.dev/internal/concierge/apiserver"
 	conciergescheme "go.pinniped.dev/internal/concierge/scheme"
 	"go.pinniped.dev/internal/config/concierge"
 	"go.pinniped.dev/internal/controller/authenticator/authncache"
+	"go.pinniped.dev/internal/controller/impersonatorconfig/proxyinfo"
 	"go.pinniped.dev/internal/controllerinit"
 	"go.pinniped.dev/internal/controllermanager"
 	"go.pinniped.dev/internal/crypto/ptls"
@@ -131,6 +134,10 @@ func (a *App) runServer(ctx context.Context) error {
 	// cert issuer used to issue certs to Pinniped clients wishing to login.
 	impersonationProxySigningCertProvider := dynamiccert.NewCA("impersonation-proxy-signing-cert")
 
+	// This cache will be filled by a controller with the endpoint and CA bundle of the impersonation
+	// proxy while it is ready, so that they can be returned by the TokenCredentialRequest API.
+	impersonationProxyInfo := proxyinfo.New()
+
 	// Get the "real" name of the login concierge API group (i.e., the API group name with the
 	// injected suffix).
 	scheme, loginGV, identityGV := conciergescheme.New(*cfg.APIGroupSuffix)
@@ -151,8 +158,35 @@ func (a *App) runServer(ctx context.Context) error {
 			ServingCertDuration:              time.Duration(*cfg.APIConfig.ServingCertificateConfig.DurationSeconds) * time.Second,
 			ServingCertRenewBefore:           time.Duration(*cfg.APIConfig.ServingCertificateConfig.RenewBeforeSeconds) * time.Second,
 			AuthenticatorCache:               authenticators,
+			ImpersonationProxyInfoCache:      impersonationProxyInfo,
 			// This port should be safe to cast because the config reader already validated it.
-			ImpersonationProxyServerPort: int(*cfg.ImpersonationProxyServerPort),
+			ImpersonationProxyServerPort:          int(*cfg.ImpersonationProxyServerPort),
+			ImpersonationProxyCADuration:          cfg.ImpersonationProxyCertificateConfig.CADuration.Duration,
+			ImpersonationProxyCASubject:           impersonationProxyCASubject(&cfg.ImpersonationProxyCertificateConfig),
+			ImpersonationProxyCAKeyAlgorithm:      certauthority.KeyAlgorithm(cfg.ImpersonationProxyCertificateConfig.KeyAlgorithm),
+			ImpersonationProxyCertificateDuration: cfg.ImpersonationProxyCertificateConfig.CertificateDuration.Duration,
+			ImpersonationProxyTLSSecretRef:        cfg.ImpersonationProxyCertificateConfig.TLSSecretRef,
+			ImpersonationProxyCABundleConfigMap:   cfg.ImpersonationProxyCertificateConfig.CABundleConfigMap,
+			ImpersonationProxyAdditionalSANs:      cfg.ImpersonationProxyCertificateConfig.AdditionalSANs,
+			// This percentage should be safe to cast because the config reader already validated it.
+			ImpersonationProxyRotationWindowPercentage:  int(*cfg.ImpersonationProxyCertificateConfig.RotationWindowPercentage),
+			ImpersonationProxyRequestTimeout:            cfg.ImpersonationProxyRequestTimeout.Duration,
+			ImpersonationProxyShutdownDrainTimeout:      cfg.ImpersonationProxyShutdownDrainTimeout.Duration,
+			ImpersonationProxyResyncInterval:            cfg.ImpersonationProxyResyncInterval.Duration,
+			ImpersonationProxyControlPlaneNodeSelectors: cfg.ImpersonationProxyControlPlaneNodeSelectors,
+			ImpersonationProxyExcludedNodeRoles:         cfg.ImpersonationProxyExcludedNodeRoles,
+			ImpersonationProxyAutoModeStopDelay:         cfg.ImpersonationProxyAutoModeStopDelay.Duration,
+			ImpersonationProxyStateChangeWebhookURL:     cfg.ImpersonationProxyStateChangeWebhookURL,
+			ImpersonationProxyRequestLogLevel:           cfg.ImpersonationProxyRequestLogLevel,
+			ImpersonationProxyMaxResponseBodyBytes:      cfg.ImpersonationProxyMaxResponseBodyBytes,
+			ImpersonationProxyMinTLSVersion:             cfg.ImpersonationProxyMinTLSVersion,
+			ImpersonationProxyCipherSuites:              cfg.ImpersonationProxyCipherSuites,
+			ImpersonationProxyPermitPortSharing:         cfg.ImpersonationProxyPermitPortSharing,
+			ImpersonationProxyMetricsAddress:            cfg.ImpersonationProxyMetricsAddress,
+			ImpersonationProxyStripRequestHeaders:       cfg.ImpersonationProxyStripRequestHeaders,
+			ImpersonationProxyPassThroughRequestHeaders: cfg.ImpersonationProxyPassThroughRequestHeaders,
+			ImpersonationProxyServiceSelector:           cfg.ImpersonationProxyServiceSelector,
+			ImpersonationProxyExtraLabels:               cfg.ImpersonationProxyExtraLabels,
 		},
 	)
 	if err != nil {
@@ -169,6 +203,7 @@ func (a *App) runServer(ctx context.Context) error {
 		dynamicServingCertProvider,
 		authenticators,
 		certIssuer,
+		impersonationProxyInfo,
 		buildControllers,
 		*cfg.APIGroupSuffix,
 		*cfg.AggregatedAPIServerPort,
@@ -190,11 +225,21 @@ func (a *App) runServer(ctx context.Context) error {
 	return server.GenericAPIServer.PrepareRun().Run(ctx.Done())
 }
 
+// impersonationProxyCASubject returns the subject of the impersonation proxy's CA certificate from the static config.
+func impersonationProxyCASubject(certConfig *concierge.ImpersonationProxyCertificateConfigSpec) pkix.Name {
+	subject := pkix.Name{CommonName: certConfig.CACommonName}
+	if certConfig.CAOrganization != "" {
+		subject.Organization = []string{certConfig.CAOrganization}
+	}
+	return subject
+}
+
 // Create a configuration for the aggregated API server.
 func getAggregatedAPIServerConfig(
 	dynamicCertProvider dynamiccert.Private,
 	authenticator credentialrequest.TokenCredentialRequestAuthenticator,
 	issuer issuer.ClientCertIssuer,
+	impersonationProxyInfo credentialrequest.ImpersonationProxyInfoGetter,
 	buildControllers controllerinit.RunnerBuilder,
 	apiGroupSuffix string,
 	aggregatedAPIServerPort int64,
@@ -246,6 +291,7 @@ func getAggregatedAPIServerConfig(
 		ExtraConfig: apiserver.ExtraConfig{
 			Authenticator:                 authenticator,
 			Issuer:                        issuer,
+			ImpersonationProxyInfo:        impersonationProxyInfo,
 			BuildControllersPostStartHook: buildControllers,
 			Scheme:                        scheme,
 			NegotiatedSerializer:          codecs,
@@ -8,12 +8,22 @@ package concierge
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/yaml"
 
+	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/clusterhost"
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/groupsuffix"
 	"go.pinniped.dev/internal/plog"
@@ -33,6 +43,25 @@ const (
 	// impersonation proxy, and has been the value since. It was originally selected because the
 	// aggregated API server used to run on 8443 (has since changed), so 8444 was the next available port.
 	impersonationProxyPortDefault = 8444
+
+	impersonationProxyCADurationDefault          = 365 * 24 * time.Hour
+	impersonationProxyCertificateDurationDefault = 90 * 24 * time.Hour
+	impersonationProxyRotationWindowDefault      = 25
+
+	// Use the same default as the Kube API server's --request-timeout flag, since the impersonation proxy
+	// is just a proxy to the Kube API server.
+	impersonationProxyRequestTimeoutDefault = 60 * time.Second
+
+	// By default, allow requests which are in flight when the impersonation proxy is stopped as long to finish as
+	// a request is normally allowed to take.
+	impersonationProxyShutdownDrainTimeoutDefault = 60 * time.Second
+
+	// Frequent enough to notice changes which do not cause informer events within a few minutes, but not so frequent
+	// that the Concierge makes many needless requests to the Kube API server.
+	impersonationProxyResyncIntervalDefault = 5 * time.Minute
+
+	// Long enough for control plane nodes to be seen by a few resyncs before auto mode stops the impersonation proxy.
+	impersonationProxyAutoModeStopDelayDefault = 10 * time.Minute
 )
 
 // FromPath loads an Config from a provided local file path, inserts any
@@ -56,6 +85,12 @@ func FromPath(ctx context.Context, path string) (*Config, error) {
 	maybeSetAPIDefaults(&config.APIConfig)
 	maybeSetAggregatedAPIServerPortDefaults(&config.AggregatedAPIServerPort)
 	maybeSetImpersonationProxyServerPortDefaults(&config.ImpersonationProxyServerPort)
+	maybeSetImpersonationProxyCertificateDefaults(&config.ImpersonationProxyCertificateConfig)
+	maybeSetImpersonationProxyRequestTimeoutDefault(&config.ImpersonationProxyRequestTimeout)
+	maybeSetImpersonationProxyShutdownDrainTimeoutDefault(&config.ImpersonationProxyShutdownDrainTimeout)
+	maybeSetImpersonationProxyResyncIntervalDefault(&config.ImpersonationProxyResyncInterval)
+	maybeSetImpersonationProxyAutoModeStopDelayDefault(&config.ImpersonationProxyAutoModeStopDelay)
+	maybeSetImpersonationProxyRequestLogLevelDefault(&config.ImpersonationProxyRequestLogLevel)
 	maybeSetAPIGroupSuffixDefault(&config.APIGroupSuffix)
 	maybeSetKubeCertAgentDefaults(&config.KubeCertAgentConfig)
 
@@ -75,6 +110,50 @@ func FromPath(ctx context.Context, path string) (*Config, error) {
 		return nil, fmt.Errorf("validate impersonationProxyServerPort: %w", err)
 	}
 
+	if err := validateImpersonationProxyCertificate(&config.ImpersonationProxyCertificateConfig); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyCertificate: %w", err)
+	}
+
+	if err := validateImpersonationProxyRequestTimeout(config.ImpersonationProxyRequestTimeout); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyRequestTimeout: %w", err)
+	}
+
+	if err := validateImpersonationProxyShutdownDrainTimeout(config.ImpersonationProxyShutdownDrainTimeout); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyShutdownDrainTimeout: %w", err)
+	}
+
+	if err := validateImpersonationProxyResyncInterval(config.ImpersonationProxyResyncInterval); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyResyncInterval: %w", err)
+	}
+
+	if err := validateImpersonationProxyAutoModeStopDelay(config.ImpersonationProxyAutoModeStopDelay); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyAutoModeStopDelay: %w", err)
+	}
+
+	if _, err := clusterhost.ParseControlPlaneNodeSelectors(config.ImpersonationProxyControlPlaneNodeSelectors); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyControlPlaneNodeSelectors: %w", err)
+	}
+
+	if err := clusterhost.ValidateExcludedNodeRoles(config.ImpersonationProxyExcludedNodeRoles); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyExcludedNodeRoles: %w", err)
+	}
+
+	if err := validateImpersonationProxyRequestLogLevel(config.ImpersonationProxyRequestLogLevel); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyRequestLogLevel: %w", err)
+	}
+
+	if err := validateImpersonationProxyMaxResponseBodyBytes(config.ImpersonationProxyMaxResponseBodyBytes); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyMaxResponseBodyBytes: %w", err)
+	}
+
+	if err := validateImpersonationProxyExtraLabels(config.ImpersonationProxyExtraLabels, config.Labels); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyExtraLabels: %w", err)
+	}
+
+	if err := validateImpersonationProxyStateChangeWebhookURL(config.ImpersonationProxyStateChangeWebhookURL); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyStateChangeWebhookURL: %w", err)
+	}
+
 	if err := validateNames(&config.NamesConfig); err != nil {
 		return nil, fmt.Errorf("validate names: %w", err)
 	}
@@ -119,6 +198,50 @@ func maybeSetImpersonationProxyServerPortDefaults(port **int64) {
 	}
 }
 
+func maybeSetImpersonationProxyCertificateDefaults(certConfig *ImpersonationProxyCertificateConfigSpec) {
+	if certConfig.CADuration == nil {
+		certConfig.CADuration = &metav1.Duration{Duration: impersonationProxyCADurationDefault}
+	}
+
+	if certConfig.CertificateDuration == nil {
+		certConfig.CertificateDuration = &metav1.Duration{Duration: impersonationProxyCertificateDurationDefault}
+	}
+
+	if certConfig.RotationWindowPercentage == nil {
+		certConfig.RotationWindowPercentage = pointer.Int64(impersonationProxyRotationWindowDefault)
+	}
+}
+
+func maybeSetImpersonationProxyRequestTimeoutDefault(requestTimeout **metav1.Duration) {
+	if *requestTimeout == nil {
+		*requestTimeout = &metav1.Duration{Duration: impersonationProxyRequestTimeoutDefault}
+	}
+}
+
+func maybeSetImpersonationProxyShutdownDrainTimeoutDefault(drainTimeout **metav1.Duration) {
+	if *drainTimeout == nil {
+		*drainTimeout = &metav1.Duration{Duration: impersonationProxyShutdownDrainTimeoutDefault}
+	}
+}
+
+func maybeSetImpersonationProxyResyncIntervalDefault(resyncInterval **metav1.Duration) {
+	if *resyncInterval == nil {
+		*resyncInterval = &metav1.Duration{Duration: impersonationProxyResyncIntervalDefault}
+	}
+}
+
+func maybeSetImpersonationProxyAutoModeStopDelayDefault(stopDelay **metav1.Duration) {
+	if *stopDelay == nil {
+		*stopDelay = &metav1.Duration{Duration: impersonationProxyAutoModeStopDelayDefault}
+	}
+}
+
+func maybeSetImpersonationProxyRequestLogLevelDefault(level *plog.LogLevel) {
+	if *level == "" {
+		*level = plog.LevelDebug
+	}
+}
+
 func maybeSetKubeCertAgentDefaults(cfg *KubeCertAgentSpec) {
 	if cfg.NamePrefix == nil {
 		cfg.NamePrefix = pointer.String("pinniped-kube-cert-agent-")
@@ -127,6 +250,19 @@ func maybeSetKubeCertAgentDefaults(cfg *KubeCertAgentSpec) {
 	if cfg.Image == nil {
 		cfg.Image = pointer.String("debian:latest")
 	}
+
+	if cfg.Resources == nil {
+		cfg.Resources = &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("32Mi"),
+				corev1.ResourceCPU:    resource.MustParse("20m"),
+			},
+			Requests: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("32Mi"),
+				corev1.ResourceCPU:    resource.MustParse("20m"),
+			},
+		}
+	}
 }
 
 func validateNames(names *NamesConfigSpec) error {
@@ -149,6 +285,9 @@ func validateNames(names *NamesConfigSpec) error {
 	if names.ImpersonationClusterIPService == "" {
 		missingNames = append(missingNames, "impersonationClusterIPService")
 	}
+	if names.ImpersonationNodePortService == "" {
+		missingNames = append(missingNames, "impersonationNodePortService")
+	}
 	if names.ImpersonationTLSCertificateSecret == "" {
 		missingNames = append(missingNames, "impersonationTLSCertificateSecret")
 	}
@@ -179,6 +318,143 @@ func validateAPI(apiConfig *APIConfigSpec) error {
 	return nil
 }
 
+func validateImpersonationProxyCertificate(certConfig *ImpersonationProxyCertificateConfigSpec) error {
+	if certConfig.CADuration.Duration <= 0 {
+		return constable.Error("caDuration must be positive")
+	}
+
+	if certConfig.CertificateDuration.Duration <= 0 {
+		return constable.Error("certificateDuration must be positive")
+	}
+
+	if certConfig.CertificateDuration.Duration > certConfig.CADuration.Duration {
+		return constable.Error("certificateDuration cannot be larger than caDuration")
+	}
+
+	if *certConfig.RotationWindowPercentage < 1 || *certConfig.RotationWindowPercentage > 99 {
+		return constable.Error("rotationWindowPercentage must be between 1 and 99")
+	}
+
+	// These are the upper bounds for these attributes from RFC 5280.
+	if len(certConfig.CACommonName) > 64 {
+		return constable.Error("caCommonName must be at most 64 characters")
+	}
+
+	if len(certConfig.CAOrganization) > 64 {
+		return constable.Error("caOrganization must be at most 64 characters")
+	}
+
+	if err := certauthority.KeyAlgorithm(certConfig.KeyAlgorithm).Validate(); err != nil {
+		supported := make([]string, 0, len(certauthority.KeyAlgorithms()))
+		for _, algorithm := range certauthority.KeyAlgorithms() {
+			supported = append(supported, string(algorithm))
+		}
+		return fmt.Errorf("keyAlgorithm must be one of %s", strings.Join(supported, ", "))
+	}
+
+	if certConfig.TLSSecretRef != "" {
+		if errs := validation.IsDNS1123Subdomain(certConfig.TLSSecretRef); len(errs) > 0 {
+			return fmt.Errorf("tlsSecretRef must be a valid Secret name: %s", strings.Join(errs, ", "))
+		}
+	}
+
+	if certConfig.CABundleConfigMap != "" {
+		if errs := validation.IsDNS1123Subdomain(certConfig.CABundleConfigMap); len(errs) > 0 {
+			return fmt.Errorf("caBundleConfigMap must be a valid ConfigMap name: %s", strings.Join(errs, ", "))
+		}
+	}
+
+	for _, san := range certConfig.AdditionalSANs {
+		if net.ParseIP(san) != nil {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(san); len(errs) > 0 {
+			return fmt.Errorf("additionalSANs entry %q must be an IP address or a DNS name: %s", san, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
+
+func validateImpersonationProxyRequestTimeout(requestTimeout *metav1.Duration) error {
+	if requestTimeout.Duration <= 0 {
+		return constable.Error("must be positive")
+	}
+	return nil
+}
+
+func validateImpersonationProxyShutdownDrainTimeout(drainTimeout *metav1.Duration) error {
+	if drainTimeout.Duration <= 0 {
+		return constable.Error("must be positive")
+	}
+	return nil
+}
+
+func validateImpersonationProxyResyncInterval(resyncInterval *metav1.Duration) error {
+	if resyncInterval.Duration <= 0 {
+		return constable.Error("must be positive")
+	}
+	return nil
+}
+
+func validateImpersonationProxyAutoModeStopDelay(stopDelay *metav1.Duration) error {
+	if stopDelay.Duration < 0 {
+		return constable.Error("must not be negative")
+	}
+	return nil
+}
+
+func validateImpersonationProxyRequestLogLevel(level plog.LogLevel) error {
+	switch level {
+	case plog.LevelInfo, plog.LevelDebug, plog.LevelTrace, plog.LevelAll:
+		return nil
+	default:
+		return constable.Error("valid choices are info, debug, trace and all")
+	}
+}
+
+func validateImpersonationProxyMaxResponseBodyBytes(maxBytes int64) error {
+	if maxBytes < 0 {
+		return constable.Error("must not be negative")
+	}
+	return nil
+}
+
+func validateImpersonationProxyStateChangeWebhookURL(webhookURL string) error {
+	if webhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return constable.Error("must be an absolute URL with an http or https scheme")
+	}
+	return nil
+}
+
+func validateImpersonationProxyExtraLabels(extraLabels, labels map[string]string) error {
+	keys := make([]string, 0, len(extraLabels))
+	for k := range extraLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // report the errors in a predictable order
+
+	for _, k := range keys {
+		if _, ok := labels[k]; ok {
+			return fmt.Errorf("label %q is already set by labels", k)
+		}
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", k, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(extraLabels[k]); len(errs) > 0 {
+			return fmt.Errorf("invalid value of label %q: %s", k, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
 func validateAPIGroupSuffix(apiGroupSuffix string) error {
 	return groupsuffix.Validate(apiGroupSuffix)
 }
@@ -6,9 +6,14 @@ package concierge
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 
 	"go.pinniped.dev/internal/here"
@@ -16,6 +21,17 @@ import (
 )
 
 func TestFromPath(t *testing.T) {
+	defaultKubeCertAgentResources := &corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+			corev1.ResourceCPU:    resource.MustParse("20m"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+			corev1.ResourceCPU:    resource.MustParse("20m"),
+		},
+	}
+
 	tests := []struct {
 		name       string
 		yaml       string
@@ -35,6 +51,46 @@ func TestFromPath(t *testing.T) {
 				apiGroupSuffix: some.suffix.com
 				aggregatedAPIServerPort: 12345
 				impersonationProxyServerPort: 4242
+				impersonationProxyCertificate:
+				  caDuration: 48h
+				  certificateDuration: 12h30m
+				  rotationWindowPercentage: 33
+				  caCommonName: my-cluster Impersonation Proxy CA
+				  caOrganization: Example Org
+				  keyAlgorithm: RSA-3072
+				  tlsSecretRef: my-cert-manager-secret
+				  caBundleConfigMap: my-impersonation-proxy-ca-bundle
+				  additionalSANs:
+				  - impersonation-proxy.internal.example.com
+				  - 10.0.0.42
+				  - fd00::42
+				impersonationProxyRequestTimeout: 2m
+				impersonationProxyShutdownDrainTimeout: 90s
+				impersonationProxyResyncInterval: 10m
+				impersonationProxyAutoModeStopDelay: 2m
+				impersonationProxyControlPlaneNodeSelectors:
+				- example.com/role=control
+				- node-role.kubernetes.io/master
+				impersonationProxyExcludedNodeRoles:
+				- edge
+				impersonationProxyRequestLogLevel: info
+				impersonationProxyMaxResponseBodyBytes: 10485760
+				impersonationProxyMinTLSVersion: VersionTLS12
+				impersonationProxyCipherSuites:
+				- TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384
+				impersonationProxyPermitPortSharing: true
+				impersonationProxyMetricsAddress: 127.0.0.1:9090
+				impersonationProxyStripRequestHeaders:
+				- X-Custom-Auth
+				impersonationProxyPassThroughRequestHeaders:
+				- X-Forwarded-For
+				impersonationProxyServiceSelector:
+				  app: custom-app
+				  component: concierge
+				impersonationProxyExtraLabels:
+				  example.com/team: identity
+				  cost-center: "1234"
+				impersonationProxyStateChangeWebhookURL: https://automation.example.com/impersonation-proxy
 				names:
 				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
 				  credentialIssuer: pinniped-config
@@ -42,6 +98,7 @@ func TestFromPath(t *testing.T) {
 				  kubeCertAgentPrefix: kube-cert-agent-prefix
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -55,6 +112,20 @@ func TestFromPath(t *testing.T) {
 				  namePrefix: kube-cert-agent-name-prefix-
 				  image: kube-cert-agent-image
 				  imagePullSecrets: [kube-cert-agent-image-pull-secret]
+				  resources:
+				    requests: {cpu: 10m, memory: 16Mi}
+				    limits: {cpu: 100m, memory: 64Mi}
+				  tolerations:
+				  - {key: example.com/control-plane, operator: Exists, effect: NoSchedule}
+				  priorityClassName: system-cluster-critical
+				  nodeSelector:
+				    example.com/signing-key: "true"
+				  affinity:
+				    nodeAffinity:
+				      requiredDuringSchedulingIgnoredDuringExecution:
+				        nodeSelectorTerms:
+				        - matchExpressions:
+				          - {key: example.com/role, operator: In, values: [signer]}
 				logLevel: debug
 			`),
 			wantConfig: &Config{
@@ -70,12 +141,44 @@ func TestFromPath(t *testing.T) {
 				APIGroupSuffix:               pointer.String("some.suffix.com"),
 				AggregatedAPIServerPort:      pointer.Int64(12345),
 				ImpersonationProxyServerPort: pointer.Int64(4242),
+				ImpersonationProxyCertificateConfig: ImpersonationProxyCertificateConfigSpec{
+					CADuration:               &metav1.Duration{Duration: 48 * time.Hour},
+					CertificateDuration:      &metav1.Duration{Duration: 12*time.Hour + 30*time.Minute},
+					RotationWindowPercentage: pointer.Int64(33),
+					CACommonName:             "my-cluster Impersonation Proxy CA",
+					CAOrganization:           "Example Org",
+					KeyAlgorithm:             "RSA-3072",
+					TLSSecretRef:             "my-cert-manager-secret",
+					CABundleConfigMap:        "my-impersonation-proxy-ca-bundle",
+					AdditionalSANs:           []string{"impersonation-proxy.internal.example.com", "10.0.0.42", "fd00::42"},
+				},
+				ImpersonationProxyRequestTimeout:       &metav1.Duration{Duration: 2 * time.Minute},
+				ImpersonationProxyShutdownDrainTimeout: &metav1.Duration{Duration: 90 * time.Second},
+				ImpersonationProxyResyncInterval:       &metav1.Duration{Duration: 10 * time.Minute},
+				ImpersonationProxyAutoModeStopDelay:    &metav1.Duration{Duration: 2 * time.Minute},
+				ImpersonationProxyControlPlaneNodeSelectors: []string{
+					"example.com/role=control",
+					"node-role.kubernetes.io/master",
+				},
+				ImpersonationProxyExcludedNodeRoles:         []string{"edge"},
+				ImpersonationProxyRequestLogLevel:           plog.LevelInfo,
+				ImpersonationProxyMaxResponseBodyBytes:      10 * 1024 * 1024,
+				ImpersonationProxyMinTLSVersion:             "VersionTLS12",
+				ImpersonationProxyCipherSuites:              []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+				ImpersonationProxyPermitPortSharing:         true,
+				ImpersonationProxyMetricsAddress:            "127.0.0.1:9090",
+				ImpersonationProxyStripRequestHeaders:       []string{"X-Custom-Auth"},
+				ImpersonationProxyPassThroughRequestHeaders: []string{"X-Forwarded-For"},
+				ImpersonationProxyServiceSelector:           map[string]string{"app": "custom-app", "component": "concierge"},
+				ImpersonationProxyExtraLabels:               map[string]string{"example.com/team": "identity", "cost-center": "1234"},
+				ImpersonationProxyStateChangeWebhookURL:     "https://automation.example.com/impersonation-proxy",
 				NamesConfig: NamesConfigSpec{
 					ServingCertificateSecret:          "pinniped-concierge-api-tls-serving-certificate",
 					CredentialIssuer:                  "pinniped-config",
 					APIService:                        "pinniped-api",
 					ImpersonationLoadBalancerService:  "impersonationLoadBalancerService-value",
 					ImpersonationClusterIPService:     "impersonationClusterIPService-value",
+					ImpersonationNodePortService:      "impersonationNodePortService-value",
 					ImpersonationTLSCertificateSecret: "impersonationTLSCertificateSecret-value",
 					ImpersonationCACertificateSecret:  "impersonationCACertificateSecret-value",
 					ImpersonationSignerSecret:         "impersonationSignerSecret-value",
@@ -89,6 +192,36 @@ func TestFromPath(t *testing.T) {
 					NamePrefix:       pointer.String("kube-cert-agent-name-prefix-"),
 					Image:            pointer.String("kube-cert-agent-image"),
 					ImagePullSecrets: []string{"kube-cert-agent-image-pull-secret"},
+					Resources: &corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("10m"),
+							corev1.ResourceMemory: resource.MustParse("16Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("100m"),
+							corev1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+					},
+					Tolerations: []corev1.Toleration{{
+						Key:      "example.com/control-plane",
+						Operator: corev1.TolerationOpExists,
+						Effect:   corev1.TaintEffectNoSchedule,
+					}},
+					PriorityClassName: "system-cluster-critical",
+					NodeSelector:      map[string]string{"example.com/signing-key": "true"},
+					Affinity: &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+								NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+									MatchExpressions: []corev1.NodeSelectorRequirement{{
+										Key:      "example.com/role",
+										Operator: corev1.NodeSelectorOpIn,
+										Values:   []string{"signer"},
+									}},
+								}},
+							},
+						},
+					},
 				},
 				LogLevel: func(level plog.LogLevel) *plog.LogLevel { return &level }(plog.LevelDebug),
 				Log: plog.LogSpec{
@@ -116,6 +249,7 @@ func TestFromPath(t *testing.T) {
 				  kubeCertAgentPrefix: kube-cert-agent-prefix
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -146,12 +280,23 @@ func TestFromPath(t *testing.T) {
 				APIGroupSuffix:               pointer.String("some.suffix.com"),
 				AggregatedAPIServerPort:      pointer.Int64(12345),
 				ImpersonationProxyServerPort: pointer.Int64(4242),
+				ImpersonationProxyCertificateConfig: ImpersonationProxyCertificateConfigSpec{
+					CADuration:               &metav1.Duration{Duration: 365 * 24 * time.Hour},
+					CertificateDuration:      &metav1.Duration{Duration: 90 * 24 * time.Hour},
+					RotationWindowPercentage: pointer.Int64(25),
+				},
+				ImpersonationProxyRequestTimeout:       &metav1.Duration{Duration: 60 * time.Second},
+				ImpersonationProxyShutdownDrainTimeout: &metav1.Duration{Duration: 60 * time.Second},
+				ImpersonationProxyResyncInterval:       &metav1.Duration{Duration: 5 * time.Minute},
+				ImpersonationProxyAutoModeStopDelay:    &metav1.Duration{Duration: 10 * time.Minute},
+				ImpersonationProxyRequestLogLevel:      plog.LevelDebug,
 				NamesConfig: NamesConfigSpec{
 					ServingCertificateSecret:          "pinniped-concierge-api-tls-serving-certificate",
 					CredentialIssuer:                  "pinniped-config",
 					APIService:                        "pinniped-api",
 					ImpersonationLoadBalancerService:  "impersonationLoadBalancerService-value",
 					ImpersonationClusterIPService:     "impersonationClusterIPService-value",
+					ImpersonationNodePortService:      "impersonationNodePortService-value",
 					ImpersonationTLSCertificateSecret: "impersonationTLSCertificateSecret-value",
 					ImpersonationCACertificateSecret:  "impersonationCACertificateSecret-value",
 					ImpersonationSignerSecret:         "impersonationSignerSecret-value",
@@ -165,6 +310,7 @@ func TestFromPath(t *testing.T) {
 					NamePrefix:       pointer.String("kube-cert-agent-name-prefix-"),
 					Image:            pointer.String("kube-cert-agent-image"),
 					ImagePullSecrets: []string{"kube-cert-agent-image-pull-secret"},
+					Resources:        defaultKubeCertAgentResources,
 				},
 				Log: plog.LogSpec{
 					Level:  plog.LevelAll,
@@ -192,6 +338,7 @@ func TestFromPath(t *testing.T) {
 				  kubeCertAgentPrefix: kube-cert-agent-prefix
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -223,12 +370,23 @@ func TestFromPath(t *testing.T) {
 				APIGroupSuffix:               pointer.String("some.suffix.com"),
 				AggregatedAPIServerPort:      pointer.Int64(12345),
 				ImpersonationProxyServerPort: pointer.Int64(4242),
+				ImpersonationProxyCertificateConfig: ImpersonationProxyCertificateConfigSpec{
+					CADuration:               &metav1.Duration{Duration: 365 * 24 * time.Hour},
+					CertificateDuration:      &metav1.Duration{Duration: 90 * 24 * time.Hour},
+					RotationWindowPercentage: pointer.Int64(25),
+				},
+				ImpersonationProxyRequestTimeout:       &metav1.Duration{Duration: 60 * time.Second},
+				ImpersonationProxyShutdownDrainTimeout: &metav1.Duration{Duration: 60 * time.Second},
+				ImpersonationProxyResyncInterval:       &metav1.Duration{Duration: 5 * time.Minute},
+				ImpersonationProxyAutoModeStopDelay:    &metav1.Duration{Duration: 10 * time.Minute},
+				ImpersonationProxyRequestLogLevel:      plog.LevelDebug,
 				NamesConfig: NamesConfigSpec{
 					ServingCertificateSecret:          "pinniped-concierge-api-tls-serving-certificate",
 					CredentialIssuer:                  "pinniped-config",
 					APIService:                        "pinniped-api",
 					ImpersonationLoadBalancerService:  "impersonationLoadBalancerService-value",
 					ImpersonationClusterIPService:     "impersonationClusterIPService-value",
+					ImpersonationNodePortService:      "impersonationNodePortService-value",
 					ImpersonationTLSCertificateSecret: "impersonationTLSCertificateSecret-value",
 					ImpersonationCACertificateSecret:  "impersonationCACertificateSecret-value",
 					ImpersonationSignerSecret:         "impersonationSignerSecret-value",
@@ -242,6 +400,7 @@ func TestFromPath(t *testing.T) {
 					NamePrefix:       pointer.String("kube-cert-agent-name-prefix-"),
 					Image:            pointer.String("kube-cert-agent-image"),
 					ImagePullSecrets: []string{"kube-cert-agent-image-pull-secret"},
+					Resources:        defaultKubeCertAgentResources,
 				},
 				LogLevel: func(level plog.LogLevel) *plog.LogLevel { return &level }(plog.LevelDebug),
 				Log: plog.LogSpec{
@@ -260,6 +419,7 @@ func TestFromPath(t *testing.T) {
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -280,6 +440,7 @@ func TestFromPath(t *testing.T) {
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -292,6 +453,16 @@ func TestFromPath(t *testing.T) {
 				APIGroupSuffix:               pointer.String("pinniped.dev"),
 				AggregatedAPIServerPort:      pointer.Int64(10250),
 				ImpersonationProxyServerPort: pointer.Int64(8444),
+				ImpersonationProxyCertificateConfig: ImpersonationProxyCertificateConfigSpec{
+					CADuration:               &metav1.Duration{Duration: 365 * 24 * time.Hour},
+					CertificateDuration:      &metav1.Duration{Duration: 90 * 24 * time.Hour},
+					RotationWindowPercentage: pointer.Int64(25),
+				},
+				ImpersonationProxyRequestTimeout:       &metav1.Duration{Duration: 60 * time.Second},
+				ImpersonationProxyShutdownDrainTimeout: &metav1.Duration{Duration: 60 * time.Second},
+				ImpersonationProxyResyncInterval:       &metav1.Duration{Duration: 5 * time.Minute},
+				ImpersonationProxyAutoModeStopDelay:    &metav1.Duration{Duration: 10 * time.Minute},
+				ImpersonationProxyRequestLogLevel:      plog.LevelDebug,
 				APIConfig: APIConfigSpec{
 					ServingCertificateConfig: ServingCertificateConfigSpec{
 						DurationSeconds:    pointer.Int64(60 * 60 * 24 * 365),    // about a year
@@ -304,6 +475,7 @@ func TestFromPath(t *testing.T) {
 					APIService:                        "pinniped-api",
 					ImpersonationLoadBalancerService:  "impersonationLoadBalancerService-value",
 					ImpersonationClusterIPService:     "impersonationClusterIPService-value",
+					ImpersonationNodePortService:      "impersonationNodePortService-value",
 					ImpersonationTLSCertificateSecret: "impersonationTLSCertificateSecret-value",
 					ImpersonationCACertificateSecret:  "impersonationCACertificateSecret-value",
 					ImpersonationSignerSecret:         "impersonationSignerSecret-value",
@@ -313,6 +485,7 @@ func TestFromPath(t *testing.T) {
 				KubeCertAgentConfig: KubeCertAgentSpec{
 					NamePrefix: pointer.String("pinniped-kube-cert-agent-"),
 					Image:      pointer.String("debian:latest"),
+					Resources:  defaultKubeCertAgentResources,
 				},
 			},
 		},
@@ -321,7 +494,7 @@ func TestFromPath(t *testing.T) {
 			yaml: here.Doc(``),
 			wantError: "validate names: missing required names: servingCertificateSecret, credentialIssuer, " +
 				"apiService, impersonationLoadBalancerService, " +
-				"impersonationClusterIPService, impersonationTLSCertificateSecret, impersonationCACertificateSecret, " +
+				"impersonationClusterIPService, impersonationNodePortService, impersonationTLSCertificateSecret, impersonationCACertificateSecret, " +
 				"impersonationSignerSecret, agentServiceAccount",
 		},
 		{
@@ -333,6 +506,7 @@ func TestFromPath(t *testing.T) {
 				  credentialIssuer: pinniped-config
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -349,6 +523,7 @@ func TestFromPath(t *testing.T) {
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -365,6 +540,7 @@ func TestFromPath(t *testing.T) {
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -381,6 +557,7 @@ func TestFromPath(t *testing.T) {
 				  credentialIssuer: pinniped-config
 				  apiService: pinniped-api
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -397,6 +574,7 @@ func TestFromPath(t *testing.T) {
 				  credentialIssuer: pinniped-config
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
@@ -404,6 +582,23 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: "validate names: missing required names: impersonationClusterIPService",
 		},
+		{
+			name: "Missing impersonationNodePortService name",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+			`),
+			wantError: "validate names: missing required names: impersonationNodePortService",
+		},
 		{
 			name: "Missing impersonationTLSCertificateSecret name",
 			yaml: here.Doc(`
@@ -414,6 +609,7 @@ func TestFromPath(t *testing.T) {
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
 				  agentServiceAccount: agentServiceAccount-value
@@ -430,6 +626,7 @@ func TestFromPath(t *testing.T) {
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
 				  agentServiceAccount: agentServiceAccount-value
@@ -446,6 +643,7 @@ func TestFromPath(t *testing.T) {
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
 				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
 				  agentServiceAccount: agentServiceAccount-value
@@ -462,6 +660,7 @@ func TestFromPath(t *testing.T) {
 				  apiService: pinniped-api
 				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
 				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationNodePortService: impersonationNodePortService-value
 				  impersonationSignerSecret: impersonationSignerSecret-value
 				  agentServiceAccount: agentServiceAccount-value
 			`),
@@ -538,6 +737,253 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: "validate impersonationProxyServerPort: must be within range 1024 to 65535",
 		},
+		{
+			name: "Invalid impersonationProxyCertificate duration string",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  caDuration: one-year
+			`),
+			wantError: "decode yaml: error unmarshaling JSON: while decoding JSON: time: invalid duration \"one-year\"",
+		},
+		{
+			name: "Zero impersonationProxyCertificate caDuration",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  caDuration: 0s
+			`),
+			wantError: "validate impersonationProxyCertificate: caDuration must be positive",
+		},
+		{
+			name: "Negative impersonationProxyCertificate certificateDuration",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  certificateDuration: -1h
+			`),
+			wantError: "validate impersonationProxyCertificate: certificateDuration must be positive",
+		},
+		{
+			name: "impersonationProxyCertificate certificateDuration larger than caDuration",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  caDuration: 24h
+				  certificateDuration: 25h
+			`),
+			wantError: "validate impersonationProxyCertificate: certificateDuration cannot be larger than caDuration",
+		},
+		{
+			name: "impersonationProxyCertificate rotationWindowPercentage too small",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  rotationWindowPercentage: 0
+			`),
+			wantError: "validate impersonationProxyCertificate: rotationWindowPercentage must be between 1 and 99",
+		},
+		{
+			name: "impersonationProxyCertificate rotationWindowPercentage too large",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  rotationWindowPercentage: 100
+			`),
+			wantError: "validate impersonationProxyCertificate: rotationWindowPercentage must be between 1 and 99",
+		},
+		{
+			name: "impersonationProxyCertificate caCommonName too long",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  caCommonName: ` + strings.Repeat("a", 65) + `
+			`),
+			wantError: "validate impersonationProxyCertificate: caCommonName must be at most 64 characters",
+		},
+		{
+			name: "impersonationProxyCertificate caOrganization too long",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  caOrganization: ` + strings.Repeat("a", 65) + `
+			`),
+			wantError: "validate impersonationProxyCertificate: caOrganization must be at most 64 characters",
+		},
+		{
+			name: "impersonationProxyCertificate keyAlgorithm is not supported",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  keyAlgorithm: RSA-1024
+			`),
+			wantError: "validate impersonationProxyCertificate: keyAlgorithm must be one of ECDSA-P256, ECDSA-P384, RSA-2048, RSA-3072",
+		},
+		{
+			name: "impersonationProxyCertificate tlsSecretRef is not a valid Secret name",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  tlsSecretRef: Not_A_Secret_Name
+			`),
+			wantError: "validate impersonationProxyCertificate: tlsSecretRef must be a valid Secret name: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
+		},
+		{
+			name: "impersonationProxyCertificate caBundleConfigMap is not a valid ConfigMap name",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  caBundleConfigMap: Not_A_ConfigMap_Name
+			`),
+			wantError: "validate impersonationProxyCertificate: caBundleConfigMap must be a valid ConfigMap name: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
+		},
+		{
+			name: "impersonationProxyCertificate additionalSANs has an entry which is neither an IP address nor a DNS name",
+			yaml: here.Doc(`
+				---
+				impersonationProxyCertificate:
+				  additionalSANs:
+				  - impersonation-proxy.example.com
+				  - 10.0.0.42
+				  - not a dns name
+			`),
+			wantError: "validate impersonationProxyCertificate: additionalSANs entry \"not a dns name\" must be an IP address or a DNS name: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
+		},
+		{
+			name: "impersonationProxyExtraLabels uses a key of labels",
+			yaml: here.Doc(`
+				---
+				labels:
+				  app: pinniped-concierge
+				impersonationProxyExtraLabels:
+				  team: identity
+				  app: something-else
+			`),
+			wantError: `validate impersonationProxyExtraLabels: label "app" is already set by labels`,
+		},
+		{
+			name: "impersonationProxyExtraLabels has an invalid key",
+			yaml: here.Doc(`
+				---
+				impersonationProxyExtraLabels:
+				  not a key: identity
+			`),
+			wantError: `validate impersonationProxyExtraLabels: invalid label key "not a key": name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')`,
+		},
+		{
+			name: "impersonationProxyExtraLabels has an invalid value",
+			yaml: here.Doc(`
+				---
+				impersonationProxyExtraLabels:
+				  team: not a value
+			`),
+			wantError: `validate impersonationProxyExtraLabels: invalid value of label "team": a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')`,
+		},
+		{
+			name: "impersonationProxyStateChangeWebhookURL is not an http or https URL",
+			yaml: here.Doc(`
+				---
+				impersonationProxyStateChangeWebhookURL: ftp://automation.example.com/impersonation-proxy
+			`),
+			wantError: "validate impersonationProxyStateChangeWebhookURL: must be an absolute URL with an http or https scheme",
+		},
+		{
+			name: "impersonationProxyStateChangeWebhookURL cannot be parsed",
+			yaml: here.Doc(`
+				---
+				impersonationProxyStateChangeWebhookURL: "https://automation.example.com:port/impersonation-proxy"
+			`),
+			wantError: `validate impersonationProxyStateChangeWebhookURL: parse "https://automation.example.com:port/impersonation-proxy": invalid port ":port" after host`,
+		},
+		{
+			name: "Invalid impersonationProxyRequestTimeout duration string",
+			yaml: here.Doc(`
+				---
+				impersonationProxyRequestTimeout: one-minute
+			`),
+			wantError: "decode yaml: error unmarshaling JSON: while decoding JSON: time: invalid duration \"one-minute\"",
+		},
+		{
+			name: "Zero impersonationProxyRequestTimeout",
+			yaml: here.Doc(`
+				---
+				impersonationProxyRequestTimeout: 0s
+			`),
+			wantError: "validate impersonationProxyRequestTimeout: must be positive",
+		},
+		{
+			name: "Negative impersonationProxyShutdownDrainTimeout",
+			yaml: here.Doc(`
+				---
+				impersonationProxyShutdownDrainTimeout: -1s
+			`),
+			wantError: "validate impersonationProxyShutdownDrainTimeout: must be positive",
+		},
+		{
+			name: "Zero impersonationProxyResyncInterval",
+			yaml: here.Doc(`
+				---
+				impersonationProxyResyncInterval: 0s
+			`),
+			wantError: "validate impersonationProxyResyncInterval: must be positive",
+		},
+		{
+			name: "Negative impersonationProxyAutoModeStopDelay",
+			yaml: here.Doc(`
+				---
+				impersonationProxyAutoModeStopDelay: -1s
+			`),
+			wantError: "validate impersonationProxyAutoModeStopDelay: must not be negative",
+		},
+		{
+			name: "Invalid impersonationProxyRequestLogLevel",
+			yaml: here.Doc(`
+				---
+				impersonationProxyRequestLogLevel: loud
+			`),
+			wantError: "validate impersonationProxyRequestLogLevel: valid choices are info, debug, trace and all",
+		},
+		{
+			name: "Negative impersonationProxyMaxResponseBodyBytes",
+			yaml: here.Doc(`
+				---
+				impersonationProxyMaxResponseBodyBytes: -1
+			`),
+			wantError: "validate impersonationProxyMaxResponseBodyBytes: must not be negative",
+		},
+		{
+			name: "Invalid impersonationProxyControlPlaneNodeSelectors",
+			yaml: here.Doc(`
+				---
+				impersonationProxyControlPlaneNodeSelectors:
+				- node-role.kubernetes.io/master
+				- example.com/role in control
+			`),
+			wantError: `validate impersonationProxyControlPlaneNodeSelectors: selector 1 "example.com/role in control" is invalid: ` +
+				`unable to parse requirement: found 'control' expected: '('`,
+		},
+		{
+			name: "Empty impersonationProxyControlPlaneNodeSelectors selector",
+			yaml: here.Doc(`
+				---
+				impersonationProxyControlPlaneNodeSelectors:
+				- ""
+			`),
+			wantError: "validate impersonationProxyControlPlaneNodeSelectors: selector 0 must not be empty",
+		},
+		{
+			name: "Invalid impersonationProxyExcludedNodeRoles",
+			yaml: here.Doc(`
+				---
+				impersonationProxyExcludedNodeRoles:
+				- edge
+				- not/a/role
+			`),
+			wantError: `validate impersonationProxyExcludedNodeRoles: role 1 "not/a/role" is invalid: ` +
+				`a qualified name must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character ` +
+				`(e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]') ` +
+				`with an optional DNS subdomain prefix and '/' (e.g. 'example.com/MyName')`,
+		},
 		{
 			name: "ZeroRenewBefore",
 			yaml: here.Doc(`
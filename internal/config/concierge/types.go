@@ -3,18 +3,91 @@
 
 package concierge
 
-import "go.pinniped.dev/internal/plog"
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"go.pinniped.dev/internal/plog"
+)
 
 // Config contains knobs to setup an instance of the Pinniped Concierge.
 type Config struct {
-	DiscoveryInfo                DiscoveryInfoSpec `json:"discovery"`
-	APIConfig                    APIConfigSpec     `json:"api"`
-	APIGroupSuffix               *string           `json:"apiGroupSuffix,omitempty"`
-	AggregatedAPIServerPort      *int64            `json:"aggregatedAPIServerPort"`
-	ImpersonationProxyServerPort *int64            `json:"impersonationProxyServerPort"`
-	NamesConfig                  NamesConfigSpec   `json:"names"`
-	KubeCertAgentConfig          KubeCertAgentSpec `json:"kubeCertAgent"`
-	Labels                       map[string]string `json:"labels"`
+	DiscoveryInfo                       DiscoveryInfoSpec                       `json:"discovery"`
+	APIConfig                           APIConfigSpec                           `json:"api"`
+	APIGroupSuffix                      *string                                 `json:"apiGroupSuffix,omitempty"`
+	AggregatedAPIServerPort             *int64                                  `json:"aggregatedAPIServerPort"`
+	ImpersonationProxyServerPort        *int64                                  `json:"impersonationProxyServerPort"`
+	ImpersonationProxyCertificateConfig ImpersonationProxyCertificateConfigSpec `json:"impersonationProxyCertificate"`
+	ImpersonationProxyRequestTimeout    *metav1.Duration                        `json:"impersonationProxyRequestTimeout,omitempty"`
+	// ImpersonationProxyShutdownDrainTimeout is how long the impersonation proxy waits for in-flight requests,
+	// including long-running requests such as exec and port-forward streams, to finish when it is stopped.
+	// Any connections which are still open after this time are closed. The default is 60s.
+	ImpersonationProxyShutdownDrainTimeout *metav1.Duration `json:"impersonationProxyShutdownDrainTimeout,omitempty"`
+	// ImpersonationProxyResyncInterval is how often the impersonation proxy's configuration is reconciled even when
+	// none of the resources which it watches have changed. The default is 5m.
+	ImpersonationProxyResyncInterval *metav1.Duration `json:"impersonationProxyResyncInterval,omitempty"`
+	// ImpersonationProxyControlPlaneNodeSelectors are label selectors, in the same format as the --selector
+	// flag of kubectl, which identify control plane nodes when the impersonation proxy is in auto mode. A node
+	// is a control plane node when its labels match any of the selectors. By default, the well-known
+	// node-role.kubernetes.io/<role> and kubernetes.io/node-role=<role> labels with the roles control-plane
+	// and master are used.
+	ImpersonationProxyControlPlaneNodeSelectors []string `json:"impersonationProxyControlPlaneNodeSelectors,omitempty"`
+	// ImpersonationProxyExcludedNodeRoles are node roles, such as edge, which cause a node to be ignored when the
+	// impersonation proxy is in auto mode and is looking for control plane nodes, even when the node also matches
+	// the control plane node selectors. A node has a role when it has the node-role.kubernetes.io/<role> label or
+	// the kubernetes.io/node-role=<role> label. By default, no nodes are ignored.
+	ImpersonationProxyExcludedNodeRoles []string `json:"impersonationProxyExcludedNodeRoles,omitempty"`
+	// ImpersonationProxyAutoModeStopDelay is how long control plane nodes must stay visible before the impersonation
+	// proxy is stopped in auto mode, so that a node listing which only intermittently includes the control plane nodes
+	// does not cause it to be stopped and started again. Zero stops it as soon as they are noticed. The default is 10m.
+	ImpersonationProxyAutoModeStopDelay *metav1.Duration `json:"impersonationProxyAutoModeStopDelay,omitempty"`
+	// ImpersonationProxyRequestLogLevel is the log level at which the impersonation proxy logs each request that
+	// it receives. It must be one of info, debug, trace, or all. The default is debug.
+	ImpersonationProxyRequestLogLevel plog.LogLevel `json:"impersonationProxyRequestLogLevel,omitempty"`
+	// ImpersonationProxyMaxResponseBodyBytes is the largest response body which the impersonation proxy relays
+	// from the Kube API server for a request which does not stream its response. Larger responses fail with a 502.
+	// Streaming requests, such as watch, exec, attach, port-forward, and following logs, are exempt. The default
+	// is 0, which means unlimited.
+	ImpersonationProxyMaxResponseBodyBytes int64 `json:"impersonationProxyMaxResponseBodyBytes,omitempty"`
+	// ImpersonationProxyMinTLSVersion is the minimum TLS version which clients of the impersonation proxy must
+	// use, either VersionTLS12 or VersionTLS13. The default is VersionTLS12. Invalid values are reported as an
+	// error in the status of the CredentialIssuer.
+	ImpersonationProxyMinTLSVersion string `json:"impersonationProxyMinTLSVersion,omitempty"`
+	// ImpersonationProxyCipherSuites are the names of the secure TLS 1.2 cipher suites, as named by Go's
+	// crypto/tls package, which clients of the impersonation proxy may use. They cannot be set when the minimum
+	// TLS version is VersionTLS13. By default, the cipher suites of Pinniped's default TLS profile are used.
+	ImpersonationProxyCipherSuites []string `json:"impersonationProxyCipherSuites,omitempty"`
+	// ImpersonationProxyPermitPortSharing, when true, binds the impersonation proxy's port with SO_REUSEPORT on
+	// the platforms which support it, so that a restarted impersonation proxy can bind to the port before the
+	// previous listener has been closed. The default is false.
+	ImpersonationProxyPermitPortSharing bool `json:"impersonationProxyPermitPortSharing,omitempty"`
+	// ImpersonationProxyMetricsAddress is the host:port of an optional plain HTTP listener, separate from the TLS
+	// listener of the impersonation proxy, which serves the Prometheus metrics at /metrics while the impersonation
+	// proxy is running. An empty host means 127.0.0.1. By default, there is no such listener.
+	ImpersonationProxyMetricsAddress string `json:"impersonationProxyMetricsAddress,omitempty"`
+	// ImpersonationProxyStripRequestHeaders are the names of client request headers which the impersonation proxy
+	// removes before proxying requests to the Kube API server, e.g. custom authentication headers which are only
+	// meant for a proxy in front of the impersonation proxy. The Impersonate-* and Authorization headers are reserved
+	// and cannot be listed. By default, only X-Forwarded-For is removed.
+	ImpersonationProxyStripRequestHeaders []string `json:"impersonationProxyStripRequestHeaders,omitempty"`
+	// ImpersonationProxyPassThroughRequestHeaders are the names of client request headers which the impersonation
+	// proxy would remove by default, but which should be proxied to the Kube API server. Only X-Forwarded-For is
+	// removed by default. The reserved headers cannot be listed. By default, no such headers are passed through.
+	ImpersonationProxyPassThroughRequestHeaders []string `json:"impersonationProxyPassThroughRequestHeaders,omitempty"`
+	// ImpersonationProxyServiceSelector is the label selector of the Services which the Concierge creates for the
+	// impersonation proxy. It must select the Concierge pods. The default selects the pods by their app label.
+	ImpersonationProxyServiceSelector map[string]string `json:"impersonationProxyServiceSelector,omitempty"`
+	// ImpersonationProxyStateChangeWebhookURL is an http or https URL to which a small JSON document is POSTed
+	// whenever the impersonation proxy starts, stops, or is issued a new TLS serving certificate, e.g. to update an
+	// external DNS record. The notifications are best-effort and are not retried. By default, none are sent.
+	ImpersonationProxyStateChangeWebhookURL string `json:"impersonationProxyStateChangeWebhookURL,omitempty"`
+	// ImpersonationProxyExtraLabels are added to the Services, Secrets, and ConfigMap which the Concierge creates
+	// for the impersonation proxy, in addition to Labels. Unlike Labels, they are only set when an object is created,
+	// so later changes to them on those objects are left alone. They must not use the same keys as Labels.
+	ImpersonationProxyExtraLabels map[string]string `json:"impersonationProxyExtraLabels,omitempty"`
+	NamesConfig                   NamesConfigSpec   `json:"names"`
+	KubeCertAgentConfig           KubeCertAgentSpec `json:"kubeCertAgent"`
+	Labels                        map[string]string `json:"labels"`
 	// Deprecated: use log.level instead
 	LogLevel *plog.LogLevel `json:"logLevel"`
 	Log      plog.LogSpec   `json:"log"`
@@ -41,6 +114,7 @@ type NamesConfigSpec struct {
 	APIService                        string `json:"apiService"`
 	ImpersonationLoadBalancerService  string `json:"impersonationLoadBalancerService"`
 	ImpersonationClusterIPService     string `json:"impersonationClusterIPService"`
+	ImpersonationNodePortService      string `json:"impersonationNodePortService"`
 	ImpersonationTLSCertificateSecret string `json:"impersonationTLSCertificateSecret"`
 	ImpersonationCACertificateSecret  string `json:"impersonationCACertificateSecret"`
 	ImpersonationSignerSecret         string `json:"impersonationSignerSecret"`
@@ -65,6 +139,65 @@ type ServingCertificateConfigSpec struct {
 	RenewBeforeSeconds *int64 `json:"renewBeforeSeconds,omitempty"`
 }
 
+// ImpersonationProxyCertificateConfigSpec contains the configuration knobs for the
+// certificates which are minted for the impersonation proxy, i.e., the CA certificate
+// which is advertised to clients and the TLS serving certificate issued by that CA.
+// Both certificates are rotated once they enter their rotation window.
+type ImpersonationProxyCertificateConfigSpec struct {
+	// CADuration is the validity period of the impersonation proxy's CA certificate,
+	// as a Go duration string (e.g. "8760h"). By default, the CA certificate is
+	// issued for 8760h (1 year).
+	CADuration *metav1.Duration `json:"caDuration,omitempty"`
+
+	// CertificateDuration is the validity period of the impersonation proxy's TLS
+	// serving certificate, as a Go duration string (e.g. "2160h"). This must not
+	// be larger than CADuration. By default, the serving certificate is issued for
+	// 2160h (90 days).
+	CertificateDuration *metav1.Duration `json:"certificateDuration,omitempty"`
+
+	// RotationWindowPercentage is the final portion of each certificate's validity
+	// period, as a percentage from 1 to 99, during which the certificate is replaced
+	// by a newly issued one. By default, certificates are rotated during the last 25%
+	// of their validity period.
+	RotationWindowPercentage *int64 `json:"rotationWindowPercentage,omitempty"`
+
+	// CACommonName is the common name in the subject of the impersonation proxy's CA
+	// certificate, e.g. the name of the cluster. By default, it is
+	// "Pinniped Impersonation Proxy Serving CA". The CA certificate is replaced when
+	// the subject of the existing CA certificate does not match.
+	CACommonName string `json:"caCommonName,omitempty"`
+
+	// CAOrganization is the organization in the subject of the impersonation proxy's CA
+	// certificate. By default, the subject has no organization.
+	CAOrganization string `json:"caOrganization,omitempty"`
+
+	// KeyAlgorithm is the algorithm of the private keys of the impersonation proxy's CA certificate and of the TLS
+	// serving certificates which it issues: one of ECDSA-P256, ECDSA-P384, RSA-2048 or RSA-3072. The CA certificate
+	// is replaced when the key algorithm of the existing CA certificate does not match. By default, it is ECDSA-P256.
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+
+	// TLSSecretRef is the name of a Secret of type kubernetes.io/tls in the Concierge's namespace,
+	// e.g. one which is managed by cert-manager, whose tls.crt and tls.key are served by the
+	// impersonation proxy. When it is set, no CA or TLS serving certificate is minted and the
+	// impersonation proxy's default TLS Secret is not created or managed. The Secret's ca.crt is
+	// advertised to clients, or the certificate itself when there is no ca.crt. By default, the
+	// certificates are minted as described above.
+	TLSSecretRef string `json:"tlsSecretRef,omitempty"`
+
+	// CABundleConfigMap is the name of a ConfigMap in the Concierge's namespace in which to publish the CA bundle
+	// which is advertised to clients of the impersonation proxy, under the ca.crt key, so that other tools can read
+	// it. The ConfigMap is kept up to date as the CA bundle changes, and it is deleted while the impersonation
+	// proxy is not running. By default, the CA bundle is only advertised in the CredentialIssuer's status.
+	CABundleConfigMap string `json:"caBundleConfigMap,omitempty"`
+
+	// AdditionalSANs are extra DNS names and IP addresses which are added to the subject alternative names of the
+	// impersonation proxy's minted TLS serving certificate, in addition to the address of the load balancer or the
+	// configured external endpoint, e.g. for other DNS names of a proxy which is in front of the impersonation proxy.
+	// The serving certificate is issued again when these names change. They have no effect when TLSSecretRef is set.
+	// By default, there are no additional names.
+	AdditionalSANs []string `json:"additionalSANs,omitempty"`
+}
+
 type KubeCertAgentSpec struct {
 	// NamePrefix is the prefix of the name of the kube-cert-agent pods. For example, if this field is
 	// set to "some-prefix-", then the name of the pods will look like "some-prefix-blah". The default
@@ -79,4 +212,25 @@ type KubeCertAgentSpec struct {
 	// ImagePullSecrets is a list of names of Kubernetes Secret objects that will be used as
 	// ImagePullSecrets on the kube-cert-agent pods.
 	ImagePullSecrets []string
+
+	// Resources are the compute resource requests and limits of the kube-cert-agent container. The default
+	// for this value is a request and a limit of 20m CPU and 32Mi memory.
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Tolerations are added to the tolerations of the kube-cert-agent pods, which are otherwise copied from the
+	// kube-controller-manager pod. This can be used when the control plane nodes have taints which are not
+	// tolerated by the kube-controller-manager pod.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// PriorityClassName is the name of the PriorityClass of the kube-cert-agent pods, e.g. so that they are not
+	// preempted when their node is under pressure. When empty, the pods do not specify a PriorityClass.
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// NodeSelector and Affinity constrain which nodes the kube-cert-agent pods may run on. By default, the pods run
+	// on the same node as the kube-controller-manager pod. When either is set, they replace that default, so they
+	// must select nodes which have the cluster signing key at the same path as the kube-controller-manager pod.
+	// The pods still only tolerate the taints tolerated by the kube-controller-manager pod, so use Tolerations
+	// when the selected nodes have other taints.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	Affinity     *corev1.Affinity  `json:"affinity,omitempty"`
 }
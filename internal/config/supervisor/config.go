@@ -1,4 +1,4 @@
-// Copyright 2020-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package supervisor contains functionality to load/store Config's from/to
@@ -11,7 +11,10 @@ import (
 	"net"
 	"os"
 	"strings"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/yaml"
 
@@ -30,6 +33,12 @@ const (
 	// allow traffic from the control plane to most ports, but do allow traffic to port 10250. This allows
 	// the Concierge to work without additional configuration on these types of clusters.
 	aggregatedAPIServerPortDefault = 10250
+
+	ldapServerCertificateExpiryWarningWindowDefault = 30 * 24 * time.Hour
+
+	// Successful LDAP logins should only be remembered briefly, since changes to the user in the LDAP server,
+	// such as a changed password, are not noticed until the remembered login expires.
+	ldapAuthenticationCacheTTLMax = 5 * time.Minute
 )
 
 // FromPath loads an Config from a provided local file path, inserts any
@@ -62,6 +71,22 @@ func FromPath(ctx context.Context, path string) (*Config, error) {
 		return nil, fmt.Errorf("validate aggregatedAPIServerPort: %w", err)
 	}
 
+	maybeSetLDAPServerCertificateExpiryWarningWindowDefault(&config.LDAPServerCertificateExpiryWarningWindow)
+
+	if err := validateLDAPServerCertificateExpiryWarningWindow(config.LDAPServerCertificateExpiryWarningWindow); err != nil {
+		return nil, fmt.Errorf("validate ldapServerCertificateExpiryWarningWindow: %w", err)
+	}
+
+	maybeSetLDAPAuthenticationCacheTTLDefault(&config.LDAPAuthenticationCacheTTL)
+
+	if err := validateLDAPAuthenticationCacheTTL(config.LDAPAuthenticationCacheTTL); err != nil {
+		return nil, fmt.Errorf("validate ldapAuthenticationCacheTTL: %w", err)
+	}
+
+	if err := validateLDAPIdentityProviderNamespaces(config.LDAPIdentityProviderNamespaces); err != nil {
+		return nil, fmt.Errorf("validate ldapIdentityProviderNamespaces: %w", err)
+	}
+
 	if err := validateNames(&config.NamesConfig); err != nil {
 		return nil, fmt.Errorf("validate names: %w", err)
 	}
@@ -123,6 +148,41 @@ func maybeSetAggregatedAPIServerPortDefaults(port **int64) {
 	}
 }
 
+func maybeSetLDAPServerCertificateExpiryWarningWindowDefault(window **metav1.Duration) {
+	if *window == nil {
+		*window = &metav1.Duration{Duration: ldapServerCertificateExpiryWarningWindowDefault}
+	}
+}
+
+func validateLDAPServerCertificateExpiryWarningWindow(window *metav1.Duration) error {
+	if window.Duration < 0 {
+		return constable.Error("must not be negative")
+	}
+	return nil
+}
+
+func maybeSetLDAPAuthenticationCacheTTLDefault(ttl **metav1.Duration) {
+	if *ttl == nil {
+		*ttl = &metav1.Duration{}
+	}
+}
+
+func validateLDAPAuthenticationCacheTTL(ttl *metav1.Duration) error {
+	if ttl.Duration < 0 || ttl.Duration > ldapAuthenticationCacheTTLMax {
+		return fmt.Errorf("must be between 0s and %s", ldapAuthenticationCacheTTLMax)
+	}
+	return nil
+}
+
+func validateLDAPIdentityProviderNamespaces(namespaces []string) error {
+	for _, namespace := range namespaces {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("%q is not a valid namespace name: %s", namespace, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
 func validateNames(names *NamesConfigSpec) error {
 	missingNames := []string{}
 	if names.DefaultTLSCertificateSecret == "" {
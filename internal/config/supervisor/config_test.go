@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 
 	"go.pinniped.dev/internal/here"
@@ -43,6 +45,12 @@ func TestFromPath(t *testing.T) {
 				insecureAcceptExternalUnencryptedHttpRequests: false
 				logLevel: trace
 				aggregatedAPIServerPort: 12345
+				ldapServerCertificateExpiryWarningWindow: 240h
+				ldapAuthenticationCacheTTL: 30s
+				ldapSkipStatusUpdates: true
+				ldapIdentityProviderNamespaces:
+				- tenant-a
+				- tenant-b
 			`),
 			wantConfig: &Config{
 				APIGroupSuffix: pointer.String("some.suffix.com"),
@@ -68,7 +76,11 @@ func TestFromPath(t *testing.T) {
 				Log: plog.LogSpec{
 					Level: plog.LevelTrace,
 				},
-				AggregatedAPIServerPort: pointer.Int64(12345),
+				AggregatedAPIServerPort:                  pointer.Int64(12345),
+				LDAPServerCertificateExpiryWarningWindow: &metav1.Duration{Duration: 240 * time.Hour},
+				LDAPAuthenticationCacheTTL:               &metav1.Duration{Duration: 30 * time.Second},
+				LDAPSkipStatusUpdates:                    true,
+				LDAPIdentityProviderNamespaces:           []string{"tenant-a", "tenant-b"},
 			},
 		},
 		{
@@ -118,7 +130,9 @@ func TestFromPath(t *testing.T) {
 					Level:  plog.LevelInfo,
 					Format: plog.FormatText,
 				},
-				AggregatedAPIServerPort: pointer.Int64(12345),
+				AggregatedAPIServerPort:                  pointer.Int64(12345),
+				LDAPServerCertificateExpiryWarningWindow: &metav1.Duration{Duration: 720 * time.Hour},
+				LDAPAuthenticationCacheTTL:               &metav1.Duration{},
 			},
 		},
 		{
@@ -169,7 +183,9 @@ func TestFromPath(t *testing.T) {
 					Level:  plog.LevelTrace,
 					Format: plog.FormatText,
 				},
-				AggregatedAPIServerPort: pointer.Int64(10250),
+				AggregatedAPIServerPort:                  pointer.Int64(10250),
+				LDAPServerCertificateExpiryWarningWindow: &metav1.Duration{Duration: 720 * time.Hour},
+				LDAPAuthenticationCacheTTL:               &metav1.Duration{},
 			},
 		},
 		{
@@ -206,8 +222,10 @@ func TestFromPath(t *testing.T) {
 						Network: "disabled",
 					},
 				},
-				AllowExternalHTTP:       false,
-				AggregatedAPIServerPort: pointer.Int64(10250),
+				AllowExternalHTTP:                        false,
+				AggregatedAPIServerPort:                  pointer.Int64(10250),
+				LDAPServerCertificateExpiryWarningWindow: &metav1.Duration{Duration: 720 * time.Hour},
+				LDAPAuthenticationCacheTTL:               &metav1.Duration{},
 			},
 		},
 		{
@@ -337,8 +355,10 @@ func TestFromPath(t *testing.T) {
 						Address: ":1234",
 					},
 				},
-				AllowExternalHTTP:       true,
-				AggregatedAPIServerPort: pointer.Int64(10250),
+				AllowExternalHTTP:                        true,
+				AggregatedAPIServerPort:                  pointer.Int64(10250),
+				LDAPServerCertificateExpiryWarningWindow: &metav1.Duration{Duration: 720 * time.Hour},
+				LDAPAuthenticationCacheTTL:               &metav1.Duration{},
 			},
 		},
 		{
@@ -369,8 +389,10 @@ func TestFromPath(t *testing.T) {
 						Address: ":1234",
 					},
 				},
-				AllowExternalHTTP:       true,
-				AggregatedAPIServerPort: pointer.Int64(10250),
+				AllowExternalHTTP:                        true,
+				AggregatedAPIServerPort:                  pointer.Int64(10250),
+				LDAPServerCertificateExpiryWarningWindow: &metav1.Duration{Duration: 720 * time.Hour},
+				LDAPAuthenticationCacheTTL:               &metav1.Duration{},
 			},
 		},
 		{
@@ -443,6 +465,40 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: "validate aggregatedAPIServerPort: must be within range 1024 to 65535",
 		},
+		{
+			name: "negative ldapServerCertificateExpiryWarningWindow",
+			yaml: here.Doc(`
+				---
+				ldapServerCertificateExpiryWarningWindow: -1h
+			`),
+			wantError: "validate ldapServerCertificateExpiryWarningWindow: must not be negative",
+		},
+		{
+			name: "negative ldapAuthenticationCacheTTL",
+			yaml: here.Doc(`
+				---
+				ldapAuthenticationCacheTTL: -1s
+			`),
+			wantError: "validate ldapAuthenticationCacheTTL: must be between 0s and 5m0s",
+		},
+		{
+			name: "too large ldapAuthenticationCacheTTL",
+			yaml: here.Doc(`
+				---
+				ldapAuthenticationCacheTTL: 6m
+			`),
+			wantError: "validate ldapAuthenticationCacheTTL: must be between 0s and 5m0s",
+		},
+		{
+			name: "invalid ldapIdentityProviderNamespaces",
+			yaml: here.Doc(`
+				---
+				ldapIdentityProviderNamespaces:
+				- tenant-a
+				- Tenant_B
+			`),
+			wantError: `validate ldapIdentityProviderNamespaces: "Tenant_B" is not a valid namespace name: a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')`,
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -1,4 +1,4 @@
-// Copyright 2020-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisor
@@ -6,6 +6,8 @@ package supervisor
 import (
 	"errors"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"go.pinniped.dev/internal/plog"
 )
 
@@ -20,6 +22,25 @@ type Config struct {
 	Endpoints               *Endpoints         `json:"endpoints"`
 	AllowExternalHTTP       stringOrBoolAsBool `json:"insecureAcceptExternalUnencryptedHttpRequests"`
 	AggregatedAPIServerPort *int64             `json:"aggregatedAPIServerPort"`
+	// LDAPServerCertificateExpiryWarningWindow is how long before the certificate of an LDAP or Active Directory
+	// server expires that the LDAPServerCertificateExpiringSoon condition is added to the status of its identity
+	// provider. When zero, the condition is never added. The default is 720h (30 days).
+	LDAPServerCertificateExpiryWarningWindow *metav1.Duration `json:"ldapServerCertificateExpiryWarningWindow,omitempty"`
+	// LDAPAuthenticationCacheTTL is how long a successful login to an LDAP or Active Directory identity provider is
+	// remembered, so that another login with the same username and password during that time does not contact the
+	// server again. Failed logins are never remembered. It must be at most 5m. The default is 0, which disables
+	// the cache.
+	LDAPAuthenticationCacheTTL *metav1.Duration `json:"ldapAuthenticationCacheTTL,omitempty"`
+	// LDAPSkipStatusUpdates, when true, stops the Supervisor from writing the status of LDAPIdentityProviders, for
+	// clusters where the Supervisor is not allowed to update their status. The providers are still validated and
+	// used for logins, and the status which would have been written is only logged at the debug level.
+	LDAPSkipStatusUpdates bool `json:"ldapSkipStatusUpdates,omitempty"`
+	// LDAPIdentityProviderNamespaces are additional namespaces, besides the Supervisor's own namespace, whose
+	// LDAPIdentityProviders are watched and can be used for logins. Those providers are known to clients by names of
+	// the form "<namespace>/<name>", and their bind Secrets must be in their own namespace. The Supervisor must be
+	// allowed to read the LDAPIdentityProviders and Secrets, and to update the status of the LDAPIdentityProviders,
+	// in each of these namespaces.
+	LDAPIdentityProviderNamespaces []string `json:"ldapIdentityProviderNamespaces,omitempty"`
 }
 
 // NamesConfigSpec configures the names of some Kubernetes resources for the Supervisor.
@@ -16,9 +16,12 @@ import (
 	"github.com/go-logr/logr"
 	"gopkg.in/square/go-jose.v2"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/token/union"
 	"k8s.io/apiserver/plugin/pkg/authenticator/token/oidc"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	auth1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/authentication/v1alpha1"
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
@@ -191,21 +194,80 @@ func newJWTAuthenticator(spec *auth1alpha1.JWTAuthenticatorSpec) (*jwtAuthentica
 	if len(providerJSON.JWKSURL) == 0 {
 		return nil, fmt.Errorf("issuer %q does not have jwks_uri set", spec.Issuer)
 	}
-	oidcAuthenticator, err := oidc.New(oidc.Options{
-		IssuerURL:            spec.Issuer,
-		KeySet:               coreosoidc.NewRemoteKeySet(ctx, providerJSON.JWKSURL),
-		ClientID:             spec.Audience,
-		UsernameClaim:        usernameClaim,
-		GroupsClaim:          groupsClaim,
-		SupportedSigningAlgs: defaultSupportedSigningAlgos(),
-		Client:               client,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("could not initialize authenticator: %w", err)
+	// The keys are cached and refreshed in the background, rather than fetched on demand, so that each
+	// token verification does not need to wait on the issuer's JWKS endpoint.
+	keySet := newCachingKeySet(client, providerJSON.JWKSURL, clock.RealClock{})
+	closers := []pinnipedauthenticator.Closer{keySet}
+
+	// The Kube OIDC authenticator only trusts a single audience, so create one authenticator per
+	// acceptable audience. They share a key set so that the JWKS is only fetched once.
+	audiences := jwtAuthenticatorAudiences(spec)
+	tokenAuthenticators := make([]authenticator.Token, 0, len(audiences))
+	for _, audience := range audiences {
+		oidcAuthenticator, err := oidc.New(oidc.Options{
+			IssuerURL:            spec.Issuer,
+			KeySet:               keySet,
+			ClientID:             audience,
+			UsernameClaim:        usernameClaim,
+			UsernamePrefix:       spec.Claims.UsernamePrefix,
+			GroupsClaim:          groupsClaim,
+			SupportedSigningAlgs: defaultSupportedSigningAlgos(),
+			Client:               client,
+		})
+		if err != nil {
+			for _, c := range closers {
+				c.Close()
+			}
+			return nil, fmt.Errorf("could not initialize authenticator: %w", err)
+		}
+		tokenAuthenticators = append(tokenAuthenticators, oidcAuthenticator)
+		closers = append(closers, oidcAuthenticator)
+	}
+	keySet.start(ctx)
+
+	tokenAuthenticator := tokenAuthenticators[0]
+	if len(tokenAuthenticators) > 1 {
+		tokenAuthenticator = union.New(tokenAuthenticators...)
+	}
+	if len(spec.RequiredClaims) > 0 {
+		tokenAuthenticator = &requiredClaimsAuthenticator{
+			delegate:       tokenAuthenticator,
+			requiredClaims: spec.RequiredClaims,
+		}
 	}
 
 	return &jwtAuthenticator{
-		tokenAuthenticatorCloser: oidcAuthenticator,
-		spec:                     spec,
+		tokenAuthenticatorCloser: &closingAuthenticator{
+			Token:   tokenAuthenticator,
+			closers: closers,
+		},
+		spec: spec,
 	}, nil
 }
+
+// jwtAuthenticatorAudiences returns the distinct acceptable audiences from the provided spec, starting
+// with spec.Audience.
+func jwtAuthenticatorAudiences(spec *auth1alpha1.JWTAuthenticatorSpec) []string {
+	audiences := []string{spec.Audience}
+	seen := sets.NewString(spec.Audience)
+	for _, audience := range spec.Audiences {
+		if seen.Has(audience) {
+			continue
+		}
+		seen.Insert(audience)
+		audiences = append(audiences, audience)
+	}
+	return audiences
+}
+
+// closingAuthenticator is an authenticator.Token which closes all of its closers when it is closed.
+type closingAuthenticator struct {
+	authenticator.Token
+	closers []pinnipedauthenticator.Closer
+}
+
+func (a *closingAuthenticator) Close() {
+	for _, c := range a.closers {
+		c.Close()
+	}
+}
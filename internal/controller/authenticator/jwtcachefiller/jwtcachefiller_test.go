@@ -172,6 +172,26 @@ func TestController(t *testing.T) {
 		Audience: goodAudience,
 		TLS:      &auth1alpha1.TLSSpec{CertificateAuthorityData: "LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0tCk1JSURVVENDQWptZ0F3SUJBZ0lWQUpzNStTbVRtaTJXeUI0bGJJRXBXaUs5a1RkUE1BMEdDU3FHU0liM0RRRUIKQ3dVQU1COHhDekFKQmdOVkJBWVRBbFZUTVJBd0RnWURWUVFLREFkUWFYWnZkR0ZzTUI0WERUSXdNRFV3TkRFMgpNamMxT0ZvWERUSTBNRFV3TlRFMk1qYzFPRm93SHpFTE1Ba0dBMVVFQmhNQ1ZWTXhFREFPQmdOVkJBb01CMUJwCmRtOTBZV3d3Z2dFaU1BMEdDU3FHU0liM0RRRUJBUVVBQTRJQkR3QXdnZ0VLQW9JQkFRRERZWmZvWGR4Z2NXTEMKZEJtbHB5a0tBaG9JMlBuUWtsVFNXMno1cGcwaXJjOGFRL1E3MXZzMTRZYStmdWtFTGlvOTRZYWw4R01DdVFrbApMZ3AvUEE5N1VYelhQNDBpK25iNXcwRGpwWWd2dU9KQXJXMno2MFRnWE5NSFh3VHk4ME1SZEhpUFVWZ0VZd0JpCmtkNThzdEFVS1Y1MnBQTU1reTJjNy9BcFhJNmRXR2xjalUvaFBsNmtpRzZ5dEw2REtGYjJQRWV3MmdJM3pHZ2IKOFVVbnA1V05DZDd2WjNVY0ZHNXlsZEd3aGc3cnZ4U1ZLWi9WOEhCMGJmbjlxamlrSVcxWFM4dzdpUUNlQmdQMApYZWhKZmVITlZJaTJtZlczNlVQbWpMdnVKaGpqNDIrdFBQWndvdDkzdWtlcEgvbWpHcFJEVm9wamJyWGlpTUYrCkYxdnlPNGMxQWdNQkFBR2pnWU13Z1lBd0hRWURWUjBPQkJZRUZNTWJpSXFhdVkwajRVWWphWDl0bDJzby9LQ1IKTUI4R0ExVWRJd1FZTUJhQUZNTWJpSXFhdVkwajRVWWphWDl0bDJzby9LQ1JNQjBHQTFVZEpRUVdNQlFHQ0NzRwpBUVVGQndNQ0JnZ3JCZ0VGQlFjREFUQVBCZ05WSFJNQkFmOEVCVEFEQVFIL01BNEdBMVVkRHdFQi93UUVBd0lCCkJqQU5CZ2txaGtpRzl3MEJBUXNGQUFPQ0FRRUFYbEh4M2tIMDZwY2NDTDlEVE5qTnBCYnlVSytGd2R6T2IwWFYKcmpNaGtxdHVmdEpUUnR5T3hKZ0ZKNXhUR3pCdEtKamcrVU1pczBOV0t0VDBNWThVMU45U2c5SDl0RFpHRHBjVQpxMlVRU0Y4dXRQMVR3dnJIUzIrdzB2MUoxdHgrTEFiU0lmWmJCV0xXQ21EODUzRlVoWlFZekkvYXpFM28vd0p1CmlPUklMdUpNUk5vNlBXY3VLZmRFVkhaS1RTWnk3a25FcHNidGtsN3EwRE91eUFWdG9HVnlkb3VUR0FOdFhXK2YKczNUSTJjKzErZXg3L2RZOEJGQTFzNWFUOG5vZnU3T1RTTzdiS1kzSkRBUHZOeFQzKzVZUXJwNGR1Nmh0YUFMbAppOHNaRkhidmxpd2EzdlhxL3p1Y2JEaHEzQzBhZnAzV2ZwRGxwSlpvLy9QUUFKaTZLQT09Ci0tLS0tRU5EIENFUlRJRklDQVRFLS0tLS0K"},
 	}
+	someJWTAuthenticatorSpecWithUsernamePrefix := &auth1alpha1.JWTAuthenticatorSpec{
+		Issuer:   goodIssuer,
+		Audience: goodAudience,
+		TLS:      tlsSpecFromTLSConfig(server.TLS),
+		Claims: auth1alpha1.JWTTokenClaims{
+			UsernamePrefix: "some-prefix:",
+		},
+	}
+	someJWTAuthenticatorSpecWithAudiences := &auth1alpha1.JWTAuthenticatorSpec{
+		Issuer:    goodIssuer,
+		Audience:  goodAudience,
+		Audiences: []string{"some-other-audience", goodAudience, "yet-another-audience"},
+		TLS:       tlsSpecFromTLSConfig(server.TLS),
+	}
+	someJWTAuthenticatorSpecWithRequiredClaims := &auth1alpha1.JWTAuthenticatorSpec{
+		Issuer:         goodIssuer,
+		Audience:       goodAudience,
+		TLS:            tlsSpecFromTLSConfig(server.TLS),
+		RequiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "iss"}, {Claim: "username", Value: "pinny123"}},
+	}
 	missingTLSJWTAuthenticatorSpec := &auth1alpha1.JWTAuthenticatorSpec{
 		Issuer:   goodIssuer,
 		Audience: goodAudience,
@@ -193,6 +213,8 @@ func TestController(t *testing.T) {
 		wantCacheEntries                 int
 		wantUsernameClaim                string
 		wantGroupsClaim                  string
+		wantUsernamePrefix               string
+		wantAcceptedAudiences            []string
 		runTestsOnResultingAuthenticator bool
 	}{
 		{
@@ -255,6 +277,58 @@ func TestController(t *testing.T) {
 			wantGroupsClaim:                  someJWTAuthenticatorSpecWithGroupsClaim.Claims.Groups,
 			runTestsOnResultingAuthenticator: true,
 		},
+		{
+			name:    "valid jwt authenticator with username prefix",
+			syncKey: controllerlib.Key{Name: "test-name"},
+			jwtAuthenticators: []runtime.Object{
+				&auth1alpha1.JWTAuthenticator{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test-name",
+					},
+					Spec: *someJWTAuthenticatorSpecWithUsernamePrefix,
+				},
+			},
+			wantLogs: []string{
+				`jwtcachefiller-controller "level"=0 "msg"="added new jwt authenticator" "issuer"="` + goodIssuer + `" "jwtAuthenticator"={"name":"test-name"}`,
+			},
+			wantCacheEntries:                 1,
+			wantUsernamePrefix:               someJWTAuthenticatorSpecWithUsernamePrefix.Claims.UsernamePrefix,
+			runTestsOnResultingAuthenticator: true,
+		},
+		{
+			name:    "valid jwt authenticator with required claims",
+			syncKey: controllerlib.Key{Name: "test-name"},
+			jwtAuthenticators: []runtime.Object{
+				&auth1alpha1.JWTAuthenticator{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test-name",
+					},
+					Spec: *someJWTAuthenticatorSpecWithRequiredClaims,
+				},
+			},
+			wantLogs: []string{
+				`jwtcachefiller-controller "level"=0 "msg"="added new jwt authenticator" "issuer"="` + goodIssuer + `" "jwtAuthenticator"={"name":"test-name"}`,
+			},
+			wantCacheEntries:                 1,
+			runTestsOnResultingAuthenticator: true,
+		},
+		{
+			name:    "valid jwt authenticator with additional audiences",
+			syncKey: controllerlib.Key{Name: "test-name"},
+			jwtAuthenticators: []runtime.Object{
+				&auth1alpha1.JWTAuthenticator{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test-name",
+					},
+					Spec: *someJWTAuthenticatorSpecWithAudiences,
+				},
+			},
+			wantLogs: []string{
+				`jwtcachefiller-controller "level"=0 "msg"="added new jwt authenticator" "issuer"="` + goodIssuer + `" "jwtAuthenticator"={"name":"test-name"}`,
+			},
+			wantCacheEntries:      1,
+			wantAcceptedAudiences: []string{goodAudience, "some-other-audience", "yet-another-audience"},
+		},
 		{
 			name: "updating jwt authenticator with new fields closes previous instance",
 			cache: func(t *testing.T, cache *authncache.Cache, wantClose bool) {
@@ -399,7 +473,7 @@ func TestController(t *testing.T) {
 			require.Equal(t, tt.wantLogs, testLog.Lines())
 			require.Equal(t, tt.wantCacheEntries, len(cache.Keys()))
 
-			if !tt.runTestsOnResultingAuthenticator {
+			if !tt.runTestsOnResultingAuthenticator && tt.wantAcceptedAudiences == nil {
 				return // end of test unless we wanted to run tests on the resulting authenticator from the cache
 			}
 
@@ -415,6 +489,55 @@ func TestController(t *testing.T) {
 			// Schedule it to be closed at the end of the test.
 			t.Cleanup(cachedAuthenticator.(*jwtAuthenticator).Close)
 
+			if tt.wantAcceptedAudiences != nil {
+				authenticateWithAudiences := func(t *testing.T, audiences []string) (*authenticator.Response, bool, error) {
+					t.Helper()
+					claims := jwt.Claims{
+						Issuer:    goodIssuer,
+						Subject:   "some-subject",
+						Audience:  audiences,
+						Expiry:    jwt.NewNumericDate(time.Now().Add(time.Hour)),
+						NotBefore: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+						IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+					}
+					jwt := createJWT(t, goodECSigningKey, goodECSigningAlgo, goodECSigningKeyID, &claims, "groups", nil, "", "username", "pinny123")
+
+					// Loop for a while here to allow the underlying OIDC authenticators to initialize themselves asynchronously.
+					var (
+						rsp           *authenticator.Response
+						authenticated bool
+						err           error
+					)
+					_ = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+						rsp, authenticated, err = cachedAuthenticator.AuthenticateToken(context.Background(), jwt)
+						return !isNotInitialized(err), nil
+					})
+					return rsp, authenticated, err
+				}
+
+				wantResponse := &authenticator.Response{User: &user.DefaultInfo{Name: "pinny123"}}
+				for _, audience := range tt.wantAcceptedAudiences {
+					rsp, authenticated, err := authenticateWithAudiences(t, []string{audience})
+					require.NoError(t, err, "audience %q", audience)
+					require.True(t, authenticated, "audience %q", audience)
+					require.Equal(t, wantResponse, rsp)
+				}
+
+				rsp, authenticated, err := authenticateWithAudiences(t, []string{"wrong-audience", tt.wantAcceptedAudiences[len(tt.wantAcceptedAudiences)-1]})
+				require.NoError(t, err)
+				require.True(t, authenticated)
+				require.Equal(t, wantResponse, rsp)
+
+				rsp, authenticated, err = authenticateWithAudiences(t, []string{"wrong-audience"})
+				require.ErrorContains(t, err, `oidc: verify token: oidc: expected audience "some-audience" got ["wrong-audience"]`)
+				require.False(t, authenticated)
+				require.Nil(t, rsp)
+			}
+
+			if !tt.runTestsOnResultingAuthenticator {
+				return
+			}
+
 			const (
 				goodSubject  = "some-subject"
 				group0       = "some-group-0"
@@ -440,6 +563,7 @@ func TestController(t *testing.T) {
 				goodUsername,
 				tt.wantUsernameClaim,
 				tt.wantGroupsClaim,
+				tt.wantUsernamePrefix,
 				goodIssuer,
 			) {
 				test := test
@@ -520,6 +644,7 @@ func testTableForAuthenticateTokenTests(
 	goodUsername string,
 	expectedUsernameClaim string,
 	expectedGroupsClaim string,
+	expectedUsernamePrefix string,
 	issuer string,
 ) []struct {
 	name                      string
@@ -543,7 +668,7 @@ func testTableForAuthenticateTokenTests(
 			name: "good token without groups and with EC signature",
 			wantResponse: &authenticator.Response{
 				User: &user.DefaultInfo{
-					Name: goodUsername,
+					Name: expectedUsernamePrefix + goodUsername,
 				},
 			},
 			wantAuthenticated: true,
@@ -557,7 +682,7 @@ func testTableForAuthenticateTokenTests(
 			},
 			wantResponse: &authenticator.Response{
 				User: &user.DefaultInfo{
-					Name: goodUsername,
+					Name: expectedUsernamePrefix + goodUsername,
 				},
 			},
 			wantAuthenticated: true,
@@ -569,7 +694,7 @@ func testTableForAuthenticateTokenTests(
 			},
 			wantResponse: &authenticator.Response{
 				User: &user.DefaultInfo{
-					Name:   goodUsername,
+					Name:   expectedUsernamePrefix + goodUsername,
 					Groups: []string{group0, group1},
 				},
 			},
@@ -582,7 +707,7 @@ func testTableForAuthenticateTokenTests(
 			distributedGroupsClaimURL: issuer + "/claim_source",
 			wantResponse: &authenticator.Response{
 				User: &user.DefaultInfo{
-					Name:   goodUsername,
+					Name:   expectedUsernamePrefix + goodUsername,
 					Groups: []string{"some-distributed-group-1", "some-distributed-group-2"},
 				},
 			},
@@ -609,7 +734,7 @@ func testTableForAuthenticateTokenTests(
 			},
 			wantResponse: &authenticator.Response{
 				User: &user.DefaultInfo{
-					Name:   goodUsername,
+					Name:   expectedUsernamePrefix + goodUsername,
 					Groups: []string{group0},
 				},
 			},
@@ -622,7 +747,7 @@ func testTableForAuthenticateTokenTests(
 			},
 			wantResponse: &authenticator.Response{
 				User: &user.DefaultInfo{
-					Name: goodUsername,
+					Name: expectedUsernamePrefix + goodUsername,
 				},
 			},
 			wantAuthenticated: true,
@@ -0,0 +1,209 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jwtcachefiller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
+
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	// keySetRefreshInterval is how often the keys are re-fetched in the background, so that keys
+	// which are rotated out of the JWKS eventually stop being trusted.
+	keySetRefreshInterval = 10 * time.Minute
+
+	// keySetMinRefreshInterval bounds how often a JWT with an unknown key ID can cause the keys to
+	// be re-fetched, so that a flood of bad tokens cannot overload the issuer's JWKS endpoint.
+	keySetMinRefreshInterval = 30 * time.Second
+)
+
+// cachingKeySet is a coreosoidc.KeySet which caches the keys from a remote JWKS endpoint. The keys are
+// refreshed in the background on a timer, and on demand (at a bounded rate) when a JWT is signed by a key
+// ID which is not in the cache. It is safe for concurrent use.
+type cachingKeySet struct {
+	jwksURL            string
+	client             *http.Client
+	clock              clock.PassiveClock
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	// refreshMutex serializes fetches, so that concurrent requests for an unknown key ID cause only one fetch.
+	refreshMutex sync.Mutex
+
+	mutex              sync.RWMutex
+	keys               []jose.JSONWebKey
+	lastRefreshAttempt time.Time
+
+	cancel context.CancelFunc
+}
+
+func newCachingKeySet(client *http.Client, jwksURL string, clock clock.PassiveClock) *cachingKeySet {
+	return &cachingKeySet{
+		jwksURL:            jwksURL,
+		client:             client,
+		clock:              clock,
+		refreshInterval:    keySetRefreshInterval,
+		minRefreshInterval: keySetMinRefreshInterval,
+	}
+}
+
+// start begins refreshing the keys in the background until Close is called.
+func (k *cachingKeySet) start(ctx context.Context) {
+	ctx, k.cancel = context.WithCancel(ctx)
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := k.refresh(ctx); err != nil {
+			plog.DebugErr("failed to refresh jwks", err, "jwksURL", k.jwksURL)
+		}
+	}, k.refreshInterval)
+}
+
+// Close implements pinnipedauthenticator.Closer.
+func (k *cachingKeySet) Close() {
+	if k.cancel != nil {
+		k.cancel()
+	}
+}
+
+// VerifySignature implements coreosoidc.KeySet.
+func (k *cachingKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	jws, err := jose.ParseSigned(jwt)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt: %w", err)
+	}
+
+	var keyID string
+	if len(jws.Signatures) > 0 {
+		keyID = jws.Signatures[0].Header.KeyID
+	}
+
+	keys, lastRefreshAttempt := k.cachedKeys(keyID)
+	if payload, ok := verifyWithKeys(jws, keys); ok {
+		return payload, nil
+	}
+	if keyID != "" && len(keys) > 0 {
+		// The key ID is known, so re-fetching the keys would not help.
+		return nil, errors.New("failed to verify id token signature")
+	}
+
+	if err := k.refreshForUnknownKey(ctx, lastRefreshAttempt); err != nil {
+		return nil, fmt.Errorf("fetching keys %w", err)
+	}
+
+	keys, _ = k.cachedKeys(keyID)
+	if payload, ok := verifyWithKeys(jws, keys); ok {
+		return payload, nil
+	}
+	return nil, errors.New("failed to verify id token signature")
+}
+
+// cachedKeys returns the cached keys which match keyID (or all cached keys when keyID is empty),
+// along with the time of the last refresh attempt.
+func (k *cachingKeySet) cachedKeys(keyID string) ([]jose.JSONWebKey, time.Time) {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	if keyID == "" {
+		return k.keys, k.lastRefreshAttempt
+	}
+	var keys []jose.JSONWebKey
+	for _, key := range k.keys {
+		if key.KeyID == keyID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, k.lastRefreshAttempt
+}
+
+// refreshForUnknownKey re-fetches the keys, unless another refresh was attempted since lastRefreshAttempt
+// or the previous refresh attempt was too recent.
+func (k *cachingKeySet) refreshForUnknownKey(ctx context.Context, lastRefreshAttempt time.Time) error {
+	k.refreshMutex.Lock()
+	defer k.refreshMutex.Unlock()
+
+	k.mutex.RLock()
+	latestRefreshAttempt := k.lastRefreshAttempt
+	k.mutex.RUnlock()
+
+	if latestRefreshAttempt.After(lastRefreshAttempt) {
+		return nil // somebody else refreshed the keys while we were waiting
+	}
+	if !latestRefreshAttempt.IsZero() && k.clock.Since(latestRefreshAttempt) < k.minRefreshInterval {
+		return nil // rate limit the refreshes caused by unknown key IDs
+	}
+
+	return k.refreshLocked(ctx)
+}
+
+func (k *cachingKeySet) refresh(ctx context.Context) error {
+	k.refreshMutex.Lock()
+	defer k.refreshMutex.Unlock()
+
+	return k.refreshLocked(ctx)
+}
+
+// refreshLocked fetches the keys. The caller must hold refreshMutex.
+func (k *cachingKeySet) refreshLocked(ctx context.Context) error {
+	k.mutex.Lock()
+	k.lastRefreshAttempt = k.clock.Now()
+	k.mutex.Unlock()
+
+	keys, err := k.fetchKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	k.mutex.Lock()
+	k.keys = keys
+	k.mutex.Unlock()
+	return nil
+}
+
+func (k *cachingKeySet) fetchKeys(ctx context.Context) ([]jose.JSONWebKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: can't create request: %w", err)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: get keys failed %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: get keys failed: %s %s", resp.Status, body)
+	}
+
+	var keySet jose.JSONWebKeySet
+	if err := json.Unmarshal(body, &keySet); err != nil {
+		return nil, fmt.Errorf("oidc: failed to decode keys: %w %s", err, body)
+	}
+	return keySet.Keys, nil
+}
+
+func verifyWithKeys(jws *jose.JSONWebSignature, keys []jose.JSONWebKey) ([]byte, bool) {
+	for _, key := range keys {
+		key := key
+		if payload, err := jws.Verify(&key); err == nil {
+			return payload, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jwtcachefiller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestCachingKeySet(t *testing.T) {
+	t.Parallel()
+
+	newKey := func(t *testing.T) *ecdsa.PrivateKey {
+		t.Helper()
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		return key
+	}
+
+	sign := func(t *testing.T, key *ecdsa.PrivateKey, keyID string) string {
+		t.Helper()
+		opts := &jose.SignerOptions{}
+		if keyID != "" {
+			opts = opts.WithHeader("kid", keyID)
+		}
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, opts)
+		require.NoError(t, err)
+		jws, err := signer.Sign([]byte("some-payload"))
+		require.NoError(t, err)
+		jwt, err := jws.CompactSerialize()
+		require.NoError(t, err)
+		return jwt
+	}
+
+	// jwksServer serves the public keys in keys, which may be changed by the test, and counts the requests.
+	type jwksServer struct {
+		mutex    sync.Mutex
+		keys     map[string]*ecdsa.PrivateKey
+		status   int
+		requests atomic.Int32
+		url      string
+	}
+
+	newJWKSServer := func(t *testing.T, keys map[string]*ecdsa.PrivateKey) *jwksServer {
+		t.Helper()
+		s := &jwksServer{keys: keys, status: http.StatusOK}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.requests.Add(1)
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			if s.status != http.StatusOK {
+				http.Error(w, "some error", s.status)
+				return
+			}
+			jwks := jose.JSONWebKeySet{}
+			for keyID, key := range s.keys {
+				jwks.Keys = append(jwks.Keys, jose.JSONWebKey{Key: key.Public(), KeyID: keyID, Algorithm: string(jose.ES256), Use: "sig"})
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(jwks))
+		}))
+		t.Cleanup(server.Close)
+		s.url = server.URL
+		return s
+	}
+
+	ctx := context.Background()
+
+	t.Run("keys are fetched once and then served from the cache", func(t *testing.T) {
+		t.Parallel()
+		key := newKey(t)
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"some-key-id": key})
+		keySet := newCachingKeySet(http.DefaultClient, server.url, clocktesting.NewFakeClock(time.Now()))
+
+		for i := 0; i < 3; i++ {
+			payload, err := keySet.VerifySignature(ctx, sign(t, key, "some-key-id"))
+			require.NoError(t, err)
+			require.Equal(t, "some-payload", string(payload))
+		}
+		require.Equal(t, int32(1), server.requests.Load())
+	})
+
+	t.Run("a JWT without a key ID is verified against all cached keys", func(t *testing.T) {
+		t.Parallel()
+		key := newKey(t)
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"some-other-key-id": newKey(t), "some-key-id": key})
+		keySet := newCachingKeySet(http.DefaultClient, server.url, clocktesting.NewFakeClock(time.Now()))
+
+		payload, err := keySet.VerifySignature(ctx, sign(t, key, ""))
+		require.NoError(t, err)
+		require.Equal(t, "some-payload", string(payload))
+		require.Equal(t, int32(1), server.requests.Load())
+	})
+
+	t.Run("an unknown key ID causes the keys to be refreshed to pick up rotated keys", func(t *testing.T) {
+		t.Parallel()
+		oldKey, newKey := newKey(t), newKey(t)
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"old-key-id": oldKey})
+		fakeClock := clocktesting.NewFakeClock(time.Now())
+		keySet := newCachingKeySet(http.DefaultClient, server.url, fakeClock)
+
+		_, err := keySet.VerifySignature(ctx, sign(t, oldKey, "old-key-id"))
+		require.NoError(t, err)
+		require.Equal(t, int32(1), server.requests.Load())
+
+		server.mutex.Lock()
+		server.keys = map[string]*ecdsa.PrivateKey{"new-key-id": newKey}
+		server.mutex.Unlock()
+		fakeClock.Step(keySetMinRefreshInterval)
+
+		payload, err := keySet.VerifySignature(ctx, sign(t, newKey, "new-key-id"))
+		require.NoError(t, err)
+		require.Equal(t, "some-payload", string(payload))
+		require.Equal(t, int32(2), server.requests.Load())
+
+		_, err = keySet.VerifySignature(ctx, sign(t, oldKey, "old-key-id"))
+		require.EqualError(t, err, "failed to verify id token signature")
+	})
+
+	t.Run("a bad signature with a known key ID does not cause the keys to be refreshed", func(t *testing.T) {
+		t.Parallel()
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"some-key-id": newKey(t)})
+		fakeClock := clocktesting.NewFakeClock(time.Now())
+		keySet := newCachingKeySet(http.DefaultClient, server.url, fakeClock)
+		require.NoError(t, keySet.refresh(ctx))
+		fakeClock.Step(time.Hour)
+
+		_, err := keySet.VerifySignature(ctx, sign(t, newKey(t), "some-key-id"))
+		require.EqualError(t, err, "failed to verify id token signature")
+		require.Equal(t, int32(1), server.requests.Load())
+	})
+
+	t.Run("refreshes caused by unknown key IDs are rate limited", func(t *testing.T) {
+		t.Parallel()
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"some-key-id": newKey(t)})
+		fakeClock := clocktesting.NewFakeClock(time.Now())
+		keySet := newCachingKeySet(http.DefaultClient, server.url, fakeClock)
+
+		for i := 0; i < 10; i++ {
+			_, err := keySet.VerifySignature(ctx, sign(t, newKey(t), "unknown-key-id"))
+			require.EqualError(t, err, "failed to verify id token signature")
+		}
+		require.Equal(t, int32(1), server.requests.Load())
+
+		fakeClock.Step(keySetMinRefreshInterval - time.Second)
+		_, err := keySet.VerifySignature(ctx, sign(t, newKey(t), "unknown-key-id"))
+		require.EqualError(t, err, "failed to verify id token signature")
+		require.Equal(t, int32(1), server.requests.Load())
+
+		fakeClock.Step(time.Second)
+		_, err = keySet.VerifySignature(ctx, sign(t, newKey(t), "unknown-key-id"))
+		require.EqualError(t, err, "failed to verify id token signature")
+		require.Equal(t, int32(2), server.requests.Load())
+	})
+
+	t.Run("concurrent requests for an unknown key ID cause a single refresh", func(t *testing.T) {
+		t.Parallel()
+		key := newKey(t)
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"some-key-id": key})
+		keySet := newCachingKeySet(http.DefaultClient, server.url, clocktesting.NewFakeClock(time.Now()))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := keySet.VerifySignature(ctx, sign(t, key, "some-key-id"))
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), server.requests.Load())
+	})
+
+	t.Run("errors fetching the keys are returned and also count towards the rate limit", func(t *testing.T) {
+		t.Parallel()
+		key := newKey(t)
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"some-key-id": key})
+		server.status = http.StatusInternalServerError
+		keySet := newCachingKeySet(http.DefaultClient, server.url, clocktesting.NewFakeClock(time.Now()))
+
+		_, err := keySet.VerifySignature(ctx, sign(t, key, "some-key-id"))
+		require.EqualError(t, err, "fetching keys oidc: get keys failed: 500 Internal Server Error some error\n")
+
+		_, err = keySet.VerifySignature(ctx, sign(t, key, "some-key-id"))
+		require.EqualError(t, err, "failed to verify id token signature")
+		require.Equal(t, int32(1), server.requests.Load())
+	})
+
+	t.Run("malformed JWTs are rejected without fetching the keys", func(t *testing.T) {
+		t.Parallel()
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"some-key-id": newKey(t)})
+		keySet := newCachingKeySet(http.DefaultClient, server.url, clocktesting.NewFakeClock(time.Now()))
+
+		_, err := keySet.VerifySignature(ctx, "not-a-jwt")
+		require.ErrorContains(t, err, "oidc: malformed jwt: ")
+		require.Equal(t, int32(0), server.requests.Load())
+	})
+
+	t.Run("keys are refreshed in the background until the key set is closed", func(t *testing.T) {
+		t.Parallel()
+		key := newKey(t)
+		server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"some-key-id": key})
+		keySet := newCachingKeySet(http.DefaultClient, server.url, clocktesting.NewFakeClock(time.Now()))
+		keySet.refreshInterval = 10 * time.Millisecond
+
+		keySet.start(ctx)
+		require.Eventually(t, func() bool { return server.requests.Load() >= 3 }, 10*time.Second, 10*time.Millisecond)
+
+		keySet.Close()
+		time.Sleep(50 * time.Millisecond) // let any in-flight refresh finish
+		requests := server.requests.Load()
+
+		// The keys were already fetched in the background, so verification does not need to fetch them.
+		payload, err := keySet.VerifySignature(ctx, sign(t, key, "some-key-id"))
+		require.NoError(t, err)
+		require.Equal(t, "some-payload", string(payload))
+
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, requests, server.requests.Load())
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jwtcachefiller
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+
+	auth1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/authentication/v1alpha1"
+	"go.pinniped.dev/internal/constable"
+)
+
+const (
+	// ErrRequiredClaimMissing is returned when a JWT does not have a claim which is required by the
+	// JWTAuthenticator, or when that claim is null or empty.
+	ErrRequiredClaimMissing = constable.Error("jwt is missing required claim")
+
+	// ErrRequiredClaimMismatch is returned when a JWT has a claim which is required by the JWTAuthenticator,
+	// but the claim does not have the required value.
+	ErrRequiredClaimMismatch = constable.Error("jwt claim does not have the required value")
+)
+
+// requiredClaimsAuthenticator is an authenticator.Token which rejects tokens that were successfully authenticated
+// by its delegate when they do not satisfy all of the required claims.
+type requiredClaimsAuthenticator struct {
+	delegate       authenticator.Token
+	requiredClaims []auth1alpha1.JWTRequiredClaim
+}
+
+func (a *requiredClaimsAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	response, authenticated, err := a.delegate.AuthenticateToken(ctx, token)
+	if err != nil || !authenticated {
+		return response, authenticated, err
+	}
+
+	// The delegate has already verified the signature of the token, so it is safe to read its claims here.
+	claims, err := unverifiedClaims(token)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, requiredClaim := range a.requiredClaims {
+		if err := checkRequiredClaim(claims, requiredClaim); err != nil {
+			return nil, false, err
+		}
+	}
+	return response, true, nil
+}
+
+// unverifiedClaims decodes the payload of a compact serialized JWT without verifying its signature.
+// Numbers are decoded as json.Number so that they can be compared to required values without losing precision.
+func unverifiedClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("jwt has %d parts, but expected 3", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode jwt payload: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var claims map[string]interface{}
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("could not parse jwt claims: %w", err)
+	}
+	return claims, nil
+}
+
+func checkRequiredClaim(claims map[string]interface{}, requiredClaim auth1alpha1.JWTRequiredClaim) error {
+	value, ok := claims[requiredClaim.Claim]
+	if !ok || isEmptyClaim(value) {
+		return fmt.Errorf("%w %q", ErrRequiredClaimMissing, requiredClaim.Claim)
+	}
+	if requiredClaim.Value == "" {
+		return nil
+	}
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if claimValueEquals(item, requiredClaim.Value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: claim %q does not contain %q", ErrRequiredClaimMismatch, requiredClaim.Claim, requiredClaim.Value)
+	}
+	if !claimValueEquals(value, requiredClaim.Value) {
+		return fmt.Errorf("%w: claim %q is not %q", ErrRequiredClaimMismatch, requiredClaim.Claim, requiredClaim.Value)
+	}
+	return nil
+}
+
+func isEmptyClaim(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+func claimValueEquals(value interface{}, want string) bool {
+	switch v := value.(type) {
+	case string:
+		return v == want
+	case bool:
+		return strconv.FormatBool(v) == want
+	case json.Number:
+		return v.String() == want
+	default:
+		return false
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jwtcachefiller
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	auth1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/authentication/v1alpha1"
+)
+
+func TestRequiredClaimsAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	goodResponse := &authenticator.Response{User: &user.DefaultInfo{Name: "pinny123"}}
+	delegate := authenticator.TokenFunc(func(_ context.Context, token string) (*authenticator.Response, bool, error) {
+		if token == "unauthenticated" {
+			return nil, false, nil
+		}
+		return goodResponse, true, nil
+	})
+
+	// The delegate is responsible for verifying the signature, so the tokens in these tests are not signed.
+	tokenWithPayload := func(payload string) string {
+		return "e30." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2ln"
+	}
+	const payload = `{"sub":"some-subject","email_verified":true,"groups":["group-a","group-b"],"level":3,` +
+		`"tenant":"acme","empty_string":"","empty_list":[],"empty_object":{},"null_claim":null,"object":{"a":"b"}}`
+
+	tests := []struct {
+		name           string
+		requiredClaims []auth1alpha1.JWTRequiredClaim
+		token          string
+		wantErr        string
+		wantErrIs      error
+	}{
+		{
+			name: "claims which are present and non-empty",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{
+				{Claim: "email_verified"}, {Claim: "groups"}, {Claim: "level"}, {Claim: "tenant"}, {Claim: "object"},
+			},
+		},
+		{
+			name: "claims which have the required values",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{
+				{Claim: "email_verified", Value: "true"},
+				{Claim: "groups", Value: "group-b"},
+				{Claim: "level", Value: "3"},
+				{Claim: "tenant", Value: "acme"},
+			},
+		},
+		{
+			name:           "claim is absent",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "tenant"}, {Claim: "department"}},
+			wantErr:        `jwt is missing required claim "department"`,
+			wantErrIs:      ErrRequiredClaimMissing,
+		},
+		{
+			name:           "claim is absent when a value is required",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "department", Value: "engineering"}},
+			wantErr:        `jwt is missing required claim "department"`,
+			wantErrIs:      ErrRequiredClaimMissing,
+		},
+		{
+			name:           "claim is null",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "null_claim"}},
+			wantErr:        `jwt is missing required claim "null_claim"`,
+			wantErrIs:      ErrRequiredClaimMissing,
+		},
+		{
+			name:           "claim is an empty string",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "empty_string"}},
+			wantErr:        `jwt is missing required claim "empty_string"`,
+			wantErrIs:      ErrRequiredClaimMissing,
+		},
+		{
+			name:           "claim is an empty list",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "empty_list"}},
+			wantErr:        `jwt is missing required claim "empty_list"`,
+			wantErrIs:      ErrRequiredClaimMissing,
+		},
+		{
+			name:           "claim is an empty object",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "empty_object"}},
+			wantErr:        `jwt is missing required claim "empty_object"`,
+			wantErrIs:      ErrRequiredClaimMissing,
+		},
+		{
+			name:           "boolean claim has the wrong value",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "email_verified", Value: "false"}},
+			wantErr:        `jwt claim does not have the required value: claim "email_verified" is not "false"`,
+			wantErrIs:      ErrRequiredClaimMismatch,
+		},
+		{
+			name:           "number claim has the wrong value",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "level", Value: "3.0"}},
+			wantErr:        `jwt claim does not have the required value: claim "level" is not "3.0"`,
+			wantErrIs:      ErrRequiredClaimMismatch,
+		},
+		{
+			name:           "string claim has the wrong value",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "tenant", Value: "other"}},
+			wantErr:        `jwt claim does not have the required value: claim "tenant" is not "other"`,
+			wantErrIs:      ErrRequiredClaimMismatch,
+		},
+		{
+			name:           "list claim does not contain the value",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "groups", Value: "group-c"}},
+			wantErr:        `jwt claim does not have the required value: claim "groups" does not contain "group-c"`,
+			wantErrIs:      ErrRequiredClaimMismatch,
+		},
+		{
+			name:           "object claims never have the required value",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "object", Value: "b"}},
+			wantErr:        `jwt claim does not have the required value: claim "object" is not "b"`,
+			wantErrIs:      ErrRequiredClaimMismatch,
+		},
+		{
+			name:           "token was not authenticated by the delegate",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "department"}},
+			token:          "unauthenticated",
+		},
+		{
+			name:           "token payload cannot be decoded",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "tenant"}},
+			token:          "e30.!!!.c2ln",
+			wantErr:        "could not decode jwt payload: illegal base64 data at input byte 0",
+		},
+		{
+			name:           "token payload is not a JSON object",
+			requiredClaims: []auth1alpha1.JWTRequiredClaim{{Claim: "tenant"}},
+			token:          tokenWithPayload(`["not", "an", "object"]`),
+			wantErr:        "could not parse jwt claims: json: cannot unmarshal array into Go value of type map[string]interface {}",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			token := tt.token
+			if token == "" {
+				token = tokenWithPayload(payload)
+			}
+			a := &requiredClaimsAuthenticator{delegate: delegate, requiredClaims: tt.requiredClaims}
+			rsp, authenticated, err := a.AuthenticateToken(context.Background(), token)
+
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				if tt.wantErrIs != nil {
+					require.True(t, errors.Is(err, tt.wantErrIs))
+				}
+				require.False(t, authenticated)
+				require.Nil(t, rsp)
+				return
+			}
+			require.NoError(t, err)
+			if tt.token == "unauthenticated" {
+				require.False(t, authenticated)
+				require.Nil(t, rsp)
+				return
+			}
+			require.True(t, authenticated)
+			require.Equal(t, goodResponse, rsp)
+		})
+	}
+}
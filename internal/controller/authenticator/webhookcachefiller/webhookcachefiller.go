@@ -1,4 +1,4 @@
-// Copyright 2020-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package webhookcachefiller implements a controller for filling an authncache.Cache with each added/updated WebhookAuthenticator.
@@ -7,12 +7,14 @@ package webhookcachefiller
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/go-logr/logr"
 	k8sauthv1beta1 "k8s.io/api/authentication/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
+	tokencache "k8s.io/apiserver/pkg/authentication/token/cache"
 	webhookutil "k8s.io/apiserver/pkg/util/webhook"
 	"k8s.io/apiserver/plugin/pkg/authenticator/token/webhook"
 	"k8s.io/client-go/tools/clientcmd"
@@ -27,6 +29,16 @@ import (
 	"go.pinniped.dev/internal/controllerlib"
 )
 
+const (
+	// defaultCacheTTL is how long successful token reviews are cached when the WebhookAuthenticator does not
+	// specify a cacheTTL. It is kept short so that a revoked token stops working soon after it is revoked.
+	defaultCacheTTL = 10 * time.Second
+
+	// maxDeniedCacheTTL bounds how long denied token reviews are cached, so that a token which was denied
+	// (e.g. because the webhook had not yet learned about it) starts working soon after it becomes valid.
+	maxDeniedCacheTTL = 2 * time.Second
+)
+
 // New instantiates a new controllerlib.Controller which will populate the provided authncache.Cache.
 func New(cache *authncache.Cache, webhooks authinformers.WebhookAuthenticatorInformer, log logr.Logger) controllerlib.Controller {
 	return controllerlib.New(
@@ -63,6 +75,11 @@ func (c *controller) Sync(ctx controllerlib.Context) error {
 		return fmt.Errorf("failed to get WebhookAuthenticator %s/%s: %w", ctx.Key.Namespace, ctx.Key.Name, err)
 	}
 
+	cacheTTL, err := validatedCacheTTL(&obj.Spec)
+	if err != nil {
+		return fmt.Errorf("invalid webhook config: %w", err)
+	}
+
 	webhookAuthenticator, err := newWebhookAuthenticator(&obj.Spec, os.CreateTemp, clientcmd.WriteToFile)
 	if err != nil {
 		return fmt.Errorf("failed to build webhook config: %w", err)
@@ -72,11 +89,37 @@ func (c *controller) Sync(ctx controllerlib.Context) error {
 		APIGroup: auth1alpha1.GroupName,
 		Kind:     "WebhookAuthenticator",
 		Name:     ctx.Key.Name,
-	}, webhookAuthenticator)
+	}, newCachingAuthenticator(webhookAuthenticator, cacheTTL))
 	c.log.WithValues("webhook", klog.KObj(obj), "endpoint", obj.Spec.Endpoint).Info("added new webhook authenticator")
 	return nil
 }
 
+// validatedCacheTTL returns the validated cacheTTL from the spec, or the default when it is not specified.
+func validatedCacheTTL(spec *auth1alpha1.WebhookAuthenticatorSpec) (time.Duration, error) {
+	if spec.CacheTTL == nil {
+		return defaultCacheTTL, nil
+	}
+	if spec.CacheTTL.Duration < 0 {
+		return 0, fmt.Errorf("cacheTTL %q must not be negative", spec.CacheTTL.Duration)
+	}
+	return spec.CacheTTL.Duration, nil
+}
+
+// newCachingAuthenticator wraps the webhook authenticator with a bounded cache of token review results, keyed
+// by a hash of the token. Successful reviews are cached for cacheTTL, denied reviews are cached for at most
+// maxDeniedCacheTTL (and never longer than cacheTTL), and errors calling the webhook are not cached at all.
+// A cacheTTL of zero disables caching.
+func newCachingAuthenticator(delegate authenticator.Token, cacheTTL time.Duration) authenticator.Token {
+	if cacheTTL == 0 {
+		return delegate
+	}
+	deniedCacheTTL := cacheTTL
+	if deniedCacheTTL > maxDeniedCacheTTL {
+		deniedCacheTTL = maxDeniedCacheTTL
+	}
+	return tokencache.New(delegate, false, cacheTTL, deniedCacheTTL)
+}
+
 // newWebhookAuthenticator creates a webhook from the provided API server url and caBundle
 // used to validate TLS connections.
 func newWebhookAuthenticator(
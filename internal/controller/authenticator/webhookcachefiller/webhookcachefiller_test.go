@@ -1,4 +1,4 @@
-// Copyright 2020-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package webhookcachefiller
@@ -10,11 +10,15 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
@@ -60,6 +64,22 @@ func TestController(t *testing.T) {
 			},
 			wantErr: `failed to build webhook config: parse "http://invalid url": invalid character " " in host name`,
 		},
+		{
+			name:    "negative cacheTTL",
+			syncKey: controllerlib.Key{Name: "test-name"},
+			webhooks: []runtime.Object{
+				&auth1alpha1.WebhookAuthenticator{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test-name",
+					},
+					Spec: auth1alpha1.WebhookAuthenticatorSpec{
+						Endpoint: "https://example.com",
+						CacheTTL: &metav1.Duration{Duration: -time.Second},
+					},
+				},
+			},
+			wantErr: `invalid webhook config: cacheTTL "-1s" must not be negative`,
+		},
 		{
 			name:    "valid webhook",
 			syncKey: controllerlib.Key{Name: "test-name"},
@@ -176,3 +196,135 @@ func TestNewWebhookAuthenticator(t *testing.T) {
 		require.False(t, authenticated)
 	})
 }
+
+func TestNewCachingAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	type delegateResult struct {
+		authenticated bool
+		err           error
+	}
+
+	newDelegate := func(result delegateResult) (authenticator.Token, *atomic.Int32) {
+		var calls atomic.Int32
+		return authenticator.TokenFunc(func(_ context.Context, token string) (*authenticator.Response, bool, error) {
+			calls.Add(1)
+			if result.err != nil || !result.authenticated {
+				return nil, false, result.err
+			}
+			return &authenticator.Response{User: &user.DefaultInfo{Name: "user-for-" + token}}, true, nil
+		}), &calls
+	}
+
+	authenticate := func(t *testing.T, a authenticator.Token, token string) (*authenticator.Response, bool, error) {
+		t.Helper()
+		return a.AuthenticateToken(context.Background(), token)
+	}
+
+	t.Run("successful token reviews are cached per token until the cacheTTL expires", func(t *testing.T) {
+		t.Parallel()
+		delegate, calls := newDelegate(delegateResult{authenticated: true})
+		subject := newCachingAuthenticator(delegate, 500*time.Millisecond)
+
+		for i := 0; i < 3; i++ {
+			resp, authenticated, err := authenticate(t, subject, "some-token")
+			require.NoError(t, err)
+			require.True(t, authenticated)
+			require.Equal(t, "user-for-some-token", resp.User.GetName())
+		}
+		require.Equal(t, int32(1), calls.Load())
+
+		resp, authenticated, err := authenticate(t, subject, "some-other-token")
+		require.NoError(t, err)
+		require.True(t, authenticated)
+		require.Equal(t, "user-for-some-other-token", resp.User.GetName())
+		require.Equal(t, int32(2), calls.Load())
+
+		require.Eventually(t, func() bool {
+			_, _, _ = authenticate(t, subject, "some-token")
+			return calls.Load() == 3
+		}, 10*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("denied token reviews are cached for no longer than the max denied cacheTTL", func(t *testing.T) {
+		t.Parallel()
+		delegate, calls := newDelegate(delegateResult{authenticated: false})
+		subject := newCachingAuthenticator(delegate, time.Hour)
+
+		for i := 0; i < 3; i++ {
+			resp, authenticated, err := authenticate(t, subject, "some-token")
+			require.NoError(t, err)
+			require.False(t, authenticated)
+			require.Nil(t, resp)
+		}
+		require.Equal(t, int32(1), calls.Load())
+
+		require.Eventually(t, func() bool {
+			_, _, _ = authenticate(t, subject, "some-token")
+			return calls.Load() == 2
+		}, 10*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("denied token reviews are cached for no longer than a short cacheTTL", func(t *testing.T) {
+		t.Parallel()
+		require.Greater(t, maxDeniedCacheTTL, 100*time.Millisecond)
+		delegate, calls := newDelegate(delegateResult{authenticated: false})
+		subject := newCachingAuthenticator(delegate, 100*time.Millisecond)
+
+		_, authenticated, err := authenticate(t, subject, "some-token")
+		require.NoError(t, err)
+		require.False(t, authenticated)
+		time.Sleep(200 * time.Millisecond)
+
+		_, authenticated, err = authenticate(t, subject, "some-token")
+		require.NoError(t, err)
+		require.False(t, authenticated)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("errors calling the webhook are not cached", func(t *testing.T) {
+		t.Parallel()
+		delegate, calls := newDelegate(delegateResult{err: fmt.Errorf("some webhook error")})
+		subject := newCachingAuthenticator(delegate, time.Hour)
+
+		for i := 0; i < 3; i++ {
+			resp, authenticated, err := authenticate(t, subject, "some-token")
+			require.EqualError(t, err, "some webhook error")
+			require.False(t, authenticated)
+			require.Nil(t, resp)
+		}
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("a cacheTTL of zero disables caching", func(t *testing.T) {
+		t.Parallel()
+		delegate, calls := newDelegate(delegateResult{authenticated: true})
+		subject := newCachingAuthenticator(delegate, 0)
+
+		for i := 0; i < 3; i++ {
+			_, authenticated, err := authenticate(t, subject, "some-token")
+			require.NoError(t, err)
+			require.True(t, authenticated)
+		}
+		require.Equal(t, int32(3), calls.Load())
+	})
+}
+
+func TestValidatedCacheTTL(t *testing.T) {
+	t.Parallel()
+
+	ttl, err := validatedCacheTTL(&auth1alpha1.WebhookAuthenticatorSpec{})
+	require.NoError(t, err)
+	require.Equal(t, defaultCacheTTL, ttl)
+
+	ttl, err = validatedCacheTTL(&auth1alpha1.WebhookAuthenticatorSpec{CacheTTL: &metav1.Duration{Duration: time.Minute}})
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, ttl)
+
+	ttl, err = validatedCacheTTL(&auth1alpha1.WebhookAuthenticatorSpec{CacheTTL: &metav1.Duration{}})
+	require.NoError(t, err)
+	require.Zero(t, ttl)
+
+	_, err = validatedCacheTTL(&auth1alpha1.WebhookAuthenticatorSpec{CacheTTL: &metav1.Duration{Duration: -time.Minute}})
+	require.EqualError(t, err, `cacheTTL "-1m0s" must not be negative`)
+}
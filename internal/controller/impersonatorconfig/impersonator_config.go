@@ -4,15 +4,19 @@
 package impersonatorconfig
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
+	stderrors "errors"
 	"fmt"
 	"net"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/errors"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -29,10 +34,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/component-base/metrics"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 
 	"go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
 	pinnipedclientset "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned"
 	conciergeconfiginformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions/config/v1alpha1"
 	"go.pinniped.dev/internal/certauthority"
@@ -41,6 +49,7 @@ import (
 	"go.pinniped.dev/internal/constable"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
 	"go.pinniped.dev/internal/controller/apicerts"
+	"go.pinniped.dev/internal/controller/impersonatorconfig/proxyinfo"
 	"go.pinniped.dev/internal/controller/issuerconfig"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/dynamiccert"
@@ -49,13 +58,30 @@ import (
 )
 
 const (
-	defaultHTTPSPort             = 443
-	approximatelyOneHundredYears = 100 * 365 * 24 * time.Hour
-	caCommonName                 = "Pinniped Impersonation Proxy Serving CA"
-	caCrtKey                     = "ca.crt"
-	caKeyKey                     = "ca.key"
-	appLabelKey                  = "app"
-	annotationKeysKey            = "credentialissuer.pinniped.dev/annotation-keys"
+	defaultHTTPSPort  = 443
+	caCommonName      = "Pinniped Impersonation Proxy Serving CA"
+	caCrtKey          = "ca.crt"
+	caKeyKey          = "ca.key"
+	appLabelKey       = "app"
+	annotationKeysKey = "credentialissuer.pinniped.dev/annotation-keys"
+
+	// userProvidedTLSAnnotationKey marks a TLS Secret which was provided by the user instead of being generated
+	// by this controller. While its certificate has not expired, it is served as-is and no certificate is issued.
+	userProvidedTLSAnnotationKey = "credentialissuer.pinniped.dev/user-provided-tls-certificate"
+
+	// forceSyncAnnotationKey can be set on the CredentialIssuer to any value, such as a timestamp. Each time that
+	// its value changes, everything is evaluated again, including the decisions which are otherwise only made once,
+	// e.g. after the load balancer was re-provisioned by the cloud provider without any change to its Service.
+	forceSyncAnnotationKey = "credentialissuer.pinniped.dev/force-sync"
+
+	// serviceOwnerLabelKey is added to each Service which this controller creates, so that a Service can still be
+	// found and deleted after the configured name for Services of its type has changed.
+	serviceOwnerLabelKey   = "credentialissuer.pinniped.dev/owner"
+	serviceOwnerLabelValue = "impersonator-config-controller"
+
+	// hostnameResolutionTimeout bounds the DNS lookup of the load balancer's hostname, so a slow DNS server
+	// cannot stall the sync.
+	hostnameResolutionTimeout = 5 * time.Second
 )
 
 type impersonatorConfigController struct {
@@ -64,9 +90,25 @@ type impersonatorConfigController struct {
 	impersonationProxyPort           int
 	generatedLoadBalancerServiceName string
 	generatedClusterIPServiceName    string
+	generatedNodePortServiceName     string
 	tlsSecretName                    string
+	tlsSecretRef                     string
 	caSecretName                     string
+	caBundleConfigMapName            string
 	impersonationSignerSecretName    string
+	caSubject                        pkix.Name
+	caCertificateDuration            time.Duration
+	caKeyAlgorithm                   certauthority.KeyAlgorithm
+	certificateDuration              time.Duration
+	additionalIPs                    []net.IP
+	additionalHostnames              []string
+	rotationWindowPercentage         int
+	requestTimeout                   time.Duration
+	shutdownDrainTimeout             time.Duration
+	resyncInterval                   time.Duration
+	controlPlaneNodeSelectors        []labels.Selector
+	excludedNodeRoles                []string
+	autoModeStopDelay                time.Duration
 
 	k8sClient         kubernetes.Interface
 	pinnipedAPIClient pinnipedclientset.Interface
@@ -74,16 +116,29 @@ type impersonatorConfigController struct {
 	credIssuerInformer conciergeconfiginformers.CredentialIssuerInformer
 	servicesInformer   corev1informers.ServiceInformer
 	secretsInformer    corev1informers.SecretInformer
+	configMapsInformer corev1informers.ConfigMapInformer
 
 	labels                           map[string]string
+	extraLabels                      map[string]string
+	serviceSelector                  map[string]string
 	clock                            clock.Clock
+	recorder                         events.EventRecorder
+	metrics                          *impersonatorMetrics
 	impersonationSigningCertProvider dynamiccert.Provider
+	impersonationProxyInfoCache      *proxyinfo.Cache
 	impersonatorFunc                 impersonator.FactoryFunc
+	stateChangeWebhook               *stateChangeWebhook
+	lookupIP                         func(ctx context.Context, host string) ([]net.IP, error)
 
 	hasControlPlaneNodes              *bool
+	lastControlPlaneNodesCheck        time.Time
+	controlPlaneNodesVisibleSince     time.Time
+	lastForceSyncValue                string
 	serverStopCh                      chan struct{}
+	serverAddress                     string
 	errorCh                           chan error
 	tlsServingCertDynamicCertProvider dynamiccert.Private
+	pendingStateChanges               []stateChange
 	infoLog                           logr.Logger
 	debugLog                          logr.Logger
 }
@@ -96,42 +151,114 @@ func NewImpersonatorConfigController(
 	credentialIssuerInformer conciergeconfiginformers.CredentialIssuerInformer,
 	servicesInformer corev1informers.ServiceInformer,
 	secretsInformer corev1informers.SecretInformer,
+	configMapsInformer corev1informers.ConfigMapInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	withInitialEvent pinnipedcontroller.WithInitialEventOptionFunc,
 	impersonationProxyPort int,
 	generatedLoadBalancerServiceName string,
 	generatedClusterIPServiceName string,
+	generatedNodePortServiceName string,
 	tlsSecretName string,
+	tlsSecretRef string,
 	caSecretName string,
+	caBundleConfigMapName string,
 	labels map[string]string,
+	extraLabels map[string]string,
+	serviceSelector map[string]string,
 	clock clock.Clock,
+	recorder events.EventRecorder,
+	registerMetrics func(...metrics.Registerable),
 	impersonatorFunc impersonator.FactoryFunc,
 	impersonationSignerSecretName string,
 	impersonationSigningCertProvider dynamiccert.Provider,
+	impersonationProxyInfoCache *proxyinfo.Cache,
+	caSubject pkix.Name,
+	caCertificateDuration time.Duration,
+	caKeyAlgorithm certauthority.KeyAlgorithm,
+	certificateDuration time.Duration,
+	additionalSANs []string,
+	rotationWindowPercentage int,
+	requestTimeout time.Duration,
+	shutdownDrainTimeout time.Duration,
+	resyncInterval time.Duration,
+	controlPlaneNodeSelectors []labels.Selector,
+	excludedNodeRoles []string,
+	autoModeStopDelay time.Duration,
+	stateChangeWebhookURL string,
 	log logr.Logger,
 ) controllerlib.Controller {
 	secretNames := sets.NewString(tlsSecretName, caSecretName, impersonationSignerSecretName)
+	if tlsSecretRef != "" {
+		secretNames.Insert(tlsSecretRef)
+	}
+	if caSubject.CommonName == "" {
+		caSubject.CommonName = caCommonName
+	}
+	if caKeyAlgorithm == "" {
+		caKeyAlgorithm = certauthority.KeyAlgorithmECDSAP256
+	}
+	if len(serviceSelector) == 0 {
+		// By default, select the Concierge pods by the app label which is applied to all of Pinniped's resources.
+		serviceSelector = map[string]string{appLabelKey: labels[appLabelKey]}
+	}
+	// The additional names were already validated by the config reader, so anything which is not an IP is a hostname.
+	var additionalIPs []net.IP
+	var additionalHostnames []string
+	for _, san := range additionalSANs {
+		if ip := net.ParseIP(san); ip != nil {
+			additionalIPs = append(additionalIPs, ip)
+		} else {
+			additionalHostnames = append(additionalHostnames, san)
+		}
+	}
 	log = log.WithName("impersonator-config-controller")
 	return controllerlib.New(
 		controllerlib.Config{
 			Name: "impersonator-config-controller",
 			Syncer: &impersonatorConfigController{
-				namespace:                         namespace,
-				credentialIssuerResourceName:      credentialIssuerResourceName,
-				impersonationProxyPort:            impersonationProxyPort,
-				generatedLoadBalancerServiceName:  generatedLoadBalancerServiceName,
-				generatedClusterIPServiceName:     generatedClusterIPServiceName,
-				tlsSecretName:                     tlsSecretName,
-				caSecretName:                      caSecretName,
-				impersonationSignerSecretName:     impersonationSignerSecretName,
-				k8sClient:                         k8sClient,
-				pinnipedAPIClient:                 pinnipedAPIClient,
-				credIssuerInformer:                credentialIssuerInformer,
-				servicesInformer:                  servicesInformer,
-				secretsInformer:                   secretsInformer,
-				labels:                            labels,
-				clock:                             clock,
-				impersonationSigningCertProvider:  impersonationSigningCertProvider,
-				impersonatorFunc:                  impersonatorFunc,
+				namespace:                        namespace,
+				credentialIssuerResourceName:     credentialIssuerResourceName,
+				impersonationProxyPort:           impersonationProxyPort,
+				generatedLoadBalancerServiceName: generatedLoadBalancerServiceName,
+				generatedClusterIPServiceName:    generatedClusterIPServiceName,
+				generatedNodePortServiceName:     generatedNodePortServiceName,
+				tlsSecretName:                    tlsSecretName,
+				tlsSecretRef:                     tlsSecretRef,
+				caSecretName:                     caSecretName,
+				caBundleConfigMapName:            caBundleConfigMapName,
+				impersonationSignerSecretName:    impersonationSignerSecretName,
+				caSubject:                        caSubject,
+				caCertificateDuration:            caCertificateDuration,
+				caKeyAlgorithm:                   caKeyAlgorithm,
+				certificateDuration:              certificateDuration,
+				additionalIPs:                    additionalIPs,
+				additionalHostnames:              additionalHostnames,
+				rotationWindowPercentage:         rotationWindowPercentage,
+				requestTimeout:                   requestTimeout,
+				shutdownDrainTimeout:             shutdownDrainTimeout,
+				resyncInterval:                   resyncInterval,
+				controlPlaneNodeSelectors:        controlPlaneNodeSelectors,
+				excludedNodeRoles:                excludedNodeRoles,
+				autoModeStopDelay:                autoModeStopDelay,
+				k8sClient:                        k8sClient,
+				pinnipedAPIClient:                pinnipedAPIClient,
+				credIssuerInformer:               credentialIssuerInformer,
+				servicesInformer:                 servicesInformer,
+				secretsInformer:                  secretsInformer,
+				configMapsInformer:               configMapsInformer,
+				labels:                           labels,
+				extraLabels:                      extraLabels,
+				serviceSelector:                  serviceSelector,
+				clock:                            clock,
+				recorder:                         recorder,
+				metrics:                          newImpersonatorMetrics(registerMetrics),
+				impersonationSigningCertProvider: impersonationSigningCertProvider,
+				impersonationProxyInfoCache:      impersonationProxyInfoCache,
+				impersonatorFunc:                 impersonatorFunc,
+				stateChangeWebhook:               newStateChangeWebhook(stateChangeWebhookURL, clock, log.V(plog.KlogLevelInfo)),
+				lookupIP: func(ctx context.Context, host string) ([]net.IP, error) {
+					return net.DefaultResolver.LookupIP(ctx, "ip", host)
+				},
 				tlsServingCertDynamicCertProvider: dynamiccert.NewServingCert("impersonation-proxy-serving-cert"),
 				infoLog:                           log.V(plog.KlogLevelInfo),
 				debugLog:                          log.V(plog.KlogLevelDebug),
@@ -150,7 +277,7 @@ func NewImpersonatorConfigController(
 					return false
 				}
 				switch obj.GetName() {
-				case generatedLoadBalancerServiceName, generatedClusterIPServiceName:
+				case generatedLoadBalancerServiceName, generatedClusterIPServiceName, generatedNodePortServiceName:
 					return true
 				default:
 					return false
@@ -165,12 +292,27 @@ func NewImpersonatorConfigController(
 			}),
 			controllerlib.InformerOption{},
 		),
+		withInformer(
+			configMapsInformer,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return caBundleConfigMapName != "" && obj.GetNamespace() == namespace && obj.GetName() == caBundleConfigMapName
+			}),
+			controllerlib.InformerOption{},
+		),
+		// Sync once at startup, which also starts the periodic resyncs. This uses the same key as the singleton
+		// queue of the informers, so that a resync and an informer event which happen together cause only one sync.
+		withInitialEvent(controllerlib.Key{}),
 	)
 }
 
 func (c *impersonatorConfigController) Sync(syncCtx controllerlib.Context) error {
 	c.debugLog.Info("starting impersonatorConfigController Sync")
 
+	// Sync again after the resync interval even when there are no informer events, e.g. to notice that a load
+	// balancer was assigned an ingress without an event for its Service, or that a certificate needs rotation.
+	// When a sooner sync was already scheduled, such as for certificate rotation, the queue keeps the sooner one.
+	syncCtx.Queue.AddAfter(syncCtx.Key, c.resyncInterval)
+
 	// Load the CredentialIssuer that we'll update with status.
 	credIssuer, err := c.credIssuerInformer.Lister().Get(c.credentialIssuerResourceName)
 	if err != nil {
@@ -179,6 +321,8 @@ func (c *impersonatorConfigController) Sync(syncCtx controllerlib.Context) error
 
 	strategy, err := c.doSync(syncCtx, credIssuer)
 	if err != nil {
+		c.recorder.Eventf(credIssuer, nil, v1.EventTypeWarning, "SyncFailed", "Sync",
+			"Failed to configure impersonation proxy: %v", err)
 		strategy = &v1alpha1.CredentialIssuerStrategy{
 			Type:           v1alpha1.ImpersonationProxyStrategyType,
 			Status:         v1alpha1.ErrorStrategyStatus,
@@ -188,6 +332,13 @@ func (c *impersonatorConfigController) Sync(syncCtx controllerlib.Context) error
 		}
 	}
 
+	// Tell clients of the TokenCredentialRequest API how to reach the impersonation proxy, but only while it is ready.
+	c.impersonationProxyInfoCache.Set(impersonationProxyInfoForStrategy(strategy))
+
+	// Tell the state change webhook about the transitions which happened during this sync, even when it failed.
+	c.stateChangeWebhook.notify(c.pendingStateChanges, impersonationProxyInfoForStrategy(strategy))
+	c.pendingStateChanges = nil
+
 	err = utilerrors.NewAggregate([]error{err, issuerconfig.Update(
 		syncCtx.Context,
 		c.pinnipedAPIClient,
@@ -219,14 +370,20 @@ type certNameInfo struct {
 	// When false, the other fields in this struct should not be considered meaningful and may be zero values.
 	ready bool
 
-	// The IP address or hostname which was selected to be used as the name in the cert.
-	// Either selectedIP or selectedHostname will be set, but not both.
+	// The IP addresses and/or hostname which were selected to be used as the names in the cert.
+	// Usually either selectedIPs or selectedHostname will be set, but not both. The exceptions are a load balancer
+	// with a hostname which is resolved to its IPs, and a load balancer with a hostname and an additional ClusterIP
+	// Service, in which case those IPs are also included.
 	selectedIPs      []net.IP
 	selectedHostname string
 
 	// The name of the endpoint to which a client should connect to talk to the impersonator.
 	// This may be a hostname or an IP, and may include a port number.
 	clientEndpoint string
+
+	// hostnameResolutionFailed will be true when the selected hostname should have been resolved to IP addresses
+	// for the cert, but the lookup failed. The cert is still valid for the hostname, and the lookup should be retried.
+	hostnameResolutionFailed bool
 }
 
 func (c *impersonatorConfigController) doSync(syncCtx controllerlib.Context, credIssuer *v1alpha1.CredentialIssuer) (*v1alpha1.CredentialIssuerStrategy, error) {
@@ -237,21 +394,45 @@ func (c *impersonatorConfigController) doSync(syncCtx controllerlib.Context, cre
 		return nil, err
 	}
 
+	if forceSyncValue := credIssuer.Annotations[forceSyncAnnotationKey]; forceSyncValue != c.lastForceSyncValue {
+		c.infoLog.Info("force sync was requested, so evaluating the impersonation proxy configuration again",
+			"credentialIssuer", klog.KObj(credIssuer),
+			"forceSync", forceSyncValue,
+		)
+		c.lastForceSyncValue = forceSyncValue
+		c.hasControlPlaneNodes = nil
+		c.controlPlaneNodesVisibleSince = time.Time{}
+	}
+
+	if c.hasControlPlaneNodes != nil && !c.enabledByAutoMode(impersonationSpec) {
+		// The stop delay only counts while auto mode is running the impersonator.
+		c.controlPlaneNodesVisibleSince = time.Time{}
+	}
+
 	// Make a live API call to avoid the cost of having an informer watch all node changes on the cluster,
 	// since there could be lots and we don't especially care about node changes.
 	// Once we have concluded that there is or is not a visible control plane, then cache that decision
-	// to avoid listing nodes very often.
-	if c.hasControlPlaneNodes == nil {
-		hasControlPlaneNodes, err := clusterhost.New(c.k8sClient).HasControlPlaneNodes(ctx)
+	// to avoid listing nodes very often, until a force sync is requested. The exception is while auto mode is
+	// running the impersonator, when the nodes are listed again periodically to notice a control plane which
+	// became visible.
+	if c.hasControlPlaneNodes == nil || c.shouldCheckControlPlaneNodesAgain(impersonationSpec) {
+		hasControlPlaneNodes, err := clusterhost.New(c.k8sClient, c.controlPlaneNodeSelectors...).
+			WithExcludedNodeRoles(c.excludedNodeRoles...).
+			HasControlPlaneNodes(ctx)
 		if err != nil {
 			return nil, err
 		}
-		c.hasControlPlaneNodes = &hasControlPlaneNodes
 		c.debugLog.Info("queried for control plane nodes", "foundControlPlaneNodes", hasControlPlaneNodes)
+		if c.hasControlPlaneNodes != nil {
+			hasControlPlaneNodes = c.controlPlaneNodesVisibleForStopDelay(syncCtx, hasControlPlaneNodes)
+		}
+		c.hasControlPlaneNodes = &hasControlPlaneNodes
+		c.lastControlPlaneNodesCheck = c.clock.Now()
 	}
 
+	wasRunning := c.serverStopCh != nil
 	if c.shouldHaveImpersonator(impersonationSpec) {
-		if err = c.ensureImpersonatorIsStarted(syncCtx); err != nil {
+		if err = c.ensureImpersonatorIsStarted(syncCtx, impersonationSpec); err != nil {
 			return nil, err
 		}
 	} else {
@@ -259,48 +440,78 @@ func (c *impersonatorConfigController) doSync(syncCtx controllerlib.Context, cre
 			return nil, err
 		}
 	}
-
-	if c.shouldHaveLoadBalancer(impersonationSpec) {
-		if err = c.ensureLoadBalancerIsStarted(ctx, impersonationSpec); err != nil {
-			return nil, err
-		}
-	} else {
-		if err = c.ensureLoadBalancerIsStopped(ctx); err != nil {
-			return nil, err
-		}
+	switch isRunning := c.serverStopCh != nil; {
+	case isRunning && !wasRunning:
+		c.recorder.Eventf(credIssuer, nil, v1.EventTypeNormal, "ImpersonationProxyStarted", "StartImpersonationProxy",
+			"Started impersonation proxy on port %d", c.impersonationProxyPort)
+		c.pendingStateChanges = append(c.pendingStateChanges, stateChangeStarted)
+	case !isRunning && wasRunning:
+		c.recorder.Eventf(credIssuer, nil, v1.EventTypeNormal, "ImpersonationProxyStopped", "StopImpersonationProxy",
+			"Stopped impersonation proxy")
+		c.pendingStateChanges = append(c.pendingStateChanges, stateChangeStopped)
 	}
 
-	if c.shouldHaveClusterIPService(impersonationSpec) {
-		if err = c.ensureClusterIPServiceIsStarted(ctx, impersonationSpec); err != nil {
-			return nil, err
-		}
-	} else {
-		if err = c.ensureClusterIPServiceIsStopped(ctx); err != nil {
+	if c.shouldManageServices(impersonationSpec) {
+		if err = c.ensureServices(ctx, impersonationSpec); err != nil {
 			return nil, err
 		}
 	}
 
-	nameInfo, err := c.findDesiredTLSCertificateName(impersonationSpec)
+	nameInfo, err := c.findDesiredTLSCertificateName(ctx, impersonationSpec)
 	if err != nil {
 		return nil, err
 	}
+	if nameInfo.hostnameResolutionFailed {
+		// Keep using the hostname in the cert for now, and try to resolve it again soon.
+		syncCtx.Queue.AddRateLimited(syncCtx.Key)
+	}
 
-	var impersonationCA *certauthority.CA
-	if c.shouldHaveImpersonator(impersonationSpec) {
-		if impersonationCA, err = c.ensureCASecretIsCreated(ctx); err != nil {
+	var caBundle []byte
+	switch {
+	case c.shouldHaveImpersonator(impersonationSpec) && c.tlsSecretRef != "":
+		referencedCert, err := c.loadReferencedTLSSecret()
+		if err != nil {
 			return nil, err
 		}
-		if err = c.ensureTLSSecret(ctx, nameInfo, impersonationCA); err != nil {
+		caBundle = referencedCert.caBundle
+		c.requeueForUserProvidedCertificateExpiry(syncCtx, referencedCert.notAfter)
+	case c.shouldHaveImpersonator(impersonationSpec):
+		userProvidedCert, err := c.loadUserProvidedTLSSecret()
+		if err != nil {
 			return nil, err
 		}
-	} else {
-		if err = c.ensureTLSSecretIsRemoved(ctx); err != nil {
+		if userProvidedCert != nil {
+			caBundle = userProvidedCert.caBundle
+			c.requeueForUserProvidedCertificateExpiry(syncCtx, userProvidedCert.notAfter)
+		} else {
+			impersonationCA, err := c.ensureCASecretIsCreated(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if err = c.ensureTLSSecret(ctx, nameInfo, impersonationCA); err != nil {
+				return nil, err
+			}
+			c.requeueForCertificateRotation(syncCtx, impersonationCA)
+			caBundle = impersonationCA.Bundle()
+		}
+	case c.tlsSecretRef != "":
+		// The default TLS Secret is not managed when a TLS Secret is referenced, and the referenced Secret is
+		// managed by someone else, so there is nothing to delete.
+		c.clearTLSSecret()
+	default:
+		if err = c.ensureGeneratedTLSSecretIsRemoved(ctx); err != nil {
 			return nil, err
 		}
 		c.clearTLSSecret()
 	}
 
-	credentialIssuerStrategyResult := c.doSyncResult(nameInfo, impersonationSpec, impersonationCA)
+	if c.caBundleConfigMapName != "" {
+		if err = c.ensureCABundleConfigMap(ctx, caBundle); err != nil {
+			return nil, err
+		}
+	}
+
+	credentialIssuerStrategyResult := c.doSyncResult(nameInfo, impersonationSpec, caBundle)
 
 	if c.shouldHaveImpersonator(impersonationSpec) {
 		if err = c.loadSignerCA(); err != nil {
@@ -313,6 +524,79 @@ func (c *impersonatorConfigController) doSync(syncCtx controllerlib.Context, cre
 	return credentialIssuerStrategyResult, nil
 }
 
+// ensureServices creates, updates, and deletes the Services of each type so that only the Services of the
+// desired types exist, which is usually only one Service, or a load balancer and an additional ClusterIP.
+func (c *impersonatorConfigController) ensureServices(ctx context.Context, config *v1alpha1.ImpersonationProxySpec) error {
+	if c.shouldHaveLoadBalancer(config) {
+		if err := c.ensureLoadBalancerIsStarted(ctx, config); err != nil {
+			return err
+		}
+	} else {
+		if err := c.ensureLoadBalancerIsStopped(ctx); err != nil {
+			return err
+		}
+	}
+
+	if c.shouldHaveClusterIPService(config) {
+		if err := c.ensureClusterIPServiceIsStarted(ctx, config); err != nil {
+			return err
+		}
+	} else {
+		if err := c.ensureClusterIPServiceIsStopped(ctx); err != nil {
+			return err
+		}
+	}
+
+	if c.shouldHaveNodePortService(config) {
+		if err := c.ensureNodePortServiceIsStarted(ctx, config); err != nil {
+			return err
+		}
+	} else {
+		if err := c.ensureNodePortServiceIsStopped(ctx); err != nil {
+			return err
+		}
+	}
+
+	return c.ensureOrphanedServicesAreRemoved(ctx)
+}
+
+// ensureOrphanedServicesAreRemoved deletes the Services which were created by this controller under names which
+// are no longer configured, e.g. a load balancer which would otherwise linger after its configured name changed.
+// The Services which have the currently configured names are handled by the other ensure functions.
+func (c *impersonatorConfigController) ensureOrphanedServicesAreRemoved(ctx context.Context) error {
+	ownedServices, err := c.servicesInformer.Lister().Services(c.namespace).List(
+		labels.SelectorFromSet(labels.Set{serviceOwnerLabelKey: serviceOwnerLabelValue}),
+	)
+	if err != nil {
+		return err
+	}
+	sort.Slice(ownedServices, func(i, j int) bool { return ownedServices[i].Name < ownedServices[j].Name })
+
+	configuredNames := sets.NewString(c.generatedLoadBalancerServiceName, c.generatedClusterIPServiceName, c.generatedNodePortServiceName)
+	for _, service := range ownedServices {
+		if configuredNames.Has(service.Name) {
+			continue
+		}
+		c.infoLog.Info("deleting orphaned service for impersonation proxy",
+			"serviceType", service.Spec.Type,
+			"service", klog.KObj(service),
+		)
+		err = c.k8sClient.CoreV1().Services(c.namespace).Delete(ctx, service.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{
+				UID:             &service.UID,
+				ResourceVersion: &service.ResourceVersion,
+			},
+		})
+		if err = utilerrors.FilterOut(err, k8serrors.IsNotFound); err != nil {
+			return err
+		}
+		if service.Spec.Type == v1.ServiceTypeLoadBalancer {
+			c.metrics.loadBalancerDeletes.Inc()
+		}
+	}
+	return nil
+}
+
 func (c *impersonatorConfigController) loadImpersonationProxyConfiguration(credIssuer *v1alpha1.CredentialIssuer) (*v1alpha1.ImpersonationProxySpec, error) {
 	// Make a copy of the spec since we got this object from informer cache.
 	spec := credIssuer.Spec.DeepCopy().ImpersonationProxy
@@ -340,6 +624,45 @@ func (c *impersonatorConfigController) enabledByAutoMode(config *v1alpha1.Impers
 	return config.Mode == v1alpha1.ImpersonationProxyModeAuto && !*c.hasControlPlaneNodes
 }
 
+// shouldCheckControlPlaneNodesAgain returns true when auto mode is running the impersonator and the nodes were last
+// listed a resync interval ago, or when the stop delay of the control plane nodes which were already found has ended.
+func (c *impersonatorConfigController) shouldCheckControlPlaneNodesAgain(config *v1alpha1.ImpersonationProxySpec) bool {
+	if !c.enabledByAutoMode(config) {
+		return false
+	}
+	nextCheck := c.lastControlPlaneNodesCheck.Add(c.resyncInterval)
+	if !c.controlPlaneNodesVisibleSince.IsZero() {
+		if stopTime := c.controlPlaneNodesVisibleSince.Add(c.autoModeStopDelay); stopTime.Before(nextCheck) {
+			nextCheck = stopTime
+		}
+	}
+	return !c.clock.Now().Before(nextCheck)
+}
+
+// controlPlaneNodesVisibleForStopDelay returns true once control plane nodes have been found by every check for at
+// least the stop delay, so that auto mode does not stop and start the impersonator again when a node listing only
+// intermittently includes them. Until then, it schedules another sync for the end of the stop delay.
+func (c *impersonatorConfigController) controlPlaneNodesVisibleForStopDelay(syncCtx controllerlib.Context, found bool) bool {
+	if !found {
+		c.controlPlaneNodesVisibleSince = time.Time{}
+		return false
+	}
+
+	now := c.clock.Now()
+	if c.controlPlaneNodesVisibleSince.IsZero() {
+		c.controlPlaneNodesVisibleSince = now
+	}
+	if remaining := c.controlPlaneNodesVisibleSince.Add(c.autoModeStopDelay).Sub(now); remaining > 0 {
+		c.infoLog.Info("control plane nodes are visible, so the impersonation proxy will be stopped if they stay visible",
+			"visibleSince", c.controlPlaneNodesVisibleSince, "remaining", remaining)
+		syncCtx.Queue.AddAfter(syncCtx.Key, remaining)
+		return false
+	}
+
+	c.controlPlaneNodesVisibleSince = time.Time{}
+	return true
+}
+
 func (c *impersonatorConfigController) disabledByAutoMode(config *v1alpha1.ImpersonationProxySpec) bool {
 	return config.Mode == v1alpha1.ImpersonationProxyModeAuto && *c.hasControlPlaneNodes
 }
@@ -348,12 +671,39 @@ func (c *impersonatorConfigController) disabledExplicitly(config *v1alpha1.Imper
 	return config.Mode == v1alpha1.ImpersonationProxyModeDisabled
 }
 
+// shouldManageServices returns false when the impersonation proxy is exposed by something outside the Concierge,
+// in which case the controller must leave all Services alone, including any which it created previously.
+func (c *impersonatorConfigController) shouldManageServices(config *v1alpha1.ImpersonationProxySpec) bool {
+	if autoModeSkipsLoadBalancer(config) {
+		return false
+	}
+	return !c.shouldHaveImpersonator(config) || config.Service.Type != v1alpha1.ImpersonationProxyServiceTypeExternal
+}
+
+// autoModeSkipsLoadBalancer returns true when auto mode was configured to never create the load balancer,
+// because the networking for the impersonation proxy is managed by someone else.
+func autoModeSkipsLoadBalancer(config *v1alpha1.ImpersonationProxySpec) bool {
+	return config.Mode == v1alpha1.ImpersonationProxyModeAuto &&
+		config.Service.Type == v1alpha1.ImpersonationProxyServiceTypeLoadBalancer &&
+		config.AutoMode != nil && config.AutoMode.CreateLoadBalancer != nil && !*config.AutoMode.CreateLoadBalancer
+}
+
 func (c *impersonatorConfigController) shouldHaveLoadBalancer(config *v1alpha1.ImpersonationProxySpec) bool {
 	return c.shouldHaveImpersonator(config) && config.Service.Type == v1alpha1.ImpersonationProxyServiceTypeLoadBalancer
 }
 
 func (c *impersonatorConfigController) shouldHaveClusterIPService(config *v1alpha1.ImpersonationProxySpec) bool {
-	return c.shouldHaveImpersonator(config) && config.Service.Type == v1alpha1.ImpersonationProxyServiceTypeClusterIP
+	return c.shouldHaveImpersonator(config) &&
+		(config.Service.Type == v1alpha1.ImpersonationProxyServiceTypeClusterIP || c.shouldHaveAdditionalClusterIPService(config))
+}
+
+// shouldHaveAdditionalClusterIPService returns true when the ClusterIP Service is served alongside the load balancer.
+func (c *impersonatorConfigController) shouldHaveAdditionalClusterIPService(config *v1alpha1.ImpersonationProxySpec) bool {
+	return c.shouldHaveLoadBalancer(config) && config.Service.AdditionalClusterIP
+}
+
+func (c *impersonatorConfigController) shouldHaveNodePortService(config *v1alpha1.ImpersonationProxySpec) bool {
+	return c.shouldHaveImpersonator(config) && config.Service.Type == v1alpha1.ImpersonationProxyServiceTypeNodePort
 }
 
 func (c *impersonatorConfigController) serviceExists(serviceName string) (bool, *v1.Service, error) {
@@ -380,7 +730,9 @@ func (c *impersonatorConfigController) tlsSecretExists() (bool, *v1.Secret, erro
 	return true, secret, nil
 }
 
-func (c *impersonatorConfigController) ensureImpersonatorIsStarted(syncCtx controllerlib.Context) error {
+func (c *impersonatorConfigController) ensureImpersonatorIsStarted(syncCtx controllerlib.Context, config *v1alpha1.ImpersonationProxySpec) error {
+	address := net.JoinHostPort(config.BindAddress, strconv.Itoa(c.impersonationProxyPort))
+
 	if c.serverStopCh != nil {
 		// The server was already started, but it could have died in the background, so make a non-blocking
 		// check to see if it has sent any errors on the errorCh.
@@ -399,14 +751,24 @@ func (c *impersonatorConfigController) ensureImpersonatorIsStarted(syncCtx contr
 			stoppingErr := c.ensureImpersonatorIsStopped(false)
 			return errors.NewAggregate([]error{runningErr, stoppingErr})
 		default:
-			// Seems like it is still running, so nothing to do.
-			return nil
+			// Seems like it is still running, so nothing to do unless it needs to listen on a different address.
+			if c.serverAddress == address {
+				return nil
+			}
+		}
+
+		c.infoLog.Info("restarting impersonation proxy because its bind address changed",
+			"oldAddress", c.serverAddress, "newAddress", address)
+		if err := c.ensureImpersonatorIsStopped(true); err != nil {
+			return err
 		}
 	}
 
-	c.infoLog.Info("starting impersonation proxy", "port", c.impersonationProxyPort)
+	c.infoLog.Info("starting impersonation proxy", "port", c.impersonationProxyPort, "address", address)
 	startImpersonatorFunc, err := c.impersonatorFunc(
-		c.impersonationProxyPort,
+		address,
+		c.requestTimeout,
+		c.shutdownDrainTimeout,
 		c.tlsServingCertDynamicCertProvider,
 		c.impersonationSigningCertProvider,
 	)
@@ -415,6 +777,8 @@ func (c *impersonatorConfigController) ensureImpersonatorIsStarted(syncCtx contr
 	}
 
 	c.serverStopCh = make(chan struct{})
+	c.serverAddress = address
+	c.metrics.listenerStarts.Inc()
 	// use a buffered channel so that startImpersonatorFunc can send
 	// on it without coordinating with the main controller go routine
 	c.errorCh = make(chan error, 1)
@@ -439,22 +803,31 @@ func (c *impersonatorConfigController) ensureImpersonatorIsStopped(shouldCloseEr
 		return nil
 	}
 
-	c.infoLog.Info("stopping impersonation proxy", "port", c.impersonationProxyPort)
+	c.infoLog.Info("stopping impersonation proxy", "port", c.impersonationProxyPort, "drainTimeout", c.shutdownDrainTimeout)
 	close(c.serverStopCh)
+	c.metrics.listenerStops.Inc()
 	stopErr := <-c.errorCh
+	switch {
+	case stopErr == nil:
+	case stderrors.Is(stopErr, impersonator.ErrDrainTimeout):
+		// The server was stopped, but some connections did not finish in time, so they were cut off.
+		stopErr = fmt.Errorf("impersonation proxy connections did not drain within %s: %w", c.shutdownDrainTimeout, stopErr)
+	default:
+		stopErr = fmt.Errorf("error while shutting down impersonation proxy: %w", stopErr)
+	}
 
 	if shouldCloseErrChan {
 		close(c.errorCh)
 	}
 
 	c.serverStopCh = nil
+	c.serverAddress = ""
 	c.errorCh = nil
 
 	return stopErr
 }
 
 func (c *impersonatorConfigController) ensureLoadBalancerIsStarted(ctx context.Context, config *v1alpha1.ImpersonationProxySpec) error {
-	appNameLabel := c.labels[appLabelKey]
 	loadBalancer := v1.Service{
 		Spec: v1.ServiceSpec{
 			Type: v1.ServiceTypeLoadBalancer,
@@ -465,8 +838,9 @@ func (c *impersonatorConfigController) ensureLoadBalancerIsStarted(ctx context.C
 					Protocol:   v1.ProtocolTCP,
 				},
 			},
-			LoadBalancerIP: config.Service.LoadBalancerIP,
-			Selector:       map[string]string{appLabelKey: appNameLabel},
+			LoadBalancerIP:           config.Service.LoadBalancerIP,
+			LoadBalancerSourceRanges: config.Service.LoadBalancerSourceRanges,
+			Selector:                 c.serviceSelector,
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        c.generatedLoadBalancerServiceName,
@@ -496,11 +870,18 @@ func (c *impersonatorConfigController) ensureLoadBalancerIsStopped(ctx context.C
 			ResourceVersion: &service.ResourceVersion,
 		},
 	})
+	if err == nil {
+		c.metrics.loadBalancerDeletes.Inc()
+	}
 	return utilerrors.FilterOut(err, k8serrors.IsNotFound)
 }
 
 func (c *impersonatorConfigController) ensureClusterIPServiceIsStarted(ctx context.Context, config *v1alpha1.ImpersonationProxySpec) error {
-	appNameLabel := c.labels[appLabelKey]
+	annotations := config.Service.Annotations
+	if c.shouldHaveAdditionalClusterIPService(config) {
+		// The annotations are meant for the load balancer, e.g. to configure it with the cloud provider.
+		annotations = nil
+	}
 	clusterIP := v1.Service{
 		Spec: v1.ServiceSpec{
 			Type: v1.ServiceTypeClusterIP,
@@ -511,13 +892,13 @@ func (c *impersonatorConfigController) ensureClusterIPServiceIsStarted(ctx conte
 					Protocol:   v1.ProtocolTCP,
 				},
 			},
-			Selector: map[string]string{appLabelKey: appNameLabel},
+			Selector: c.serviceSelector,
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        c.generatedClusterIPServiceName,
 			Namespace:   c.namespace,
 			Labels:      c.labels,
-			Annotations: config.Service.Annotations,
+			Annotations: annotations,
 		},
 	}
 	return c.createOrUpdateService(ctx, &clusterIP)
@@ -544,6 +925,50 @@ func (c *impersonatorConfigController) ensureClusterIPServiceIsStopped(ctx conte
 	return utilerrors.FilterOut(err, k8serrors.IsNotFound)
 }
 
+func (c *impersonatorConfigController) ensureNodePortServiceIsStarted(ctx context.Context, config *v1alpha1.ImpersonationProxySpec) error {
+	nodePort := v1.Service{
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeNodePort,
+			Ports: []v1.ServicePort{
+				{
+					TargetPort: intstr.FromInt(c.impersonationProxyPort),
+					Port:       defaultHTTPSPort,
+					Protocol:   v1.ProtocolTCP,
+				},
+			},
+			Selector: c.serviceSelector,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        c.generatedNodePortServiceName,
+			Namespace:   c.namespace,
+			Labels:      c.labels,
+			Annotations: config.Service.Annotations,
+		},
+	}
+	return c.createOrUpdateService(ctx, &nodePort)
+}
+
+func (c *impersonatorConfigController) ensureNodePortServiceIsStopped(ctx context.Context) error {
+	running, service, err := c.serviceExists(c.generatedNodePortServiceName)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return nil
+	}
+
+	c.infoLog.Info("deleting node port for impersonation proxy",
+		"service", klog.KRef(c.namespace, c.generatedNodePortServiceName),
+	)
+	err = c.k8sClient.CoreV1().Services(c.namespace).Delete(ctx, c.generatedNodePortServiceName, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{
+			UID:             &service.UID,
+			ResourceVersion: &service.ResourceVersion,
+		},
+	})
+	return utilerrors.FilterOut(err, k8serrors.IsNotFound)
+}
+
 func (c *impersonatorConfigController) createOrUpdateService(ctx context.Context, desiredService *v1.Service) error {
 	log := c.infoLog.WithValues("serviceType", desiredService.Spec.Type, "service", klog.KObj(desiredService))
 
@@ -571,8 +996,18 @@ func (c *impersonatorConfigController) createOrUpdateService(ctx context.Context
 	existingService, err := c.servicesInformer.Lister().Services(c.namespace).Get(desiredService.Name)
 	if k8serrors.IsNotFound(err) {
 		log.Info("creating service for impersonation proxy")
-		_, err := c.k8sClient.CoreV1().Services(c.namespace).Create(ctx, desiredService, metav1.CreateOptions{})
-		return err
+		desiredService.Labels = c.labelsForCreate(desiredService.Labels)
+		desiredService.Labels = withServiceOwnerLabel(desiredService.Labels)
+		createdService, err := c.k8sClient.CoreV1().Services(c.namespace).Create(ctx, desiredService, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		c.recorder.Eventf(createdService, nil, v1.EventTypeNormal, "ServiceCreated", "CreateService",
+			"Created %s Service for impersonation proxy", desiredService.Spec.Type)
+		if desiredService.Spec.Type == v1.ServiceTypeLoadBalancer {
+			c.metrics.loadBalancerCreates.Inc()
+		}
+		return nil
 	}
 	if err != nil {
 		return err
@@ -580,8 +1015,18 @@ func (c *impersonatorConfigController) createOrUpdateService(ctx context.Context
 
 	// The Service already exists, so update only the specific fields that are meaningfully part of our desired state.
 	updatedService := existingService.DeepCopy()
-	updatedService.ObjectMeta.Labels = desiredService.ObjectMeta.Labels
+	// Only reconcile the labels which are owned by this controller, so that labels which were added by someone
+	// else, including the extra labels which were added when the Service was created, are left alone. The owner
+	// label is also added to a Service which was created before this controller started adding it, so that the
+	// Service can still be found and deleted after its configured name changes.
+	if updatedService.Labels == nil {
+		updatedService.Labels = map[string]string{}
+	}
+	for k, v := range withServiceOwnerLabel(desiredService.Labels) {
+		updatedService.Labels[k] = v
+	}
 	updatedService.Spec.LoadBalancerIP = desiredService.Spec.LoadBalancerIP
+	updatedService.Spec.LoadBalancerSourceRanges = desiredService.Spec.LoadBalancerSourceRanges
 	updatedService.Spec.Type = desiredService.Spec.Type
 	updatedService.Spec.Selector = desiredService.Spec.Selector
 
@@ -634,6 +1079,106 @@ func (c *impersonatorConfigController) createOrUpdateService(ctx context.Context
 	return err
 }
 
+// userProvidedCertificate describes the certificate from a user-provided TLS Secret which is being served.
+type userProvidedCertificate struct {
+	// caBundle is the CA bundle which clients should use to verify the certificate.
+	caBundle []byte
+	notAfter time.Time
+}
+
+// loadUserProvidedTLSSecret loads the certificate from the TLS Secret when the Secret has the
+// userProvidedTLSAnnotationKey annotation and it contains a valid certificate and private key which have not
+// expired. When it returns nil without an error, a certificate should be issued as usual instead, in which case
+// ensureTLSSecret will replace a user-provided Secret which is not usable.
+func (c *impersonatorConfigController) loadUserProvidedTLSSecret() (*userProvidedCertificate, error) {
+	secret, err := c.secretsInformer.Lister().Secrets(c.namespace).Get(c.tlsSecretName)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, userProvided := secret.Annotations[userProvidedTLSAnnotationKey]; !userProvided {
+		return nil, nil
+	}
+
+	certPEM := secret.Data[v1.TLSCertKey]
+	keyPEM := secret.Data[v1.TLSPrivateKeyKey]
+	keyPair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		c.infoLog.Error(err, "found invalid certificate or private key PEM data in user-provided TLS Secret, so generating a certificate instead",
+			"secret", klog.KObj(secret),
+		)
+		return nil, nil
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		c.infoLog.Error(err, "found invalid certificate in user-provided TLS Secret, so generating a certificate instead",
+			"secret", klog.KObj(secret),
+		)
+		return nil, nil
+	}
+	if !c.clock.Now().Before(cert.NotAfter) {
+		c.infoLog.Info("found expired certificate in user-provided TLS Secret, so generating a certificate instead",
+			"notAfter", cert.NotAfter,
+			"secret", klog.KObj(secret),
+		)
+		return nil, nil
+	}
+
+	if err = c.loadTLSCertFromSecret(secret); err != nil {
+		return nil, err
+	}
+
+	return &userProvidedCertificate{caBundle: tlsSecretCABundle(secret), notAfter: cert.NotAfter}, nil
+}
+
+// loadReferencedTLSSecret loads the certificate from the externally managed TLS Secret named by tlsSecretRef.
+// Unlike a user-provided certificate in the default TLS Secret, there is no fallback to issuing a certificate,
+// so a missing or unusable Secret is an error until it is fixed by whoever manages it.
+func (c *impersonatorConfigController) loadReferencedTLSSecret() (*userProvidedCertificate, error) {
+	secret, err := c.secretsInformer.Lister().Secrets(c.namespace).Get(c.tlsSecretRef)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the referenced TLS Secret %q: %w", c.tlsSecretRef, err)
+	}
+
+	keyPair, err := tls.X509KeyPair(secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("found invalid certificate or private key PEM data in the referenced TLS Secret %q: %w", c.tlsSecretRef, err)
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("found invalid certificate in the referenced TLS Secret %q: %w", c.tlsSecretRef, err)
+	}
+	if !c.clock.Now().Before(cert.NotAfter) {
+		return nil, fmt.Errorf("found expired certificate in the referenced TLS Secret %q: it expired at %s",
+			c.tlsSecretRef, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	if err = c.loadTLSCertFromSecret(secret); err != nil {
+		return nil, err
+	}
+
+	return &userProvidedCertificate{caBundle: tlsSecretCABundle(secret), notAfter: cert.NotAfter}, nil
+}
+
+// tlsSecretCABundle returns the CA bundle from a TLS Secret which was not issued by this controller, when it has one,
+// or else the certificate itself, so that clients can verify the certificate by trusting it directly.
+func tlsSecretCABundle(secret *v1.Secret) []byte {
+	if caBundle := secret.Data[caCrtKey]; len(caBundle) > 0 {
+		return caBundle
+	}
+	return secret.Data[v1.TLSCertKey]
+}
+
+// requeueForUserProvidedCertificateExpiry schedules a sync for when the user-provided or referenced TLS serving
+// certificate expires. Unless it has been replaced by then, an expired user-provided certificate is replaced by an
+// issued certificate, and an expired referenced certificate is reported as an error.
+func (c *impersonatorConfigController) requeueForUserProvidedCertificateExpiry(syncCtx controllerlib.Context, notAfter time.Time) {
+	c.debugLog.Info("scheduling sync for expiry of user-provided impersonation proxy certificate", "notAfter", notAfter)
+	syncCtx.Queue.AddAfter(syncCtx.Key, notAfter.Sub(c.clock.Now()))
+}
+
 func (c *impersonatorConfigController) ensureTLSSecret(ctx context.Context, nameInfo *certNameInfo, ca *certauthority.CA) error {
 	secretFromInformer, err := c.secretsInformer.Lister().Secrets(c.namespace).Get(c.tlsSecretName)
 	notFound := k8serrors.IsNotFound(err)
@@ -706,6 +1251,17 @@ func (c *impersonatorConfigController) deleteTLSSecretWhenCertificateDoesNotMatc
 		return true, nil
 	}
 
+	if c.certificateShouldBeRotated(actualCertFromSecret) {
+		c.infoLog.Info("TLS serving certificate for impersonation proxy is due for rotation",
+			"notAfter", actualCertFromSecret.NotAfter,
+			"secret", klog.KObj(secret),
+		)
+		if err = c.ensureTLSSecretIsRemoved(ctx); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
 	if !nameInfo.ready {
 		// We currently have a secret but we are waiting for a load balancer to be assigned an ingress, so
 		// our current secret must be old/unwanted.
@@ -715,17 +1271,18 @@ func (c *impersonatorConfigController) deleteTLSSecretWhenCertificateDoesNotMatc
 		return true, nil
 	}
 
+	desiredIPs, desiredHostnames := c.desiredCertNames(nameInfo)
 	actualIPs := actualCertFromSecret.IPAddresses
 	actualHostnames := actualCertFromSecret.DNSNames
 	c.infoLog.Info("checking TLS certificate names",
-		"desiredIPs", nameInfo.selectedIPs,
-		"desiredHostname", nameInfo.selectedHostname,
+		"desiredIPs", desiredIPs,
+		"desiredHostnames", desiredHostnames,
 		"actualIPs", actualIPs,
 		"actualHostnames", actualHostnames,
 		"secret", klog.KObj(secret),
 	)
 
-	if certHostnameAndIPMatchDesiredState(nameInfo.selectedIPs, actualIPs, nameInfo.selectedHostname, actualHostnames) {
+	if certHostnameAndIPMatchDesiredState(desiredIPs, actualIPs, desiredHostnames, actualHostnames) {
 		// The cert already matches the desired state, so there is no need to delete/recreate it.
 		return false, nil
 	}
@@ -736,21 +1293,63 @@ func (c *impersonatorConfigController) deleteTLSSecretWhenCertificateDoesNotMatc
 	return true, nil
 }
 
-func certHostnameAndIPMatchDesiredState(desiredIPs []net.IP, actualIPs []net.IP, desiredHostname string, actualHostnames []string) bool {
-	if len(desiredIPs) > 0 && len(actualIPs) > 0 && len(actualIPs) == len(desiredIPs) && len(actualHostnames) == 0 {
-		for i := range desiredIPs {
-			if !actualIPs[i].Equal(desiredIPs[i]) {
-				return false
-			}
+// desiredCertNames returns the IPs and hostnames which the TLS serving cert should have, which are the selected
+// names followed by the configured additional names which were not already selected.
+func (c *impersonatorConfigController) desiredCertNames(nameInfo *certNameInfo) ([]net.IP, []string) {
+	ips := append([]net.IP{}, nameInfo.selectedIPs...)
+	for _, additionalIP := range c.additionalIPs {
+		if !containsIP(ips, additionalIP) {
+			ips = append(ips, additionalIP)
+		}
+	}
+
+	var hostnames []string
+	if nameInfo.selectedHostname != "" {
+		hostnames = append(hostnames, nameInfo.selectedHostname)
+	}
+	seenHostnames := sets.NewString(hostnames...)
+	for _, additionalHostname := range c.additionalHostnames {
+		if !seenHostnames.Has(additionalHostname) {
+			seenHostnames.Insert(additionalHostname)
+			hostnames = append(hostnames, additionalHostname)
 		}
-		return true
 	}
-	if desiredHostname != "" && len(actualHostnames) == 1 && desiredHostname == actualHostnames[0] && len(actualIPs) == 0 {
-		return true
+
+	return ips, hostnames
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, existingIP := range ips {
+		if existingIP.Equal(ip) {
+			return true
+		}
 	}
 	return false
 }
 
+func certHostnameAndIPMatchDesiredState(desiredIPs []net.IP, actualIPs []net.IP, desiredHostnames []string, actualHostnames []string) bool {
+	if len(desiredIPs) == 0 && len(desiredHostnames) == 0 {
+		return false
+	}
+	if len(actualIPs) != len(desiredIPs) {
+		return false
+	}
+	for i := range desiredIPs {
+		if !actualIPs[i].Equal(desiredIPs[i]) {
+			return false
+		}
+	}
+	if len(actualHostnames) != len(desiredHostnames) {
+		return false
+	}
+	for i := range desiredHostnames {
+		if actualHostnames[i] != desiredHostnames[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *impersonatorConfigController) ensureTLSSecretIsCreatedAndLoaded(ctx context.Context, nameInfo *certNameInfo, secret *v1.Secret, ca *certauthority.CA) error {
 	if secret != nil {
 		err := c.loadTLSCertFromSecret(secret)
@@ -764,7 +1363,8 @@ func (c *impersonatorConfigController) ensureTLSSecretIsCreatedAndLoaded(ctx con
 		return nil
 	}
 
-	newTLSSecret, err := c.createNewTLSSecret(ctx, ca, nameInfo.selectedIPs, nameInfo.selectedHostname)
+	ips, hostnames := c.desiredCertNames(nameInfo)
+	newTLSSecret, err := c.createNewTLSSecret(ctx, ca, ips, hostnames)
 	if err != nil {
 		return err
 	}
@@ -789,7 +1389,10 @@ func (c *impersonatorConfigController) ensureCASecretIsCreated(ctx context.Conte
 	} else {
 		crtBytes := caSecret.Data[caCrtKey]
 		keyBytes := caSecret.Data[caKeyKey]
-		impersonationCA, err = certauthority.Load(string(crtBytes), string(keyBytes))
+		impersonationCA, err = certauthority.LoadWithKeyAlgorithm(string(crtBytes), string(keyBytes), c.caKeyAlgorithm)
+		if err == nil && c.caCertificateShouldBeReplaced(crtBytes) {
+			impersonationCA, err = c.rotateCASecret(ctx, caSecret)
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -798,13 +1401,104 @@ func (c *impersonatorConfigController) ensureCASecretIsCreated(ctx context.Conte
 	return impersonationCA, nil
 }
 
-func (c *impersonatorConfigController) createCASecret(ctx context.Context) (*certauthority.CA, error) {
-	impersonationCA, err := certauthority.New(caCommonName, approximatelyOneHundredYears)
+// certificateShouldBeRotated returns true once the certificate has entered its rotation window, i.e. the final
+// rotationWindowPercentage of its validity period, which leaves time to roll out its replacement before it expires.
+func (c *impersonatorConfigController) certificateShouldBeRotated(cert *x509.Certificate) bool {
+	return !c.clock.Now().Before(c.certificateRotationTime(cert))
+}
+
+func (c *impersonatorConfigController) certificateRotationTime(cert *x509.Certificate) time.Time {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotAfter.Add(-lifetime / 100 * time.Duration(c.rotationWindowPercentage))
+}
+
+// requeueForCertificateRotation schedules a sync for when the CA or the TLS serving certificate enters its rotation
+// window. Otherwise, nothing might trigger a sync to rotate them until after they have expired.
+func (c *impersonatorConfigController) requeueForCertificateRotation(syncCtx controllerlib.Context, ca *certauthority.CA) {
+	// The TLS serving cert may not have been issued yet, e.g. while waiting for a load balancer to get an ingress.
+	tlsCertPEM, _ := c.tlsServingCertDynamicCertProvider.CurrentCertKeyContent()
+
+	var rotationTime time.Time
+	for _, certPEM := range [][]byte{ca.Bundle(), tlsCertPEM} {
+		block, _ := pem.Decode(certPEM)
+		if block == nil {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if certRotationTime := c.certificateRotationTime(cert); rotationTime.IsZero() || certRotationTime.Before(rotationTime) {
+			rotationTime = certRotationTime
+		}
+	}
+	if rotationTime.IsZero() {
+		return
+	}
+
+	c.debugLog.Info("scheduling sync to rotate impersonation proxy certificates", "rotationTime", rotationTime)
+	syncCtx.Queue.AddAfter(syncCtx.Key, rotationTime.Sub(c.clock.Now()))
+}
+
+// caCertificateShouldBeReplaced returns true when the CA certificate has entered its rotation window, or when its
+// subject or key algorithm does not match the configured one, e.g. because the configured common name was changed.
+func (c *impersonatorConfigController) caCertificateShouldBeReplaced(crtBytes []byte) bool {
+	block, _ := pem.Decode(crtBytes)
+	if block == nil {
+		return false // certauthority.Load would have already failed in this case
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false // certauthority.Load would have already failed in this case
+	}
+	return c.certificateShouldBeRotated(caCert) ||
+		!caSubjectMatches(caCert.Subject, c.caSubject) ||
+		certauthority.KeyAlgorithmOf(caCert.PublicKey) != c.caKeyAlgorithm
+}
+
+func caSubjectMatches(actual, desired pkix.Name) bool {
+	return actual.CommonName == desired.CommonName &&
+		sets.NewString(actual.Organization...).Equal(sets.NewString(desired.Organization...))
+}
+
+func (c *impersonatorConfigController) rotateCASecret(ctx context.Context, caSecret *v1.Secret) (*certauthority.CA, error) {
+	impersonationCA, data, err := c.newCASecretData()
+	if err != nil {
+		return nil, err
+	}
+
+	updatedSecret := caSecret.DeepCopy()
+	updatedSecret.Data = data
+
+	c.infoLog.Info("rotating CA certificates for impersonation proxy",
+		"secret", klog.KObj(updatedSecret),
+	)
+	if _, err = c.k8sClient.CoreV1().Secrets(c.namespace).Update(ctx, updatedSecret, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+
+	return impersonationCA, nil
+}
+
+func (c *impersonatorConfigController) newCASecretData() (*certauthority.CA, map[string][]byte, error) {
+	impersonationCA, err := certauthority.NewWithKeyAlgorithm(c.caSubject, c.caCertificateDuration, c.caKeyAlgorithm)
 	if err != nil {
-		return nil, fmt.Errorf("could not create impersonation CA: %w", err)
+		return nil, nil, fmt.Errorf("could not create impersonation CA: %w", err)
 	}
 
 	caPrivateKeyPEM, err := impersonationCA.PrivateKeyToPEM()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return impersonationCA, map[string][]byte{
+		caCrtKey: impersonationCA.Bundle(),
+		caKeyKey: caPrivateKeyPEM,
+	}, nil
+}
+
+func (c *impersonatorConfigController) createCASecret(ctx context.Context) (*certauthority.CA, error) {
+	impersonationCA, data, err := c.newCASecretData()
 	if err != nil {
 		return nil, err
 	}
@@ -813,12 +1507,9 @@ func (c *impersonatorConfigController) createCASecret(ctx context.Context) (*cer
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      c.caSecretName,
 			Namespace: c.namespace,
-			Labels:    c.labels,
-		},
-		Data: map[string][]byte{
-			caCrtKey: impersonationCA.Bundle(),
-			caKeyKey: caPrivateKeyPEM,
+			Labels:    c.labelsForCreate(c.labels),
 		},
+		Data: data,
 		Type: v1.SecretTypeOpaque,
 	}
 
@@ -832,13 +1523,30 @@ func (c *impersonatorConfigController) createCASecret(ctx context.Context) (*cer
 	return impersonationCA, nil
 }
 
-func (c *impersonatorConfigController) findDesiredTLSCertificateName(config *v1alpha1.ImpersonationProxySpec) (*certNameInfo, error) {
+func (c *impersonatorConfigController) findDesiredTLSCertificateName(ctx context.Context, config *v1alpha1.ImpersonationProxySpec) (*certNameInfo, error) {
+	nameInfo, err := c.findDesiredTLSCertificateNameForAdvertisedEndpoint(ctx, config)
+	if err != nil || !nameInfo.ready || !c.shouldHaveAdditionalClusterIPService(config) {
+		return nameInfo, err
+	}
+
+	// The cert must also be valid for the additional ClusterIP Service, since in-cluster clients may use it.
+	clusterIPNameInfo, err := c.findTLSCertificateNameFromClusterIPService()
+	if err != nil || !clusterIPNameInfo.ready {
+		return clusterIPNameInfo, err
+	}
+	nameInfo.selectedIPs = append(nameInfo.selectedIPs, clusterIPNameInfo.selectedIPs...)
+	return nameInfo, nil
+}
+
+func (c *impersonatorConfigController) findDesiredTLSCertificateNameForAdvertisedEndpoint(ctx context.Context, config *v1alpha1.ImpersonationProxySpec) (*certNameInfo, error) {
 	if config.ExternalEndpoint != "" {
 		return c.findTLSCertificateNameFromEndpointConfig(config), nil
 	} else if config.Service.Type == v1alpha1.ImpersonationProxyServiceTypeClusterIP {
 		return c.findTLSCertificateNameFromClusterIPService()
+	} else if config.Service.Type == v1alpha1.ImpersonationProxyServiceTypeNodePort {
+		return c.findTLSCertificateNameFromNodePortService(ctx)
 	}
-	return c.findTLSCertificateNameFromLoadBalancer()
+	return c.findTLSCertificateNameFromLoadBalancer(ctx, config)
 }
 
 func (c *impersonatorConfigController) findTLSCertificateNameFromEndpointConfig(config *v1alpha1.ImpersonationProxySpec) *certNameInfo {
@@ -851,7 +1559,7 @@ func (c *impersonatorConfigController) findTLSCertificateNameFromEndpointConfig(
 	return &certNameInfo{ready: true, selectedHostname: addr.Host, clientEndpoint: endpoint}
 }
 
-func (c *impersonatorConfigController) findTLSCertificateNameFromLoadBalancer() (*certNameInfo, error) {
+func (c *impersonatorConfigController) findTLSCertificateNameFromLoadBalancer(ctx context.Context, config *v1alpha1.ImpersonationProxySpec) (*certNameInfo, error) {
 	lb, err := c.servicesInformer.Lister().Services(c.namespace).Get(c.generatedLoadBalancerServiceName)
 	notFound := k8serrors.IsNotFound(err)
 	if notFound {
@@ -871,20 +1579,54 @@ func (c *impersonatorConfigController) findTLSCertificateNameFromLoadBalancer()
 	for _, ingress := range ingresses {
 		hostname := ingress.Hostname
 		if hostname != "" {
-			return &certNameInfo{ready: true, selectedHostname: hostname, clientEndpoint: hostname}, nil
+			nameInfo := &certNameInfo{ready: true, selectedHostname: hostname, clientEndpoint: hostname}
+			if config.Service.ResolveEndpointHostname {
+				c.resolveSelectedHostname(ctx, nameInfo)
+			}
+			return nameInfo, nil
 		}
 	}
 	for _, ingress := range ingresses {
 		ip := ingress.IP
 		parsedIP := net.ParseIP(ip)
 		if parsedIP != nil {
-			return &certNameInfo{ready: true, selectedIPs: []net.IP{parsedIP}, clientEndpoint: ip}, nil
+			return &certNameInfo{ready: true, selectedIPs: []net.IP{parsedIP}, clientEndpoint: ipEndpoint(parsedIP)}, nil
 		}
 	}
 
 	return nil, fmt.Errorf("could not find valid IP addresses or hostnames from load balancer %s/%s", c.namespace, lb.Name)
 }
 
+// resolveSelectedHostname adds the IP addresses of the selected hostname to the cert names, for clients which connect
+// to the impersonation proxy by IP address. When the lookup fails, the cert names are left with only the hostname.
+func (c *impersonatorConfigController) resolveSelectedHostname(ctx context.Context, nameInfo *certNameInfo) {
+	lookupCtx, cancel := context.WithTimeout(ctx, hostnameResolutionTimeout)
+	defer cancel()
+
+	ips, err := c.lookupIP(lookupCtx, nameInfo.selectedHostname)
+	if err == nil && len(ips) == 0 {
+		err = stderrors.New("no IP addresses found")
+	}
+	if err != nil {
+		c.infoLog.Error(err, "could not resolve the hostname of the load balancer for impersonation proxy, so the tls cert will only include the hostname for now",
+			"hostname", nameInfo.selectedHostname)
+		nameInfo.hostnameResolutionFailed = true
+		return
+	}
+
+	// Sort the addresses, since DNS servers may return them in any order and the cert should not change because of that.
+	sorted := make([]net.IP, 0, len(ips))
+	seen := sets.NewString()
+	for _, ip := range ips {
+		if !seen.Has(ip.String()) {
+			seen.Insert(ip.String())
+			sorted = append(sorted, ip)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i].To16(), sorted[j].To16()) < 0 })
+	nameInfo.selectedIPs = append(nameInfo.selectedIPs, sorted...)
+}
+
 func (c *impersonatorConfigController) findTLSCertificateNameFromClusterIPService() (*certNameInfo, error) {
 	clusterIP, err := c.servicesInformer.Lister().Services(c.namespace).Get(c.generatedClusterIPServiceName)
 	notFound := k8serrors.IsNotFound(err)
@@ -907,18 +1649,88 @@ func (c *impersonatorConfigController) findTLSCertificateNameFromClusterIPServic
 		} else {
 			parsedIPs = []net.IP{net.ParseIP(ip)}
 		}
-		return &certNameInfo{ready: true, selectedIPs: parsedIPs, clientEndpoint: ip}, nil
+		endpoint := ip
+		if parsedIP := net.ParseIP(ip); parsedIP != nil {
+			endpoint = ipEndpoint(parsedIP)
+		}
+		return &certNameInfo{ready: true, selectedIPs: parsedIPs, clientEndpoint: endpoint}, nil
 	}
 	return &certNameInfo{ready: false}, nil
 }
 
-func (c *impersonatorConfigController) createNewTLSSecret(ctx context.Context, ca *certauthority.CA, ips []net.IP, hostname string) (*v1.Secret, error) {
-	var hostnames []string
-	if hostname != "" {
-		hostnames = []string{hostname}
+// ipEndpoint formats the IP as the host of the endpoint which is advertised to clients, which must be enclosed in
+// brackets when it is an IPv6 address, e.g. https://[fd00::1].
+func ipEndpoint(ip net.IP) string {
+	if ip.To4() == nil {
+		return "[" + ip.String() + "]"
 	}
+	return ip.String()
+}
 
-	impersonationCert, err := ca.IssueServerCert(hostnames, ips, approximatelyOneHundredYears)
+func (c *impersonatorConfigController) findTLSCertificateNameFromNodePortService(ctx context.Context) (*certNameInfo, error) {
+	nodePortService, err := c.servicesInformer.Lister().Services(c.namespace).Get(c.generatedNodePortServiceName)
+	notFound := k8serrors.IsNotFound(err)
+	if notFound {
+		// We aren't ready and will try again later in this case.
+		return &certNameInfo{ready: false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var nodePort int32
+	for _, port := range nodePortService.Spec.Ports {
+		if port.NodePort != 0 {
+			nodePort = port.NodePort
+			break
+		}
+	}
+	if nodePort == 0 {
+		c.infoLog.Info("node port service for impersonation proxy does not have a node port yet, so skipping tls cert generation while we wait",
+			"service", klog.KObj(nodePortService),
+		)
+		return &certNameInfo{ready: false}, nil
+	}
+
+	// Make a live API call for the same reason that we do when looking for control plane nodes.
+	nodes, err := c.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing nodes: %w", err)
+	}
+	nodeIP := selectNodeIP(nodes.Items)
+	if nodeIP == nil {
+		c.infoLog.Info("could not find any node with an IP address, so skipping tls cert generation while we wait",
+			"service", klog.KObj(nodePortService),
+		)
+		return &certNameInfo{ready: false}, nil
+	}
+	endpoint := net.JoinHostPort(nodeIP.String(), fmt.Sprintf("%d", nodePort))
+	return &certNameInfo{ready: true, selectedIPs: []net.IP{nodeIP}, clientEndpoint: endpoint}, nil
+}
+
+// selectNodeIP chooses which node IP will be advertised to clients of a NodePort Service. External IPs are preferred
+// over internal IPs, and nodes are considered in order of their names so the choice is stable across syncs.
+func selectNodeIP(nodes []v1.Node) net.IP {
+	sortedNodes := make([]v1.Node, len(nodes))
+	copy(sortedNodes, nodes)
+	sort.Slice(sortedNodes, func(i, j int) bool { return sortedNodes[i].Name < sortedNodes[j].Name })
+
+	for _, addressType := range []v1.NodeAddressType{v1.NodeExternalIP, v1.NodeInternalIP} {
+		for _, node := range sortedNodes {
+			for _, address := range node.Status.Addresses {
+				if address.Type != addressType {
+					continue
+				}
+				if ip := net.ParseIP(address.Address); ip != nil {
+					return ip
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *impersonatorConfigController) createNewTLSSecret(ctx context.Context, ca *certauthority.CA, ips []net.IP, hostnames []string) (*v1.Secret, error) {
+	impersonationCert, err := ca.IssueServerCert(hostnames, ips, c.certificateDuration)
 	if err != nil {
 		return nil, fmt.Errorf("could not create impersonation cert: %w", err)
 	}
@@ -932,7 +1744,7 @@ func (c *impersonatorConfigController) createNewTLSSecret(ctx context.Context, c
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      c.tlsSecretName,
 			Namespace: c.namespace,
-			Labels:    c.labels,
+			Labels:    c.labelsForCreate(c.labels),
 		},
 		Data: map[string][]byte{
 			v1.TLSPrivateKeyKey: keyPEM,
@@ -946,7 +1758,15 @@ func (c *impersonatorConfigController) createNewTLSSecret(ctx context.Context, c
 		"hostnames", hostnames,
 		"secret", klog.KObj(newTLSSecret),
 	)
-	return c.k8sClient.CoreV1().Secrets(c.namespace).Create(ctx, newTLSSecret, metav1.CreateOptions{})
+	createdTLSSecret, err := c.k8sClient.CoreV1().Secrets(c.namespace).Create(ctx, newTLSSecret, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	c.metrics.certIssuances.Inc()
+	c.recorder.Eventf(createdTLSSecret, nil, v1.EventTypeNormal, "CertificateIssued", "IssueCertificate",
+		"Issued TLS serving certificate for impersonation proxy with IPs %v and hostnames %v", ips, hostnames)
+	c.pendingStateChanges = append(c.pendingStateChanges, stateChangeCertificateIssued)
+	return createdTLSSecret, nil
 }
 
 func (c *impersonatorConfigController) loadTLSCertFromSecret(tlsSecret *v1.Secret) error {
@@ -987,6 +1807,109 @@ func (c *impersonatorConfigController) ensureTLSSecretIsRemoved(ctx context.Cont
 	return utilerrors.FilterOut(err, k8serrors.IsNotFound)
 }
 
+// ensureGeneratedTLSSecretIsRemoved deletes the TLS Secret, unless it was provided by the user.
+func (c *impersonatorConfigController) ensureGeneratedTLSSecretIsRemoved(ctx context.Context) error {
+	tlsSecretExists, secret, err := c.tlsSecretExists()
+	if err != nil {
+		return err
+	}
+	if tlsSecretExists {
+		if _, userProvided := secret.Annotations[userProvidedTLSAnnotationKey]; userProvided {
+			return nil
+		}
+	}
+	return c.ensureTLSSecretIsRemoved(ctx)
+}
+
+// ensureCABundleConfigMap publishes the CA bundle which is advertised to clients in the ConfigMap, so that other tools
+// can read it. The ConfigMap is deleted when there is no CA bundle, i.e. when the impersonator should not run.
+func (c *impersonatorConfigController) ensureCABundleConfigMap(ctx context.Context, caBundle []byte) error {
+	configMap, err := c.configMapsInformer.Lister().ConfigMaps(c.namespace).Get(c.caBundleConfigMapName)
+	notFound := k8serrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return err
+	}
+
+	if len(caBundle) == 0 {
+		if notFound {
+			return nil
+		}
+		c.infoLog.Info("deleting CA bundle ConfigMap for impersonation proxy",
+			"configmap", klog.KObj(configMap),
+		)
+		err = c.k8sClient.CoreV1().ConfigMaps(c.namespace).Delete(ctx, c.caBundleConfigMapName, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{
+				UID:             &configMap.UID,
+				ResourceVersion: &configMap.ResourceVersion,
+			},
+		})
+		// It is okay if it was already deleted, e.g. by another instance of the Concierge.
+		return utilerrors.FilterOut(err, k8serrors.IsNotFound)
+	}
+
+	desiredData := map[string]string{caCrtKey: string(caBundle)}
+
+	if notFound {
+		desiredConfigMap := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.caBundleConfigMapName,
+				Namespace: c.namespace,
+				Labels:    c.labelsForCreate(c.labels),
+			},
+			Data: desiredData,
+		}
+		c.infoLog.Info("creating CA bundle ConfigMap for impersonation proxy",
+			"configmap", klog.KObj(desiredConfigMap),
+		)
+		_, err = c.k8sClient.CoreV1().ConfigMaps(c.namespace).Create(ctx, desiredConfigMap, metav1.CreateOptions{})
+		return err
+	}
+
+	updatedConfigMap := configMap.DeepCopy()
+	updatedConfigMap.Data = desiredData
+	if updatedConfigMap.Labels == nil {
+		updatedConfigMap.Labels = map[string]string{}
+	}
+	for k, v := range c.labels {
+		updatedConfigMap.Labels[k] = v
+	}
+	if equality.Semantic.DeepEqual(configMap, updatedConfigMap) {
+		return nil
+	}
+	c.infoLog.Info("updating CA bundle ConfigMap for impersonation proxy",
+		"configmap", klog.KObj(updatedConfigMap),
+	)
+	_, err = c.k8sClient.CoreV1().ConfigMaps(c.namespace).Update(ctx, updatedConfigMap, metav1.UpdateOptions{})
+	return err
+}
+
+// labelsForCreate returns the labels of an object which is about to be created, which are the extra labels
+// plus the given controller-owned labels. The extra labels are not reconciled after the object is created.
+func (c *impersonatorConfigController) labelsForCreate(ownedLabels map[string]string) map[string]string {
+	if len(c.extraLabels) == 0 {
+		return ownedLabels
+	}
+	createLabels := make(map[string]string, len(c.extraLabels)+len(ownedLabels))
+	for k, v := range c.extraLabels {
+		createLabels[k] = v
+	}
+	for k, v := range ownedLabels {
+		createLabels[k] = v
+	}
+	return createLabels
+}
+
+// withServiceOwnerLabel returns a copy of the labels which also has the label that marks a Service as created by
+// this controller.
+func withServiceOwnerLabel(serviceLabels map[string]string) map[string]string {
+	ownerLabels := make(map[string]string, len(serviceLabels)+1)
+	for k, v := range serviceLabels {
+		ownerLabels[k] = v
+	}
+	ownerLabels[serviceOwnerLabelKey] = serviceOwnerLabelValue
+	return ownerLabels
+}
+
 func (c *impersonatorConfigController) clearTLSSecret() {
 	c.debugLog.Info("clearing TLS serving certificate for impersonation proxy")
 	c.tlsServingCertDynamicCertProvider.UnsetCertKeyContent()
@@ -1018,7 +1941,7 @@ func (c *impersonatorConfigController) clearSignerCA() {
 	c.impersonationSigningCertProvider.UnsetCertKeyContent()
 }
 
-func (c *impersonatorConfigController) doSyncResult(nameInfo *certNameInfo, config *v1alpha1.ImpersonationProxySpec, ca *certauthority.CA) *v1alpha1.CredentialIssuerStrategy {
+func (c *impersonatorConfigController) doSyncResult(nameInfo *certNameInfo, config *v1alpha1.ImpersonationProxySpec, caBundle []byte) *v1alpha1.CredentialIssuerStrategy {
 	switch {
 	case c.disabledExplicitly(config):
 		return &v1alpha1.CredentialIssuerStrategy{
@@ -1055,13 +1978,24 @@ func (c *impersonatorConfigController) doSyncResult(nameInfo *certNameInfo, conf
 				Type: v1alpha1.ImpersonationProxyFrontendType,
 				ImpersonationProxyInfo: &v1alpha1.ImpersonationProxyInfo{
 					Endpoint:                 "https://" + nameInfo.clientEndpoint,
-					CertificateAuthorityData: base64.StdEncoding.EncodeToString(ca.Bundle()),
+					CertificateAuthorityData: base64.StdEncoding.EncodeToString(caBundle),
 				},
 			},
 		}
 	}
 }
 
+// impersonationProxyInfoForStrategy returns the client connection details from a successful strategy, or nil.
+func impersonationProxyInfoForStrategy(strategy *v1alpha1.CredentialIssuerStrategy) *loginapi.ImpersonationProxyInfo {
+	if strategy.Status != v1alpha1.SuccessStrategyStatus || strategy.Frontend == nil || strategy.Frontend.ImpersonationProxyInfo == nil {
+		return nil
+	}
+	return &loginapi.ImpersonationProxyInfo{
+		Endpoint:                 strategy.Frontend.ImpersonationProxyInfo.Endpoint,
+		CertificateAuthorityData: strategy.Frontend.ImpersonationProxyInfo.CertificateAuthorityData,
+	}
+}
+
 func validateCredentialIssuerSpec(spec *v1alpha1.ImpersonationProxySpec) error {
 	// Validate that the mode is one of our known values.
 	switch spec.Mode {
@@ -1081,9 +2015,23 @@ func validateCredentialIssuerSpec(spec *v1alpha1.ImpersonationProxySpec) error {
 	switch spec.Service.Type {
 	case v1alpha1.ImpersonationProxyServiceTypeNone:
 	case v1alpha1.ImpersonationProxyServiceTypeLoadBalancer:
+	case v1alpha1.ImpersonationProxyServiceTypeNodePort:
 	case v1alpha1.ImpersonationProxyServiceTypeClusterIP:
+	case v1alpha1.ImpersonationProxyServiceTypeExternal:
 	default:
-		return fmt.Errorf("invalid service type %q (expected None, LoadBalancer, or ClusterIP)", spec.Service.Type)
+		return fmt.Errorf("invalid service type %q (expected None, LoadBalancer, NodePort, ClusterIP, or External)", spec.Service.Type)
+	}
+
+	if spec.Service.AdditionalClusterIP && spec.Service.Type != v1alpha1.ImpersonationProxyServiceTypeLoadBalancer {
+		return fmt.Errorf("additionalClusterIP must not be set when service.type is %s", spec.Service.Type)
+	}
+
+	if spec.Service.ResolveEndpointHostname && spec.Service.Type != v1alpha1.ImpersonationProxyServiceTypeLoadBalancer {
+		return fmt.Errorf("resolveEndpointHostname must not be set when service.type is %s", spec.Service.Type)
+	}
+
+	if spec.Service.AdditionalClusterIP && autoModeSkipsLoadBalancer(spec) {
+		return stderrors.New("additionalClusterIP must not be set when autoMode.createLoadBalancer is false")
 	}
 
 	// If specified, validate that the LoadBalancerIP is a valid IPv4 or IPv6 address.
@@ -1091,9 +2039,22 @@ func validateCredentialIssuerSpec(spec *v1alpha1.ImpersonationProxySpec) error {
 		return fmt.Errorf("invalid LoadBalancerIP %q", spec.Service.LoadBalancerIP)
 	}
 
-	// If service is type "None", a non-empty external endpoint must be specified.
-	if spec.ExternalEndpoint == "" && spec.Service.Type == v1alpha1.ImpersonationProxyServiceTypeNone {
-		return fmt.Errorf("externalEndpoint must be set when service.type is None")
+	// If specified, validate that each of the LoadBalancerSourceRanges is a valid IPv4 or IPv6 CIDR.
+	for _, sourceRange := range spec.Service.LoadBalancerSourceRanges {
+		if _, _, err := net.ParseCIDR(sourceRange); err != nil {
+			return fmt.Errorf("invalid LoadBalancerSourceRanges entry %q", sourceRange)
+		}
+	}
+
+	// If specified, validate that the BindAddress is a valid IPv4 or IPv6 address.
+	if ip := spec.BindAddress; ip != "" && len(validation.IsValidIP(ip)) > 0 {
+		return fmt.Errorf("invalid BindAddress %q", spec.BindAddress)
+	}
+
+	// If service is type "None" or "External", a non-empty external endpoint must be specified.
+	if spec.ExternalEndpoint == "" &&
+		(spec.Service.Type == v1alpha1.ImpersonationProxyServiceTypeNone || spec.Service.Type == v1alpha1.ImpersonationProxyServiceTypeExternal) {
+		return fmt.Errorf("externalEndpoint must be set when service.type is %s", spec.Service.Type)
 	}
 
 	if spec.ExternalEndpoint != "" {
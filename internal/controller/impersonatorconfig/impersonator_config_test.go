@@ -5,17 +5,24 @@ package impersonatorconfig
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"regexp"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -27,19 +34,27 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	kubeinformers "k8s.io/client-go/informers"
 	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 	coretesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/component-base/metrics"
 	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
 
 	"go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
 	pinnipedfake "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned/fake"
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions"
 	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/clusterhost"
+	"go.pinniped.dev/internal/concierge/impersonator"
 	"go.pinniped.dev/internal/controller/apicerts"
+	"go.pinniped.dev/internal/controller/impersonatorconfig/proxyinfo"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/dynamiccert"
 	"go.pinniped.dev/internal/kubeclient"
@@ -54,24 +69,38 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 		const credentialIssuerResourceName = "some-credential-issuer-resource-name" //nolint:gosec // this is not a credential
 		const generatedLoadBalancerServiceName = "some-service-resource-name"
 		const generatedClusterIPServiceName = "some-cluster-ip-resource-name"
-		const tlsSecretName = "some-tls-secret-name" //nolint:gosec // this is not a credential
+		const generatedNodePortServiceName = "some-node-port-resource-name"
+		const tlsSecretName = "some-tls-secret-name"           //nolint:gosec // this is not a credential
+		const tlsSecretRef = "some-referenced-tls-secret-name" //nolint:gosec // this is not a credential
 		const caSecretName = "some-ca-secret-name"
+		const caBundleConfigMapName = "some-ca-bundle-configmap-name"
 		const caSignerName = "some-ca-signer-name"
+		const caCertificateDuration = 2 * 365 * 24 * time.Hour
+		const certificateDuration = 365 * 24 * time.Hour
+		const rotationWindowPercentage = 25
+		const requestTimeout = 90 * time.Second
+		const shutdownDrainTimeout = 30 * time.Second
+		const resyncInterval = 5 * time.Minute
+		const autoModeStopDelay = 10 * time.Minute
 
 		var r *require.Assertions
 		var observableWithInformerOption *testutil.ObservableWithInformerOption
+		var observableWithInitialEventOption *testutil.ObservableWithInitialEventOption
 		var credIssuerInformerFilter controllerlib.Filter
 		var servicesInformerFilter controllerlib.Filter
 		var secretsInformerFilter controllerlib.Filter
+		var configMapsInformerFilter controllerlib.Filter
 
 		it.Before(func() {
 			r = require.New(t)
 			observableWithInformerOption = testutil.NewObservableWithInformerOption()
+			observableWithInitialEventOption = testutil.NewObservableWithInitialEventOption()
 			pinnipedInformerFactory := pinnipedinformers.NewSharedInformerFactory(nil, 0)
 			sharedInformerFactory := kubeinformers.NewSharedInformerFactory(nil, 0)
 			credIssuerInformer := pinnipedInformerFactory.Config().V1alpha1().CredentialIssuers()
 			servicesInformer := sharedInformerFactory.Core().V1().Services()
 			secretsInformer := sharedInformerFactory.Core().V1().Secrets()
+			configMapsInformer := sharedInformerFactory.Core().V1().ConfigMaps()
 
 			_ = NewImpersonatorConfigController(
 				installedInNamespace,
@@ -81,22 +110,46 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 				credIssuerInformer,
 				servicesInformer,
 				secretsInformer,
+				configMapsInformer,
 				observableWithInformerOption.WithInformer,
+				observableWithInitialEventOption.WithInitialEvent,
 				impersonationProxyPort,
 				generatedLoadBalancerServiceName,
 				generatedClusterIPServiceName,
+				generatedNodePortServiceName,
 				tlsSecretName,
+				tlsSecretRef,
 				caSecretName,
+				caBundleConfigMapName,
+				nil,
+				nil,
+				nil,
 				nil,
 				nil,
+				metrics.NewKubeRegistry().MustRegister,
 				nil,
 				caSignerName,
 				nil,
+				nil,
+				pkix.Name{},
+				caCertificateDuration,
+				"",
+				certificateDuration,
+				nil,
+				rotationWindowPercentage,
+				requestTimeout,
+				shutdownDrainTimeout,
+				resyncInterval,
+				nil,
+				nil,
+				autoModeStopDelay,
+				"",
 				plog.Logr(), //nolint:staticcheck  // old test with no log assertions
 			)
 			credIssuerInformerFilter = observableWithInformerOption.GetFilterForInformer(credIssuerInformer)
 			servicesInformerFilter = observableWithInformerOption.GetFilterForInformer(servicesInformer)
 			secretsInformerFilter = observableWithInformerOption.GetFilterForInformer(secretsInformer)
+			configMapsInformerFilter = observableWithInformerOption.GetFilterForInformer(configMapsInformer)
 		})
 
 		when("watching CredentialIssuer objects", func() {
@@ -131,12 +184,13 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 
 		when("watching Service objects", func() {
 			var subject controllerlib.Filter
-			var targetLBService, targetClusterIPService, wrongNamespace, wrongName, unrelated *corev1.Service
+			var targetLBService, targetClusterIPService, targetNodePortService, wrongNamespace, wrongName, unrelated *corev1.Service
 
 			it.Before(func() {
 				subject = servicesInformerFilter
 				targetLBService = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: generatedLoadBalancerServiceName, Namespace: installedInNamespace}}
 				targetClusterIPService = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: generatedClusterIPServiceName, Namespace: installedInNamespace}}
+				targetNodePortService = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: generatedNodePortServiceName, Namespace: installedInNamespace}}
 				wrongNamespace = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: generatedLoadBalancerServiceName, Namespace: "wrong-namespace"}}
 				wrongName = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "wrong-name", Namespace: installedInNamespace}}
 				unrelated = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "wrong-name", Namespace: "wrong-namespace"}}
@@ -152,6 +206,10 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 					r.True(subject.Update(targetClusterIPService, unrelated))
 					r.True(subject.Update(unrelated, targetClusterIPService))
 					r.True(subject.Delete(targetClusterIPService))
+					r.True(subject.Add(targetNodePortService))
+					r.True(subject.Update(targetNodePortService, unrelated))
+					r.True(subject.Update(unrelated, targetNodePortService))
+					r.True(subject.Delete(targetNodePortService))
 				})
 			})
 
@@ -184,13 +242,14 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 
 		when("watching Secret objects", func() {
 			var subject controllerlib.Filter
-			var target1, target2, target3, wrongNamespace1, wrongNamespace2, wrongName, unrelated *corev1.Secret
+			var target1, target2, target3, target4, wrongNamespace1, wrongNamespace2, wrongName, unrelated *corev1.Secret
 
 			it.Before(func() {
 				subject = secretsInformerFilter
 				target1 = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tlsSecretName, Namespace: installedInNamespace}}
 				target2 = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: caSecretName, Namespace: installedInNamespace}}
 				target3 = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: caSignerName, Namespace: installedInNamespace}}
+				target4 = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tlsSecretRef, Namespace: installedInNamespace}}
 				wrongNamespace1 = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tlsSecretName, Namespace: "wrong-namespace"}}
 				wrongNamespace2 = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: caSecretName, Namespace: "wrong-namespace"}}
 				wrongName = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "wrong-name", Namespace: installedInNamespace}}
@@ -211,6 +270,10 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 					r.True(subject.Update(target3, unrelated))
 					r.True(subject.Update(unrelated, target3))
 					r.True(subject.Delete(target3))
+					r.True(subject.Add(target4))
+					r.True(subject.Update(target4, unrelated))
+					r.True(subject.Update(unrelated, target4))
+					r.True(subject.Delete(target4))
 				})
 			})
 
@@ -244,6 +307,52 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 				})
 			})
 		})
+
+		when("watching ConfigMap objects", func() {
+			var subject controllerlib.Filter
+			var target, wrongNamespace, wrongName, unrelated *corev1.ConfigMap
+
+			it.Before(func() {
+				subject = configMapsInformerFilter
+				target = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: caBundleConfigMapName, Namespace: installedInNamespace}}
+				wrongNamespace = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: caBundleConfigMapName, Namespace: "wrong-namespace"}}
+				wrongName = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "wrong-name", Namespace: installedInNamespace}}
+				unrelated = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "wrong-name", Namespace: "wrong-namespace"}}
+			})
+
+			when("the target ConfigMap changes", func() {
+				it("returns true to trigger the sync method", func() {
+					r.True(subject.Add(target))
+					r.True(subject.Update(target, unrelated))
+					r.True(subject.Update(unrelated, target))
+					r.True(subject.Delete(target))
+				})
+			})
+
+			when("a ConfigMap from another namespace changes", func() {
+				it("returns false to avoid triggering the sync method", func() {
+					r.False(subject.Add(wrongNamespace))
+					r.False(subject.Update(wrongNamespace, unrelated))
+					r.False(subject.Update(unrelated, wrongNamespace))
+					r.False(subject.Delete(wrongNamespace))
+				})
+			})
+
+			when("a ConfigMap with a different name changes", func() {
+				it("returns false to avoid triggering the sync method", func() {
+					r.False(subject.Add(wrongName))
+					r.False(subject.Update(wrongName, unrelated))
+					r.False(subject.Update(unrelated, wrongName))
+					r.False(subject.Delete(wrongName))
+				})
+			})
+		})
+
+		when("starting up", func() {
+			it("asks for an initial event using the same key as the informers' singleton queue", func() {
+				r.Equal(&controllerlib.Key{}, observableWithInitialEventOption.GetInitialEventKey())
+			})
+		})
 	}, spec.Parallel(), spec.Report(report.Terminal{}))
 }
 
@@ -255,9 +364,17 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 		const credentialIssuerResourceName = "some-credential-issuer-resource-name" //nolint:gosec // this is not a credential
 		const loadBalancerServiceName = "some-service-resource-name"
 		const clusterIPServiceName = "some-cluster-ip-resource-name"
+		const nodePortServiceName = "some-node-port-resource-name"
 		const tlsSecretName = "some-tls-secret-name" //nolint:gosec // this is not a credential
 		const caSecretName = "some-ca-secret-name"
 		const caSignerName = "some-ca-signer-name"
+		const caCertificateDuration = 2 * 365 * 24 * time.Hour
+		const certificateDuration = 365 * 24 * time.Hour
+		const rotationWindowPercentage = 25
+		const requestTimeout = 90 * time.Second
+		const shutdownDrainTimeout = 30 * time.Second
+		const resyncInterval = 5 * time.Minute
+		const autoModeStopDelay = 10 * time.Minute
 		const localhostIP = "127.0.0.1"
 		const httpsPort = ":443"
 		const fakeServerResponseBody = "hello, world!"
@@ -266,6 +383,17 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 		var r *require.Assertions
 
 		var subject controllerlib.Controller
+		var caSubject pkix.Name
+		var caKeyAlgorithm certauthority.KeyAlgorithm
+		var tlsSecretRef string
+		var caBundleConfigMapName string
+		var extraLabels map[string]string
+		var serviceSelector map[string]string
+		var lookupIP func(ctx context.Context, host string) ([]net.IP, error)
+		var controlPlaneNodeSelectors []k8slabels.Selector
+		var excludedNodeRoles []string
+		var additionalSANs []string
+		var stateChangeWebhookURL string
 		var kubeAPIClient *kubernetesfake.Clientset
 		var pinnipedAPIClient *pinnipedfake.Clientset
 		var pinnipedInformerClient *pinnipedfake.Clientset
@@ -276,13 +404,17 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 		var cancelContextCancelFunc context.CancelFunc
 		var syncContext *controllerlib.Context
 		var frozenNow time.Time
+		var fakeClock *clocktesting.FakeClock
 		var tlsServingCertDynamicCertProvider dynamiccert.Private
 		var signingCertProvider dynamiccert.Provider
+		var impersonationProxyInfoCache *proxyinfo.Cache
 		var signingCACertPEM, signingCAKeyPEM []byte
 		var signingCASecret *corev1.Secret
 		var impersonatorFuncWasCalled int
+		var impersonatorFuncAddress string
 		var impersonatorFuncError error
 		var impersonatorFuncReturnedFuncError error
+		var impersonatorFuncReturnedFuncStopError error
 		var startedTLSListener net.Listener
 		var startedTLSListenerMutex sync.RWMutex
 		var testHTTPServer *http.Server
@@ -290,14 +422,20 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 		var testHTTPServerInterruptCh chan struct{}
 		var queue *testQueue
 		var validClientCert *tls.Certificate
+		var eventRecorder *events.FakeRecorder
+		var metricsRegistry metrics.KubeRegistry
 
 		var impersonatorFunc = func(
-			port int,
+			address string,
+			timeout time.Duration,
+			drainTimeout time.Duration,
 			dynamicCertProvider dynamiccert.Private,
 			impersonationProxySignerCAProvider dynamiccert.Public,
 		) (func(stopCh <-chan struct{}) error, error) {
 			impersonatorFuncWasCalled++
-			r.Equal(8444, port)
+			impersonatorFuncAddress = address
+			r.Equal(requestTimeout, timeout)
+			r.Equal(shutdownDrainTimeout, drainTimeout)
 			r.NotNil(dynamicCertProvider)
 			r.NotNil(impersonationProxySignerCAProvider)
 
@@ -396,7 +534,8 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				t.Log("Got an unexpected error while stopping the fake http server!")
 				r.NoError(err) // causes the test to crash, which is good enough because this should never happen
 
-				return nil
+				// Simulate the real impersonation server's errors from draining and closing its connections.
+				return impersonatorFuncReturnedFuncStopError
 			}, nil
 		}
 
@@ -544,6 +683,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 		// nested Before's can keep adding things to the informer caches.
 		var startInformersAndController = func() {
 			// Set this at the last second to allow for injection of server override.
+			fakeClock = clocktesting.NewFakeClock(frozenNow)
 			subject = NewImpersonatorConfigController(
 				installedInNamespace,
 				credentialIssuerResourceName,
@@ -552,21 +692,47 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				pinnipedInformers.Config().V1alpha1().CredentialIssuers(),
 				kubeInformers.Core().V1().Services(),
 				kubeInformers.Core().V1().Secrets(),
+				kubeInformers.Core().V1().ConfigMaps(),
 				controllerlib.WithInformer,
+				controllerlib.WithInitialEvent,
 				impersonationProxyPort,
 				loadBalancerServiceName,
 				clusterIPServiceName,
+				nodePortServiceName,
 				tlsSecretName,
+				tlsSecretRef,
 				caSecretName,
+				caBundleConfigMapName,
 				labels,
-				clocktesting.NewFakeClock(frozenNow),
+				extraLabels,
+				serviceSelector,
+				fakeClock,
+				eventRecorder,
+				metricsRegistry.MustRegister,
 				impersonatorFunc,
 				caSignerName,
 				signingCertProvider,
+				impersonationProxyInfoCache,
+				caSubject,
+				caCertificateDuration,
+				caKeyAlgorithm,
+				certificateDuration,
+				additionalSANs,
+				rotationWindowPercentage,
+				requestTimeout,
+				shutdownDrainTimeout,
+				resyncInterval,
+				controlPlaneNodeSelectors,
+				excludedNodeRoles,
+				autoModeStopDelay,
+				stateChangeWebhookURL,
 				plog.Logr(), //nolint:staticcheck  // old test with no log assertions
 			)
 			controllerlib.TestWrap(t, subject, func(syncer controllerlib.Syncer) controllerlib.Syncer {
 				tlsServingCertDynamicCertProvider = syncer.(*impersonatorConfigController).tlsServingCertDynamicCertProvider
+				if lookupIP != nil {
+					syncer.(*impersonatorConfigController).lookupIP = lookupIP
+				}
 				return syncer
 			})
 
@@ -609,7 +775,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 		}
 
 		var newCA = func() *certauthority.CA {
-			ca, err := certauthority.New("test CA", 24*time.Hour)
+			ca, err := certauthority.New(caCommonName, 24*time.Hour)
 			r.NoError(err)
 			return ca
 		}
@@ -658,7 +824,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      resourceName,
 					Namespace: installedInNamespace,
-					Labels:    labels,
+					Labels:    withServiceOwnerLabel(labels),
 				},
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
@@ -680,7 +846,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      resourceName,
 					Namespace: installedInNamespace,
-					Labels:    labels,
+					Labels:    withServiceOwnerLabel(labels),
 				},
 				Spec:   spec,
 				Status: status,
@@ -807,6 +973,22 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			r.NoError(client.Tracker().Add(clusterIPService))
 		}
 
+		var addNodePortServiceToTracker = func(resourceName string, nodePort int32, client *kubernetesfake.Clientset) {
+			nodePortService := newClusterIPService(resourceName, corev1.ServiceStatus{}, corev1.ServiceSpec{
+				Type: corev1.ServiceTypeNodePort,
+				Ports: []corev1.ServicePort{
+					{
+						TargetPort: intstr.FromInt(impersonationProxyPort),
+						Port:       defaultHTTPSPort,
+						NodePort:   nodePort,
+						Protocol:   corev1.ProtocolTCP,
+					},
+				},
+				Selector: map[string]string{appLabelKey: labels[appLabelKey]},
+			})
+			r.NoError(client.Tracker().Add(nodePortService))
+		}
+
 		var addDualStackClusterIPServiceToTracker = func(resourceName string, clusterIP0 string, clusterIP1 string, client *kubernetesfake.Clientset) {
 			clusterIPService := newClusterIPService(resourceName, corev1.ServiceStatus{}, corev1.ServiceSpec{
 				Type:       corev1.ServiceTypeClusterIP,
@@ -865,6 +1047,18 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			))
 		}
 
+		var addNodeWithAddressesToTracker = func(name string, addresses []corev1.NodeAddress, client *kubernetesfake.Clientset) {
+			r.NoError(client.Tracker().Add(
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   name,
+						Labels: map[string]string{"kubernetes.io/node-role": "worker"},
+					},
+					Status: corev1.NodeStatus{Addresses: addresses},
+				},
+			))
+		}
+
 		var requireNodesListed = func(action coretesting.Action) {
 			r.Equal(
 				coretesting.NewListAction(
@@ -960,6 +1154,16 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			// of this test.
 			credentialIssuer := getCredentialIssuer()
 			r.Equal([]v1alpha1.CredentialIssuerStrategy{expectedStrategy}, credentialIssuer.Status.Strategies)
+
+			// The TokenCredentialRequest API should only be told about the impersonation proxy while it is ready.
+			if expectedStrategy.Status == v1alpha1.SuccessStrategyStatus {
+				r.Equal(&loginapi.ImpersonationProxyInfo{
+					Endpoint:                 expectedStrategy.Frontend.ImpersonationProxyInfo.Endpoint,
+					CertificateAuthorityData: expectedStrategy.Frontend.ImpersonationProxyInfo.CertificateAuthorityData,
+				}, impersonationProxyInfoCache.Get())
+			} else {
+				r.Nil(impersonationProxyInfoCache.Get())
+			}
 		}
 
 		var requireServiceWasDeleted = func(action coretesting.Action, serviceName string) {
@@ -970,17 +1174,58 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			r.Equal("services", deleteAction.GetResource().Resource)
 		}
 
-		var requireLoadBalancerWasCreated = func(action coretesting.Action) *corev1.Service {
+		// The Services should select the configured labels, or else the app label of the Concierge pods.
+		var requireServiceSelector = func(service *corev1.Service) {
+			if len(serviceSelector) > 0 {
+				r.Equal(serviceSelector, service.Spec.Selector)
+				return
+			}
+			r.Equal(map[string]string{"app": "app-name"}, service.Spec.Selector)
+		}
+
+		// Created objects have the extra labels in addition to the controller-owned labels.
+		var requireCreatedLabels = func(actualLabels map[string]string) {
+			wantLabels := map[string]string{}
+			for k, v := range extraLabels {
+				wantLabels[k] = v
+			}
+			for k, v := range labels {
+				wantLabels[k] = v
+			}
+			r.Equal(wantLabels, actualLabels)
+		}
+
+		var withoutServiceOwnerLabel = func(serviceLabels map[string]string) map[string]string {
+			labelsWithoutOwner := map[string]string{}
+			for k, v := range serviceLabels {
+				if k != serviceOwnerLabelKey {
+					labelsWithoutOwner[k] = v
+				}
+			}
+			return labelsWithoutOwner
+		}
+
+		// requireCreatedServiceLabels is like requireCreatedLabels, but Services also get the owner label.
+		var requireCreatedServiceLabels = func(actualLabels map[string]string) {
+			r.Equal(serviceOwnerLabelValue, actualLabels[serviceOwnerLabelKey])
+			requireCreatedLabels(withoutServiceOwnerLabel(actualLabels))
+		}
+
+		var requireServiceWasCreated = func(action coretesting.Action, serviceName string, serviceType corev1.ServiceType) *corev1.Service {
 			createAction, ok := action.(coretesting.CreateAction)
 			r.True(ok, "should have been able to cast this action to CreateAction: %v", action)
 			r.Equal("create", createAction.GetVerb())
-			createdLoadBalancerService := createAction.GetObject().(*corev1.Service)
-			r.Equal(loadBalancerServiceName, createdLoadBalancerService.Name)
-			r.Equal(installedInNamespace, createdLoadBalancerService.Namespace)
-			r.Equal(corev1.ServiceTypeLoadBalancer, createdLoadBalancerService.Spec.Type)
-			r.Equal("app-name", createdLoadBalancerService.Spec.Selector["app"])
-			r.Equal(labels, createdLoadBalancerService.Labels)
-			return createdLoadBalancerService
+			createdService := createAction.GetObject().(*corev1.Service)
+			r.Equal(serviceName, createdService.Name)
+			r.Equal(installedInNamespace, createdService.Namespace)
+			r.Equal(serviceType, createdService.Spec.Type)
+			requireServiceSelector(createdService)
+			requireCreatedServiceLabels(createdService.Labels)
+			return createdService
+		}
+
+		var requireLoadBalancerWasCreated = func(action coretesting.Action) *corev1.Service {
+			return requireServiceWasCreated(action, loadBalancerServiceName, corev1.ServiceTypeLoadBalancer)
 		}
 
 		var requireLoadBalancerWasUpdated = func(action coretesting.Action) *corev1.Service {
@@ -991,21 +1236,17 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			r.Equal(loadBalancerServiceName, updatedLoadBalancerService.Name)
 			r.Equal(installedInNamespace, updatedLoadBalancerService.Namespace)
 			r.Equal(corev1.ServiceTypeLoadBalancer, updatedLoadBalancerService.Spec.Type)
-			r.Equal("app-name", updatedLoadBalancerService.Spec.Selector["app"])
-			r.Equal(labels, updatedLoadBalancerService.Labels)
+			requireServiceSelector(updatedLoadBalancerService)
+			r.Equal(labels, withoutServiceOwnerLabel(updatedLoadBalancerService.Labels))
 			return updatedLoadBalancerService
 		}
 
 		var requireClusterIPWasCreated = func(action coretesting.Action) *corev1.Service {
-			createAction, ok := action.(coretesting.CreateAction)
-			r.True(ok, "should have been able to cast this action to CreateAction: %v", action)
-			r.Equal("create", createAction.GetVerb())
-			createdClusterIPService := createAction.GetObject().(*corev1.Service)
-			r.Equal(clusterIPServiceName, createdClusterIPService.Name)
-			r.Equal(corev1.ServiceTypeClusterIP, createdClusterIPService.Spec.Type)
-			r.Equal("app-name", createdClusterIPService.Spec.Selector["app"])
-			r.Equal(labels, createdClusterIPService.Labels)
-			return createdClusterIPService
+			return requireServiceWasCreated(action, clusterIPServiceName, corev1.ServiceTypeClusterIP)
+		}
+
+		var requireNodePortWasCreated = func(action coretesting.Action) *corev1.Service {
+			return requireServiceWasCreated(action, nodePortServiceName, corev1.ServiceTypeNodePort)
 		}
 
 		var requireClusterIPWasUpdated = func(action coretesting.Action) *corev1.Service {
@@ -1016,8 +1257,8 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			r.Equal(clusterIPServiceName, updatedLoadBalancerService.Name)
 			r.Equal(installedInNamespace, updatedLoadBalancerService.Namespace)
 			r.Equal(corev1.ServiceTypeClusterIP, updatedLoadBalancerService.Spec.Type)
-			r.Equal("app-name", updatedLoadBalancerService.Spec.Selector["app"])
-			r.Equal(labels, updatedLoadBalancerService.Labels)
+			requireServiceSelector(updatedLoadBalancerService)
+			r.Equal(labels, withoutServiceOwnerLabel(updatedLoadBalancerService.Labels))
 			return updatedLoadBalancerService
 		}
 
@@ -1040,7 +1281,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			r.Equal(caSecretName, createdSecret.Name)
 			r.Equal(installedInNamespace, createdSecret.Namespace)
 			r.Equal(corev1.SecretTypeOpaque, createdSecret.Type)
-			r.Equal(labels, createdSecret.Labels)
+			requireCreatedLabels(createdSecret.Labels)
 			r.Len(createdSecret.Data, 2)
 			createdCertPEM := createdSecret.Data["ca.crt"]
 			createdKeyPEM := createdSecret.Data["ca.key"]
@@ -1055,10 +1296,42 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			require.NoError(t, err)
 			require.Equal(t, "Pinniped Impersonation Proxy Serving CA", caCert.Subject.CommonName)
 			require.WithinDuration(t, time.Now().Add(-5*time.Minute), caCert.NotBefore, 10*time.Second)
-			require.WithinDuration(t, time.Now().Add(100*time.Hour*24*365), caCert.NotAfter, 10*time.Second)
+			require.WithinDuration(t, time.Now().Add(caCertificateDuration), caCert.NotAfter, 10*time.Second)
 			return createdCertPEM
 		}
 
+		var requireCASecretWasUpdated = func(action coretesting.Action, oldCACert []byte) []byte {
+			updateAction, ok := action.(coretesting.UpdateAction)
+			r.True(ok, "should have been able to cast this action to UpdateAction: %v", action)
+			r.Equal("update", updateAction.GetVerb())
+			updatedSecret := updateAction.GetObject().(*corev1.Secret)
+			r.Equal(caSecretName, updatedSecret.Name)
+			r.Equal(installedInNamespace, updatedSecret.Namespace)
+			r.Equal("rv-5678", updatedSecret.ResourceVersion)
+			r.Len(updatedSecret.Data, 2)
+			updatedCertPEM := updatedSecret.Data["ca.crt"]
+			_, err := tls.X509KeyPair(updatedCertPEM, updatedSecret.Data["ca.key"])
+			r.NoError(err, "key does not match cert")
+			r.NotEqual(string(oldCACert), string(updatedCertPEM))
+			block, _ := pem.Decode(updatedCertPEM)
+			require.NotNil(t, block)
+			caCert, err := x509.ParseCertificate(block.Bytes)
+			require.NoError(t, err)
+			if caSubject.CommonName == "" {
+				require.Equal(t, "Pinniped Impersonation Proxy Serving CA", caCert.Subject.CommonName)
+			} else {
+				require.Equal(t, caSubject.CommonName, caCert.Subject.CommonName)
+			}
+			require.Equal(t, caSubject.Organization, caCert.Subject.Organization)
+			if caKeyAlgorithm == "" {
+				require.Equal(t, certauthority.KeyAlgorithmECDSAP256, certauthority.KeyAlgorithmOf(caCert.PublicKey))
+			} else {
+				require.Equal(t, caKeyAlgorithm, certauthority.KeyAlgorithmOf(caCert.PublicKey))
+			}
+			require.WithinDuration(t, time.Now().Add(caCertificateDuration), caCert.NotAfter, 10*time.Second)
+			return updatedCertPEM
+		}
+
 		var requireTLSSecretWasCreated = func(action coretesting.Action, caCert []byte) {
 			createAction, ok := action.(coretesting.CreateAction)
 			r.True(ok, "should have been able to cast this action to CreateAction: %v", action)
@@ -1067,7 +1340,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			r.Equal(tlsSecretName, createdSecret.Name)
 			r.Equal(installedInNamespace, createdSecret.Namespace)
 			r.Equal(corev1.SecretTypeTLS, createdSecret.Type)
-			r.Equal(labels, createdSecret.Labels)
+			requireCreatedLabels(createdSecret.Labels)
 			r.Len(createdSecret.Data, 2)
 			createdCertPEM := createdSecret.Data[corev1.TLSCertKey]
 			createdKeyPEM := createdSecret.Data[corev1.TLSPrivateKeyKey]
@@ -1075,7 +1348,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			r.NotNil(createdCertPEM)
 			validCert := testutil.ValidateServerCertificate(t, string(caCert), string(createdCertPEM))
 			validCert.RequireMatchesPrivateKey(string(createdKeyPEM))
-			validCert.RequireLifetime(time.Now().Add(-5*time.Minute), time.Now().Add(100*time.Hour*24*365), 10*time.Second)
+			validCert.RequireLifetime(time.Now().Add(-5*time.Minute), time.Now().Add(certificateDuration), 10*time.Second)
 		}
 
 		var requireSigningCertProviderHasLoadedCerts = func(certPEM, keyPEM []byte) {
@@ -1091,6 +1364,32 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			r.Nil(actualKey)
 		}
 
+		// requireMetricValues asserts the values of all the controller's metrics, by name without their common prefix.
+		var requireMetricValues = func(expectedValues map[string]float64) {
+			metricFamilies, err := metricsRegistry.Gather()
+			r.NoError(err)
+			actualValues := map[string]float64{}
+			for _, metricFamily := range metricFamilies {
+				r.Len(metricFamily.GetMetric(), 1)
+				name := strings.TrimPrefix(metricFamily.GetName(), "pinniped_concierge_impersonation_proxy_")
+				actualValues[name] = metricFamily.GetMetric()[0].GetCounter().GetValue()
+			}
+			r.Equal(expectedValues, actualValues)
+		}
+
+		var requireEventsRecorded = func(expectedEvents ...string) {
+			var actualEvents []string
+			for {
+				select {
+				case event := <-eventRecorder.Events:
+					actualEvents = append(actualEvents, event)
+				default:
+					r.Equal(expectedEvents, actualEvents)
+					return
+				}
+			}
+		}
+
 		var runControllerSync = func() error {
 			return controllerlib.TestSync(t, subject, *syncContext)
 		}
@@ -1098,6 +1397,19 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 		it.Before(func() {
 			r = require.New(t)
 			queue = &testQueue{}
+			caSubject = pkix.Name{}
+			caKeyAlgorithm = ""
+			tlsSecretRef = ""
+			caBundleConfigMapName = ""
+			extraLabels = nil
+			serviceSelector = nil
+			lookupIP = nil
+			controlPlaneNodeSelectors = nil
+			excludedNodeRoles = nil
+			additionalSANs = nil
+			stateChangeWebhookURL = ""
+			eventRecorder = events.NewFakeRecorder(1000)
+			metricsRegistry = metrics.NewKubeRegistry()
 			cancelContext, cancelContextCancelFunc = context.WithCancel(context.Background())
 
 			pinnipedInformerClient = pinnipedfake.NewSimpleClientset()
@@ -1111,6 +1423,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			pinnipedAPIClient = pinnipedfake.NewSimpleClientset()
 			frozenNow = time.Date(2021, time.March, 2, 7, 42, 0, 0, time.Local)
 			signingCertProvider = dynamiccert.NewCA(name)
+			impersonationProxyInfoCache = proxyinfo.New()
 
 			ca := newCA()
 			signingCACertPEM = ca.Bundle()
@@ -1145,6 +1458,10 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 					r.EqualError(runControllerSync(), `could not get CredentialIssuer to update: credentialissuer.config.concierge.pinniped.dev "some-credential-issuer-resource-name" not found`)
 					requireTLSServerWasNeverStarted()
 					r.Len(kubeAPIClient.Actions(), 0)
+
+					// Keep resyncing, so that the CredentialIssuer is noticed once it exists even if there is no event.
+					r.Equal(syncContext.Key, queue.afterKey)
+					r.Equal(resyncInterval, queue.afterDuration)
 				})
 			})
 		})
@@ -1199,308 +1516,616 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 				})
 			})
-		})
-
-		when("the configuration is auto mode", func() {
-			it.Before(func() {
-				addSecretToTrackers(signingCASecret, kubeInformerClient)
-				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
-					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
-					Spec: v1alpha1.CredentialIssuerSpec{
-						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode: v1alpha1.ImpersonationProxyModeAuto,
-						},
-					},
-				}, pinnipedInformerClient, pinnipedAPIClient)
-			})
 
-			when("there are visible control plane nodes", func() {
+			when("the visible control plane nodes go away and then a force sync is requested", func() {
 				it.Before(func() {
 					addNodeWithRoleToTracker("control-plane", kubeAPIClient)
 				})
 
-				it("does not start the impersonator or load balancer", func() {
+				it("lists the nodes again only after the force-sync annotation changes", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
-					requireTLSServerWasNeverStarted()
 					r.Len(kubeAPIClient.Actions(), 1)
 					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerWasNeverStarted()
 					requireCredentialIssuer(newAutoDisabledStrategy())
-					requireSigningCertProviderIsEmpty()
-				})
-			})
-
-			when("there are visible control plane nodes and a loadbalancer and a tls Secret", func() {
-				it.Before(func() {
-					addNodeWithRoleToTracker("control-plane", kubeAPIClient)
-					addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeInformerClient)
-					addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeAPIClient)
-					addSecretToTrackers(newEmptySecret(tlsSecretName), kubeAPIClient, kubeInformerClient)
-				})
 
-				it("does not start the impersonator, deletes the loadbalancer, deletes the Secret", func() {
-					startInformersAndController()
+					// The decision about the control plane nodes is cached, so a node change is not noticed.
+					nodesGVR := schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+					r.NoError(kubeAPIClient.Tracker().Delete(nodesGVR, "", "node"))
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					kubeAPIClient.ClearActions()
 					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 0)
 					requireTLSServerWasNeverStarted()
+
+					// Requesting a force sync by changing the annotation causes the nodes to be listed again.
+					credIssuersGVR := v1alpha1.Resource("credentialissuers").WithVersion("v1alpha1")
+					credIssuerObj, err := pinnipedInformerClient.Tracker().Get(credIssuersGVR, "", credentialIssuerResourceName)
+					r.NoError(err)
+					credIssuer := credIssuerObj.(*v1alpha1.CredentialIssuer).DeepCopy()
+					credIssuer.Annotations = map[string]string{"credentialissuer.pinniped.dev/force-sync": "2023-01-01T00:00:00Z"}
+					r.NoError(pinnipedInformerClient.Tracker().Update(credIssuersGVR, credIssuer, ""))
+					waitForClusterScopedObjectToAppearInInformer(credIssuer, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireServiceWasDeleted(kubeAPIClient.Actions()[1], loadBalancerServiceName)
-					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[2])
-					requireCredentialIssuer(newAutoDisabledStrategy())
-					requireSigningCertProviderIsEmpty()
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
+
+					// Syncing again with the same annotation value does not list the nodes again.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+					kubeAPIClient.ClearActions()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 0)
 				})
 			})
 
-			when("there are not visible control plane nodes", func() {
+			when("control plane nodes become visible while auto mode is running the impersonator", func() {
 				it.Before(func() {
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					startInformersAndController()
-					r.NoError(runControllerSync())
 				})
 
-				it("starts the load balancer automatically", func() {
-					requireTLSServerIsRunningWithoutCerts()
+				it("lists the nodes again each resync interval and stops the impersonator once they stay visible for the stop delay", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
-					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
-					requireCredentialIssuer(newPendingStrategyWaitingForLB())
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-				})
-			})
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
 
-			when("there are not visible control plane nodes and a load balancer already exists without an IP/hostname", func() {
-				it.Before(func() {
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeInformerClient)
-					addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeAPIClient)
-					startInformersAndController()
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// A control plane node becomes visible, but it is not noticed before the resync interval has passed.
+					nodesGVR := schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+					r.NoError(kubeAPIClient.Tracker().Delete(nodesGVR, "", "node"))
+					addNodeWithRoleToTracker("control-plane", kubeAPIClient)
+					kubeAPIClient.ClearActions()
 					r.NoError(runControllerSync())
-				})
+					r.Len(kubeAPIClient.Actions(), 0)
 
-				it("does not start the load balancer automatically", func() {
-					requireTLSServerIsRunningWithoutCerts()
-					r.Len(kubeAPIClient.Actions(), 2)
+					// Once the resync interval has passed, the nodes are listed again, but the impersonator keeps
+					// running until the control plane node has been visible for the stop delay.
+					fakeClock.Step(resyncInterval)
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireCredentialIssuer(newPendingStrategyWaitingForLB())
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-				})
-			})
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
 
-			when("there are not visible control plane nodes and a load balancer already exists with empty ingress", func() {
-				it.Before(func() {
+					// The control plane node goes away for one check, which restarts the stop delay.
+					r.NoError(kubeAPIClient.Tracker().Delete(nodesGVR, "", "node"))
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "", Hostname: ""}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "", Hostname: ""}}, kubeAPIClient)
-					startInformersAndController()
+					kubeAPIClient.ClearActions()
+					fakeClock.Step(resyncInterval)
 					r.NoError(runControllerSync())
-				})
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
 
-				it("does not start the load balancer automatically", func() {
-					requireTLSServerIsRunningWithoutCerts()
+					r.NoError(kubeAPIClient.Tracker().Delete(nodesGVR, "", "node"))
+					addNodeWithRoleToTracker("control-plane", kubeAPIClient)
+					kubeAPIClient.ClearActions()
+					fakeClock.Step(resyncInterval)
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
+
+					fakeClock.Step(resyncInterval)
+					kubeAPIClient.ClearActions()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
+
+					// The nodes are listed again when the stop delay ends, even though that is sooner than the resync
+					// interval, and the impersonator is stopped once the control plane node has been visible for that long.
+					fakeClock.Step(autoModeStopDelay - resyncInterval)
+					kubeAPIClient.ClearActions()
+					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 2)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireCredentialIssuer(newPendingStrategyWaitingForLB())
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[1])
+					requireTLSServerIsNoLongerRunning()
+					requireSigningCertProviderIsEmpty()
+
+					// Simulate the informer cache's background update from its watch.
+					deleteSecretFromTracker(tlsSecretName, kubeInformerClient)
+					waitForObjectToBeDeletedFromInformer(tlsSecretName, kubeInformers.Core().V1().Secrets())
+
+					// Now that auto mode has stopped the impersonator, the nodes are not listed again.
+					kubeAPIClient.ClearActions()
+					fakeClock.Step(resyncInterval)
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 0)
 				})
 			})
 
-			when("there are not visible control plane nodes and a load balancer already exists with invalid ip", func() {
+			when("there are nodes which only match the configured control plane node selectors", func() {
 				it.Before(func() {
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "not-an-ip"}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "not-an-ip"}}, kubeAPIClient)
-					startInformersAndController()
-					r.EqualError(runControllerSync(), "could not find valid IP addresses or hostnames from load balancer some-namespace/some-service-resource-name")
+					var err error
+					controlPlaneNodeSelectors, err = clusterhost.ParseControlPlaneNodeSelectors([]string{"example.com/role=control"})
+					r.NoError(err)
+					r.NoError(kubeAPIClient.Tracker().Add(&corev1.Node{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "node",
+							Labels: map[string]string{"example.com/role": "control"},
+						},
+					}))
 				})
 
-				it("does not start the load balancer automatically", func() {
-					requireTLSServerIsRunningWithoutCerts()
-					r.Len(kubeAPIClient.Actions(), 1)
+				it("does not start the impersonator", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					requireTLSServerWasNeverStarted()
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireCredentialIssuer(newErrorStrategy("could not find valid IP addresses or hostnames from load balancer some-namespace/some-service-resource-name"))
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireCredentialIssuer(newAutoDisabledStrategy())
 					requireSigningCertProviderIsEmpty()
 				})
 			})
 
-			when("there are not visible control plane nodes and a load balancer already exists with multiple ips", func() {
-				const fakeIP = "127.0.0.123"
+			when("the only control plane nodes also have an excluded node role", func() {
 				it.Before(func() {
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: fakeIP}, {IP: "127.0.0.456"}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: fakeIP}, {IP: "127.0.0.456"}}, kubeAPIClient)
-					startInformersAndController()
-					r.NoError(runControllerSync())
+					excludedNodeRoles = []string{"edge"}
+					r.NoError(kubeAPIClient.Tracker().Add(&corev1.Node{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "node",
+							Labels: map[string]string{
+								"node-role.kubernetes.io/control-plane": "",
+								"node-role.kubernetes.io/edge":          "",
+							},
+						},
+					}))
 				})
 
-				it("starts the impersonator with certs that match the first IP address", func() {
+				it("starts the impersonator according to the settings in the CredentialIssuer", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
 					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
 					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + ":443": testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-
-					// keeps the secret around after resync
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3) // nothing changed
-					requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
 				})
 			})
 
-			when("there are not visible control plane nodes and a load balancer already exists with multiple hostnames", func() {
-				firstHostname := "fake-1.example.com"
+			when("there are nodes which only match the default control plane node labels, but control plane node selectors are configured", func() {
 				it.Before(func() {
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{Hostname: firstHostname}, {Hostname: "fake-2.example.com"}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{Hostname: firstHostname}, {Hostname: "fake-2.example.com"}}, kubeAPIClient)
-					startInformersAndController()
-					r.NoError(runControllerSync())
+					var err error
+					controlPlaneNodeSelectors, err = clusterhost.ParseControlPlaneNodeSelectors([]string{"example.com/role=control"})
+					r.NoError(err)
+					addNodeWithRoleToTracker("control-plane", kubeAPIClient)
 				})
 
-				it("starts the impersonator with certs that match the first hostname", func() {
+				it("starts the impersonator according to the settings in the CredentialIssuer", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
 					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
 					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					requireTLSServerIsRunning(ca, firstHostname, map[string]string{firstHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(firstHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-
-					// keeps the secret around after resync
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3) // nothing changed
-					requireCredentialIssuer(newSuccessStrategy(firstHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
 				})
 			})
+		})
 
-			when("there are not visible control plane nodes and a load balancer already exists with hostnames and ips", func() {
-				firstHostname := "fake-1.example.com"
+		for _, autoModeTest := range []struct {
+			name                string
+			autoMode            *v1alpha1.ImpersonationProxyAutoModeSpec
+			createsLoadBalancer bool
+		}{
+			{name: "autoMode is not configured", autoMode: nil, createsLoadBalancer: true},
+			{name: "autoMode.createLoadBalancer is true", autoMode: &v1alpha1.ImpersonationProxyAutoModeSpec{CreateLoadBalancer: pointer.Bool(true)}, createsLoadBalancer: true},
+			{name: "autoMode.createLoadBalancer is false", autoMode: &v1alpha1.ImpersonationProxyAutoModeSpec{CreateLoadBalancer: pointer.Bool(false)}, createsLoadBalancer: false},
+		} {
+			autoModeTest := autoModeTest
+
+			when("the configuration is auto mode and "+autoModeTest.name, func() {
 				it.Before(func() {
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "127.0.0.254"}, {Hostname: firstHostname}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "127.0.0.254"}, {Hostname: firstHostname}}, kubeAPIClient)
-					startInformersAndController()
-					r.NoError(runControllerSync())
+					addSecretToTrackers(signingCASecret, kubeInformerClient)
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:     v1alpha1.ImpersonationProxyModeAuto,
+								AutoMode: autoModeTest.autoMode,
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
 				})
 
-				it("starts the impersonator with certs that match the first hostname", func() {
-					r.Len(kubeAPIClient.Actions(), 3)
-					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					requireTLSServerIsRunning(ca, firstHostname, map[string]string{firstHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(firstHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				when("there are visible control plane nodes", func() {
+					it.Before(func() {
+						addNodeWithRoleToTracker("control-plane", kubeAPIClient)
+					})
 
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+					it("does not start the impersonator or load balancer", func() {
+						startInformersAndController()
+						r.NoError(runControllerSync())
+						requireTLSServerWasNeverStarted()
+						r.Len(kubeAPIClient.Actions(), 1)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						requireCredentialIssuer(newAutoDisabledStrategy())
+						requireSigningCertProviderIsEmpty()
+					})
+				})
 
-					// keeps the secret around after resync
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3) // nothing changed
-					requireCredentialIssuer(newSuccessStrategy(firstHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				when("there are visible control plane nodes and a loadbalancer and a tls Secret", func() {
+					it.Before(func() {
+						addNodeWithRoleToTracker("control-plane", kubeAPIClient)
+						addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeInformerClient)
+						addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeAPIClient)
+						addSecretToTrackers(newEmptySecret(tlsSecretName), kubeAPIClient, kubeInformerClient)
+					})
+
+					it("does not start the impersonator, deletes the loadbalancer unless it was never created, deletes the Secret", func() {
+						startInformersAndController()
+						r.NoError(runControllerSync())
+						requireTLSServerWasNeverStarted()
+						if autoModeTest.createsLoadBalancer {
+							r.Len(kubeAPIClient.Actions(), 3)
+							requireNodesListed(kubeAPIClient.Actions()[0])
+							requireServiceWasDeleted(kubeAPIClient.Actions()[1], loadBalancerServiceName)
+							requireTLSSecretWasDeleted(kubeAPIClient.Actions()[2])
+						} else {
+							r.Len(kubeAPIClient.Actions(), 2)
+							requireNodesListed(kubeAPIClient.Actions()[0])
+							requireTLSSecretWasDeleted(kubeAPIClient.Actions()[1])
+						}
+						requireCredentialIssuer(newAutoDisabledStrategy())
+						requireSigningCertProviderIsEmpty()
+					})
 				})
-			})
 
-			when("there are not visible control plane nodes, a TLS secret exists with multiple hostnames and an IP", func() {
-				var caCrt []byte
-				it.Before(func() {
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeAPIClient)
-					ca := newCA()
-					caSecret := newActualCASecret(ca, caSecretName)
-					caCrt = caSecret.Data["ca.crt"]
-					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
-					addSecretToTrackers(newActualTLSSecretWithMultipleHostnames(ca, tlsSecretName, localhostIP), kubeAPIClient, kubeInformerClient)
-					startInformersAndController()
-					r.NoError(runControllerSync())
+				when("there are not visible control plane nodes", func() {
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
+
+					it("starts the load balancer automatically unless it should not create one", func() {
+						requireTLSServerIsRunningWithoutCerts()
+						if autoModeTest.createsLoadBalancer {
+							r.Len(kubeAPIClient.Actions(), 3)
+							requireNodesListed(kubeAPIClient.Actions()[0])
+							requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+							requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+						} else {
+							r.Len(kubeAPIClient.Actions(), 2)
+							requireNodesListed(kubeAPIClient.Actions()[0])
+							requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						}
+						requireCredentialIssuer(newPendingStrategyWaitingForLB())
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
 				})
 
-				it("deletes and recreates the secret to match the IP in the load balancer without the extra hostnames", func() {
-					r.Len(kubeAPIClient.Actions(), 3)
-					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], caCrt)
-					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
-					requireCredentialIssuer(newSuccessStrategy(localhostIP, caCrt))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				when("there are not visible control plane nodes and an external endpoint is configured", func() {
+					const externalEndpoint = "impersonator.example.com"
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						credIssuersGVR := v1alpha1.Resource("credentialissuers").WithVersion("v1alpha1")
+						for _, client := range []*pinnipedfake.Clientset{pinnipedInformerClient, pinnipedAPIClient} {
+							r.NoError(client.Tracker().Update(credIssuersGVR, &v1alpha1.CredentialIssuer{
+								ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+								Spec: v1alpha1.CredentialIssuerSpec{
+									ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+										Mode:             v1alpha1.ImpersonationProxyModeAuto,
+										AutoMode:         autoModeTest.autoMode,
+										ExternalEndpoint: externalEndpoint,
+									},
+								},
+							}, ""))
+						}
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
+
+					it("starts the impersonator with certs that match the external endpoint", func() {
+						var ca []byte
+						if autoModeTest.createsLoadBalancer {
+							r.Len(kubeAPIClient.Actions(), 4)
+							requireNodesListed(kubeAPIClient.Actions()[0])
+							requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+							ca = requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+							requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+						} else {
+							r.Len(kubeAPIClient.Actions(), 3)
+							requireNodesListed(kubeAPIClient.Actions()[0])
+							ca = requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+							requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						}
+						requireTLSServerIsRunning(ca, externalEndpoint, map[string]string{externalEndpoint + httpsPort: testServerAddr()})
+						requireCredentialIssuer(newSuccessStrategy(externalEndpoint, ca))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
 				})
-			})
 
-			when("the cert's name needs to change but there is an error while deleting the tls Secret", func() {
-				it.Before(func() {
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "127.0.0.42"}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "127.0.0.42"}}, kubeAPIClient)
-					ca := newCA()
-					addSecretToTrackers(newActualCASecret(ca, caSecretName), kubeAPIClient, kubeInformerClient)
-					addSecretToTrackers(newActualTLSSecretWithMultipleHostnames(ca, tlsSecretName, localhostIP), kubeAPIClient, kubeInformerClient)
-					kubeAPIClient.PrependReactor("delete", "secrets", func(action coretesting.Action) (handled bool, ret runtime.Object, err error) {
-						return true, nil, fmt.Errorf("error on delete")
+				if autoModeTest.createsLoadBalancer {
+					when("there are not visible control plane nodes and a service selector is configured", func() {
+						it.Before(func() {
+							serviceSelector = map[string]string{"app": "custom-app", "component": "concierge"}
+							addNodeWithRoleToTracker("worker", kubeAPIClient)
+							startInformersAndController()
+							r.NoError(runControllerSync())
+						})
+
+						it("starts the load balancer automatically with the configured selector", func() {
+							requireTLSServerIsRunningWithoutCerts()
+							r.Len(kubeAPIClient.Actions(), 3)
+							requireNodesListed(kubeAPIClient.Actions()[0])
+							createdService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+							r.Equal(map[string]string{"app": "custom-app", "component": "concierge"}, createdService.Spec.Selector)
+							requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+							requireCredentialIssuer(newPendingStrategyWaitingForLB())
+							requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+						})
+					})
+				}
+
+				when("there are not visible control plane nodes and a load balancer already exists without an IP/hostname", func() {
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeInformerClient)
+						addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeAPIClient)
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
+
+					it("does not start the load balancer automatically", func() {
+						requireTLSServerIsRunningWithoutCerts()
+						r.Len(kubeAPIClient.Actions(), 2)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireCredentialIssuer(newPendingStrategyWaitingForLB())
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 					})
 				})
 
-				it("returns an error and runs the proxy without certs", func() {
-					startInformersAndController()
-					r.Error(runControllerSync(), "error on delete")
-					r.Len(kubeAPIClient.Actions(), 2)
-					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[1])
-					requireTLSServerIsRunningWithoutCerts()
-					requireCredentialIssuer(newErrorStrategy("error on delete"))
-					requireSigningCertProviderIsEmpty()
+				when("there are not visible control plane nodes and a load balancer already exists with empty ingress", func() {
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "", Hostname: ""}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "", Hostname: ""}}, kubeAPIClient)
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
+
+					it("does not start the load balancer automatically", func() {
+						requireTLSServerIsRunningWithoutCerts()
+						r.Len(kubeAPIClient.Actions(), 2)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireCredentialIssuer(newPendingStrategyWaitingForLB())
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
 				})
-			})
 
-			when("the cert's name might need to change but there is an error while determining the new name", func() {
-				var caCrt []byte
-				it.Before(func() {
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeAPIClient)
-					ca := newCA()
-					caSecret := newActualCASecret(ca, caSecretName)
-					caCrt = caSecret.Data["ca.crt"]
-					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
-					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
-					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
+				when("there are not visible control plane nodes and a load balancer already exists with invalid ip", func() {
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "not-an-ip"}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "not-an-ip"}}, kubeAPIClient)
+						startInformersAndController()
+						r.EqualError(runControllerSync(), "could not find valid IP addresses or hostnames from load balancer some-namespace/some-service-resource-name")
+					})
+
+					it("does not start the load balancer automatically", func() {
+						requireTLSServerIsRunningWithoutCerts()
+						r.Len(kubeAPIClient.Actions(), 1)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						requireCredentialIssuer(newErrorStrategy("could not find valid IP addresses or hostnames from load balancer some-namespace/some-service-resource-name"))
+						requireSigningCertProviderIsEmpty()
+					})
 				})
 
-				it("returns an error and keeps the proxy running but now without certs", func() {
-					startInformersAndController()
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 1)
-					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+				when("there are not visible control plane nodes and a load balancer already exists with multiple ips", func() {
+					const fakeIP = "127.0.0.123"
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: fakeIP}, {IP: "127.0.0.456"}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: fakeIP}, {IP: "127.0.0.456"}}, kubeAPIClient)
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
 
-					updateLoadBalancerServiceInInformerAndWait(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "not-an-ip"}}, kubeInformers.Core().V1().Services())
+					it("starts the impersonator with certs that match the first IP address", func() {
+						r.Len(kubeAPIClient.Actions(), 3)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + ":443": testServerAddr()})
+						requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 
-					errString := "could not find valid IP addresses or hostnames from load balancer some-namespace/some-service-resource-name"
-					r.EqualError(runControllerSync(), errString)
-					r.Len(kubeAPIClient.Actions(), 1)                       // no new actions
-					requireTLSServerIsRunning(caCrt, testServerAddr(), nil) // serving certificate is not unloaded in this case
-					requireCredentialIssuer(newErrorStrategy(errString))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+						// Simulate the informer cache's background update from its watch.
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+						// keeps the secret around after resync
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3) // nothing changed
+						requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
+				})
+
+				when("there are not visible control plane nodes and a load balancer already exists with an IPv6 ip", func() {
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "::1"}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "::1"}}, kubeAPIClient)
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
+
+					it("starts the impersonator with certs that match the IPv6 address and advertises it in brackets", func() {
+						r.Len(kubeAPIClient.Actions(), 3)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						requireTLSServerIsRunning(ca, "[::1]", map[string]string{"[::1]:443": testServerAddr()})
+						requireCredentialIssuer(newSuccessStrategy("[::1]", ca))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
+				})
+
+				when("there are not visible control plane nodes and a load balancer already exists with multiple hostnames", func() {
+					firstHostname := "fake-1.example.com"
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{Hostname: firstHostname}, {Hostname: "fake-2.example.com"}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{Hostname: firstHostname}, {Hostname: "fake-2.example.com"}}, kubeAPIClient)
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
+
+					it("starts the impersonator with certs that match the first hostname", func() {
+						r.Len(kubeAPIClient.Actions(), 3)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						requireTLSServerIsRunning(ca, firstHostname, map[string]string{firstHostname + httpsPort: testServerAddr()})
+						requireCredentialIssuer(newSuccessStrategy(firstHostname, ca))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+						// Simulate the informer cache's background update from its watch.
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+						// keeps the secret around after resync
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3) // nothing changed
+						requireCredentialIssuer(newSuccessStrategy(firstHostname, ca))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
+				})
+
+				when("there are not visible control plane nodes and a load balancer already exists with hostnames and ips", func() {
+					firstHostname := "fake-1.example.com"
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "127.0.0.254"}, {Hostname: firstHostname}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "127.0.0.254"}, {Hostname: firstHostname}}, kubeAPIClient)
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
+
+					it("starts the impersonator with certs that match the first hostname", func() {
+						r.Len(kubeAPIClient.Actions(), 3)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						requireTLSServerIsRunning(ca, firstHostname, map[string]string{firstHostname + httpsPort: testServerAddr()})
+						requireCredentialIssuer(newSuccessStrategy(firstHostname, ca))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+						// Simulate the informer cache's background update from its watch.
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+						// keeps the secret around after resync
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3) // nothing changed
+						requireCredentialIssuer(newSuccessStrategy(firstHostname, ca))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
+				})
+
+				when("there are not visible control plane nodes, a TLS secret exists with multiple hostnames and an IP", func() {
+					var caCrt []byte
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeAPIClient)
+						ca := newCA()
+						caSecret := newActualCASecret(ca, caSecretName)
+						caCrt = caSecret.Data["ca.crt"]
+						addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+						addSecretToTrackers(newActualTLSSecretWithMultipleHostnames(ca, tlsSecretName, localhostIP), kubeAPIClient, kubeInformerClient)
+						startInformersAndController()
+						r.NoError(runControllerSync())
+					})
+
+					it("deletes and recreates the secret to match the IP in the load balancer without the extra hostnames", func() {
+						r.Len(kubeAPIClient.Actions(), 3)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						requireTLSSecretWasDeleted(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], caCrt)
+						requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+						requireCredentialIssuer(newSuccessStrategy(localhostIP, caCrt))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
+				})
+
+				when("the cert's name needs to change but there is an error while deleting the tls Secret", func() {
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "127.0.0.42"}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "127.0.0.42"}}, kubeAPIClient)
+						ca := newCA()
+						addSecretToTrackers(newActualCASecret(ca, caSecretName), kubeAPIClient, kubeInformerClient)
+						addSecretToTrackers(newActualTLSSecretWithMultipleHostnames(ca, tlsSecretName, localhostIP), kubeAPIClient, kubeInformerClient)
+						kubeAPIClient.PrependReactor("delete", "secrets", func(action coretesting.Action) (handled bool, ret runtime.Object, err error) {
+							return true, nil, fmt.Errorf("error on delete")
+						})
+					})
+
+					it("returns an error and runs the proxy without certs", func() {
+						startInformersAndController()
+						r.Error(runControllerSync(), "error on delete")
+						r.Len(kubeAPIClient.Actions(), 2)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						requireTLSSecretWasDeleted(kubeAPIClient.Actions()[1])
+						requireTLSServerIsRunningWithoutCerts()
+						requireCredentialIssuer(newErrorStrategy("error on delete"))
+						requireSigningCertProviderIsEmpty()
+					})
+				})
+
+				when("the cert's name might need to change but there is an error while determining the new name", func() {
+					var caCrt []byte
+					it.Before(func() {
+						addNodeWithRoleToTracker("worker", kubeAPIClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeInformerClient)
+						addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeAPIClient)
+						ca := newCA()
+						caSecret := newActualCASecret(ca, caSecretName)
+						caCrt = caSecret.Data["ca.crt"]
+						addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+						tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
+						addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
+					})
+
+					it("returns an error and keeps the proxy running but now without certs", func() {
+						startInformersAndController()
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 1)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+
+						updateLoadBalancerServiceInInformerAndWait(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: "not-an-ip"}}, kubeInformers.Core().V1().Services())
+
+						errString := "could not find valid IP addresses or hostnames from load balancer some-namespace/some-service-resource-name"
+						r.EqualError(runControllerSync(), errString)
+						r.Len(kubeAPIClient.Actions(), 1)                       // no new actions
+						requireTLSServerIsRunning(caCrt, testServerAddr(), nil) // serving certificate is not unloaded in this case
+						requireCredentialIssuer(newErrorStrategy(errString))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
 				})
 			})
-		})
+		}
 
 		when("the configuration is disabled mode", func() {
 			it.Before(func() {
@@ -1525,6 +2150,13 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				requireCredentialIssuer(newManuallyDisabledStrategy())
 				requireSigningCertProviderIsEmpty()
 			})
+
+			it("schedules the next sync for the resync interval", func() {
+				startInformersAndController()
+				r.NoError(runControllerSync())
+				r.Equal(syncContext.Key, queue.afterKey)
+				r.Equal(resyncInterval, queue.afterDuration)
+			})
 		})
 
 		when("the configuration is enabled mode", func() {
@@ -1554,6 +2186,10 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 					requireTLSServerIsRunningWithoutCerts()
 					requireCredentialIssuer(newPendingStrategyWaitingForLB())
 					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireEventsRecorded(
+						"Normal ImpersonationProxyStarted Started impersonation proxy on port 8444",
+						"Normal ServiceCreated Created LoadBalancer Service for impersonation proxy",
+					)
 				})
 
 				it("returns an error when the impersonation TLS server fails to start", func() {
@@ -1562,6 +2198,9 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 					r.EqualError(runControllerSync(), "impersonation server start error")
 					requireCredentialIssuer(newErrorStrategy("impersonation server start error"))
 					requireSigningCertProviderIsEmpty()
+					requireEventsRecorded(
+						"Warning SyncFailed Failed to configure impersonation proxy: impersonation server start error",
+					)
 				})
 			})
 
@@ -1600,8 +2239,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				})
 			})
 
-			when("a clusterip already exists with ingress", func() {
-				const fakeIP = "127.0.0.123"
+			when("a clusterip already exists with an IPv6 ip", func() {
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
@@ -1615,26 +2253,24 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addClusterIPServiceToTracker(clusterIPServiceName, fakeIP, kubeInformerClient)
-					addClusterIPServiceToTracker(clusterIPServiceName, fakeIP, kubeAPIClient)
+					addClusterIPServiceToTracker(clusterIPServiceName, "::1", kubeInformerClient)
+					addClusterIPServiceToTracker(clusterIPServiceName, "::1", kubeAPIClient)
 				})
 
-				it("starts the impersonator without creating a clusterip", func() {
+				it("starts the impersonator with certs that match the IPv6 address and advertises it in brackets", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
 					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
 					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + ":443": testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
-					// requireSigningCertProviderHasLoadedCerts()
+					requireTLSServerIsRunning(ca, "[::1]", map[string]string{"[::1]:443": testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy("[::1]", ca))
 				})
 			})
 
-			when("a clusterip service exists with dual stack ips", func() {
-				const fakeIP1 = "127.0.0.123"
-				const fakeIP2 = "fd00::5118"
+			when("a clusterip already exists with ingress", func() {
+				const fakeIP = "127.0.0.123"
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
@@ -1648,57 +2284,59 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					addDualStackClusterIPServiceToTracker(clusterIPServiceName, fakeIP1, fakeIP2, kubeInformerClient)
-					addDualStackClusterIPServiceToTracker(clusterIPServiceName, fakeIP1, fakeIP2, kubeAPIClient)
+					addClusterIPServiceToTracker(clusterIPServiceName, fakeIP, kubeInformerClient)
+					addClusterIPServiceToTracker(clusterIPServiceName, fakeIP, kubeAPIClient)
 				})
 
-				it("certs are valid for both ip addresses", func() {
+				it("starts the impersonator without creating a clusterip", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
 					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
 					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					requireTLSServerIsRunning(ca, "["+fakeIP2+"]", map[string]string{"[fd00::5118]:443": testServerAddr()})
-					requireTLSServerIsRunning(ca, fakeIP1, map[string]string{fakeIP1 + ":443": testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeIP1, ca))
+					requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + ":443": testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
+					// requireSigningCertProviderHasLoadedCerts()
 				})
 			})
 
-			when("a load balancer and a secret already exists", func() {
-				var caCrt []byte
+			when("the CredentialIssuer has service type loadbalancer with an additional clusterip and no services exist yet", func() {
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type:                v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+									Annotations:         map[string]string{"some-annotation": "some-value"},
+									AdditionalClusterIP: true,
+								},
 							},
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					ca := newCA()
-					caSecret := newActualCASecret(ca, caSecretName)
-					caCrt = caSecret.Data["ca.crt"]
-					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
-					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
-					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeInformerClient)
-					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeAPIClient)
 				})
 
-				it("starts the impersonator with the existing tls certs, does not start loadbalancer or make tls secret", func() {
+				it("starts the impersonator and creates both services, with the annotations only on the load balancer", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 1)
+					r.Len(kubeAPIClient.Actions(), 4)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
-					requireCredentialIssuer(newSuccessStrategy(localhostIP, caCrt))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+					r.Equal("some-value", lbService.Annotations["some-annotation"])
+					clusterIPService := requireClusterIPWasCreated(kubeAPIClient.Actions()[2])
+					r.Empty(clusterIPService.Annotations)
+					requireCASecretWasCreated(kubeAPIClient.Actions()[3])
+					requireTLSServerIsRunningWithoutCerts()
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
 				})
 			})
 
-			when("credentialissuer has service type loadbalancer and custom annotations", func() {
+			when("the CredentialIssuer has service type loadbalancer with an additional clusterip and both services already exist", func() {
+				const fakeHostname = "fake-1.example.com"
+				const fakeIP = "127.0.0.123"
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
@@ -1706,73 +2344,135 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 								Mode: v1alpha1.ImpersonationProxyModeEnabled,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
-									Type:        v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
-									Annotations: map[string]string{"some-annotation-key": "some-annotation-value"},
+									Type:                v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+									AdditionalClusterIP: true,
 								},
 							},
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{Hostname: fakeHostname}}, kubeInformerClient)
+					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{Hostname: fakeHostname}}, kubeAPIClient)
+					addClusterIPServiceToTracker(clusterIPServiceName, fakeIP, kubeInformerClient)
+					addClusterIPServiceToTracker(clusterIPServiceName, fakeIP, kubeAPIClient)
 				})
 
-				it("starts the impersonator, generates a valid cert for the specified hostname, starts a loadbalancer", func() {
+				it("starts the impersonator with certs that match both services and advertises the load balancer", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
-					require.Equal(t, lbService.Annotations, map[string]string{
-						"some-annotation-key":                           "some-annotation-value",
-						"credentialissuer.pinniped.dev/annotation-keys": `["some-annotation-key"]`,
-					})
-					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
-					requireTLSServerIsRunningWithoutCerts()
-					requireCredentialIssuer(newPendingStrategyWaitingForLB())
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + ":443": testServerAddr()})
+					requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + ":443": testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// keeps the secret around after resync
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3) // nothing changed
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
 				})
 			})
 
-			when("the CredentialIssuer has a hostname specified and service type none", func() {
-				const fakeHostname = "fake.example.com"
+			when("the CredentialIssuer has service type loadbalancer which resolves the hostname of the load balancer", func() {
+				const fakeHostname = "fake-1.example.com"
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeHostname,
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
-									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+									Type:                    v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+									ResolveEndpointHostname: true,
 								},
 							},
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{Hostname: fakeHostname}}, kubeInformerClient)
+					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{Hostname: fakeHostname}}, kubeAPIClient)
 				})
 
-				it("starts the impersonator, generates a valid cert for the specified hostname", func() {
-					startInformersAndController()
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3)
-					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				var requireTLSSecretNames = func(action coretesting.Action, wantIPs []string) {
+					createdSecret := action.(coretesting.CreateAction).GetObject().(*corev1.Secret)
+					block, _ := pem.Decode(createdSecret.Data[corev1.TLSCertKey])
+					r.NotNil(block)
+					cert, err := x509.ParseCertificate(block.Bytes)
+					r.NoError(err)
+					r.Equal([]string{fakeHostname}, cert.DNSNames)
+					actualIPs := make([]string, 0, len(cert.IPAddresses))
+					for _, ip := range cert.IPAddresses {
+						actualIPs = append(actualIPs, ip.String())
+					}
+					r.Equal(wantIPs, actualIPs)
+				}
+
+				when("the hostname can be resolved", func() {
+					it.Before(func() {
+						lookupIP = func(_ context.Context, host string) ([]net.IP, error) {
+							r.Equal(fakeHostname, host)
+							return []net.IP{net.ParseIP("127.0.0.42"), net.ParseIP("127.0.0.41"), net.ParseIP("127.0.0.42")}, nil
+						}
+					})
+
+					it("starts the impersonator with certs that match the hostname and its sorted IPs", func() {
+						startInformersAndController()
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						requireTLSSecretNames(kubeAPIClient.Actions()[2], []string{"127.0.0.41", "127.0.0.42"})
+						requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + ":443": testServerAddr()})
+						requireTLSServerIsRunning(ca, "127.0.0.41", map[string]string{"127.0.0.41:443": testServerAddr()})
+						requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+						r.Equal(controllerlib.Key{}, queue.key) // no retry was needed
+
+						// Simulate the informer cache's background update from its watch.
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+						// keeps the secret around after resync
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3) // nothing changed
+					})
+				})
+
+				when("the hostname cannot be resolved", func() {
+					it.Before(func() {
+						lookupIP = func(_ context.Context, _ string) ([]net.IP, error) {
+							return nil, errors.New("some lookup error")
+						}
+					})
+
+					it("starts the impersonator with certs that match only the hostname and tries again later", func() {
+						startInformersAndController()
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						requireTLSSecretNames(kubeAPIClient.Actions()[2], []string{})
+						requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + ":443": testServerAddr()})
+						requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+						r.Equal(syncContext.Key, queue.key)
+					})
 				})
 			})
 
-			when("the CredentialIssuer has a hostname specified and service type loadbalancer", func() {
-				const fakeHostname = "fake.example.com"
+			when("the CredentialIssuer has service type loadbalancer without an additional clusterip and both services already exist", func() {
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeHostname,
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
 									Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
 								},
@@ -1780,24 +2480,24 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeInformerClient)
+					addLoadBalancerServiceToTracker(loadBalancerServiceName, kubeAPIClient)
+					addClusterIPServiceToTracker(clusterIPServiceName, "127.0.0.123", kubeInformerClient)
+					addClusterIPServiceToTracker(clusterIPServiceName, "127.0.0.123", kubeAPIClient)
 				})
 
-				it("starts the impersonator, generates a valid cert for the specified hostname, starts a loadbalancer", func() {
+				it("deletes the clusterip and keeps the load balancer", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 4)
+					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireServiceWasDeleted(kubeAPIClient.Actions()[1], clusterIPServiceName)
+					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
 				})
 			})
 
-			when("the CredentialIssuer has a hostname specified and service type clusterip", func() {
+			when("the CredentialIssuer has service type nodeport and the node port service does not exist yet", func() {
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
@@ -1805,7 +2505,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 								Mode: v1alpha1.ImpersonationProxyModeEnabled,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
-									Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+									Type: v1alpha1.ImpersonationProxyServiceTypeNodePort,
 								},
 							},
 						},
@@ -1813,12 +2513,17 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
 				})
 
-				it("starts the impersonator and creates a clusterip service", func() {
+				it("starts the impersonator and creates a nodeport service", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireClusterIPWasCreated(kubeAPIClient.Actions()[1])
+					nodePortService := requireNodePortWasCreated(kubeAPIClient.Actions()[1])
+					r.Equal([]corev1.ServicePort{{
+						TargetPort: intstr.FromInt(impersonationProxyPort),
+						Port:       defaultHTTPSPort,
+						Protocol:   corev1.ProtocolTCP,
+					}}, nodePortService.Spec.Ports)
 					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
 					// Check that the server is running without certs.
 					requireTLSServerIsRunningWithoutCerts()
@@ -1827,192 +2532,155 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				})
 			})
 
-			when("the CredentialIssuer has a endpoint which is an IP address with a port", func() {
-				const fakeIPWithPort = "127.0.0.1:3000"
+			when("a nodeport service already exists with an assigned node port", func() {
+				const fakeExternalIP = "127.0.0.42"
+				const nodePort = 30443
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeIPWithPort,
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
-									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+									Type: v1alpha1.ImpersonationProxyServiceTypeNodePort,
 								},
 							},
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
-					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					addNodeWithAddressesToTracker("node-b", []corev1.NodeAddress{
+						{Type: corev1.NodeInternalIP, Address: "10.0.0.2"},
+						{Type: corev1.NodeExternalIP, Address: "127.0.0.99"},
+					}, kubeAPIClient)
+					addNodeWithAddressesToTracker("node-a", []corev1.NodeAddress{
+						{Type: corev1.NodeHostName, Address: "node-a"},
+						{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+						{Type: corev1.NodeExternalIP, Address: fakeExternalIP},
+					}, kubeAPIClient)
+					addNodePortServiceToTracker(nodePortServiceName, nodePort, kubeInformerClient)
+					addNodePortServiceToTracker(nodePortServiceName, nodePort, kubeAPIClient)
 				})
 
-				it("starts the impersonator, generates a valid cert for the specified IP address", func() {
+				it("starts the impersonator without creating a nodeport service and uses the first node's external IP with the node port", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3)
+					r.Len(kubeAPIClient.Actions(), 4)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeIPWithPort.
-					requireTLSServerIsRunning(ca, fakeIPWithPort, map[string]string{fakeIPWithPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeIPWithPort, ca))
+					requireNodesListed(kubeAPIClient.Actions()[1])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+					endpoint := fakeExternalIP + ":30443"
+					requireTLSServerIsRunning(ca, endpoint, map[string]string{endpoint: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(endpoint, ca))
 					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 				})
 			})
 
-			when("the CredentialIssuer has a endpoint which is a hostname with a port, service type none", func() {
-				const fakeHostnameWithPort = "fake.example.com:3000"
+			when("a nodeport service exists but no node has an IP address", func() {
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeHostnameWithPort,
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
-									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+									Type: v1alpha1.ImpersonationProxyServiceTypeNodePort,
 								},
 							},
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					addNodePortServiceToTracker(nodePortServiceName, 30443, kubeInformerClient)
+					addNodePortServiceToTracker(nodePortServiceName, 30443, kubeAPIClient)
 				})
 
-				it("starts the impersonator, generates a valid cert for the specified hostname", func() {
+				it("starts the impersonator without certs and waits", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostnameWithPort.
-					requireTLSServerIsRunning(ca, fakeHostnameWithPort, map[string]string{fakeHostnameWithPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostnameWithPort, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireNodesListed(kubeAPIClient.Actions()[1])
+					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireTLSServerIsRunningWithoutCerts()
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
 				})
 			})
 
-			when("the CredentialIssuer has a endpoint which is a hostname with a port, service type loadbalancer with loadbalancerip", func() {
-				const fakeHostnameWithPort = "fake.example.com:3000"
+			when("a clusterip service exists with dual stack ips", func() {
+				const fakeIP1 = "127.0.0.123"
+				const fakeIP2 = "fd00::5118"
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeHostnameWithPort,
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
-									Type:           v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
-									LoadBalancerIP: localhostIP,
+									Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
 								},
 							},
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					addDualStackClusterIPServiceToTracker(clusterIPServiceName, fakeIP1, fakeIP2, kubeInformerClient)
+					addDualStackClusterIPServiceToTracker(clusterIPServiceName, fakeIP1, fakeIP2, kubeAPIClient)
 				})
 
-				it("starts the impersonator, starts the loadbalancer, generates a valid cert for the specified hostname", func() {
+				it("certs are valid for both ip addresses", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 4)
+					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
-					require.Equal(t, lbService.Spec.LoadBalancerIP, localhostIP)
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostnameWithPort.
-					requireTLSServerIsRunning(ca, fakeHostnameWithPort, map[string]string{fakeHostnameWithPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostnameWithPort, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					requireTLSServerIsRunning(ca, "["+fakeIP2+"]", map[string]string{"[fd00::5118]:443": testServerAddr()})
+					requireTLSServerIsRunning(ca, fakeIP1, map[string]string{fakeIP1 + ":443": testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeIP1, ca))
 				})
 			})
 
-			when("switching the CredentialIssuer from ip address endpoint to hostname endpoint and back to ip address", func() {
-				const fakeHostname = "fake.example.com"
-				const fakeIP = "127.0.0.42"
-
-				var hostnameConfig = v1alpha1.CredentialIssuerSpec{
-					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-						ExternalEndpoint: fakeHostname,
-						Service: v1alpha1.ImpersonationProxyServiceSpec{
-							Type: v1alpha1.ImpersonationProxyServiceTypeNone,
-						},
-					},
-				}
-				var ipAddressConfig = v1alpha1.CredentialIssuerSpec{
-					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-						ExternalEndpoint: fakeIP,
-						Service: v1alpha1.ImpersonationProxyServiceSpec{
-							Type: v1alpha1.ImpersonationProxyServiceTypeNone,
-						},
-					},
-				}
-
+			when("a load balancer and a secret already exists", func() {
+				var caCrt []byte
 				it.Before(func() {
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
-						Spec:       ipAddressConfig,
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+							},
+						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					ca := newCA()
+					caSecret := newActualCASecret(ca, caSecretName)
+					caCrt = caSecret.Data["ca.crt"]
+					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
+					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
+					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeInformerClient)
+					addLoadBalancerServiceWithIngressToTracker(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeAPIClient)
 				})
 
-				it("regenerates the cert for the hostname, then regenerates it for the IP again", func() {
+				it("starts the impersonator with the existing tls certs, does not start loadbalancer or make tls secret", func() {
 					startInformersAndController()
 					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3)
+					r.Len(kubeAPIClient.Actions(), 1)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeIP.
-					requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-
-					// Switch the endpoint config to a hostname.
-					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, hostnameConfig, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
-
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 5)
-					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[4], ca) // reuses the old CA
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-					// Simulate the informer cache's background update from its watch.
-					deleteSecretFromTracker(tlsSecretName, kubeInformerClient)
-					waitForObjectToBeDeletedFromInformer(tlsSecretName, kubeInformers.Core().V1().Secrets())
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[4], kubeInformers.Core().V1().Secrets())
-
-					// Switch the endpoint config back to an IP.
-					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, ipAddressConfig, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
-
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 7)
-					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[5])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[6], ca) // reuses the old CA again
-					// Check that the server is running and that TLS certs that are being served are are for fakeIP.
-					requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
+					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, caCrt))
 					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 				})
 			})
 
-			when("the TLS cert goes missing and needs to be recreated, e.g. when a user manually deleted it", func() {
-				const fakeHostname = "fake.example.com"
+			when("the existing CA and TLS certs have passed three quarters of their lifetimes", func() {
+				var oldCACrt []byte
 				it.Before(func() {
+					frozenNow = time.Now().Add(20 * time.Hour) // both certs are valid for 24 hours
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeHostname,
+								ExternalEndpoint: localhostIP,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
 									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
 								},
@@ -2020,47 +2688,38 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					startInformersAndController()
+					ca := newCA()
+					caSecret := newActualCASecret(ca, caSecretName)
+					oldCACrt = caSecret.Data["ca.crt"]
+					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
+					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
 				})
 
-				it("uses the existing CA cert the make a new TLS cert", func() {
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3)
-					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
-
-					// Delete the TLS Secret that was just created from the Kube API server. Note that we never
-					// simulated it getting added to the informer cache, so we don't need to remove it from there.
-					deleteSecretFromTracker(tlsSecretName, kubeAPIClient)
-
-					// Run again. It should create a new TLS cert using the old CA cert.
+				it("rotates the CA and then issues a new TLS cert from the new CA", func() {
+					startInformersAndController()
 					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 4)
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					newCACrt := requireCASecretWasUpdated(kubeAPIClient.Actions()[1], oldCACrt)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], newCACrt)
+					requireTLSServerIsRunning(newCACrt, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, newCACrt))
 					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 				})
 			})
 
-			when("the CA cert goes missing and needs to be recreated, e.g. when a user manually deleted it", func() {
-				const fakeHostname = "fake.example.com"
+			when("a CA subject is configured and the existing CA certificate has a different subject", func() {
+				var oldCACrt []byte
 				it.Before(func() {
+					caSubject = pkix.Name{CommonName: "my-cluster Impersonation Proxy CA", Organization: []string{"Example Org"}}
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeHostname,
+								ExternalEndpoint: localhostIP,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
 									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
 								},
@@ -2068,50 +2727,37 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					startInformersAndController()
+					ca := newCA()
+					caSecret := newActualCASecret(ca, caSecretName)
+					oldCACrt = caSecret.Data["ca.crt"]
+					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
+					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
 				})
 
-				it("makes a new CA cert, deletes the old TLS cert, and makes a new TLS cert using the new CA", func() {
+				it("replaces the CA with one which has the configured subject and then issues a new TLS cert from the new CA", func() {
+					startInformersAndController()
 					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3)
+					r.Len(kubeAPIClient.Actions(), 4)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-
-					// Delete the CA Secret that was just created from the Kube API server. Note that we never
-					// simulated it getting added to the informer cache, so we don't need to remove it from there.
-					deleteSecretFromTracker(caSecretName, kubeAPIClient)
-
-					// Run again. It should create both a new CA cert and a new TLS cert using the new CA cert.
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 6)
-					ca = requireCASecretWasCreated(kubeAPIClient.Actions()[3])
-					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[4])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[5], ca) // created using the new CA
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					newCACrt := requireCASecretWasUpdated(kubeAPIClient.Actions()[1], oldCACrt)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], newCACrt)
+					requireTLSServerIsRunning(newCACrt, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, newCACrt))
 				})
 			})
 
-			when("the CA cert is overwritten by another valid CA cert", func() {
-				const fakeHostname = "fake.example.com"
+			when("a CA subject is configured and the existing CA certificate already has that subject", func() {
 				var caCrt []byte
 				it.Before(func() {
+					caSubject = pkix.Name{CommonName: "my-cluster Impersonation Proxy CA", Organization: []string{"Example Org"}}
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeHostname,
+								ExternalEndpoint: localhostIP,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
 									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
 								},
@@ -2119,208 +2765,152 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
-					startInformersAndController()
-					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 3)
-					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-
-					// Simulate someone updating the CA Secret out of band, e.g. when a human edits it with kubectl.
-					// Delete the CA Secret that was just created from the Kube API server. Note that we never
-					// simulated it getting added to the informer cache, so we don't need to remove it from there.
-					// Then add a new one. Delete + new = update, since only the final state is observed.
-					deleteSecretFromTracker(caSecretName, kubeAPIClient)
-					anotherCA := newCA()
-					newCASecret := newActualCASecret(anotherCA, caSecretName)
-					caCrt = newCASecret.Data["ca.crt"]
-					addSecretToTrackers(newCASecret, kubeAPIClient)
-					addObjectToKubeInformerAndWait(newCASecret, kubeInformers.Core().V1().Secrets())
+					ca, err := certauthority.NewWithSubject(caSubject, 24*time.Hour)
+					r.NoError(err)
+					caSecret := newActualCASecret(ca, caSecretName)
+					caCrt = caSecret.Data["ca.crt"]
+					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
+					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
 				})
 
-				it("deletes the old TLS cert and makes a new TLS cert using the new CA", func() {
-					// Run again. It should use the updated CA cert to create a new TLS cert.
+				it("keeps the existing CA and TLS certs", func() {
+					startInformersAndController()
 					r.NoError(runControllerSync())
-					r.Len(kubeAPIClient.Actions(), 5)
-					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[4], caCrt) // created using the updated CA
-					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
-					requireTLSServerIsRunning(caCrt, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, caCrt))
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-				})
-
-				when("deleting the TLS cert due to mismatched CA results in an error", func() {
-					it.Before(func() {
-						kubeAPIClient.PrependReactor("delete", "secrets", func(action coretesting.Action) (handled bool, ret runtime.Object, err error) {
-							if action.(coretesting.DeleteAction).GetName() == tlsSecretName {
-								return true, nil, fmt.Errorf("error on tls secret delete")
-							}
-							return false, nil, nil
-						})
-					})
-
-					it("returns an error", func() {
-						r.Error(runControllerSync(), "error on tls secret delete")
-						r.Len(kubeAPIClient.Actions(), 4)
-						requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3]) // tried to delete cert but failed
-						requireCredentialIssuer(newErrorStrategy("error on tls secret delete"))
-						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-					})
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, caCrt))
 				})
 			})
-		})
 
-		when("the configuration switches from enabled to disabled mode", func() {
-			when("service type loadbalancer", func() {
+			when("a CA key algorithm is configured and the existing CA certificate has a different key algorithm", func() {
+				var oldCACrt []byte
 				it.Before(func() {
-					addSecretToTrackers(signingCASecret, kubeInformerClient)
+					caKeyAlgorithm = certauthority.KeyAlgorithmRSA2048
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: localhostIP,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
 							},
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					ca := newCA()
+					caSecret := newActualCASecret(ca, caSecretName)
+					oldCACrt = caSecret.Data["ca.crt"]
+					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
+					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
 				})
 
-				it("starts the impersonator and loadbalancer, then shuts it down, then starts it again", func() {
+				it("replaces the CA with one which has the configured key algorithm and then issues a new TLS cert from the new CA", func() {
 					startInformersAndController()
-
-					r.NoError(runControllerSync())
-					requireTLSServerIsRunningWithoutCerts()
-					r.Len(kubeAPIClient.Actions(), 3)
-					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
-					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
-					requireCredentialIssuer(newPendingStrategyWaitingForLB())
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM) // load when enabled
-
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-
-					// Update the CredentialIssuer.
-					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode: v1alpha1.ImpersonationProxyModeDisabled,
-						},
-					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
-
 					r.NoError(runControllerSync())
-					requireTLSServerIsNoLongerRunning()
 					r.Len(kubeAPIClient.Actions(), 4)
-					requireServiceWasDeleted(kubeAPIClient.Actions()[3], loadBalancerServiceName)
-					requireCredentialIssuer(newManuallyDisabledStrategy())
-					requireSigningCertProviderIsEmpty() // only unload when disabled
-
-					deleteServiceFromTracker(loadBalancerServiceName, kubeInformerClient)
-					waitForObjectToBeDeletedFromInformer(loadBalancerServiceName, kubeInformers.Core().V1().Services())
-
-					// Update the CredentialIssuer again.
-					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode: v1alpha1.ImpersonationProxyModeEnabled,
-						},
-					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
-
-					r.NoError(runControllerSync())
-					requireTLSServerIsRunningWithoutCerts()
-					r.Len(kubeAPIClient.Actions(), 5)
-					requireLoadBalancerWasCreated(kubeAPIClient.Actions()[4])
-					requireCredentialIssuer(newPendingStrategyWaitingForLB())
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM) // load again when enabled
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					newCACrt := requireCASecretWasUpdated(kubeAPIClient.Actions()[1], oldCACrt)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], newCACrt)
+					requireTLSServerIsRunning(newCACrt, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, newCACrt))
+
+					tlsSecret := kubeAPIClient.Actions()[3].(coretesting.CreateAction).GetObject().(*corev1.Secret)
+					tlsCert, err := tls.X509KeyPair(tlsSecret.Data[corev1.TLSCertKey], tlsSecret.Data[corev1.TLSPrivateKeyKey])
+					r.NoError(err)
+					r.IsType(&rsa.PrivateKey{}, tlsCert.PrivateKey)
 				})
 			})
 
-			when("service type clusterip", func() {
+			when("a CA key algorithm is configured and the existing CA certificate already has that key algorithm", func() {
+				var caCrt []byte
 				it.Before(func() {
-					addSecretToTrackers(signingCASecret, kubeInformerClient)
+					caKeyAlgorithm = certauthority.KeyAlgorithmECDSAP384
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: localhostIP,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
-									Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
 								},
 							},
 						},
 					}, pinnipedInformerClient, pinnipedAPIClient)
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					ca, err := certauthority.NewWithKeyAlgorithm(pkix.Name{CommonName: "Pinniped Impersonation Proxy Serving CA"}, 24*time.Hour, caKeyAlgorithm)
+					r.NoError(err)
+					caSecret := newActualCASecret(ca, caSecretName)
+					caCrt = caSecret.Data["ca.crt"]
+					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
+					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
 				})
 
-				it("starts the impersonator and clusterip, then shuts it down, then starts it again", func() {
+				it("keeps the existing CA and TLS certs", func() {
 					startInformersAndController()
-
 					r.NoError(runControllerSync())
-					requireTLSServerIsRunningWithoutCerts()
-					r.Len(kubeAPIClient.Actions(), 3)
+					r.Len(kubeAPIClient.Actions(), 1)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					requireClusterIPWasCreated(kubeAPIClient.Actions()[1])
-					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
-					requireCredentialIssuer(newPendingStrategyWaitingForLB())
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM) // load when enabled
-
-					// Simulate the informer cache's background update from its watch.
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
-					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-
-					// Update the CredentialIssuer.
-					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode: v1alpha1.ImpersonationProxyModeDisabled,
-						},
-					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
-
-					r.NoError(runControllerSync())
-					requireTLSServerIsNoLongerRunning()
-					r.Len(kubeAPIClient.Actions(), 4)
-					requireServiceWasDeleted(kubeAPIClient.Actions()[3], clusterIPServiceName)
-					requireCredentialIssuer(newManuallyDisabledStrategy())
-					requireSigningCertProviderIsEmpty() // only unload when disabled
-
-					deleteServiceFromTracker(clusterIPServiceName, kubeInformerClient)
-					waitForObjectToBeDeletedFromInformer(clusterIPServiceName, kubeInformers.Core().V1().Services())
+					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, caCrt))
+				})
+			})
 
-					// Update the CredentialIssuer again.
-					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode: v1alpha1.ImpersonationProxyModeEnabled,
-							Service: v1alpha1.ImpersonationProxyServiceSpec{
-								Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+			when("only the existing TLS cert has passed three quarters of its lifetime", func() {
+				var caCrt []byte
+				it.Before(func() {
+					frozenNow = time.Now().Add(20 * time.Hour) // the TLS cert is valid for 24 hours
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: localhostIP,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
 							},
 						},
-					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					ca, err := certauthority.New(caCommonName, 100*24*time.Hour)
+					r.NoError(err)
+					caSecret := newActualCASecret(ca, caSecretName)
+					caCrt = caSecret.Data["ca.crt"]
+					addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+					tlsSecret := newActualTLSSecret(ca, tlsSecretName, localhostIP)
+					addSecretToTrackers(tlsSecret, kubeAPIClient, kubeInformerClient)
+				})
 
+				it("keeps the CA and issues a new TLS cert", func() {
+					startInformersAndController()
 					r.NoError(runControllerSync())
-					requireTLSServerIsRunningWithoutCerts()
-					r.Len(kubeAPIClient.Actions(), 5)
-					requireClusterIPWasCreated(kubeAPIClient.Actions()[4])
-					requireCredentialIssuer(newPendingStrategyWaitingForLB())
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM) // load again when enabled
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], caCrt)
+					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, caCrt))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 				})
 			})
 
-			when("service type none with a hostname", func() {
-				const fakeHostname = "hello.com"
+			when("the TLS cert enters its rotation window while the impersonator is running", func() {
 				it.Before(func() {
-					addSecretToTrackers(signingCASecret, kubeInformerClient)
+					frozenNow = time.Now()
 					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 						Spec: v1alpha1.CredentialIssuerSpec{
 							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-								ExternalEndpoint: fakeHostname,
+								ExternalEndpoint: localhostIP,
 								Service: v1alpha1.ImpersonationProxyServiceSpec{
 									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
 								},
@@ -2330,77 +2920,1755 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 					addNodeWithRoleToTracker("worker", kubeAPIClient)
 				})
 
-				it("starts the impersonator, then shuts it down, then starts it again", func() {
+				it("schedules a sync for the start of the rotation window, and then re-issues the TLS cert without restarting the server", func() {
 					startInformersAndController()
-
 					r.NoError(runControllerSync())
 					r.Len(kubeAPIClient.Actions(), 3)
 					requireNodesListed(kubeAPIClient.Actions()[0])
-					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					caCrt := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], caCrt)
+					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
 
-					// load when enabled
-					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-					requireTLSSecretProviderHasLoadedCerts()
+					// The TLS cert expires before the CA, so the next sync is scheduled for its rotation window,
+					// which is the last 25% of its lifetime.
+					oldCertPEM, _ := tlsServingCertDynamicCertProvider.CurrentCertKeyContent()
+					block, _ := pem.Decode(oldCertPEM)
+					r.NotNil(block)
+					oldCert, err := x509.ParseCertificate(block.Bytes)
+					r.NoError(err)
+					lifetime := oldCert.NotAfter.Sub(oldCert.NotBefore)
+					r.Equal(syncContext.Key, queue.afterKey)
+					r.Equal(oldCert.NotAfter.Add(-lifetime/4).Sub(frozenNow), queue.afterDuration)
 
 					// Simulate the informer cache's background update from its watch.
 					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
 					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
 
-					// Update the CredentialIssuer.
-					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode: v1alpha1.ImpersonationProxyModeDisabled,
+					// Before the rotation window, nothing changes.
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+
+					// Once the rotation window is reached, the TLS cert is replaced and the new cert is served by the
+					// already running server.
+					fakeClock.Step(queue.afterDuration)
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 5)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[4], caCrt)
+					newCertPEM, _ := tlsServingCertDynamicCertProvider.CurrentCertKeyContent()
+					r.NotEqual(oldCertPEM, newCertPEM)
+					r.Equal(1, impersonatorFuncWasCalled)
+					requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+				})
+			})
+
+			when("credentialissuer has service type loadbalancer and custom annotations", func() {
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type:        v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+									Annotations: map[string]string{"some-annotation-key": "some-annotation-value"},
+								},
+							},
 						},
-					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
 
+				it("starts the impersonator, generates a valid cert for the specified hostname, starts a loadbalancer", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+					require.Equal(t, lbService.Annotations, map[string]string{
+						"some-annotation-key":                           "some-annotation-value",
+						"credentialissuer.pinniped.dev/annotation-keys": `["some-annotation-key"]`,
+					})
+					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireTLSServerIsRunningWithoutCerts()
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("the CredentialIssuer has a hostname specified and service type none", func() {
+				const fakeHostname = "fake.example.com"
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeHostname,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator, generates a valid cert for the specified hostname", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireEventsRecorded(
+						"Normal ImpersonationProxyStarted Started impersonation proxy on port 8444",
+						"Normal CertificateIssued Issued TLS serving certificate for impersonation proxy with IPs [] and hostnames [fake.example.com]",
+					)
+				})
+
+				when("a state change webhook is configured", func() {
+					var webhookPayloads chan stateChangePayload
+					var webhookStatus int
+
+					var requireWebhookPayloads = func(want ...stateChangePayload) {
+						for _, wantPayload := range want {
+							select {
+							case payload := <-webhookPayloads:
+								r.Equal(wantPayload, payload)
+							case <-time.After(10 * time.Second):
+								r.FailNow("timed out waiting for state change webhook", "wanted %#v", wantPayload)
+							}
+						}
+					}
+
+					var caFingerprint = func(caPEM []byte) string {
+						block, _ := pem.Decode(caPEM)
+						r.NotNil(block)
+						sum := sha256.Sum256(block.Bytes)
+						return hex.EncodeToString(sum[:])
+					}
+
+					it.Before(func() {
+						webhookPayloads = make(chan stateChangePayload, 10)
+						webhookStatus = http.StatusNoContent
+						server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+							assert.Equal(t, http.MethodPost, req.Method)
+							assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+							var payload stateChangePayload
+							assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+							webhookPayloads <- payload
+							w.WriteHeader(webhookStatus)
+						}))
+						t.Cleanup(server.Close)
+						stateChangeWebhookURL = server.URL
+					})
+
+					it("notifies the webhook when the impersonator starts, issues a cert, and stops", func() {
+						startInformersAndController()
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3)
+						ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+						requireWebhookPayloads(
+							stateChangePayload{
+								Event:               stateChangeStarted,
+								Time:                frozenNow.UTC(),
+								Endpoint:            "https://" + fakeHostname,
+								CAFingerprintSHA256: caFingerprint(ca),
+							},
+							stateChangePayload{
+								Event:               stateChangeCertificateIssued,
+								Time:                frozenNow.UTC(),
+								Endpoint:            "https://" + fakeHostname,
+								CAFingerprintSHA256: caFingerprint(ca),
+							},
+						)
+
+						// Simulate the informer cache's background update from its watch.
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+						addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+						updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode: v1alpha1.ImpersonationProxyModeDisabled,
+							},
+						}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+						r.NoError(runControllerSync())
+						requireTLSServerIsNoLongerRunning()
+						requireCredentialIssuer(newManuallyDisabledStrategy())
+						requireWebhookPayloads(stateChangePayload{Event: stateChangeStopped, Time: frozenNow.UTC()})
+					})
+
+					it("does not fail the sync when the webhook returns an error", func() {
+						webhookStatus = http.StatusInternalServerError
+						startInformersAndController()
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3)
+						ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+						requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+						requireWebhookPayloads(
+							stateChangePayload{
+								Event:               stateChangeStarted,
+								Time:                frozenNow.UTC(),
+								Endpoint:            "https://" + fakeHostname,
+								CAFingerprintSHA256: caFingerprint(ca),
+							},
+							stateChangePayload{
+								Event:               stateChangeCertificateIssued,
+								Time:                frozenNow.UTC(),
+								Endpoint:            "https://" + fakeHostname,
+								CAFingerprintSHA256: caFingerprint(ca),
+							},
+						)
+					})
+				})
+			})
+
+			when("the CredentialIssuer has a hostname specified and service type loadbalancer", func() {
+				const fakeHostname = "fake.example.com"
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeHostname,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator, generates a valid cert for the specified hostname, starts a loadbalancer", func() {
+					startInformersAndController()
 					r.NoError(runControllerSync())
-					requireTLSServerIsNoLongerRunning()
 					r.Len(kubeAPIClient.Actions(), 4)
-					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3])
-					requireCredentialIssuer(newManuallyDisabledStrategy())
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("the CredentialIssuer has a hostname specified and service type clusterip", func() {
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator and creates a clusterip service", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireClusterIPWasCreated(kubeAPIClient.Actions()[1])
+					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					// Check that the server is running without certs.
+					requireTLSServerIsRunningWithoutCerts()
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("the CredentialIssuer has a endpoint which is an IP address with a port", func() {
+				const fakeIPWithPort = "127.0.0.1:3000"
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeIPWithPort,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator, generates a valid cert for the specified IP address", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeIPWithPort.
+					requireTLSServerIsRunning(ca, fakeIPWithPort, map[string]string{fakeIPWithPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeIPWithPort, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("the CredentialIssuer has a endpoint which is a hostname with a port, service type none", func() {
+				const fakeHostnameWithPort = "fake.example.com:3000"
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeHostnameWithPort,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator, generates a valid cert for the specified hostname", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostnameWithPort.
+					requireTLSServerIsRunning(ca, fakeHostnameWithPort, map[string]string{fakeHostnameWithPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostnameWithPort, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("additional SANs are configured and the CredentialIssuer has an endpoint which is an IP address", func() {
+				it.Before(func() {
+					additionalSANs = []string{"internal.example.com", "10.1.2.3", "fd00::1", localhostIP, "external.example.com"}
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: localhostIP,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				var requireTLSSecretNames = func(action coretesting.Action) {
+					createdSecret := action.(coretesting.CreateAction).GetObject().(*corev1.Secret)
+					block, _ := pem.Decode(createdSecret.Data[corev1.TLSCertKey])
+					r.NotNil(block)
+					cert, err := x509.ParseCertificate(block.Bytes)
+					r.NoError(err)
+					r.Equal([]string{"internal.example.com", "external.example.com"}, cert.DNSNames)
+					actualIPs := make([]string, 0, len(cert.IPAddresses))
+					for _, ip := range cert.IPAddresses {
+						actualIPs = append(actualIPs, ip.String())
+					}
+					// The endpoint's IP comes first, and the duplicate additional SAN is not repeated.
+					r.Equal([]string{localhostIP, "10.1.2.3", "fd00::1"}, actualIPs)
+				}
+
+				it("starts the impersonator with a cert which also has the additional DNS names and IPs", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					requireTLSSecretNames(kubeAPIClient.Actions()[2])
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
+					requireTLSServerIsRunning(ca, "internal.example.com", map[string]string{"internal.example.com:443": testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// keeps the secret around after resync
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3) // nothing changed
+				})
+
+				when("the existing TLS cert was issued without the additional SANs", func() {
+					var caCrt []byte
+					it.Before(func() {
+						ca := newCA()
+						caSecret := newActualCASecret(ca, caSecretName)
+						caCrt = caSecret.Data["ca.crt"]
+						addSecretToTrackers(caSecret, kubeAPIClient, kubeInformerClient)
+						addSecretToTrackers(newActualTLSSecret(ca, tlsSecretName, localhostIP), kubeAPIClient, kubeInformerClient)
+					})
+
+					it("re-issues the TLS cert with the additional SANs using the existing CA", func() {
+						startInformersAndController()
+						r.NoError(runControllerSync())
+						r.Len(kubeAPIClient.Actions(), 3)
+						requireNodesListed(kubeAPIClient.Actions()[0])
+						requireTLSSecretWasDeleted(kubeAPIClient.Actions()[1])
+						requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], caCrt)
+						requireTLSSecretNames(kubeAPIClient.Actions()[2])
+						requireTLSServerIsRunning(caCrt, testServerAddr(), nil)
+						requireCredentialIssuer(newSuccessStrategy(localhostIP, caCrt))
+					})
+				})
+			})
+
+			when("the CredentialIssuer has a endpoint which is a hostname with a port, service type loadbalancer with loadbalancerip", func() {
+				const fakeHostnameWithPort = "fake.example.com:3000"
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeHostnameWithPort,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type:           v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+									LoadBalancerIP: localhostIP,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator, starts the loadbalancer, generates a valid cert for the specified hostname", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+					require.Equal(t, lbService.Spec.LoadBalancerIP, localhostIP)
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostnameWithPort.
+					requireTLSServerIsRunning(ca, fakeHostnameWithPort, map[string]string{fakeHostnameWithPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostnameWithPort, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("switching the CredentialIssuer from ip address endpoint to hostname endpoint and back to ip address", func() {
+				const fakeHostname = "fake.example.com"
+				const fakeIP = "127.0.0.42"
+
+				var hostnameConfig = v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: fakeHostname,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+						},
+					},
+				}
+				var ipAddressConfig = v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: fakeIP,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+						},
+					},
+				}
+
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec:       ipAddressConfig,
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("regenerates the cert for the hostname, then regenerates it for the IP again", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeIP.
+					requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// Switch the endpoint config to a hostname.
+					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, hostnameConfig, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 5)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[4], ca) // reuses the old CA
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+					// Simulate the informer cache's background update from its watch.
+					deleteSecretFromTracker(tlsSecretName, kubeInformerClient)
+					waitForObjectToBeDeletedFromInformer(tlsSecretName, kubeInformers.Core().V1().Secrets())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[4], kubeInformers.Core().V1().Secrets())
+
+					// Switch the endpoint config back to an IP.
+					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, ipAddressConfig, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 7)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[5])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[6], ca) // reuses the old CA again
+					// Check that the server is running and that TLS certs that are being served are are for fakeIP.
+					requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("the TLS cert goes missing and needs to be recreated, e.g. when a user manually deleted it", func() {
+				const fakeHostname = "fake.example.com"
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeHostname,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					startInformersAndController()
+				})
+
+				it("uses the existing CA cert the make a new TLS cert", func() {
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+
+					// Delete the TLS Secret that was just created from the Kube API server. Note that we never
+					// simulated it getting added to the informer cache, so we don't need to remove it from there.
+					deleteSecretFromTracker(tlsSecretName, kubeAPIClient)
+
+					// Run again. It should create a new TLS cert using the old CA cert.
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("the CA cert goes missing and needs to be recreated, e.g. when a user manually deleted it", func() {
+				const fakeHostname = "fake.example.com"
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeHostname,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					startInformersAndController()
+				})
+
+				it("makes a new CA cert, deletes the old TLS cert, and makes a new TLS cert using the new CA", func() {
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// Delete the CA Secret that was just created from the Kube API server. Note that we never
+					// simulated it getting added to the informer cache, so we don't need to remove it from there.
+					deleteSecretFromTracker(caSecretName, kubeAPIClient)
+
+					// Run again. It should create both a new CA cert and a new TLS cert using the new CA cert.
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 6)
+					ca = requireCASecretWasCreated(kubeAPIClient.Actions()[3])
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[4])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[5], ca) // created using the new CA
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+			})
+
+			when("the CA cert is overwritten by another valid CA cert", func() {
+				const fakeHostname = "fake.example.com"
+				var caCrt []byte
+				it.Before(func() {
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeHostname,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// Simulate someone updating the CA Secret out of band, e.g. when a human edits it with kubectl.
+					// Delete the CA Secret that was just created from the Kube API server. Note that we never
+					// simulated it getting added to the informer cache, so we don't need to remove it from there.
+					// Then add a new one. Delete + new = update, since only the final state is observed.
+					deleteSecretFromTracker(caSecretName, kubeAPIClient)
+					anotherCA := newCA()
+					newCASecret := newActualCASecret(anotherCA, caSecretName)
+					caCrt = newCASecret.Data["ca.crt"]
+					addSecretToTrackers(newCASecret, kubeAPIClient)
+					addObjectToKubeInformerAndWait(newCASecret, kubeInformers.Core().V1().Secrets())
+				})
+
+				it("deletes the old TLS cert and makes a new TLS cert using the new CA", func() {
+					// Run again. It should use the updated CA cert to create a new TLS cert.
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 5)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[4], caCrt) // created using the updated CA
+					// Check that the server is running and that TLS certs that are being served are are for fakeHostname.
+					requireTLSServerIsRunning(caCrt, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, caCrt))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				})
+
+				when("deleting the TLS cert due to mismatched CA results in an error", func() {
+					it.Before(func() {
+						kubeAPIClient.PrependReactor("delete", "secrets", func(action coretesting.Action) (handled bool, ret runtime.Object, err error) {
+							if action.(coretesting.DeleteAction).GetName() == tlsSecretName {
+								return true, nil, fmt.Errorf("error on tls secret delete")
+							}
+							return false, nil, nil
+						})
+					})
+
+					it("returns an error", func() {
+						r.Error(runControllerSync(), "error on tls secret delete")
+						r.Len(kubeAPIClient.Actions(), 4)
+						requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3]) // tried to delete cert but failed
+						requireCredentialIssuer(newErrorStrategy("error on tls secret delete"))
+						requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					})
+				})
+			})
+		})
+
+		when("the configuration switches from enabled to disabled mode", func() {
+			when("service type loadbalancer", func() {
+				it.Before(func() {
+					addSecretToTrackers(signingCASecret, kubeInformerClient)
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator and loadbalancer, then shuts it down, then starts it again", func() {
+					startInformersAndController()
+
+					r.NoError(runControllerSync())
+					requireTLSServerIsRunningWithoutCerts()
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM) // load when enabled
+					requireEventsRecorded(
+						"Normal ImpersonationProxyStarted Started impersonation proxy on port 8444",
+						"Normal ServiceCreated Created LoadBalancer Service for impersonation proxy",
+					)
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           1,
+						"listener_stops_total":            0,
+						"tls_certificate_issuances_total": 0,
+						"load_balancer_creates_total":     1,
+						"load_balancer_deletes_total":     0,
+					})
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// Update the CredentialIssuer.
+					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeDisabled,
+						},
+					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
+					requireTLSServerIsNoLongerRunning()
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireServiceWasDeleted(kubeAPIClient.Actions()[3], loadBalancerServiceName)
+					requireCredentialIssuer(newManuallyDisabledStrategy())
+					requireSigningCertProviderIsEmpty() // only unload when disabled
+					requireEventsRecorded("Normal ImpersonationProxyStopped Stopped impersonation proxy")
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           1,
+						"listener_stops_total":            1,
+						"tls_certificate_issuances_total": 0,
+						"load_balancer_creates_total":     1,
+						"load_balancer_deletes_total":     1,
+					})
+
+					deleteServiceFromTracker(loadBalancerServiceName, kubeInformerClient)
+					waitForObjectToBeDeletedFromInformer(loadBalancerServiceName, kubeInformers.Core().V1().Services())
+
+					// Update the CredentialIssuer again.
+					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeEnabled,
+						},
+					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
+					requireTLSServerIsRunningWithoutCerts()
+					r.Len(kubeAPIClient.Actions(), 5)
+					requireLoadBalancerWasCreated(kubeAPIClient.Actions()[4])
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM) // load again when enabled
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           2,
+						"listener_stops_total":            1,
+						"tls_certificate_issuances_total": 0,
+						"load_balancer_creates_total":     2,
+						"load_balancer_deletes_total":     1,
+					})
+				})
+			})
+
+			when("service type clusterip", func() {
+				it.Before(func() {
+					addSecretToTrackers(signingCASecret, kubeInformerClient)
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator and clusterip, then shuts it down, then starts it again", func() {
+					startInformersAndController()
+
+					r.NoError(runControllerSync())
+					requireTLSServerIsRunningWithoutCerts()
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireClusterIPWasCreated(kubeAPIClient.Actions()[1])
+					requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM) // load when enabled
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// Update the CredentialIssuer.
+					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeDisabled,
+						},
+					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
+					requireTLSServerIsNoLongerRunning()
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireServiceWasDeleted(kubeAPIClient.Actions()[3], clusterIPServiceName)
+					requireCredentialIssuer(newManuallyDisabledStrategy())
+					requireSigningCertProviderIsEmpty() // only unload when disabled
+
+					deleteServiceFromTracker(clusterIPServiceName, kubeInformerClient)
+					waitForObjectToBeDeletedFromInformer(clusterIPServiceName, kubeInformers.Core().V1().Services())
+
+					// Update the CredentialIssuer again.
+					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeEnabled,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+							},
+						},
+					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
+					requireTLSServerIsRunningWithoutCerts()
+					r.Len(kubeAPIClient.Actions(), 5)
+					requireClusterIPWasCreated(kubeAPIClient.Actions()[4])
+					requireCredentialIssuer(newPendingStrategyWaitingForLB())
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM) // load again when enabled
+				})
+			})
+
+			when("service type none with a hostname", func() {
+				const fakeHostname = "hello.com"
+				it.Before(func() {
+					addSecretToTrackers(signingCASecret, kubeInformerClient)
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+								ExternalEndpoint: fakeHostname,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("worker", kubeAPIClient)
+				})
+
+				it("starts the impersonator, then shuts it down, then starts it again", func() {
+					startInformersAndController()
+
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 3)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+
+					// load when enabled
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireTLSSecretProviderHasLoadedCerts()
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           1,
+						"listener_stops_total":            0,
+						"tls_certificate_issuances_total": 1,
+						"load_balancer_creates_total":     0,
+						"load_balancer_deletes_total":     0,
+					})
+
+					// Simulate the informer cache's background update from its watch.
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+					addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+					// Update the CredentialIssuer.
+					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeDisabled,
+						},
+					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
+					requireTLSServerIsNoLongerRunning()
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3])
+					requireCredentialIssuer(newManuallyDisabledStrategy())
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           1,
+						"listener_stops_total":            1,
+						"tls_certificate_issuances_total": 1,
+						"load_balancer_creates_total":     0,
+						"load_balancer_deletes_total":     0,
+					})
+
+					// only unload when disabled
+					requireSigningCertProviderIsEmpty()
+					requireTLSSecretProviderIsEmpty()
+
+					deleteSecretFromTracker(tlsSecretName, kubeInformerClient)
+					waitForObjectToBeDeletedFromInformer(tlsSecretName, kubeInformers.Core().V1().Secrets())
+
+					// Update the CredentialIssuer again.
+					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: fakeHostname,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+							},
+						},
+					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+					r.NoError(runControllerSync())
+					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
+					r.Len(kubeAPIClient.Actions(), 5)
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[4], ca)
+					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+
+					// The CA Secret was reused rather than regenerated, so only the TLS serving cert was re-issued and
+					// kubeconfigs which were distributed before the disable/enable cycle still trust the impersonator.
+					caSecret, err := kubeAPIClient.CoreV1().Secrets(installedInNamespace).Get(context.Background(), caSecretName, metav1.GetOptions{})
+					r.NoError(err)
+					r.Equal(ca, caSecret.Data["ca.crt"])
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           2,
+						"listener_stops_total":            1,
+						"tls_certificate_issuances_total": 2,
+						"load_balancer_creates_total":     0,
+						"load_balancer_deletes_total":     0,
+					})
+
+					// load again when enabled
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireTLSSecretProviderHasLoadedCerts()
+				})
+			})
+		})
+
+		when("the configuration switches from enabled to disabled mode and the impersonator does not stop cleanly", func() {
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+			})
+
+			var startThenDisable = func() {
+				startInformersAndController()
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 3)
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode: v1alpha1.ImpersonationProxyModeDisabled,
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+			}
+
+			var requireNextSyncFinishesDisabling = func() {
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4)
+				requireTLSSecretWasDeleted(kubeAPIClient.Actions()[3])
+				requireCredentialIssuer(newManuallyDisabledStrategy())
+				requireTLSServerIsNoLongerRunning()
+			}
+
+			when("some connections do not drain before the drain timeout", func() {
+				it.Before(func() {
+					impersonatorFuncReturnedFuncStopError = fmt.Errorf("%w: closing 1 connections which were still open after 30s", impersonator.ErrDrainTimeout)
+				})
+
+				it("stops the impersonator and returns a drain timeout error, and then finishes disabling it on the next sync", func() {
+					startThenDisable()
+
+					wantErr := "impersonation proxy connections did not drain within 30s: " +
+						"timed out waiting for impersonation proxy connections to drain: closing 1 connections which were still open after 30s"
+					r.EqualError(runControllerSync(), wantErr)
+					requireCredentialIssuer(newErrorStrategy(wantErr))
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           1,
+						"listener_stops_total":            1,
+						"tls_certificate_issuances_total": 1,
+						"load_balancer_creates_total":     0,
+						"load_balancer_deletes_total":     0,
+					})
+
+					requireNextSyncFinishesDisabling()
+				})
+			})
+
+			when("closing the connections fails", func() {
+				it.Before(func() {
+					impersonatorFuncReturnedFuncStopError = errors.New("some close error")
+				})
+
+				it("stops the impersonator and returns the close error, and then finishes disabling it on the next sync", func() {
+					startThenDisable()
+
+					wantErr := "error while shutting down impersonation proxy: some close error"
+					r.EqualError(runControllerSync(), wantErr)
+					requireCredentialIssuer(newErrorStrategy(wantErr))
+
+					requireNextSyncFinishesDisabling()
+				})
+			})
+		})
+
+		when("the endpoint and mode switch from specified with no service, to not specified, to specified again", func() {
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+			})
+
+			it("doesn't create, then creates, then deletes the load balancer", func() {
+				startInformersAndController()
+
+				// Should have started in "enabled" mode with an "endpoint", so no load balancer is needed.
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 3)
+				requireNodesListed(kubeAPIClient.Actions()[0])
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1]) // created immediately because "endpoint" was specified
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+				// Switch to "enabled" mode without an "endpoint", so a load balancer is needed now.
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode: v1alpha1.ImpersonationProxyModeEnabled,
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 5)
+				requireLoadBalancerWasCreated(kubeAPIClient.Actions()[3])
+				requireTLSSecretWasDeleted(kubeAPIClient.Actions()[4]) // the Secret was deleted because it contained a cert with the wrong IP
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)   // serving certificate is not unloaded in this case
+				requireCredentialIssuer(newPendingStrategyWaitingForLB())
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Services())
+				deleteSecretFromTracker(tlsSecretName, kubeInformerClient)
+				waitForObjectToBeDeletedFromInformer(tlsSecretName, kubeInformers.Core().V1().Secrets())
+
+				// The controller should be waiting for the load balancer's ingress to become available.
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 5)                    // no new actions while it is waiting for the load balancer's ingress
+				requireTLSServerIsRunning(ca, testServerAddr(), nil) // serving certificate is not unloaded in this case
+				requireCredentialIssuer(newPendingStrategyWaitingForLB())
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Update the ingress of the LB in the informer's client and run Sync again.
+				fakeIP := "127.0.0.123"
+				updateLoadBalancerServiceInInformerAndWait(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: fakeIP}}, kubeInformers.Core().V1().Services())
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 6)
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[5], ca) // reuses the existing CA
+				// Check that the server is running and that TLS certs that are being served are are for fakeIP.
+				requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + httpsPort: testServerAddr()})
+				requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[5], kubeInformers.Core().V1().Secrets())
+
+				// Now switch back to having the "endpoint" specified and explicitly saying that we don't want the load balancer service.
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: localhostIP,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+						},
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 9)
+				requireServiceWasDeleted(kubeAPIClient.Actions()[6], loadBalancerServiceName)
+				requireTLSSecretWasDeleted(kubeAPIClient.Actions()[7])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[8], ca) // recreated because the endpoint was updated, reused the old CA
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+			})
+		})
+
+		when("requesting a load balancer via CredentialIssuer, then updating the annotations", func() {
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+			})
+
+			it("creates the load balancer without annotations, then adds them", func() {
+				startInformersAndController()
+
+				// Should have started in "enabled" mode with service type load balancer, so one is created.
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4)
+				requireNodesListed(kubeAPIClient.Actions()[0])
+				lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+				require.Equal(t, map[string]string(nil), lbService.Annotations) // there should be no annotations at first
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Simulate another actor in the system, like a human user or a non-Pinniped controller,
+				// updating the new Service's annotations. The map was nil, so we can overwrite the whole thing,
+				lbService.Annotations = map[string]string{
+					"annotation-from-unrelated-controller-key": "annotation-from-unrelated-controller-val",
+					"my-annotation-key":                        "my-annotation-from-unrelated-controller-val",
+				}
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectToKubeInformerAndWait(lbService, kubeInformers.Core().V1().Services())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4) // no new actions because the controller decides there is nothing to update on the Service
+
+				// Add annotations to the CredentialIssuer spec.
+				credentialIssuerAnnotations := map[string]string{"my-annotation-key": "my-annotation-val"}
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: localhostIP,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type:        v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							Annotations: credentialIssuerAnnotations,
+						},
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 5) // one more item to update the loadbalancer
+				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[4])
+				require.Equal(t, map[string]string{
+					// Now the CredentialIssuer annotations should be merged on the load balancer.
+					// In the unlikely case where keys conflict, the CredentialIssuer value overwrites the other value.
+					// Otherwise the annotations from the other actor should not be modified.
+					"annotation-from-unrelated-controller-key":      "annotation-from-unrelated-controller-val",
+					"my-annotation-key":                             "my-annotation-val",
+					"credentialissuer.pinniped.dev/annotation-keys": `["my-annotation-key"]`,
+				}, lbService.Annotations)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+			})
+		})
+
+		when("requesting a cluster ip via CredentialIssuer, then updating the annotations", func() {
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+			})
+
+			it("creates the cluster ip without annotations, then adds them", func() {
+				startInformersAndController()
+
+				// Should have started in "enabled" mode with service type load balancer, so one is created.
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4)
+				requireNodesListed(kubeAPIClient.Actions()[0])
+				clusterIPService := requireClusterIPWasCreated(kubeAPIClient.Actions()[1])
+				require.Equal(t, map[string]string(nil), clusterIPService.Annotations) // there should be no annotations at first
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Simulate another actor in the system, like a human user or a non-Pinniped controller,
+				// updating the new Service's annotations.
+				clusterIPService.Annotations = map[string]string{
+					"annotation-from-unrelated-controller-key": "annotation-from-unrelated-controller-val",
+					"my-annotation-key":                        "my-annotation-from-unrelated-controller-val",
+				}
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectToKubeInformerAndWait(clusterIPService, kubeInformers.Core().V1().Services())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4) // no new actions because the controller decides there is nothing to update on the Service
+
+				// Add annotations to the CredentialIssuer spec.
+				credentialIssuerAnnotations := map[string]string{"my-annotation-key": "my-annotation-val"}
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: localhostIP,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type:        v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+							Annotations: credentialIssuerAnnotations,
+						},
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 5) // one more item to update the loadbalancer
+				clusterIPService = requireClusterIPWasUpdated(kubeAPIClient.Actions()[4])
+				require.Equal(t, map[string]string{
+					// Now the CredentialIssuer annotations should be merged on the load balancer.
+					// In the unlikely case where keys conflict, the CredentialIssuer value overwrites the other value.
+					// Otherwise the annotations from the other actor should not be modified.
+					"annotation-from-unrelated-controller-key":      "annotation-from-unrelated-controller-val",
+					"my-annotation-key":                             "my-annotation-val",
+					"credentialissuer.pinniped.dev/annotation-keys": `["my-annotation-key"]`,
+				}, clusterIPService.Annotations)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+			})
+		})
+
+		when("requesting a load balancer via CredentialIssuer with annotations, then updating the CredentialIssuer annotations to remove one", func() {
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+								Annotations: map[string]string{
+									"my-initial-annotation1-key": "my-initial-annotation1-val",
+									"my-initial-annotation2-key": "my-initial-annotation2-val",
+									"my-initial-annotation3-key": "my-initial-annotation3-val",
+								},
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+			})
+
+			it("creates the load balancer with annotations, then removes the removed annotation", func() {
+				startInformersAndController()
+
+				// Should have started in "enabled" mode with service type load balancer, so one is created.
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4)
+				requireNodesListed(kubeAPIClient.Actions()[0])
+				lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+				require.Equal(t, map[string]string{
+					"my-initial-annotation1-key":                    "my-initial-annotation1-val",
+					"my-initial-annotation2-key":                    "my-initial-annotation2-val",
+					"my-initial-annotation3-key":                    "my-initial-annotation3-val",
+					"credentialissuer.pinniped.dev/annotation-keys": `["my-initial-annotation1-key","my-initial-annotation2-key","my-initial-annotation3-key"]`,
+				}, lbService.Annotations) // there should be some annotations at first
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Simulate another actor in the system, like a human user or a non-Pinniped controller,
+				// updating the new Service to add another annotation.
+				lbService.Annotations["annotation-from-unrelated-controller-key"] = "annotation-from-unrelated-controller-val"
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectToKubeInformerAndWait(lbService, kubeInformers.Core().V1().Services())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4) // no new actions because the controller decides there is nothing to update on the Service
+
+				// Remove one of the annotations from the CredentialIssuer spec.
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: localhostIP,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							Annotations: map[string]string{
+								"my-initial-annotation1-key": "my-initial-annotation1-val",
+								"my-initial-annotation3-key": "my-initial-annotation3-val",
+							},
+						},
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 5) // one more item to update the loadbalancer
+				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[4])
+				require.Equal(t, map[string]string{
+					// Now the CredentialIssuer annotations should be merged on the load balancer.
+					// Since the user removed the "my-initial-annotation2-key" key from the CredentialIssuer spec,
+					// it should be removed from the Service.
+					// The annotations from the other actor should not be modified.
+					"annotation-from-unrelated-controller-key":      "annotation-from-unrelated-controller-val",
+					"my-initial-annotation1-key":                    "my-initial-annotation1-val",
+					"my-initial-annotation3-key":                    "my-initial-annotation3-val",
+					"credentialissuer.pinniped.dev/annotation-keys": `["my-initial-annotation1-key","my-initial-annotation3-key"]`,
+				}, lbService.Annotations)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Remove all the rest of the annotations from the CredentialIssuer spec so there are none remaining.
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: localhostIP,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type:        v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							Annotations: map[string]string{},
+						},
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 6) // one more item to update the loadbalancer
+				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[5])
+				require.Equal(t, map[string]string{
+					// Since the user removed all annotations from the CredentialIssuer spec,
+					// they should all be removed from the Service, along with the special bookkeeping annotation too.
+					// The annotations from the other actor should not be modified.
+					"annotation-from-unrelated-controller-key": "annotation-from-unrelated-controller-val",
+				}, lbService.Annotations)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+			})
+		})
+
+		when("the configuration is enabled mode with an endpoint and service type external, and there is already a load balancer", func() {
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeExternal,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+				addServiceToTrackers(newLoadBalancerService(loadBalancerServiceName, corev1.ServiceStatus{}), kubeInformerClient, kubeAPIClient)
+			})
+
+			it("starts the impersonator using the endpoint without creating or deleting any services", func() {
+				startInformersAndController()
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 3)
+				requireNodesListed(kubeAPIClient.Actions()[0])
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				requireMetricValues(map[string]float64{
+					"listener_starts_total":           1,
+					"listener_stops_total":            0,
+					"tls_certificate_issuances_total": 1,
+					"load_balancer_creates_total":     0,
+					"load_balancer_deletes_total":     0,
+				})
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+				// Syncing again should not touch the pre-existing load balancer.
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 3)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+			})
+		})
+
+		when("the TLS Secret was provided by the user", func() {
+			var userCA *certauthority.CA
+			var userProvidedTLSSecret *corev1.Secret
+
+			var addCredentialIssuerWithMode = func(mode v1alpha1.ImpersonationProxyMode) {
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             mode,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			}
+
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+				userCA = newCA()
+				userProvidedTLSSecret = newActualTLSSecret(userCA, tlsSecretName, localhostIP)
+				userProvidedTLSSecret.Annotations = map[string]string{"credentialissuer.pinniped.dev/user-provided-tls-certificate": "true"}
+			})
+
+			when("its certificate has not expired and it includes a CA bundle", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+					userProvidedTLSSecret.Data["ca.crt"] = userCA.Bundle()
+					addSecretToTrackers(userProvidedTLSSecret, kubeAPIClient, kubeInformerClient)
+				})
+
+				it("serves the user-provided certificate without creating a CA or issuing a certificate", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerIsRunning(userCA.Bundle(), testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, userCA.Bundle()))
+					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           1,
+						"listener_stops_total":            0,
+						"tls_certificate_issuances_total": 0,
+						"load_balancer_creates_total":     0,
+						"load_balancer_deletes_total":     0,
+					})
+
+					// The next sync is scheduled for when the user-provided certificate expires.
+					block, _ := pem.Decode(userProvidedTLSSecret.Data[corev1.TLSCertKey])
+					r.NotNil(block)
+					userCert, err := x509.ParseCertificate(block.Bytes)
+					r.NoError(err)
+					r.Equal(syncContext.Key, queue.afterKey)
+					r.Equal(userCert.NotAfter.Sub(frozenNow), queue.afterDuration)
+				})
+			})
+
+			when("its certificate has not expired and it does not include a CA bundle", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+					addSecretToTrackers(userProvidedTLSSecret, kubeAPIClient, kubeInformerClient)
+				})
+
+				it("serves the user-provided certificate and advertises the certificate itself as the CA bundle", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSSecretProviderHasLoadedCerts()
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, userProvidedTLSSecret.Data[corev1.TLSCertKey]))
+				})
+			})
+
+			when("its certificate has expired", func() {
+				it.Before(func() {
+					frozenNow = time.Now().Add(25 * time.Hour) // the user-provided cert is valid for 24 hours
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+					addSecretToTrackers(userProvidedTLSSecret, kubeAPIClient, kubeInformerClient)
+				})
+
+				it("falls back to replacing it with a certificate issued by a generated CA", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				})
+			})
+
+			when("it is missing its private key", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+					delete(userProvidedTLSSecret.Data, corev1.TLSPrivateKeyKey)
+					addSecretToTrackers(userProvidedTLSSecret, kubeAPIClient, kubeInformerClient)
+				})
+
+				it("falls back to replacing it with a certificate issued by a generated CA", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+					requireTLSSecretWasDeleted(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+					requireTLSServerIsRunning(ca, testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				})
+			})
 
-					// only unload when disabled
-					requireSigningCertProviderIsEmpty()
+			when("the impersonator is disabled", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeDisabled)
+					addSecretToTrackers(userProvidedTLSSecret, kubeAPIClient, kubeInformerClient)
+				})
+
+				it("does not delete the user-provided Secret", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerWasNeverStarted()
 					requireTLSSecretProviderIsEmpty()
+					requireCredentialIssuer(newManuallyDisabledStrategy())
+				})
+			})
+		})
 
-					deleteSecretFromTracker(tlsSecretName, kubeInformerClient)
-					waitForObjectToBeDeletedFromInformer(tlsSecretName, kubeInformers.Core().V1().Secrets())
+		when("an externally managed TLS Secret is referenced", func() {
+			const referencedTLSSecretName = "some-cert-manager-secret" //nolint:gosec // this is not a credential
+			var referencedCA *certauthority.CA
+			var referencedTLSSecret *corev1.Secret
 
-					// Update the CredentialIssuer again.
-					updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+			var addCredentialIssuerWithMode = func(mode v1alpha1.ImpersonationProxyMode) {
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
 						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-							ExternalEndpoint: fakeHostname,
+							Mode:             mode,
+							ExternalEndpoint: localhostIP,
 							Service: v1alpha1.ImpersonationProxyServiceSpec{
 								Type: v1alpha1.ImpersonationProxyServiceTypeNone,
 							},
 						},
-					}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			}
 
-					r.NoError(runControllerSync())
-					requireTLSServerIsRunning(ca, fakeHostname, map[string]string{fakeHostname + httpsPort: testServerAddr()})
-					r.Len(kubeAPIClient.Actions(), 5)
-					requireTLSSecretWasCreated(kubeAPIClient.Actions()[4], ca)
-					requireCredentialIssuer(newSuccessStrategy(fakeHostname, ca))
+			it.Before(func() {
+				tlsSecretRef = referencedTLSSecretName
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+				referencedCA = newCA()
+				referencedTLSSecret = newActualTLSSecret(referencedCA, referencedTLSSecretName, localhostIP)
+			})
 
-					// load again when enabled
+			when("the referenced Secret has a valid certificate and a CA bundle", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+					referencedTLSSecret.Data["ca.crt"] = referencedCA.Bundle()
+					addSecretToTrackers(referencedTLSSecret, kubeAPIClient, kubeInformerClient)
+				})
+
+				it("serves the referenced certificate without creating a CA or the default TLS Secret", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerIsRunning(referencedCA.Bundle(), testServerAddr(), nil)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, referencedCA.Bundle()))
 					requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					requireMetricValues(map[string]float64{
+						"listener_starts_total":           1,
+						"listener_stops_total":            0,
+						"tls_certificate_issuances_total": 0,
+						"load_balancer_creates_total":     0,
+						"load_balancer_deletes_total":     0,
+					})
+
+					// The next sync is scheduled for when the referenced certificate expires.
+					block, _ := pem.Decode(referencedTLSSecret.Data[corev1.TLSCertKey])
+					r.NotNil(block)
+					referencedCert, err := x509.ParseCertificate(block.Bytes)
+					r.NoError(err)
+					r.Equal(syncContext.Key, queue.afterKey)
+					r.Equal(referencedCert.NotAfter.Sub(frozenNow), queue.afterDuration)
+				})
+			})
+
+			when("the referenced Secret has a valid certificate and no CA bundle", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+					addSecretToTrackers(referencedTLSSecret, kubeAPIClient, kubeInformerClient)
+				})
+
+				it("serves the referenced certificate and advertises the certificate itself as the CA bundle", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
 					requireTLSSecretProviderHasLoadedCerts()
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, referencedTLSSecret.Data[corev1.TLSCertKey]))
+				})
+			})
+
+			when("the referenced Secret does not exist", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+				})
+
+				it("returns an error without falling back to minting certificates", func() {
+					startInformersAndController()
+					errString := `could not load the referenced TLS Secret "some-cert-manager-secret": secret "some-cert-manager-secret" not found`
+					r.EqualError(runControllerSync(), errString)
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSSecretProviderIsEmpty()
+					requireCredentialIssuer(newErrorStrategy(errString))
+				})
+			})
+
+			when("the certificate of the referenced Secret has expired", func() {
+				it.Before(func() {
+					frozenNow = time.Now().Add(25 * time.Hour) // the referenced cert is valid for 24 hours
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+					addSecretToTrackers(referencedTLSSecret, kubeAPIClient, kubeInformerClient)
+				})
+
+				it("returns an error without falling back to minting certificates", func() {
+					startInformersAndController()
+					err := runControllerSync()
+					r.Error(err)
+					r.Regexp(`^found expired certificate in the referenced TLS Secret "some-cert-manager-secret": it expired at .+$`, err.Error())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSSecretProviderIsEmpty()
+					requireCredentialIssuer(newErrorStrategy(err.Error()))
+				})
+			})
+
+			when("the impersonator is disabled", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeDisabled)
+					addSecretToTrackers(referencedTLSSecret, kubeAPIClient, kubeInformerClient)
+					addSecretToTrackers(newActualTLSSecret(newCA(), tlsSecretName, localhostIP), kubeAPIClient, kubeInformerClient)
+				})
+
+				it("does not delete the referenced Secret or the default TLS Secret", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireTLSServerWasNeverStarted()
+					requireCredentialIssuer(newManuallyDisabledStrategy())
 				})
 			})
 		})
 
-		when("the endpoint and mode switch from specified with no service, to not specified, to specified again", func() {
-			it.Before(func() {
-				addSecretToTrackers(signingCASecret, kubeInformerClient)
+		when("the CA bundle is published in a ConfigMap", func() {
+			const configMapName = "some-ca-bundle-configmap-name"
+			var referencedCA *certauthority.CA
+
+			var addCredentialIssuerWithMode = func(mode v1alpha1.ImpersonationProxyMode) {
 				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 					Spec: v1alpha1.CredentialIssuerSpec{
 						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							Mode:             mode,
 							ExternalEndpoint: localhostIP,
 							Service: v1alpha1.ImpersonationProxyServiceSpec{
 								Type: v1alpha1.ImpersonationProxyServiceTypeNone,
@@ -2408,90 +4676,242 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 						},
 					},
 				}, pinnipedInformerClient, pinnipedAPIClient)
+			}
+
+			var addConfigMapToTrackers = func(data map[string]string, clients ...*kubernetesfake.Clientset) {
+				for _, client := range clients {
+					r.NoError(client.Tracker().Add(&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      configMapName,
+							Namespace: installedInNamespace,
+							UID:       "some-configmap-uid",
+							Labels:    map[string]string{"some-other-label": "some-other-value"},
+						},
+						Data: data,
+					}))
+				}
+			}
+
+			it.Before(func() {
+				// Use a referenced TLS Secret so that the CA bundle is known and no other Secrets are created.
+				tlsSecretRef = "some-cert-manager-secret"
+				caBundleConfigMapName = configMapName
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
 				addNodeWithRoleToTracker("worker", kubeAPIClient)
+				referencedCA = newCA()
+				referencedTLSSecret := newActualTLSSecret(referencedCA, tlsSecretRef, localhostIP)
+				referencedTLSSecret.Data["ca.crt"] = referencedCA.Bundle()
+				addSecretToTrackers(referencedTLSSecret, kubeAPIClient, kubeInformerClient)
 			})
 
-			it("doesn't create, then creates, then deletes the load balancer", func() {
-				startInformersAndController()
+			when("the impersonator is enabled and the ConfigMap does not exist", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+				})
 
-				// Should have started in "enabled" mode with an "endpoint", so no load balancer is needed.
-				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 3)
-				requireNodesListed(kubeAPIClient.Actions()[0])
-				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1]) // created immediately because "endpoint" was specified
-				requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
-				requireTLSServerIsRunning(ca, testServerAddr(), nil)
-				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				it("creates the ConfigMap with the CA bundle", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 2)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					createAction, ok := kubeAPIClient.Actions()[1].(coretesting.CreateAction)
+					r.True(ok, "should have been a CreateAction but was %s", kubeAPIClient.Actions()[1])
+					createdConfigMap, ok := createAction.GetObject().(*corev1.ConfigMap)
+					r.True(ok, "should have created a ConfigMap but created %s", createAction.GetObject())
+					r.Equal(configMapName, createdConfigMap.Name)
+					r.Equal(installedInNamespace, createdConfigMap.Namespace)
+					requireCreatedLabels(createdConfigMap.Labels)
+					r.Equal(map[string]string{"ca.crt": string(referencedCA.Bundle())}, createdConfigMap.Data)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, referencedCA.Bundle()))
+				})
+			})
 
-				// Simulate the informer cache's background update from its watch.
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+			when("the impersonator is enabled and the ConfigMap has a stale CA bundle", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+					addConfigMapToTrackers(map[string]string{"ca.crt": "some-old-ca-bundle"}, kubeAPIClient, kubeInformerClient)
+				})
 
-				// Switch to "enabled" mode without an "endpoint", so a load balancer is needed now.
-				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-						Mode: v1alpha1.ImpersonationProxyModeEnabled,
-					},
-				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+				it("updates the ConfigMap without removing its other labels", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 2)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					updateAction, ok := kubeAPIClient.Actions()[1].(coretesting.UpdateAction)
+					r.True(ok, "should have been an UpdateAction but was %s", kubeAPIClient.Actions()[1])
+					updatedConfigMap, ok := updateAction.GetObject().(*corev1.ConfigMap)
+					r.True(ok, "should have updated a ConfigMap but updated %s", updateAction.GetObject())
+					r.Equal(configMapName, updatedConfigMap.Name)
+					r.Equal(map[string]string{
+						"app":              "app-name",
+						"other-key":        "other-value",
+						"some-other-label": "some-other-value",
+					}, updatedConfigMap.Labels)
+					r.Equal(map[string]string{"ca.crt": string(referencedCA.Bundle())}, updatedConfigMap.Data)
+				})
+			})
 
-				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 5)
-				requireLoadBalancerWasCreated(kubeAPIClient.Actions()[3])
-				requireTLSSecretWasDeleted(kubeAPIClient.Actions()[4]) // the Secret was deleted because it contained a cert with the wrong IP
-				requireTLSServerIsRunning(ca, testServerAddr(), nil)   // serving certificate is not unloaded in this case
-				requireCredentialIssuer(newPendingStrategyWaitingForLB())
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+			when("the impersonator is enabled and the ConfigMap is already up to date", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeEnabled)
+				})
 
-				// Simulate the informer cache's background update from its watch.
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Services())
-				deleteSecretFromTracker(tlsSecretName, kubeInformerClient)
-				waitForObjectToBeDeletedFromInformer(tlsSecretName, kubeInformers.Core().V1().Secrets())
+				it("does not update the ConfigMap", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 2)
+					createdConfigMap := kubeAPIClient.Actions()[1].(coretesting.CreateAction).GetObject().(*corev1.ConfigMap)
+					r.NoError(kubeInformerClient.Tracker().Add(createdConfigMap))
+					waitForObjectToAppearInInformer(createdConfigMap, kubeInformers.Core().V1().ConfigMaps())
 
-				// The controller should be waiting for the load balancer's ingress to become available.
-				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 5)                    // no new actions while it is waiting for the load balancer's ingress
-				requireTLSServerIsRunning(ca, testServerAddr(), nil) // serving certificate is not unloaded in this case
-				requireCredentialIssuer(newPendingStrategyWaitingForLB())
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 2)
+				})
+			})
 
-				// Update the ingress of the LB in the informer's client and run Sync again.
-				fakeIP := "127.0.0.123"
-				updateLoadBalancerServiceInInformerAndWait(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: fakeIP}}, kubeInformers.Core().V1().Services())
-				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 6)
-				requireTLSSecretWasCreated(kubeAPIClient.Actions()[5], ca) // reuses the existing CA
-				// Check that the server is running and that TLS certs that are being served are are for fakeIP.
-				requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + httpsPort: testServerAddr()})
-				requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+			when("the impersonator is disabled and the ConfigMap exists", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeDisabled)
+					addConfigMapToTrackers(map[string]string{"ca.crt": "some-old-ca-bundle"}, kubeAPIClient, kubeInformerClient)
+				})
 
-				// Simulate the informer cache's background update from its watch.
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[5], kubeInformers.Core().V1().Secrets())
+				it("deletes the ConfigMap", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 2)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					deleteAction, ok := kubeAPIClient.Actions()[1].(coretesting.DeleteAction)
+					r.True(ok, "should have been a DeleteAction but was %s", kubeAPIClient.Actions()[1])
+					r.Equal("configmaps", deleteAction.GetResource().Resource)
+					r.Equal(configMapName, deleteAction.GetName())
+					requireCredentialIssuer(newManuallyDisabledStrategy())
+				})
+			})
 
-				// Now switch back to having the "endpoint" specified and explicitly saying that we don't want the load balancer service.
-				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-						ExternalEndpoint: localhostIP,
-						Service: v1alpha1.ImpersonationProxyServiceSpec{
-							Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+			when("the impersonator is disabled and the ConfigMap does not exist", func() {
+				it.Before(func() {
+					addCredentialIssuerWithMode(v1alpha1.ImpersonationProxyModeDisabled)
+				})
+
+				it("does nothing", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 1)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+				})
+			})
+		})
+
+		when("extra labels are configured", func() {
+			it.Before(func() {
+				extraLabels = map[string]string{"cost-center": "1234", "team": "identity"}
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+			})
+
+			when("the load balancer does not exist yet", func() {
+				it("adds the extra labels to the created Service and Secrets", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+					requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				})
+			})
+
+			when("the load balancer already exists with other labels and a stale controller-owned label", func() {
+				it.Before(func() {
+					loadBalancerService := newLoadBalancerService(loadBalancerServiceName, corev1.ServiceStatus{})
+					loadBalancerService.Labels = map[string]string{
+						"app":             "app-name",
+						"other-key":       "stale-value",
+						"team":            "changed-by-the-team",
+						"some-user-label": "some-user-value",
+					}
+					addServiceToTrackers(loadBalancerService, kubeInformerClient, kubeAPIClient)
+				})
+
+				it("only reconciles the controller-owned labels", func() {
+					startInformersAndController()
+					r.NoError(runControllerSync())
+					r.Len(kubeAPIClient.Actions(), 4)
+					requireNodesListed(kubeAPIClient.Actions()[0])
+					updateAction, ok := kubeAPIClient.Actions()[1].(coretesting.UpdateAction)
+					r.True(ok, "should have been able to cast this action to UpdateAction: %v", kubeAPIClient.Actions()[1])
+					r.Equal(map[string]string{
+						"app":                "app-name",
+						"other-key":          "other-value",
+						"team":               "changed-by-the-team",
+						"some-user-label":    "some-user-value",
+						serviceOwnerLabelKey: serviceOwnerLabelValue,
+					}, updateAction.GetObject().(*corev1.Service).Labels)
+					ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+					requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+				})
+			})
+		})
+
+		when("requesting a load balancer via CredentialIssuer, but there is already a load balancer with an invalid bookkeeping annotation value", func() {
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+							ExternalEndpoint: localhostIP,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type:        v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+								Annotations: map[string]string{"some-annotation": "annotation-value"},
+							},
 						},
 					},
-				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+				// Add a Service with a messed up bookkeeping annotation.
+				loadBalancerService := newLoadBalancerService(loadBalancerServiceName, corev1.ServiceStatus{})
+				loadBalancerService.Annotations = map[string]string{
+					annotationKeysKey: `["this is not valid json`,
+				}
+				addServiceToTrackers(loadBalancerService, kubeInformerClient, kubeAPIClient)
+			})
+
+			it("just acts like the annotation wasn't present since that is better than becoming inoperable", func() {
+				startInformersAndController()
 
+				// Should have started in "enabled" mode with service type load balancer, so one is created.
 				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 9)
-				requireServiceWasDeleted(kubeAPIClient.Actions()[6], loadBalancerServiceName)
-				requireTLSSecretWasDeleted(kubeAPIClient.Actions()[7])
-				requireTLSSecretWasCreated(kubeAPIClient.Actions()[8], ca) // recreated because the endpoint was updated, reused the old CA
+				r.Len(kubeAPIClient.Actions(), 4)
+				requireNodesListed(kubeAPIClient.Actions()[0])
+				lbService := requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[1])
+				require.Equal(t, map[string]string{
+					"some-annotation": "annotation-value",
+					"credentialissuer.pinniped.dev/annotation-keys": `["some-annotation"]`,
+				}, lbService.Annotations)
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
 				requireTLSServerIsRunning(ca, testServerAddr(), nil)
 				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
 				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 			})
 		})
 
-		when("requesting a load balancer via CredentialIssuer, then updating the annotations", func() {
+		when("requesting a load balancer via CredentialIssuer, then adding a static loadBalancerIP to the spec", func() {
 			it.Before(func() {
 				addSecretToTrackers(signingCASecret, kubeInformerClient)
 				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
@@ -2509,7 +4929,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				addNodeWithRoleToTracker("worker", kubeAPIClient)
 			})
 
-			it("creates the load balancer without annotations, then adds them", func() {
+			it("creates the load balancer without loadBalancerIP set, then adds it", func() {
 				startInformersAndController()
 
 				// Should have started in "enabled" mode with service type load balancer, so one is created.
@@ -2518,36 +4938,27 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				requireNodesListed(kubeAPIClient.Actions()[0])
 				lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
 				require.Equal(t, map[string]string(nil), lbService.Annotations) // there should be no annotations at first
+				require.Equal(t, "", lbService.Spec.LoadBalancerIP)
 				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
 				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
 				requireTLSServerIsRunning(ca, testServerAddr(), nil)
 				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
 				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 
-				// Simulate another actor in the system, like a human user or a non-Pinniped controller,
-				// updating the new Service's annotations. The map was nil, so we can overwrite the whole thing,
-				lbService.Annotations = map[string]string{
-					"annotation-from-unrelated-controller-key": "annotation-from-unrelated-controller-val",
-					"my-annotation-key":                        "my-annotation-from-unrelated-controller-val",
-				}
-
 				// Simulate the informer cache's background update from its watch.
-				addObjectToKubeInformerAndWait(lbService, kubeInformers.Core().V1().Services())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
 				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
 				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
 
-				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 4) // no new actions because the controller decides there is nothing to update on the Service
-
-				// Add annotations to the CredentialIssuer spec.
-				credentialIssuerAnnotations := map[string]string{"my-annotation-key": "my-annotation-val"}
+				// Add annotations to the spec.
+				loadBalancerIP := "1.2.3.4"
 				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
 					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
 						ExternalEndpoint: localhostIP,
 						Service: v1alpha1.ImpersonationProxyServiceSpec{
-							Type:        v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
-							Annotations: credentialIssuerAnnotations,
+							Type:           v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							LoadBalancerIP: loadBalancerIP,
 						},
 					},
 				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
@@ -2555,99 +4966,80 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				r.NoError(runControllerSync())
 				r.Len(kubeAPIClient.Actions(), 5) // one more item to update the loadbalancer
 				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[4])
-				require.Equal(t, map[string]string{
-					// Now the CredentialIssuer annotations should be merged on the load balancer.
-					// In the unlikely case where keys conflict, the CredentialIssuer value overwrites the other value.
-					// Otherwise the annotations from the other actor should not be modified.
-					"annotation-from-unrelated-controller-key":      "annotation-from-unrelated-controller-val",
-					"my-annotation-key":                             "my-annotation-val",
-					"credentialissuer.pinniped.dev/annotation-keys": `["my-annotation-key"]`,
-				}, lbService.Annotations)
+				require.Equal(t, loadBalancerIP, lbService.Spec.LoadBalancerIP)
 				requireTLSServerIsRunning(ca, testServerAddr(), nil)
 				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
 				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 			})
 		})
 
-		when("requesting a cluster ip via CredentialIssuer, then updating the annotations", func() {
+		when("the configured name of the load balancer was changed and the Service with the old name still exists", func() {
+			const oldLoadBalancerServiceName = "some-old-load-balancer-service-name"
+			const unownedServiceName = "some-service-created-by-someone-else"
 			it.Before(func() {
 				addSecretToTrackers(signingCASecret, kubeInformerClient)
 				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
 					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
 					Spec: v1alpha1.CredentialIssuerSpec{
 						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-							ExternalEndpoint: localhostIP,
+							Mode: v1alpha1.ImpersonationProxyModeEnabled,
 							Service: v1alpha1.ImpersonationProxyServiceSpec{
-								Type: v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+								Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
 							},
 						},
 					},
 				}, pinnipedInformerClient, pinnipedAPIClient)
 				addNodeWithRoleToTracker("worker", kubeAPIClient)
+				addServiceToTrackers(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      oldLoadBalancerServiceName,
+						Namespace: installedInNamespace,
+						Labels:    withServiceOwnerLabel(labels),
+					},
+					Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+				}, kubeInformerClient, kubeAPIClient)
+				addServiceToTrackers(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      unownedServiceName,
+						Namespace: installedInNamespace,
+						Labels:    labels,
+					},
+					Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+				}, kubeInformerClient, kubeAPIClient)
 			})
 
-			it("creates the cluster ip without annotations, then adds them", func() {
+			it("creates the load balancer with the new name and deletes the orphaned one, but not Services it did not create", func() {
 				startInformersAndController()
-
-				// Should have started in "enabled" mode with service type load balancer, so one is created.
 				r.NoError(runControllerSync())
 				r.Len(kubeAPIClient.Actions(), 4)
 				requireNodesListed(kubeAPIClient.Actions()[0])
-				clusterIPService := requireClusterIPWasCreated(kubeAPIClient.Actions()[1])
-				require.Equal(t, map[string]string(nil), clusterIPService.Annotations) // there should be no annotations at first
-				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
-				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
-				requireTLSServerIsRunning(ca, testServerAddr(), nil)
-				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-				// Simulate another actor in the system, like a human user or a non-Pinniped controller,
-				// updating the new Service's annotations.
-				clusterIPService.Annotations = map[string]string{
-					"annotation-from-unrelated-controller-key": "annotation-from-unrelated-controller-val",
-					"my-annotation-key":                        "my-annotation-from-unrelated-controller-val",
-				}
+				requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+				requireServiceWasDeleted(kubeAPIClient.Actions()[2], oldLoadBalancerServiceName)
+				requireCASecretWasCreated(kubeAPIClient.Actions()[3])
+				requireCredentialIssuer(newPendingStrategyWaitingForLB())
+				requireMetricValues(map[string]float64{
+					"listener_starts_total":           1,
+					"listener_stops_total":            0,
+					"tls_certificate_issuances_total": 0,
+					"load_balancer_creates_total":     1,
+					"load_balancer_deletes_total":     1,
+				})
 
 				// Simulate the informer cache's background update from its watch.
-				addObjectToKubeInformerAndWait(clusterIPService, kubeInformers.Core().V1().Services())
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
 				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
+				deleteServiceFromTracker(oldLoadBalancerServiceName, kubeInformerClient)
+				waitForObjectToBeDeletedFromInformer(oldLoadBalancerServiceName, kubeInformers.Core().V1().Services())
 
+				// The Service with the currently configured name is not deleted on the next sync.
 				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 4) // no new actions because the controller decides there is nothing to update on the Service
-
-				// Add annotations to the CredentialIssuer spec.
-				credentialIssuerAnnotations := map[string]string{"my-annotation-key": "my-annotation-val"}
-				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-						ExternalEndpoint: localhostIP,
-						Service: v1alpha1.ImpersonationProxyServiceSpec{
-							Type:        v1alpha1.ImpersonationProxyServiceTypeClusterIP,
-							Annotations: credentialIssuerAnnotations,
-						},
-					},
-				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
-
-				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 5) // one more item to update the loadbalancer
-				clusterIPService = requireClusterIPWasUpdated(kubeAPIClient.Actions()[4])
-				require.Equal(t, map[string]string{
-					// Now the CredentialIssuer annotations should be merged on the load balancer.
-					// In the unlikely case where keys conflict, the CredentialIssuer value overwrites the other value.
-					// Otherwise the annotations from the other actor should not be modified.
-					"annotation-from-unrelated-controller-key":      "annotation-from-unrelated-controller-val",
-					"my-annotation-key":                             "my-annotation-val",
-					"credentialissuer.pinniped.dev/annotation-keys": `["my-annotation-key"]`,
-				}, clusterIPService.Annotations)
-				requireTLSServerIsRunning(ca, testServerAddr(), nil)
-				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				r.Len(kubeAPIClient.Actions(), 4) // nothing changed
+				_, err := kubeAPIClient.CoreV1().Services(installedInNamespace).Get(context.Background(), unownedServiceName, metav1.GetOptions{})
+				r.NoError(err)
 			})
 		})
 
-		when("requesting a load balancer via CredentialIssuer with annotations, then updating the CredentialIssuer annotations to remove one", func() {
+		when("the load balancer already exists but was created before the controller started adding the owner label", func() {
 			it.Before(func() {
 				addSecretToTrackers(signingCASecret, kubeInformerClient)
 				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
@@ -2658,110 +5050,95 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 							ExternalEndpoint: localhostIP,
 							Service: v1alpha1.ImpersonationProxyServiceSpec{
 								Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
-								Annotations: map[string]string{
-									"my-initial-annotation1-key": "my-initial-annotation1-val",
-									"my-initial-annotation2-key": "my-initial-annotation2-val",
-									"my-initial-annotation3-key": "my-initial-annotation3-val",
-								},
 							},
 						},
 					},
 				}, pinnipedInformerClient, pinnipedAPIClient)
 				addNodeWithRoleToTracker("worker", kubeAPIClient)
+				loadBalancerService := newLoadBalancerService(loadBalancerServiceName, corev1.ServiceStatus{})
+				loadBalancerService.Labels = labels
+				addServiceToTrackers(loadBalancerService, kubeInformerClient, kubeAPIClient)
 			})
 
-			it("creates the load balancer with annotations, then removes the removed annotation", func() {
+			it("adds the owner label so that the Service can be found as an orphan after its configured name changes", func() {
 				startInformersAndController()
-
-				// Should have started in "enabled" mode with service type load balancer, so one is created.
 				r.NoError(runControllerSync())
 				r.Len(kubeAPIClient.Actions(), 4)
 				requireNodesListed(kubeAPIClient.Actions()[0])
-				lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
-				require.Equal(t, map[string]string{
-					"my-initial-annotation1-key":                    "my-initial-annotation1-val",
-					"my-initial-annotation2-key":                    "my-initial-annotation2-val",
-					"my-initial-annotation3-key":                    "my-initial-annotation3-val",
-					"credentialissuer.pinniped.dev/annotation-keys": `["my-initial-annotation1-key","my-initial-annotation2-key","my-initial-annotation3-key"]`,
-				}, lbService.Annotations) // there should be some annotations at first
+				lbService := requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[1])
+				r.Equal(withServiceOwnerLabel(labels), lbService.Labels)
 				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
 				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
-				requireTLSServerIsRunning(ca, testServerAddr(), nil)
 				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
-
-				// Simulate another actor in the system, like a human user or a non-Pinniped controller,
-				// updating the new Service to add another annotation.
-				lbService.Annotations["annotation-from-unrelated-controller-key"] = "annotation-from-unrelated-controller-val"
 
-				// Simulate the informer cache's background update from its watch.
-				addObjectToKubeInformerAndWait(lbService, kubeInformers.Core().V1().Services())
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
+				// The Service is now selected by the same label selector that is used to find orphaned Services.
+				ownedServices, err := kubeAPIClient.CoreV1().Services(installedInNamespace).List(context.Background(), metav1.ListOptions{
+					LabelSelector: serviceOwnerLabelKey + "=" + serviceOwnerLabelValue,
+				})
+				r.NoError(err)
+				r.Len(ownedServices.Items, 1)
+				r.Equal(loadBalancerServiceName, ownedServices.Items[0].Name)
+			})
+		})
 
-				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 4) // no new actions because the controller decides there is nothing to update on the Service
+		when("requesting a load balancer via CredentialIssuer with a loadBalancerIP and loadBalancerSourceRanges but no endpoint", func() {
+			const fakeIP = "127.0.0.123"
+			sourceRanges := []string{"10.0.0.0/8", "fd00::/8"}
 
-				// Remove one of the annotations from the CredentialIssuer spec.
-				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-						ExternalEndpoint: localhostIP,
-						Service: v1alpha1.ImpersonationProxyServiceSpec{
-							Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
-							Annotations: map[string]string{
-								"my-initial-annotation1-key": "my-initial-annotation1-val",
-								"my-initial-annotation3-key": "my-initial-annotation3-val",
+			it.Before(func() {
+				addSecretToTrackers(signingCASecret, kubeInformerClient)
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeEnabled,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type:                     v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+								LoadBalancerIP:           fakeIP,
+								LoadBalancerSourceRanges: sourceRanges,
 							},
 						},
 					},
-				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
+			})
+
+			it("creates the load balancer with those fields, then waits for its ingress before issuing a cert for it", func() {
+				startInformersAndController()
 
 				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 5) // one more item to update the loadbalancer
-				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[4])
-				require.Equal(t, map[string]string{
-					// Now the CredentialIssuer annotations should be merged on the load balancer.
-					// Since the user removed the "my-initial-annotation2-key" key from the CredentialIssuer spec,
-					// it should be removed from the Service.
-					// The annotations from the other actor should not be modified.
-					"annotation-from-unrelated-controller-key":      "annotation-from-unrelated-controller-val",
-					"my-initial-annotation1-key":                    "my-initial-annotation1-val",
-					"my-initial-annotation3-key":                    "my-initial-annotation3-val",
-					"credentialissuer.pinniped.dev/annotation-keys": `["my-initial-annotation1-key","my-initial-annotation3-key"]`,
-				}, lbService.Annotations)
-				requireTLSServerIsRunning(ca, testServerAddr(), nil)
-				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				r.Len(kubeAPIClient.Actions(), 3)
+				requireNodesListed(kubeAPIClient.Actions()[0])
+				lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+				require.Equal(t, fakeIP, lbService.Spec.LoadBalancerIP)
+				require.Equal(t, sourceRanges, lbService.Spec.LoadBalancerSourceRanges)
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+				requireTLSServerIsRunningWithoutCerts()
+				requireCredentialIssuer(newPendingStrategyWaitingForLB())
 				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 
-				// Remove all the rest of the annotations from the CredentialIssuer spec so there are none remaining.
-				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
-					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
-						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
-						ExternalEndpoint: localhostIP,
-						Service: v1alpha1.ImpersonationProxyServiceSpec{
-							Type:        v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
-							Annotations: map[string]string{},
-						},
-					},
-				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
 
+				// Requesting the IP does not mean that the cloud provider gave it to the load balancer, so keep waiting.
 				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 6) // one more item to update the loadbalancer
-				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[5])
-				require.Equal(t, map[string]string{
-					// Since the user removed all annotations from the CredentialIssuer spec,
-					// they should all be removed from the Service, along with the special bookkeeping annotation too.
-					// The annotations from the other actor should not be modified.
-					"annotation-from-unrelated-controller-key": "annotation-from-unrelated-controller-val",
-				}, lbService.Annotations)
-				requireTLSServerIsRunning(ca, testServerAddr(), nil)
-				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+				r.Len(kubeAPIClient.Actions(), 3) // no new actions while it is waiting for the load balancer's ingress
+				requireTLSServerIsRunningWithoutCerts()
+				requireCredentialIssuer(newPendingStrategyWaitingForLB())
+
+				// Update the ingress of the LB in the informer's client and run Sync again.
+				updateLoadBalancerServiceInInformerAndWait(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: fakeIP}}, kubeInformers.Core().V1().Services())
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4)
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+				requireTLSServerIsRunning(ca, fakeIP, map[string]string{fakeIP + httpsPort: testServerAddr()})
+				requireCredentialIssuer(newSuccessStrategy(fakeIP, ca))
 				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 			})
 		})
 
-		when("requesting a load balancer via CredentialIssuer, but there is already a load balancer with an invalid bookkeeping annotation value", func() {
+		when("requesting a load balancer via CredentialIssuer, then adding and removing loadBalancerSourceRanges in the spec", func() {
 			it.Before(func() {
 				addSecretToTrackers(signingCASecret, kubeInformerClient)
 				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
@@ -2771,42 +5148,77 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
 							ExternalEndpoint: localhostIP,
 							Service: v1alpha1.ImpersonationProxyServiceSpec{
-								Type:        v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
-								Annotations: map[string]string{"some-annotation": "annotation-value"},
-							},
-						},
-					},
-				}, pinnipedInformerClient, pinnipedAPIClient)
-				addNodeWithRoleToTracker("worker", kubeAPIClient)
-				// Add a Service with a messed up bookkeeping annotation.
-				loadBalancerService := newLoadBalancerService(loadBalancerServiceName, corev1.ServiceStatus{})
-				loadBalancerService.Annotations = map[string]string{
-					annotationKeysKey: `["this is not valid json`,
-				}
-				addServiceToTrackers(loadBalancerService, kubeInformerClient, kubeAPIClient)
+								Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+				addNodeWithRoleToTracker("worker", kubeAPIClient)
 			})
 
-			it("just acts like the annotation wasn't present since that is better than becoming inoperable", func() {
+			it("creates the load balancer without loadBalancerSourceRanges set, then adds them, then removes them", func() {
 				startInformersAndController()
 
-				// Should have started in "enabled" mode with service type load balancer, so one is created.
 				r.NoError(runControllerSync())
 				r.Len(kubeAPIClient.Actions(), 4)
 				requireNodesListed(kubeAPIClient.Actions()[0])
-				lbService := requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[1])
-				require.Equal(t, map[string]string{
-					"some-annotation": "annotation-value",
-					"credentialissuer.pinniped.dev/annotation-keys": `["some-annotation"]`,
-				}, lbService.Annotations)
+				lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+				require.Nil(t, lbService.Spec.LoadBalancerSourceRanges)
 				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
 				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
 				requireTLSServerIsRunning(ca, testServerAddr(), nil)
 				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
+
+				// Add source ranges to the spec.
+				sourceRanges := []string{"192.168.0.0/16"}
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: localhostIP,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type:                     v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							LoadBalancerSourceRanges: sourceRanges,
+						},
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 5) // one more item to update the loadbalancer
+				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[4])
+				require.Equal(t, sourceRanges, lbService.Spec.LoadBalancerSourceRanges)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+
+				// Simulate the informer cache's background update from its watch.
+				r.NoError(kubeInformerClient.Tracker().Update(schema.GroupVersionResource{Version: "v1", Resource: "services"}, lbService, installedInNamespace))
+				waitForObjectToAppearInInformer(lbService, kubeInformers.Core().V1().Services())
+
+				// Remove the source ranges from the spec.
+				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
+					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
+						ExternalEndpoint: localhostIP,
+						Service: v1alpha1.ImpersonationProxyServiceSpec{
+							Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+						},
+					},
+				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 6) // one more item to update the loadbalancer
+				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[5])
+				require.Nil(t, lbService.Spec.LoadBalancerSourceRanges)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
 			})
 		})
 
-		when("requesting a load balancer via CredentialIssuer, then adding a static loadBalancerIP to the spec", func() {
+		when("requesting the impersonator via CredentialIssuer, then changing the bindAddress in the spec", func() {
 			it.Before(func() {
 				addSecretToTrackers(signingCASecret, kubeInformerClient)
 				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
@@ -2816,7 +5228,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 							Mode:             v1alpha1.ImpersonationProxyModeEnabled,
 							ExternalEndpoint: localhostIP,
 							Service: v1alpha1.ImpersonationProxyServiceSpec{
-								Type: v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+								Type: v1alpha1.ImpersonationProxyServiceTypeNone,
 							},
 						},
 					},
@@ -2824,47 +5236,45 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				addNodeWithRoleToTracker("worker", kubeAPIClient)
 			})
 
-			it("creates the load balancer without loadBalancerIP set, then adds it", func() {
+			it("starts the impersonator on all interfaces, then restarts it on the new bind address", func() {
 				startInformersAndController()
 
-				// Should have started in "enabled" mode with service type load balancer, so one is created.
 				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 4)
+				r.Len(kubeAPIClient.Actions(), 3)
 				requireNodesListed(kubeAPIClient.Actions()[0])
-				lbService := requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
-				require.Equal(t, map[string]string(nil), lbService.Annotations) // there should be no annotations at first
-				require.Equal(t, "", lbService.Spec.LoadBalancerIP)
-				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
-				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[1])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[2], ca)
 				requireTLSServerIsRunning(ca, testServerAddr(), nil)
 				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+				r.Equal(1, impersonatorFuncWasCalled)
+				r.Equal(":8444", impersonatorFuncAddress)
 
 				// Simulate the informer cache's background update from its watch.
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Secrets())
 				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
-				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
 
-				// Add annotations to the spec.
-				loadBalancerIP := "1.2.3.4"
+				// Syncing again without changing the bind address should not restart the impersonator.
+				r.NoError(runControllerSync())
+				r.Equal(1, impersonatorFuncWasCalled)
+
+				// Add a bind address to the spec.
 				updateCredentialIssuerInInformerAndWait(credentialIssuerResourceName, v1alpha1.CredentialIssuerSpec{
 					ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
 						Mode:             v1alpha1.ImpersonationProxyModeEnabled,
 						ExternalEndpoint: localhostIP,
+						BindAddress:      localhostIP,
 						Service: v1alpha1.ImpersonationProxyServiceSpec{
-							Type:           v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
-							LoadBalancerIP: loadBalancerIP,
+							Type: v1alpha1.ImpersonationProxyServiceTypeNone,
 						},
 					},
 				}, pinnipedInformers.Config().V1alpha1().CredentialIssuers())
 
 				r.NoError(runControllerSync())
-				r.Len(kubeAPIClient.Actions(), 5) // one more item to update the loadbalancer
-				lbService = requireLoadBalancerWasUpdated(kubeAPIClient.Actions()[4])
-				require.Equal(t, loadBalancerIP, lbService.Spec.LoadBalancerIP)
+				r.Len(kubeAPIClient.Actions(), 3) // no new API calls
+				r.Equal(2, impersonatorFuncWasCalled)
+				r.Equal(localhostIP+":8444", impersonatorFuncAddress)
 				requireTLSServerIsRunning(ca, testServerAddr(), nil)
 				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
-				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 			})
 		})
 
@@ -2941,6 +5351,50 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
 			})
 
+			it("re-issues certs when the ip address listed on the load balancer changes", func() {
+				const changedIP = "127.0.0.2"
+				startInformersAndController()
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 3)
+				requireNodesListed(kubeAPIClient.Actions()[0])
+				requireLoadBalancerWasCreated(kubeAPIClient.Actions()[1])
+				ca := requireCASecretWasCreated(kubeAPIClient.Actions()[2])
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[1], kubeInformers.Core().V1().Services())
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[2], kubeInformers.Core().V1().Secrets())
+
+				updateLoadBalancerServiceInInformerAndWait(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: localhostIP}}, kubeInformers.Core().V1().Services())
+
+				r.NoError(runControllerSync())
+				r.Len(kubeAPIClient.Actions(), 4)
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[3], ca)
+				requireTLSServerIsRunning(ca, testServerAddr(), nil)
+				requireCredentialIssuer(newSuccessStrategy(localhostIP, ca))
+
+				// Simulate the informer cache's background update from its watch.
+				addObjectFromCreateActionToInformerAndWait(kubeAPIClient.Actions()[3], kubeInformers.Core().V1().Secrets())
+
+				// Simulate the cloud provider reassigning the load balancer's IP.
+				updateLoadBalancerServiceInInformerAndWait(loadBalancerServiceName, []corev1.LoadBalancerIngress{{IP: changedIP}}, kubeInformers.Core().V1().Services())
+
+				r.NoError(runControllerSync())
+				r.Equal(1, impersonatorFuncWasCalled) // wasn't started a second time
+				r.Len(kubeAPIClient.Actions(), 6)
+				requireTLSSecretWasDeleted(kubeAPIClient.Actions()[4])
+				requireTLSSecretWasCreated(kubeAPIClient.Actions()[5], ca) // uses the ca from last time
+				reissuedSecret := kubeAPIClient.Actions()[5].(coretesting.CreateAction).GetObject().(*corev1.Secret)
+				block, _ := pem.Decode(reissuedSecret.Data[corev1.TLSCertKey])
+				r.NotNil(block)
+				reissuedCert, err := x509.ParseCertificate(block.Bytes)
+				r.NoError(err)
+				r.Len(reissuedCert.IPAddresses, 1)
+				r.Equal(changedIP, reissuedCert.IPAddresses[0].String())
+				requireTLSServerIsRunning(ca, changedIP, map[string]string{changedIP + httpsPort: testServerAddr()}) // serving the new cert
+				requireCredentialIssuer(newSuccessStrategy(changedIP, ca))
+				requireSigningCertProviderHasLoadedCerts(signingCACertPEM, signingCAKeyPEM)
+			})
+
 			it("creates certs from the hostname listed on the load balancer", func() {
 				hostname := "fake.example.com"
 				startInformersAndController()
@@ -3225,7 +5679,86 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 
 			it("returns an error", func() {
 				startInformersAndController()
-				errString := `could not load CredentialIssuer spec.impersonationProxy: invalid service type "not-valid" (expected None, LoadBalancer, or ClusterIP)`
+				errString := `could not load CredentialIssuer spec.impersonationProxy: invalid service type "not-valid" (expected None, LoadBalancer, NodePort, ClusterIP, or External)`
+				r.EqualError(runControllerSync(), errString)
+				requireCredentialIssuer(newErrorStrategy(errString))
+				requireSigningCertProviderIsEmpty()
+				requireTLSServerWasNeverStarted()
+			})
+		})
+
+		when("the CredentialIssuer has an additional ClusterIP for a service type other than LoadBalancer", func() {
+			it.Before(func() {
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeEnabled,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type:                v1alpha1.ImpersonationProxyServiceTypeNodePort,
+								AdditionalClusterIP: true,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			})
+
+			it("returns an error", func() {
+				startInformersAndController()
+				errString := `could not load CredentialIssuer spec.impersonationProxy: additionalClusterIP must not be set when service.type is NodePort`
+				r.EqualError(runControllerSync(), errString)
+				requireCredentialIssuer(newErrorStrategy(errString))
+				requireSigningCertProviderIsEmpty()
+				requireTLSServerWasNeverStarted()
+			})
+		})
+
+		when("the CredentialIssuer has an additional ClusterIP when auto mode does not create the load balancer", func() {
+			it.Before(func() {
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeAuto,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type:                v1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+								AdditionalClusterIP: true,
+							},
+							AutoMode: &v1alpha1.ImpersonationProxyAutoModeSpec{CreateLoadBalancer: pointer.Bool(false)},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			})
+
+			it("returns an error", func() {
+				startInformersAndController()
+				errString := `could not load CredentialIssuer spec.impersonationProxy: additionalClusterIP must not be set when autoMode.createLoadBalancer is false`
+				r.EqualError(runControllerSync(), errString)
+				requireCredentialIssuer(newErrorStrategy(errString))
+				requireSigningCertProviderIsEmpty()
+				requireTLSServerWasNeverStarted()
+			})
+		})
+
+		when("the CredentialIssuer resolves the endpoint hostname for a service type other than LoadBalancer", func() {
+			it.Before(func() {
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeEnabled,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type:                    v1alpha1.ImpersonationProxyServiceTypeClusterIP,
+								ResolveEndpointHostname: true,
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			})
+
+			it("returns an error", func() {
+				startInformersAndController()
+				errString := `could not load CredentialIssuer spec.impersonationProxy: resolveEndpointHostname must not be set when service.type is ClusterIP`
 				r.EqualError(runControllerSync(), errString)
 				requireCredentialIssuer(newErrorStrategy(errString))
 				requireSigningCertProviderIsEmpty()
@@ -3258,6 +5791,54 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			})
 		})
 
+		when("the CredentialIssuer has an invalid LoadBalancerSourceRanges entry", func() {
+			it.Before(func() {
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeEnabled,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								LoadBalancerSourceRanges: []string{"10.0.0.0/8", "10.1.2.3"},
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			})
+
+			it("returns an error", func() {
+				startInformersAndController()
+				errString := `could not load CredentialIssuer spec.impersonationProxy: invalid LoadBalancerSourceRanges entry "10.1.2.3"`
+				r.EqualError(runControllerSync(), errString)
+				requireCredentialIssuer(newErrorStrategy(errString))
+				requireSigningCertProviderIsEmpty()
+				requireTLSServerWasNeverStarted()
+			})
+		})
+
+		when("the CredentialIssuer has invalid BindAddress", func() {
+			it.Before(func() {
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode:        v1alpha1.ImpersonationProxyModeEnabled,
+							BindAddress: "invalid-ip-address",
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			})
+
+			it("returns an error", func() {
+				startInformersAndController()
+				errString := `could not load CredentialIssuer spec.impersonationProxy: invalid BindAddress "invalid-ip-address"`
+				r.EqualError(runControllerSync(), errString)
+				requireCredentialIssuer(newErrorStrategy(errString))
+				requireSigningCertProviderIsEmpty()
+				requireTLSServerWasNeverStarted()
+			})
+		})
+
 		when("the CredentialIssuer has invalid ExternalEndpoint", func() {
 			it.Before(func() {
 				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
@@ -3360,6 +5941,10 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				requireCredentialIssuer(newErrorStrategy("error on create"))
 				requireSigningCertProviderIsEmpty()
 				requireTLSServerIsRunningWithoutCerts()
+				requireEventsRecorded(
+					"Normal ImpersonationProxyStarted Started impersonation proxy on port 8444",
+					"Warning SyncFailed Failed to configure impersonation proxy: error on create",
+				)
 			})
 		})
 
@@ -3909,14 +6494,100 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 					r.Len(kubeAPIClient.Actions(), 0)
 				})
 			})
+
+			when("the impersonator is enabled but the service type is external and the external endpoint is empty", func() {
+				it.Before(func() {
+					addSecretToTrackers(signingCASecret, kubeInformerClient)
+					addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+						ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+						Spec: v1alpha1.CredentialIssuerSpec{
+							ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+								Mode: v1alpha1.ImpersonationProxyModeEnabled,
+								Service: v1alpha1.ImpersonationProxyServiceSpec{
+									Type: v1alpha1.ImpersonationProxyServiceTypeExternal,
+								},
+							},
+						},
+					}, pinnipedInformerClient, pinnipedAPIClient)
+					addNodeWithRoleToTracker("control-plane", kubeAPIClient)
+				})
+
+				it("returns a validation error", func() {
+					startInformersAndController()
+					r.EqualError(runControllerSync(), "could not load CredentialIssuer spec.impersonationProxy: externalEndpoint must be set when service.type is External")
+					r.Len(kubeAPIClient.Actions(), 0)
+				})
+			})
 		})
 	}, spec.Parallel(), spec.Report(report.Terminal{}))
 }
 
+func TestFindTLSCertificateNameFromEndpointConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		externalEndpoint string
+		wantIPs          []net.IP
+		wantHostname     string
+		wantEndpoint     string
+	}{
+		{
+			name:             "bare IP",
+			externalEndpoint: "127.0.0.1",
+			wantIPs:          []net.IP{net.ParseIP("127.0.0.1")},
+			wantEndpoint:     "127.0.0.1",
+		},
+		{
+			name:             "IP with port",
+			externalEndpoint: "127.0.0.1:8443",
+			wantIPs:          []net.IP{net.ParseIP("127.0.0.1")},
+			wantEndpoint:     "127.0.0.1:8443",
+		},
+		{
+			name:             "IPv6 with port",
+			externalEndpoint: "[fd00::1]:8443",
+			wantIPs:          []net.IP{net.ParseIP("fd00::1")},
+			wantEndpoint:     "[fd00::1]:8443",
+		},
+		{
+			name:             "IP with default port",
+			externalEndpoint: "127.0.0.1:443",
+			wantIPs:          []net.IP{net.ParseIP("127.0.0.1")},
+			wantEndpoint:     "127.0.0.1",
+		},
+		{
+			name:             "hostname",
+			externalEndpoint: "proxy.example.com",
+			wantHostname:     "proxy.example.com",
+			wantEndpoint:     "proxy.example.com",
+		},
+		{
+			name:             "hostname with port",
+			externalEndpoint: "proxy.example.com:8443",
+			wantHostname:     "proxy.example.com",
+			wantEndpoint:     "proxy.example.com:8443",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			c := &impersonatorConfigController{}
+			nameInfo := c.findTLSCertificateNameFromEndpointConfig(&v1alpha1.ImpersonationProxySpec{ExternalEndpoint: tt.externalEndpoint})
+			require.True(t, nameInfo.ready)
+			require.Equal(t, tt.wantIPs, nameInfo.selectedIPs)
+			require.Equal(t, tt.wantHostname, nameInfo.selectedHostname)
+			require.Equal(t, tt.wantEndpoint, nameInfo.clientEndpoint)
+		})
+	}
+}
+
 type testQueue struct {
 	key   controllerlib.Key
 	mutex sync.RWMutex
 
+	// afterKey and afterDuration record the most recent call to AddAfter.
+	afterKey      controllerlib.Key
+	afterDuration time.Duration
+
 	controllerlib.Queue
 }
 
@@ -3934,3 +6605,15 @@ func (q *testQueue) AddRateLimited(key controllerlib.Key) {
 
 	q.key = key
 }
+
+func (q *testQueue) AddAfter(key controllerlib.Key, duration time.Duration) {
+	q.mutex.Lock() // this is to satisfy the race detector
+	defer q.mutex.Unlock()
+
+	if key == (controllerlib.Key{}) {
+		panic("unexpected empty key")
+	}
+
+	q.afterKey = key
+	q.afterDuration = duration
+}
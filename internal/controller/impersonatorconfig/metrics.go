@@ -0,0 +1,47 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonatorconfig
+
+import (
+	"k8s.io/component-base/metrics"
+)
+
+const (
+	metricsNamespace = "pinniped"
+	metricsSubsystem = "concierge_impersonation_proxy"
+)
+
+// impersonatorMetrics counts the operations of the impersonator config controller. Frequent listener
+// restarts or certificate issuances usually mean that the impersonation proxy configuration is thrashing.
+type impersonatorMetrics struct {
+	listenerStarts      *metrics.Counter
+	listenerStops       *metrics.Counter
+	certIssuances       *metrics.Counter
+	loadBalancerCreates *metrics.Counter
+	loadBalancerDeletes *metrics.Counter
+}
+
+// newImpersonatorMetrics creates the metrics and registers them using registerMetrics, which is usually
+// legacyregistry.MustRegister so that they are served by the Concierge's metrics endpoint.
+func newImpersonatorMetrics(registerMetrics func(...metrics.Registerable)) *impersonatorMetrics {
+	newCounter := func(name, help string) *metrics.Counter {
+		return metrics.NewCounter(&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           name,
+			Help:           help,
+			StabilityLevel: metrics.ALPHA,
+		})
+	}
+
+	m := &impersonatorMetrics{
+		listenerStarts:      newCounter("listener_starts_total", "Number of times the impersonation proxy listener was started."),
+		listenerStops:       newCounter("listener_stops_total", "Number of times the impersonation proxy listener was stopped."),
+		certIssuances:       newCounter("tls_certificate_issuances_total", "Number of TLS serving certificates issued for the impersonation proxy."),
+		loadBalancerCreates: newCounter("load_balancer_creates_total", "Number of load balancer Services created for the impersonation proxy."),
+		loadBalancerDeletes: newCounter("load_balancer_deletes_total", "Number of load balancer Services deleted for the impersonation proxy."),
+	}
+	registerMetrics(m.listenerStarts, m.listenerStops, m.certIssuances, m.loadBalancerCreates, m.loadBalancerDeletes)
+	return m
+}
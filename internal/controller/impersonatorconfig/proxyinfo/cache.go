@@ -0,0 +1,37 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package proxyinfo implements a cache of the client connection details of the running impersonation proxy.
+package proxyinfo
+
+import (
+	"sync"
+
+	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
+)
+
+// Cache holds the endpoint and CA bundle of the impersonation proxy while it is ready to accept client connections.
+// It is written by the impersonator config controller and read by the TokenCredentialRequest API.
+type Cache struct {
+	mu   sync.RWMutex
+	info *loginapi.ImpersonationProxyInfo
+}
+
+// New returns an empty cache.
+func New() *Cache {
+	return &Cache{}
+}
+
+// Set stores a copy of the info, or clears the cache when info is nil.
+func (c *Cache) Set(info *loginapi.ImpersonationProxyInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.info = info.DeepCopy()
+}
+
+// Get returns a copy of the cached info, or nil when the impersonation proxy is not ready.
+func (c *Cache) Get() *loginapi.ImpersonationProxyInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.info.DeepCopy()
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package proxyinfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
+)
+
+func TestCache(t *testing.T) {
+	cache := New()
+	require.Nil(t, cache.Get())
+
+	info := &loginapi.ImpersonationProxyInfo{Endpoint: "https://some-endpoint", CertificateAuthorityData: "some-ca-data"}
+	cache.Set(info)
+	info.Endpoint = "https://changed-after-set"
+
+	got := cache.Get()
+	require.Equal(t, &loginapi.ImpersonationProxyInfo{Endpoint: "https://some-endpoint", CertificateAuthorityData: "some-ca-data"}, got)
+	got.Endpoint = "https://changed-after-get"
+	require.Equal(t, "https://some-endpoint", cache.Get().Endpoint)
+
+	cache.Set(nil)
+	require.Nil(t, cache.Get())
+}
@@ -0,0 +1,161 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonatorconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/utils/clock"
+
+	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
+	"go.pinniped.dev/internal/net/phttp"
+)
+
+const (
+	// stateChangeWebhookTimeout bounds each request to the state change webhook, so that an unresponsive receiver
+	// cannot hold up the notifications which come after it for long.
+	stateChangeWebhookTimeout = 5 * time.Second
+
+	// stateChangeWebhookQueueSize is how many notifications may wait to be sent. Further notifications are dropped
+	// until there is room again.
+	stateChangeWebhookQueueSize = 16
+)
+
+// stateChange is a transition of the impersonation proxy which is reported to the state change webhook.
+type stateChange string
+
+const (
+	stateChangeStarted           = stateChange("started")
+	stateChangeStopped           = stateChange("stopped")
+	stateChangeCertificateIssued = stateChange("certificateIssued")
+)
+
+// stateChangePayload is the JSON body which is POSTed to the state change webhook. The endpoint and the CA
+// fingerprint are only included while the impersonation proxy is ready to accept client connections.
+type stateChangePayload struct {
+	Event               stateChange `json:"event"`
+	Time                time.Time   `json:"time"`
+	Endpoint            string      `json:"endpoint,omitempty"`
+	CAFingerprintSHA256 string      `json:"caFingerprintSHA256,omitempty"`
+}
+
+// stateChangeWebhook notifies an external URL of the impersonation proxy's state changes. The notifications are
+// best-effort: they are sent one at a time and in order by a background worker, and when they are dropped because
+// too many are waiting, or when they fail, that is only logged, so they never slow down or fail a sync.
+// A nil *stateChangeWebhook sends nothing.
+type stateChangeWebhook struct {
+	url    string
+	client *http.Client
+	clock  clock.Clock
+	log    logr.Logger
+	queue  chan stateChangePayload
+}
+
+func newStateChangeWebhook(url string, clock clock.Clock, log logr.Logger) *stateChangeWebhook {
+	if url == "" {
+		return nil
+	}
+	client := phttp.Default(nil)
+	client.Timeout = stateChangeWebhookTimeout
+	w := &stateChangeWebhook{
+		url:    url,
+		client: client,
+		clock:  clock,
+		log:    log,
+		queue:  make(chan stateChangePayload, stateChangeWebhookQueueSize),
+	}
+	// The worker runs for as long as the process, like the controller which owns the webhook.
+	go w.run()
+	return w
+}
+
+// notify queues one notification for each state change, in order, using the client connection details of the
+// impersonation proxy when it is ready, or nil otherwise. It never blocks.
+func (w *stateChangeWebhook) notify(changes []stateChange, info *loginapi.ImpersonationProxyInfo) {
+	if w == nil {
+		return
+	}
+
+	for _, change := range changes {
+		payload := stateChangePayload{Event: change, Time: w.clock.Now().UTC()}
+		if info != nil {
+			payload.Endpoint = info.Endpoint
+			payload.CAFingerprintSHA256 = caFingerprintSHA256(info.CertificateAuthorityData)
+		}
+		select {
+		case w.queue <- payload:
+		default:
+			w.log.Info("dropped impersonation proxy state change webhook notification because too many are waiting to be sent",
+				"event", payload.Event,
+			)
+		}
+	}
+}
+
+// run sends the queued notifications one at a time, so that they arrive in the order of the state changes.
+func (w *stateChangeWebhook) run() {
+	for payload := range w.queue {
+		if err := w.post(payload); err != nil {
+			w.log.Info("could not notify the impersonation proxy state change webhook",
+				"event", payload.Event,
+				"err", err.Error(),
+			)
+		}
+	}
+}
+
+func (w *stateChangeWebhook) post(payload stateChangePayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), stateChangeWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return nil
+}
+
+// caFingerprintSHA256 returns the hex encoded SHA-256 hash of the DER bytes of the first certificate in the
+// base64 encoded PEM bundle, or an empty string when the bundle does not contain a certificate.
+func caFingerprintSHA256(certificateAuthorityData string) string {
+	bundle, err := base64.StdEncoding.DecodeString(certificateAuthorityData)
+	if err != nil {
+		return ""
+	}
+	for {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			return ""
+		}
+		if block.Type == "CERTIFICATE" {
+			sum := sha256.Sum256(block.Bytes)
+			return hex.EncodeToString(sum[:])
+		}
+	}
+}
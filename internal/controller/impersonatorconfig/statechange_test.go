@@ -0,0 +1,88 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonatorconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/plog"
+)
+
+func TestStateChangeWebhook(t *testing.T) {
+	received := make(chan stateChange, 2*stateChangeWebhookQueueSize)
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var payload stateChangePayload
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		received <- payload.Event
+		<-unblock
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	var log bytes.Buffer
+	webhook := newStateChangeWebhook(server.URL, clocktesting.NewFakeClock(time.Now()), plog.TestZapr(t, &log))
+
+	requireReceived := func(want stateChange) {
+		t.Helper()
+		select {
+		case got := <-received:
+			require.Equal(t, want, got)
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "timed out waiting for state change webhook", "wanted %q", want)
+		}
+	}
+
+	// Wait until the worker is busy sending the first notification.
+	webhook.notify([]stateChange{stateChangeStarted}, nil)
+	requireReceived(stateChangeStarted)
+
+	// These queue up behind it without blocking, until the queue is full and the rest are dropped.
+	var want []stateChange
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < stateChangeWebhookQueueSize; i++ {
+			change := []stateChange{stateChangeCertificateIssued, stateChangeStopped, stateChangeStarted}[i%3]
+			want = append(want, change)
+			webhook.notify([]stateChange{change}, nil)
+		}
+		webhook.notify([]stateChange{stateChangeStopped, stateChangeStarted}, nil)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "notify blocked")
+	}
+	require.Contains(t, log.String(), "dropped impersonation proxy state change webhook notification")
+	require.Contains(t, log.String(), `"event":"stopped"`)
+	require.Contains(t, log.String(), `"event":"started"`)
+
+	// The queued notifications are sent in order.
+	close(unblock)
+	for _, change := range want {
+		requireReceived(change)
+	}
+	select {
+	case got := <-received:
+		require.FailNow(t, "dropped notification was sent", "got %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNilStateChangeWebhook(t *testing.T) {
+	require.Nil(t, newStateChangeWebhook("", clocktesting.NewFakeClock(time.Now()), plog.TestZapr(t, &bytes.Buffer{})))
+
+	var webhook *stateChangeWebhook
+	webhook.notify([]stateChange{stateChangeStarted}, nil) // does not panic
+}
@@ -19,7 +19,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/cache"
@@ -89,6 +88,20 @@ type AgentConfig struct {
 	// ImagePullSecrets on the kube-cert-agent pods.
 	ContainerImagePullSecrets []string
 
+	// Resources are the compute resource requests and limits of the agent pods' container.
+	Resources corev1.ResourceRequirements
+
+	// Tolerations are added to the tolerations which the agent pods copy from the kube-controller-manager pod.
+	Tolerations []corev1.Toleration
+
+	// PriorityClassName is the name of the PriorityClass of the agent pods. It is not set when empty.
+	PriorityClassName string
+
+	// NodeSelector and Affinity decide which nodes the agent pods may run on. When either is set, they replace the
+	// node name and node selector which the agent pods otherwise copy from the kube-controller-manager pod.
+	NodeSelector map[string]string
+	Affinity     *corev1.Affinity
+
 	// CredentialIssuerName specifies the CredentialIssuer to be created/updated.
 	CredentialIssuerName string
 
@@ -514,6 +527,12 @@ func (c *agentController) newAgentDeployment(controllerManagerPod *corev1.Pod) *
 		}
 	}
 
+	// By default, run on the same node as the kube-controller-manager pod, since that node has the signing key.
+	nodeName, nodeSelector := controllerManagerPod.Spec.NodeName, controllerManagerPod.Spec.NodeSelector
+	if len(c.cfg.NodeSelector) > 0 || c.cfg.Affinity != nil {
+		nodeName, nodeSelector = "", c.cfg.NodeSelector
+	}
+
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      c.cfg.deploymentName(),
@@ -541,25 +560,18 @@ func (c *agentController) newAgentDeployment(controllerManagerPod *corev1.Pod) *
 								{Name: "CERT_PATH", Value: getContainerArgByName(controllerManagerPod, "cluster-signing-cert-file", "/etc/kubernetes/ca/ca.pem")},
 								{Name: "KEY_PATH", Value: getContainerArgByName(controllerManagerPod, "cluster-signing-key-file", "/etc/kubernetes/ca/ca.key")},
 							},
-							Resources: corev1.ResourceRequirements{
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("32Mi"),
-									corev1.ResourceCPU:    resource.MustParse("20m"),
-								},
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("32Mi"),
-									corev1.ResourceCPU:    resource.MustParse("20m"),
-								},
-							},
+							Resources: *c.cfg.Resources.DeepCopy(),
 						},
 					},
 					Volumes:                      controllerManagerPod.Spec.Volumes,
 					RestartPolicy:                corev1.RestartPolicyAlways,
-					NodeSelector:                 controllerManagerPod.Spec.NodeSelector,
+					NodeSelector:                 nodeSelector,
+					Affinity:                     c.cfg.Affinity.DeepCopy(),
 					AutomountServiceAccountToken: pointer.Bool(false),
 					ServiceAccountName:           c.cfg.ServiceAccountName,
-					NodeName:                     controllerManagerPod.Spec.NodeName,
-					Tolerations:                  controllerManagerPod.Spec.Tolerations,
+					NodeName:                     nodeName,
+					Tolerations:                  mergeTolerations(controllerManagerPod.Spec.Tolerations, c.cfg.Tolerations),
+					PriorityClassName:            c.cfg.PriorityClassName,
 					// We need to run the agent pod as root since the file permissions
 					// on the cluster keypair usually restricts access to only root.
 					SecurityContext: &corev1.PodSecurityContext{
@@ -576,6 +588,31 @@ func (c *agentController) newAgentDeployment(controllerManagerPod *corev1.Pod) *
 	}
 }
 
+// mergeTolerations returns the tolerations of the kube-controller-manager pod followed by each
+// configured toleration which is not already one of them.
+func mergeTolerations(controllerManagerTolerations []corev1.Toleration, configuredTolerations []corev1.Toleration) []corev1.Toleration {
+	if len(configuredTolerations) == 0 {
+		return controllerManagerTolerations
+	}
+	result := make([]corev1.Toleration, 0, len(controllerManagerTolerations)+len(configuredTolerations))
+	result = append(result, controllerManagerTolerations...)
+	for _, configured := range configuredTolerations {
+		if !containsToleration(result, configured) {
+			result = append(result, configured)
+		}
+	}
+	return result
+}
+
+func containsToleration(tolerations []corev1.Toleration, toleration corev1.Toleration) bool {
+	for _, t := range tolerations {
+		if apiequality.Semantic.DeepEqual(t, toleration) {
+			return true
+		}
+	}
+	return false
+}
+
 func mergeLabelsAndAnnotations(existing metav1.ObjectMeta, desired metav1.ObjectMeta) metav1.ObjectMeta {
 	result := existing.DeepCopy()
 	for k, v := range desired.Labels {
@@ -190,6 +190,70 @@ func TestAgentController(t *testing.T) {
 		{Name: "KEY_PATH", Value: "/etc/kubernetes/ca/ca.key"},
 	}
 
+	// When the resources are configured differently than the existing deployment's resources,
+	// we expect the controller to update the resources of the deployment.
+	customAgentResources := &corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("16Mi"),
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+		},
+	}
+	healthyAgentDeploymentWithCustomResources := healthyAgentDeployment.DeepCopy()
+	healthyAgentDeploymentWithCustomResources.Spec.Template.Spec.Containers[0].Resources = *customAgentResources
+
+	// The configured tolerations should be added to the deployment, and any other tolerations
+	// which were added to the existing deployment should be removed.
+	configuredAgentToleration := corev1.Toleration{
+		Key:      "example.com/control-plane",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+	healthyAgentDeploymentWithUnexpectedToleration := healthyAgentDeployment.DeepCopy()
+	healthyAgentDeploymentWithUnexpectedToleration.Spec.Template.Spec.Tolerations = []corev1.Toleration{{
+		Key:      "unexpected-key",
+		Operator: corev1.TolerationOpExists,
+	}}
+	healthyAgentDeploymentWithConfiguredToleration := healthyAgentDeployment.DeepCopy()
+	healthyAgentDeploymentWithConfiguredToleration.Spec.Template.Spec.Tolerations = []corev1.Toleration{configuredAgentToleration}
+
+	// When a PriorityClass is configured, we expect the controller to revert any other PriorityClass on the deployment.
+	healthyAgentDeploymentWithOtherPriorityClass := healthyAgentDeployment.DeepCopy()
+	healthyAgentDeploymentWithOtherPriorityClass.Spec.Template.Spec.PriorityClassName = "some-other-priority-class"
+	healthyAgentDeploymentWithConfiguredPriorityClass := healthyAgentDeployment.DeepCopy()
+	healthyAgentDeploymentWithConfiguredPriorityClass.Spec.Template.Spec.PriorityClassName = "system-cluster-critical"
+
+	// When a node selector or affinity is configured, we expect the controller to use them instead of running the
+	// agent pod on the same node as the kube-controller-manager pod.
+	kubeControllerManagerPodOnNode := healthyKubeControllerManagerPod.DeepCopy()
+	kubeControllerManagerPodOnNode.Spec.NodeName = "control-plane-node"
+	kubeControllerManagerPodOnNode.Spec.NodeSelector = map[string]string{"kubernetes.io/os": "linux"}
+	healthyAgentDeploymentOnControlPlaneNode := healthyAgentDeployment.DeepCopy()
+	healthyAgentDeploymentOnControlPlaneNode.Spec.Template.Spec.NodeName = "control-plane-node"
+	healthyAgentDeploymentOnControlPlaneNode.Spec.Template.Spec.NodeSelector = map[string]string{"kubernetes.io/os": "linux"}
+	configuredAgentNodeSelector := map[string]string{"example.com/signing-key": "true"}
+	configuredAgentAffinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key:      "example.com/role",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{"signer"},
+					}},
+				}},
+			},
+		},
+	}
+	healthyAgentDeploymentWithConfiguredNodeSelectorAndAffinity := healthyAgentDeployment.DeepCopy()
+	healthyAgentDeploymentWithConfiguredNodeSelectorAndAffinity.Spec.Template.Spec.NodeSelector = configuredAgentNodeSelector
+	healthyAgentDeploymentWithConfiguredNodeSelectorAndAffinity.Spec.Template.Spec.Affinity = configuredAgentAffinity
+	healthyAgentDeploymentWithConfiguredAffinity := healthyAgentDeployment.DeepCopy()
+	healthyAgentDeploymentWithConfiguredAffinity.Spec.Template.Spec.Affinity = configuredAgentAffinity
+
 	// If an admission controller sets extra labels or annotations, that's okay.
 	// We test this by ensuring that if a Deployment exists with extra labels, we don't try to delete them.
 	healthyAgentDeploymentWithExtraLabels := healthyAgentDeployment.DeepCopy()
@@ -238,6 +302,11 @@ func TestAgentController(t *testing.T) {
 	tests := []struct {
 		name                             string
 		discoveryURLOverride             *string
+		agentResources                   *corev1.ResourceRequirements
+		agentTolerations                 []corev1.Toleration
+		agentPriorityClassName           string
+		agentNodeSelector                map[string]string
+		agentAffinity                    *corev1.Affinity
 		pinnipedObjects                  []runtime.Object
 		kubeObjects                      []runtime.Object
 		addKubeReactions                 func(*kubefake.Clientset)
@@ -624,6 +693,214 @@ func TestAgentController(t *testing.T) {
 				`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"kube-cert-agent-controller","caller":"kubecertagent/kubecertagent.go:<line>$kubecertagent.(*agentController).createOrUpdateDeployment","message":"updating existing deployment","deployment":{"name":"pinniped-concierge-kube-cert-agent","namespace":"concierge"},"templatePod":{"name":"kube-controller-manager-1","namespace":"kube-system"}}`,
 			},
 		},
+		{
+			name: "deployment exists, but has different resources than the configured resources",
+			pinnipedObjects: []runtime.Object{
+				initialCredentialIssuer,
+			},
+			kubeObjects: []runtime.Object{
+				healthyKubeControllerManagerPod,
+				healthyAgentDeployment,
+				healthyAgentPod,
+			},
+			agentResources: customAgentResources,
+			wantDistinctErrors: []string{
+				"failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+			},
+			wantAgentDeployment:       healthyAgentDeploymentWithCustomResources,
+			wantDeploymentActionVerbs: []string{"list", "watch", "update"},
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.KubeClusterSigningCertificateStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        "failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+			wantDistinctLogs: []string{
+				`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"kube-cert-agent-controller","caller":"kubecertagent/kubecertagent.go:<line>$kubecertagent.(*agentController).createOrUpdateDeployment","message":"updating existing deployment","deployment":{"name":"pinniped-concierge-kube-cert-agent","namespace":"concierge"},"templatePod":{"name":"kube-controller-manager-1","namespace":"kube-system"}}`,
+			},
+		},
+		{
+			name: "deployment exists, but has an unexpected toleration instead of the configured toleration",
+			pinnipedObjects: []runtime.Object{
+				initialCredentialIssuer,
+			},
+			kubeObjects: []runtime.Object{
+				healthyKubeControllerManagerPod,
+				healthyAgentDeploymentWithUnexpectedToleration,
+				healthyAgentPod,
+			},
+			agentTolerations: []corev1.Toleration{configuredAgentToleration},
+			wantDistinctErrors: []string{
+				"failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+			},
+			wantAgentDeployment:       healthyAgentDeploymentWithConfiguredToleration,
+			wantDeploymentActionVerbs: []string{"list", "watch", "update"},
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.KubeClusterSigningCertificateStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        "failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+			wantDistinctLogs: []string{
+				`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"kube-cert-agent-controller","caller":"kubecertagent/kubecertagent.go:<line>$kubecertagent.(*agentController).createOrUpdateDeployment","message":"updating existing deployment","deployment":{"name":"pinniped-concierge-kube-cert-agent","namespace":"concierge"},"templatePod":{"name":"kube-controller-manager-1","namespace":"kube-system"}}`,
+			},
+		},
+		{
+			name: "deployment exists, but has a different priority class than the configured priority class",
+			pinnipedObjects: []runtime.Object{
+				initialCredentialIssuer,
+			},
+			kubeObjects: []runtime.Object{
+				healthyKubeControllerManagerPod,
+				healthyAgentDeploymentWithOtherPriorityClass,
+				healthyAgentPod,
+			},
+			agentPriorityClassName: "system-cluster-critical",
+			wantDistinctErrors: []string{
+				"failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+			},
+			wantAgentDeployment:       healthyAgentDeploymentWithConfiguredPriorityClass,
+			wantDeploymentActionVerbs: []string{"list", "watch", "update"},
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.KubeClusterSigningCertificateStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        "failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+			wantDistinctLogs: []string{
+				`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"kube-cert-agent-controller","caller":"kubecertagent/kubecertagent.go:<line>$kubecertagent.(*agentController).createOrUpdateDeployment","message":"updating existing deployment","deployment":{"name":"pinniped-concierge-kube-cert-agent","namespace":"concierge"},"templatePod":{"name":"kube-controller-manager-1","namespace":"kube-system"}}`,
+			},
+		},
+		{
+			name: "deployment exists with the configured priority class, configmap missing",
+			pinnipedObjects: []runtime.Object{
+				initialCredentialIssuer,
+			},
+			kubeObjects: []runtime.Object{
+				healthyKubeControllerManagerPod,
+				healthyAgentDeploymentWithConfiguredPriorityClass,
+				healthyAgentPod,
+			},
+			agentPriorityClassName: "system-cluster-critical",
+			wantDistinctErrors: []string{
+				"failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+			},
+			wantAgentDeployment:       healthyAgentDeploymentWithConfiguredPriorityClass,
+			wantDeploymentActionVerbs: []string{"list", "watch"},
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.KubeClusterSigningCertificateStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        "failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name: "deployment exists on the kube-controller-manager pod's node, configmap missing",
+			pinnipedObjects: []runtime.Object{
+				initialCredentialIssuer,
+			},
+			kubeObjects: []runtime.Object{
+				kubeControllerManagerPodOnNode,
+				healthyAgentDeploymentOnControlPlaneNode,
+				healthyAgentPod,
+			},
+			wantDistinctErrors: []string{
+				"failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+			},
+			wantAgentDeployment:       healthyAgentDeploymentOnControlPlaneNode,
+			wantDeploymentActionVerbs: []string{"list", "watch"},
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.KubeClusterSigningCertificateStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        "failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name: "deployment exists on the kube-controller-manager pod's node, but a node selector and affinity are configured",
+			pinnipedObjects: []runtime.Object{
+				initialCredentialIssuer,
+			},
+			kubeObjects: []runtime.Object{
+				kubeControllerManagerPodOnNode,
+				healthyAgentDeploymentOnControlPlaneNode,
+				healthyAgentPod,
+			},
+			agentNodeSelector: configuredAgentNodeSelector,
+			agentAffinity:     configuredAgentAffinity,
+			wantDistinctErrors: []string{
+				"failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+			},
+			wantAgentDeployment:       healthyAgentDeploymentWithConfiguredNodeSelectorAndAffinity,
+			wantDeploymentActionVerbs: []string{"list", "watch", "update"},
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.KubeClusterSigningCertificateStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        "failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+			wantDistinctLogs: []string{
+				`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"kube-cert-agent-controller","caller":"kubecertagent/kubecertagent.go:<line>$kubecertagent.(*agentController).createOrUpdateDeployment","message":"updating existing deployment","deployment":{"name":"pinniped-concierge-kube-cert-agent","namespace":"concierge"},"templatePod":{"name":"kube-controller-manager-1","namespace":"kube-system"}}`,
+			},
+		},
+		{
+			name: "deployment exists on the kube-controller-manager pod's node, but only an affinity is configured",
+			pinnipedObjects: []runtime.Object{
+				initialCredentialIssuer,
+			},
+			kubeObjects: []runtime.Object{
+				kubeControllerManagerPodOnNode,
+				healthyAgentDeploymentOnControlPlaneNode,
+				healthyAgentPod,
+			},
+			agentAffinity: configuredAgentAffinity,
+			wantDistinctErrors: []string{
+				"failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+			},
+			wantAgentDeployment:       healthyAgentDeploymentWithConfiguredAffinity,
+			wantDeploymentActionVerbs: []string{"list", "watch", "update"},
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.KubeClusterSigningCertificateStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        "failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+			wantDistinctLogs: []string{
+				`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"kube-cert-agent-controller","caller":"kubecertagent/kubecertagent.go:<line>$kubecertagent.(*agentController).createOrUpdateDeployment","message":"updating existing deployment","deployment":{"name":"pinniped-concierge-kube-cert-agent","namespace":"concierge"},"templatePod":{"name":"kube-controller-manager-1","namespace":"kube-system"}}`,
+			},
+		},
+		{
+			name: "deployment exists with the configured node selector and affinity, configmap missing",
+			pinnipedObjects: []runtime.Object{
+				initialCredentialIssuer,
+			},
+			kubeObjects: []runtime.Object{
+				kubeControllerManagerPodOnNode,
+				healthyAgentDeploymentWithConfiguredNodeSelectorAndAffinity,
+				healthyAgentPod,
+			},
+			agentNodeSelector: configuredAgentNodeSelector,
+			agentAffinity:     configuredAgentAffinity,
+			wantDistinctErrors: []string{
+				"failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+			},
+			wantAgentDeployment:       healthyAgentDeploymentWithConfiguredNodeSelectorAndAffinity,
+			wantDeploymentActionVerbs: []string{"list", "watch"},
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.KubeClusterSigningCertificateStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        "failed to get kube-public/cluster-info configmap: configmap \"cluster-info\" not found",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
 		{
 			name: "deployment exists, configmap missing",
 			pinnipedObjects: []runtime.Object{
@@ -1043,6 +1320,10 @@ func TestAgentController(t *testing.T) {
 			if tt.mocks != nil {
 				tt.mocks(t, mockExecutor.EXPECT(), mockDynamicCert.EXPECT(), execCache)
 			}
+			agentResources := healthyAgentDeployment.Spec.Template.Spec.Containers[0].Resources
+			if tt.agentResources != nil {
+				agentResources = *tt.agentResources
+			}
 			controller := newAgentController(
 				AgentConfig{
 					Namespace:                 "concierge",
@@ -1050,6 +1331,11 @@ func TestAgentController(t *testing.T) {
 					ServiceAccountName:        "test-service-account-name",
 					NamePrefix:                "pinniped-concierge-kube-cert-agent-",
 					ContainerImagePullSecrets: []string{"pinniped-image-pull-secret"},
+					Resources:                 agentResources,
+					Tolerations:               tt.agentTolerations,
+					PriorityClassName:         tt.agentPriorityClassName,
+					NodeSelector:              tt.agentNodeSelector,
+					Affinity:                  tt.agentAffinity,
 					CredentialIssuerName:      initialCredentialIssuer.Name,
 					Labels: map[string]string{
 						"extralabel": "labelvalue",
@@ -1197,6 +1483,49 @@ func TestMergeLabelsAndAnnotations(t *testing.T) {
 	}
 }
 
+func TestMergeTolerations(t *testing.T) {
+	t.Parallel()
+
+	toleration1 := corev1.Toleration{Key: "key1", Operator: corev1.TolerationOpExists}
+	toleration2 := corev1.Toleration{Key: "key2", Operator: corev1.TolerationOpEqual, Value: "value2", Effect: corev1.TaintEffectNoSchedule}
+	toleration3 := corev1.Toleration{Key: "key3", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute}
+
+	tests := []struct {
+		name                         string
+		controllerManagerTolerations []corev1.Toleration
+		configuredTolerations        []corev1.Toleration
+		expected                     []corev1.Toleration
+	}{
+		{
+			name:     "empty",
+			expected: nil,
+		},
+		{
+			name:                         "only tolerations from the kube-controller-manager pod",
+			controllerManagerTolerations: []corev1.Toleration{toleration1},
+			expected:                     []corev1.Toleration{toleration1},
+		},
+		{
+			name:                  "only configured tolerations",
+			configuredTolerations: []corev1.Toleration{toleration1, toleration2},
+			expected:              []corev1.Toleration{toleration1, toleration2},
+		},
+		{
+			name:                         "configured tolerations are added after the kube-controller-manager pod's tolerations without duplicates",
+			controllerManagerTolerations: []corev1.Toleration{toleration1, toleration2},
+			configuredTolerations:        []corev1.Toleration{toleration2, toleration3},
+			expected:                     []corev1.Toleration{toleration1, toleration2, toleration3},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, mergeTolerations(tt.controllerManagerTolerations, tt.configuredTolerations))
+		})
+	}
+}
+
 func deduplicate(strings []string) []string {
 	if strings == nil {
 		return nil
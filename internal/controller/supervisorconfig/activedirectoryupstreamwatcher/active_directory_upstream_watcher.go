@@ -10,13 +10,16 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
 	"github.com/google/uuid"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
 	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
@@ -56,6 +59,10 @@ const (
 	// - perform nested group search by default.
 	defaultActiveDirectoryGroupSearchFilter = "(&(objectClass=group)(member:1.2.840.113556.1.4.1941:={}))"
 
+	// ldapMatchingRuleInChainOID is the OID of the LDAP_MATCHING_RULE_IN_CHAIN matching rule, which makes
+	// ActiveDirectory walk the chain of ancestry of the entries, e.g. to find nested groups.
+	ldapMatchingRuleInChainOID = "1.2.840.113556.1.4.1941"
+
 	sAMAccountNameAttribute = "sAMAccountName"
 	// pwdLastSetAttribute is the date and time that the password for this account was last changed.
 	// https://docs.microsoft.com/en-us/windows/win32/adschema/a-pwdlastset
@@ -108,10 +115,22 @@ func (s *activeDirectoryUpstreamGenericLDAPSpec) TLSSpec() *v1alpha1.TLSSpec {
 	return s.activeDirectoryIdentityProvider.Spec.TLS
 }
 
+func (s *activeDirectoryUpstreamGenericLDAPSpec) ConnectionProtocol() v1alpha1.LDAPConnectionProtocol {
+	return s.activeDirectoryIdentityProvider.Spec.ConnectionProtocol
+}
+
 func (s *activeDirectoryUpstreamGenericLDAPSpec) BindSecretName() string {
 	return s.activeDirectoryIdentityProvider.Spec.Bind.SecretName
 }
 
+func (s *activeDirectoryUpstreamGenericLDAPSpec) BindDNTemplate() string {
+	return "" // the bind username of an Active Directory bind user does not need to be a DN
+}
+
+func (s *activeDirectoryUpstreamGenericLDAPSpec) AllowAnonymousBind() bool {
+	return false // anonymous bind is not supported for Active Directory
+}
+
 func (s *activeDirectoryUpstreamGenericLDAPSpec) UserSearch() upstreamwatchers.UpstreamGenericLDAPUserSearch {
 	return &activeDirectoryUpstreamGenericLDAPUserSearch{s.activeDirectoryIdentityProvider.Spec.UserSearch}
 }
@@ -159,6 +178,21 @@ func (s *activeDirectoryUpstreamGenericLDAPSpec) DetectAndSetSearchBase(ctx cont
 	}
 }
 
+func (s *activeDirectoryUpstreamGenericLDAPSpec) ValidateUserSearchBase(_ context.Context, _ *upstreamldap.ProviderConfig) *v1alpha1.Condition {
+	// Not implemented for Active Directory, so no condition is added.
+	return nil
+}
+
+func (s *activeDirectoryUpstreamGenericLDAPSpec) DryRunGroupSearch(_ context.Context, _ *upstreamldap.ProviderConfig) *v1alpha1.Condition {
+	// Not implemented for Active Directory, so no condition is added.
+	return nil
+}
+
+func (s *activeDirectoryUpstreamGenericLDAPSpec) DryRunUserSearch(_ context.Context, _ *upstreamldap.ProviderConfig) *v1alpha1.Condition {
+	// Not implemented for Active Directory, so no condition is added.
+	return nil
+}
+
 type activeDirectoryUpstreamGenericLDAPUserSearch struct {
 	userSearch v1alpha1.ActiveDirectoryIdentityProviderUserSearch
 }
@@ -226,10 +260,14 @@ type UpstreamActiveDirectoryIdentityProviderICache interface {
 type activeDirectoryWatcherController struct {
 	cache                                   UpstreamActiveDirectoryIdentityProviderICache
 	validatedSettingsCache                  upstreamwatchers.ValidatedSettingsCacheI
+	testConnectionThrottle                  *upstreamwatchers.TestConnectionThrottle
+	providerCache                           *upstreamwatchers.ProviderCache
 	ldapDialer                              upstreamldap.LDAPDialer
 	client                                  pinnipedclientset.Interface
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer
 	secretInformer                          corev1informers.SecretInformer
+	serverCertExpiryWarningWindow           time.Duration
+	authenticationCacheTTL                  time.Duration
 }
 
 // New instantiates a new controllerlib.Controller which will populate the provided UpstreamActiveDirectoryIdentityProviderICache.
@@ -238,17 +276,23 @@ func New(
 	client pinnipedclientset.Interface,
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer,
 	secretInformer corev1informers.SecretInformer,
+	serverCertExpiryWarningWindow time.Duration,
+	authenticationCacheTTL time.Duration,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
 	return newInternal(
 		idpCache,
 		// start with an empty cache
 		upstreamwatchers.NewValidatedSettingsCache(),
+		// start without remembering any previous probes of the servers
+		upstreamwatchers.NewTestConnectionThrottle(clock.RealClock{}),
 		// nil means to use a real production dialer when creating objects to add to the cache
 		nil,
 		client,
 		activeDirectoryIdentityProviderInformer,
 		secretInformer,
+		serverCertExpiryWarningWindow,
+		authenticationCacheTTL,
 		withInformer,
 	)
 }
@@ -257,19 +301,26 @@ func New(
 func newInternal(
 	idpCache UpstreamActiveDirectoryIdentityProviderICache,
 	validatedSettingsCache upstreamwatchers.ValidatedSettingsCacheI,
+	testConnectionThrottle *upstreamwatchers.TestConnectionThrottle,
 	ldapDialer upstreamldap.LDAPDialer,
 	client pinnipedclientset.Interface,
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer,
 	secretInformer corev1informers.SecretInformer,
+	serverCertExpiryWarningWindow time.Duration,
+	authenticationCacheTTL time.Duration,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
 	c := activeDirectoryWatcherController{
 		cache:                                   idpCache,
 		validatedSettingsCache:                  validatedSettingsCache,
+		testConnectionThrottle:                  testConnectionThrottle,
+		providerCache:                           upstreamwatchers.NewProviderCache(),
 		ldapDialer:                              ldapDialer,
 		client:                                  client,
 		activeDirectoryIdentityProviderInformer: activeDirectoryIdentityProviderInformer,
 		secretInformer:                          secretInformer,
+		serverCertExpiryWarningWindow:           serverCertExpiryWarningWindow,
+		authenticationCacheTTL:                  authenticationCacheTTL,
 	}
 	return controllerlib.New(
 		controllerlib.Config{Name: activeDirectoryControllerName, Syncer: &c},
@@ -306,6 +357,12 @@ func (c *activeDirectoryWatcherController) Sync(ctx controllerlib.Context) error
 	}
 
 	c.cache.SetActiveDirectoryIdentityProviders(validatedUpstreams)
+	// Keep the Providers of the upstreams which are still in the cache for the next sync, closing the others.
+	cachedNames := sets.NewString()
+	for _, validatedUpstream := range validatedUpstreams {
+		cachedNames.Insert(validatedUpstream.GetName())
+	}
+	c.providerCache.DeleteAllExcept(cachedNames)
 
 	if requeue {
 		return controllerlib.ErrSyntheticRequeue
@@ -334,7 +391,9 @@ func (c *activeDirectoryWatcherController) validateUpstream(ctx context.Context,
 			GroupNameAttribute: adUpstreamImpl.Spec().GroupSearch().GroupNameAttribute(),
 			SkipGroupRefresh:   spec.GroupSearch.SkipGroupRefresh,
 		},
-		Dialer: c.ldapDialer,
+		Dialer:              c.ldapDialer,
+		ConnectionPool:      upstreamwatchers.LDAPConnectionPoolConfig(),
+		AuthenticationCache: upstreamwatchers.LDAPAuthenticationCacheConfig(c.authenticationCacheTTL),
 		UIDAttributeParsingOverrides: map[string]func(*ldap.Entry) (string, error){
 			"objectGUID": microsoftUUIDFromBinaryAttr("objectGUID"),
 		},
@@ -345,23 +404,29 @@ func (c *activeDirectoryWatcherController) validateUpstream(ctx context.Context,
 		},
 	}
 
+	if !strings.Contains(config.GroupSearch.Filter, ":"+ldapMatchingRuleInChainOID+":") {
+		// A filter which uses the in-chain matching rule already finds the nested groups in a single search.
+		config.GroupSearch.NestedGroupsMaxDepth = upstreamwatchers.NestedGroupsMaxDepth(spec.GroupSearch.ResolveNestedGroups, spec.GroupSearch.NestedGroupsMaxDepth)
+	}
+
 	if spec.GroupSearch.Attributes.GroupName == "" {
 		config.GroupAttributeParsingOverrides = map[string]func(*ldap.Entry) (string, error){
 			defaultActiveDirectoryGroupNameAttributeName: groupSAMAccountNameWithDomainSuffix,
 		}
 	}
 
-	conditions := upstreamwatchers.ValidateGenericLDAP(ctx, adUpstreamImpl, c.secretInformer, c.validatedSettingsCache, config)
+	conditions, _ := upstreamwatchers.ValidateGenericLDAP(ctx, adUpstreamImpl, c.secretInformer, c.validatedSettingsCache, c.testConnectionThrottle, config, c.serverCertExpiryWarningWindow)
 
 	c.updateStatus(ctx, upstream, conditions.Conditions())
 
-	return upstreamwatchers.EvaluateConditions(conditions, config)
+	return upstreamwatchers.EvaluateConditions(conditions, config, c.providerCache)
 }
 
 func (c *activeDirectoryWatcherController) updateStatus(ctx context.Context, upstream *v1alpha1.ActiveDirectoryIdentityProvider, conditions []*v1alpha1.Condition) {
 	log := plog.WithValues("namespace", upstream.Namespace, "name", upstream.Name)
 	updated := upstream.DeepCopy()
 
+	upstreamwatchers.RemoveStaleInformationalConditions(conditions, &updated.Status.Conditions)
 	hadErrorCondition := conditionsutil.MergeIDPConditions(conditions, upstream.Generation, &updated.Status.Conditions, log)
 
 	updated.Status.Phase = v1alpha1.ActiveDirectoryPhaseReady
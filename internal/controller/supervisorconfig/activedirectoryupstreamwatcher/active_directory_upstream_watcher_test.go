@@ -21,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
 	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
@@ -82,7 +83,7 @@ func TestActiveDirectoryUpstreamWatcherControllerFilterSecrets(t *testing.T) {
 			secretInformer := kubeInformers.Core().V1().Secrets()
 			withInformer := testutil.NewObservableWithInformerOption()
 
-			New(nil, nil, activeDirectoryIDPInformer, secretInformer, withInformer.WithInformer)
+			New(nil, nil, activeDirectoryIDPInformer, secretInformer, 0, 0, withInformer.WithInformer)
 
 			unrelated := corev1.Secret{}
 			filter := withInformer.GetFilterForInformer(secretInformer)
@@ -127,7 +128,7 @@ func TestActiveDirectoryUpstreamWatcherControllerFilterActiveDirectoryIdentityPr
 			secretInformer := kubeInformers.Core().V1().Secrets()
 			withInformer := testutil.NewObservableWithInformerOption()
 
-			New(nil, nil, activeDirectoryIDPInformer, secretInformer, withInformer.WithInformer)
+			New(nil, nil, activeDirectoryIDPInformer, secretInformer, 0, 0, withInformer.WithInformer)
 
 			unrelated := corev1.Secret{}
 			filter := withInformer.GetFilterForInformer(activeDirectoryIDPInformer)
@@ -163,8 +164,12 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 		testUsernameAttrName  = "test-username-attr"
 		testGroupNameAttrName = "test-group-name-attr"
 		testUIDAttrName       = "test-uid-attr"
+
+		testAuthenticationCacheTTL = 10 * time.Second
 	)
 
+	testBindCredentialsFingerprint := upstreamwatchers.BindCredentialsFingerprint(testBindUsername, testBindPassword)
+
 	testValidSecretData := map[string][]byte{"username": []byte(testBindUsername), "password": []byte(testBindPassword)}
 
 	testCA, err := certauthority.New("test CA", time.Minute)
@@ -240,7 +245,7 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			Status:             "True",
 			LastTransitionTime: now,
 			Reason:             "Success",
-			Message:            "loaded bind secret",
+			Message:            `loaded bind secret of type "kubernetes.io/basic-auth"`,
 			ObservedGeneration: gen,
 		}
 	}
@@ -392,13 +397,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -445,7 +452,7 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 							Status:             "False",
 							LastTransitionTime: now,
 							Reason:             "SecretWrongType",
-							Message:            fmt.Sprintf(`referenced Secret "%s" has wrong type "some-other-type" (should be "kubernetes.io/basic-auth")`, testSecretName),
+							Message:            fmt.Sprintf(`referenced Secret "%s" has wrong type "some-other-type" (should be "kubernetes.io/basic-auth" or "Opaque")`, testSecretName),
 							ObservedGeneration: 1234,
 						},
 						tlsConfigurationValidLoadedTrueCondition(1234),
@@ -498,7 +505,7 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 							Type:               "TLSConfigurationValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "InvalidTLSConfig",
+							Reason:             "InvalidBase64",
 							Message:            "certificateAuthorityData is invalid: illegal base64 data at input byte 4",
 							ObservedGeneration: 1234,
 						},
@@ -524,7 +531,7 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 							Type:               "TLSConfigurationValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "InvalidTLSConfig",
+							Reason:             "NoCertificatesFound",
 							Message:            "certificateAuthorityData is invalid: no certificates found",
 							ObservedGeneration: 1234,
 						},
@@ -591,13 +598,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -660,13 +669,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -734,11 +745,13 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.StartTLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.StartTLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             "ldap.example.com",
+				IDPSpecGeneration:          1234,
 				ConnectionValidCondition: &v1alpha1.Condition{
 					Type:   "LDAPConnectionValid",
 					Status: "True",
@@ -862,13 +875,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -914,13 +929,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -947,7 +964,7 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 							Type:               "LDAPConnectionValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "LDAPConnectionError",
+							Reason:             "LDAPBindError",
 							Message: fmt.Sprintf(
 								`could not successfully connect to "%s" and bind as user "%s": error binding as "%s": some bind error`,
 								testHost, testBindUsername, testBindUsername),
@@ -1014,7 +1031,7 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 							Type:               "LDAPConnectionValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "LDAPConnectionError",
+							Reason:             "LDAPBindError",
 							Message: fmt.Sprintf(
 								`could not successfully connect to "%s" and bind as user "%s": error binding as "%s": some bind error`,
 								testHost, testBindUsername, testBindUsername),
@@ -1050,7 +1067,7 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 							Type:               "LDAPConnectionValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "LDAPConnectionError",
+							Reason:             "LDAPBindError",
 							Message: fmt.Sprintf(
 								`could not successfully connect to "%s" and bind as user "%s": error binding as "%s": some bind error`,
 								testHost, testBindUsername, testBindUsername),
@@ -1074,13 +1091,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should not perform a test dial and bind. No mocking here means the test will fail if Bind() or Close() are called.
@@ -1094,13 +1113,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -1116,9 +1137,10 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				IDPSpecGeneration:         1234,
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				IDPSpecGeneration:          1234,
 			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(2)
@@ -1165,13 +1187,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            exampleDefaultNamingContext,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             exampleDefaultNamingContext,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
 			}},
 		},
 		{
@@ -1186,13 +1210,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            exampleDefaultNamingContext,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             exampleDefaultNamingContext,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
 			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 			},
@@ -1237,13 +1263,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            exampleDefaultNamingContext,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             exampleDefaultNamingContext,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
 			}},
 		},
 		{
@@ -1257,13 +1285,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.StartTLS,
-				IDPSpecGeneration:         1234,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.StartTLS,
+				IDPSpecGeneration:          1234,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should not perform a test dial and bind. No mocking here means the test will fail if Bind() or Close() are called.
@@ -1277,13 +1307,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.StartTLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.StartTLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -1296,13 +1328,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1233,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1233,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should perform a test dial and bind.
@@ -1318,13 +1352,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -1337,13 +1373,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				IDPSpecGeneration:         1234,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")), // already previously validated with version 4242
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				IDPSpecGeneration:          1234,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")), // already previously validated with version 4242
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// The connection had already been validated previously and the result was cached, so don't probe the server again.
@@ -1358,13 +1396,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -1397,13 +1437,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -1416,13 +1458,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")}, // newer secret version!
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4241",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4241")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4241",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4241")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}}, // old version was validated
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should perform a test dial and bind.
@@ -1438,13 +1482,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -1498,13 +1544,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 		{
@@ -1562,13 +1610,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            exampleDefaultNamingContext,
-				GroupSearchBase:           exampleDefaultNamingContext,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             exampleDefaultNamingContext,
+				GroupSearchBase:            exampleDefaultNamingContext,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
 			}},
 		},
 		{
@@ -1625,13 +1675,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            exampleDefaultNamingContext,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             exampleDefaultNamingContext,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
 			}},
 		},
 		{
@@ -1688,13 +1740,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           exampleDefaultNamingContext,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            exampleDefaultNamingContext,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
 			}},
 		},
 		{
@@ -1844,13 +1898,15 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4241",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4241")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
+				BindSecretResourceVersion:  "4241",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4241")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
 			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should perform a test dial and bind.
@@ -1900,12 +1956,14 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{
 				testName: {BindSecretResourceVersion: "4242",
-					LDAPConnectionProtocol:   upstreamldap.TLS,
-					GroupSearchBase:          exampleDefaultNamingContext,
-					UserSearchBase:           testUserSearchBase,
-					IDPSpecGeneration:        1234,
-					ConnectionValidCondition: condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-					SearchBaseFoundCondition: condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
+					BindCredentialsFingerprint: testBindCredentialsFingerprint,
+					LDAPConnectionProtocol:     upstreamldap.TLS,
+					GroupSearchBase:            exampleDefaultNamingContext,
+					ConnectionHost:             testHost,
+					UserSearchBase:             testUserSearchBase,
+					IDPSpecGeneration:          1234,
+					ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+					SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInRootDSECondition(0))),
 				}},
 		},
 		{
@@ -1968,13 +2026,158 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+			}},
+		},
+		{
+			name: "resolving nested groups with a filter which does not use the in-chain matching rule sets the max depth",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.ActiveDirectoryIdentityProvider) {
+				upstream.Spec.GroupSearch.ResolveNestedGroups = true
+				upstream.Spec.GroupSearch.NestedGroupsMaxDepth = 3
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:                 testGroupSearchBase,
+						Filter:               testGroupSearchFilter,
+						GroupNameAttribute:   testGroupNameAttrName,
+						NestedGroupsMaxDepth: 3,
+					},
+					UIDAttributeParsingOverrides: map[string]func(*ldap.Entry) (string, error){"objectGUID": microsoftUUIDFromBinaryAttr("objectGUID")},
+					RefreshAttributeChecks: map[string]func(*ldap.Entry, provider.RefreshAttributes) error{
+						"pwdLastSet":                         upstreamldap.AttributeUnchangedSinceLogin("pwdLastSet"),
+						"userAccountControl":                 validUserAccountControl,
+						"msDS-User-Account-Control-Computed": validComputedUserAccountControl,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.ActiveDirectoryIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.ActiveDirectoryIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						activeDirectoryConnectionValidTrueCondition(1234, "4242"),
+						searchBaseFoundInConfigCondition(1234),
+						{
+							Type:               "TLSConfigurationValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            "loaded TLS configuration",
+							ObservedGeneration: 1234,
+						},
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+			}},
+		},
+		{
+			name: "resolving nested groups with a filter which uses the in-chain matching rule does not search for nested groups again",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.ActiveDirectoryIdentityProvider) {
+				upstream.Spec.GroupSearch.ResolveNestedGroups = true
+				upstream.Spec.GroupSearch.Filter = "(&(objectClass=group)(member:1.2.840.113556.1.4.1941:={}))"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             "(&(objectClass=group)(member:1.2.840.113556.1.4.1941:={}))",
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+					UIDAttributeParsingOverrides: map[string]func(*ldap.Entry) (string, error){"objectGUID": microsoftUUIDFromBinaryAttr("objectGUID")},
+					RefreshAttributeChecks: map[string]func(*ldap.Entry, provider.RefreshAttributes) error{
+						"pwdLastSet":                         upstreamldap.AttributeUnchangedSinceLogin("pwdLastSet"),
+						"userAccountControl":                 validUserAccountControl,
+						"msDS-User-Account-Control-Computed": validComputedUserAccountControl,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.ActiveDirectoryIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.ActiveDirectoryIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						activeDirectoryConnectionValidTrueCondition(1234, "4242"),
+						searchBaseFoundInConfigCondition(1234),
+						{
+							Type:               "TLSConfigurationValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            "loaded TLS configuration",
+							ObservedGeneration: 1234,
+						},
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition:   condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:   condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
 	}
@@ -2025,10 +2228,13 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 			controller := newInternal(
 				cache,
 				validatedSettingsCache,
+				upstreamwatchers.NewTestConnectionThrottle(clock.RealClock{}),
 				dialer,
 				fakePinnipedClient,
 				pinnipedInformers.IDP().V1alpha1().ActiveDirectoryIdentityProviders(),
 				kubeInformers.Core().V1().Secrets(),
+				0,
+				testAuthenticationCacheTTL,
 				controllerlib.WithInformer,
 			)
 
@@ -2055,6 +2261,10 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				// The dialer that was passed in to the controller's constructor should always have been
 				// passed through to the provider.
 				copyOfExpectedValueForResultingCache.Dialer = dialer
+				// The connection pool settings should always be the defaults.
+				copyOfExpectedValueForResultingCache.ConnectionPool = upstreamwatchers.LDAPConnectionPoolConfig()
+				// The authentication cache settings should use the TTL that was passed in to the controller's constructor.
+				copyOfExpectedValueForResultingCache.AuthenticationCache = upstreamwatchers.LDAPAuthenticationCacheConfig(testAuthenticationCacheTTL)
 
 				// function equality is awkward. Do the check for equality separately from the rest of the config.
 				expectedUIDAttributeParsingOverrides := copyOfExpectedValueForResultingCache.UIDAttributeParsingOverrides
@@ -1,4 +1,4 @@
-// Copyright 2021-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package ldapupstreamwatcher implements a controller which watches LDAPIdentityProviders.
@@ -6,14 +6,23 @@ package ldapupstreamwatcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/go-ldap/ldap/v3"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	pinnipedclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
 	idpinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/idp/v1alpha1"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
@@ -27,8 +36,46 @@ import (
 
 const (
 	ldapControllerName = "ldap-upstream-observer"
+
+	// maxConnectionTimeout is the largest allowed spec.connectionTimeout. Longer timeouts would let an unresponsive
+	// server hold up this controller's syncs, and the end users' logins, for too long.
+	maxConnectionTimeout = 10 * time.Minute
+
+	// maxDryRunGroupsInMessage is how many of the groups found by the group search dry run are listed in the
+	// GroupSearchValid condition. A user may be a member of very many groups, and the condition message
+	// should stay readable.
+	maxDryRunGroupsInMessage = 10
+
+	// Constants related to conditions.
+	typeSearchConfigurationValid       = "SearchConfigurationValid"
+	reasonInvalidGroupSearchBase       = "InvalidGroupSearchBase"
+	reasonInvalidAllowedGroups         = "InvalidAllowedGroups"
+	reasonInvalidGroupNameAttribute    = "InvalidGroupNameAttribute"
+	reasonInvalidGroupSearchMode       = "InvalidGroupSearchMode"
+	reasonInvalidUserAttribute         = "InvalidUserAttributeForGroups"
+	reasonInvalidUIDEncoding           = "InvalidUIDEncoding"
+	reasonInvalidMultiValuePolicy      = "InvalidMultiValuePolicy"
+	reasonInvalidExtraAttributes       = "InvalidExtraAttributes"
+	reasonInvalidSearchScope           = "InvalidSearchScope"
+	reasonInvalidUserDNTemplate        = "InvalidUserDNTemplate"
+	reasonUserSearchFilterInsecure     = "UserSearchFilterInsecure"
+	reasonInvalidUsernameNormalization = "InvalidUsernameNormalization"
+	reasonInvalidConnectionTimeout     = "InvalidConnectionTimeout"
+	typeUserSearchBaseValid            = "UserSearchBaseValid"
+	reasonUserSearchBaseInvalid        = "UserSearchBaseInvalid"
+	typeGroupSearchValid               = "GroupSearchValid"
+	reasonGroupSearchDryRunError       = "GroupSearchDryRunError"
+	reasonGroupSearchBaseUnreadable    = "GroupSearchBaseUnreadable"
+	typeUserSearchValid                = "UserSearchValid"
+	reasonUserSearchDryRunError        = "UserSearchDryRunError"
+	reasonUserSearchAmbiguous          = "UserSearchAmbiguous"
+	reasonUserAttributeMultiValued     = "UserAttributeMultiValued"
 )
 
+// attributeNameRegexp matches an LDAP attribute description, which is a short name or an OID followed by any
+// attribute options, as described by RFC 4512 section 2.5. E.g. "cn", "2.5.4.3", or "cn;lang-en".
+var attributeNameRegexp = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*|[0-9]+(\.[0-9]+)*)(;[A-Za-z0-9-]+)*$`)
+
 type ldapUpstreamGenericLDAPImpl struct {
 	ldapIdentityProvider v1alpha1.LDAPIdentityProvider
 }
@@ -65,10 +112,22 @@ func (s *ldapUpstreamGenericLDAPSpec) TLSSpec() *v1alpha1.TLSSpec {
 	return s.ldapIdentityProvider.Spec.TLS
 }
 
+func (s *ldapUpstreamGenericLDAPSpec) ConnectionProtocol() v1alpha1.LDAPConnectionProtocol {
+	return s.ldapIdentityProvider.Spec.ConnectionProtocol
+}
+
 func (s *ldapUpstreamGenericLDAPSpec) BindSecretName() string {
 	return s.ldapIdentityProvider.Spec.Bind.SecretName
 }
 
+func (s *ldapUpstreamGenericLDAPSpec) BindDNTemplate() string {
+	return s.ldapIdentityProvider.Spec.Bind.BindDNTemplate
+}
+
+func (s *ldapUpstreamGenericLDAPSpec) AllowAnonymousBind() bool {
+	return s.ldapIdentityProvider.Spec.Bind.AllowAnonymousBind
+}
+
 func (s *ldapUpstreamGenericLDAPSpec) UserSearch() upstreamwatchers.UpstreamGenericLDAPUserSearch {
 	return &ldapUpstreamGenericLDAPUserSearch{s.ldapIdentityProvider.Spec.UserSearch}
 }
@@ -83,6 +142,222 @@ func (s *ldapUpstreamGenericLDAPSpec) DetectAndSetSearchBase(_ context.Context,
 	return nil
 }
 
+// ValidateUserSearchBase reads the user search base as the bind user. This catches mistakes such as a typo in
+// the user search base, which would otherwise only be noticed when every end user fails to log in.
+func (s *ldapUpstreamGenericLDAPSpec) ValidateUserSearchBase(ctx context.Context, config *upstreamldap.ProviderConfig) *v1alpha1.Condition {
+	if len(config.UserSearch.Base) == 0 {
+		return &v1alpha1.Condition{
+			Type:    typeUserSearchBaseValid,
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  upstreamwatchers.ReasonSuccess,
+			Message: "user search base validation is skipped because userSearch.base is empty",
+		}
+	}
+
+	if err := upstreamldap.New(*config).ValidateUserSearchBase(ctx); err != nil {
+		return &v1alpha1.Condition{
+			Type:    typeUserSearchBaseValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonUserSearchBaseInvalid,
+			Message: fmt.Sprintf(`userSearch.base %q could not be validated: %s`, config.UserSearch.Base, err.Error()),
+		}
+	}
+
+	return &v1alpha1.Condition{
+		Type:    typeUserSearchBaseValid,
+		Status:  v1alpha1.ConditionTrue,
+		Reason:  upstreamwatchers.ReasonSuccess,
+		Message: fmt.Sprintf(`userSearch.base %q was found`, config.UserSearch.Base),
+	}
+}
+
+// DryRunGroupSearch runs the configured group search for the dry run user, once that user's entry is found, since
+// that user's groups are resolved like the groups of the end users. This catches mistakes such as a bad group search
+// filter before any end user tries to log in. When there is no dry run username, or the dry run user is not found,
+// there is no user whose groups could be searched, so only the group search base and filter are validated.
+func (s *ldapUpstreamGenericLDAPSpec) DryRunGroupSearch(ctx context.Context, config *upstreamldap.ProviderConfig) *v1alpha1.Condition {
+	if config.GroupSearch.Mode != upstreamldap.GroupSearchModeUserAttribute {
+		if len(config.GroupSearch.Base) == 0 {
+			return &v1alpha1.Condition{
+				Type:    typeGroupSearchValid,
+				Status:  v1alpha1.ConditionTrue,
+				Reason:  upstreamwatchers.ReasonSuccess,
+				Message: "group search is skipped because groupSearch.base is empty",
+			}
+		}
+		if _, err := ldap.ParseDN(config.GroupSearch.Base); err != nil {
+			// There is no point in searching, and the invalid base is already reported by the SearchConfigurationValid condition.
+			return nil
+		}
+	}
+
+	// This Provider is thrown away after the dry run, so it should not keep its connection in a pool.
+	dryRunConfig := *config
+	dryRunConfig.ConnectionPool = upstreamldap.ConnectionPoolConfig{}
+	ldapProvider := upstreamldap.New(dryRunConfig)
+
+	username := s.ldapIdentityProvider.Spec.UserSearch.DryRunUsername
+	if len(username) == 0 {
+		return validateGroupSearchWithoutUser(ctx, ldapProvider, config, "userSearch.dryRunUsername is not configured")
+	}
+	response, authenticated, err := ldapProvider.DryRunAuthenticateUser(ctx, username, nil)
+	if err != nil || !authenticated {
+		// Why the dry run user could not be found is reported by the UserSearchValid condition.
+		return validateGroupSearchWithoutUser(ctx, ldapProvider, config, fmt.Sprintf("the dry run user %q could not be found", username))
+	}
+
+	result, err := ldapProvider.DryRunGroupSearchWithResult(ctx, response.DN)
+	if err != nil {
+		return &v1alpha1.Condition{
+			Type:   typeGroupSearchValid,
+			Status: v1alpha1.ConditionFalse,
+			Reason: groupSearchErrorReason(err),
+			Message: fmt.Sprintf(`group search dry run for user %q failed: %s; %s`,
+				username, err.Error(), groupSearchFailurePolicyDescription(config.GroupSearch.FailurePolicy)),
+		}
+	}
+
+	groups := result.Groups
+	message := fmt.Sprintf(`group search dry run for user %q found groups %q`, username, groups)
+	if len(groups) > maxDryRunGroupsInMessage {
+		message = fmt.Sprintf(`group search dry run for user %q found %d groups, including %q`,
+			username, len(groups), groups[:maxDryRunGroupsInMessage])
+	}
+	if config.GroupSearch.NestedGroupsMaxDepth > 0 {
+		message += fmt.Sprintf(` (%d direct groups and %d groups after resolving nested groups up to %d levels)`,
+			result.DirectGroupCount, result.DirectGroupCount+result.NestedGroupCount, config.GroupSearch.NestedGroupsMaxDepth)
+	}
+	if len(config.GroupSearch.AllowedGroups) > 0 {
+		message += fmt.Sprintf(` after filtering out %d groups which are not in groupSearch.allowedGroups`, result.DisallowedGroupCount)
+	}
+	message += "; " + groupSearchFailurePolicyDescription(config.GroupSearch.FailurePolicy)
+	return &v1alpha1.Condition{
+		Type:    typeGroupSearchValid,
+		Status:  v1alpha1.ConditionTrue,
+		Reason:  upstreamwatchers.ReasonSuccess,
+		Message: message,
+	}
+}
+
+// validateGroupSearchWithoutUser validates the group search base and filter when there is no user whose groups could
+// be searched, for the reason given by noUserReason. It does not find anyone's groups, so the condition message says
+// that the group search was not dry run.
+func validateGroupSearchWithoutUser(
+	ctx context.Context,
+	ldapProvider *upstreamldap.Provider,
+	config *upstreamldap.ProviderConfig,
+	noUserReason string,
+) *v1alpha1.Condition {
+	if config.GroupSearch.Mode == upstreamldap.GroupSearchModeUserAttribute {
+		return &v1alpha1.Condition{
+			Type:   typeGroupSearchValid,
+			Status: v1alpha1.ConditionTrue,
+			Reason: upstreamwatchers.ReasonSuccess,
+			Message: fmt.Sprintf(`group search dry run is skipped because %s, and groupSearch.mode %q reads the groups from the user's entry; %s`,
+				noUserReason, v1alpha1.LDAPGroupSearchModeUserAttribute, groupSearchFailurePolicyDescription(config.GroupSearch.FailurePolicy)),
+		}
+	}
+
+	if err := ldapProvider.ValidateGroupSearch(ctx); err != nil {
+		return &v1alpha1.Condition{
+			Type:   typeGroupSearchValid,
+			Status: v1alpha1.ConditionFalse,
+			Reason: groupSearchErrorReason(err),
+			Message: fmt.Sprintf(`group search validation failed: %s; %s`,
+				err.Error(), groupSearchFailurePolicyDescription(config.GroupSearch.FailurePolicy)),
+		}
+	}
+
+	return &v1alpha1.Condition{
+		Type:   typeGroupSearchValid,
+		Status: v1alpha1.ConditionTrue,
+		Reason: upstreamwatchers.ReasonSuccess,
+		Message: fmt.Sprintf(`groupSearch.base and groupSearch.filter are valid and searchable, but no user's groups were searched because %s; %s`,
+			noUserReason, groupSearchFailurePolicyDescription(config.GroupSearch.FailurePolicy)),
+	}
+}
+
+// groupSearchErrorReason returns the reason of the GroupSearchValid condition when the group search failed.
+func groupSearchErrorReason(err error) string {
+	if errors.Is(err, upstreamldap.ErrGroupSearchBaseUnreadable) {
+		// This is usually a missing permission of the bind user, rather than a mistake in the group search settings.
+		return reasonGroupSearchBaseUnreadable
+	}
+	return reasonGroupSearchDryRunError
+}
+
+// DryRunUserSearch searches for the configured dry run username without authenticating as that user. This catches
+// mistakes such as a user search filter which is too loose, and therefore finds more than one entry for a username,
+// before any end user tries to log in. The user's groups are also searched, like they would be for a login which
+// was granted the groups scope. When no dry run username is configured, no condition is added.
+func (s *ldapUpstreamGenericLDAPSpec) DryRunUserSearch(ctx context.Context, config *upstreamldap.ProviderConfig) *v1alpha1.Condition {
+	username := s.ldapIdentityProvider.Spec.UserSearch.DryRunUsername
+	if len(username) == 0 {
+		return nil
+	}
+
+	// This Provider is thrown away after the dry run, so it should not keep its connection in a pool.
+	dryRunConfig := *config
+	dryRunConfig.ConnectionPool = upstreamldap.ConnectionPoolConfig{}
+	groupsSkippedReason := ""
+	grantedScopes := []string{oidcapi.ScopeGroups}
+	if config.GroupSearch.Mode != upstreamldap.GroupSearchModeUserAttribute && len(config.GroupSearch.Base) == 0 {
+		groupsSkippedReason = "groupSearch.base is empty"
+		grantedScopes = nil
+	}
+	response, authenticated, err := upstreamldap.New(dryRunConfig).DryRunAuthenticateUser(ctx, username, grantedScopes)
+	if err != nil {
+		reason := reasonUserSearchDryRunError
+		switch {
+		case errors.Is(err, upstreamldap.ErrAmbiguousUser):
+			reason = reasonUserSearchAmbiguous
+		case errors.Is(err, upstreamldap.ErrMultiValuedAttribute):
+			// The user's entry was found, but its username or UID attribute has more than one value.
+			reason = reasonUserAttributeMultiValued
+		}
+		return &v1alpha1.Condition{
+			Type:    typeUserSearchValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reason,
+			Message: fmt.Sprintf(`user search dry run for username %q failed: %s`, username, err.Error()),
+		}
+	}
+	if !authenticated {
+		return &v1alpha1.Condition{
+			Type:    typeUserSearchValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonUserSearchDryRunError,
+			Message: fmt.Sprintf(`user search dry run for username %q did not find the user`, username),
+		}
+	}
+
+	message := fmt.Sprintf(`user search dry run for username %q found user %q with %d groups`,
+		username, response.User.GetName(), len(response.User.GetGroups()))
+	if len(groupsSkippedReason) > 0 {
+		message = fmt.Sprintf(`user search dry run for username %q found user %q, and did not search for groups because %s`,
+			username, response.User.GetName(), groupsSkippedReason)
+	}
+	return &v1alpha1.Condition{
+		Type:    typeUserSearchValid,
+		Status:  v1alpha1.ConditionTrue,
+		Reason:  upstreamwatchers.ReasonSuccess,
+		Message: message,
+	}
+}
+
+// groupSearchFailurePolicyDescription describes what happens to logins when the group search fails, for use in
+// the messages of the GroupSearchValid condition.
+func groupSearchFailurePolicyDescription(policy upstreamldap.GroupSearchFailurePolicy) string {
+	switch policy {
+	case upstreamldap.GroupSearchFailOpen:
+		return `logins will succeed without groups when the group search fails because groupSearchFailurePolicy is "FailOpen"`
+	case upstreamldap.GroupSearchFailClosed:
+		return `logins will fail when the group search fails because groupSearchFailurePolicy is "FailClosed"`
+	default:
+		return `logins will fail when the group search fails because groupSearchFailurePolicy defaults to "FailClosed"`
+	}
+}
+
 type ldapUpstreamGenericLDAPUserSearch struct {
 	userSearch v1alpha1.LDAPIdentityProviderUserSearch
 }
@@ -132,32 +407,73 @@ type UpstreamLDAPIdentityProviderICache interface {
 	SetLDAPIdentityProviders([]provider.UpstreamLDAPIdentityProviderI)
 }
 
+// NamespaceInformers are the informers for the LDAPIdentityProviders and their bind Secrets in one namespace.
+type NamespaceInformers struct {
+	LDAPIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer
+	SecretInformer               corev1informers.SecretInformer
+}
+
+// watchedNamespace is a namespace whose LDAPIdentityProviders are validated and added to the cache.
+type watchedNamespace struct {
+	NamespaceInformers
+
+	// namespacedNames is true when the providers of this namespace are added to the cache under names which are
+	// prefixed with the namespace, which is the case for all namespaces except for the Supervisor's own namespace.
+	namespacedNames bool
+}
+
+// cacheName returns the name of the provider in the cache, by which it is also known to clients, e.g. when they
+// choose a provider by name.
+func (n *watchedNamespace) cacheName(upstream *v1alpha1.LDAPIdentityProvider) string {
+	if n.namespacedNames {
+		return upstream.Namespace + "/" + upstream.Name
+	}
+	return upstream.Name
+}
+
 type ldapWatcherController struct {
-	cache                        UpstreamLDAPIdentityProviderICache
-	validatedSettingsCache       upstreamwatchers.ValidatedSettingsCacheI
-	ldapDialer                   upstreamldap.LDAPDialer
-	client                       pinnipedclientset.Interface
-	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer
-	secretInformer               corev1informers.SecretInformer
+	cache                         UpstreamLDAPIdentityProviderICache
+	validatedSettingsCache        upstreamwatchers.ValidatedSettingsCacheI
+	testConnectionThrottle        *upstreamwatchers.TestConnectionThrottle
+	providerCache                 *upstreamwatchers.ProviderCache
+	ldapDialer                    upstreamldap.LDAPDialer
+	client                        pinnipedclientset.Interface
+	namespaces                    []watchedNamespace
+	serverCertExpiryWarningWindow time.Duration
+	authenticationCacheTTL        time.Duration
+	skipStatusUpdates             bool
 }
 
 // New instantiates a new controllerlib.Controller which will populate the provided UpstreamLDAPIdentityProviderICache.
+// The LDAPIdentityProviders of the Supervisor's own namespace are watched using ldapIdentityProviderInformer and
+// secretInformer. The LDAPIdentityProviders of each of the additionalNamespaces are added to the same cache, under
+// names of the form "<namespace>/<name>", and their bind Secrets are read from their own namespace.
 func New(
 	idpCache UpstreamLDAPIdentityProviderICache,
 	client pinnipedclientset.Interface,
 	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer,
 	secretInformer corev1informers.SecretInformer,
+	additionalNamespaces []NamespaceInformers,
+	serverCertExpiryWarningWindow time.Duration,
+	authenticationCacheTTL time.Duration,
+	skipStatusUpdates bool,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
 	return newInternal(
 		idpCache,
 		// start with an empty cache
 		upstreamwatchers.NewValidatedSettingsCache(),
+		// start without remembering any previous probes of the servers
+		upstreamwatchers.NewTestConnectionThrottle(clock.RealClock{}),
 		// nil means to use a real production dialer when creating objects to add to the cache
 		nil,
 		client,
 		ldapIdentityProviderInformer,
 		secretInformer,
+		additionalNamespaces,
+		serverCertExpiryWarningWindow,
+		authenticationCacheTTL,
+		skipStatusUpdates,
 		withInformer,
 	)
 }
@@ -166,55 +482,89 @@ func New(
 func newInternal(
 	idpCache UpstreamLDAPIdentityProviderICache,
 	validatedSettingsCache upstreamwatchers.ValidatedSettingsCacheI,
+	testConnectionThrottle *upstreamwatchers.TestConnectionThrottle,
 	ldapDialer upstreamldap.LDAPDialer,
 	client pinnipedclientset.Interface,
 	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer,
 	secretInformer corev1informers.SecretInformer,
+	additionalNamespaces []NamespaceInformers,
+	serverCertExpiryWarningWindow time.Duration,
+	authenticationCacheTTL time.Duration,
+	skipStatusUpdates bool,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
+	namespaces := []watchedNamespace{{
+		NamespaceInformers: NamespaceInformers{
+			LDAPIdentityProviderInformer: ldapIdentityProviderInformer,
+			SecretInformer:               secretInformer,
+		},
+	}}
+	for _, informers := range additionalNamespaces {
+		namespaces = append(namespaces, watchedNamespace{NamespaceInformers: informers, namespacedNames: true})
+	}
+
 	c := ldapWatcherController{
-		cache:                        idpCache,
-		validatedSettingsCache:       validatedSettingsCache,
-		ldapDialer:                   ldapDialer,
-		client:                       client,
-		ldapIdentityProviderInformer: ldapIdentityProviderInformer,
-		secretInformer:               secretInformer,
+		cache:                         idpCache,
+		validatedSettingsCache:        validatedSettingsCache,
+		testConnectionThrottle:        testConnectionThrottle,
+		providerCache:                 upstreamwatchers.NewProviderCache(),
+		ldapDialer:                    ldapDialer,
+		client:                        client,
+		namespaces:                    namespaces,
+		serverCertExpiryWarningWindow: serverCertExpiryWarningWindow,
+		authenticationCacheTTL:        authenticationCacheTTL,
+		skipStatusUpdates:             skipStatusUpdates,
+	}
+	opts := make([]controllerlib.Option, 0, 2*len(namespaces))
+	for _, namespace := range namespaces {
+		opts = append(opts,
+			withInformer(
+				namespace.LDAPIdentityProviderInformer,
+				pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+				controllerlib.InformerOption{},
+			),
+			withInformer(
+				namespace.SecretInformer,
+				pinnipedcontroller.MatchAnySecretOfTypeFilter(upstreamwatchers.LDAPBindAccountSecretType, pinnipedcontroller.SingletonQueue()),
+				controllerlib.InformerOption{},
+			),
+		)
 	}
 	return controllerlib.New(
 		controllerlib.Config{Name: ldapControllerName, Syncer: &c},
-		withInformer(
-			ldapIdentityProviderInformer,
-			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
-			controllerlib.InformerOption{},
-		),
-		withInformer(
-			secretInformer,
-			pinnipedcontroller.MatchAnySecretOfTypeFilter(upstreamwatchers.LDAPBindAccountSecretType, pinnipedcontroller.SingletonQueue()),
-			controllerlib.InformerOption{},
-		),
+		opts...,
 	)
 }
 
 // Sync implements controllerlib.Syncer.
 func (c *ldapWatcherController) Sync(ctx controllerlib.Context) error {
-	actualUpstreams, err := c.ldapIdentityProviderInformer.Lister().List(labels.Everything())
-	if err != nil {
-		return fmt.Errorf("failed to list LDAPIdentityProviders: %w", err)
-	}
-
 	requeue := false
-	validatedUpstreams := make([]provider.UpstreamLDAPIdentityProviderI, 0, len(actualUpstreams))
-	for _, upstream := range actualUpstreams {
-		valid, requestedRequeue := c.validateUpstream(ctx.Context, upstream)
-		if valid != nil {
-			validatedUpstreams = append(validatedUpstreams, valid)
+	validatedUpstreams := make([]provider.UpstreamLDAPIdentityProviderI, 0)
+	for i := range c.namespaces {
+		namespace := &c.namespaces[i]
+		actualUpstreams, err := namespace.LDAPIdentityProviderInformer.Lister().List(labels.Everything())
+		if err != nil {
+			return fmt.Errorf("failed to list LDAPIdentityProviders: %w", err)
 		}
-		if requestedRequeue {
-			requeue = true
+
+		for _, upstream := range actualUpstreams {
+			valid, requestedRequeue := c.validateUpstream(ctx.Context, namespace, upstream)
+			if valid != nil {
+				validatedUpstreams = append(validatedUpstreams, valid)
+			}
+			if requestedRequeue {
+				requeue = true
+			}
 		}
 	}
 
 	c.cache.SetLDAPIdentityProviders(validatedUpstreams)
+	// Keep the Providers of the upstreams which are still in the cache for the next sync, closing the others.
+	cachedNames := sets.NewString()
+	for _, validatedUpstream := range validatedUpstreams {
+		cachedNames.Insert(validatedUpstream.GetName())
+	}
+	c.providerCache.DeleteAllExcept(cachedNames)
 
 	if requeue {
 		return controllerlib.ErrSyntheticRequeue
@@ -222,39 +572,306 @@ func (c *ldapWatcherController) Sync(ctx controllerlib.Context) error {
 	return nil
 }
 
-func (c *ldapWatcherController) validateUpstream(ctx context.Context, upstream *v1alpha1.LDAPIdentityProvider) (p provider.UpstreamLDAPIdentityProviderI, requeue bool) {
+func (c *ldapWatcherController) validateUpstream(ctx context.Context, namespace *watchedNamespace, upstream *v1alpha1.LDAPIdentityProvider) (p provider.UpstreamLDAPIdentityProviderI, requeue bool) {
 	spec := upstream.Spec
 
 	config := &upstreamldap.ProviderConfig{
-		Name:        upstream.Name,
+		Name:        namespace.cacheName(upstream),
 		ResourceUID: upstream.UID,
 		Host:        spec.Host,
+		ProxyURL:    spec.ProxyURL,
 		UserSearch: upstreamldap.UserSearchConfig{
-			Base:              spec.UserSearch.Base,
-			Filter:            spec.UserSearch.Filter,
-			UsernameAttribute: spec.UserSearch.Attributes.Username,
-			UIDAttribute:      spec.UserSearch.Attributes.UID,
+			Base:                              spec.UserSearch.Base,
+			Filter:                            spec.UserSearch.Filter,
+			UsernameAttribute:                 spec.UserSearch.Attributes.Username,
+			UsernameAttributeMultiValuePolicy: upstreamldap.MultiValuePolicy(spec.UserSearch.Attributes.UsernameMultiValuePolicy),
+			UIDAttribute:                      spec.UserSearch.Attributes.UID,
+			UIDAttributeEncoding:              upstreamldap.UIDEncoding(spec.UserSearch.Attributes.UIDEncoding),
+			UsernameCaseInsensitive:           spec.UserSearch.UsernameCaseSensitive != nil && !*spec.UserSearch.UsernameCaseSensitive,
+			UsernameNormalization: upstreamldap.UsernameNormalizationConfig{
+				TrimWhitespace:     spec.UserSearch.UsernameNormalization.TrimWhitespace,
+				StripNetBIOSPrefix: spec.UserSearch.UsernameNormalization.StripNetBIOSPrefix,
+				StripDomainSuffix:  spec.UserSearch.UsernameNormalization.StripDomainSuffix,
+			},
+			UserDNTemplate:       spec.UserSearch.UserDNTemplate,
+			AllowMultipleMatches: spec.UserSearch.AllowMultipleMatches,
+			Scope:                upstreamldap.SearchScope(spec.UserSearch.Scope),
+			ExtraAttributes:      spec.UserSearch.Attributes.Extra,
 		},
 		GroupSearch: upstreamldap.GroupSearchConfig{
-			Base:               spec.GroupSearch.Base,
-			Filter:             spec.GroupSearch.Filter,
-			GroupNameAttribute: spec.GroupSearch.Attributes.GroupName,
-			SkipGroupRefresh:   spec.GroupSearch.SkipGroupRefresh,
+			Mode:                        upstreamldap.GroupSearchMode(spec.GroupSearch.Mode),
+			UserAttributeForGroups:      spec.GroupSearch.UserAttributeForGroups,
+			Base:                        spec.GroupSearch.Base,
+			Filter:                      spec.GroupSearch.Filter,
+			GroupNameAttribute:          spec.GroupSearch.Attributes.GroupName,
+			UseDNWhenGroupNameIsMissing: spec.GroupSearch.Attributes.UseDNWhenGroupNameIsMissing,
+			PageSize:                    uint32(spec.GroupSearch.PageSize),
+			Scope:                       upstreamldap.SearchScope(spec.GroupSearch.Scope),
+			NestedGroupsMaxDepth:        upstreamwatchers.NestedGroupsMaxDepth(spec.GroupSearch.ResolveNestedGroups, spec.GroupSearch.NestedGroupsMaxDepth),
+			AllowedGroups:               spec.GroupSearch.AllowedGroups,
+			SkipGroupRefresh:            spec.GroupSearch.SkipGroupRefresh,
+			FailurePolicy:               upstreamldap.GroupSearchFailurePolicy(spec.GroupSearchFailurePolicy),
 		},
-		Dialer: c.ldapDialer,
+		SearchSizeLimit:     int(spec.SearchSizeLimit),
+		SearchTimeLimit:     time.Duration(spec.SearchTimeLimit) * time.Second,
+		Dialer:              c.ldapDialer,
+		ConnectionPool:      upstreamwatchers.LDAPConnectionPoolConfig(),
+		AuthenticationCache: upstreamwatchers.LDAPAuthenticationCacheConfig(c.authenticationCacheTTL),
+	}
+	if spec.ConnectionTimeout != nil && validConnectionTimeout(spec.ConnectionTimeout.Duration) {
+		// An invalid timeout is reported by the SearchConfigurationValid condition, so just use the default here.
+		config.ConnectionTimeout = spec.ConnectionTimeout.Duration
 	}
 
-	conditions := upstreamwatchers.ValidateGenericLDAP(ctx, &ldapUpstreamGenericLDAPImpl{*upstream}, c.secretInformer, c.validatedSettingsCache, config)
+	conditions, validatedConnection := upstreamwatchers.ValidateGenericLDAP(ctx, &ldapUpstreamGenericLDAPImpl{*upstream}, namespace.SecretInformer, c.validatedSettingsCache, c.testConnectionThrottle, config, c.serverCertExpiryWarningWindow)
+	conditions.Append(validateSearchConfiguration(&spec), true)
 
-	c.updateStatus(ctx, upstream, conditions.Conditions())
+	c.updateStatus(ctx, upstream, conditions.Conditions(), validatedConnection)
 
-	return upstreamwatchers.EvaluateConditions(conditions, config)
+	return upstreamwatchers.EvaluateConditions(conditions, config, c.providerCache)
 }
 
-func (c *ldapWatcherController) updateStatus(ctx context.Context, upstream *v1alpha1.LDAPIdentityProvider, conditions []*v1alpha1.Condition) {
+func validConnectionTimeout(timeout time.Duration) bool {
+	return timeout > 0 && timeout <= maxConnectionTimeout
+}
+
+func validateSearchConfiguration(spec *v1alpha1.LDAPIdentityProviderSpec) *v1alpha1.Condition {
+	if spec.ConnectionTimeout != nil && !validConnectionTimeout(spec.ConnectionTimeout.Duration) {
+		return &v1alpha1.Condition{
+			Type:    typeSearchConfigurationValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonInvalidConnectionTimeout,
+			Message: fmt.Sprintf(`connectionTimeout %q must be greater than 0s and at most %s`, spec.ConnectionTimeout.Duration, maxConnectionTimeout),
+		}
+	}
+
+	// An empty group search base is allowed, and means that group search should be skipped.
+	if groupSearchBase := spec.GroupSearch.Base; len(groupSearchBase) > 0 {
+		if _, err := ldap.ParseDN(groupSearchBase); err != nil {
+			return &v1alpha1.Condition{
+				Type:    typeSearchConfigurationValid,
+				Status:  v1alpha1.ConditionFalse,
+				Reason:  reasonInvalidGroupSearchBase,
+				Message: fmt.Sprintf(`groupSearch.base %q is not a valid distinguished name: %s`, groupSearchBase, err.Error()),
+			}
+		}
+	}
+
+	for i, allowedGroup := range spec.GroupSearch.AllowedGroups {
+		if _, err := ldap.ParseDN(allowedGroup); err != nil || len(allowedGroup) == 0 {
+			message := fmt.Sprintf(`groupSearch.allowedGroups[%d] %q is not a valid distinguished name`, i, allowedGroup)
+			if err != nil {
+				message += ": " + err.Error()
+			}
+			return &v1alpha1.Condition{
+				Type:    typeSearchConfigurationValid,
+				Status:  v1alpha1.ConditionFalse,
+				Reason:  reasonInvalidAllowedGroups,
+				Message: message,
+			}
+		}
+	}
+
+	switch mode := spec.GroupSearch.Mode; mode {
+	case "", v1alpha1.LDAPGroupSearchModeFilter, v1alpha1.LDAPGroupSearchModeUserAttribute:
+	default:
+		return &v1alpha1.Condition{
+			Type:   typeSearchConfigurationValid,
+			Status: v1alpha1.ConditionFalse,
+			Reason: reasonInvalidGroupSearchMode,
+			Message: fmt.Sprintf(`groupSearch.mode %q is not valid, must be one of %q`,
+				mode, []v1alpha1.LDAPGroupSearchMode{v1alpha1.LDAPGroupSearchModeFilter, v1alpha1.LDAPGroupSearchModeUserAttribute}),
+		}
+	}
+
+	// An empty attribute is allowed, and means that the default "memberOf" attribute should be used.
+	if attribute := spec.GroupSearch.UserAttributeForGroups; len(attribute) > 0 && !attributeNameRegexp.MatchString(attribute) {
+		return &v1alpha1.Condition{
+			Type:    typeSearchConfigurationValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonInvalidUserAttribute,
+			Message: fmt.Sprintf(`groupSearch.userAttributeForGroups %q is not a valid LDAP attribute name`, attribute),
+		}
+	}
+
+	// An empty group name attribute is allowed, and means that the group's DN should be used.
+	if groupName := spec.GroupSearch.Attributes.GroupName; len(groupName) > 0 && !attributeNameRegexp.MatchString(groupName) {
+		return &v1alpha1.Condition{
+			Type:    typeSearchConfigurationValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonInvalidGroupNameAttribute,
+			Message: fmt.Sprintf(`groupSearch.attributes.groupName %q is not a valid LDAP attribute name`, groupName),
+		}
+	}
+
+	switch uidEncoding := spec.UserSearch.Attributes.UIDEncoding; uidEncoding {
+	case "", v1alpha1.LDAPUIDEncodingBase64URL, v1alpha1.LDAPUIDEncodingHex:
+	default:
+		return &v1alpha1.Condition{
+			Type:   typeSearchConfigurationValid,
+			Status: v1alpha1.ConditionFalse,
+			Reason: reasonInvalidUIDEncoding,
+			Message: fmt.Sprintf(`userSearch.attributes.uidEncoding %q is not valid, must be one of %q`,
+				uidEncoding, []v1alpha1.LDAPUIDEncoding{v1alpha1.LDAPUIDEncodingBase64URL, v1alpha1.LDAPUIDEncodingHex}),
+		}
+	}
+
+	switch policy := spec.UserSearch.Attributes.UsernameMultiValuePolicy; policy {
+	case "", v1alpha1.LDAPMultiValuePolicyError, v1alpha1.LDAPMultiValuePolicyFirst, v1alpha1.LDAPMultiValuePolicyLast:
+	default:
+		return &v1alpha1.Condition{
+			Type:   typeSearchConfigurationValid,
+			Status: v1alpha1.ConditionFalse,
+			Reason: reasonInvalidMultiValuePolicy,
+			Message: fmt.Sprintf(`userSearch.attributes.usernameMultiValuePolicy %q is not valid, must be one of %q`,
+				policy, []v1alpha1.LDAPMultiValuePolicy{v1alpha1.LDAPMultiValuePolicyError, v1alpha1.LDAPMultiValuePolicyFirst, v1alpha1.LDAPMultiValuePolicyLast}),
+		}
+	}
+
+	if suffix := spec.UserSearch.UsernameNormalization.StripDomainSuffix; strings.ContainsAny(suffix, "@ \t\r\n") {
+		return &v1alpha1.Condition{
+			Type:    typeSearchConfigurationValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonInvalidUsernameNormalization,
+			Message: fmt.Sprintf(`userSearch.usernameNormalization.stripDomainSuffix %q must not contain "@" or whitespace`, suffix),
+		}
+	}
+
+	if condition := validateExtraAttributes(spec.UserSearch.Attributes.Extra); condition != nil {
+		return condition
+	}
+
+	if condition := validateSearchScope("userSearch.scope", spec.UserSearch.Scope); condition != nil {
+		return condition
+	}
+	if condition := validateSearchScope("groupSearch.scope", spec.GroupSearch.Scope); condition != nil {
+		return condition
+	}
+	scopes := fmt.Sprintf(`userSearch.scope %q, groupSearch.scope %q`,
+		searchScopeOrDefault(spec.UserSearch.Scope), searchScopeOrDefault(spec.GroupSearch.Scope))
+
+	if userDNTemplate := spec.UserSearch.UserDNTemplate; len(userDNTemplate) > 0 {
+		if !strings.Contains(userDNTemplate, "{}") {
+			return &v1alpha1.Condition{
+				Type:    typeSearchConfigurationValid,
+				Status:  v1alpha1.ConditionFalse,
+				Reason:  reasonInvalidUserDNTemplate,
+				Message: fmt.Sprintf(`userSearch.userDNTemplate %q must contain the "{}" placeholder`, userDNTemplate),
+			}
+		}
+		// Any username is escaped before it replaces the placeholder, so a template which forms a valid DN using
+		// an example username forms a valid DN for every username.
+		if _, err := ldap.ParseDN(strings.ReplaceAll(userDNTemplate, "{}", "username")); err != nil {
+			return &v1alpha1.Condition{
+				Type:    typeSearchConfigurationValid,
+				Status:  v1alpha1.ConditionFalse,
+				Reason:  reasonInvalidUserDNTemplate,
+				Message: fmt.Sprintf(`userSearch.userDNTemplate %q does not form a valid distinguished name: %s`, userDNTemplate, err.Error()),
+			}
+		}
+		if len(spec.UserSearch.Filter) > 0 {
+			return &v1alpha1.Condition{
+				Type:    typeSearchConfigurationValid,
+				Status:  v1alpha1.ConditionTrue,
+				Reason:  upstreamwatchers.ReasonSuccess,
+				Message: fmt.Sprintf("search configuration is valid (%s), but userSearch.filter is ignored because userSearch.userDNTemplate is specified", scopes),
+			}
+		}
+	} else if condition := validateUserSearchFilter(spec.UserSearch.Filter); condition != nil {
+		return condition
+	}
+
+	return &v1alpha1.Condition{
+		Type:    typeSearchConfigurationValid,
+		Status:  v1alpha1.ConditionTrue,
+		Reason:  upstreamwatchers.ReasonSuccess,
+		Message: fmt.Sprintf("search configuration is valid (%s)", scopes),
+	}
+}
+
+// validateUserSearchFilter rejects user search filters which would not select the user by their username, since
+// the first entry found by such a filter could be authenticated as any user. An empty filter is allowed, and means
+// that the default filter using the username attribute will be used.
+func validateUserSearchFilter(filter string) *v1alpha1.Condition {
+	var message string
+	switch {
+	case len(filter) == 0:
+		return nil
+	case !strings.Contains(filter, "{}"):
+		message = fmt.Sprintf(`userSearch.filter %q must contain the "{}" placeholder, or else it finds the same entries for every username`, filter)
+	case strings.Contains(filter, "*{}") || strings.Contains(filter, "{}*"):
+		message = fmt.Sprintf(`userSearch.filter %q must not use the "{}" placeholder in a wildcard match, or else it finds entries for other usernames`, filter)
+	default:
+		return nil
+	}
+	return &v1alpha1.Condition{
+		Type:    typeSearchConfigurationValid,
+		Status:  v1alpha1.ConditionFalse,
+		Reason:  reasonUserSearchFilterInsecure,
+		Message: message,
+	}
+}
+
+func validateExtraAttributes(extra map[string]string) *v1alpha1.Condition {
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // report the same problem on every sync
+	for _, key := range keys {
+		if len(key) == 0 {
+			return &v1alpha1.Condition{
+				Type:    typeSearchConfigurationValid,
+				Status:  v1alpha1.ConditionFalse,
+				Reason:  reasonInvalidExtraAttributes,
+				Message: `userSearch.attributes.extra must not contain an empty key`,
+			}
+		}
+		if attributeName := extra[key]; !attributeNameRegexp.MatchString(attributeName) {
+			return &v1alpha1.Condition{
+				Type:    typeSearchConfigurationValid,
+				Status:  v1alpha1.ConditionFalse,
+				Reason:  reasonInvalidExtraAttributes,
+				Message: fmt.Sprintf(`userSearch.attributes.extra[%q] %q is not a valid LDAP attribute name`, key, attributeName),
+			}
+		}
+	}
+	return nil
+}
+
+func validateSearchScope(fieldName string, scope v1alpha1.LDAPSearchScope) *v1alpha1.Condition {
+	switch scope {
+	case "", v1alpha1.LDAPSearchScopeBase, v1alpha1.LDAPSearchScopeOne, v1alpha1.LDAPSearchScopeSub:
+		return nil
+	default:
+		return &v1alpha1.Condition{
+			Type:   typeSearchConfigurationValid,
+			Status: v1alpha1.ConditionFalse,
+			Reason: reasonInvalidSearchScope,
+			Message: fmt.Sprintf(`%s %q is not valid, must be one of %q`,
+				fieldName, scope, []v1alpha1.LDAPSearchScope{v1alpha1.LDAPSearchScopeBase, v1alpha1.LDAPSearchScopeOne, v1alpha1.LDAPSearchScopeSub}),
+		}
+	}
+}
+
+// searchScopeOrDefault returns the scope which will be used for a search, which is "sub" when not specified.
+func searchScopeOrDefault(scope v1alpha1.LDAPSearchScope) v1alpha1.LDAPSearchScope {
+	if scope == "" {
+		return v1alpha1.LDAPSearchScopeSub
+	}
+	return scope
+}
+
+func (c *ldapWatcherController) updateStatus(
+	ctx context.Context,
+	upstream *v1alpha1.LDAPIdentityProvider,
+	conditions []*v1alpha1.Condition,
+	validatedConnection *upstreamwatchers.ValidatedConnection,
+) {
 	log := plog.WithValues("namespace", upstream.Namespace, "name", upstream.Name)
 	updated := upstream.DeepCopy()
 
+	upstreamwatchers.RemoveStaleInformationalConditions(conditions, &updated.Status.Conditions)
 	hadErrorCondition := conditionsutil.MergeIDPConditions(conditions, upstream.Generation, &updated.Status.Conditions, log)
 
 	updated.Status.Phase = v1alpha1.LDAPPhaseReady
@@ -262,10 +879,28 @@ func (c *ldapWatcherController) updateStatus(ctx context.Context, upstream *v1al
 		updated.Status.Phase = v1alpha1.LDAPPhaseError
 	}
 
+	// Describe the validated connection in a machine-readable form too, so that tools do not need to parse
+	// the message of the LDAPConnectionValid condition.
+	updated.Status.Validation = nil
+	if validatedConnection != nil {
+		updated.Status.Validation = &v1alpha1.LDAPIdentityProviderValidation{
+			ObservedGeneration:    validatedConnection.IDPSpecGeneration,
+			ObservedSecretVersion: validatedConnection.BindSecretResourceVersion,
+			ConnectionHost:        validatedConnection.Host,
+			ResolvedBindDN:        validatedConnection.BindUsername,
+		}
+	}
+
 	if equality.Semantic.DeepEqual(upstream, updated) {
 		return // nothing to update
 	}
 
+	if c.skipStatusUpdates {
+		log.Debug("skipping status update because status updates are disabled",
+			"phase", updated.Status.Phase, "conditions", updated.Status.Conditions)
+		return
+	}
+
 	_, err := c.client.
 		IDPV1alpha1().
 		LDAPIdentityProviders(upstream.Namespace).
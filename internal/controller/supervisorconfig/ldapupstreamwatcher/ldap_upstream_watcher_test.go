@@ -5,7 +5,10 @@ package ldapupstreamwatcher
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"sort"
@@ -20,6 +23,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/pointer"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
 	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
@@ -81,7 +86,7 @@ func TestLDAPUpstreamWatcherControllerFilterSecrets(t *testing.T) {
 			secretInformer := kubeInformers.Core().V1().Secrets()
 			withInformer := testutil.NewObservableWithInformerOption()
 
-			New(nil, nil, ldapIDPInformer, secretInformer, withInformer.WithInformer)
+			New(nil, nil, ldapIDPInformer, secretInformer, nil, 0, 0, false, withInformer.WithInformer)
 
 			unrelated := corev1.Secret{}
 			filter := withInformer.GetFilterForInformer(secretInformer)
@@ -126,7 +131,7 @@ func TestLDAPUpstreamWatcherControllerFilterLDAPIdentityProviders(t *testing.T)
 			secretInformer := kubeInformers.Core().V1().Secrets()
 			withInformer := testutil.NewObservableWithInformerOption()
 
-			New(nil, nil, ldapIDPInformer, secretInformer, withInformer.WithInformer)
+			New(nil, nil, ldapIDPInformer, secretInformer, nil, 0, 0, false, withInformer.WithInformer)
 
 			unrelated := corev1.Secret{}
 			filter := withInformer.GetFilterForInformer(ldapIDPInformer)
@@ -149,21 +154,36 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 
 	const (
 		testNamespace         = "test-namespace"
+		testTenantNamespace   = "test-tenant-namespace"
 		testName              = "test-name"
 		testResourceUID       = "test-resource-uid"
 		testSecretName        = "test-bind-secret"
 		testBindUsername      = "test-bind-username"
 		testBindPassword      = "test-bind-password"
+		testBindDN            = "cn=test-bind-username,ou=service-accounts,dc=example,dc=com"
 		testHost              = "ldap.example.com:123"
 		testUserSearchBase    = "test-user-search-base"
-		testUserSearchFilter  = "test-user-search-filter"
-		testGroupSearchBase   = "test-group-search-base"
-		testGroupSearchFilter = "test-group-search-filter"
+		testUserSearchFilter  = "(test-user-search-filter={})"
+		testGroupSearchBase   = "ou=groups,dc=pinniped,dc=dev"
+		testGroupSearchFilter = "test-group-search-filter=*"
 		testUsernameAttrName  = "test-username-attr"
 		testGroupNameAttrName = "test-group-name-attr"
 		testUIDAttrName       = "test-uid-attr"
+		testGroupName         = "test-group-name"
+
+		defaultGroupSearchFailurePolicyNote = `; logins will fail when the group search fails because groupSearchFailurePolicy defaults to "FailClosed"`
+
+		testDryRunUsername = "some-dry-run-user"
+		testDryRunUserDN   = "cn=some-dry-run-user," + testUserSearchBase
+
+		groupSearchValidatedWithoutUserMessage = `groupSearch.base and groupSearch.filter are valid and searchable, but no user's groups ` +
+			`were searched because userSearch.dryRunUsername is not configured` + defaultGroupSearchFailurePolicyNote
 	)
 
+	testBindCredentialsFingerprint := upstreamwatchers.BindCredentialsFingerprint(testBindUsername, testBindPassword)
+	testBindDNCredentialsFingerprint := upstreamwatchers.BindCredentialsFingerprint(testBindDN, testBindPassword)
+	testAnonymousBindCredentialsFingerprint := upstreamwatchers.BindCredentialsFingerprint("", "")
+
 	testValidSecretData := map[string][]byte{"username": []byte(testBindUsername), "password": []byte(testBindPassword)}
 
 	testCA, err := certauthority.New("test CA", time.Minute)
@@ -171,6 +191,10 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 	testCABundle := testCA.Bundle()
 	testCABundleBase64Encoded := base64.StdEncoding.EncodeToString(testCABundle)
 
+	const testServerCertExpiryWarningWindow = 30 * 24 * time.Hour
+	const testAuthenticationCacheTTL = 10 * time.Second
+	expiringServerCertNotAfter := time.Now().UTC().Add(testServerCertExpiryWarningWindow / 2).Truncate(time.Second)
+
 	validUpstream := &v1alpha1.LDAPIdentityProvider{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:       testName,
@@ -232,13 +256,19 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 	providerConfigForValidUpstreamWithStartTLS := &copyOfProviderConfigForValidUpstreamWithTLS
 	providerConfigForValidUpstreamWithStartTLS.ConnectionProtocol = upstreamldap.StartTLS
 
+	copyOfProviderConfigForValidUpstreamWithAnonymousBind := *providerConfigForValidUpstreamWithTLS
+	providerConfigForValidUpstreamWithAnonymousBind := &copyOfProviderConfigForValidUpstreamWithAnonymousBind
+	providerConfigForValidUpstreamWithAnonymousBind.BindUsername = ""
+	providerConfigForValidUpstreamWithAnonymousBind.BindPassword = ""
+	providerConfigForValidUpstreamWithAnonymousBind.AnonymousBind = true
+
 	bindSecretValidTrueCondition := func(gen int64) v1alpha1.Condition {
 		return v1alpha1.Condition{
 			Type:               "BindSecretValid",
 			Status:             "True",
 			LastTransitionTime: now,
 			Reason:             "Success",
-			Message:            "loaded bind secret",
+			Message:            `loaded bind secret of type "kubernetes.io/basic-auth"`,
 			ObservedGeneration: gen,
 		}
 	}
@@ -272,11 +302,263 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 			ObservedGeneration: gen,
 		}
 	}
+	searchConfigurationValidTrueCondition := func(gen int64) v1alpha1.Condition {
+		return v1alpha1.Condition{
+			Type:               "SearchConfigurationValid",
+			Status:             "True",
+			LastTransitionTime: now,
+			Reason:             "Success",
+			Message:            `search configuration is valid (userSearch.scope "sub", groupSearch.scope "sub")`,
+			ObservedGeneration: gen,
+		}
+	}
+	groupSearchValidTrueCondition := func(gen int64) v1alpha1.Condition {
+		return v1alpha1.Condition{
+			Type:               "GroupSearchValid",
+			Status:             "True",
+			LastTransitionTime: now,
+			Reason:             "Success",
+			Message:            groupSearchValidatedWithoutUserMessage,
+			ObservedGeneration: gen,
+		}
+	}
+	groupSearchValidTrueConditionWithoutTimeOrGeneration := func() v1alpha1.Condition {
+		c := groupSearchValidTrueCondition(0)
+		c.LastTransitionTime = metav1.Time{}
+		return c
+	}
+	userSearchBaseValidTrueCondition := func(gen int64) v1alpha1.Condition {
+		return v1alpha1.Condition{
+			Type:               "UserSearchBaseValid",
+			Status:             "True",
+			LastTransitionTime: now,
+			Reason:             "Success",
+			Message:            fmt.Sprintf(`userSearch.base "%s" was found`, testUserSearchBase),
+			ObservedGeneration: gen,
+		}
+	}
+	userSearchBaseValidTrueConditionWithoutTimeOrGeneration := func() v1alpha1.Condition {
+		c := userSearchBaseValidTrueCondition(0)
+		c.LastTransitionTime = metav1.Time{}
+		return c
+	}
 	allConditionsTrue := func(gen int64, secretVersion string) []v1alpha1.Condition {
 		return []v1alpha1.Condition{
 			bindSecretValidTrueCondition(gen),
+			groupSearchValidTrueCondition(gen),
 			ldapConnectionValidTrueCondition(gen, secretVersion),
+			searchConfigurationValidTrueCondition(gen),
+			tlsConfigurationValidLoadedTrueCondition(gen),
+			userSearchBaseValidTrueCondition(gen),
+		}
+	}
+
+	validationStatus := func(gen int64, secretVersion string) *v1alpha1.LDAPIdentityProviderValidation {
+		return &v1alpha1.LDAPIdentityProviderValidation{
+			ObservedGeneration:    gen,
+			ObservedSecretVersion: secretVersion,
+			ConnectionHost:        testHost,
+			ResolvedBindDN:        testBindUsername,
+		}
+	}
+
+	// The search for the user search base which is performed as the bind user to validate the user search base.
+	expectUserSearchBaseValidationAs := func(conn *mockldapconn.MockConn, bindUsername string) {
+		conn.EXPECT().Bind(bindUsername, testBindPassword).Times(1)
+		conn.EXPECT().Search(&ldap.SearchRequest{
+			BaseDN:       testUserSearchBase,
+			Scope:        ldap.ScopeBaseObject,
+			DerefAliases: ldap.NeverDerefAliases,
+			SizeLimit:    1,
+			TimeLimit:    90,
+			TypesOnly:    true,
+			Filter:       "(objectClass=*)",
+			Attributes:   []string{"objectClass"},
+		}).Return(&ldap.SearchResult{Entries: []*ldap.Entry{{DN: testUserSearchBase}}}, nil).Times(1)
+		conn.EXPECT().Close().Times(1)
+	}
+	expectUserSearchBaseValidation := func(conn *mockldapconn.MockConn) {
+		expectUserSearchBaseValidationAs(conn, testBindUsername)
+	}
+
+	// The read of the group search base which is performed as the bind user before the group search dry run.
+	expectGroupSearchBaseRead := func(conn *mockldapconn.MockConn) {
+		conn.EXPECT().Search(&ldap.SearchRequest{
+			BaseDN:       testGroupSearchBase,
+			Scope:        ldap.ScopeBaseObject,
+			DerefAliases: ldap.NeverDerefAliases,
+			SizeLimit:    1,
+			TimeLimit:    90,
+			TypesOnly:    true,
+			Filter:       "(objectClass=*)",
+			Attributes:   []string{"objectClass"},
+		}).Return(&ldap.SearchResult{Entries: []*ldap.Entry{{DN: testGroupSearchBase}}}, nil).Times(1)
+	}
+
+	// The group search which is performed as the bind user to validate the group search settings.
+	expectGroupSearchDryRunAs := func(conn *mockldapconn.MockConn, bindUsername string) {
+		conn.EXPECT().Bind(bindUsername, testBindPassword).Times(1)
+		expectGroupSearchBaseRead(conn)
+		conn.EXPECT().SearchWithPaging(&ldap.SearchRequest{
+			BaseDN:       testGroupSearchBase,
+			Scope:        ldap.ScopeWholeSubtree,
+			DerefAliases: ldap.NeverDerefAliases,
+			TimeLimit:    90,
+			Filter:       "(" + testGroupSearchFilter + ")",
+			Attributes:   []string{testGroupNameAttrName},
+		}, uint32(1000)).Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{{
+				DN:         "cn=" + testGroupName + "," + testGroupSearchBase,
+				Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupNameAttrName, []string{testGroupName})},
+			}},
+		}, nil).Times(1)
+		conn.EXPECT().Close().Times(1)
+	}
+	expectGroupSearchDryRun := func(conn *mockldapconn.MockConn) {
+		expectGroupSearchDryRunAs(conn, testBindUsername)
+	}
+
+	// The search for the dry run user which is performed as the bind user, both to find the DN of the dry run user
+	// for the group search dry run and to dry run the user search.
+	expectDryRunUserSearch := func(conn *mockldapconn.MockConn, extraAttributes ...*ldap.EntryAttribute) {
+		extraRequestedAttributes := []string{}
+		for _, attribute := range extraAttributes {
+			extraRequestedAttributes = append(extraRequestedAttributes, attribute.Name)
+		}
+		conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+		conn.EXPECT().Search(&ldap.SearchRequest{
+			BaseDN:       testUserSearchBase,
+			Scope:        ldap.ScopeWholeSubtree,
+			DerefAliases: ldap.NeverDerefAliases,
+			SizeLimit:    2,
+			TimeLimit:    90,
+			Filter:       "(test-user-search-filter=" + testDryRunUsername + ")",
+			Attributes:   append([]string{testUsernameAttrName, testUIDAttrName}, extraRequestedAttributes...),
+		}).Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{{
+				DN: testDryRunUserDN,
+				Attributes: append([]*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testUsernameAttrName, []string{testDryRunUsername}),
+					ldap.NewEntryAttribute(testUIDAttrName, []string{"some-dry-run-uid"}),
+				}, extraAttributes...),
+			}},
+		}, nil).Times(1)
+		conn.EXPECT().Close().Times(1)
+	}
+	groupSearchValidForDryRunUserTrueCondition := func(gen int64) v1alpha1.Condition {
+		return v1alpha1.Condition{
+			Type:               "GroupSearchValid",
+			Status:             "True",
+			LastTransitionTime: now,
+			Reason:             "Success",
+			Message:            fmt.Sprintf(`group search dry run for user "%s" found groups ["%s"]`+defaultGroupSearchFailurePolicyNote, testDryRunUsername, testGroupName),
+			ObservedGeneration: gen,
+		}
+	}
+	groupSearchValidForDryRunUserTrueConditionWithoutTimeOrGeneration := func() v1alpha1.Condition {
+		c := groupSearchValidForDryRunUserTrueCondition(0)
+		c.LastTransitionTime = metav1.Time{}
+		return c
+	}
+	userSearchValidTrueCondition := func(gen int64, groupCount int) v1alpha1.Condition {
+		return v1alpha1.Condition{
+			Type:               "UserSearchValid",
+			Status:             "True",
+			LastTransitionTime: now,
+			Reason:             "Success",
+			Message: fmt.Sprintf(`user search dry run for username "%s" found user "%s" with %d groups`,
+				testDryRunUsername, testDryRunUsername, groupCount),
+			ObservedGeneration: gen,
+		}
+	}
+	userSearchValidTrueConditionWithoutTimeOrGeneration := func(groupCount int) v1alpha1.Condition {
+		c := userSearchValidTrueCondition(0, groupCount)
+		c.LastTransitionTime = metav1.Time{}
+		return c
+	}
+	// The group search which is performed as part of the user search dry run, on the same connection.
+	expectGroupSearchForDryRunUser := func(conn *mockldapconn.MockConn) {
+		conn.EXPECT().SearchWithPaging(&ldap.SearchRequest{
+			BaseDN:       testGroupSearchBase,
+			Scope:        ldap.ScopeWholeSubtree,
+			DerefAliases: ldap.NeverDerefAliases,
+			TimeLimit:    90,
+			Filter:       "(" + testGroupSearchFilter + ")",
+			Attributes:   []string{testGroupNameAttrName},
+		}, uint32(1000)).Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{{
+				DN:         "cn=" + testGroupName + "," + testGroupSearchBase,
+				Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupNameAttrName, []string{testGroupName})},
+			}},
+		}, nil).Times(1)
+	}
+
+	// The anonymous bind and search which is performed to test the connection when using anonymous bind.
+	expectAnonymousTestConnection := func(conn *mockldapconn.MockConn) {
+		conn.EXPECT().UnauthenticatedBind("").Times(1)
+		conn.EXPECT().Search(&ldap.SearchRequest{
+			BaseDN:       testUserSearchBase,
+			Scope:        ldap.ScopeBaseObject,
+			DerefAliases: ldap.NeverDerefAliases,
+			SizeLimit:    1,
+			TimeLimit:    90,
+			TypesOnly:    true,
+			Filter:       "(objectClass=*)",
+			Attributes:   []string{"objectClass"},
+		}).Return(&ldap.SearchResult{}, nil).Times(1)
+		conn.EXPECT().Close().Times(1)
+	}
+	// The anonymous bind and search which is performed to validate the user search base when using anonymous bind.
+	expectAnonymousUserSearchBaseValidation := func(conn *mockldapconn.MockConn) {
+		conn.EXPECT().UnauthenticatedBind("").Times(1)
+		conn.EXPECT().Search(&ldap.SearchRequest{
+			BaseDN:       testUserSearchBase,
+			Scope:        ldap.ScopeBaseObject,
+			DerefAliases: ldap.NeverDerefAliases,
+			SizeLimit:    1,
+			TimeLimit:    90,
+			TypesOnly:    true,
+			Filter:       "(objectClass=*)",
+			Attributes:   []string{"objectClass"},
+		}).Return(&ldap.SearchResult{Entries: []*ldap.Entry{{DN: testUserSearchBase}}}, nil).Times(1)
+		conn.EXPECT().Close().Times(1)
+	}
+	// The anonymous bind and group search which is performed to validate the group search when using anonymous bind.
+	expectAnonymousGroupSearchValidation := func(conn *mockldapconn.MockConn) {
+		conn.EXPECT().UnauthenticatedBind("").Times(1)
+		expectGroupSearchBaseRead(conn)
+		conn.EXPECT().SearchWithPaging(gomock.Any(), uint32(1000)).Return(&ldap.SearchResult{}, nil).Times(1)
+		conn.EXPECT().Close().Times(1)
+	}
+	anonymousBindConditions := func(gen int64, bindSecretMessage string) []v1alpha1.Condition {
+		return []v1alpha1.Condition{
+			{
+				Type:               "BindSecretValid",
+				Status:             "True",
+				LastTransitionTime: now,
+				Reason:             "Success",
+				Message:            bindSecretMessage,
+				ObservedGeneration: gen,
+			},
+			{
+				Type:               "GroupSearchValid",
+				Status:             "True",
+				LastTransitionTime: now,
+				Reason:             "Success",
+				Message:            groupSearchValidatedWithoutUserMessage,
+				ObservedGeneration: gen,
+			},
+			{
+				Type:               "LDAPConnectionValid",
+				Status:             "True",
+				LastTransitionTime: now,
+				Reason:             "Success",
+				Message:            fmt.Sprintf(`successfully able to connect to "%s" and search anonymously`, testHost),
+				ObservedGeneration: gen,
+			},
+			searchConfigurationValidTrueCondition(gen),
 			tlsConfigurationValidLoadedTrueCondition(gen),
+			userSearchBaseValidTrueCondition(gen),
 		}
 	}
 
@@ -288,17 +570,29 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 		}
 	}
 
+	tenantUpstream := editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+		upstream.Namespace = testTenantNamespace
+		upstream.UID = "tenant-uid"
+	})
+	tenantBindUserSecret := validBindUserSecret("4343")
+	tenantBindUserSecret.Namespace = testTenantNamespace
+
 	tests := []struct {
 		name                     string
 		initialValidatedSettings map[string]upstreamwatchers.ValidatedSettings
+		additionalNamespaces     []string
 		inputUpstreams           []runtime.Object
 		inputSecrets             []runtime.Object
 		setupMocks               func(conn *mockldapconn.MockConn)
 		dialErrors               map[string]error
+		serverCertNotAfter       time.Time
+		skipStatusUpdates        bool
 		wantErr                  string
 		wantResultingCache       []*upstreamldap.ProviderConfig
 		wantResultingUpstreams   []v1alpha1.LDAPIdentityProvider
 		wantValidatedSettings    map[string]upstreamwatchers.ValidatedSettings
+		// When true, the controller is synced a second time, which should leave the same Providers in the cache.
+		wantSameProvidersAfterSecondSync bool
 	}{
 		{
 			name:               "no LDAPIdentityProvider upstreams clears the cache",
@@ -312,6 +606,8 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				// Should perform a test dial and bind.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
 			},
 			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
@@ -319,25 +615,133 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				Status: v1alpha1.LDAPIdentityProviderStatus{
 					Phase:      "Ready",
 					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
 		},
 		{
-			name:               "missing secret",
-			inputUpstreams:     []runtime.Object{validUpstream},
-			inputSecrets:       []runtime.Object{},
-			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
-			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			name:           "a second sync with an identical spec keeps the same Provider in the cache, along with its pooled connections and cached authentications",
+			inputUpstreams: []runtime.Object{validUpstream},
+			inputSecrets:   []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should validate the settings during the first sync only, since the second sync finds the validated settings.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+			wantSameProvidersAfterSecondSync: true,
+		},
+		{
+			name:                 "valid upstreams in additional namespaces are added to the cache under names which are prefixed with their namespace",
+			additionalNamespaces: []string{testTenantNamespace},
+			inputUpstreams:       []runtime.Object{validUpstream, tenantUpstream},
+			inputSecrets:         []runtime.Object{validBindUserSecret("4242"), tenantBindUserSecret},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind for each upstream.
+				for i := 0; i < 2; i++ {
+					conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+					conn.EXPECT().Close().Times(1)
+					expectUserSearchBaseValidation(conn)
+					expectGroupSearchDryRun(conn)
+				}
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				providerConfigForValidUpstreamWithTLS,
+				func() *upstreamldap.ProviderConfig {
+					config := *providerConfigForValidUpstreamWithTLS
+					config.Name = testTenantNamespace + "/" + testName
+					config.ResourceUID = "tenant-uid"
+					return &config
+				}(),
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+					Status: v1alpha1.LDAPIdentityProviderStatus{
+						Phase:      "Ready",
+						Conditions: allConditionsTrue(1234, "4242"),
+						Validation: validationStatus(1234, "4242"),
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testTenantNamespace, Name: testName, Generation: 1234, UID: "tenant-uid"},
+					Status: v1alpha1.LDAPIdentityProviderStatus{
+						Phase:      "Ready",
+						Conditions: allConditionsTrue(1234, "4343"),
+						Validation: validationStatus(1234, "4343"),
+					},
+				},
+			},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{
+				testName: {
+					BindSecretResourceVersion:    "4242",
+					BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+					LDAPConnectionProtocol:       upstreamldap.TLS,
+					UserSearchBase:               testUserSearchBase,
+					GroupSearchBase:              testGroupSearchBase,
+					ConnectionHost:               testHost,
+					IDPSpecGeneration:            1234,
+					ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+					UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+					GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+				},
+				testTenantNamespace + "/" + testName: {
+					BindSecretResourceVersion:    "4343",
+					BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+					LDAPConnectionProtocol:       upstreamldap.TLS,
+					UserSearchBase:               testUserSearchBase,
+					GroupSearchBase:              testGroupSearchBase,
+					ConnectionHost:               testHost,
+					IDPSpecGeneration:            1234,
+					ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4343")),
+					UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+					GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+				},
+			},
+		},
+		{
+			name:                 "an upstream in an additional namespace cannot use a bind secret from the Supervisor's namespace",
+			additionalNamespaces: []string{testTenantNamespace},
+			inputUpstreams:       []runtime.Object{tenantUpstream},
+			inputSecrets:         []runtime.Object{validBindUserSecret("4242")},
+			wantErr:              controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache:   []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testTenantNamespace, Name: testName, Generation: 1234, UID: "tenant-uid"},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
 					Phase: "Error",
 					Conditions: []v1alpha1.Condition{
@@ -349,74 +753,144 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 							Message:            fmt.Sprintf(`secret "%s" not found`, testSecretName),
 							ObservedGeneration: 1234,
 						},
+						searchConfigurationValidTrueCondition(1234),
 						tlsConfigurationValidLoadedTrueCondition(1234),
 					},
 				},
 			}},
 		},
 		{
-			name:           "secret has wrong type",
-			inputUpstreams: []runtime.Object{validUpstream},
-			inputSecrets: []runtime.Object{&corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{Name: testSecretName, Namespace: testNamespace},
-				Type:       "some-other-type",
-				Data:       testValidSecretData,
-			}},
-			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			name:               "upstreams in namespaces which are not watched are ignored",
+			inputUpstreams:     []runtime.Object{tenantUpstream},
+			inputSecrets:       []runtime.Object{tenantBindUserSecret},
 			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testTenantNamespace, Name: testName, Generation: 1234, UID: "tenant-uid"},
+			}},
+		},
+		{
+			name: "when multiple matches are allowed by the user search then the provider config allows them too",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.AllowMultipleMatches = true
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{func() *upstreamldap.ProviderConfig {
+				config := *providerConfigForValidUpstreamWithTLS
+				config.UserSearch.AllowMultipleMatches = true
+				return &config
+			}()},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
-					Phase: "Error",
-					Conditions: []v1alpha1.Condition{
-						{
-							Type:               "BindSecretValid",
-							Status:             "False",
-							LastTransitionTime: now,
-							Reason:             "SecretWrongType",
-							Message:            fmt.Sprintf(`referenced Secret "%s" has wrong type "some-other-type" (should be "kubernetes.io/basic-auth")`, testSecretName),
-							ObservedGeneration: 1234,
-						},
-						tlsConfigurationValidLoadedTrueCondition(1234),
-					},
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
 		},
 		{
-			name:           "secret is missing key",
-			inputUpstreams: []runtime.Object{validUpstream},
-			inputSecrets: []runtime.Object{&corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{Name: testSecretName, Namespace: testNamespace},
-				Type:       corev1.SecretTypeBasicAuth,
-			}},
-			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
-			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			name: "when the user search dry run finds the dry run user then the UserSearchValid condition is true",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.DryRunUsername = testDryRunUsername
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// Should find the dry run user, and then run the group search for that user's DN.
+				expectDryRunUserSearch(conn)
+				expectGroupSearchDryRun(conn)
+				// Should perform the user search dry run as the bind user, including the search for the user's groups.
+				expectDryRunUserSearch(conn)
+				expectGroupSearchForDryRunUser(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
-					Phase: "Error",
+					Phase: "Ready",
 					Conditions: []v1alpha1.Condition{
-						{
-							Type:               "BindSecretValid",
-							Status:             "False",
-							LastTransitionTime: now,
-							Reason:             "SecretMissingKeys",
-							Message:            fmt.Sprintf(`referenced Secret "%s" is missing required keys ["username" "password"]`, testSecretName),
-							ObservedGeneration: 1234,
-						},
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidForDryRunUserTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
 						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						userSearchValidTrueCondition(1234, 1),
 					},
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidForDryRunUserTrueConditionWithoutTimeOrGeneration()),
+				UserSearchValidCondition:     condPtr(userSearchValidTrueConditionWithoutTimeOrGeneration(1)),
+			}},
 		},
 		{
-			name: "CertificateAuthorityData is not base64 encoded",
+			name: "when the username attribute of the dry run user has more than one value then the UserSearchValid condition is false and the upstream is still added to the cache anyway (treated like a warning) but not the validated settings cache",
 			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Spec.TLS.CertificateAuthorityData = "this-is-not-base64-encoded"
+				upstream.Spec.UserSearch.DryRunUsername = "some-dry-run-user"
 			})},
-			inputSecrets:       []runtime.Object{validBindUserSecret("")},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+				// Should search for the dry run user for the group search dry run, and again for the user search dry run, as the bind user, which finds an entry with two usernames.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(2)
+				conn.EXPECT().Search(&ldap.SearchRequest{
+					BaseDN:       testUserSearchBase,
+					Scope:        ldap.ScopeWholeSubtree,
+					DerefAliases: ldap.NeverDerefAliases,
+					SizeLimit:    2,
+					TimeLimit:    90,
+					Filter:       "(test-user-search-filter=some-dry-run-user)",
+					Attributes:   []string{testUsernameAttrName, testUIDAttrName},
+				}).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{{
+						DN: "cn=some-dry-run-user," + testUserSearchBase,
+						Attributes: []*ldap.EntryAttribute{
+							ldap.NewEntryAttribute(testUsernameAttrName, []string{"some-dry-run-user", "some-other-username"}),
+							ldap.NewEntryAttribute(testUIDAttrName, []string{"some-dry-run-uid"}),
+						},
+					}},
+				}, nil).Times(2)
+				conn.EXPECT().Close().Times(2)
+			},
 			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
-			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
@@ -424,25 +898,65 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 					Conditions: []v1alpha1.Condition{
 						bindSecretValidTrueCondition(1234),
 						{
-							Type:               "TLSConfigurationValid",
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message: `groupSearch.base and groupSearch.filter are valid and searchable, but no user's groups were searched ` +
+								`because the dry run user "some-dry-run-user" could not be found` + defaultGroupSearchFailurePolicyNote,
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						{
+							Type:               "UserSearchValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "InvalidTLSConfig",
-							Message:            "certificateAuthorityData is invalid: illegal base64 data at input byte 4",
+							Reason:             "UserAttributeMultiValued",
+							Message: fmt.Sprintf(`user search dry run for username "some-dry-run-user" failed: found 2 values for attribute "%s" while searching for user "some-dry-run-user", but expected 1 result`,
+								testUsernameAttrName),
 							ObservedGeneration: 1234,
 						},
 					},
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
 		},
 		{
-			name: "CertificateAuthorityData is not valid pem data",
+			name: "when the user search dry run finds more than one entry for the dry run user then the UserSearchValid condition is false and the upstream is still added to the cache anyway (treated like a warning) but not the validated settings cache",
 			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Spec.TLS.CertificateAuthorityData = base64.StdEncoding.EncodeToString([]byte("this is not pem data"))
+				upstream.Spec.UserSearch.DryRunUsername = "some-dry-run-user"
 			})},
-			inputSecrets:       []runtime.Object{validBindUserSecret("")},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+				// Should search for the dry run user for the group search dry run, and again for the user search dry run, as the bind user, which finds two entries.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(2)
+				conn.EXPECT().Search(&ldap.SearchRequest{
+					BaseDN:       testUserSearchBase,
+					Scope:        ldap.ScopeWholeSubtree,
+					DerefAliases: ldap.NeverDerefAliases,
+					SizeLimit:    2,
+					TimeLimit:    90,
+					Filter:       "(test-user-search-filter=some-dry-run-user)",
+					Attributes:   []string{testUsernameAttrName, testUIDAttrName},
+				}).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{DN: "cn=some-dry-run-user,ou=one," + testUserSearchBase},
+						{DN: "cn=some-dry-run-user,ou=two," + testUserSearchBase},
+					},
+				}, nil).Times(2)
+				conn.EXPECT().Close().Times(2)
+			},
 			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
-			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
@@ -450,35 +964,2732 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 					Conditions: []v1alpha1.Condition{
 						bindSecretValidTrueCondition(1234),
 						{
-							Type:               "TLSConfigurationValid",
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message: `groupSearch.base and groupSearch.filter are valid and searchable, but no user's groups were searched ` +
+								`because the dry run user "some-dry-run-user" could not be found` + defaultGroupSearchFailurePolicyNote,
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						{
+							Type:               "UserSearchValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "InvalidTLSConfig",
-							Message:            "certificateAuthorityData is invalid: no certificates found",
+							Reason:             "UserSearchAmbiguous",
+							Message:            `user search dry run for username "some-dry-run-user" failed: searching for user "some-dry-run-user" resulted in 2 search results, but expected 1 result`,
 							ObservedGeneration: 1234,
 						},
 					},
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
 		},
 		{
-			name: "nil TLS configuration is valid",
-			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Spec.TLS = nil
-			})},
-			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			name:              "when status updates are skipped, a valid upstream still updates the cache but its status is not written",
+			inputUpstreams:    []runtime.Object{validUpstream},
+			inputSecrets:      []runtime.Object{validBindUserSecret("4242")},
+			skipStatusUpdates: true,
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should perform a test dial and bind.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
 			},
-			wantResultingCache: []*upstreamldap.ProviderConfig{
-				{
-					Name:               testName,
-					ResourceUID:        testResourceUID,
-					Host:               testHost,
-					ConnectionProtocol: upstreamldap.TLS,
-					CABundle:           nil,
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name:               "one valid upstream whose server certificate expires soon gets an informational condition",
+			inputUpstreams:     []runtime.Object{validUpstream},
+			inputSecrets:       []runtime.Object{validBindUserSecret("4242")},
+			serverCertNotAfter: expiringServerCertNotAfter,
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "LDAPServerCertificateExpiringSoon",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "ServerCertificateExpiringSoon",
+							Message:            "the certificate presented by the LDAP server expires at " + expiringServerCertNotAfter.Format(time.RFC3339),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				ServerCertificateNotAfter:    expiringServerCertNotAfter,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "one valid upstream whose server certificate no longer expires soon has its informational condition removed",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Status.Phase = "Ready"
+				upstream.Status.Conditions = []v1alpha1.Condition{{
+					Type:               "LDAPServerCertificateExpiringSoon",
+					Status:             "True",
+					LastTransitionTime: now,
+					Reason:             "ServerCertificateExpiringSoon",
+					Message:            "the certificate presented by the LDAP server expires at 2000-01-01T00:00:00Z",
+					ObservedGeneration: 1233,
+				}}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name:               "missing secret",
+			inputUpstreams:     []runtime.Object{validUpstream},
+			inputSecrets:       []runtime.Object{},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						{
+							Type:               "BindSecretValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "SecretNotFound",
+							Message:            fmt.Sprintf(`secret "%s" not found`, testSecretName),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+		},
+		{
+			name:           "secret has wrong type",
+			inputUpstreams: []runtime.Object{validUpstream},
+			inputSecrets: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: testSecretName, Namespace: testNamespace},
+				Type:       "some-other-type",
+				Data:       testValidSecretData,
+			}},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						{
+							Type:               "BindSecretValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "SecretWrongType",
+							Message:            fmt.Sprintf(`referenced Secret "%s" has wrong type "some-other-type" (should be "kubernetes.io/basic-auth" or "Opaque")`, testSecretName),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+		},
+		{
+			name:           "secret is missing key",
+			inputUpstreams: []runtime.Object{validUpstream},
+			inputSecrets: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: testSecretName, Namespace: testNamespace},
+				Type:       corev1.SecretTypeBasicAuth,
+			}},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						{
+							Type:               "BindSecretValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "SecretMissingKeys",
+							Message:            fmt.Sprintf(`referenced Secret "%s" is missing required keys ["username" "password"]`, testSecretName),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+		},
+		{
+			name: "anonymous bind is allowed and no secret is referenced",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.Bind = v1alpha1.LDAPIdentityProviderBind{AllowAnonymousBind: true}
+			})},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				expectAnonymousTestConnection(conn)
+				expectAnonymousUserSearchBaseValidation(conn)
+				expectAnonymousGroupSearchValidation(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithAnonymousBind},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: anonymousBindConditions(1234, "no bind secret is needed because anonymous bind is allowed"),
+					Validation: &v1alpha1.LDAPIdentityProviderValidation{
+						ObservedGeneration: 1234,
+						ConnectionHost:     testHost,
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:  "",
+				BindCredentialsFingerprint: testAnonymousBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition: &v1alpha1.Condition{
+					Type:    "LDAPConnectionValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: fmt.Sprintf(`successfully able to connect to "%s" and search anonymously`, testHost),
+				},
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:    "GroupSearchValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: groupSearchValidatedWithoutUserMessage,
+				},
+			}},
+		},
+		{
+			name: "anonymous bind is allowed and the referenced secret is missing keys",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.Bind.AllowAnonymousBind = true
+			})},
+			inputSecrets: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: testSecretName, Namespace: testNamespace, ResourceVersion: "4242"},
+				Type:       corev1.SecretTypeBasicAuth,
+			}},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				expectAnonymousTestConnection(conn)
+				expectAnonymousUserSearchBaseValidation(conn)
+				expectAnonymousGroupSearchValidation(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithAnonymousBind},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: anonymousBindConditions(1234,
+						fmt.Sprintf(`referenced Secret "%s" is missing keys ["username" "password"], so anonymous bind will be used`, testSecretName)),
+					Validation: &v1alpha1.LDAPIdentityProviderValidation{
+						ObservedGeneration:    1234,
+						ObservedSecretVersion: "4242",
+						ConnectionHost:        testHost,
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testAnonymousBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition: &v1alpha1.Condition{
+					Type:    "LDAPConnectionValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: fmt.Sprintf(`successfully able to connect to "%s" and search anonymously`, testHost),
+				},
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:    "GroupSearchValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: groupSearchValidatedWithoutUserMessage,
+				},
+			}},
+		},
+		{
+			name: "bind DN template is configured",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.Bind.BindDNTemplate = "cn={},ou=service-accounts,dc=example,dc=com"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind as the DN which was made from the template.
+				conn.EXPECT().Bind(testBindDN, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidationAs(conn, testBindDN)
+				expectGroupSearchDryRunAs(conn, testBindDN)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindDN,
+					BindPassword:       testBindPassword,
+					UserSearch:         providerConfigForValidUpstreamWithTLS.UserSearch,
+					GroupSearch:        providerConfigForValidUpstreamWithTLS.GroupSearch,
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            groupSearchValidatedWithoutUserMessage,
+							ObservedGeneration: 1234,
+						},
+						{
+							Type:               "LDAPConnectionValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message: fmt.Sprintf(
+								`successfully able to connect to "%s" and bind as user "%s" [validated with Secret "%s" at version "%s"]`,
+								testHost, testBindDN, testSecretName, "4242"),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: &v1alpha1.LDAPIdentityProviderValidation{
+						ObservedGeneration:    1234,
+						ObservedSecretVersion: "4242",
+						ConnectionHost:        testHost,
+						ResolvedBindDN:        testBindDN,
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindDNCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition: &v1alpha1.Condition{
+					Type:   "LDAPConnectionValid",
+					Status: "True",
+					Reason: "Success",
+					Message: fmt.Sprintf(
+						`successfully able to connect to "%s" and bind as user "%s" [validated with Secret "%s" at version "%s"]`,
+						testHost, testBindDN, testSecretName, "4242"),
+				},
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:    "GroupSearchValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: groupSearchValidatedWithoutUserMessage,
+				},
+			}},
+		},
+		{
+			name: "bind DN template does not contain the placeholder",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.Bind.BindDNTemplate = "cn=someone,ou=service-accounts,dc=example,dc=com"
+			})},
+			inputSecrets:       []runtime.Object{validBindUserSecret("4242")},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						{
+							Type:               "BindSecretValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidBindDNTemplate",
+							Message:            `bind.bindDNTemplate "cn=someone,ou=service-accounts,dc=example,dc=com" must contain the "{}" placeholder`,
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+		},
+		{
+			name: "bind DN template does not form a valid distinguished name",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.Bind.BindDNTemplate = "{}"
+			})},
+			inputSecrets:       []runtime.Object{validBindUserSecret("4242")},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						{
+							Type:               "BindSecretValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidBindDNTemplate",
+							Message:            `bind.bindDNTemplate "{}" does not form a valid distinguished name: DN ended with incomplete type, value pair`,
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+		},
+		{
+			name: "bind DN template is ignored when anonymous bind is used",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.Bind = v1alpha1.LDAPIdentityProviderBind{AllowAnonymousBind: true, BindDNTemplate: "not-a-template"}
+			})},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				expectAnonymousTestConnection(conn)
+				expectAnonymousUserSearchBaseValidation(conn)
+				expectAnonymousGroupSearchValidation(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithAnonymousBind},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: anonymousBindConditions(1234, "no bind secret is needed because anonymous bind is allowed"),
+					Validation: &v1alpha1.LDAPIdentityProviderValidation{
+						ObservedGeneration: 1234,
+						ConnectionHost:     testHost,
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:  "",
+				BindCredentialsFingerprint: testAnonymousBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition: &v1alpha1.Condition{
+					Type:    "LDAPConnectionValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: fmt.Sprintf(`successfully able to connect to "%s" and search anonymously`, testHost),
+				},
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:    "GroupSearchValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: groupSearchValidatedWithoutUserMessage,
+				},
+			}},
+		},
+		{
+			name: "CertificateAuthorityData is not base64 encoded",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.TLS.CertificateAuthorityData = "this-is-not-base64-encoded"
+			})},
+			inputSecrets:       []runtime.Object{validBindUserSecret("")},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						searchConfigurationValidTrueCondition(1234),
+						{
+							Type:               "TLSConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidBase64",
+							Message:            "certificateAuthorityData is invalid: illegal base64 data at input byte 4",
+							ObservedGeneration: 1234,
+						},
+					},
+				},
+			}},
+		},
+		{
+			name: "CertificateAuthorityData is not valid pem data",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.TLS.CertificateAuthorityData = base64.StdEncoding.EncodeToString([]byte("this is not pem data"))
+			})},
+			inputSecrets:       []runtime.Object{validBindUserSecret("")},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						searchConfigurationValidTrueCondition(1234),
+						{
+							Type:               "TLSConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "NoCertificatesFound",
+							Message:            "certificateAuthorityData is invalid: no certificates found",
+							ObservedGeneration: 1234,
+						},
+					},
+				},
+			}},
+		},
+		{
+			name: "CertificateAuthorityData contains a certificate which cannot be parsed",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.TLS.CertificateAuthorityData = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{
+					Type:  "CERTIFICATE",
+					Bytes: []byte("this is not a certificate"),
+				}))
+			})},
+			inputSecrets:       []runtime.Object{validBindUserSecret("")},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						searchConfigurationValidTrueCondition(1234),
+						{
+							Type:               "TLSConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "UnparseableCertificate",
+							Message:            "certificateAuthorityData is invalid: x509: malformed certificate",
+							ObservedGeneration: 1234,
+						},
+					},
+				},
+			}},
+		},
+		{
+			name: "nil TLS configuration is valid",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.TLS = nil
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           nil,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						{
+							Type:               "TLSConfigurationValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            "no TLS configuration provided",
+							ObservedGeneration: 1234,
+						},
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "group search base is not a valid distinguished name",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearch.Base = "not-a-dn"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind, but no group search dry run because the group search base is invalid.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidGroupSearchBase",
+							Message:            `groupSearch.base "not-a-dn" is not a valid distinguished name: DN ended with incomplete type, value pair`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              "not-a-dn",
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "uid encoding is not valid",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Attributes.UIDEncoding = "Base32"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidUIDEncoding",
+							Message:            `userSearch.attributes.uidEncoding "Base32" is not valid, must be one of ["Base64URL" "Hex"]`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "username multi-value policy is not valid",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Attributes.UsernameMultiValuePolicy = "Random"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidMultiValuePolicy",
+							Message:            `userSearch.attributes.usernameMultiValuePolicy "Random" is not valid, must be one of ["Error" "First" "Last"]`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "uid encoding is Hex",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Attributes.UIDEncoding = v1alpha1.LDAPUIDEncodingHex
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:                 testUserSearchBase,
+						Filter:               testUserSearchFilter,
+						UsernameAttribute:    testUsernameAttrName,
+						UIDAttribute:         testUIDAttrName,
+						UIDAttributeEncoding: upstreamldap.UIDEncodingHex,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "group search scope is not valid",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearch.Scope = "subtree"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidSearchScope",
+							Message:            `groupSearch.scope "subtree" is not valid, must be one of ["base" "one" "sub"]`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "user and group search scopes are specified",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Scope = v1alpha1.LDAPSearchScopeOne
+				upstream.Spec.GroupSearch.Scope = v1alpha1.LDAPSearchScopeBase
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// The group search dry run should use the configured group search scope.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				expectGroupSearchBaseRead(conn)
+				conn.EXPECT().SearchWithPaging(&ldap.SearchRequest{
+					BaseDN:       testGroupSearchBase,
+					Scope:        ldap.ScopeBaseObject,
+					DerefAliases: ldap.NeverDerefAliases,
+					TimeLimit:    90,
+					Filter:       "(" + testGroupSearchFilter + ")",
+					Attributes:   []string{testGroupNameAttrName},
+				}, uint32(1000)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{{
+						DN:         "cn=" + testGroupName + "," + testGroupSearchBase,
+						Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupNameAttrName, []string{testGroupName})},
+					}},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+						Scope:             upstreamldap.SearchScopeOne,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+						Scope:              upstreamldap.SearchScopeBase,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            `search configuration is valid (userSearch.scope "one", groupSearch.scope "base")`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name:           "secret has Opaque type",
+			inputUpstreams: []runtime.Object{validUpstream},
+			inputSecrets: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: testSecretName, Namespace: testNamespace, ResourceVersion: "4242"},
+				Type:       corev1.SecretTypeOpaque,
+				Data:       testValidSecretData,
+			}},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						{
+							Type:               "BindSecretValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            `loaded bind secret of type "Opaque"`,
+							ObservedGeneration: 1234,
+						},
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "username is configured to be case-insensitive",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.UsernameCaseSensitive = pointer.Bool(false)
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:                    testUserSearchBase,
+						Filter:                  testUserSearchFilter,
+						UsernameAttribute:       testUsernameAttrName,
+						UIDAttribute:            testUIDAttrName,
+						UsernameCaseInsensitive: true,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "username normalization is passed through to the provider",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.UsernameNormalization = v1alpha1.LDAPIdentityProviderUsernameNormalization{
+					TrimWhitespace:     true,
+					StripNetBIOSPrefix: true,
+					StripDomainSuffix:  "example.com",
+				}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+						UsernameNormalization: upstreamldap.UsernameNormalizationConfig{
+							TrimWhitespace:     true,
+							StripNetBIOSPrefix: true,
+							StripDomainSuffix:  "example.com",
+						},
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "user search filter does not contain the placeholder",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Filter = "(objectClass=person)"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "UserSearchFilterInsecure",
+							Message:            `userSearch.filter "(objectClass=person)" must contain the "{}" placeholder, or else it finds the same entries for every username`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "user search filter is a wildcard",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Filter = "(uid=*)"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "UserSearchFilterInsecure",
+							Message:            `userSearch.filter "(uid=*)" must contain the "{}" placeholder, or else it finds the same entries for every username`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "user search filter uses the placeholder in a wildcard match",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Filter = "(uid=*{}*)"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "UserSearchFilterInsecure",
+							Message:            `userSearch.filter "(uid=*{}*)" must not use the "{}" placeholder in a wildcard match, or else it finds entries for other usernames`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "user search filter which uses the placeholder more than once is valid",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Filter = "(|(uid={})(mail={}))"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{func() *upstreamldap.ProviderConfig {
+				config := *providerConfigForValidUpstreamWithTLS
+				config.UserSearch.Filter = "(|(uid={})(mail={}))"
+				return &config
+			}()},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "group name attribute is not a valid attribute name",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearch.Attributes.GroupName = "cn=group"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// The group search dry run asks for the configured attribute.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				expectGroupSearchBaseRead(conn)
+				conn.EXPECT().SearchWithPaging(gomock.Any(), uint32(1000)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{{
+						DN:         "cn=" + testGroupName + "," + testGroupSearchBase,
+						Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute("cn=group", []string{testGroupName})},
+					}},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidGroupNameAttribute",
+							Message:            `groupSearch.attributes.groupName "cn=group" is not a valid LDAP attribute name`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "group search mode userAttribute is passed through to the provider",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.DryRunUsername = testDryRunUsername
+				upstream.Spec.GroupSearch.Mode = v1alpha1.LDAPGroupSearchModeUserAttribute
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				groupDN := "cn=" + testGroupName + "," + testGroupSearchBase
+				memberOf := ldap.NewEntryAttribute("memberOf", []string{groupDN})
+				// Should find the dry run user for the group search dry run, and again for the user search dry run. These are
+				// expected first so that the reads of the entries below do not match the searches for the user.
+				expectDryRunUserSearch(conn, memberOf)
+				expectDryRunUserSearch(conn, memberOf)
+				// The group search dry run reads the group DNs from the dry run user's entry, and then reads the group's entry.
+				// The user search dry run reads the group DNs from the found entry, and then reads the group's entry again.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(gomock.Any()).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{{
+						DN:         testDryRunUserDN,
+						Attributes: []*ldap.EntryAttribute{memberOf},
+					}},
+				}, nil).Times(1)
+				conn.EXPECT().Search(gomock.Any()).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{{
+						DN:         groupDN,
+						Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupNameAttrName, []string{testGroupName})},
+					}},
+				}, nil).Times(2)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{func() *upstreamldap.ProviderConfig {
+				config := *providerConfigForValidUpstreamWithTLS
+				config.GroupSearch.Mode = upstreamldap.GroupSearchModeUserAttribute
+				return &config
+			}()},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidForDryRunUserTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						userSearchValidTrueCondition(1234, 1),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidForDryRunUserTrueConditionWithoutTimeOrGeneration()),
+				UserSearchValidCondition:     condPtr(userSearchValidTrueConditionWithoutTimeOrGeneration(1)),
+			}},
+		},
+		{
+			name: "user attribute for groups is not a valid attribute name",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearch.UserAttributeForGroups = "member of"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidUserAttributeForGroups",
+							Message:            `groupSearch.userAttributeForGroups "member of" is not a valid LDAP attribute name`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "using the group DN when the group name attribute is missing is passed through to the provider",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearch.Attributes.UseDNWhenGroupNameIsMissing = true
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{func() *upstreamldap.ProviderConfig {
+				config := *providerConfigForValidUpstreamWithTLS
+				config.GroupSearch.UseDNWhenGroupNameIsMissing = true
+				return &config
+			}()},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "extra attributes are passed through to the provider",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Attributes.Extra = map[string]string{"example.com/department": "departmentNumber"}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{func() *upstreamldap.ProviderConfig {
+				config := *providerConfigForValidUpstreamWithTLS
+				config.UserSearch.ExtraAttributes = map[string]string{"example.com/department": "departmentNumber"}
+				return &config
+			}()},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "extra attribute name is empty",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Attributes.Extra = map[string]string{
+					"example.com/department":  "departmentNumber",
+					"example.com/employee-id": "",
+				}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidExtraAttributes",
+							Message:            `userSearch.attributes.extra["example.com/employee-id"] "" is not a valid LDAP attribute name`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "extra attribute key is empty",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.Attributes.Extra = map[string]string{"": "departmentNumber"}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidExtraAttributes",
+							Message:            `userSearch.attributes.extra must not contain an empty key`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "username normalization strips a domain suffix which contains an @",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.UsernameNormalization.StripDomainSuffix = "@example.com"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidUsernameNormalization",
+							Message:            `userSearch.usernameNormalization.stripDomainSuffix "@example.com" must not contain "@" or whitespace`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "user DN template does not contain the placeholder",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.UserDNTemplate = "uid=someone,ou=people,dc=example,dc=com"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidUserDNTemplate",
+							Message:            `userSearch.userDNTemplate "uid=someone,ou=people,dc=example,dc=com" must contain the "{}" placeholder`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "user DN template does not form a valid distinguished name",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.UserDNTemplate = "{}"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidUserDNTemplate",
+							Message:            `userSearch.userDNTemplate "{}" does not form a valid distinguished name: DN ended with incomplete type, value pair`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "user DN template is configured along with a user search filter",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.UserDNTemplate = "uid={},ou=people,dc=example,dc=com"
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+						UserDNTemplate:    "uid={},ou=people,dc=example,dc=com",
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            `search configuration is valid (userSearch.scope "sub", groupSearch.scope "sub"), but userSearch.filter is ignored because userSearch.userDNTemplate is specified`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "connection timeout is configured",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.ConnectionTimeout = &metav1.Duration{Duration: 30 * time.Second}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					ConnectionTimeout:  30 * time.Second,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "connection timeout is too long",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.ConnectionTimeout = &metav1.Duration{Duration: time.Hour}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidConnectionTimeout",
+							Message:            `connectionTimeout "1h0m0s" must be greater than 0s and at most 10m0s`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "connection timeout is not positive",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.ConnectionTimeout = &metav1.Duration{Duration: 0}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidConnectionTimeout",
+							Message:            `connectionTimeout "0s" must be greater than 0s and at most 10m0s`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "allowed groups are configured",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.DryRunUsername = testDryRunUsername
+				upstream.Spec.GroupSearch.AllowedGroups = []string{"cn=some-other-group," + testGroupSearchBase}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// Should find the dry run user, and then run the group search for that user's DN.
+				expectDryRunUserSearch(conn)
+				expectGroupSearchDryRun(conn)
+				// Should perform the user search dry run as the bind user, including the search for the user's groups.
+				expectDryRunUserSearch(conn)
+				expectGroupSearchForDryRunUser(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+						AllowedGroups:      []string{"cn=some-other-group," + testGroupSearchBase},
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message: fmt.Sprintf(`group search dry run for user "%s" found groups [] `+
+								`after filtering out 1 groups which are not in groupSearch.allowedGroups`+defaultGroupSearchFailurePolicyNote, testDryRunUsername),
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						userSearchValidTrueCondition(1234, 0),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:   "GroupSearchValid",
+					Status: "True",
+					Reason: "Success",
+					Message: fmt.Sprintf(`group search dry run for user "%s" found groups [] `+
+						`after filtering out 1 groups which are not in groupSearch.allowedGroups`+defaultGroupSearchFailurePolicyNote, testDryRunUsername),
+				},
+				UserSearchValidCondition: condPtr(userSearchValidTrueConditionWithoutTimeOrGeneration(0)),
+			}},
+		},
+		{
+			name: "nested groups are resolved",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.DryRunUsername = testDryRunUsername
+				upstream.Spec.GroupSearch.ResolveNestedGroups = true
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// Should find the dry run user, and then run the group search for that user's DN.
+				expectDryRunUserSearch(conn)
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				expectGroupSearchBaseRead(conn)
+				// The filter does not use the DN, so the search for the groups which contain the group finds the group
+				// itself again, which was already visited.
+				conn.EXPECT().SearchWithPaging(&ldap.SearchRequest{
+					BaseDN:       testGroupSearchBase,
+					Scope:        ldap.ScopeWholeSubtree,
+					DerefAliases: ldap.NeverDerefAliases,
+					TimeLimit:    90,
+					Filter:       "(" + testGroupSearchFilter + ")",
+					Attributes:   []string{testGroupNameAttrName},
+				}, uint32(1000)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{{
+						DN:         "cn=" + testGroupName + "," + testGroupSearchBase,
+						Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupNameAttrName, []string{testGroupName})},
+					}},
+				}, nil).Times(2)
+				conn.EXPECT().Close().Times(1)
+				// Should perform the user search dry run as the bind user, including the search for the user's nested groups,
+				// which finds the group itself again.
+				expectDryRunUserSearch(conn)
+				expectGroupSearchForDryRunUser(conn)
+				expectGroupSearchForDryRunUser(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:                 testGroupSearchBase,
+						Filter:               testGroupSearchFilter,
+						GroupNameAttribute:   testGroupNameAttrName,
+						NestedGroupsMaxDepth: 10,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message: fmt.Sprintf(`group search dry run for user "%s" found groups ["%s"] `+
+								`(1 direct groups and 1 groups after resolving nested groups up to 10 levels)`+defaultGroupSearchFailurePolicyNote, testDryRunUsername, testGroupName),
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						userSearchValidTrueCondition(1234, 1),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:   "GroupSearchValid",
+					Status: "True",
+					Reason: "Success",
+					Message: fmt.Sprintf(`group search dry run for user "%s" found groups ["%s"] `+
+						`(1 direct groups and 1 groups after resolving nested groups up to 10 levels)`+defaultGroupSearchFailurePolicyNote, testDryRunUsername, testGroupName),
+				},
+				UserSearchValidCondition: condPtr(userSearchValidTrueConditionWithoutTimeOrGeneration(1)),
+			}},
+		},
+		{
+			name: "group search failure policy is FailOpen",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearchFailurePolicy = v1alpha1.LDAPGroupSearchFailurePolicyFailOpen
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+						FailurePolicy:      upstreamldap.GroupSearchFailOpen,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message: `groupSearch.base and groupSearch.filter are valid and searchable, but no user's groups were searched ` +
+								`because userSearch.dryRunUsername is not configured; ` +
+								`logins will succeed without groups when the group search fails because groupSearchFailurePolicy is "FailOpen"`,
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:   "GroupSearchValid",
+					Status: "True",
+					Reason: "Success",
+					Message: `groupSearch.base and groupSearch.filter are valid and searchable, but no user's groups were searched ` +
+						`because userSearch.dryRunUsername is not configured; ` +
+						`logins will succeed without groups when the group search fails because groupSearchFailurePolicy is "FailOpen"`,
+				},
+			}},
+		},
+		{
+			name: "when the group search dry run finds many groups then only some of them are listed",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.UserSearch.DryRunUsername = testDryRunUsername
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// Should find the dry run user, and then run the group search for that user's DN.
+				expectDryRunUserSearch(conn)
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				expectGroupSearchBaseRead(conn)
+				entries := []*ldap.Entry{}
+				for i := 0; i < 12; i++ {
+					groupName := fmt.Sprintf("group-%02d", i)
+					entries = append(entries, &ldap.Entry{
+						DN:         "cn=" + groupName + "," + testGroupSearchBase,
+						Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupNameAttrName, []string{groupName})},
+					})
+				}
+				// The same groups are found by the group search dry run and by the user search dry run.
+				conn.EXPECT().SearchWithPaging(gomock.Any(), uint32(1000)).Return(&ldap.SearchResult{Entries: entries}, nil).Times(2)
+				conn.EXPECT().Close().Times(1)
+				// Should perform the user search dry run as the bind user.
+				expectDryRunUserSearch(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message: fmt.Sprintf(`group search dry run for user "%s" found 12 groups, including `+
+								`["group-00" "group-01" "group-02" "group-03" "group-04" "group-05" "group-06" "group-07" "group-08" "group-09"]`+defaultGroupSearchFailurePolicyNote,
+								testDryRunUsername),
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						userSearchValidTrueCondition(1234, 12),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:   "GroupSearchValid",
+					Status: "True",
+					Reason: "Success",
+					Message: fmt.Sprintf(`group search dry run for user "%s" found 12 groups, including `+
+						`["group-00" "group-01" "group-02" "group-03" "group-04" "group-05" "group-06" "group-07" "group-08" "group-09"]`+defaultGroupSearchFailurePolicyNote,
+						testDryRunUsername),
+				},
+				UserSearchValidCondition: condPtr(userSearchValidTrueConditionWithoutTimeOrGeneration(12)),
+			}},
+		},
+		{
+			name: "allowed groups contain an invalid distinguished name",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearch.AllowedGroups = []string{"cn=" + testGroupName + "," + testGroupSearchBase, "not-a-dn"}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            groupSearchValidatedWithoutUserMessage,
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						{
+							Type:               "SearchConfigurationValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "InvalidAllowedGroups",
+							Message:            `groupSearch.allowedGroups[1] "not-a-dn" is not a valid distinguished name: DN ended with incomplete type, value pair`,
+							ObservedGeneration: 1234,
+						},
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:    "GroupSearchValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: groupSearchValidatedWithoutUserMessage,
+				},
+			}},
+		},
+		{
+			name: "group search page size is configured",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearch.PageSize = 500
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// Should perform the group search dry run using the configured page size.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				expectGroupSearchBaseRead(conn)
+				conn.EXPECT().SearchWithPaging(gomock.Any(), uint32(500)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{{
+						DN:         "cn=" + testGroupName + "," + testGroupSearchBase,
+						Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupNameAttrName, []string{testGroupName})},
+					}},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+						PageSize:           500,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "search size and time limits are configured",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.SearchSizeLimit = 50
+				upstream.Spec.SearchTimeLimit = 30
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				// Should validate the user search base using the configured time limit.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(&ldap.SearchRequest{
+					BaseDN:       testUserSearchBase,
+					Scope:        ldap.ScopeBaseObject,
+					DerefAliases: ldap.NeverDerefAliases,
+					SizeLimit:    1,
+					TimeLimit:    30,
+					TypesOnly:    true,
+					Filter:       "(objectClass=*)",
+					Attributes:   []string{"objectClass"},
+				}).Return(&ldap.SearchResult{Entries: []*ldap.Entry{{DN: testUserSearchBase}}}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+				// Should perform the group search dry run using the configured size and time limits.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(&ldap.SearchRequest{
+					BaseDN:       testGroupSearchBase,
+					Scope:        ldap.ScopeBaseObject,
+					DerefAliases: ldap.NeverDerefAliases,
+					SizeLimit:    1,
+					TimeLimit:    30,
+					TypesOnly:    true,
+					Filter:       "(objectClass=*)",
+					Attributes:   []string{"objectClass"},
+				}).Return(&ldap.SearchResult{Entries: []*ldap.Entry{{DN: testGroupSearchBase}}}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(&ldap.SearchRequest{
+					BaseDN:       testGroupSearchBase,
+					Scope:        ldap.ScopeWholeSubtree,
+					DerefAliases: ldap.NeverDerefAliases,
+					SizeLimit:    50,
+					TimeLimit:    30,
+					Filter:       "(" + testGroupSearchFilter + ")",
+					Attributes:   []string{testGroupNameAttrName},
+				}, uint32(1000)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{{
+						DN:         "cn=" + testGroupName + "," + testGroupSearchBase,
+						Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupNameAttrName, []string{testGroupName})},
+					}},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					SearchSizeLimit:    50,
+					SearchTimeLimit:    30 * time.Second,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "when TLS connection fails it tries to use StartTLS instead: without a specified port it automatically switches ports",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.Host = "ldap.example.com" // when the port is not specified, automatically switch ports for StartTLS
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			dialErrors: map[string]error{
+				"ldap.example.com:" + ldap.DefaultLdapsPort: fmt.Errorf("some ldaps dial error"),
+				"ldap.example.com:" + ldap.DefaultLdapPort:  nil, // no error on the regular ldap:// port
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               "ldap.example.com",
+					ConnectionProtocol: upstreamldap.StartTLS, // successfully fell back to using StartTLS
+					CABundle:           testCABundle,
 					BindUsername:       testBindUsername,
 					BindPassword:       testBindPassword,
 					UserSearch: upstreamldap.UserSearchConfig{
@@ -500,48 +3711,71 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 					Phase: "Ready",
 					Conditions: []v1alpha1.Condition{
 						bindSecretValidTrueCondition(1234),
-						ldapConnectionValidTrueCondition(1234, "4242"),
+						groupSearchValidTrueCondition(1234),
 						{
-							Type:               "TLSConfigurationValid",
+							Type:               "LDAPConnectionValid",
 							Status:             "True",
 							LastTransitionTime: now,
 							Reason:             "Success",
-							Message:            "no TLS configuration provided",
+							Message: fmt.Sprintf(
+								`successfully able to connect to "%s" and bind as user "%s" [validated with Secret "%s" at version "%s"]`,
+								"ldap.example.com", testBindUsername, testSecretName, "4242"),
 							ObservedGeneration: 1234,
 						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: &v1alpha1.LDAPIdentityProviderValidation{
+						ObservedGeneration:    1234,
+						ObservedSecretVersion: "4242",
+						ConnectionHost:        "ldap.example.com",
+						ResolvedBindDN:        testBindUsername,
 					},
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.StartTLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             "ldap.example.com",
+				IDPSpecGeneration:          1234,
+				ConnectionValidCondition: &v1alpha1.Condition{
+					Type:   "LDAPConnectionValid",
+					Status: "True",
+					Reason: "Success",
+					Message: fmt.Sprintf(
+						`successfully able to connect to "%s" and bind as user "%s" [validated with Secret "%s" at version "%s"]`,
+						"ldap.example.com", testBindUsername, testSecretName, "4242"),
+				},
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
 		},
 		{
-			name: "when TLS connection fails it tries to use StartTLS instead: without a specified port it automatically switches ports",
+			name: "when multiple hosts are configured and the first cannot be reached then it connects to the next and reports which host it reached",
 			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Spec.Host = "ldap.example.com" // when the port is not specified, automatically switch ports for StartTLS
+				upstream.Spec.Host = "ldap1.example.com:5678,ldap2.example.com:5678"
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should perform a test dial and bind.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
 			},
 			dialErrors: map[string]error{
-				"ldap.example.com:" + ldap.DefaultLdapsPort: fmt.Errorf("some ldaps dial error"),
-				"ldap.example.com:" + ldap.DefaultLdapPort:  nil, // no error on the regular ldap:// port
+				"ldap1.example.com:5678": fmt.Errorf("some dial error"),
 			},
 			wantResultingCache: []*upstreamldap.ProviderConfig{
 				{
 					Name:               testName,
 					ResourceUID:        testResourceUID,
-					Host:               "ldap.example.com",
-					ConnectionProtocol: upstreamldap.StartTLS, // successfully fell back to using StartTLS
+					Host:               "ldap1.example.com:5678,ldap2.example.com:5678",
+					ConnectionProtocol: upstreamldap.TLS,
 					CABundle:           testCABundle,
 					BindUsername:       testBindUsername,
 					BindPassword:       testBindPassword,
@@ -564,6 +3798,7 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 					Phase: "Ready",
 					Conditions: []v1alpha1.Condition{
 						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
 						{
 							Type:               "LDAPConnectionValid",
 							Status:             "True",
@@ -571,40 +3806,115 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 							Reason:             "Success",
 							Message: fmt.Sprintf(
 								`successfully able to connect to "%s" and bind as user "%s" [validated with Secret "%s" at version "%s"]`,
-								"ldap.example.com", testBindUsername, testSecretName, "4242"),
+								"ldap2.example.com:5678", testBindUsername, testSecretName, "4242"),
 							ObservedGeneration: 1234,
 						},
+						searchConfigurationValidTrueCondition(1234),
 						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: &v1alpha1.LDAPIdentityProviderValidation{
+						ObservedGeneration:    1234,
+						ObservedSecretVersion: "4242",
+						ConnectionHost:        "ldap2.example.com:5678",
+						ResolvedBindDN:        testBindUsername,
 					},
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.StartTLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             "ldap2.example.com:5678",
+				IDPSpecGeneration:          1234,
 				ConnectionValidCondition: &v1alpha1.Condition{
 					Type:   "LDAPConnectionValid",
 					Status: "True",
 					Reason: "Success",
 					Message: fmt.Sprintf(
 						`successfully able to connect to "%s" and bind as user "%s" [validated with Secret "%s" at version "%s"]`,
-						"ldap.example.com", testBindUsername, testSecretName, "4242"),
+						"ldap2.example.com:5678", testBindUsername, testSecretName, "4242"),
+				},
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "when TLS connection fails it tries to use StartTLS instead: with a specified port it does not automatically switch ports",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.Host = "ldap.example.com:5678" // when the port is specified, do not automatically switch ports for StartTLS
+				// The validation from an earlier successful connection test should be removed from the status.
+				upstream.Status.Validation = validationStatus(1233, "4241")
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Both dials fail, so there should be no bind.
+			},
+			dialErrors: map[string]error{
+				"ldap.example.com:5678": fmt.Errorf("some dial error"), // both TLS and StartTLS should try the same port and both fail
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				// even though the connection test failed, still loads into the cache because it is treated like a warning
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               "ldap.example.com:5678",
+					ConnectionProtocol: upstreamldap.TLS, // need to pick TLS or StartTLS to load into the cache when both fail, so choose TLS
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               testGroupSearchBase,
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
+			},
+			wantErr: controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "LDAPConnectionValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "LDAPConnectionError",
+							Message: fmt.Sprintf(
+								`could not successfully connect to "%s" and bind as user "%s": error dialing host "%s": some dial error`,
+								"ldap.example.com:5678", testBindUsername, "ldap.example.com:5678"),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
 				},
 			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
 		},
 		{
-			name: "when TLS connection fails it tries to use StartTLS instead: with a specified port it does not automatically switch ports",
+			name: "when connecting through the proxy fails it reports a proxy connection error",
 			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Spec.Host = "ldap.example.com:5678" // when the port is specified, do not automatically switch ports for StartTLS
+				upstream.Spec.Host = "ldap.example.com:5678"
+				upstream.Spec.ConnectionProtocol = v1alpha1.LDAPConnectionProtocolTLS
+				upstream.Spec.ProxyURL = "socks5://proxy.example.com:1080"
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			setupMocks: func(conn *mockldapconn.MockConn) {
-				// Both dials fail, so there should be no bind.
+				// The dial fails, so there should be no bind.
 			},
 			dialErrors: map[string]error{
-				"ldap.example.com:5678": fmt.Errorf("some dial error"), // both TLS and StartTLS should try the same port and both fail
+				"ldap.example.com:5678": fmt.Errorf(`%w "socks5://proxy.example.com:1080": some proxy error`, upstreamldap.ErrProxy),
 			},
 			wantResultingCache: []*upstreamldap.ProviderConfig{
 				// even though the connection test failed, still loads into the cache because it is treated like a warning
@@ -612,7 +3922,8 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 					Name:               testName,
 					ResourceUID:        testResourceUID,
 					Host:               "ldap.example.com:5678",
-					ConnectionProtocol: upstreamldap.TLS, // need to pick TLS or StartTLS to load into the cache when both fail, so choose TLS
+					ProxyURL:           "socks5://proxy.example.com:1080",
+					ConnectionProtocol: upstreamldap.TLS,
 					CABundle:           testCABundle,
 					BindUsername:       testBindUsername,
 					BindPassword:       testBindPassword,
@@ -640,12 +3951,13 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 							Type:               "LDAPConnectionValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "LDAPConnectionError",
+							Reason:             "LDAPProxyConnectionError",
 							Message: fmt.Sprintf(
-								`could not successfully connect to "%s" and bind as user "%s": error dialing host "%s": some dial error`,
-								"ldap.example.com:5678", testBindUsername, "ldap.example.com:5678"),
+								`could not connect to "%s" through the proxy: error dialing host "%s": error connecting through proxy "socks5://proxy.example.com:1080": some proxy error`,
+								"ldap.example.com:5678", "ldap.example.com:5678"),
 							ObservedGeneration: 1234,
 						},
+						searchConfigurationValidTrueCondition(1234),
 						tlsConfigurationValidLoadedTrueCondition(1234),
 					},
 				},
@@ -662,6 +3974,8 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				// Should perform a test dial and bind.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
 			},
 			wantResultingCache: []*upstreamldap.ProviderConfig{
 				{
@@ -690,15 +4004,20 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				Status: v1alpha1.LDAPIdentityProviderStatus{
 					Phase:      "Ready",
 					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
 		},
 		{
@@ -714,6 +4033,8 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				// Should perform a test dial and bind for the one valid upstream configuration.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
 			},
 			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
 			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
@@ -731,6 +4052,7 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 								Message:            fmt.Sprintf(`secret "%s" not found`, "non-existent-secret"),
 								ObservedGeneration: 42,
 							},
+							searchConfigurationValidTrueCondition(42),
 							tlsConfigurationValidLoadedTrueCondition(42),
 						},
 					},
@@ -740,16 +4062,21 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 					Status: v1alpha1.LDAPIdentityProviderStatus{
 						Phase:      "Ready",
 						Conditions: allConditionsTrue(1234, "4242"),
+						Validation: validationStatus(1234, "4242"),
 					},
 				},
 			},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
 		},
 		{
@@ -774,12 +4101,194 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 							Type:               "LDAPConnectionValid",
 							Status:             "False",
 							LastTransitionTime: now,
-							Reason:             "LDAPConnectionError",
+							Reason:             "LDAPBindError",
+							Message: fmt.Sprintf(
+								`could not successfully connect to "%s" and bind as user "%s": error binding as "%s": some bind error`,
+								testHost, testBindUsername, testBindUsername),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
+		},
+		{
+			name: "when the connection protocol is specified as TLS and testing the connection fails then it does not try StartTLS",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.ConnectionProtocol = v1alpha1.LDAPConnectionProtocolTLS
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform only one test dial and bind, using TLS.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1).Return(errors.New("some bind error"))
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "LDAPConnectionValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "LDAPBindError",
 							Message: fmt.Sprintf(
 								`could not successfully connect to "%s" and bind as user "%s": error binding as "%s": some bind error`,
 								testHost, testBindUsername, testBindUsername),
 							ObservedGeneration: 1234,
 						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
+		},
+		{
+			name: "when the LDAP server stops responding while testing the connection then it reports an operation timeout",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.ConnectionProtocol = v1alpha1.LDAPConnectionProtocolTLS
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should not try again, since the server would only time out again.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1).
+					Return(fmt.Errorf("%w: the LDAP server did not respond within 2m0s", upstreamldap.ErrOperationTimeout))
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "LDAPConnectionValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "LDAPOperationTimeout",
+							Message: fmt.Sprintf(
+								`could not successfully connect to "%s" and bind as user "%s": error binding as "%s": LDAPOperationTimeout: the LDAP server did not respond within 2m0s`,
+								testHost, testBindUsername, testBindUsername),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
+		},
+		{
+			name: "when the connection protocol is specified as StartTLS then it only uses StartTLS",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.ConnectionProtocol = v1alpha1.LDAPConnectionProtocolStartTLS
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform only one test dial and bind, using StartTLS.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithStartTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.StartTLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "when testing the connection fails due to a network error then it tries again",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.ConnectionProtocol = v1alpha1.LDAPConnectionProtocolTLS
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// The first test dial and bind fails with a network error, and the second one succeeds.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1).
+					Return(ldap.NewError(ldap.ErrorNetwork, errors.New("connection reset by peer")))
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(2)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "when testing the connection keeps failing due to a network error then it gives up after the last attempt",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.ConnectionProtocol = v1alpha1.LDAPConnectionProtocolTLS
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(3).
+					Return(ldap.NewError(ldap.ErrorNetwork, errors.New("connection reset by peer")))
+				conn.EXPECT().Close().Times(3)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "LDAPConnectionValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "LDAPConnectionError",
+							Message: fmt.Sprintf(
+								`could not successfully connect to "%s" and bind as user "%s": error binding as "%s": LDAP Result Code 200 "Network Error": connection reset by peer`,
+								testHost, testBindUsername, testBindUsername),
+							ObservedGeneration: 1234,
+						},
+						searchConfigurationValidTrueCondition(1234),
 						tlsConfigurationValidLoadedTrueCondition(1234),
 					},
 				},
@@ -797,11 +4306,15 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{
 				testName: {BindSecretResourceVersion: "4242",
-					LDAPConnectionProtocol:   upstreamldap.TLS,
-					UserSearchBase:           testUserSearchBase,
-					GroupSearchBase:          testGroupSearchBase,
-					IDPSpecGeneration:        1234,
-					ConnectionValidCondition: condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+					BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+					LDAPConnectionProtocol:       upstreamldap.TLS,
+					UserSearchBase:               testUserSearchBase,
+					GroupSearchBase:              testGroupSearchBase,
+					ConnectionHost:               testHost,
+					IDPSpecGeneration:            1234,
+					ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+					UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+					GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 				}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should not perform a test dial and bind. No mocking here means the test will fail if Bind() or Close() are called.
@@ -812,15 +4325,20 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				Status: v1alpha1.LDAPIdentityProviderStatus{
 					Phase:      "Ready",
 					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
 		},
 		{
@@ -833,12 +4351,16 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.StartTLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.StartTLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should not perform a test dial and bind. No mocking here means the test will fail if Bind() or Close() are called.
@@ -849,15 +4371,20 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				Status: v1alpha1.LDAPIdentityProviderStatus{
 					Phase:      "Ready",
 					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.StartTLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.StartTLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
 		},
 		{
@@ -870,16 +4397,210 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				IDPSpecGeneration:         1233,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				IDPSpecGeneration:          1233,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+			}},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "when the LDAP server connection condition failed to update previously, then write the cached condition from the previous connection validation",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Generation = 1234 // current generation
+				upstream.Status.Conditions = []v1alpha1.Condition{
+					ldapConnectionValidTrueCondition(1234, "4200"), // old version of the condition, as if the previous update of conditions had failed
+				}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				IDPSpecGeneration:            1234,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")), // already previously validated with version 4242
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// The connection had already been validated previously and the result was cached, so don't probe the server again.
+				// Should not perform a test dial and bind. No mocking here means the test will fail if Bind() or Close() are called.
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"), // updated version of the condition using the cached condition value
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "when the LDAP server connection validation previously failed for this resource generation, then try to validate it again",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Generation = 1234
+				upstream.Status.Conditions = []v1alpha1.Condition{
+					{
+						Type:               "LDAPConnectionValid",
+						Status:             "False", // failure!
+						LastTransitionTime: now,
+						Reason:             "LDAPConnectionError",
+						Message:            "some-error-message",
+						ObservedGeneration: 1234, // same (current) generation!
+					},
+				}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}},
+		},
+		{
+			name: "when the validated settings cache is incomplete, then try to validate it again",
+			// this shouldn't happen, but if it does, just throw it out and try again.
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Generation = 1234
+				upstream.Status.Conditions = []v1alpha1.Condition{
+					{
+						Type:               "LDAPConnectionValid",
+						Status:             "False", // failure!
+						LastTransitionTime: now,
+						Reason:             "LDAPConnectionError",
+						Message:            "some-error-message",
+						ObservedGeneration: 1234, // same (current) generation!
+					},
+				}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:  "4242",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+			}},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase:      "Ready",
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
+		},
+		{
+			name: "when the LDAP server connection was already validated for this resource generation but the bind secret has changed, then try to validate it again",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Generation = 1234
+				upstream.Status.Conditions = []v1alpha1.Condition{
+					ldapConnectionValidTrueCondition(1234, "4241"), // same spec generation, old secret version
+				}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")}, // newer secret version!
+			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion:  "4241",
+				BindCredentialsFingerprint: testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:     upstreamldap.TLS,
+				UserSearchBase:             testUserSearchBase,
+				GroupSearchBase:            testGroupSearchBase,
+				ConnectionHost:             testHost,
+				IDPSpecGeneration:          1234,
+			}}, // old version was validated
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should perform a test dial and bind.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
 			},
 			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
@@ -887,173 +4608,418 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				Status: v1alpha1.LDAPIdentityProviderStatus{
 					Phase:      "Ready",
 					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-			}},
-		},
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}}},
 		{
-			name: "when the LDAP server connection condition failed to update previously, then write the cached condition from the previous connection validation",
+			name: "when the LDAP server connection was already validated for this resource generation and secret version but the bind credentials have changed, then try to validate it again",
 			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Generation = 1234 // current generation
+				upstream.Generation = 1234
 				upstream.Status.Conditions = []v1alpha1.Condition{
-					ldapConnectionValidTrueCondition(1234, "4200"), // old version of the condition, as if the previous update of conditions had failed
+					ldapConnectionValidTrueCondition(1234, "4242"),
 				}
 			})},
-			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")}, // same secret version, but with a new password
 			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				IDPSpecGeneration:         1234,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")), // already previously validated with version 4242
-			}},
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   upstreamwatchers.BindCredentialsFingerprint(testBindUsername, "old-bind-password"),
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}}, // old password was validated
 			setupMocks: func(conn *mockldapconn.MockConn) {
-				// The connection had already been validated previously and the result was cached, so don't probe the server again.
-				// Should not perform a test dial and bind. No mocking here means the test will fail if Bind() or Close() are called.
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
 			},
 			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
 					Phase:      "Ready",
-					Conditions: allConditionsTrue(1234, "4242"), // updated version of the condition using the cached condition value
+					Conditions: allConditionsTrue(1234, "4242"),
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
+			}}},
+		{
+			name:           "when the group search validation fails then the upstream is still added to the cache anyway (treated like a warning) but not the validated settings cache",
+			inputUpstreams: []runtime.Object{validUpstream},
+			inputSecrets:   []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind, and then a group search which fails.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(2)
+				expectGroupSearchBaseRead(conn)
+				conn.EXPECT().SearchWithPaging(gomock.Any(), uint32(1000)).Return(nil, errors.New("some group search error")).Times(1)
+				conn.EXPECT().Close().Times(2)
+				expectUserSearchBaseValidation(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "GroupSearchDryRunError",
+							Message:            `group search validation failed: error running the group search: some group search error` + defaultGroupSearchFailurePolicyNote,
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
 			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
 		},
 		{
-			name: "when the LDAP server connection validation previously failed for this resource generation, then try to validate it again",
+			name: "when the group search for the dry run user fails then both the GroupSearchValid and UserSearchValid conditions are false, and the upstream is still added to the cache anyway (treated like a warning) but not the validated settings cache",
 			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Generation = 1234
-				upstream.Status.Conditions = []v1alpha1.Condition{
-					{
-						Type:               "LDAPConnectionValid",
-						Status:             "False", // failure!
-						LastTransitionTime: now,
-						Reason:             "LDAPConnectionError",
-						Message:            "some-error-message",
-						ObservedGeneration: 1234, // same (current) generation!
-					},
-				}
+				upstream.Spec.UserSearch.DryRunUsername = testDryRunUsername
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				// Should perform a test dial and bind.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// Should find the dry run user, and then run the group search for that user's DN, which fails.
+				expectDryRunUserSearch(conn)
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				expectGroupSearchBaseRead(conn)
+				conn.EXPECT().SearchWithPaging(gomock.Any(), uint32(1000)).Return(nil, errors.New("some group search error")).Times(2)
+				conn.EXPECT().Close().Times(1)
+				// Should perform the user search dry run as the bind user, which fails when searching for the user's groups.
+				expectDryRunUserSearch(conn)
 			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
 			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
-					Phase:      "Ready",
-					Conditions: allConditionsTrue(1234, "4242"),
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "GroupSearchDryRunError",
+							Message: fmt.Sprintf(`group search dry run for user "%s" failed: error searching for group memberships for user with DN "%s": `+
+								`some group search error`+defaultGroupSearchFailurePolicyNote, testDryRunUsername, testDryRunUserDN),
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						{
+							Type:               "UserSearchValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "UserSearchDryRunError",
+							Message: fmt.Sprintf(`user search dry run for username "%s" failed: error searching for group memberships for user with DN "%s": `+
+								`some group search error`, testDryRunUsername, testDryRunUserDN),
+							ObservedGeneration: 1234,
+						},
+					},
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
-			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
+		},
+		{
+			name:           "when the group search base cannot be read by the bind user then the upstream is still added to the cache anyway (treated like a warning) but not the validated settings cache",
+			inputUpstreams: []runtime.Object{validUpstream},
+			inputSecrets:   []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind, and then a read of the group search base which finds nothing.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(2)
+				conn.EXPECT().Search(&ldap.SearchRequest{
+					BaseDN:       testGroupSearchBase,
+					Scope:        ldap.ScopeBaseObject,
+					DerefAliases: ldap.NeverDerefAliases,
+					SizeLimit:    1,
+					TimeLimit:    90,
+					TypesOnly:    true,
+					Filter:       "(objectClass=*)",
+					Attributes:   []string{"objectClass"},
+				}).Return(&ldap.SearchResult{}, nil).Times(1)
+				conn.EXPECT().Close().Times(2)
+				expectUserSearchBaseValidation(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "GroupSearchBaseUnreadable",
+							Message: fmt.Sprintf(
+								`group search validation failed: group search base is unreadable: "%s" was not found or could not be read as "%s"`+defaultGroupSearchFailurePolicyNote,
+								testGroupSearchBase, testBindUsername),
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
+				},
 			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
 		},
 		{
-			name: "when the validated settings cache is incomplete, then try to validate it again",
-			// this shouldn't happen, but if it does, just throw it out and try again.
-			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Generation = 1234
-				upstream.Status.Conditions = []v1alpha1.Condition{
-					{
-						Type:               "LDAPConnectionValid",
-						Status:             "False", // failure!
-						LastTransitionTime: now,
-						Reason:             "LDAPConnectionError",
-						Message:            "some-error-message",
-						ObservedGeneration: 1234, // same (current) generation!
+			name:           "when the user search base is not found then the upstream is still added to the cache anyway (treated like a warning) but not the validated settings cache",
+			inputUpstreams: []runtime.Object{validUpstream},
+			inputSecrets:   []runtime.Object{validBindUserSecret("4242")},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind, and then a search for the user search base which finds nothing.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(2)
+				conn.EXPECT().Search(gomock.Any()).Return(&ldap.SearchResult{}, nil).Times(1)
+				conn.EXPECT().Close().Times(2)
+				expectGroupSearchDryRun(conn)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
+				Status: v1alpha1.LDAPIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						{
+							Type:               "UserSearchBaseValid",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "UserSearchBaseInvalid",
+							Message: fmt.Sprintf(
+								`userSearch.base "%s" could not be validated: user search base "%s" was not found or could not be read as "%s"`,
+								testUserSearchBase, testUserSearchBase, testBindUsername),
+							ObservedGeneration: 1234,
+						},
 					},
-				}
+					Validation: validationStatus(1234, "4242"),
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{},
+		},
+		{
+			name: "when the group search base is empty then group search is skipped",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
+				upstream.Spec.GroupSearch.Base = ""
 			})},
 			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
-			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-			}},
 			setupMocks: func(conn *mockldapconn.MockConn) {
-				// Should perform a test dial and bind.
+				// Should perform a test dial and bind, but no group search dry run.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               "",
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
 			},
-			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
-					Phase:      "Ready",
-					Conditions: allConditionsTrue(1234, "4242"),
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            "group search is skipped because groupSearch.base is empty",
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+					},
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              "",
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:    "GroupSearchValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: "group search is skipped because groupSearch.base is empty",
+				},
 			}},
 		},
 		{
-			name: "when the LDAP server connection was already validated for this resource generation but the bind secret has changed, then try to validate it again",
+			name: "when the group search base is empty then the user search dry run does not search for groups",
 			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
-				upstream.Generation = 1234
-				upstream.Status.Conditions = []v1alpha1.Condition{
-					ldapConnectionValidTrueCondition(1234, "4241"), // same spec generation, old secret version
-				}
+				upstream.Spec.UserSearch.DryRunUsername = testDryRunUsername
+				upstream.Spec.GroupSearch.Base = ""
 			})},
-			inputSecrets: []runtime.Object{validBindUserSecret("4242")}, // newer secret version!
-			initialValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4241",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-			}}, // old version was validated
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
 			setupMocks: func(conn *mockldapconn.MockConn) {
-				// Should perform a test dial and bind.
+				// Should perform a test dial and bind, but no group search dry run.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				// Should perform the user search dry run as the bind user, without searching for groups.
+				expectDryRunUserSearch(conn)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{
+				{
+					Name:               testName,
+					ResourceUID:        testResourceUID,
+					Host:               testHost,
+					ConnectionProtocol: upstreamldap.TLS,
+					CABundle:           testCABundle,
+					BindUsername:       testBindUsername,
+					BindPassword:       testBindPassword,
+					UserSearch: upstreamldap.UserSearchConfig{
+						Base:              testUserSearchBase,
+						Filter:            testUserSearchFilter,
+						UsernameAttribute: testUsernameAttrName,
+						UIDAttribute:      testUIDAttrName,
+					},
+					GroupSearch: upstreamldap.GroupSearchConfig{
+						Base:               "",
+						Filter:             testGroupSearchFilter,
+						GroupNameAttribute: testGroupNameAttrName,
+					},
+				},
 			},
-			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
 			wantResultingUpstreams: []v1alpha1.LDAPIdentityProvider{{
 				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testResourceUID},
 				Status: v1alpha1.LDAPIdentityProviderStatus{
-					Phase:      "Ready",
-					Conditions: allConditionsTrue(1234, "4242"),
+					Phase: "Ready",
+					Conditions: []v1alpha1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "GroupSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            "group search is skipped because groupSearch.base is empty",
+							ObservedGeneration: 1234,
+						},
+						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+						userSearchBaseValidTrueCondition(1234),
+						{
+							Type:               "UserSearchValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message: `user search dry run for username "some-dry-run-user" found user "some-dry-run-user", ` +
+								`and did not search for groups because groupSearch.base is empty`,
+							ObservedGeneration: 1234,
+						},
+					},
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
-			}}},
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              "",
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition: &v1alpha1.Condition{
+					Type:    "GroupSearchValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: "group search is skipped because groupSearch.base is empty",
+				},
+				UserSearchValidCondition: &v1alpha1.Condition{
+					Type:   "UserSearchValid",
+					Status: "True",
+					Reason: "Success",
+					Message: `user search dry run for username "some-dry-run-user" found user "some-dry-run-user", ` +
+						`and did not search for groups because groupSearch.base is empty`,
+				},
+			}},
+		},
 		{
 			name: "skipping group refresh is valid",
 			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.LDAPIdentityProvider) {
@@ -1064,6 +5030,8 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				// Should perform a test dial and bind.
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Close().Times(1)
+				expectUserSearchBaseValidation(conn)
+				expectGroupSearchDryRun(conn)
 			},
 			wantResultingCache: []*upstreamldap.ProviderConfig{
 				{
@@ -1094,7 +5062,9 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 					Phase: "Ready",
 					Conditions: []v1alpha1.Condition{
 						bindSecretValidTrueCondition(1234),
+						groupSearchValidTrueCondition(1234),
 						ldapConnectionValidTrueCondition(1234, "4242"),
+						searchConfigurationValidTrueCondition(1234),
 						{
 							Type:               "TLSConfigurationValid",
 							Status:             "True",
@@ -1103,16 +5073,22 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 							Message:            "loaded TLS configuration",
 							ObservedGeneration: 1234,
 						},
+						userSearchBaseValidTrueCondition(1234),
 					},
+					Validation: validationStatus(1234, "4242"),
 				},
 			}},
 			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
-				BindSecretResourceVersion: "4242",
-				LDAPConnectionProtocol:    upstreamldap.TLS,
-				UserSearchBase:            testUserSearchBase,
-				GroupSearchBase:           testGroupSearchBase,
-				IDPSpecGeneration:         1234,
-				ConnectionValidCondition:  condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				BindSecretResourceVersion:    "4242",
+				BindCredentialsFingerprint:   testBindCredentialsFingerprint,
+				LDAPConnectionProtocol:       upstreamldap.TLS,
+				UserSearchBase:               testUserSearchBase,
+				GroupSearchBase:              testGroupSearchBase,
+				ConnectionHost:               testHost,
+				IDPSpecGeneration:            1234,
+				ConnectionValidCondition:     condPtr(ldapConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				UserSearchBaseValidCondition: condPtr(userSearchBaseValidTrueConditionWithoutTimeOrGeneration()),
+				GroupSearchValidCondition:    condPtr(groupSearchValidTrueConditionWithoutTimeOrGeneration()),
 			}},
 		},
 	}
@@ -1123,9 +5099,20 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 			t.Parallel()
 
 			fakePinnipedClient := pinnipedfake.NewSimpleClientset(tt.inputUpstreams...)
-			pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(fakePinnipedClient, 0)
+			pinnipedInformers := pinnipedinformers.NewSharedInformerFactoryWithOptions(fakePinnipedClient, 0, pinnipedinformers.WithNamespace(testNamespace))
 			fakeKubeClient := fake.NewSimpleClientset(tt.inputSecrets...)
-			kubeInformers := informers.NewSharedInformerFactory(fakeKubeClient, 0)
+			kubeInformers := informers.NewSharedInformerFactoryWithOptions(fakeKubeClient, 0, informers.WithNamespace(testNamespace))
+			var additionalNamespaces []NamespaceInformers
+			var additionalNamespaceInformerFactories []interface{ Start(<-chan struct{}) }
+			for _, namespace := range tt.additionalNamespaces {
+				namespacePinnipedInformers := pinnipedinformers.NewSharedInformerFactoryWithOptions(fakePinnipedClient, 0, pinnipedinformers.WithNamespace(namespace))
+				namespaceKubeInformers := informers.NewSharedInformerFactoryWithOptions(fakeKubeClient, 0, informers.WithNamespace(namespace))
+				additionalNamespaces = append(additionalNamespaces, NamespaceInformers{
+					LDAPIdentityProviderInformer: namespacePinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
+					SecretInformer:               namespaceKubeInformers.Core().V1().Secrets(),
+				})
+				additionalNamespaceInformerFactories = append(additionalNamespaceInformerFactories, namespacePinnipedInformers, namespaceKubeInformers)
+			}
 			cache := provider.NewDynamicUpstreamIDPProvider()
 			cache.SetLDAPIdentityProviders([]provider.UpstreamLDAPIdentityProviderI{
 				upstreamldap.New(upstreamldap.ProviderConfig{Name: "initial-entry"}),
@@ -1146,6 +5133,9 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 						return nil, dialErr
 					}
 				}
+				if !tt.serverCertNotAfter.IsZero() {
+					return &connWithTLSState{MockConn: conn, notAfter: tt.serverCertNotAfter}, nil
+				}
 				return conn, nil
 			})}
 
@@ -1163,10 +5153,15 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 			controller := newInternal(
 				cache,
 				validatedSettingsCache,
+				upstreamwatchers.NewTestConnectionThrottle(clock.RealClock{}),
 				dialer,
 				fakePinnipedClient,
 				pinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				kubeInformers.Core().V1().Secrets(),
+				additionalNamespaces,
+				testServerCertExpiryWarningWindow,
+				testAuthenticationCacheTTL,
+				tt.skipStatusUpdates,
 				controllerlib.WithInformer,
 			)
 
@@ -1175,6 +5170,9 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 
 			pinnipedInformers.Start(ctx.Done())
 			kubeInformers.Start(ctx.Done())
+			for _, informerFactory := range additionalNamespaceInformerFactories {
+				informerFactory.Start(ctx.Done())
+			}
 			controllerlib.TestRunSynchronously(t, controller)
 
 			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{}}
@@ -1193,10 +5191,14 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				// The dialer that was passed in to the controller's constructor should always have been
 				// passed through to the provider.
 				copyOfExpectedValueForResultingCache.Dialer = dialer
+				// The connection pool settings should always be the defaults.
+				copyOfExpectedValueForResultingCache.ConnectionPool = upstreamwatchers.LDAPConnectionPoolConfig()
+				// The authentication cache settings should use the TTL that was passed in to the controller's constructor.
+				copyOfExpectedValueForResultingCache.AuthenticationCache = upstreamwatchers.LDAPAuthenticationCacheConfig(testAuthenticationCacheTTL)
 				require.Equal(t, copyOfExpectedValueForResultingCache, actualIDP.GetConfig())
 			}
 
-			actualUpstreams, err := fakePinnipedClient.IDPV1alpha1().LDAPIdentityProviders(testNamespace).List(ctx, metav1.ListOptions{})
+			actualUpstreams, err := fakePinnipedClient.IDPV1alpha1().LDAPIdentityProviders("").List(ctx, metav1.ListOptions{})
 			require.NoError(t, err)
 
 			// Assert on the expected Status of the upstreams. Preprocess the upstreams a bit so that they're easier to assert against.
@@ -1212,6 +5214,15 @@ func TestLDAPUpstreamWatcherControllerSync(t *testing.T) {
 				tt.wantValidatedSettings = map[string]upstreamwatchers.ValidatedSettings{}
 			}
 			require.Equal(t, tt.wantValidatedSettings, validatedSettingsCache.ValidatedSettingsByName)
+
+			if tt.wantSameProvidersAfterSecondSync {
+				require.NoError(t, controllerlib.TestSync(t, controller, syncCtx))
+				actualIDPListAfterSecondSync := cache.GetLDAPIdentityProviders()
+				require.Equal(t, len(actualIDPList), len(actualIDPListAfterSecondSync))
+				for i := range actualIDPList {
+					require.Same(t, actualIDPList[i], actualIDPListAfterSecondSync[i])
+				}
+			}
 		})
 	}
 }
@@ -1235,8 +5246,21 @@ func normalizeLDAPUpstreams(upstreams []v1alpha1.LDAPIdentityProvider, now metav
 	}
 
 	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
 		return result[i].Name < result[j].Name
 	})
 
 	return result
 }
+
+// connWithTLSState is a Conn which also describes its TLS connection state, like ldap.Conn does.
+type connWithTLSState struct {
+	*mockldapconn.MockConn
+	notAfter time.Time
+}
+
+func (c *connWithTLSState) TLSConnectionState() (tls.ConnectionState, bool) {
+	return tls.ConnectionState{PeerCertificates: []*x509.Certificate{{NotAfter: c.notAfter}}}, true
+}
@@ -5,13 +5,22 @@ package upstreamwatchers
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/go-ldap/ldap/v3"
 	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
 	"go.pinniped.dev/internal/constable"
@@ -30,14 +39,47 @@ const (
 	ErrNoCertificates = constable.Error("no certificates found")
 
 	LDAPBindAccountSecretType = corev1.SecretTypeBasicAuth
-	probeLDAPTimeout          = 90 * time.Second
+
+	// Settings for retrying the connection test when it fails due to a network error, so that a brief network
+	// problem does not flip the LDAPConnectionValid condition. The delay doubles after each failed attempt.
+	testConnectionMaxAttempts    = 3
+	testConnectionRetryBaseDelay = 500 * time.Millisecond
+
+	// Settings for throttling how often the server of each provider is probed when its settings were not
+	// validated yet, e.g. because the controller keeps requeuing. Failed probes may be retried sooner than
+	// successful probes are repeated, since the problem might have been fixed on the server in the meantime.
+	testConnectionMinIntervalAfterFailure = 10 * time.Second
+	testConnectionMinIntervalAfterSuccess = 5 * time.Minute
+
+	// Settings for reusing connections to the LDAP server across logins and refreshes. The idle timeout
+	// should be shorter than the idle timeouts typically used by LDAP servers to close connections. Concurrent
+	// logins and refreshes wait for a connection when the maximum number of connections to a host are open.
+	ldapConnectionPoolMaxConnections     = 20
+	ldapConnectionPoolMaxIdleConnections = 5
+	ldapConnectionPoolIdleTimeout        = time.Minute
+
+	// The maximum number of successful logins to remember for each LDAP or Active Directory provider when
+	// the authentication cache is enabled.
+	ldapAuthenticationCacheMaxEntries = 1000
+
+	// The number of levels of nested groups to resolve when nested group resolution is enabled without a depth.
+	defaultNestedGroupsMaxDepth = 10
 
 	// Constants related to conditions.
 	typeBindSecretValid              = "BindSecretValid"
 	typeTLSConfigurationValid        = "TLSConfigurationValid"
 	typeLDAPConnectionValid          = "LDAPConnectionValid"
+	typeServerCertExpiringSoon       = "LDAPServerCertificateExpiringSoon"
 	TypeSearchBaseFound              = "SearchBaseFound"
 	reasonLDAPConnectionError        = "LDAPConnectionError"
+	reasonLDAPProxyConnectionError   = "LDAPProxyConnectionError"
+	reasonLDAPBindError              = "LDAPBindError"
+	reasonLDAPOperationTimeout       = "LDAPOperationTimeout"
+	reasonInvalidBase64              = "InvalidBase64"
+	reasonNoCertificatesFound        = "NoCertificatesFound"
+	reasonUnparseableCertificate     = "UnparseableCertificate"
+	reasonInvalidBindDNTemplate      = "InvalidBindDNTemplate"
+	reasonServerCertExpiringSoon     = "ServerCertificateExpiringSoon"
 	noTLSConfigurationMessage        = "no TLS configuration provided"
 	loadedTLSConfigurationMessage    = "loaded TLS configuration"
 	ReasonUsingConfigurationFromSpec = "UsingConfigurationFromSpec"
@@ -46,21 +88,39 @@ const (
 
 // ValidatedSettings is the struct which is cached by the ValidatedSettingsCacheI interface.
 type ValidatedSettings struct {
-	IDPSpecGeneration         int64  // which IDP spec was used during the validation
-	BindSecretResourceVersion string // which bind secret was used during the validation
+	IDPSpecGeneration          int64  // which IDP spec was used during the validation
+	BindSecretResourceVersion  string // which bind secret was used during the validation
+	BindCredentialsFingerprint string // which bind credentials were used during the validation
 
-	// Cache the setting for TLS vs StartTLS. This is always auto-discovered by probing the server.
+	// Cache the setting for TLS vs StartTLS. This is either configured by the IDP spec, or else is
+	// auto-discovered by probing the server.
 	LDAPConnectionProtocol upstreamldap.LDAPConnectionProtocol
 
 	// Cache the settings for search bases. These could be configured by the IDP spec, or in the
 	// case of AD they can also be auto-discovered by probing the server.
 	UserSearchBase, GroupSearchBase string
 
+	// Cache which of the configured hosts was reached, so the controller can keep reporting it in the status.
+	ConnectionHost string
+
+	// Cache when the certificate presented by the server expires, so the controller can keep warning about it
+	// as it gets close to expiring without probing the server again. This is the zero time when it is not known.
+	ServerCertificateNotAfter time.Time
+
 	// Cache copies of the conditions that were computed when the above settings were cached, so we
 	// can keep writing them to the status in the future. This matters most when the first attempt
 	// to write them to the IDP's status fails. In this case, future Syncs calls will be able to
 	// use these cached values to try writing them again.
-	ConnectionValidCondition, SearchBaseFoundCondition *v1alpha1.Condition
+	ConnectionValidCondition, SearchBaseFoundCondition, UserSearchBaseValidCondition, GroupSearchValidCondition, UserSearchValidCondition *v1alpha1.Condition
+}
+
+// ValidatedConnection is a machine-readable description of a successful validation of the connection to an LDAP
+// or Active Directory server. It describes the same validation as the message of the LDAPConnectionValid condition.
+type ValidatedConnection struct {
+	IDPSpecGeneration         int64  // which IDP spec was used during the validation
+	BindSecretResourceVersion string // which bind secret was used during the validation, if any
+	Host                      string // which of the configured hosts was reached
+	BindUsername              string // the bind username after applying the bind DN template, or empty for anonymous bind
 }
 
 // ValidatedSettingsCacheI is an interface for an in-memory cache with an entry for each upstream
@@ -68,11 +128,12 @@ type ValidatedSettings struct {
 // secret for that upstream.
 type ValidatedSettingsCacheI interface {
 	// Get the cached settings for a given upstream at a given generation which was previously
-	// validated using a given bind secret version. If no settings have been cached for the
-	// upstream, or if the settings were cached at a different generation of the upstream or
-	// using a different version of the bind secret, then return false to indicate that the
-	// desired settings were not cached yet for that combination of spec generation and secret version.
-	Get(upstreamName, resourceVersion string, idpSpecGeneration int64) (ValidatedSettings, bool)
+	// validated using a given bind secret version and bind credentials. If no settings have been cached
+	// for the upstream, or if the settings were cached at a different generation of the upstream or
+	// using a different version of the bind secret or different bind credentials, then return false to
+	// indicate that the desired settings were not cached yet for that combination of spec generation,
+	// secret version, and credentials.
+	Get(upstreamName, resourceVersion, bindCredentialsFingerprint string, idpSpecGeneration int64) (ValidatedSettings, bool)
 
 	// Set some settings into the cache for a given upstream.
 	Set(upstreamName string, settings ValidatedSettings)
@@ -86,9 +147,12 @@ func NewValidatedSettingsCache() ValidatedSettingsCacheI {
 	return &ValidatedSettingsCache{ValidatedSettingsByName: map[string]ValidatedSettings{}}
 }
 
-func (s *ValidatedSettingsCache) Get(upstreamName, resourceVersion string, idpSpecGeneration int64) (ValidatedSettings, bool) {
+func (s *ValidatedSettingsCache) Get(upstreamName, resourceVersion, bindCredentialsFingerprint string, idpSpecGeneration int64) (ValidatedSettings, bool) {
 	validatedSettings, found := s.ValidatedSettingsByName[upstreamName]
-	if found && validatedSettings.BindSecretResourceVersion == resourceVersion && validatedSettings.IDPSpecGeneration == idpSpecGeneration {
+	if found &&
+		validatedSettings.BindSecretResourceVersion == resourceVersion &&
+		validatedSettings.BindCredentialsFingerprint == bindCredentialsFingerprint &&
+		validatedSettings.IDPSpecGeneration == idpSpecGeneration {
 		return validatedSettings, true
 	}
 	return ValidatedSettings{}, false
@@ -98,6 +162,176 @@ func (s *ValidatedSettingsCache) Set(upstreamName string, settings ValidatedSett
 	s.ValidatedSettingsByName[upstreamName] = settings
 }
 
+// TestConnectionThrottle remembers the outcome of the most recent probe of the server of each upstream provider,
+// so that the server is not probed again for the same settings until a minimum interval has passed.
+// This complements the ValidatedSettingsCacheI, which only remembers fully successful validations, and which
+// forgets them whenever the resource version of the bind Secret changes, even when its contents did not change.
+type TestConnectionThrottle struct {
+	clock        clock.PassiveClock
+	recentProbes map[string]recentProbe
+}
+
+type recentProbe struct {
+	probedAt          time.Time
+	idpSpecGeneration int64
+	configFingerprint [sha256.Size]byte
+	succeeded         bool
+	settings          ValidatedSettings
+}
+
+func NewTestConnectionThrottle(clock clock.PassiveClock) *TestConnectionThrottle {
+	return &TestConnectionThrottle{clock: clock, recentProbes: map[string]recentProbe{}}
+}
+
+// Get returns the settings from the most recent probe of the upstream's server when that probe used the same
+// spec generation and the same bind credentials and CA bundle, and when it happened recently enough that the
+// server should not be probed again yet. Otherwise, it returns false to indicate that the server should be probed.
+func (t *TestConnectionThrottle) Get(upstreamName string, idpSpecGeneration int64, config *upstreamldap.ProviderConfig) (ValidatedSettings, bool) {
+	probe, found := t.recentProbes[upstreamName]
+	if !found || probe.idpSpecGeneration != idpSpecGeneration || probe.configFingerprint != probeConfigFingerprint(config) {
+		return ValidatedSettings{}, false
+	}
+	minInterval := testConnectionMinIntervalAfterFailure
+	if probe.succeeded {
+		minInterval = testConnectionMinIntervalAfterSuccess
+	}
+	if t.clock.Since(probe.probedAt) >= minInterval {
+		return ValidatedSettings{}, false
+	}
+	return probe.settings, true
+}
+
+// Set remembers the outcome of a probe of the upstream's server which was made now.
+func (t *TestConnectionThrottle) Set(upstreamName string, idpSpecGeneration int64, config *upstreamldap.ProviderConfig, succeeded bool, settings ValidatedSettings) {
+	t.recentProbes[upstreamName] = recentProbe{
+		probedAt:          t.clock.Now(),
+		idpSpecGeneration: idpSpecGeneration,
+		configFingerprint: probeConfigFingerprint(config),
+		succeeded:         succeeded,
+		settings:          settings,
+	}
+}
+
+// probeConfigFingerprint identifies the settings which can change the outcome of a probe without changing the
+// generation of the provider's spec, without keeping a copy of the bind password.
+func probeConfigFingerprint(config *upstreamldap.ProviderConfig) [sha256.Size]byte {
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(config.BindUsername), []byte(config.BindPassword), config.CABundle} {
+		_, _ = fmt.Fprintf(h, "%d:", len(part))
+		_, _ = h.Write(part)
+	}
+	var fingerprint [sha256.Size]byte
+	copy(fingerprint[:], h.Sum(nil))
+	return fingerprint
+}
+
+// ProviderCache remembers the Provider which was most recently loaded into the cache of upstream providers for each
+// upstream provider, so that a sync which validates the same settings again keeps using the same Provider. Otherwise
+// every sync would throw away the pooled connections and the recent authentications of the Provider.
+type ProviderCache struct {
+	providers map[string]cachedProvider
+}
+
+type cachedProvider struct {
+	configFingerprint [sha256.Size]byte
+	provider          *upstreamldap.Provider
+}
+
+func NewProviderCache() *ProviderCache {
+	return &ProviderCache{providers: map[string]cachedProvider{}}
+}
+
+// GetOrCreate returns the previous Provider of the upstream when it was created with the same config, or else
+// creates a new Provider and closes the connections of the previous one.
+func (c *ProviderCache) GetOrCreate(config *upstreamldap.ProviderConfig) *upstreamldap.Provider {
+	fingerprint := providerConfigFingerprint(config)
+	previous, found := c.providers[config.Name]
+	if found && previous.configFingerprint == fingerprint {
+		return previous.provider
+	}
+	if found {
+		previous.provider.Close()
+	}
+	ldapProvider := upstreamldap.New(*config)
+	c.providers[config.Name] = cachedProvider{configFingerprint: fingerprint, provider: ldapProvider}
+	return ldapProvider
+}
+
+// DeleteAllExcept forgets the previous Providers of all upstreams which are not named, e.g. because they were
+// deleted or became invalid, and closes their connections.
+func (c *ProviderCache) DeleteAllExcept(upstreamNames sets.String) {
+	for upstreamName, previous := range c.providers {
+		if !upstreamNames.Has(upstreamName) {
+			previous.provider.Close()
+			delete(c.providers, upstreamName)
+		}
+	}
+}
+
+// providerConfigFingerprint identifies all the settings of a Provider, without keeping a copy of the bind password.
+// The functions of the config are only identified by the attributes to which they apply, because the controllers
+// create them again for each sync.
+func providerConfigFingerprint(config *upstreamldap.ProviderConfig) [sha256.Size]byte {
+	withoutFuncs := *config
+	withoutFuncs.UIDAttributeParsingOverrides = nil
+	withoutFuncs.GroupAttributeParsingOverrides = nil
+	withoutFuncs.RefreshAttributeChecks = nil
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%#v", withoutFuncs)
+	for _, attributeNames := range [][]string{
+		sets.StringKeySet(config.UIDAttributeParsingOverrides).List(),
+		sets.StringKeySet(config.GroupAttributeParsingOverrides).List(),
+		sets.StringKeySet(config.RefreshAttributeChecks).List(),
+	} {
+		_, _ = fmt.Fprintf(h, "%q", attributeNames)
+	}
+	var fingerprint [sha256.Size]byte
+	copy(fingerprint[:], h.Sum(nil))
+	return fingerprint
+}
+
+// BindCredentialsFingerprint returns a hex encoded SHA-256 hash of the bind username and password. It is part of the
+// key of the ValidatedSettingsCacheI, so that a change of the bind credentials always causes the settings to be
+// validated again, even when an external secret manager updates the bind Secret without changing its resource
+// version. It is only kept in memory, and it is never written to the status of the upstream.
+func BindCredentialsFingerprint(bindUsername, bindPassword string) string {
+	h := sha256.New()
+	for _, part := range []string{bindUsername, bindPassword} {
+		_, _ = fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LDAPConnectionPoolConfig returns the connection pooling settings to use for LDAP and Active Directory providers.
+func LDAPConnectionPoolConfig() upstreamldap.ConnectionPoolConfig {
+	return upstreamldap.ConnectionPoolConfig{
+		MaxConnections:     ldapConnectionPoolMaxConnections,
+		MaxIdleConnections: ldapConnectionPoolMaxIdleConnections,
+		IdleTimeout:        ldapConnectionPoolIdleTimeout,
+	}
+}
+
+// LDAPAuthenticationCacheConfig returns the authentication cache settings to use for LDAP and Active Directory
+// providers. A zero ttl disables the cache.
+func LDAPAuthenticationCacheConfig(ttl time.Duration) upstreamldap.AuthenticationCacheConfig {
+	return upstreamldap.AuthenticationCacheConfig{
+		TTL:        ttl,
+		MaxEntries: ldapAuthenticationCacheMaxEntries,
+	}
+}
+
+// NestedGroupsMaxDepth returns the number of levels of nested groups that LDAP and Active Directory providers should
+// resolve, based on the settings of the group search. It returns zero when nested groups should not be resolved.
+func NestedGroupsMaxDepth(resolveNestedGroups bool, maxDepth int32) int {
+	if !resolveNestedGroups {
+		return 0
+	}
+	if maxDepth <= 0 {
+		return defaultNestedGroupsMaxDepth
+	}
+	return int(maxDepth)
+}
+
 // UpstreamGenericLDAPIDP is a read-only interface for abstracting the differences between LDAP and Active Directory IDP types.
 type UpstreamGenericLDAPIDP interface {
 	Spec() UpstreamGenericLDAPSpec
@@ -110,10 +344,16 @@ type UpstreamGenericLDAPIDP interface {
 type UpstreamGenericLDAPSpec interface {
 	Host() string
 	TLSSpec() *v1alpha1.TLSSpec
+	ConnectionProtocol() v1alpha1.LDAPConnectionProtocol
 	BindSecretName() string
+	BindDNTemplate() string
+	AllowAnonymousBind() bool
 	UserSearch() UpstreamGenericLDAPUserSearch
 	GroupSearch() UpstreamGenericLDAPGroupSearch
 	DetectAndSetSearchBase(ctx context.Context, config *upstreamldap.ProviderConfig) *v1alpha1.Condition
+	ValidateUserSearchBase(ctx context.Context, config *upstreamldap.ProviderConfig) *v1alpha1.Condition
+	DryRunGroupSearch(ctx context.Context, config *upstreamldap.ProviderConfig) *v1alpha1.Condition
+	DryRunUserSearch(ctx context.Context, config *upstreamldap.ProviderConfig) *v1alpha1.Condition
 }
 
 type UpstreamGenericLDAPUserSearch interface {
@@ -143,40 +383,162 @@ func ValidateTLSConfig(tlsSpec *v1alpha1.TLSSpec, config *upstreamldap.ProviderC
 
 	bundle, err := base64.StdEncoding.DecodeString(tlsSpec.CertificateAuthorityData)
 	if err != nil {
-		return invalidTLSCondition(fmt.Sprintf("certificateAuthorityData is invalid: %s", err.Error()))
+		return invalidTLSCondition(reasonInvalidBase64, fmt.Sprintf("certificateAuthorityData is invalid: %s", err.Error()))
 	}
 
 	ca := x509.NewCertPool()
 	ok := ca.AppendCertsFromPEM(bundle)
 	if !ok {
-		return invalidTLSCondition(fmt.Sprintf("certificateAuthorityData is invalid: %s", ErrNoCertificates))
+		// AppendCertsFromPEM skips the certificates which it cannot parse, so find out whether there were any.
+		if err := certificateParseError(bundle); err != nil {
+			return invalidTLSCondition(reasonUnparseableCertificate, fmt.Sprintf("certificateAuthorityData is invalid: %s", err.Error()))
+		}
+		return invalidTLSCondition(reasonNoCertificatesFound, fmt.Sprintf("certificateAuthorityData is invalid: %s", ErrNoCertificates))
 	}
 
 	config.CABundle = bundle
 	return validTLSCondition(loadedTLSConfigurationMessage)
 }
 
+// TestConnection tests the connection to the LDAP server and sets the ConnectionProtocol in the config.
+// When the connectionProtocol is empty, it tries TLS first, falling back to StartTLS, and sets whichever worked.
+// Otherwise, only the given connectionProtocol is tested. When the test succeeds, it also returns the result of
+// the test, which tells which host was reached and when the certificate presented by the server expires.
+// The result is nil when the test fails.
 func TestConnection(
 	ctx context.Context,
 	bindSecretName string,
+	connectionProtocol v1alpha1.LDAPConnectionProtocol,
 	config *upstreamldap.ProviderConfig,
 	currentSecretVersion string,
-) *v1alpha1.Condition {
+) (*v1alpha1.Condition, *upstreamldap.TestConnectionResult) {
+	result, err := retryOnNetworkError(ctx, config.Host, func() (*upstreamldap.TestConnectionResult, error) {
+		if connectionProtocol != "" {
+			// Only try the protocol which was chosen by the spec.
+			config.ConnectionProtocol = upstreamldap.LDAPConnectionProtocol(connectionProtocol)
+			return upstreamldap.New(*config).TestConnectionWithResult(ctx)
+		}
+		return testConnectionWithTLSOrStartTLS(ctx, config)
+	})
+	connectedHost := result.Host
+
+	if err != nil && upstreamldap.IsProxyError(err) {
+		// Report failures to connect through the proxy separately, since they are not a problem with the LDAP server.
+		return &v1alpha1.Condition{
+			Type:    typeLDAPConnectionValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonLDAPProxyConnectionError,
+			Message: fmt.Sprintf(`could not connect to "%s" through the proxy: %s`, config.Host, err.Error()),
+		}, nil
+	}
+
+	if config.AnonymousBind {
+		if err != nil {
+			return &v1alpha1.Condition{
+				Type:    typeLDAPConnectionValid,
+				Status:  v1alpha1.ConditionFalse,
+				Reason:  connectionErrorReason(err),
+				Message: fmt.Sprintf(`could not successfully connect to "%s" and search anonymously: %s`, config.Host, err.Error()),
+			}, nil
+		}
+
+		return &v1alpha1.Condition{
+			Type:    typeLDAPConnectionValid,
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  ReasonSuccess,
+			Message: fmt.Sprintf(`successfully able to connect to "%s" and search anonymously`, connectedHost),
+		}, result
+	}
+
+	if err != nil {
+		return &v1alpha1.Condition{
+			Type:   typeLDAPConnectionValid,
+			Status: v1alpha1.ConditionFalse,
+			Reason: connectionErrorReason(err),
+			Message: fmt.Sprintf(`could not successfully connect to "%s" and bind as user "%s": %s`,
+				config.Host, config.BindUsername, err.Error()),
+		}, nil
+	}
+
+	return &v1alpha1.Condition{
+		Type:   typeLDAPConnectionValid,
+		Status: v1alpha1.ConditionTrue,
+		Reason: ReasonSuccess,
+		Message: fmt.Sprintf(`successfully able to connect to "%s" and bind as user "%s" [validated with Secret "%s" at version "%s"]`,
+			connectedHost, config.BindUsername, bindSecretName, currentSecretVersion),
+	}, result
+}
+
+// connectionErrorReason returns the reason of the LDAPConnectionValid condition when testing the connection failed.
+func connectionErrorReason(err error) string {
+	if errors.Is(err, upstreamldap.ErrOperationTimeout) {
+		// The server was reached, but it stopped responding.
+		return reasonLDAPOperationTimeout
+	}
+	if errors.Is(err, upstreamldap.ErrBindFailed) {
+		// The server was reached, but it rejected the bind.
+		return reasonLDAPBindError
+	}
+	return reasonLDAPConnectionError
+}
+
+// retryOnNetworkError calls testConnection until it succeeds, fails with an error which is not a network error,
+// runs out of attempts, or the context is done. It returns the result of the final attempt. Operation timeouts are
+// not retried, since a server which stopped responding would only make each attempt wait for the timeout again.
+func retryOnNetworkError(
+	ctx context.Context,
+	host string,
+	testConnection func() (*upstreamldap.TestConnectionResult, error),
+) (*upstreamldap.TestConnectionResult, error) {
+	delay := testConnectionRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		result, err := testConnection()
+		if err == nil || attempt == testConnectionMaxAttempts || !isNetworkError(err) || errors.Is(err, upstreamldap.ErrOperationTimeout) {
+			return result, err
+		}
+		plog.InfoErr("testing LDAP connection failed due to a network error, so trying again", err,
+			"host", host, "attempt", attempt, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isNetworkError returns true when the error, or every error in an aggregate error, is an LDAP network error.
+func isNetworkError(err error) bool {
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		for _, e := range aggregate.Errors() {
+			if !isNetworkError(e) {
+				return false
+			}
+		}
+		return len(aggregate.Errors()) > 0
+	}
+	var ldapErr *ldap.Error
+	return errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.ErrorNetwork
+}
+
+func testConnectionWithTLSOrStartTLS(ctx context.Context, config *upstreamldap.ProviderConfig) (*upstreamldap.TestConnectionResult, error) {
 	// First try using TLS.
 	config.ConnectionProtocol = upstreamldap.TLS
 	tlsLDAPProvider := upstreamldap.New(*config)
-	err := tlsLDAPProvider.TestConnection(ctx)
+	result, err := tlsLDAPProvider.TestConnectionWithResult(ctx)
 	if err != nil {
 		plog.InfoErr("testing LDAP connection using TLS failed, so trying again with StartTLS", err, "host", config.Host)
 		// If there was any error, try again with StartTLS instead.
 		config.ConnectionProtocol = upstreamldap.StartTLS
 		startTLSLDAPProvider := upstreamldap.New(*config)
-		startTLSErr := startTLSLDAPProvider.TestConnection(ctx)
+		startTLSResult, startTLSErr := startTLSLDAPProvider.TestConnectionWithResult(ctx)
 		if startTLSErr == nil {
 			plog.Info("testing LDAP connection using StartTLS succeeded", "host", config.Host)
 			// Successfully able to fall back to using StartTLS, so clear the original
 			// error and consider the connection test to be successful.
 			err = nil
+			result = startTLSResult
 		} else {
 			plog.InfoErr("testing LDAP connection using StartTLS also failed", err, "host", config.Host)
 			// Falling back to StartTLS also failed, so put TLS back into the config
@@ -184,24 +546,7 @@ func TestConnection(
 			config.ConnectionProtocol = upstreamldap.TLS
 		}
 	}
-
-	if err != nil {
-		return &v1alpha1.Condition{
-			Type:   typeLDAPConnectionValid,
-			Status: v1alpha1.ConditionFalse,
-			Reason: reasonLDAPConnectionError,
-			Message: fmt.Sprintf(`could not successfully connect to "%s" and bind as user "%s": %s`,
-				config.Host, config.BindUsername, err.Error()),
-		}
-	}
-
-	return &v1alpha1.Condition{
-		Type:   typeLDAPConnectionValid,
-		Status: v1alpha1.ConditionTrue,
-		Reason: ReasonSuccess,
-		Message: fmt.Sprintf(`successfully able to connect to "%s" and bind as user "%s" [validated with Secret "%s" at version "%s"]`,
-			config.Host, config.BindUsername, bindSecretName, currentSecretVersion),
-	}
+	return result, err
 }
 
 func validTLSCondition(message string) *v1alpha1.Condition {
@@ -213,16 +558,53 @@ func validTLSCondition(message string) *v1alpha1.Condition {
 	}
 }
 
-func invalidTLSCondition(message string) *v1alpha1.Condition {
+func invalidTLSCondition(reason string, message string) *v1alpha1.Condition {
 	return &v1alpha1.Condition{
 		Type:    typeTLSConfigurationValid,
 		Status:  v1alpha1.ConditionFalse,
-		Reason:  ReasonInvalidTLSConfig,
+		Reason:  reason,
 		Message: message,
 	}
 }
 
-func ValidateSecret(secretInformer corev1informers.SecretInformer, secretName string, secretNamespace string, config *upstreamldap.ProviderConfig) (*v1alpha1.Condition, string) {
+// certificateParseError returns the error from parsing the first PEM-encoded certificate in the bundle which
+// cannot be parsed, or nil when all of them can be parsed.
+func certificateParseError(bundle []byte) error {
+	for rest := bundle; len(rest) > 0; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil
+		}
+		if block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+			continue // AppendCertsFromPEM ignores these blocks too
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSecret loads the bind username and password from the referenced Secret into the config. When anonymous
+// bind is allowed, the Secret may be omitted or may be missing its keys, and the config is set to bind anonymously.
+func ValidateSecret(
+	secretInformer corev1informers.SecretInformer,
+	secretName string,
+	secretNamespace string,
+	allowAnonymousBind bool,
+	config *upstreamldap.ProviderConfig,
+) (*v1alpha1.Condition, string) {
+	if allowAnonymousBind && len(secretName) == 0 {
+		config.AnonymousBind = true
+		return &v1alpha1.Condition{
+			Type:    typeBindSecretValid,
+			Status:  v1alpha1.ConditionTrue,
+			Reason:  ReasonSuccess,
+			Message: "no bind secret is needed because anonymous bind is allowed",
+		}, ""
+	}
+
 	secret, err := secretInformer.Lister().Secrets(secretNamespace).Get(secretName)
 	if err != nil {
 		return &v1alpha1.Condition{
@@ -233,19 +615,33 @@ func ValidateSecret(secretInformer corev1informers.SecretInformer, secretName st
 		}, ""
 	}
 
-	if secret.Type != corev1.SecretTypeBasicAuth {
+	// Prefer the BasicAuth type, but also accept Opaque Secrets with the same keys, since those are commonly
+	// produced by tools which sync Secrets from external secret stores.
+	if secret.Type != corev1.SecretTypeBasicAuth && secret.Type != corev1.SecretTypeOpaque {
 		return &v1alpha1.Condition{
 			Type:   typeBindSecretValid,
 			Status: v1alpha1.ConditionFalse,
 			Reason: ReasonWrongType,
-			Message: fmt.Sprintf("referenced Secret %q has wrong type %q (should be %q)",
-				secretName, secret.Type, corev1.SecretTypeBasicAuth),
+			Message: fmt.Sprintf("referenced Secret %q has wrong type %q (should be %q or %q)",
+				secretName, secret.Type, corev1.SecretTypeBasicAuth, corev1.SecretTypeOpaque),
 		}, secret.ResourceVersion
 	}
 
 	config.BindUsername = string(secret.Data[corev1.BasicAuthUsernameKey])
 	config.BindPassword = string(secret.Data[corev1.BasicAuthPasswordKey])
 	if len(config.BindUsername) == 0 || len(config.BindPassword) == 0 {
+		if allowAnonymousBind {
+			config.BindUsername = ""
+			config.BindPassword = ""
+			config.AnonymousBind = true
+			return &v1alpha1.Condition{
+				Type:   typeBindSecretValid,
+				Status: v1alpha1.ConditionTrue,
+				Reason: ReasonSuccess,
+				Message: fmt.Sprintf("referenced Secret %q is missing keys %q, so anonymous bind will be used",
+					secretName, []string{corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey}),
+			}, secret.ResourceVersion
+		}
 		return &v1alpha1.Condition{
 			Type:   typeBindSecretValid,
 			Status: v1alpha1.ConditionFalse,
@@ -259,10 +655,41 @@ func ValidateSecret(secretInformer corev1informers.SecretInformer, secretName st
 		Type:    typeBindSecretValid,
 		Status:  v1alpha1.ConditionTrue,
 		Reason:  ReasonSuccess,
-		Message: "loaded bind secret",
+		Message: fmt.Sprintf("loaded bind secret of type %q", secret.Type),
 	}, secret.ResourceVersion
 }
 
+// ValidateBindDNTemplate checks that the bind DN template, when there is one, contains the "{}" placeholder, and then
+// replaces the bind username which was loaded from the bind Secret with the resulting DN. It returns nil when there
+// is no bind DN template, or when there is no bind username because of anonymous bind.
+func ValidateBindDNTemplate(bindDNTemplate string, config *upstreamldap.ProviderConfig) *v1alpha1.Condition {
+	if len(bindDNTemplate) == 0 || config.AnonymousBind {
+		return nil
+	}
+
+	if !strings.Contains(bindDNTemplate, "{}") {
+		return &v1alpha1.Condition{
+			Type:    typeBindSecretValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonInvalidBindDNTemplate,
+			Message: fmt.Sprintf(`bind.bindDNTemplate %q must contain the "{}" placeholder`, bindDNTemplate),
+		}
+	}
+
+	bindDN := upstreamldap.ExpandDNTemplate(bindDNTemplate, config.BindUsername)
+	if _, err := ldap.ParseDN(bindDN); err != nil {
+		return &v1alpha1.Condition{
+			Type:    typeBindSecretValid,
+			Status:  v1alpha1.ConditionFalse,
+			Reason:  reasonInvalidBindDNTemplate,
+			Message: fmt.Sprintf(`bind.bindDNTemplate %q does not form a valid distinguished name: %s`, bindDNTemplate, err.Error()),
+		}
+	}
+	config.BindUsername = bindDN
+
+	return nil
+}
+
 // gradatedCondition is a condition and a boolean that tells you whether the condition is fatal or just a warning.
 type gradatedCondition struct {
 	condition *v1alpha1.Condition
@@ -286,84 +713,213 @@ func (g *GradatedConditions) Append(condition *v1alpha1.Condition, isFatal bool)
 	g.gradatedConditions = append(g.gradatedConditions, gradatedCondition{condition: condition, isFatal: isFatal})
 }
 
+// ValidateGenericLDAP validates the settings of an LDAP or Active Directory provider and returns the resulting
+// conditions. When the connection to the server was validated, it also describes that validation, or else it
+// returns nil for the ValidatedConnection. The validated settings are remembered by the name of the config, which
+// is unique among the providers of the same type even when they are watched in several namespaces.
 func ValidateGenericLDAP(
 	ctx context.Context,
 	upstream UpstreamGenericLDAPIDP,
 	secretInformer corev1informers.SecretInformer,
 	validatedSettingsCache ValidatedSettingsCacheI,
+	testConnectionThrottle *TestConnectionThrottle,
 	config *upstreamldap.ProviderConfig,
-) GradatedConditions {
+	serverCertExpiryWarningWindow time.Duration,
+) (GradatedConditions, *ValidatedConnection) {
 	conditions := GradatedConditions{}
 
-	secretValidCondition, currentSecretVersion := ValidateSecret(secretInformer, upstream.Spec().BindSecretName(), upstream.Namespace(), config)
+	secretValidCondition, currentSecretVersion := ValidateSecret(secretInformer, upstream.Spec().BindSecretName(), upstream.Namespace(), upstream.Spec().AllowAnonymousBind(), config)
+	if secretValidCondition.Status == v1alpha1.ConditionTrue {
+		if bindDNTemplateCondition := ValidateBindDNTemplate(upstream.Spec().BindDNTemplate(), config); bindDNTemplateCondition != nil {
+			secretValidCondition = bindDNTemplateCondition
+		}
+	}
 	conditions.Append(secretValidCondition, true)
 
 	tlsValidCondition := ValidateTLSConfig(upstream.Spec().TLSSpec(), config)
 	conditions.Append(tlsValidCondition, true)
 
-	var ldapConnectionValidCondition, searchBaseFoundCondition *v1alpha1.Condition
+	var ldapConnectionValidCondition, searchBaseFoundCondition, userSearchBaseValidCondition, groupSearchValidCondition, userSearchValidCondition *v1alpha1.Condition
+	var serverCertNotAfter time.Time
+	var connectedHost string
+	var validatedConnection *ValidatedConnection
 	// No point in trying to connect to the server if the config was already determined to be invalid.
 	if secretValidCondition.Status == v1alpha1.ConditionTrue && tlsValidCondition.Status == v1alpha1.ConditionTrue {
-		ldapConnectionValidCondition, searchBaseFoundCondition = validateAndSetLDAPServerConnectivityAndSearchBase(ctx, validatedSettingsCache, upstream, config, currentSecretVersion)
+		ldapConnectionValidCondition, searchBaseFoundCondition, userSearchBaseValidCondition, groupSearchValidCondition, userSearchValidCondition, serverCertNotAfter, connectedHost = validateAndSetLDAPServerConnectivityAndSearchBase(ctx, validatedSettingsCache, testConnectionThrottle, upstream, config, currentSecretVersion)
 		conditions.Append(ldapConnectionValidCondition, false)
+		if ldapConnectionValidCondition.Status == v1alpha1.ConditionTrue {
+			validatedConnection = &ValidatedConnection{
+				IDPSpecGeneration:         upstream.Generation(),
+				BindSecretResourceVersion: currentSecretVersion,
+				Host:                      connectedHost,
+				BindUsername:              config.BindUsername,
+			}
+		}
+		if serverCertExpiringSoonCondition := ServerCertificateExpiringSoon(serverCertNotAfter, serverCertExpiryWarningWindow); serverCertExpiringSoonCondition != nil {
+			conditions.Append(serverCertExpiringSoonCondition, false)
+		}
 		if searchBaseFoundCondition != nil { // currently, only used for AD, so may be nil
 			conditions.Append(searchBaseFoundCondition, true)
 		}
+		if userSearchBaseValidCondition != nil { // currently, only used for LDAP, so may be nil
+			conditions.Append(userSearchBaseValidCondition, false)
+		}
+		if groupSearchValidCondition != nil { // currently, only used for LDAP, so may be nil
+			conditions.Append(groupSearchValidCondition, false)
+		}
+		if userSearchValidCondition != nil { // currently, only used for LDAP when a dry run username is configured, so may be nil
+			conditions.Append(userSearchValidCondition, false)
+		}
 	}
-	return conditions
+	return conditions, validatedConnection
 }
 
 func validateAndSetLDAPServerConnectivityAndSearchBase(
 	ctx context.Context,
 	validatedSettingsCache ValidatedSettingsCacheI,
+	testConnectionThrottle *TestConnectionThrottle,
 	upstream UpstreamGenericLDAPIDP,
 	config *upstreamldap.ProviderConfig,
 	currentSecretVersion string,
-) (*v1alpha1.Condition, *v1alpha1.Condition) {
-	validatedSettings, hasPreviousValidatedSettings := validatedSettingsCache.Get(upstream.Name(), currentSecretVersion, upstream.Generation())
-	var ldapConnectionValidCondition, searchBaseFoundCondition *v1alpha1.Condition
+) (*v1alpha1.Condition, *v1alpha1.Condition, *v1alpha1.Condition, *v1alpha1.Condition, *v1alpha1.Condition, time.Time, string) {
+	bindCredentialsFingerprint := BindCredentialsFingerprint(config.BindUsername, config.BindPassword)
+	validatedSettings, hasPreviousValidatedSettings := validatedSettingsCache.Get(config.Name, currentSecretVersion, bindCredentialsFingerprint, upstream.Generation())
+	usePreviousSettings := hasPreviousValidatedSettings && validatedSettings.UserSearchBase != "" && validatedSettings.GroupSearchBase != ""
+	if !usePreviousSettings {
+		if recentSettings, probedRecently := testConnectionThrottle.Get(config.Name, upstream.Generation(), config); probedRecently {
+			plog.Debug("the LDAP server was probed recently with the same settings, so reusing the outcome of that probe",
+				"upstreamName", upstream.Name(), "host", config.Host)
+			validatedSettings, usePreviousSettings = recentSettings, true
+		}
+	}
+	var ldapConnectionValidCondition, searchBaseFoundCondition, userSearchBaseValidCondition, groupSearchValidCondition, userSearchValidCondition *v1alpha1.Condition
+	var serverCertNotAfter time.Time
+	var connectedHost string
 
-	if hasPreviousValidatedSettings && validatedSettings.UserSearchBase != "" && validatedSettings.GroupSearchBase != "" {
-		// Found previously validated settings in the cache (which is also not missing search base fields), so use them.
+	if usePreviousSettings {
+		// Found previously validated settings in the cache (which is also not missing search base fields), or the
+		// outcome of a recent probe of the server with the same settings, so use them.
 		config.ConnectionProtocol = validatedSettings.LDAPConnectionProtocol
 		config.UserSearch.Base = validatedSettings.UserSearchBase
 		config.GroupSearch.Base = validatedSettings.GroupSearchBase
 		ldapConnectionValidCondition = validatedSettings.ConnectionValidCondition.DeepCopy()
 		searchBaseFoundCondition = validatedSettings.SearchBaseFoundCondition.DeepCopy()
+		userSearchBaseValidCondition = validatedSettings.UserSearchBaseValidCondition.DeepCopy()
+		groupSearchValidCondition = validatedSettings.GroupSearchValidCondition.DeepCopy()
+		userSearchValidCondition = validatedSettings.UserSearchValidCondition.DeepCopy()
+		serverCertNotAfter = validatedSettings.ServerCertificateNotAfter
+		connectedHost = validatedSettings.ConnectionHost
 	} else {
 		// Did not find previously validated settings in the cache, so probe the LDAP server.
+		probeLDAPTimeout := config.ConnectionTimeout
+		if probeLDAPTimeout == 0 {
+			probeLDAPTimeout = upstreamldap.DefaultConnectionTimeout
+		}
 		testConnectionTimeout, cancelFunc := context.WithTimeout(ctx, probeLDAPTimeout)
 		defer cancelFunc()
-		ldapConnectionValidCondition = TestConnection(testConnectionTimeout, upstream.Spec().BindSecretName(), config, currentSecretVersion)
+		var connectionResult *upstreamldap.TestConnectionResult
+		ldapConnectionValidCondition, connectionResult = TestConnection(testConnectionTimeout, upstream.Spec().BindSecretName(), upstream.Spec().ConnectionProtocol(), config, currentSecretVersion)
+		if connectionResult != nil {
+			serverCertNotAfter = connectionResult.ServerCertificateNotAfter
+			connectedHost = connectionResult.Host
+		}
 
 		searchBaseTimeout, cancelFunc := context.WithTimeout(ctx, probeLDAPTimeout)
 		defer cancelFunc()
 		searchBaseFoundCondition = upstream.Spec().DetectAndSetSearchBase(searchBaseTimeout, config)
 
-		// When there were no failures, write the newly validated settings to the cache.
-		// It's okay for the search base condition to be nil, since it's only used by Active Directory providers,
-		// but if it exists make sure it was not a failure.
+		// Only check the user search base and try the group search after the connection and the search base
+		// are known to be good, since they depend on both of them.
 		if ldapConnectionValidCondition.Status == v1alpha1.ConditionTrue &&
 			(searchBaseFoundCondition == nil || (searchBaseFoundCondition.Status == v1alpha1.ConditionTrue)) {
+			userSearchBaseTimeout, cancelFunc := context.WithTimeout(ctx, probeLDAPTimeout)
+			defer cancelFunc()
+			userSearchBaseValidCondition = upstream.Spec().ValidateUserSearchBase(userSearchBaseTimeout, config)
+
+			groupSearchTimeout, cancelFunc := context.WithTimeout(ctx, probeLDAPTimeout)
+			defer cancelFunc()
+			groupSearchValidCondition = upstream.Spec().DryRunGroupSearch(groupSearchTimeout, config)
+
+			userSearchTimeout, cancelFunc := context.WithTimeout(ctx, probeLDAPTimeout)
+			defer cancelFunc()
+			userSearchValidCondition = upstream.Spec().DryRunUserSearch(userSearchTimeout, config)
+		}
+
+		newSettings := ValidatedSettings{
+			IDPSpecGeneration:            upstream.Generation(),
+			BindSecretResourceVersion:    currentSecretVersion,
+			BindCredentialsFingerprint:   bindCredentialsFingerprint,
+			LDAPConnectionProtocol:       config.ConnectionProtocol,
+			UserSearchBase:               config.UserSearch.Base,
+			GroupSearchBase:              config.GroupSearch.Base,
+			ConnectionHost:               connectedHost,
+			ServerCertificateNotAfter:    serverCertNotAfter,
+			ConnectionValidCondition:     ldapConnectionValidCondition.DeepCopy(),
+			SearchBaseFoundCondition:     searchBaseFoundCondition.DeepCopy(),     // currently, only used for AD, so may be nil
+			UserSearchBaseValidCondition: userSearchBaseValidCondition.DeepCopy(), // currently, only used for LDAP, so may be nil
+			GroupSearchValidCondition:    groupSearchValidCondition.DeepCopy(),    // currently, only used for LDAP, so may be nil
+			UserSearchValidCondition:     userSearchValidCondition.DeepCopy(),     // currently, only used for LDAP, so may be nil
+		}
+
+		// It's okay for the search base, user search base, and group search conditions to be nil, since they are
+		// each only used by one type of provider, but if they exist make sure they were not failures.
+		succeeded := ldapConnectionValidCondition.Status == v1alpha1.ConditionTrue &&
+			(searchBaseFoundCondition == nil || (searchBaseFoundCondition.Status == v1alpha1.ConditionTrue)) &&
+			(userSearchBaseValidCondition == nil || (userSearchBaseValidCondition.Status == v1alpha1.ConditionTrue)) &&
+			(groupSearchValidCondition == nil || (groupSearchValidCondition.Status == v1alpha1.ConditionTrue)) &&
+			(userSearchValidCondition == nil || (userSearchValidCondition.Status == v1alpha1.ConditionTrue))
+
+		// When there were no failures, write the newly validated settings to the cache.
+		if succeeded {
 			// Remember (in-memory for this pod) that the controller has successfully validated the LDAP or AD provider
 			// using this version of the Secret. This is for performance reasons, to avoid attempting to connect to
 			// the LDAP server more than is needed. If the pod restarts, it will attempt this validation again.
-			validatedSettingsCache.Set(upstream.Name(), ValidatedSettings{
-				IDPSpecGeneration:         upstream.Generation(),
-				BindSecretResourceVersion: currentSecretVersion,
-				LDAPConnectionProtocol:    config.ConnectionProtocol,
-				UserSearchBase:            config.UserSearch.Base,
-				GroupSearchBase:           config.GroupSearch.Base,
-				ConnectionValidCondition:  ldapConnectionValidCondition.DeepCopy(),
-				SearchBaseFoundCondition:  searchBaseFoundCondition.DeepCopy(), // currently, only used for AD, so may be nil
-			})
+			validatedSettingsCache.Set(config.Name, newSettings)
 		}
+		// Whether it failed or not, avoid probing the server again with the same settings for a while.
+		testConnectionThrottle.Set(config.Name, upstream.Generation(), config, succeeded, newSettings)
+	}
+
+	return ldapConnectionValidCondition, searchBaseFoundCondition, userSearchBaseValidCondition, groupSearchValidCondition, userSearchValidCondition, serverCertNotAfter, connectedHost
+}
+
+// ServerCertificateExpiringSoon returns an informational condition when the certificate presented by the LDAP server
+// expires within the warning window, or nil otherwise. The condition is only present while the certificate is
+// expiring soon, so it should be removed from the status otherwise, e.g. by using RemoveStaleInformationalConditions.
+func ServerCertificateExpiringSoon(notAfter time.Time, warningWindow time.Duration) *v1alpha1.Condition {
+	if notAfter.IsZero() || warningWindow <= 0 || time.Until(notAfter) > warningWindow {
+		return nil
+	}
+	return &v1alpha1.Condition{
+		Type:    typeServerCertExpiringSoon,
+		Status:  v1alpha1.ConditionTrue,
+		Reason:  reasonServerCertExpiringSoon,
+		Message: fmt.Sprintf("the certificate presented by the LDAP server expires at %s", notAfter.UTC().Format(time.RFC3339)),
 	}
+}
 
-	return ldapConnectionValidCondition, searchBaseFoundCondition
+// RemoveStaleInformationalConditions removes the informational conditions which are only present while they apply,
+// such as the one returned by ServerCertificateExpiringSoon, from the existing conditions when they are not among
+// the new conditions. Merging the new conditions into the existing conditions does not remove any conditions.
+func RemoveStaleInformationalConditions(conditions []*v1alpha1.Condition, existingConditions *[]v1alpha1.Condition) {
+	for _, condition := range conditions {
+		if condition.Type == typeServerCertExpiringSoon {
+			return
+		}
+	}
+	kept := make([]v1alpha1.Condition, 0, len(*existingConditions))
+	for _, condition := range *existingConditions {
+		if condition.Type != typeServerCertExpiringSoon {
+			kept = append(kept, condition)
+		}
+	}
+	*existingConditions = kept
 }
 
-func EvaluateConditions(conditions GradatedConditions, config *upstreamldap.ProviderConfig) (provider.UpstreamLDAPIdentityProviderI, bool) {
+// EvaluateConditions decides whether the provider should be loaded into the cache of upstream providers, and whether
+// it should be validated again soon. The Provider is taken from the providerCache, so that it is reused as long as
+// its config does not change.
+func EvaluateConditions(conditions GradatedConditions, config *upstreamldap.ProviderConfig, providerCache *ProviderCache) (provider.UpstreamLDAPIdentityProviderI, bool) {
 	for _, gradatedCondition := range conditions.gradatedConditions {
 		if gradatedCondition.condition.Status != v1alpha1.ConditionTrue && gradatedCondition.isFatal {
 			// Invalid provider, so do not load it into the cache.
@@ -375,9 +931,9 @@ func EvaluateConditions(conditions GradatedConditions, config *upstreamldap.Prov
 		if gradatedCondition.condition.Status != v1alpha1.ConditionTrue && !gradatedCondition.isFatal {
 			// Error but load it into the cache anyway, treating this condition failure more like a warning.
 			// Try again hoping that the condition will improve.
-			return upstreamldap.New(*config), true
+			return providerCache.GetOrCreate(config), true
 		}
 	}
 	// Fully validated provider, so load it into the cache.
-	return upstreamldap.New(*config), false
+	return providerCache.GetOrCreate(config), false
 }
@@ -0,0 +1,199 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamwatchers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/oidc/provider"
+	"go.pinniped.dev/internal/upstreamldap"
+)
+
+func TestTestConnectionThrottle(t *testing.T) {
+	config := &upstreamldap.ProviderConfig{
+		BindUsername: "some-bind-username",
+		BindPassword: "some-bind-password",
+		CABundle:     []byte("some-ca-bundle"),
+	}
+	settings := ValidatedSettings{IDPSpecGeneration: 42, BindSecretResourceVersion: "some-version", UserSearchBase: "some-base"}
+
+	tests := []struct {
+		name       string
+		succeeded  bool
+		elapsed    time.Duration
+		generation int64
+		editConfig func(config *upstreamldap.ProviderConfig)
+		wantReused bool
+	}{
+		{
+			name:       "a failed probe is reused until the failure interval has passed",
+			succeeded:  false,
+			elapsed:    testConnectionMinIntervalAfterFailure - time.Nanosecond,
+			generation: 42,
+			wantReused: true,
+		},
+		{
+			name:       "a failed probe is not reused after the failure interval has passed",
+			succeeded:  false,
+			elapsed:    testConnectionMinIntervalAfterFailure,
+			generation: 42,
+			wantReused: false,
+		},
+		{
+			name:       "a successful probe is reused for longer than a failed probe",
+			succeeded:  true,
+			elapsed:    testConnectionMinIntervalAfterFailure,
+			generation: 42,
+			wantReused: true,
+		},
+		{
+			name:       "a successful probe is not reused after the success interval has passed",
+			succeeded:  true,
+			elapsed:    testConnectionMinIntervalAfterSuccess,
+			generation: 42,
+			wantReused: false,
+		},
+		{
+			name:       "a probe is not reused for a different generation of the spec",
+			succeeded:  false,
+			generation: 43,
+			wantReused: false,
+		},
+		{
+			name:       "a probe is not reused when the bind username changed",
+			succeeded:  false,
+			generation: 42,
+			editConfig: func(config *upstreamldap.ProviderConfig) { config.BindUsername = "other-bind-username" },
+			wantReused: false,
+		},
+		{
+			name:       "a probe is not reused when the bind password changed",
+			succeeded:  true,
+			generation: 42,
+			editConfig: func(config *upstreamldap.ProviderConfig) { config.BindPassword = "other-bind-password" },
+			wantReused: false,
+		},
+		{
+			name:       "a probe is not reused when the CA bundle changed",
+			succeeded:  false,
+			generation: 42,
+			editConfig: func(config *upstreamldap.ProviderConfig) { config.CABundle = nil },
+			wantReused: false,
+		},
+		{
+			name:       "a probe is reused when other settings which are discovered by probing changed",
+			succeeded:  false,
+			generation: 42,
+			editConfig: func(config *upstreamldap.ProviderConfig) { config.ConnectionProtocol = upstreamldap.StartTLS },
+			wantReused: true,
+		},
+	}
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clocktesting.NewFakeClock(time.Now())
+			throttle := NewTestConnectionThrottle(fakeClock)
+
+			_, found := throttle.Get("some-upstream", 42, config)
+			require.False(t, found, "should not reuse anything before the first probe")
+
+			throttle.Set("some-upstream", 42, config, tt.succeeded, settings)
+			fakeClock.Step(tt.elapsed)
+
+			currentConfig := *config
+			if tt.editConfig != nil {
+				tt.editConfig(&currentConfig)
+			}
+			reusedSettings, found := throttle.Get("some-upstream", tt.generation, &currentConfig)
+			require.Equal(t, tt.wantReused, found)
+			if tt.wantReused {
+				require.Equal(t, settings, reusedSettings)
+			} else {
+				require.Equal(t, ValidatedSettings{}, reusedSettings)
+			}
+
+			_, found = throttle.Get("other-upstream", tt.generation, &currentConfig)
+			require.False(t, found, "should not reuse probes of other upstreams")
+		})
+	}
+}
+
+func TestProviderCache(t *testing.T) {
+	newConfig := func() *upstreamldap.ProviderConfig {
+		return &upstreamldap.ProviderConfig{
+			Name:         "some-upstream",
+			Host:         "ldap.example.com",
+			BindUsername: "some-bind-username",
+			BindPassword: "some-bind-password",
+			UserSearch:   upstreamldap.UserSearchConfig{Base: "some-user-base"},
+			// The controllers create new functions for each sync.
+			RefreshAttributeChecks: map[string]func(*ldap.Entry, provider.RefreshAttributes) error{
+				"some-attribute": func(*ldap.Entry, provider.RefreshAttributes) error { return nil },
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		editConfig func(config *upstreamldap.ProviderConfig)
+		wantReused bool
+	}{
+		{
+			name:       "the Provider is reused when the config is the same",
+			wantReused: true,
+		},
+		{
+			name:       "the Provider is not reused when the bind password changed",
+			editConfig: func(config *upstreamldap.ProviderConfig) { config.BindPassword = "other-bind-password" },
+			wantReused: false,
+		},
+		{
+			name:       "the Provider is not reused when the user search changed",
+			editConfig: func(config *upstreamldap.ProviderConfig) { config.UserSearch.Base = "other-user-base" },
+			wantReused: false,
+		},
+		{
+			name: "the Provider is not reused when the functions apply to other attributes",
+			editConfig: func(config *upstreamldap.ProviderConfig) {
+				config.RefreshAttributeChecks = map[string]func(*ldap.Entry, provider.RefreshAttributes) error{
+					"other-attribute": func(*ldap.Entry, provider.RefreshAttributes) error { return nil },
+				}
+			},
+			wantReused: false,
+		},
+	}
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			providerCache := NewProviderCache()
+			first := providerCache.GetOrCreate(newConfig())
+			require.Equal(t, "some-upstream", first.GetName())
+
+			currentConfig := newConfig()
+			if tt.editConfig != nil {
+				tt.editConfig(currentConfig)
+			}
+			second := providerCache.GetOrCreate(currentConfig)
+			if tt.wantReused {
+				require.Same(t, first, second)
+			} else {
+				require.NotSame(t, first, second)
+				require.Equal(t, currentConfig.BindPassword, second.GetConfig().BindPassword)
+			}
+
+			otherConfig := newConfig()
+			otherConfig.Name = "other-upstream"
+			require.NotSame(t, second, providerCache.GetOrCreate(otherConfig), "should not reuse the Providers of other upstreams")
+
+			providerCache.DeleteAllExcept(sets.NewString("other-upstream"))
+			require.NotSame(t, second, providerCache.GetOrCreate(currentConfig), "should not reuse deleted Providers")
+		})
+	}
+}
@@ -6,16 +6,26 @@
 package controllermanager
 
 import (
+	"context"
+	"crypto/x509/pkix"
 	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	k8sinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	k8sscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/utils/clock"
 
 	pinnipedclientset "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned"
+	pinnipedscheme "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned/scheme"
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions"
 	"go.pinniped.dev/internal/apiserviceref"
+	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/clusterhost"
 	"go.pinniped.dev/internal/concierge/impersonator"
 	"go.pinniped.dev/internal/config/concierge"
 	"go.pinniped.dev/internal/controller/apicerts"
@@ -24,6 +34,7 @@ import (
 	"go.pinniped.dev/internal/controller/authenticator/jwtcachefiller"
 	"go.pinniped.dev/internal/controller/authenticator/webhookcachefiller"
 	"go.pinniped.dev/internal/controller/impersonatorconfig"
+	"go.pinniped.dev/internal/controller/impersonatorconfig/proxyinfo"
 	"go.pinniped.dev/internal/controller/kubecertagent"
 	"go.pinniped.dev/internal/controllerinit"
 	"go.pinniped.dev/internal/controllerlib"
@@ -62,6 +73,96 @@ type Config struct {
 	// ImpersonationProxyServerPort decides which port the impersonation proxy should bind.
 	ImpersonationProxyServerPort int
 
+	// ImpersonationProxyCADuration is the validity period of the impersonation proxy's CA certificate.
+	ImpersonationProxyCADuration time.Duration
+
+	// ImpersonationProxyCAKeyAlgorithm is the key algorithm of the impersonation proxy's CA certificate and of the
+	// TLS serving certificates which it issues. When it is empty, a default key algorithm is used.
+	ImpersonationProxyCAKeyAlgorithm certauthority.KeyAlgorithm
+
+	// ImpersonationProxyCASubject is the subject of the impersonation proxy's CA certificate. When its common name
+	// is empty, a default common name is used.
+	ImpersonationProxyCASubject pkix.Name
+
+	// ImpersonationProxyTLSSecretRef is the name of an externally managed TLS Secret which the impersonation proxy
+	// serves instead of minting its own certificates. When empty, the certificates are minted.
+	ImpersonationProxyTLSSecretRef string
+
+	// ImpersonationProxyCABundleConfigMap is the name of a ConfigMap in which the impersonation proxy's CA bundle
+	// is published for other tools. When empty, the CA bundle is not published in a ConfigMap.
+	ImpersonationProxyCABundleConfigMap string
+
+	// ImpersonationProxyAdditionalSANs are extra DNS names and IP addresses which are included in the impersonation
+	// proxy's minted TLS serving certificate.
+	ImpersonationProxyAdditionalSANs []string
+
+	// ImpersonationProxyCertificateDuration is the validity period of the impersonation proxy's TLS serving certificate.
+	ImpersonationProxyCertificateDuration time.Duration
+
+	// ImpersonationProxyRotationWindowPercentage is the final percentage of the validity period of the impersonation
+	// proxy's certificates during which they are rotated.
+	ImpersonationProxyRotationWindowPercentage int
+
+	// ImpersonationProxyRequestTimeout is how long the impersonation proxy allows a non-long-running request to take.
+	ImpersonationProxyRequestTimeout time.Duration
+
+	// ImpersonationProxyShutdownDrainTimeout is how long the impersonation proxy waits for its open connections to
+	// finish when it is stopped, before closing them.
+	ImpersonationProxyShutdownDrainTimeout time.Duration
+
+	// ImpersonationProxyResyncInterval is how often the impersonator config controller syncs even when nothing
+	// that it watches has changed.
+	ImpersonationProxyResyncInterval time.Duration
+
+	// ImpersonationProxyControlPlaneNodeSelectors are the label selectors which identify control plane nodes when
+	// the impersonation proxy is in auto mode. When empty, the well-known node role labels are used.
+	ImpersonationProxyControlPlaneNodeSelectors []string
+
+	// ImpersonationProxyExcludedNodeRoles are the node roles which cause a node to be ignored when looking for
+	// control plane nodes in auto mode.
+	ImpersonationProxyExcludedNodeRoles []string
+
+	// ImpersonationProxyAutoModeStopDelay is how long control plane nodes must stay visible before auto mode stops
+	// the impersonation proxy.
+	ImpersonationProxyAutoModeStopDelay time.Duration
+
+	// ImpersonationProxyStateChangeWebhookURL is the URL to which the impersonation proxy's state changes are
+	// POSTed, or empty to not send them.
+	ImpersonationProxyStateChangeWebhookURL string
+
+	// ImpersonationProxyRequestLogLevel is the log level at which the impersonation proxy logs each request.
+	ImpersonationProxyRequestLogLevel plog.LogLevel
+
+	// ImpersonationProxyMaxResponseBodyBytes is the largest response body which the impersonation proxy relays
+	// for a request which does not stream its response, or 0 for unlimited.
+	ImpersonationProxyMaxResponseBodyBytes int64
+
+	// ImpersonationProxyMinTLSVersion is the minimum TLS version of the impersonation proxy, or empty for the default.
+	ImpersonationProxyMinTLSVersion string
+
+	// ImpersonationProxyCipherSuites are the TLS 1.2 cipher suites of the impersonation proxy, or empty for the default.
+	ImpersonationProxyCipherSuites []string
+
+	// ImpersonationProxyPermitPortSharing binds the impersonation proxy's port with SO_REUSEPORT when supported.
+	ImpersonationProxyPermitPortSharing bool
+
+	// ImpersonationProxyMetricsAddress is the host:port of the impersonation proxy's metrics listener, or empty for none.
+	ImpersonationProxyMetricsAddress string
+
+	// ImpersonationProxyStripRequestHeaders are the client request headers which the impersonation proxy removes.
+	ImpersonationProxyStripRequestHeaders []string
+
+	// ImpersonationProxyPassThroughRequestHeaders are the client request headers which the impersonation proxy
+	// proxies even though it would remove them by default.
+	ImpersonationProxyPassThroughRequestHeaders []string
+
+	// ImpersonationProxyServiceSelector is the selector of the impersonation proxy's Services, or empty for the default.
+	ImpersonationProxyServiceSelector map[string]string
+
+	// ImpersonationProxyExtraLabels are added to the impersonation proxy's Services, Secrets, and ConfigMap when
+	// they are created, in addition to Labels, but are not reconciled afterwards.
+	ImpersonationProxyExtraLabels map[string]string
+
 	// DiscoveryURLOverride allows a caller to inject a hardcoded discovery URL into Pinniped
 	// discovery document.
 	DiscoveryURLOverride *string
@@ -92,6 +193,10 @@ type Config struct {
 	// AuthenticatorCache is a cache of authenticators shared amongst various authenticated-related controllers.
 	AuthenticatorCache *authncache.Cache
 
+	// ImpersonationProxyInfoCache is filled with the endpoint and CA bundle of the impersonation proxy by a
+	// controller while the impersonation proxy is ready, so that the TokenCredentialRequest API can return them.
+	ImpersonationProxyInfoCache *proxyinfo.Cache
+
 	// Labels are labels that should be added to any resources created by the controllers.
 	Labels map[string]string
 }
@@ -121,15 +226,28 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 		return nil, fmt.Errorf("could not create clients for the controllers: %w", err)
 	}
 
+	impersonationProxyControlPlaneNodeSelectors, err := clusterhost.ParseControlPlaneNodeSelectors(c.ImpersonationProxyControlPlaneNodeSelectors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid impersonation proxy control plane node selectors: %w", err)
+	}
+
 	// Create informers. Don't forget to make sure they get started in the function returned below.
 	informers := createInformers(c.ServerInstallationInfo.Namespace, client.Kubernetes, client.PinnipedConcierge)
 
+	// Events are only sent to the API server while this pod is running the controllers.
+	eventBroadcaster := events.NewBroadcaster(&events.EventSinkImpl{Interface: client.Kubernetes.EventsV1()})
+
 	agentConfig := kubecertagent.AgentConfig{
 		Namespace:                 c.ServerInstallationInfo.Namespace,
 		ServiceAccountName:        c.NamesConfig.AgentServiceAccount,
 		ContainerImage:            *c.KubeCertAgentConfig.Image,
 		NamePrefix:                *c.KubeCertAgentConfig.NamePrefix,
 		ContainerImagePullSecrets: c.KubeCertAgentConfig.ImagePullSecrets,
+		Resources:                 *c.KubeCertAgentConfig.Resources,
+		Tolerations:               c.KubeCertAgentConfig.Tolerations,
+		PriorityClassName:         c.KubeCertAgentConfig.PriorityClassName,
+		NodeSelector:              c.KubeCertAgentConfig.NodeSelector,
+		Affinity:                  c.KubeCertAgentConfig.Affinity,
 		Labels:                    c.Labels,
 		CredentialIssuerName:      c.NamesConfig.CredentialIssuer,
 		DiscoveryURLOverride:      c.DiscoveryURLOverride,
@@ -264,17 +382,49 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 				informers.pinniped.Config().V1alpha1().CredentialIssuers(),
 				informers.installationNamespaceK8s.Core().V1().Services(),
 				informers.installationNamespaceK8s.Core().V1().Secrets(),
+				informers.installationNamespaceK8s.Core().V1().ConfigMaps(),
 				controllerlib.WithInformer,
+				controllerlib.WithInitialEvent,
 				c.ImpersonationProxyServerPort,
 				c.NamesConfig.ImpersonationLoadBalancerService,
 				c.NamesConfig.ImpersonationClusterIPService,
+				c.NamesConfig.ImpersonationNodePortService,
 				c.NamesConfig.ImpersonationTLSCertificateSecret,
+				c.ImpersonationProxyTLSSecretRef,
 				c.NamesConfig.ImpersonationCACertificateSecret,
+				c.ImpersonationProxyCABundleConfigMap,
 				c.Labels,
+				c.ImpersonationProxyExtraLabels,
+				c.ImpersonationProxyServiceSelector,
 				clock.RealClock{},
-				impersonator.New,
+				eventBroadcaster.NewRecorder(newEventScheme(), "pinniped-concierge-impersonator-config-controller"),
+				legacyregistry.MustRegister,
+				impersonator.NewWithConfig(impersonator.Config{
+					RequestLogLevel:           c.ImpersonationProxyRequestLogLevel,
+					MaxResponseBodyBytes:      c.ImpersonationProxyMaxResponseBodyBytes,
+					MinTLSVersion:             c.ImpersonationProxyMinTLSVersion,
+					CipherSuites:              c.ImpersonationProxyCipherSuites,
+					PermitPortSharing:         c.ImpersonationProxyPermitPortSharing,
+					MetricsAddress:            c.ImpersonationProxyMetricsAddress,
+					StripRequestHeaders:       c.ImpersonationProxyStripRequestHeaders,
+					PassThroughRequestHeaders: c.ImpersonationProxyPassThroughRequestHeaders,
+				}),
 				c.NamesConfig.ImpersonationSignerSecret,
 				c.ImpersonationSigningCertProvider,
+				c.ImpersonationProxyInfoCache,
+				c.ImpersonationProxyCASubject,
+				c.ImpersonationProxyCADuration,
+				c.ImpersonationProxyCAKeyAlgorithm,
+				c.ImpersonationProxyCertificateDuration,
+				c.ImpersonationProxyAdditionalSANs,
+				c.ImpersonationProxyRotationWindowPercentage,
+				c.ImpersonationProxyRequestTimeout,
+				c.ImpersonationProxyShutdownDrainTimeout,
+				c.ImpersonationProxyResyncInterval,
+				impersonationProxyControlPlaneNodeSelectors,
+				c.ImpersonationProxyExcludedNodeRoles,
+				c.ImpersonationProxyAutoModeStopDelay,
+				c.ImpersonationProxyStateChangeWebhookURL,
 				plog.Logr(), //nolint:staticcheck  // old controller with lots of log statements
 			),
 			singletonWorker,
@@ -308,7 +458,13 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 			singletonWorker,
 		)
 
-	return controllerinit.Prepare(controllerManager.Start, leaderElector,
+	startControllers := func(ctx context.Context) {
+		eventBroadcaster.StartRecordingToSink(ctx.Done())
+		defer eventBroadcaster.Shutdown()
+		controllerManager.Start(ctx)
+	}
+
+	return controllerinit.Prepare(startControllers, leaderElector,
 		informers.kubePublicNamespaceK8s,
 		informers.kubeSystemNamespaceK8s,
 		informers.installationNamespaceK8s,
@@ -351,3 +507,12 @@ func createInformers(
 		),
 	}
 }
+
+// newEventScheme returns a scheme which knows about both the Kubernetes types and the Concierge's own types,
+// so that events can be recorded regarding either kind of object.
+func newEventScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(k8sscheme.AddToScheme(scheme))
+	utilruntime.Must(pinnipedscheme.AddToScheme(scheme))
+	return scheme
+}
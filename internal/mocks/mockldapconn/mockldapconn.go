@@ -93,3 +93,17 @@ func (mr *MockConnMockRecorder) SearchWithPaging(arg0, arg1 interface{}) *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchWithPaging", reflect.TypeOf((*MockConn)(nil).SearchWithPaging), arg0, arg1)
 }
+
+// UnauthenticatedBind mocks base method.
+func (m *MockConn) UnauthenticatedBind(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnauthenticatedBind", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnauthenticatedBind indicates an expected call of UnauthenticatedBind.
+func (mr *MockConnMockRecorder) UnauthenticatedBind(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnauthenticatedBind", reflect.TypeOf((*MockConn)(nil).UnauthenticatedBind), arg0)
+}
@@ -31,18 +31,26 @@ type TokenCredentialRequestAuthenticator interface {
 	AuthenticateTokenCredentialRequest(ctx context.Context, req *loginapi.TokenCredentialRequest) (user.Info, error)
 }
 
-func NewREST(authenticator TokenCredentialRequestAuthenticator, issuer issuer.ClientCertIssuer, resource schema.GroupResource) *REST {
+// ImpersonationProxyInfoGetter returns the client connection details of the impersonation proxy,
+// or nil when the impersonation proxy is not ready to accept client connections.
+type ImpersonationProxyInfoGetter interface {
+	Get() *loginapi.ImpersonationProxyInfo
+}
+
+func NewREST(authenticator TokenCredentialRequestAuthenticator, issuer issuer.ClientCertIssuer, impersonationProxyInfo ImpersonationProxyInfoGetter, resource schema.GroupResource) *REST {
 	return &REST{
-		authenticator:  authenticator,
-		issuer:         issuer,
-		tableConvertor: rest.NewDefaultTableConvertor(resource),
+		authenticator:          authenticator,
+		issuer:                 issuer,
+		impersonationProxyInfo: impersonationProxyInfo,
+		tableConvertor:         rest.NewDefaultTableConvertor(resource),
 	}
 }
 
 type REST struct {
-	authenticator  TokenCredentialRequestAuthenticator
-	issuer         issuer.ClientCertIssuer
-	tableConvertor rest.TableConvertor
+	authenticator          TokenCredentialRequestAuthenticator
+	issuer                 issuer.ClientCertIssuer
+	impersonationProxyInfo ImpersonationProxyInfoGetter
+	tableConvertor         rest.TableConvertor
 }
 
 // Assert that our *REST implements all the optional interfaces that we expect it to implement.
@@ -125,10 +133,18 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 				ClientCertificateData: string(certPEM),
 				ClientKeyData:         string(keyPEM),
 			},
+			ImpersonationProxyInfo: r.getImpersonationProxyInfo(),
 		},
 	}, nil
 }
 
+func (r *REST) getImpersonationProxyInfo() *loginapi.ImpersonationProxyInfo {
+	if r.impersonationProxyInfo == nil {
+		return nil
+	}
+	return r.impersonationProxyInfo.Get()
+}
+
 func validateRequest(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions, t *trace.Trace) (*loginapi.TokenCredentialRequest, error) {
 	credentialRequest, ok := obj.(*loginapi.TokenCredentialRequest)
 	if !ok {
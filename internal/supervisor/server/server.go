@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/sets"
 	apimachineryversion "k8s.io/apimachinery/pkg/version"
 	genericapifilters "k8s.io/apiserver/pkg/endpoints/filters"
 	openapinamer "k8s.io/apiserver/pkg/endpoints/openapi"
@@ -35,6 +36,7 @@ import (
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/pkg/version"
 	"k8s.io/client-go/rest"
+	"k8s.io/component-base/metrics/legacyregistry"
 	aggregatorclient "k8s.io/kube-aggregator/pkg/client/clientset_generated/clientset"
 	"k8s.io/utils/clock"
 
@@ -69,6 +71,7 @@ import (
 	"go.pinniped.dev/internal/secret"
 	"go.pinniped.dev/internal/supervisor/apiserver"
 	supervisorscheme "go.pinniped.dev/internal/supervisor/scheme"
+	"go.pinniped.dev/internal/upstreamldap"
 )
 
 const (
@@ -150,6 +153,31 @@ func prepareControllers(
 	oidcClientInformer := pinnipedInformers.Config().V1alpha1().OIDCClients()
 	secretInformer := kubeInformers.Core().V1().Secrets()
 
+	// Each additional namespace which is watched for LDAPIdentityProviders gets its own informers, so that the
+	// Supervisor only needs to be allowed to read the LDAPIdentityProviders and Secrets of those namespaces.
+	var ldapNamespaceInformers []ldapupstreamwatcher.NamespaceInformers
+	var ldapNamespaceInformerFactories []controllerinit.Informer
+	for _, namespace := range sets.NewString(cfg.LDAPIdentityProviderNamespaces...).Delete(podInfo.Namespace).List() {
+		kubeNamespaceInformers := kubeinformers.NewSharedInformerFactoryWithOptions(
+			kubeClient,
+			defaultResyncInterval,
+			kubeinformers.WithNamespace(namespace),
+		)
+		pinnipedNamespaceInformers := pinnipedinformers.NewSharedInformerFactoryWithOptions(
+			pinnipedClient,
+			defaultResyncInterval,
+			pinnipedinformers.WithNamespace(namespace),
+		)
+		ldapNamespaceInformers = append(ldapNamespaceInformers, ldapupstreamwatcher.NamespaceInformers{
+			LDAPIdentityProviderInformer: pinnipedNamespaceInformers.IDP().V1alpha1().LDAPIdentityProviders(),
+			SecretInformer:               kubeNamespaceInformers.Core().V1().Secrets(),
+		})
+		ldapNamespaceInformerFactories = append(ldapNamespaceInformerFactories, kubeNamespaceInformers, pinnipedNamespaceInformers)
+	}
+
+	// The LDAP and Active Directory identity providers record the latency of the operations that they perform.
+	upstreamldap.RegisterMetrics(legacyregistry.MustRegister)
+
 	// Create controller manager.
 	controllerManager := controllerlib.
 		NewManager().
@@ -303,6 +331,10 @@ func prepareControllers(
 				pinnipedClient,
 				pinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				secretInformer,
+				ldapNamespaceInformers,
+				cfg.LDAPServerCertificateExpiryWarningWindow.Duration,
+				cfg.LDAPAuthenticationCacheTTL.Duration,
+				cfg.LDAPSkipStatusUpdates,
 				controllerlib.WithInformer,
 			),
 			singletonWorker).
@@ -312,6 +344,8 @@ func prepareControllers(
 				pinnipedClient,
 				pinnipedInformers.IDP().V1alpha1().ActiveDirectoryIdentityProviders(),
 				secretInformer,
+				cfg.LDAPServerCertificateExpiryWarningWindow.Duration,
+				cfg.LDAPAuthenticationCacheTTL.Duration,
 				controllerlib.WithInformer,
 			),
 			singletonWorker).
@@ -374,7 +408,8 @@ func prepareControllers(
 			singletonWorker,
 		)
 
-	return controllerinit.Prepare(controllerManager.Start, leaderElector, kubeInformers, pinnipedInformers)
+	informers := append([]controllerinit.Informer{kubeInformers, pinnipedInformers}, ldapNamespaceInformerFactories...)
+	return controllerinit.Prepare(controllerManager.Start, leaderElector, informers...)
 }
 
 //nolint:funlen
@@ -0,0 +1,169 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"go.pinniped.dev/internal/authenticators"
+)
+
+// AuthenticationCacheConfig configures the caching of successful calls to AuthenticateUser, so that repeated logins
+// by the same user with the same password during the TTL do not contact the LDAP server again. Failed logins are
+// never cached. The zero value disables caching.
+//
+// The cache belongs to a Provider, so it starts empty whenever a new Provider is created for a changed configuration.
+type AuthenticationCacheConfig struct {
+	// TTL is how long a successful authentication result is reused.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of results to keep. When the cache is full, the result which expires
+	// soonest is evicted to make room for a new result.
+	MaxEntries int
+}
+
+func (c AuthenticationCacheConfig) enabled() bool {
+	return c.TTL > 0 && c.MaxEntries > 0
+}
+
+// authCache holds successful authentication results, keyed by a salted hash of the credentials so that
+// the passwords are not kept in memory. It is safe for concurrent use.
+type authCache struct {
+	config AuthenticationCacheConfig
+	salt   []byte
+	now    func() time.Time // for unit tests
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*authCacheEntry
+}
+
+type authCacheEntry struct {
+	response  *authenticators.Response
+	expiresAt time.Time
+}
+
+func newAuthCache(config AuthenticationCacheConfig) *authCache {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err) // this should never happen, and there is no safe way to cache without a salt
+	}
+	return &authCache{
+		config:  config,
+		salt:    salt,
+		now:     time.Now,
+		entries: map[[sha256.Size]byte]*authCacheEntry{},
+	}
+}
+
+// key returns the salted hash of the credentials and the granted scopes, since the scopes change the response.
+func (ac *authCache) key(username, password string, grantedScopes []string) [sha256.Size]byte {
+	mac := hmac.New(sha256.New, ac.salt)
+	writeLengthPrefixed(mac, username)
+	writeLengthPrefixed(mac, password)
+	scopes := append([]string{}, grantedScopes...)
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		writeLengthPrefixed(mac, scope)
+	}
+	var key [sha256.Size]byte
+	copy(key[:], mac.Sum(nil))
+	return key
+}
+
+func writeLengthPrefixed(h hash.Hash, s string) {
+	_ = binary.Write(h, binary.BigEndian, uint64(len(s)))
+	_, _ = h.Write([]byte(s))
+}
+
+// get returns a copy of the cached response for the key, or nil when there is none which has not expired.
+func (ac *authCache) get(key [sha256.Size]byte) *authenticators.Response {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	entry, ok := ac.entries[key]
+	if !ok {
+		return nil
+	}
+	if !ac.now().Before(entry.expiresAt) {
+		delete(ac.entries, key)
+		return nil
+	}
+	return copyResponse(entry.response)
+}
+
+// put caches a copy of a successful response for the key, evicting another entry when the cache is full.
+func (ac *authCache) put(key [sha256.Size]byte, response *authenticators.Response) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	now := ac.now()
+	if _, exists := ac.entries[key]; !exists && len(ac.entries) >= ac.config.MaxEntries {
+		ac.evict(now)
+	}
+	ac.entries[key] = &authCacheEntry{response: copyResponse(response), expiresAt: now.Add(ac.config.TTL)}
+}
+
+// evict removes all expired entries, or the entry which expires soonest when none have expired.
+// The caller must hold the lock.
+func (ac *authCache) evict(now time.Time) {
+	var soonestKey [sha256.Size]byte
+	var soonest *authCacheEntry
+	for key, entry := range ac.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(ac.entries, key)
+			continue
+		}
+		if soonest == nil || entry.expiresAt.Before(soonest.expiresAt) {
+			soonestKey, soonest = key, entry
+		}
+	}
+	if len(ac.entries) >= ac.config.MaxEntries && soonest != nil {
+		delete(ac.entries, soonestKey)
+	}
+}
+
+// copyResponse returns a deep copy of the response, so that a caller which changes a response does not change the
+// cached response, nor the responses returned to other callers.
+func copyResponse(response *authenticators.Response) *authenticators.Response {
+	c := *response
+	if response.User != nil {
+		c.User = copyUserInfo(response.User)
+	}
+	if response.ExtraRefreshAttributes != nil {
+		c.ExtraRefreshAttributes = make(map[string]string, len(response.ExtraRefreshAttributes))
+		for k, v := range response.ExtraRefreshAttributes {
+			c.ExtraRefreshAttributes[k] = v
+		}
+	}
+	return &c
+}
+
+func copyUserInfo(in user.Info) *user.DefaultInfo {
+	out := &user.DefaultInfo{Name: in.GetName(), UID: in.GetUID()}
+	if groups := in.GetGroups(); groups != nil {
+		out.Groups = make([]string, len(groups))
+		copy(out.Groups, groups)
+	}
+	if extra := in.GetExtra(); extra != nil {
+		out.Extra = make(map[string][]string, len(extra))
+		for key, values := range extra {
+			if values == nil {
+				out.Extra[key] = nil
+				continue
+			}
+			out.Extra[key] = make([]string, len(values))
+			copy(out.Extra[key], values)
+		}
+	}
+	return out
+}
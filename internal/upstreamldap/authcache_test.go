@@ -0,0 +1,148 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"go.pinniped.dev/internal/authenticators"
+)
+
+func TestAuthenticationCacheConfigEnabled(t *testing.T) {
+	require.False(t, AuthenticationCacheConfig{}.enabled())
+	require.False(t, AuthenticationCacheConfig{TTL: time.Minute}.enabled())
+	require.False(t, AuthenticationCacheConfig{MaxEntries: 1}.enabled())
+	require.True(t, AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 1}.enabled())
+}
+
+func TestAuthCache(t *testing.T) {
+	newResponse := func(name string) *authenticators.Response {
+		return &authenticators.Response{
+			User: &user.DefaultInfo{
+				Name:   name,
+				Groups: []string{"some-group", "other-group"},
+				Extra:  map[string][]string{"some-extra": {"some-extra-value"}},
+			},
+			DN:                     "cn=" + name,
+			ExtraRefreshAttributes: map[string]string{"some-attribute": "some-value"},
+		}
+	}
+
+	newTestCache := func(config AuthenticationCacheConfig) (*authCache, *time.Time) {
+		now := time.Now()
+		cache := newAuthCache(config)
+		cache.now = func() time.Time { return now }
+		return cache, &now
+	}
+
+	t.Run("keys differ by username, password, and granted scopes, but not by the order of the scopes", func(t *testing.T) {
+		cache, _ := newTestCache(AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 10})
+		key := cache.key("user", "password", []string{"openid", "groups"})
+
+		require.Equal(t, key, cache.key("user", "password", []string{"groups", "openid"}))
+		require.NotEqual(t, key, cache.key("other-user", "password", []string{"openid", "groups"}))
+		require.NotEqual(t, key, cache.key("user", "other-password", []string{"openid", "groups"}))
+		require.NotEqual(t, key, cache.key("user", "password", []string{"openid"}))
+		// The lengths are part of the key, so moving characters between the username and password changes the key.
+		require.NotEqual(t, cache.key("ab", "c", nil), cache.key("a", "bc", nil))
+	})
+
+	t.Run("keys are salted differently by each cache", func(t *testing.T) {
+		cache1, _ := newTestCache(AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 10})
+		cache2, _ := newTestCache(AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 10})
+		require.NotEqual(t, cache1.key("user", "password", nil), cache2.key("user", "password", nil))
+	})
+
+	t.Run("get returns a copy of the cached response until it expires", func(t *testing.T) {
+		cache, now := newTestCache(AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 10})
+		key := cache.key("user", "password", nil)
+		require.Nil(t, cache.get(key))
+
+		response := newResponse("user")
+		cache.put(key, response)
+		response.ExtraRefreshAttributes["some-attribute"] = "changed-after-put"
+
+		cached := cache.get(key)
+		require.Equal(t, newResponse("user"), cached)
+		cached.ExtraRefreshAttributes["some-attribute"] = "changed-after-get"
+		require.Equal(t, newResponse("user"), cache.get(key))
+
+		*now = now.Add(time.Minute - time.Nanosecond)
+		require.Equal(t, newResponse("user"), cache.get(key))
+
+		*now = now.Add(time.Nanosecond)
+		require.Nil(t, cache.get(key))
+		require.Empty(t, cache.entries)
+	})
+
+	t.Run("changing the groups or extra of a returned response does not change the next cache hit", func(t *testing.T) {
+		cache, _ := newTestCache(AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 10})
+		key := cache.key("user", "password", nil)
+
+		response := newResponse("user")
+		cache.put(key, response)
+		response.User.(*user.DefaultInfo).Groups[0] = "changed-after-put"
+		response.User.(*user.DefaultInfo).Extra["some-extra"][0] = "changed-after-put"
+
+		cached := cache.get(key)
+		require.Equal(t, newResponse("user"), cached)
+		cachedUser := cached.User.(*user.DefaultInfo)
+		cachedUser.Groups[1] = "changed-after-get"
+		cachedUser.Groups = append(cachedUser.Groups, "appended-after-get")
+		cachedUser.Extra["some-extra"][0] = "changed-after-get"
+		cachedUser.Extra["other-extra"] = []string{"added-after-get"}
+		require.Equal(t, newResponse("user"), cache.get(key))
+	})
+
+	t.Run("put evicts the expired entries when the cache is full", func(t *testing.T) {
+		cache, now := newTestCache(AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 2})
+		key1, key2, key3 := cache.key("user1", "password", nil), cache.key("user2", "password", nil), cache.key("user3", "password", nil)
+
+		cache.put(key1, newResponse("user1"))
+		cache.put(key2, newResponse("user2"))
+		*now = now.Add(time.Minute)
+		cache.put(key3, newResponse("user3"))
+
+		require.Len(t, cache.entries, 1)
+		require.Equal(t, newResponse("user3"), cache.get(key3))
+	})
+
+	t.Run("put evicts the entry which expires soonest when the cache is full of unexpired entries", func(t *testing.T) {
+		cache, now := newTestCache(AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 2})
+		key1, key2, key3 := cache.key("user1", "password", nil), cache.key("user2", "password", nil), cache.key("user3", "password", nil)
+
+		cache.put(key1, newResponse("user1"))
+		*now = now.Add(time.Second)
+		cache.put(key2, newResponse("user2"))
+		*now = now.Add(time.Second)
+		cache.put(key3, newResponse("user3"))
+
+		require.Len(t, cache.entries, 2)
+		require.Nil(t, cache.get(key1))
+		require.Equal(t, newResponse("user2"), cache.get(key2))
+		require.Equal(t, newResponse("user3"), cache.get(key3))
+	})
+
+	t.Run("put replaces an existing entry without evicting another entry", func(t *testing.T) {
+		cache, now := newTestCache(AuthenticationCacheConfig{TTL: time.Minute, MaxEntries: 2})
+		key1, key2 := cache.key("user1", "password", nil), cache.key("user2", "password", nil)
+
+		cache.put(key1, newResponse("user1"))
+		cache.put(key2, newResponse("user2"))
+		*now = now.Add(30 * time.Second)
+		cache.put(key1, newResponse("user1-again"))
+
+		require.Len(t, cache.entries, 2)
+		require.Equal(t, newResponse("user2"), cache.get(key2))
+
+		// Replacing the entry also extended its expiration.
+		*now = now.Add(45 * time.Second)
+		require.Equal(t, newResponse("user1-again"), cache.get(key1))
+		require.Nil(t, cache.get(key2))
+	})
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ConnectionPoolConfig configures the reuse of connections to the LDAP server across calls to
+// AuthenticateUser, DryRunAuthenticateUser, and PerformRefresh. The zero value disables reuse,
+// so every call will dial a new connection and close it when finished.
+type ConnectionPoolConfig struct {
+	// MaxConnections is the maximum number of connections to each host which may be open at the same time, counting
+	// both the connections which are in use and the idle ones. When a host has that many open connections, calls
+	// wait until one of them is closed or becomes idle, or until their context is done. Zero means no limit.
+	MaxConnections int
+
+	// MaxIdleConnections is the maximum number of idle connections to keep open to each host.
+	MaxIdleConnections int
+
+	// IdleTimeout is how long a connection may stay idle in the pool before it is closed.
+	IdleTimeout time.Duration
+}
+
+func (c ConnectionPoolConfig) enabled() bool {
+	return c.MaxIdleConnections > 0 && c.IdleTimeout > 0
+}
+
+// connPool holds idle connections, keyed by the configured host to which they are connected, and limits how many
+// connections may be open to each host. It is safe for concurrent use.
+type connPool struct {
+	config ConnectionPoolConfig
+
+	mu     sync.Mutex
+	idle   map[string][]*idleConn
+	open   map[string]int // only counted when config.MaxConnections limits the open connections
+	closed bool
+
+	// changed is closed and replaced whenever a connection is closed or becomes idle, to wake up the calls which
+	// are waiting in acquire.
+	changed chan struct{}
+}
+
+type idleConn struct {
+	conn  Conn
+	timer *time.Timer
+}
+
+func newConnPool(config ConnectionPoolConfig) *connPool {
+	return &connPool{config: config, idle: map[string][]*idleConn{}, open: map[string]int{}, changed: make(chan struct{})}
+}
+
+func (cp *connPool) limited() bool {
+	return cp.config.MaxConnections > 0
+}
+
+// acquire removes and returns the most recently used idle connection to the host. When there is none, it returns
+// nil once a new connection to the host may be opened, which then counts as open until it is given to put or
+// discard. While the host already has the maximum number of open connections, it waits until that changes, or
+// returns the error of the context when the context is done first.
+func (cp *connPool) acquire(ctx context.Context, host string) (Conn, error) {
+	for {
+		cp.mu.Lock()
+		if conn := cp.getLocked(host); conn != nil {
+			cp.mu.Unlock()
+			return conn, nil
+		}
+		if !cp.limited() || cp.open[host] < cp.config.MaxConnections {
+			if cp.limited() {
+				cp.open[host]++
+			}
+			cp.mu.Unlock()
+			return nil, nil
+		}
+		changed := cp.changed
+		cp.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// discard closes a connection which was acquired from the pool, or which was opened after acquire returned nil,
+// instead of returning it to the pool. The conn may be nil when the new connection could not be opened.
+func (cp *connPool) discard(host string, conn Conn) {
+	if conn != nil {
+		conn.Close()
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.closedLocked(host)
+}
+
+// get removes and returns the most recently used idle connection to the host, or nil when there is none.
+func (cp *connPool) get(host string) Conn {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.getLocked(host)
+}
+
+func (cp *connPool) getLocked(host string) Conn {
+	conns := cp.idle[host]
+	if len(conns) == 0 {
+		return nil
+	}
+	ic := conns[len(conns)-1]
+	cp.idle[host] = conns[:len(conns)-1]
+	// If the timer already fired, then its func is waiting for the lock and will not
+	// find this connection in the pool anymore, so it will leave it open for us.
+	ic.timer.Stop()
+	return ic.conn
+}
+
+// put returns a healthy connection to the pool, or closes it when the pool for that host is already full.
+// Idle connections are closed after the idle timeout, even if this pool is never used again.
+func (cp *connPool) put(host string, conn Conn) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.closed || len(cp.idle[host]) >= cp.config.MaxIdleConnections {
+		conn.Close()
+		cp.closedLocked(host)
+		return
+	}
+	ic := &idleConn{conn: conn}
+	ic.timer = time.AfterFunc(cp.config.IdleTimeout, func() {
+		if cp.remove(host, ic) {
+			ic.conn.Close()
+		}
+	})
+	cp.idle[host] = append(cp.idle[host], ic)
+	cp.notifyLocked()
+}
+
+// close closes all idle connections. Connections which are returned to the pool afterwards are closed immediately,
+// so that calls which were still using a connection when the pool was closed do not leave it open.
+func (cp *connPool) close() {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	for host, conns := range cp.idle {
+		for _, ic := range conns {
+			ic.timer.Stop()
+			ic.conn.Close()
+			cp.closedLocked(host)
+		}
+		delete(cp.idle, host)
+	}
+	cp.closed = true
+}
+
+func (cp *connPool) remove(host string, ic *idleConn) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	conns := cp.idle[host]
+	for i := range conns {
+		if conns[i] == ic {
+			cp.idle[host] = append(conns[:i], conns[i+1:]...)
+			cp.closedLocked(host)
+			return true
+		}
+	}
+	return false
+}
+
+// closedLocked records that one of the open connections to the host was closed.
+func (cp *connPool) closedLocked(host string) {
+	if cp.limited() {
+		cp.open[host]--
+	}
+	cp.notifyLocked()
+}
+
+func (cp *connPool) notifyLocked() {
+	close(cp.changed)
+	cp.changed = make(chan struct{})
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/mocks/mockldapconn"
+)
+
+func TestConnectionPoolConfigEnabled(t *testing.T) {
+	require.False(t, ConnectionPoolConfig{}.enabled())
+	require.False(t, ConnectionPoolConfig{MaxIdleConnections: 1}.enabled())
+	require.False(t, ConnectionPoolConfig{IdleTimeout: time.Minute}.enabled())
+	require.True(t, ConnectionPoolConfig{MaxIdleConnections: 1, IdleTimeout: time.Minute}.enabled())
+}
+
+func TestConnPool(t *testing.T) {
+	t.Run("get returns the most recently returned idle connection for the host", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		conn1, conn2, conn3 := mockldapconn.NewMockConn(ctrl), mockldapconn.NewMockConn(ctrl), mockldapconn.NewMockConn(ctrl)
+
+		pool := newConnPool(ConnectionPoolConfig{MaxIdleConnections: 2, IdleTimeout: time.Hour})
+		require.Nil(t, pool.get("host1"))
+
+		pool.put("host1", conn1)
+		pool.put("host1", conn2)
+		pool.put("host2", conn3)
+
+		require.Equal(t, conn2, pool.get("host1"))
+		require.Equal(t, conn1, pool.get("host1"))
+		require.Nil(t, pool.get("host1"))
+		require.Equal(t, conn3, pool.get("host2"))
+		require.Nil(t, pool.get("host2"))
+	})
+
+	t.Run("put closes the connection when the pool for that host is full", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		conn1, conn2 := mockldapconn.NewMockConn(ctrl), mockldapconn.NewMockConn(ctrl)
+		conn2.EXPECT().Close().Times(1)
+
+		pool := newConnPool(ConnectionPoolConfig{MaxIdleConnections: 1, IdleTimeout: time.Hour})
+		pool.put("host1", conn1)
+		pool.put("host1", conn2)
+
+		require.Equal(t, conn1, pool.get("host1"))
+		require.Nil(t, pool.get("host1"))
+	})
+
+	t.Run("idle connections are closed and removed after the idle timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		conn := mockldapconn.NewMockConn(ctrl)
+		closed := make(chan struct{})
+		conn.EXPECT().Close().Times(1).Do(func() { close(closed) })
+
+		pool := newConnPool(ConnectionPoolConfig{MaxIdleConnections: 1, IdleTimeout: time.Millisecond})
+		pool.put("host1", conn)
+
+		select {
+		case <-closed:
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "idle connection was not closed")
+		}
+		require.Nil(t, pool.get("host1"))
+	})
+
+	t.Run("connections taken from the pool are not closed by the idle timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		conn := mockldapconn.NewMockConn(ctrl) // no calls to Close expected
+
+		pool := newConnPool(ConnectionPoolConfig{MaxIdleConnections: 1, IdleTimeout: 10 * time.Millisecond})
+		pool.put("host1", conn)
+		require.Equal(t, conn, pool.get("host1"))
+
+		time.Sleep(50 * time.Millisecond)
+	})
+
+	t.Run("close closes the idle connections, and the connections which are returned afterwards", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		conn1, conn2, conn3 := mockldapconn.NewMockConn(ctrl), mockldapconn.NewMockConn(ctrl), mockldapconn.NewMockConn(ctrl)
+		conn1.EXPECT().Close().Times(1)
+		conn2.EXPECT().Close().Times(1)
+		conn3.EXPECT().Close().Times(1)
+
+		pool := newConnPool(ConnectionPoolConfig{MaxIdleConnections: 2, IdleTimeout: time.Hour})
+		pool.put("host1", conn1)
+		pool.put("host2", conn2)
+		pool.close()
+		require.Nil(t, pool.get("host1"))
+		require.Nil(t, pool.get("host2"))
+
+		pool.put("host1", conn3)
+		require.Nil(t, pool.get("host1"))
+	})
+
+	t.Run("acquire waits while the host has the maximum number of open connections", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		conn := mockldapconn.NewMockConn(ctrl)
+
+		pool := newConnPool(ConnectionPoolConfig{MaxConnections: 1, MaxIdleConnections: 1, IdleTimeout: time.Hour})
+		got, err := pool.acquire(context.Background(), "host1")
+		require.NoError(t, err)
+		require.Nil(t, got) // may open a new connection
+
+		// Other hosts have their own limit.
+		got, err = pool.acquire(context.Background(), "host2")
+		require.NoError(t, err)
+		require.Nil(t, got)
+
+		acquired := make(chan Conn)
+		go func() {
+			got, err := pool.acquire(context.Background(), "host1")
+			if err != nil {
+				got = nil
+			}
+			acquired <- got
+		}()
+		select {
+		case <-acquired:
+			require.FailNow(t, "acquire did not wait")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// The waiting call gets the connection which became idle.
+		pool.put("host1", conn)
+		select {
+		case got := <-acquired:
+			require.Equal(t, conn, got)
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "acquire did not return the idle connection")
+		}
+	})
+
+	t.Run("discard allows a waiting acquire to open a new connection", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		conn := mockldapconn.NewMockConn(ctrl)
+		conn.EXPECT().Close().Times(1)
+
+		pool := newConnPool(ConnectionPoolConfig{MaxConnections: 1, MaxIdleConnections: 1, IdleTimeout: time.Hour})
+		got, err := pool.acquire(context.Background(), "host1")
+		require.NoError(t, err)
+		require.Nil(t, got)
+
+		acquired := make(chan error)
+		go func() {
+			got, err := pool.acquire(context.Background(), "host1")
+			if err == nil && got != nil {
+				err = errors.New("unexpected idle connection")
+			}
+			acquired <- err
+		}()
+
+		pool.discard("host1", conn)
+		select {
+		case err := <-acquired:
+			require.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "acquire did not return")
+		}
+
+		// The connection which could not be opened no longer counts either.
+		pool.discard("host1", nil)
+		got, err = pool.acquire(context.Background(), "host1")
+		require.NoError(t, err)
+		require.Nil(t, got)
+	})
+
+	t.Run("acquire returns the error of the context when it is done while waiting", func(t *testing.T) {
+		pool := newConnPool(ConnectionPoolConfig{MaxConnections: 1, MaxIdleConnections: 1, IdleTimeout: time.Hour})
+		_, err := pool.acquire(context.Background(), "host1")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		got, err := pool.acquire(ctx, "host1")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Nil(t, got)
+	})
+
+	t.Run("acquire does not wait without a maximum number of open connections", func(t *testing.T) {
+		pool := newConnPool(ConnectionPoolConfig{MaxIdleConnections: 1, IdleTimeout: time.Hour})
+		for i := 0; i < 10; i++ {
+			got, err := pool.acquire(context.Background(), "host1")
+			require.NoError(t, err)
+			require.Nil(t, got)
+		}
+	})
+
+	t.Run("is safe for concurrent use", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+		conn := mockldapconn.NewMockConn(ctrl)
+		conn.EXPECT().Close().AnyTimes()
+
+		pool := newConnPool(ConnectionPoolConfig{MaxIdleConnections: 3, IdleTimeout: time.Millisecond})
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					pool.put("host1", conn)
+					_ = pool.get("host1")
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
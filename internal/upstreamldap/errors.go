@@ -0,0 +1,88 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"errors"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"go.pinniped.dev/internal/constable"
+)
+
+// These errors can be matched using errors.Is against the errors returned by a Provider, so callers can tell
+// what kind of problem happened without inspecting the error message. The messages of the returned errors are
+// not changed by being matchable.
+const (
+	// ErrConnectionFailed is matched by errors which happen while connecting to the LDAP server, including TLS
+	// and proxy errors.
+	ErrConnectionFailed = constable.Error("could not connect to the LDAP server")
+
+	// ErrBindFailed is matched by errors which happen while binding to the LDAP server after connecting to it,
+	// either as the bind user or as the end user.
+	ErrBindFailed = constable.Error("could not bind to the LDAP server")
+
+	// ErrUserNotFound is matched by errors which happen because no entry was found for a user.
+	ErrUserNotFound = constable.Error("user was not found")
+
+	// ErrAmbiguousUser is matched by errors which happen because more than one entry was found for a user.
+	ErrAmbiguousUser = constable.Error("more than one entry was found for the user")
+
+	// ErrMultiValuedAttribute is matched by errors which happen because an attribute which must have one value,
+	// such as the username or UID attribute of a user, has more than one value.
+	ErrMultiValuedAttribute = constable.Error("an attribute which must have one value has more than one value")
+
+	// ErrOperationTimeout is matched by errors which happen because the server did not respond to an operation,
+	// such as a bind or a search, within ProviderConfig.OperationTimeout after the connection was established.
+	ErrOperationTimeout = constable.Error("LDAPOperationTimeout")
+)
+
+// classifiedError is an error which also matches its kind using errors.Is, without changing its message.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func classify(kind error, err error) error {
+	return &classifiedError{kind: kind, err: err}
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.kind
+}
+
+// classifyBindError classifies an error which happened while binding. When the connection broke during the bind,
+// the server did not reject the bind, so it is a connection failure instead of a bind failure.
+func classifyBindError(err error) error {
+	var ldapErr *ldap.Error
+	if errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.ErrorNetwork {
+		return classify(ErrConnectionFailed, err)
+	}
+	return classify(ErrBindFailed, err)
+}
+
+// classifyUserEntryCount classifies an error about a search for a user which did not find exactly one entry.
+func classifyUserEntryCount(entryCount int, err error) error {
+	if entryCount == 0 {
+		return classify(ErrUserNotFound, err)
+	}
+	return classify(ErrAmbiguousUser, err)
+}
+
+// classifyAttributeValueCount classifies an error about an attribute which did not have exactly one value. Only too
+// many values is classified, since a missing attribute is usually a mistake in the configuration of the provider.
+func classifyAttributeValueCount(valueCount int, err error) error {
+	if valueCount > 1 {
+		return classify(ErrMultiValuedAttribute, err)
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	cause := errors.New("some cause")
+	err := classify(ErrUserNotFound, fmt.Errorf("some context: %w", cause))
+
+	// The message is not changed by classifying the error.
+	require.EqualError(t, err, "some context: some cause")
+	require.ErrorIs(t, err, ErrUserNotFound)
+	require.ErrorIs(t, err, cause)
+	require.NotErrorIs(t, err, ErrAmbiguousUser)
+
+	// The classification is still found after the error is wrapped again.
+	require.ErrorIs(t, fmt.Errorf("more context: %w", err), ErrUserNotFound)
+}
+
+func TestClassifyBindError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantErrorIs error
+	}{
+		{
+			name:        "the server rejected the bind",
+			err:         fmt.Errorf("error binding: %w", ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("some bind error"))),
+			wantErrorIs: ErrBindFailed,
+		},
+		{
+			name:        "some other error",
+			err:         errors.New("some bind error"),
+			wantErrorIs: ErrBindFailed,
+		},
+		{
+			name:        "the connection broke during the bind",
+			err:         fmt.Errorf("error binding: %w", ldap.NewError(ldap.ErrorNetwork, errors.New("connection reset by peer"))),
+			wantErrorIs: ErrConnectionFailed,
+		},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			err := classifyBindError(test.err)
+			require.EqualError(t, err, test.err.Error())
+			require.ErrorIs(t, err, test.wantErrorIs)
+		})
+	}
+}
+
+func TestClassifyUserEntryCount(t *testing.T) {
+	require.ErrorIs(t, classifyUserEntryCount(0, errors.New("some error")), ErrUserNotFound)
+	require.ErrorIs(t, classifyUserEntryCount(2, errors.New("some error")), ErrAmbiguousUser)
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"time"
+
+	"k8s.io/component-base/metrics"
+)
+
+const (
+	metricsNamespace = "pinniped"
+	metricsSubsystem = "supervisor_upstream_ldap"
+
+	// The operations which are recorded by the operation duration histogram. The set is small and fixed,
+	// and the labels never include usernames or DNs, so that the cardinality of the metric stays low.
+	operationDial                 = "dial"
+	operationBind                 = "bind"
+	operationUserSearch           = "user_search"
+	operationGroupSearch          = "group_search"
+	operationUserSearchBase       = "user_search_base"
+	operationGroupSearchBase      = "group_search_base"
+	operationDefaultNamingContext = "default_naming_context"
+
+	operationResultSuccess = "success"
+	operationResultError   = "error"
+)
+
+// operationDuration records how long each LDAP operation performed by a Provider takes. It is shared by all
+// Providers, because a new Provider is created whenever the configuration of an identity provider changes.
+// Observations are dropped until it is registered by RegisterMetrics.
+var operationDuration = metrics.NewHistogramVec(&metrics.HistogramOpts{
+	Namespace:      metricsNamespace,
+	Subsystem:      metricsSubsystem,
+	Name:           "operation_duration_seconds",
+	Help:           "Latency of the operations performed against upstream LDAP and Active Directory servers.",
+	Buckets:        []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	StabilityLevel: metrics.ALPHA,
+}, []string{"provider", "operation", "result"})
+
+// RegisterMetrics registers the LDAP metrics using registerMetrics, which is usually legacyregistry.MustRegister
+// so that they are served by the Supervisor's metrics endpoint. It must only be called once for each registry.
+func RegisterMetrics(registerMetrics func(...metrics.Registerable)) {
+	registerMetrics(operationDuration)
+}
+
+// observeOperation records the duration of an operation which started at start and finished with err.
+func (p *Provider) observeOperation(operation string, start time.Time, err error) {
+	result := operationResultSuccess
+	if err != nil {
+		result = operationResultError
+	}
+	operationDuration.WithLabelValues(p.GetName(), operation, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/component-base/metrics"
+
+	"go.pinniped.dev/internal/endpointaddr"
+	"go.pinniped.dev/internal/mocks/mockldapconn"
+)
+
+func TestOperationDurationMetrics(t *testing.T) {
+	// The histogram is shared by all Providers, so use a provider name which is not used by any other test.
+	const providerName = "metrics-test-provider"
+
+	registry := metrics.NewKubeRegistry()
+	RegisterMetrics(registry.MustRegister)
+
+	// gatherSampleCounts returns the number of observations for each operation and result of the provider.
+	gatherSampleCounts := func() map[string]uint64 {
+		metricFamilies, err := registry.Gather()
+		require.NoError(t, err)
+		sampleCounts := map[string]uint64{}
+		for _, metricFamily := range metricFamilies {
+			require.Equal(t, "pinniped_supervisor_upstream_ldap_operation_duration_seconds", metricFamily.GetName())
+			for _, metric := range metricFamily.GetMetric() {
+				labels := map[string]string{}
+				for _, label := range metric.GetLabel() {
+					require.NotContains(t, label.GetValue(), testUpstreamUsername)
+					labels[label.GetName()] = label.GetValue()
+				}
+				require.Len(t, labels, 3)
+				if labels["provider"] == providerName {
+					sampleCounts[labels["operation"]+"/"+labels["result"]] = metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return sampleCounts
+	}
+	// Observations from earlier runs of this test in the same process are still in the histogram.
+	countsBefore := gatherSampleCounts()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	userSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testUserSearchResultDNValue,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+					ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+				},
+			},
+		},
+	}
+	groupSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testGroupSearchResultDNValue1,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+				},
+			},
+		},
+	}
+
+	conn := mockldapconn.NewMockConn(ctrl)
+	// The group search happens before the end user bind, so both logins search for groups.
+	conn.EXPECT().SearchWithPaging(gomock.Any(), gomock.Any()).Return(groupSearchResult, nil).Times(2)
+	// A successful login.
+	conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+	conn.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(1)
+	conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+	// A login with the wrong password.
+	conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+	conn.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(1)
+	conn.EXPECT().Bind(testUserSearchResultDNValue, "wrong-password").
+		Return(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("some bind error"))).Times(1)
+	// A connection test.
+	conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+	conn.EXPECT().Close().Times(3)
+
+	dialAttempts := 0
+	ldapProvider := New(ProviderConfig{
+		Name:               providerName,
+		Host:               testHost,
+		ConnectionProtocol: TLS,
+		BindUsername:       testBindUsername,
+		BindPassword:       testBindPassword,
+		UserSearch: UserSearchConfig{
+			Base:              testUserSearchBase,
+			Filter:            testUserSearchFilter,
+			UsernameAttribute: testUserSearchUsernameAttribute,
+			UIDAttribute:      testUserSearchUIDAttribute,
+		},
+		GroupSearch: GroupSearchConfig{
+			Base:               testGroupSearchBase,
+			GroupNameAttribute: testGroupSearchGroupNameAttribute,
+		},
+		Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+			dialAttempts++
+			if dialAttempts == 1 {
+				return nil, errors.New("some dial error")
+			}
+			return conn, nil
+		}),
+	})
+
+	_, _, err := ldapProvider.AuthenticateUser(context.Background(), testUpstreamUsername, testUpstreamPassword, []string{"groups"})
+	require.EqualError(t, err, `error dialing host "ldap.example.com:8443": some dial error`)
+	_, authenticated, err := ldapProvider.AuthenticateUser(context.Background(), testUpstreamUsername, testUpstreamPassword, []string{"groups"})
+	require.NoError(t, err)
+	require.True(t, authenticated)
+	_, authenticated, err = ldapProvider.AuthenticateUser(context.Background(), testUpstreamUsername, "wrong-password", []string{"groups"})
+	require.NoError(t, err)
+	require.False(t, authenticated)
+	_, err = ldapProvider.TestConnection(context.Background())
+	require.NoError(t, err)
+
+	sampleCounts := gatherSampleCounts()
+	for key, count := range countsBefore {
+		sampleCounts[key] -= count
+	}
+	require.Equal(t, map[string]uint64{
+		"dial/error":           1,
+		"dial/success":         3,
+		"bind/success":         4,
+		"bind/error":           1,
+		"user_search/success":  2,
+		"group_search/success": 2,
+	}, sampleCounts)
+}
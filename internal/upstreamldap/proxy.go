@@ -0,0 +1,157 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/net/proxy"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/endpointaddr"
+)
+
+const (
+	// ErrProxy is wrapped by the errors which happen while connecting to the LDAP server through the proxy
+	// configured by ProviderConfig.ProxyURL, as opposed to errors from the LDAP server itself.
+	ErrProxy = constable.Error("error connecting through proxy")
+
+	defaultSOCKS5ProxyPort = 1080
+	defaultHTTPProxyPort   = 80
+)
+
+// IsProxyError returns true when the error, or every error in an aggregate error, happened while connecting
+// to the LDAP server through the configured proxy.
+func IsProxyError(err error) bool {
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		for _, e := range aggregate.Errors() {
+			if !IsProxyError(e) {
+				return false
+			}
+		}
+		return len(aggregate.Errors()) > 0
+	}
+	// The ldap.Error type does not support unwrapping, so look inside it ourselves.
+	var ldapErr *ldap.Error
+	if errors.As(err, &ldapErr) {
+		return errors.Is(ldapErr.Err, ErrProxy)
+	}
+	return errors.Is(err, ErrProxy)
+}
+
+// dialTCP makes the TCP connection to the LDAP server, through the configured proxy when there is one.
+func (p *Provider) dialTCP(ctx context.Context, addr endpointaddr.HostPort) (net.Conn, error) {
+	if p.c.ProxyURL == "" {
+		return p.netDialer().DialContext(ctx, "tcp", addr.Endpoint())
+	}
+
+	proxyURL, err := url.Parse(p.c.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid proxy URL: %v", ErrProxy, err)
+	}
+	conn, err := p.dialThroughProxy(ctx, proxyURL, addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrProxy, proxyURL.Redacted(), err)
+	}
+	return conn, nil
+}
+
+func (p *Provider) dialThroughProxy(ctx context.Context, proxyURL *url.URL, addr endpointaddr.HostPort) (net.Conn, error) {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		proxyAddr, err := endpointaddr.Parse(proxyURL.Host, defaultSOCKS5ProxyPort)
+		if err != nil {
+			return nil, err
+		}
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", proxyAddr.Endpoint(), auth, p.netDialer())
+		if err != nil {
+			return nil, err
+		}
+		return dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", addr.Endpoint())
+	case "http":
+		proxyAddr, err := endpointaddr.Parse(proxyURL.Host, defaultHTTPProxyPort)
+		if err != nil {
+			return nil, err
+		}
+		return p.dialHTTPConnect(ctx, proxyURL, proxyAddr, addr)
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected socks5, socks5h, or http", proxyURL.Scheme)
+	}
+}
+
+// dialHTTPConnect asks an HTTP proxy to open a tunnel to the LDAP server using the CONNECT method.
+func (p *Provider) dialHTTPConnect(ctx context.Context, proxyURL *url.URL, proxyAddr, addr endpointaddr.HostPort) (net.Conn, error) {
+	conn, err := p.netDialer().DialContext(ctx, "tcp", proxyAddr.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+
+	// Bound the CONNECT exchange by the same timeout as the dial, since the proxy may never answer.
+	deadline := time.Now().Add(p.netDialer().Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr.Endpoint()},
+		Host:   addr.Endpoint(),
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy refused to connect to %q: %s", addr.Endpoint(), resp.Status)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		// The server spoke first and its bytes were read along with the proxy's response, so keep them.
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn which first returns the bytes which were already read into its reader.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"go.pinniped.dev/internal/endpointaddr"
+)
+
+func TestDialTCPThroughProxy(t *testing.T) {
+	t.Parallel()
+
+	// The target server writes a greeting to each connection, so the tests can tell that they reached it.
+	target := listen(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("hello from the target"))
+	})
+	targetAddr, err := endpointaddr.Parse(target.Addr().String(), 0)
+	require.NoError(t, err)
+
+	httpProxy := listen(t, func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		if req.Method != http.MethodConnect || req.Host != targetAddr.Endpoint() || req.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			_, _ = conn.Write([]byte("HTTP/1.1 403 Forbidden\r\n\r\n"))
+			return
+		}
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		tunnel(conn, req.Host)
+	})
+
+	socks5Proxy := listen(t, func(conn net.Conn) {
+		// Read the greeting and choose no authentication.
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, make([]byte, greeting[1])); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{5, 0})
+
+		// Read a CONNECT request for an IPv4 address and report success.
+		request := make([]byte, 10)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		ip := net.IP(request[4:8])
+		port := binary.BigEndian.Uint16(request[8:10])
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		tunnel(conn, net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))
+	})
+
+	// A listener which is closed right away, so that connecting to it is refused.
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closedListener.Addr().String()
+	require.NoError(t, closedListener.Close())
+
+	tests := []struct {
+		name     string
+		proxyURL string
+		wantErr  string
+	}{
+		{
+			name:     "no proxy",
+			proxyURL: "",
+		},
+		{
+			name:     "http proxy",
+			proxyURL: "http://user:pass@" + httpProxy.Addr().String(),
+		},
+		{
+			name:     "http proxy refuses the connection",
+			proxyURL: "http://" + httpProxy.Addr().String(),
+			wantErr:  fmt.Sprintf(`error connecting through proxy "http://%s": proxy refused to connect to %q: 403 Forbidden`, httpProxy.Addr(), targetAddr.Endpoint()),
+		},
+		{
+			name:     "socks5 proxy",
+			proxyURL: "socks5://" + socks5Proxy.Addr().String(),
+		},
+		{
+			name:     "proxy cannot be reached",
+			proxyURL: "socks5://user:pass@" + closedAddr,
+			wantErr:  fmt.Sprintf(`error connecting through proxy "socks5://user:xxxxx@%s": socks connect tcp %s->%s: dial tcp %s: connect: connection refused`, closedAddr, closedAddr, targetAddr.Endpoint(), closedAddr),
+		},
+		{
+			name:     "unsupported proxy scheme",
+			proxyURL: "https://" + httpProxy.Addr().String(),
+			wantErr:  fmt.Sprintf(`error connecting through proxy "https://%s": unsupported proxy scheme "https", expected socks5, socks5h, or http`, httpProxy.Addr()),
+		},
+		{
+			name:     "invalid proxy URL",
+			proxyURL: "http://proxy.example.com:port",
+			wantErr:  `error connecting through proxy: invalid proxy URL: parse "http://proxy.example.com:port": invalid port ":port" after host`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn, err := New(ProviderConfig{ProxyURL: tt.proxyURL}).dialTCP(context.Background(), targetAddr)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.True(t, IsProxyError(err))
+				return
+			}
+			require.NoError(t, err)
+			defer conn.Close()
+
+			greeting, err := io.ReadAll(conn)
+			require.NoError(t, err)
+			require.Equal(t, "hello from the target", string(greeting))
+		})
+	}
+}
+
+func TestIsProxyError(t *testing.T) {
+	proxyErr := fmt.Errorf("%w %q: some error", ErrProxy, "socks5://proxy.example.com")
+	otherErr := fmt.Errorf("some other error")
+
+	require.True(t, IsProxyError(proxyErr))
+	require.True(t, IsProxyError(ldap.NewError(ldap.ErrorNetwork, proxyErr)))
+	require.True(t, IsProxyError(fmt.Errorf("some context: %w", ldap.NewError(ldap.ErrorNetwork, proxyErr))))
+	require.True(t, IsProxyError(utilerrors.NewAggregate([]error{ldap.NewError(ldap.ErrorNetwork, proxyErr), proxyErr})))
+
+	require.False(t, IsProxyError(nil))
+	require.False(t, IsProxyError(otherErr))
+	require.False(t, IsProxyError(ldap.NewError(ldap.ErrorNetwork, otherErr)))
+	require.False(t, IsProxyError(utilerrors.NewAggregate([]error{ldap.NewError(ldap.ErrorNetwork, proxyErr), otherErr})))
+}
+
+// listen starts a TCP server which calls handle for each connection and then closes the connection.
+func listen(t *testing.T, handle func(conn net.Conn)) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+// tunnel copies the bytes sent by the address back to the connection until the address closes its connection.
+func tunnel(conn net.Conn, addr string) {
+	upstream, err := net.Dial("tcp", addr)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	_, _ = io.Copy(conn, upstream)
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// connectionTimedOutMessage is the message of the error with which ldap.Conn fails a request after the
+// timeout which was configured by ldap.Conn.SetTimeout, since go-ldap does not export a matchable error for it.
+const connectionTimedOutMessage = "ldap: connection timed out"
+
+// operationTimeoutConn is the ldap.Conn made by the production dialers, whose operations fail with an error which
+// matches ErrOperationTimeout when the server does not respond to them within the operation timeout.
+type operationTimeoutConn struct {
+	*ldap.Conn
+	timeout time.Duration
+}
+
+var _ Conn = &operationTimeoutConn{}
+
+var _ tlsConn = &operationTimeoutConn{}
+
+func (c *operationTimeoutConn) Bind(username, password string) error {
+	return c.classifyError(c.Conn.Bind(username, password))
+}
+
+func (c *operationTimeoutConn) UnauthenticatedBind(username string) error {
+	return c.classifyError(c.Conn.UnauthenticatedBind(username))
+}
+
+func (c *operationTimeoutConn) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result, err := c.Conn.Search(searchRequest)
+	return result, c.classifyError(err)
+}
+
+func (c *operationTimeoutConn) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	result, err := c.Conn.SearchWithPaging(searchRequest, pagingSize)
+	return result, c.classifyError(err)
+}
+
+// classifyError returns the error of an operation, which matches ErrOperationTimeout when the operation timed out.
+// The original error is still wrapped, so it is also still an LDAP network error.
+func (c *operationTimeoutConn) classifyError(err error) error {
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.ErrorNetwork ||
+		ldapErr.Err == nil || ldapErr.Err.Error() != connectionTimedOutMessage {
+		return err
+	}
+	return classify(ErrOperationTimeout,
+		fmt.Errorf("%s: the LDAP server did not respond within %s: %w", ErrOperationTimeout, c.timeout, err))
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/certauthority"
+)
+
+func TestOperationTimeout(t *testing.T) {
+	// The slow servers accept connections and read the requests, but never respond to them.
+	slowTLSServer, caBundle := listenSlowTLS(t)
+	slowPlainServer := listen(t, func(conn net.Conn) {
+		_, _ = io.Copy(io.Discard, conn)
+	})
+
+	const operationTimeout = 100 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		host      string
+		connProto LDAPConnectionProtocol
+		anonymous bool
+		wantError string
+	}{
+		{
+			name:      "a bind which the server never responds to times out when using TLS",
+			host:      slowTLSServer.Addr().String(),
+			connProto: TLS,
+			wantError: fmt.Sprintf(`error binding as "%s": LDAPOperationTimeout: the LDAP server did not respond within 100ms: `+
+				`LDAP Result Code 200 "Network Error": ldap: connection timed out`, testBindUsername),
+		},
+		{
+			name:      "an anonymous bind which the server never responds to times out",
+			host:      slowTLSServer.Addr().String(),
+			connProto: TLS,
+			anonymous: true,
+			wantError: `error binding as anonymous user: LDAPOperationTimeout: the LDAP server did not respond within 100ms: ` +
+				`LDAP Result Code 200 "Network Error": ldap: connection timed out`,
+		},
+		{
+			name:      "a StartTLS request which the server never responds to times out",
+			host:      slowPlainServer.Addr().String(),
+			connProto: StartTLS,
+			wantError: fmt.Sprintf(`error dialing host %q: LDAPOperationTimeout: the LDAP server did not respond within 100ms: `+
+				`LDAP Result Code 200 "Network Error": ldap: connection timed out`, slowPlainServer.Addr().String()),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			config := ProviderConfig{
+				Host:               tt.host,
+				ConnectionProtocol: tt.connProto,
+				CABundle:           caBundle,
+				OperationTimeout:   operationTimeout,
+			}
+			if tt.anonymous {
+				config.AnonymousBind = true
+			} else {
+				config.BindUsername = testBindUsername
+				config.BindPassword = testBindPassword
+			}
+			provider := New(config)
+
+			start := time.Now()
+			_, err := provider.TestConnection(context.Background())
+			elapsed := time.Since(start)
+
+			require.EqualError(t, err, tt.wantError)
+			require.ErrorIs(t, err, ErrOperationTimeout)
+			require.ErrorIs(t, err, ErrConnectionFailed)
+			var ldapErr *ldap.Error
+			require.True(t, errors.As(err, &ldapErr))
+			require.Equal(t, uint16(ldap.ErrorNetwork), ldapErr.ResultCode)
+			require.GreaterOrEqual(t, elapsed, operationTimeout)
+			require.Less(t, elapsed, 10*time.Second)
+		})
+	}
+}
+
+func TestOperationTimeoutForSearches(t *testing.T) {
+	slowTLSServer, caBundle := listenSlowTLS(t)
+
+	provider := New(ProviderConfig{
+		Host:               slowTLSServer.Addr().String(),
+		ConnectionProtocol: TLS,
+		CABundle:           caBundle,
+		OperationTimeout:   100 * time.Millisecond,
+	})
+	conn, err := provider.dialHost(context.Background(), slowTLSServer.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+
+	searchRequest := ldap.NewSearchRequest("dc=pinniped,dc=dev", ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=*)", nil, nil)
+	wantError := `LDAPOperationTimeout: the LDAP server did not respond within 100ms: ` +
+		`LDAP Result Code 200 "Network Error": ldap: connection timed out`
+
+	_, err = conn.Search(searchRequest)
+	require.EqualError(t, err, wantError)
+	require.ErrorIs(t, err, ErrOperationTimeout)
+
+	_, err = conn.SearchWithPaging(searchRequest, 10)
+	require.EqualError(t, err, wantError)
+	require.ErrorIs(t, err, ErrOperationTimeout)
+}
+
+func TestOperationTimeoutDefault(t *testing.T) {
+	require.Equal(t, DefaultOperationTimeout, New(ProviderConfig{}).operationTimeout())
+	require.Equal(t, time.Minute, New(ProviderConfig{OperationTimeout: time.Minute}).operationTimeout())
+}
+
+func TestOperationTimeoutConnDoesNotClassifyOtherErrors(t *testing.T) {
+	conn := &operationTimeoutConn{timeout: time.Minute}
+
+	require.NoError(t, conn.classifyError(nil))
+
+	otherNetworkErr := ldap.NewError(ldap.ErrorNetwork, errors.New("some network error"))
+	require.Same(t, otherNetworkErr, conn.classifyError(otherNetworkErr))
+
+	bindErr := ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("some bind error"))
+	require.Same(t, bindErr, conn.classifyError(bindErr))
+
+	require.EqualError(t, conn.classifyError(ldap.NewError(ldap.ErrorNetwork, errors.New("ldap: connection timed out"))),
+		`LDAPOperationTimeout: the LDAP server did not respond within 1m0s: LDAP Result Code 200 "Network Error": ldap: connection timed out`)
+}
+
+// listenSlowTLS starts a TLS server which completes the handshake but then never responds to any request, and
+// returns it with the CA bundle which trusts its certificate.
+func listenSlowTLS(t *testing.T) (net.Listener, []byte) {
+	t.Helper()
+	ca, err := certauthority.New("Test CA", time.Hour)
+	require.NoError(t, err)
+	cert, err := ca.IssueServerCert(nil, []net.IP{net.ParseIP("127.0.0.1")}, time.Hour)
+	require.NoError(t, err)
+	listener := listen(t, func(conn net.Conn) {
+		tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*cert}, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		_, _ = io.Copy(io.Discard, tlsConn)
+	})
+	return listener, ca.Bundle()
+}
@@ -6,18 +6,24 @@ package upstreamldap
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
 	"net/url"
+	"regexp"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-ldap/ldap/v3"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/utils/strings/slices"
@@ -25,6 +31,7 @@ import (
 
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	"go.pinniped.dev/internal/authenticators"
+	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/crypto/ptls"
 	"go.pinniped.dev/internal/endpointaddr"
 	"go.pinniped.dev/internal/oidc/downstreamsession"
@@ -36,15 +43,44 @@ const (
 	ldapsScheme                             = "ldaps"
 	distinguishedNameAttributeName          = "dn"
 	searchFilterInterpolationLocationMarker = "{}"
-	groupSearchPageSize                     = uint32(250)
+	defaultGroupSearchPageSize              = uint32(1000)
+	defaultUserAttributeForGroups           = "memberOf"
+	hostListSeparator                       = ","
 	defaultLDAPPort                         = uint16(389)
 	defaultLDAPSPort                        = uint16(636)
+	caseIgnoreMatchingRule                  = "caseIgnoreMatch"
+
+	// DefaultConnectionTimeout is used when ProviderConfig.ConnectionTimeout is zero.
+	DefaultConnectionTimeout = 90 * time.Second
+
+	// DefaultKeepAlive is used when ProviderConfig.KeepAlive is zero.
+	DefaultKeepAlive = 30 * time.Second
+
+	// DefaultSearchTimeLimit is used when ProviderConfig.SearchTimeLimit is zero.
+	DefaultSearchTimeLimit = 90 * time.Second
+
+	// DefaultOperationTimeout is used when ProviderConfig.OperationTimeout is zero. It is longer than
+	// DefaultSearchTimeLimit, so that the server's own time limit for a search is normally reached first.
+	DefaultOperationTimeout = 2 * time.Minute
+
+	// groupSearchValidationDN is used in place of a user's DN by ValidateGroupSearch. It is a valid DN, so it
+	// makes a valid filter, but it is not expected to be the DN of any user.
+	groupSearchValidationDN = "cn=pinniped-group-search-validation"
+
+	// ErrGroupSearchBaseUnreadable is wrapped by the error of DryRunGroupSearch when the bind user cannot read
+	// the group search base, as opposed to errors from the group search itself.
+	ErrGroupSearchBaseUnreadable = constable.Error("group search base is unreadable")
 )
 
+// equalityAssertionOfUsernameRegexp matches "attribute={}" in a search filter, e.g. the "uid={}" in "&(objectClass=person)(uid={})".
+var equalityAssertionOfUsernameRegexp = regexp.MustCompile(`(^|\()([A-Za-z0-9.;-]+)=(` + regexp.QuoteMeta(searchFilterInterpolationLocationMarker) + `)`)
+
 // Conn abstracts the upstream LDAP communication protocol (mostly for testing).
 type Conn interface {
 	Bind(username, password string) error
 
+	UnauthenticatedBind(username string) error
+
 	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
 
 	SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error)
@@ -55,6 +91,13 @@ type Conn interface {
 // Our Conn type is subset of the ldap.Client interface, which is implemented by ldap.Conn.
 var _ Conn = &ldap.Conn{}
 
+// tlsConn is implemented by a Conn which can describe its TLS connection, such as ldap.Conn.
+type tlsConn interface {
+	TLSConnectionState() (tls.ConnectionState, bool)
+}
+
+var _ tlsConn = &ldap.Conn{}
+
 // LDAPDialer is a factory of Conn, and the resulting Conn can then be used to interact with an upstream LDAP IDP.
 type LDAPDialer interface {
 	Dial(ctx context.Context, addr endpointaddr.HostPort) (Conn, error)
@@ -76,6 +119,74 @@ const (
 	TLS      = LDAPConnectionProtocol("TLS")
 )
 
+// UIDEncoding is how the raw bytes of the UID attribute's value are encoded to become the user's UID.
+type UIDEncoding string
+
+const (
+	// UIDEncodingBase64URL is unpadded base64url encoding. This is the default when the encoding is empty.
+	UIDEncodingBase64URL = UIDEncoding("Base64URL")
+	// UIDEncodingHex is lower-case hexadecimal encoding.
+	UIDEncodingHex = UIDEncoding("Hex")
+)
+
+// SearchScope is the scope of an LDAP search, relative to its search base.
+type SearchScope string
+
+const (
+	// SearchScopeBase searches only the search base entry itself.
+	SearchScopeBase = SearchScope("base")
+	// SearchScopeOne searches only the immediate children of the search base.
+	SearchScopeOne = SearchScope("one")
+	// SearchScopeSub searches the search base and its entire subtree. This is the default when the scope is empty.
+	SearchScopeSub = SearchScope("sub")
+)
+
+// MultiValuePolicy is how the value of an attribute is chosen when the attribute has more than one value.
+type MultiValuePolicy string
+
+const (
+	// MultiValueError makes it an error for the attribute to have more than one value. This is the default when
+	// the policy is empty.
+	MultiValueError = MultiValuePolicy("Error")
+	// MultiValueFirst chooses the first value, in the order in which the values were returned by the server.
+	MultiValueFirst = MultiValuePolicy("First")
+	// MultiValueLast chooses the last value, in the order in which the values were returned by the server.
+	MultiValueLast = MultiValuePolicy("Last")
+)
+
+// GroupSearchFailurePolicy is what happens to a login when the user's password is accepted but the group search fails.
+type GroupSearchFailurePolicy string
+
+const (
+	// GroupSearchFailOpen lets the user log in without any groups.
+	GroupSearchFailOpen = GroupSearchFailurePolicy("FailOpen")
+	// GroupSearchFailClosed rejects the login. This is the default when the policy is empty.
+	GroupSearchFailClosed = GroupSearchFailurePolicy("FailClosed")
+)
+
+// GroupSearchMode is how the groups of a user are found.
+type GroupSearchMode string
+
+const (
+	// GroupSearchModeFilter searches for the group entries under the group search base which match the group
+	// search filter. This is the default when the mode is empty.
+	GroupSearchModeFilter = GroupSearchMode("filter")
+	// GroupSearchModeUserAttribute reads the DNs of the user's groups from an attribute of the user's entry.
+	GroupSearchModeUserAttribute = GroupSearchMode("userAttribute")
+)
+
+// ldapScope returns the ldap library's scope constant for this scope.
+func (s SearchScope) ldapScope() int {
+	switch s {
+	case SearchScopeBase:
+		return ldap.ScopeBaseObject
+	case SearchScopeOne:
+		return ldap.ScopeSingleLevel
+	default:
+		return ldap.ScopeWholeSubtree
+	}
+}
+
 // ProviderConfig includes all of the settings for connection and searching for users and groups in
 // the upstream LDAP IDP. It also provides methods for testing the connection and performing logins.
 // The nested structs are not pointer fields to enable deep copy on function params and return values.
@@ -87,12 +198,41 @@ type ProviderConfig struct {
 	ResourceUID types.UID
 
 	// Host is the hostname or "hostname:port" of the LDAP server. When the port is not specified,
-	// the default LDAP port will be used.
+	// the default LDAP port will be used. Multiple servers may be given as a comma-separated list,
+	// in which case they will be tried in order until one of them accepts a connection.
 	Host string
 
 	// ConnectionProtocol determines how to establish the connection to the server. Either StartTLS or TLS.
 	ConnectionProtocol LDAPConnectionProtocol
 
+	// ConnectionTimeout bounds how long it may take to establish a network connection to the server.
+	// Zero means to use DefaultConnectionTimeout.
+	ConnectionTimeout time.Duration
+
+	// OperationTimeout bounds how long to wait for the server to respond to each operation, such as a bind or a search,
+	// on an established connection. Unlike ConnectionTimeout, it also applies when the server accepted the connection
+	// but then stopped responding. An operation which times out fails with an error which matches ErrOperationTimeout.
+	// It does not apply to the connections made by a custom Dialer. Zero means to use DefaultOperationTimeout.
+	OperationTimeout time.Duration
+
+	// KeepAlive is the interval between TCP keep-alive probes on the network connections to the server, so that
+	// idle connections are not dropped by firewalls. Zero means to use DefaultKeepAlive, and a negative value
+	// disables keep-alive probes.
+	KeepAlive time.Duration
+
+	// ProxyURL is the URL of a proxy through which to make the TCP connections to the server, e.g.
+	// "socks5://proxy.example.com:1080" or "http://proxy.example.com:3128". SOCKS5 proxies and HTTP proxies
+	// which support the CONNECT method are supported. Empty means to connect to the server directly.
+	ProxyURL string
+
+	// SearchSizeLimit is the maximum number of entries which the server should return for each group search.
+	// Zero means to use the server's limit. The user searches always ask for at most two entries.
+	SearchSizeLimit int
+
+	// SearchTimeLimit is how long the server may spend on each search, in whole seconds.
+	// Zero means to use DefaultSearchTimeLimit.
+	SearchTimeLimit time.Duration
+
 	// PEM-encoded CA cert bundle to trust when connecting to the LDAP server. Can be nil.
 	CABundle []byte
 
@@ -102,6 +242,10 @@ type ProviderConfig struct {
 	// BindPassword is the password to use when performing a bind with the upstream LDAP IDP.
 	BindPassword string
 
+	// AnonymousBind means to perform an anonymous bind instead of binding as BindUsername before performing
+	// searches. It should only be used with LDAP servers which allow anonymous users to search the directory.
+	AnonymousBind bool
+
 	// UserSearch contains information about how to search for users in the upstream LDAP IDP.
 	UserSearch UserSearchConfig
 
@@ -111,6 +255,13 @@ type ProviderConfig struct {
 	// Dialer exists to enable testing. When nil, will use a default appropriate for production use.
 	Dialer LDAPDialer
 
+	// ConnectionPool configures the reuse of connections across logins and refreshes. The zero value disables reuse.
+	ConnectionPool ConnectionPoolConfig
+
+	// AuthenticationCache configures the reuse of successful login results for the same username and password.
+	// The zero value disables caching.
+	AuthenticationCache AuthenticationCacheConfig
+
 	// UIDAttributeParsingOverrides are mappings between an attribute name and a way to parse it as a UID when
 	// it comes out of LDAP.
 	UIDAttributeParsingOverrides map[string]func(*ldap.Entry) (string, error)
@@ -138,21 +289,108 @@ type UserSearchConfig struct {
 	// UIDAttribute is the attribute in the LDAP entry from which the user's unique ID should be
 	// retrieved.
 	UIDAttribute string
+
+	// UsernameAttributeMultiValuePolicy is how the username is chosen when the UsernameAttribute has more than one
+	// value in the user's entry. Empty means to use MultiValueError. The UIDAttribute must always have exactly one
+	// value, since choosing one of several values could give two users the same unique ID.
+	UsernameAttributeMultiValuePolicy MultiValuePolicy
+
+	// UIDAttributeEncoding is how the raw bytes of the UIDAttribute's value are encoded to become the
+	// user's unique ID. Empty means to use UIDEncodingBase64URL. It is not used for attributes which have
+	// an entry in UIDAttributeParsingOverrides.
+	UIDAttributeEncoding UIDEncoding
+
+	// UsernameCaseInsensitive causes each "attribute={}" equality assertion in the Filter, or in the default
+	// filter, to use the caseIgnoreMatch extensible matching rule, so the username is matched regardless of case.
+	UsernameCaseInsensitive bool
+
+	// UsernameNormalization describes the changes which are made to the username typed by the user before it is
+	// used to find the user's entry. The zero value makes no changes.
+	UsernameNormalization UsernameNormalizationConfig
+
+	// UserDNTemplate, when not empty, is used instead of the user search to find the user's entry. The "{}"
+	// placeholder is replaced by the escaped username to form the user's DN, and that entry is read directly
+	// instead of searching Base. When set, Filter and Scope are ignored.
+	UserDNTemplate string
+
+	// Scope is the scope of the user search, relative to Base. Empty means to use SearchScopeSub.
+	Scope SearchScope
+
+	// AllowMultipleMatches causes the first entry returned by the server to be used when the user search finds
+	// more than one entry for a username. Otherwise, finding more than one entry is an error which wraps
+	// ErrAmbiguousUser, so that a filter which is too loose cannot cause someone to be authenticated as the wrong user.
+	AllowMultipleMatches bool
+
+	// ExtraAttributes maps keys of the authenticated user's extra information to the attributes in the LDAP entry
+	// whose values become the values of those keys. Every value of a multi-valued attribute is used, and a key is
+	// omitted when the user's entry has no values for its attribute. The "dn" attribute means the user's DN.
+	ExtraAttributes map[string]string
+}
+
+// UsernameNormalizationConfig describes how the username typed by the user is changed before it replaces the
+// placeholder in the user search filter or in the UserDNTemplate. The changes are made in the order of the fields.
+type UsernameNormalizationConfig struct {
+	// TrimWhitespace removes any leading and trailing whitespace.
+	TrimWhitespace bool
+
+	// StripNetBIOSPrefix removes everything up to and including the first backslash, e.g. "EXAMPLE\jdoe"
+	// becomes "jdoe".
+	StripNetBIOSPrefix bool
+
+	// StripDomainSuffix, when not empty, is a domain which is removed along with the "@" before it from the end
+	// of the username. It is compared case-insensitively, e.g. "example.com" changes "jdoe@Example.COM" to "jdoe".
+	StripDomainSuffix string
 }
 
 // GroupSearchConfig contains information about how to search for group membership for users in the upstream LDAP IDP.
 type GroupSearchConfig struct {
+	// Mode is how the user's groups are found. Empty means to use GroupSearchModeFilter.
+	Mode GroupSearchMode
+
+	// UserAttributeForGroups is the attribute of the user's entry whose values are the DNs of the user's groups
+	// when Mode is GroupSearchModeUserAttribute. Empty means to use `memberOf`. When GroupNameAttribute is set to
+	// something other than the DN, each group's entry is read to find its group name.
+	UserAttributeForGroups string
+
 	// Base is the base DN to use for the group search in the upstream LDAP IDP. Empty means to skip group search
 	// entirely, in which case authenticated users will not belong to any groups from the upstream LDAP IDP.
+	// It is ignored when Mode is GroupSearchModeUserAttribute, as are Filter, PageSize, and Scope.
 	Base string
 
 	// Filter is the filter to use for the group search in the upstream LDAP IDP. Empty means to use `member={}`.
 	Filter string
 
 	// GroupNameAttribute is the attribute in the LDAP group entry from which the group name should be
-	// retrieved. Empty means to use 'cn'.
+	// retrieved. Empty means to use the group entry's DN.
 	GroupNameAttribute string
 
+	// UseDNWhenGroupNameIsMissing means to use the group entry's DN as the group name when the entry has no value
+	// for the GroupNameAttribute. Group entries which have neither are then skipped instead of causing an error.
+	UseDNWhenGroupNameIsMissing bool
+
+	// PageSize is the number of group entries to request per page when searching for groups using the
+	// simple paged results control. All pages are read. Zero means to use 1000.
+	PageSize uint32
+
+	// Scope is the scope of the group search, relative to Base. Empty means to use SearchScopeSub.
+	Scope SearchScope
+
+	// NestedGroupsMaxDepth is how many levels of groups above the user's own groups are also given to the user.
+	// The groups which contain a group are found by searching again with the group's DN in place of the user's DN
+	// in the Filter, or by reading the UserAttributeForGroups of the group's entry when Mode is
+	// GroupSearchModeUserAttribute. Each group is only searched once, so cycles in the group memberships do not
+	// cause loops. Zero means that only the user's own groups are used.
+	NestedGroupsMaxDepth int
+
+	// AllowedGroups is a list of group DNs. When not empty, only the groups found by the group search whose DNs
+	// are in this list are returned. DNs which cannot be parsed never match any group.
+	AllowedGroups []string
+
+	// FailurePolicy determines whether a login succeeds without groups or fails when the group search fails.
+	// It does not apply to refreshes, which always fail when the group search fails. Empty means to use
+	// GroupSearchFailClosed.
+	FailurePolicy GroupSearchFailurePolicy
+
 	// SkipGroupRefresh skips the group refresh operation that occurs with each refresh
 	// (every 5 minutes). This can be done if group search is very slow or resource intensive for the LDAP
 	// server.
@@ -160,7 +398,9 @@ type GroupSearchConfig struct {
 }
 
 type Provider struct {
-	c ProviderConfig
+	c         ProviderConfig
+	pool      *connPool  // nil when connection pooling is disabled
+	authCache *authCache // nil when authentication caching is disabled
 }
 
 var _ provider.UpstreamLDAPIdentityProviderI = &Provider{}
@@ -169,7 +409,22 @@ var _ authenticators.UserAuthenticator = &Provider{}
 // Create a Provider. The config is not a pointer to ensure that a copy of the config is created,
 // making the resulting Provider use an effectively read-only configuration.
 func New(config ProviderConfig) *Provider {
-	return &Provider{c: config}
+	p := &Provider{c: config}
+	if config.ConnectionPool.enabled() {
+		p.pool = newConnPool(config.ConnectionPool)
+	}
+	if config.AuthenticationCache.enabled() {
+		p.authCache = newAuthCache(config.AuthenticationCache)
+	}
+	return p
+}
+
+// Close closes the idle connections which the Provider keeps for reuse, when connection pooling is enabled. The
+// Provider can still be used afterwards, but it will not keep any connections for reuse anymore.
+func (p *Provider) Close() {
+	if p.pool != nil {
+		p.pool.close()
+	}
 }
 
 // A reader for the config. Returns a copy of the config to keep the underlying config read-only.
@@ -182,16 +437,12 @@ func (p *Provider) PerformRefresh(ctx context.Context, storedRefreshAttributes p
 	defer t.LogIfLong(500 * time.Millisecond) // to help users debug slow LDAP searches
 	userDN := storedRefreshAttributes.DN
 
-	conn, err := p.dial(ctx)
+	conn, host, err := p.connectAsBindUser(ctx)
 	if err != nil {
-		return nil, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
-	}
-	defer conn.Close()
-
-	err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
-	if err != nil {
-		return nil, fmt.Errorf(`error binding as %q before user search: %w`, p.c.BindUsername, err)
+		return nil, err
 	}
+	healthy := false
+	defer func() { p.releaseConn(conn, host, healthy) }()
 
 	searchResult, err := p.performUserRefreshSearch(conn, userDN)
 	if err != nil {
@@ -202,9 +453,9 @@ func (p *Provider) PerformRefresh(ctx context.Context, storedRefreshAttributes p
 	// if any more or less than one entry, error.
 	// we don't need to worry about logging this because we know it's a dn.
 	if len(searchResult.Entries) != 1 {
-		return nil, fmt.Errorf(`searching for user %q resulted in %d search results, but expected 1 result`,
+		return nil, classifyUserEntryCount(len(searchResult.Entries), fmt.Errorf(`searching for user %q resulted in %d search results, but expected 1 result`,
 			userDN, len(searchResult.Entries),
-		)
+		))
 	}
 
 	userEntry := searchResult.Entries[0]
@@ -212,7 +463,7 @@ func (p *Provider) PerformRefresh(ctx context.Context, storedRefreshAttributes p
 		return nil, fmt.Errorf(`searching for user with original DN %q resulted in search result without DN`, userDN)
 	}
 
-	newUsername, err := p.getSearchResultAttributeValue(p.c.UserSearch.UsernameAttribute, userEntry, userDN)
+	newUsername, err := p.getSearchResultAttributeValue(p.c.UserSearch.UsernameAttribute, userEntry, userDN, p.c.UserSearch.UsernameAttributeMultiValuePolicy)
 	if err != nil {
 		return nil, err
 	}
@@ -238,24 +489,29 @@ func (p *Provider) PerformRefresh(ctx context.Context, storedRefreshAttributes p
 	}
 
 	if p.c.GroupSearch.SkipGroupRefresh {
+		healthy = true
 		return storedRefreshAttributes.Groups, nil
 	}
 	// if we were not granted the groups scope, we should not search for groups or return any.
 	if !slices.Contains(storedRefreshAttributes.GrantedScopes, oidcapi.ScopeGroups) {
+		healthy = true
 		return nil, nil
 	}
 
-	mappedGroupNames, err := p.searchGroupsForUserDN(conn, userDN)
+	mappedGroupNames, err := p.searchGroupsForUser(conn, userDN, userEntry)
 	if err != nil {
 		return nil, err
 	}
+	healthy = true
 	return mappedGroupNames, nil
 }
 
 func (p *Provider) performUserRefreshSearch(conn Conn, userDN string) (*ldap.SearchResult, error) {
 	search := p.refreshUserSearchRequest(userDN)
 
+	start := time.Now()
 	searchResult, err := conn.Search(search)
+	p.observeOperation(operationUserSearch, start, err)
 
 	if err != nil {
 		return nil, fmt.Errorf(`error searching for user %q: %w`, userDN, err)
@@ -263,13 +519,179 @@ func (p *Provider) performUserRefreshSearch(conn Conn, userDN string) (*ldap.Sea
 	return searchResult, nil
 }
 
-func (p *Provider) dial(ctx context.Context) (Conn, error) {
-	tlsAddr, err := endpointaddr.Parse(p.c.Host, defaultLDAPSPort)
+// dial connects to the first of the configured hosts which accepts a connection, trying each in order.
+// When the context has a deadline, the remaining time is divided evenly across the hosts which have not
+// been tried yet, so one unresponsive host cannot use up all the time available for the others.
+// Returns the connection along with the configured host to which it was made.
+func (p *Provider) dial(ctx context.Context) (Conn, string, error) {
+	start := time.Now()
+	conn, host, err := p.dialFirstAvailableHost(ctx, p.dialHost)
+	p.observeOperation(operationDial, start, err)
+	return conn, host, err
+}
+
+func (p *Provider) dialFirstAvailableHost(
+	ctx context.Context,
+	dialHost func(ctx context.Context, host string) (Conn, error),
+) (Conn, string, error) {
+	hosts := p.hosts()
+	errs := make([]error, 0, len(hosts))
+	for i, host := range hosts {
+		hostCtx, cancel := contextForDialAttempt(ctx, len(hosts)-i)
+		conn, err := dialHost(hostCtx, host)
+		cancel()
+		if err == nil {
+			return conn, host, nil
+		}
+		if len(hosts) == 1 {
+			return nil, "", err
+		}
+		plog.DebugErr("error dialing ldap host, trying next host", err, "upstreamName", p.GetName(), "host", host)
+		errs = append(errs, fmt.Errorf("host %q: %w", host, err))
+	}
+	return nil, "", utilerrors.NewAggregate(errs)
+}
+
+// connectAsBindUser returns a connection which is bound as the bind user, along with the configured host to which
+// it is connected. When connection pooling is enabled, an idle connection is reused when one is available.
+// A reused connection which cannot be bound anymore, e.g. because the server closed it while it was idle,
+// is closed and transparently replaced by another connection.
+func (p *Provider) connectAsBindUser(ctx context.Context) (Conn, string, error) {
+	for {
+		conn, host, reused, err := p.openConn(ctx)
+		if err != nil {
+			return nil, "", classify(ErrConnectionFailed, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err))
+		}
+
+		err = p.bindAsBindUser(conn)
+		if err == nil {
+			return conn, host, nil
+		}
+		p.discardConn(conn, host)
+		if !reused {
+			return nil, "", classifyBindError(fmt.Errorf(`error binding as %s before user search: %w`, p.bindUserDescription(), err))
+		}
+		plog.DebugErr("could not reuse idle ldap connection, so trying another connection", err, "upstreamName", p.GetName(), "host", host)
+	}
+}
+
+// openConn returns an idle connection when there is one, or else a new connection to the first of the configured
+// hosts which accepts one, along with the host and whether the connection is being reused. When the pool limits the
+// open connections to a host, a new connection to it is only dialed once it may be opened, and a connection to it
+// which became idle while waiting for that is reused instead.
+func (p *Provider) openConn(ctx context.Context) (Conn, string, bool, error) {
+	if conn, host := p.getIdleConn(); conn != nil {
+		return conn, host, true, nil
+	}
+	if p.pool == nil {
+		conn, host, err := p.dial(ctx)
+		return conn, host, false, err
+	}
+
+	reused := false
+	start := time.Now()
+	conn, host, err := p.dialFirstAvailableHost(ctx, func(ctx context.Context, host string) (Conn, error) {
+		conn, err := p.pool.acquire(ctx, host)
+		if err != nil || conn != nil {
+			reused = conn != nil
+			return conn, err
+		}
+		conn, err = p.dialHost(ctx, host)
+		if err != nil {
+			p.pool.discard(host, nil)
+		}
+		return conn, err
+	})
+	p.observeOperation(operationDial, start, err)
+	return conn, host, reused, err
+}
+
+// bindAsBindUser performs the bind which precedes searches, either as the configured bind user or anonymously.
+func (p *Provider) bindAsBindUser(conn Conn) error {
+	start := time.Now()
+	var err error
+	if p.c.AnonymousBind {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
+	}
+	p.observeOperation(operationBind, start, err)
+	return err
+}
+
+// bindUserDescription describes who bindAsBindUser binds as, for use in error messages.
+func (p *Provider) bindUserDescription() string {
+	if p.c.AnonymousBind {
+		return "anonymous user"
+	}
+	return fmt.Sprintf("%q", p.c.BindUsername)
+}
+
+// getIdleConn returns an idle connection from the pool, preferring the hosts in the order that they are configured.
+func (p *Provider) getIdleConn() (Conn, string) {
+	if p.pool == nil {
+		return nil, ""
+	}
+	for _, host := range p.hosts() {
+		if conn := p.pool.get(host); conn != nil {
+			return conn, host
+		}
+	}
+	return nil, ""
+}
+
+// releaseConn returns a connection to the pool for reuse when it is healthy and pooling is enabled, or else closes it.
+func (p *Provider) releaseConn(conn Conn, host string, healthy bool) {
+	if !healthy {
+		p.discardConn(conn, host)
+		return
+	}
+	if p.pool == nil {
+		conn.Close()
+		return
+	}
+	p.pool.put(host, conn)
+}
+
+// discardConn closes a connection from connectAsBindUser without returning it to the pool.
+func (p *Provider) discardConn(conn Conn, host string) {
+	if p.pool == nil {
+		conn.Close()
+		return
+	}
+	p.pool.discard(host, conn)
+}
+
+// hosts returns the list of configured hosts, in the order in which they should be tried.
+func (p *Provider) hosts() []string {
+	hosts := []string{}
+	for _, host := range strings.Split(p.c.Host, hostListSeparator) {
+		if host = strings.TrimSpace(host); len(host) > 0 {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		// Let the dial attempt fail with the usual error for an invalid host.
+		hosts = append(hosts, p.c.Host)
+	}
+	return hosts
+}
+
+func contextForDialAttempt(ctx context.Context, remainingAttempts int) (context.Context, context.CancelFunc) {
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline || remainingAttempts <= 1 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(remainingAttempts))
+}
+
+func (p *Provider) dialHost(ctx context.Context, host string) (Conn, error) {
+	tlsAddr, err := endpointaddr.Parse(host, defaultLDAPSPort)
 	if err != nil {
 		return nil, ldap.NewError(ldap.ErrorNetwork, err)
 	}
 
-	startTLSAddr, err := endpointaddr.Parse(p.c.Host, defaultLDAPPort)
+	startTLSAddr, err := endpointaddr.Parse(host, defaultLDAPPort)
 	if err != nil {
 		return nil, ldap.NewError(ldap.ErrorNetwork, err)
 	}
@@ -305,15 +727,21 @@ func (p *Provider) dialTLS(ctx context.Context, addr endpointaddr.HostPort) (Con
 		return nil, ldap.NewError(ldap.ErrorNetwork, err)
 	}
 
-	dialer := &tls.Dialer{NetDialer: netDialer(), Config: tlsConfig}
-	c, err := dialer.DialContext(ctx, "tcp", addr.Endpoint())
+	var c net.Conn
+	if p.c.ProxyURL == "" {
+		dialer := &tls.Dialer{NetDialer: p.netDialer(), Config: tlsConfig}
+		c, err = dialer.DialContext(ctx, "tcp", addr.Endpoint())
+	} else {
+		c, err = p.dialTLSThroughProxy(ctx, addr, tlsConfig)
+	}
 	if err != nil {
 		return nil, ldap.NewError(ldap.ErrorNetwork, err)
 	}
 
 	conn := ldap.NewConn(c, true)
+	conn.SetTimeout(p.operationTimeout())
 	conn.Start()
-	return conn, nil
+	return &operationTimeoutConn{Conn: conn, timeout: p.operationTimeout()}, nil
 }
 
 // dialTLS is a default implementation of the Dialer, used when Dialer is nil and ConnectionProtocol is StartTLS.
@@ -328,23 +756,61 @@ func (p *Provider) dialStartTLS(ctx context.Context, addr endpointaddr.HostPort)
 	// Unfortunately, this seems to be required for StartTLS, even though it is not needed for regular TLS.
 	tlsConfig.ServerName = addr.Host
 
-	c, err := netDialer().DialContext(ctx, "tcp", addr.Endpoint())
+	c, err := p.dialTCP(ctx, addr)
 	if err != nil {
 		return nil, ldap.NewError(ldap.ErrorNetwork, err)
 	}
 
 	conn := ldap.NewConn(c, false)
+	conn.SetTimeout(p.operationTimeout())
 	conn.Start()
+	timeoutConn := &operationTimeoutConn{Conn: conn, timeout: p.operationTimeout()}
 	err = conn.StartTLS(tlsConfig)
+	if err != nil {
+		conn.Close()
+		return nil, timeoutConn.classifyError(err)
+	}
+
+	return timeoutConn, nil
+}
+
+// dialTLSThroughProxy makes the TCP connection through the configured proxy and then performs the TLS handshake,
+// bounded by the same timeout that tls.Dialer would have used.
+func (p *Provider) dialTLSThroughProxy(ctx context.Context, addr endpointaddr.HostPort, tlsConfig *tls.Config) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.netDialer().Timeout)
+	defer cancel()
+
+	c, err := p.dialTCP(ctx, addr)
 	if err != nil {
 		return nil, err
 	}
 
-	return conn, nil
+	tlsConfig.ServerName = addr.Host
+	tlsConn := tls.Client(c, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return tlsConn, nil
 }
 
-func netDialer() *net.Dialer {
-	return &net.Dialer{Timeout: time.Minute}
+func (p *Provider) netDialer() *net.Dialer {
+	timeout := p.c.ConnectionTimeout
+	if timeout == 0 {
+		timeout = DefaultConnectionTimeout
+	}
+	keepAlive := p.c.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	return &net.Dialer{Timeout: timeout, KeepAlive: keepAlive}
+}
+
+func (p *Provider) operationTimeout() time.Duration {
+	if p.c.OperationTimeout == 0 {
+		return DefaultOperationTimeout
+	}
+	return p.c.OperationTimeout
 }
 
 func (p *Provider) tlsConfig() (*tls.Config, error) {
@@ -370,31 +836,321 @@ func (p *Provider) GetResourceUID() types.UID {
 // Return a URL which uniquely identifies this LDAP provider, e.g. "ldaps://host.example.com:1234?base=user-search-base".
 // This URL is not used for connecting to the provider, but rather is used for creating a globally unique user
 // identifier by being combined with the user's UID, since user UIDs are only unique within one provider.
+// When multiple hosts are configured, only the first is used, so that adding failover hosts to an existing
+// provider does not change the identity of its users.
 func (p *Provider) GetURL() *url.URL {
-	u := &url.URL{Scheme: ldapsScheme, Host: p.c.Host}
+	u := &url.URL{Scheme: ldapsScheme, Host: p.hosts()[0]}
 	q := u.Query()
 	q.Set("base", p.c.UserSearch.Base)
 	u.RawQuery = q.Encode()
 	return u
 }
 
+// TestConnectionResult describes how far TestConnectionWithResult got before it returned.
+type TestConnectionResult struct {
+	// Host is the configured host which was reached, or empty when none of the configured hosts could be reached.
+	Host string
+	// Reachable is true when a connection was made to one of the configured hosts.
+	Reachable bool
+	// Bound is true when the bind succeeded. When using an anonymous bind, it is only true when the
+	// anonymous user could also read the user search base.
+	Bound bool
+	// ServerCertificateNotAfter is the expiration time of the leaf certificate which was presented by the server
+	// that was reached, or the zero time when it is not known.
+	ServerCertificateNotAfter time.Time
+}
+
 // TestConnection provides a method for testing the connection and bind settings. It performs a dial and bind
-// and returns any errors that we encountered.
-func (p *Provider) TestConnection(ctx context.Context) error {
+// and returns any errors that we encountered. When using an anonymous bind, it also checks that the anonymous
+// user can read the user search base. When successful, it returns which of the configured hosts it was able to reach.
+func (p *Provider) TestConnection(ctx context.Context) (string, error) {
+	result, err := p.TestConnectionWithResult(ctx)
+	if err != nil {
+		return "", err
+	}
+	return result.Host, nil
+}
+
+// TestConnectionWithResult is the same as TestConnection, except that it also describes which steps
+// succeeded, even when it returns an error. The returned result is never nil.
+// When the connection is dropped while it is being tested, e.g. because a firewall dropped an idle connection
+// on the way to the server, then the connection is tested once more before giving up.
+func (p *Provider) TestConnectionWithResult(ctx context.Context) (*TestConnectionResult, error) {
+	result, err := p.testConnection(ctx)
+	if err != nil && isDroppedConnectionError(err) {
+		plog.DebugErr("ldap connection was dropped while testing it, so trying again", err, "upstreamName", p.GetName())
+		result, err = p.testConnection(ctx)
+	}
+	return result, err
+}
+
+func (p *Provider) testConnection(ctx context.Context) (*TestConnectionResult, error) {
+	result := &TestConnectionResult{}
+
 	err := p.validateConfig()
 	if err != nil {
+		return result, err
+	}
+
+	conn, host, err := p.dial(ctx)
+	if err != nil {
+		return result, classify(ErrConnectionFailed, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err))
+	}
+	defer conn.Close()
+	result.Host = host
+	result.Reachable = true
+	result.ServerCertificateNotAfter = serverCertificateNotAfter(conn)
+
+	err = p.bindAsBindUser(conn)
+	if err != nil {
+		return result, classifyBindError(fmt.Errorf(`error binding as %s: %w`, p.bindUserDescription(), err))
+	}
+
+	if p.c.AnonymousBind {
+		// An anonymous bind will usually succeed, even when the server does not allow anonymous searches.
+		start := time.Now()
+		_, err = conn.Search(p.searchBaseRequest(p.c.UserSearch.Base))
+		p.observeOperation(operationUserSearchBase, start, err)
+		if err != nil {
+			return result, fmt.Errorf(`error searching for user search base %q as anonymous user: %w`, p.c.UserSearch.Base, err)
+		}
+	}
+
+	result.Bound = true
+	return result, nil
+}
+
+// isDroppedConnectionError returns true when the error was caused by the connection being reset or closed
+// by something between the client and the server, as opposed to an error returned by the server itself.
+func isDroppedConnectionError(err error) bool {
+	// The ldap.Error type does not support unwrapping, so look inside it ourselves.
+	var ldapErr *ldap.Error
+	if errors.As(err, &ldapErr) {
+		err = ldapErr.Err
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// serverCertificateNotAfter returns the expiration time of the server's leaf certificate, or the zero time when the
+// connection cannot describe its TLS connection state, e.g. because it is a fake connection used by tests.
+func serverCertificateNotAfter(conn Conn) time.Time {
+	c, ok := conn.(tlsConn)
+	if !ok {
+		return time.Time{}
+	}
+	state, ok := c.TLSConnectionState()
+	if !ok || len(state.PeerCertificates) == 0 {
+		return time.Time{}
+	}
+	return state.PeerCertificates[0].NotAfter
+}
+
+// DryRunResult describes how far DryRun got before it returned.
+type DryRunResult struct {
+	TestConnectionResult
+	// UserSearchBaseValid is true when the user search base could be read, or when it is not configured.
+	UserSearchBaseValid bool
+	// User is the user who was found for the sample username, or nil when no sample username was given.
+	User user.Info
+	// Groups are the groups of the sample user, or of the bind user when no sample username was given.
+	Groups []string
+	// DisallowedGroupCount is the number of the bind user's groups which were filtered out because they
+	// are not in the AllowedGroups. It is only counted when no sample username was given.
+	DisallowedGroupCount int
+}
+
+// DryRun provides a method for testing all of the Provider settings, for example from a CLI before the
+// settings are used to configure an identity provider. It performs the same checks as the LDAPIdentityProvider
+// controller, using the configured ConnectionProtocol as-is. When a sample username is given, it performs a
+// DryRunAuthenticateUser for that user. Otherwise, it performs a DryRunGroupSearch for the bind user, unless
+// group search is not configured or an anonymous bind is being used. The returned result is never nil, and
+// describes which steps succeeded even when an error is returned.
+func (p *Provider) DryRun(ctx context.Context, sampleUsername string) (*DryRunResult, error) {
+	result := &DryRunResult{}
+
+	connectionResult, err := p.TestConnectionWithResult(ctx)
+	result.TestConnectionResult = *connectionResult
+	if err != nil {
+		return result, err
+	}
+
+	if err = p.ValidateUserSearchBase(ctx); err != nil {
+		return result, err
+	}
+	result.UserSearchBaseValid = true
+
+	if len(sampleUsername) > 0 {
+		response, authenticated, err := p.DryRunAuthenticateUser(ctx, sampleUsername, []string{oidcapi.ScopeGroups})
+		if err != nil {
+			return result, err
+		}
+		if !authenticated {
+			return result, classify(ErrUserNotFound, fmt.Errorf(`sample user %q was not found`, sampleUsername))
+		}
+		result.User = response.User
+		result.Groups = response.User.GetGroups()
+		return result, nil
+	}
+
+	if p.c.AnonymousBind {
+		// There is no bind user on whose behalf the groups could be searched.
+		return result, nil
+	}
+	result.Groups, result.DisallowedGroupCount, err = p.DryRunGroupSearch(ctx, p.c.BindUsername)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// GroupSearchResult describes the groups which were found for a user.
+type GroupSearchResult struct {
+	// Groups are the names of the user's groups which are allowed by the AllowedGroups.
+	Groups []string
+	// DisallowedGroupCount is the number of the user's groups which were filtered out because they
+	// are not in the AllowedGroups.
+	DisallowedGroupCount int
+	// DirectGroupCount is the number of groups of which the user is a member, before resolving the groups
+	// which contain them.
+	DirectGroupCount int
+	// NestedGroupCount is the number of groups which were found by resolving the groups which contain the
+	// user's groups. It is always zero when NestedGroupsMaxDepth is zero.
+	NestedGroupCount int
+}
+
+// DryRunGroupSearch provides a method for testing the group search settings. It performs a dial and bind
+// as the bind user, and then runs the same group search that AuthenticateUser would run for a user with
+// the given DN. It returns the resulting group names along with the number of groups which were found but
+// filtered out because they are not in the AllowedGroups, or any errors that we encountered.
+// When group search is not configured, it returns an empty list without connecting to the server.
+func (p *Provider) DryRunGroupSearch(ctx context.Context, userDN string) ([]string, int, error) {
+	result, err := p.DryRunGroupSearchWithResult(ctx, userDN)
+	if err != nil {
+		return nil, 0, err
+	}
+	return result.Groups, result.DisallowedGroupCount, nil
+}
+
+// DryRunGroupSearchWithResult is the same as DryRunGroupSearch, except that it also counts the groups which were
+// found before and after resolving nested groups. It returns a nil result when it returns an error.
+func (p *Provider) DryRunGroupSearchWithResult(ctx context.Context, userDN string) (*GroupSearchResult, error) {
+	if !p.groupSearchIsConfigured() {
+		return &GroupSearchResult{Groups: []string{}}, nil
+	}
+
+	conn, _, err := p.dial(ctx)
+	if err != nil {
+		return nil, classify(ErrConnectionFailed, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err))
+	}
+	defer conn.Close()
+
+	err = p.bindAsBindUser(conn)
+	if err != nil {
+		return nil, classifyBindError(fmt.Errorf(`error binding as %s before group search: %w`, p.bindUserDescription(), err))
+	}
+
+	if p.c.GroupSearch.Mode != GroupSearchModeUserAttribute {
+		if err := p.validateGroupSearchBase(conn); err != nil {
+			return nil, err
+		}
+		return p.searchAllowedGroupsForUserDN(conn, userDN)
+	}
+
+	start := time.Now()
+	searchResult, err := conn.Search(p.userGroupsAttributeRequest(userDN))
+	p.observeOperation(operationUserSearch, start, err)
+	if err != nil {
+		return nil, fmt.Errorf(`error reading the entry of user with DN %q: %w`, userDN, err)
+	}
+	if len(searchResult.Entries) != 1 {
+		return nil, fmt.Errorf(`reading the entry of user with DN %q resulted in %d search results, but expected 1 result`,
+			userDN, len(searchResult.Entries))
+	}
+	return p.allowedGroupsFromUserAttribute(conn, searchResult.Entries[0])
+}
+
+// validateGroupSearchBase reads the entry at the group search base as the bind user. When the bind user is not
+// allowed to read the group search base, the group search usually finds no groups instead of failing, which
+// would look like every user has no groups.
+func (p *Provider) validateGroupSearchBase(conn Conn) error {
+	start := time.Now()
+	searchResult, err := conn.Search(p.searchBaseRequest(p.c.GroupSearch.Base))
+	p.observeOperation(operationGroupSearchBase, start, err)
+	if err != nil {
+		return fmt.Errorf(`%w: error searching for %q as %s: %v`, ErrGroupSearchBaseUnreadable, p.c.GroupSearch.Base, p.bindUserDescription(), err)
+	}
+	if len(searchResult.Entries) == 0 {
+		// Some servers hide entries which the user is not allowed to read instead of returning an error.
+		return fmt.Errorf(`%w: %q was not found or could not be read as %s`, ErrGroupSearchBaseUnreadable, p.c.GroupSearch.Base, p.bindUserDescription())
+	}
+	return nil
+}
+
+// ValidateGroupSearch provides a method for testing the group search settings when there is no user whose groups
+// could be searched. It checks that the group search filter parses, performs a dial and bind as the bind user,
+// reads the entry at the group search base, and then runs the group search for a DN which does not belong to any
+// user, so that a group search base which cannot be read, or a filter which the server rejects, is noticed before
+// any end user tries to log in. The groups which are found are ignored. When group search is not configured, or
+// the groups are read from an attribute of the user's entry, there is nothing to search, so it returns nil
+// without connecting to the server.
+func (p *Provider) ValidateGroupSearch(ctx context.Context) error {
+	if p.c.GroupSearch.Mode == GroupSearchModeUserAttribute || len(p.c.GroupSearch.Base) == 0 {
+		return nil
+	}
+
+	filter := p.groupSearchFilter(groupSearchValidationDN)
+	if _, err := ldap.CompileFilter(filter); err != nil {
+		return fmt.Errorf(`group search filter %q is invalid: %w`, filter, err)
+	}
+
+	conn, _, err := p.dial(ctx)
+	if err != nil {
+		return classify(ErrConnectionFailed, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err))
+	}
+	defer conn.Close()
+
+	err = p.bindAsBindUser(conn)
+	if err != nil {
+		return classifyBindError(fmt.Errorf(`error binding as %s before group search: %w`, p.bindUserDescription(), err))
+	}
+
+	if err := p.validateGroupSearchBase(conn); err != nil {
 		return err
 	}
+	if _, err := p.searchGroupEntries(conn, groupSearchValidationDN); err != nil {
+		return fmt.Errorf(`error running the group search: %w`, err)
+	}
+	return nil
+}
+
+// ValidateUserSearchBase provides a method for testing the user search base. It performs a dial and bind
+// as the bind user, and then reads the entry at the user search base, so that a user search base which does
+// not exist or which cannot be read by the bind user is noticed before any end user tries to log in.
+func (p *Provider) ValidateUserSearchBase(ctx context.Context) error {
+	if len(p.c.UserSearch.Base) == 0 {
+		return nil
+	}
 
-	conn, err := p.dial(ctx)
+	conn, _, err := p.dial(ctx)
 	if err != nil {
-		return fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
+		return classify(ErrConnectionFailed, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err))
 	}
 	defer conn.Close()
 
-	err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
+	err = p.bindAsBindUser(conn)
 	if err != nil {
-		return fmt.Errorf(`error binding as %q: %w`, p.c.BindUsername, err)
+		return classifyBindError(fmt.Errorf(`error binding as %s before searching for user search base: %w`, p.bindUserDescription(), err))
+	}
+
+	start := time.Now()
+	searchResult, err := conn.Search(p.searchBaseRequest(p.c.UserSearch.Base))
+	p.observeOperation(operationUserSearchBase, start, err)
+	if err != nil {
+		return fmt.Errorf(`error searching for user search base %q as %s: %w`, p.c.UserSearch.Base, p.bindUserDescription(), err)
+	}
+	if len(searchResult.Entries) == 0 {
+		// Some servers hide entries which the user is not allowed to read instead of returning an error.
+		return fmt.Errorf(`user search base %q was not found or could not be read as %s`, p.c.UserSearch.Base, p.bindUserDescription())
 	}
 
 	return nil
@@ -413,11 +1169,30 @@ func (p *Provider) DryRunAuthenticateUser(ctx context.Context, username string,
 }
 
 // Authenticate an end user and return their mapped username, groups, and UID. Implements authenticators.UserAuthenticator.
+// When the authentication cache is enabled, a successful result from a recent call with the same username, password,
+// and granted scopes is returned without contacting the LDAP server.
 func (p *Provider) AuthenticateUser(ctx context.Context, username, password string, grantedScopes []string) (*authenticators.Response, bool, error) {
+	useCache := p.authCache != nil && len(username) != 0 && len(password) != 0
+	var cacheKey [sha256.Size]byte
+	if useCache {
+		cacheKey = p.authCache.key(username, password, grantedScopes)
+		if response := p.authCache.get(cacheKey); response != nil {
+			plog.Debug("ldap authenticate user attempt used a cached result", "upstreamName", p.GetName())
+			return response, true, nil
+		}
+	}
+
 	endUserBindFunc := func(conn Conn, foundUserDN string) error {
-		return conn.Bind(foundUserDN, password)
+		start := time.Now()
+		err := conn.Bind(foundUserDN, password)
+		p.observeOperation(operationBind, start, err)
+		return err
 	}
-	return p.authenticateUserImpl(ctx, username, grantedScopes, endUserBindFunc)
+	response, authenticated, err := p.authenticateUserImpl(ctx, username, grantedScopes, endUserBindFunc)
+	if useCache && err == nil && authenticated {
+		p.authCache.put(cacheKey, response)
+	}
+	return response, authenticated, err
 }
 
 func (p *Provider) authenticateUserImpl(ctx context.Context, username string, grantedScopes []string, bindFunc func(conn Conn, foundUserDN string) error) (*authenticators.Response, bool, error) {
@@ -430,30 +1205,31 @@ func (p *Provider) authenticateUserImpl(ctx context.Context, username string, gr
 		return nil, false, err
 	}
 
+	// The normalized username is only used to find the user's entry. The username of the authenticated user is
+	// always read from that entry.
+	username = p.normalizeUsername(username)
+
 	if len(username) == 0 {
 		// Empty passwords are already handled by go-ldap.
 		p.traceAuthFailure(t, fmt.Errorf("empty username"))
 		return nil, false, nil
 	}
 
-	conn, err := p.dial(ctx)
+	conn, host, err := p.connectAsBindUser(ctx)
 	if err != nil {
 		p.traceAuthFailure(t, err)
-		return nil, false, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
-	}
-	defer conn.Close()
-
-	err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
-	if err != nil {
-		p.traceAuthFailure(t, err)
-		return nil, false, fmt.Errorf(`error binding as %q before user search: %w`, p.c.BindUsername, err)
+		return nil, false, err
 	}
+	healthy := false
+	defer func() { p.releaseConn(conn, host, healthy) }()
 
 	response, err := p.searchAndBindUser(conn, username, grantedScopes, bindFunc)
 	if err != nil {
 		p.traceAuthFailure(t, err)
 		return nil, false, err
 	}
+	// A failed end user bind does not break the connection, since it will be bound as the bind user again before reuse.
+	healthy = true
 	if response == nil {
 		p.traceAuthFailure(t, fmt.Errorf("bad username or password"))
 		return nil, false, nil
@@ -463,50 +1239,303 @@ func (p *Provider) authenticateUserImpl(ctx context.Context, username string, gr
 	return response, true, nil
 }
 
-func (p *Provider) searchGroupsForUserDN(conn Conn, userDN string) ([]string, error) {
+func (p *Provider) searchGroupsForUser(conn Conn, userDN string, userEntry *ldap.Entry) ([]string, error) {
+	var result *GroupSearchResult
+	var err error
+	if p.c.GroupSearch.Mode == GroupSearchModeUserAttribute {
+		result, err = p.allowedGroupsFromUserAttribute(conn, userEntry)
+	} else {
+		result, err = p.searchAllowedGroupsForUserDN(conn, userDN)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.Groups, nil
+}
+
+// groupSearchIsConfigured returns false when authenticated users should not belong to any groups.
+func (p *Provider) groupSearchIsConfigured() bool {
+	return p.c.GroupSearch.Mode == GroupSearchModeUserAttribute || len(p.c.GroupSearch.Base) > 0
+}
+
+// allowedGroupsFromUserAttribute returns the names of the groups whose DNs are the values of the
+// UserAttributeForGroups of the user's entry, like searchAllowedGroupsForUserDN does for the group search.
+func (p *Provider) allowedGroupsFromUserAttribute(conn Conn, userEntry *ldap.Entry) (*GroupSearchResult, error) {
+	groupEntries, err := p.groupEntriesFromUserAttribute(conn, userEntry)
+	if err != nil {
+		return nil, err
+	}
+	return p.allowedGroupsWithNestedGroups(conn, groupEntries, userEntry.DN)
+}
+
+// groupEntriesFromUserAttribute returns the entries of the groups whose DNs are the values of the
+// UserAttributeForGroups of the user's entry.
+func (p *Provider) groupEntriesFromUserAttribute(conn Conn, userEntry *ldap.Entry) ([]*ldap.Entry, error) {
+	allowedGroupDNs := p.allowedGroupDNs()
+	readGroupEntries := p.groupNameAttribute() != distinguishedNameAttributeName
+
+	groupEntries := []*ldap.Entry{}
+	for _, groupDN := range userEntry.GetAttributeValues(p.userAttributeForGroups()) {
+		if len(groupDN) == 0 {
+			continue
+		}
+		// Disallowed groups are skipped by their DN, so do not bother reading their entries, unless the groups
+		// which contain them are also needed.
+		if p.c.GroupSearch.NestedGroupsMaxDepth == 0 &&
+			(!readGroupEntries || (allowedGroupDNs != nil && !dnIsInList(groupDN, allowedGroupDNs))) {
+			groupEntries = append(groupEntries, &ldap.Entry{DN: groupDN})
+			continue
+		}
+		groupEntry, err := p.readGroupEntry(conn, groupDN)
+		if err != nil {
+			return nil, fmt.Errorf(`error reading group entry %q for user with DN %q: %w`, groupDN, userEntry.DN, err)
+		}
+		groupEntries = append(groupEntries, groupEntry)
+	}
+	return groupEntries, nil
+}
+
+// readGroupEntry reads the group name attribute of the group entry at the DN. A group entry which does not
+// exist, or which cannot be read, is treated like an entry without a value for the group name attribute.
+func (p *Provider) readGroupEntry(conn Conn, groupDN string) (*ldap.Entry, error) {
+	start := time.Now()
+	searchResult, err := conn.Search(p.groupEntryRequest(groupDN))
+	p.observeOperation(operationGroupSearch, start, err)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return &ldap.Entry{DN: groupDN}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(searchResult.Entries) == 0 {
+		return &ldap.Entry{DN: groupDN}, nil
+	}
+	if len(searchResult.Entries) > 1 {
+		return nil, fmt.Errorf(`expected 1 result but found %d`, len(searchResult.Entries))
+	}
+	groupEntry := searchResult.Entries[0]
+	if len(groupEntry.DN) == 0 {
+		// Keep the DN from the user's entry, since the DN is always known even when the server does not return it.
+		groupEntry.DN = groupDN
+	}
+	return groupEntry, nil
+}
+
+// searchAllowedGroupsForUserDN returns the names of the user's groups which are allowed by AllowedGroups,
+// along with the number of the user's groups which were not allowed.
+func (p *Provider) searchAllowedGroupsForUserDN(conn Conn, userDN string) (*GroupSearchResult, error) {
 	// If we do not have group search configured, skip this search.
 	if len(p.c.GroupSearch.Base) == 0 {
-		return []string{}, nil
+		return &GroupSearchResult{Groups: []string{}}, nil
 	}
 
-	searchResult, err := conn.SearchWithPaging(p.groupSearchRequest(userDN), groupSearchPageSize)
+	groupEntries, err := p.searchGroupEntries(conn, userDN)
 	if err != nil {
 		return nil, fmt.Errorf(`error searching for group memberships for user with DN %q: %w`, userDN, err)
 	}
+	return p.allowedGroupsWithNestedGroups(conn, groupEntries, userDN)
+}
+
+// searchGroupEntries runs the group search with the DN in place of the user's DN in the Filter. The DN is the
+// user's DN or, when resolving nested groups, the DN of a group.
+func (p *Provider) searchGroupEntries(conn Conn, dn string) ([]*ldap.Entry, error) {
+	// SearchWithPaging reads every page of results. If reading any page fails, then return the error
+	// instead of the partial results, so the user's group memberships are never silently truncated.
+	start := time.Now()
+	searchResult, err := conn.SearchWithPaging(p.groupSearchRequest(dn), p.groupSearchPageSize())
+	p.observeOperation(operationGroupSearch, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return searchResult.Entries, nil
+}
+
+// allowedGroupsWithNestedGroups adds the groups which contain the user's groups to them, when nested groups should
+// be resolved, and returns the names of the resulting groups which are allowed by AllowedGroups.
+func (p *Provider) allowedGroupsWithNestedGroups(conn Conn, groupEntries []*ldap.Entry, userDN string) (*GroupSearchResult, error) {
+	nestedGroupEntries, err := p.nestedGroupEntries(conn, groupEntries, userDN)
+	if err != nil {
+		return nil, err
+	}
+	groups, disallowedGroupCount, err := p.allowedGroupNames(append(groupEntries, nestedGroupEntries...), userDN)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupSearchResult{
+		Groups:               groups,
+		DisallowedGroupCount: disallowedGroupCount,
+		DirectGroupCount:     len(groupEntries),
+		NestedGroupCount:     len(nestedGroupEntries),
+	}, nil
+}
+
+// nestedGroupEntries returns the entries of the groups which contain the given groups, and of the groups which
+// contain those, and so on, for up to NestedGroupsMaxDepth levels. The given groups are not included. Each group
+// is only visited once, so a cycle in the group memberships ends the walk instead of causing it to loop.
+func (p *Provider) nestedGroupEntries(conn Conn, groupEntries []*ldap.Entry, userDN string) ([]*ldap.Entry, error) {
+	maxDepth := p.c.GroupSearch.NestedGroupsMaxDepth
+	if maxDepth <= 0 {
+		return nil, nil
+	}
+
+	visited := sets.NewString()
+	for _, groupEntry := range groupEntries {
+		visited.Insert(dnKey(groupEntry.DN))
+	}
+
+	nestedGroupEntries := []*ldap.Entry{}
+	childGroupEntries := groupEntries
+	for depth := 0; depth < maxDepth && len(childGroupEntries) > 0; depth++ {
+		parentGroupEntries := []*ldap.Entry{}
+		for _, childGroupEntry := range childGroupEntries {
+			if len(childGroupEntry.DN) == 0 {
+				continue
+			}
+			if p.c.GroupSearch.Mode == GroupSearchModeUserAttribute {
+				for _, groupDN := range childGroupEntry.GetAttributeValues(p.userAttributeForGroups()) {
+					// Skip the groups which were already visited before reading their entries.
+					if len(groupDN) == 0 || visited.Has(dnKey(groupDN)) {
+						continue
+					}
+					visited.Insert(dnKey(groupDN))
+					groupEntry, err := p.readGroupEntry(conn, groupDN)
+					if err != nil {
+						return nil, fmt.Errorf(`error reading group entry %q for user with DN %q: %w`, groupDN, userDN, err)
+					}
+					parentGroupEntries = append(parentGroupEntries, groupEntry)
+				}
+				continue
+			}
+
+			candidates, err := p.searchGroupEntries(conn, childGroupEntry.DN)
+			if err != nil {
+				return nil, fmt.Errorf(`error searching for the groups which contain group %q for user with DN %q: %w`,
+					childGroupEntry.DN, userDN, err)
+			}
+			for _, candidate := range candidates {
+				if len(candidate.DN) == 0 || visited.Has(dnKey(candidate.DN)) {
+					continue
+				}
+				visited.Insert(dnKey(candidate.DN))
+				parentGroupEntries = append(parentGroupEntries, candidate)
+			}
+		}
+		nestedGroupEntries = append(nestedGroupEntries, parentGroupEntries...)
+		childGroupEntries = parentGroupEntries
+	}
+	return nestedGroupEntries, nil
+}
 
-	groupAttributeName := p.c.GroupSearch.GroupNameAttribute
-	if len(groupAttributeName) == 0 {
-		groupAttributeName = distinguishedNameAttributeName
+// dnKey returns a string which is the same for DNs which are equal without regard to case or insignificant
+// whitespace, or the lowercase DN when it cannot be parsed.
+func dnKey(dn string) string {
+	parsedDN, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+	rdns := make([]string, 0, len(parsedDN.RDNs))
+	for _, rdn := range parsedDN.RDNs {
+		attributes := make([]string, 0, len(rdn.Attributes))
+		for _, attribute := range rdn.Attributes {
+			attributes = append(attributes, strings.ToLower(attribute.Type)+"="+strings.ToLower(attribute.Value))
+		}
+		sort.Strings(attributes)
+		rdns = append(rdns, strings.Join(attributes, "+"))
 	}
+	return strings.Join(rdns, ",")
+}
+
+// allowedGroupNames returns the names of the group entries which are allowed by AllowedGroups, along with
+// the number of group entries which were not allowed.
+func (p *Provider) allowedGroupNames(groupEntries []*ldap.Entry, userDN string) ([]string, int, error) {
+	groupAttributeName := p.groupNameAttribute()
+	allowedGroupDNs := p.allowedGroupDNs()
 
 	groups := []string{}
+	disallowedGroupDNs := sets.NewString()
 entries:
-	for _, groupEntry := range searchResult.Entries {
-		if len(groupEntry.DN) == 0 {
-			return nil, fmt.Errorf(`searching for group memberships for user with DN %q resulted in search result without DN`, userDN)
+	for _, groupEntry := range groupEntries {
+		if len(groupEntry.DN) == 0 && !p.c.GroupSearch.UseDNWhenGroupNameIsMissing {
+			return nil, 0, fmt.Errorf(`searching for group memberships for user with DN %q resulted in search result without DN`, userDN)
+		}
+		if allowedGroupDNs != nil && !dnIsInList(groupEntry.DN, allowedGroupDNs) {
+			disallowedGroupDNs.Insert(groupEntry.DN)
+			continue entries
 		}
 		if overrideFunc := p.c.GroupAttributeParsingOverrides[groupAttributeName]; overrideFunc != nil {
 			overrideGroupName, err := overrideFunc(groupEntry)
 			if err != nil {
-				return nil, fmt.Errorf("error finding groups for user %s: %w", userDN, err)
+				return nil, 0, fmt.Errorf("error finding groups for user %s: %w", userDN, err)
 			}
 			groups = append(groups, overrideGroupName)
 			continue entries
 		}
+		if p.c.GroupSearch.UseDNWhenGroupNameIsMissing && !hasNonEmptyAttributeValue(groupAttributeName, groupEntry) {
+			if len(groupEntry.DN) == 0 {
+				plog.Warning("skipping group search result which has neither a group name attribute value nor a DN",
+					"upstreamName", p.GetName(), "userDN", userDN, "groupNameAttribute", groupAttributeName)
+				continue entries
+			}
+			groups = append(groups, groupEntry.DN)
+			continue entries
+		}
 		// if none of the overrides matched, use the default behavior (no mapping)
-		mappedGroupName, err := p.getSearchResultAttributeValue(groupAttributeName, groupEntry, userDN)
+		mappedGroupName, err := p.getSearchResultAttributeValue(groupAttributeName, groupEntry, userDN, MultiValueError)
 		if err != nil {
-			return nil, fmt.Errorf(`error searching for group memberships for user with DN %q: %w`, userDN, err)
+			return nil, 0, fmt.Errorf(`error searching for group memberships for user with DN %q: %w`, userDN, err)
 		}
 		groups = append(groups, mappedGroupName)
 	}
 	// de-duplicate the list of groups by turning it into a set,
 	// then turn it back into a sorted list.
-	return sets.NewString(groups...).List(), nil
+	return sets.NewString(groups...).List(), disallowedGroupDNs.Len(), nil
+}
+
+// hasNonEmptyAttributeValue returns true when the entry has at least one non-empty value for the attribute.
+func hasNonEmptyAttributeValue(attributeName string, entry *ldap.Entry) bool {
+	if attributeName == distinguishedNameAttributeName {
+		return len(entry.DN) > 0
+	}
+	for _, value := range entry.GetAttributeValues(attributeName) {
+		if len(value) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedGroupDNs returns the parsed AllowedGroups, or nil when all groups are allowed.
+func (p *Provider) allowedGroupDNs() []*ldap.DN {
+	if len(p.c.GroupSearch.AllowedGroups) == 0 {
+		return nil
+	}
+	dns := []*ldap.DN{}
+	for _, allowedGroup := range p.c.GroupSearch.AllowedGroups {
+		dn, err := ldap.ParseDN(allowedGroup)
+		if err != nil {
+			plog.DebugErr("ignoring invalid allowed group DN", err, "upstreamName", p.GetName(), "dn", allowedGroup)
+			continue
+		}
+		dns = append(dns, dn)
+	}
+	return dns
+}
+
+func dnIsInList(dn string, list []*ldap.DN) bool {
+	parsedDN, err := ldap.ParseDN(dn)
+	if err != nil {
+		return false
+	}
+	for _, listDN := range list {
+		if parsedDN.EqualFold(listDN) {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *Provider) validateConfig() error {
-	if p.c.UserSearch.UsernameAttribute == distinguishedNameAttributeName && len(p.c.UserSearch.Filter) == 0 {
+	if p.c.UserSearch.UsernameAttribute == distinguishedNameAttributeName && len(p.c.UserSearch.Filter) == 0 && len(p.c.UserSearch.UserDNTemplate) == 0 {
 		// LDAP search filters do not allow searching by DN, so we would have no reasonable default for Filter.
 		return fmt.Errorf(`must specify UserSearch Filter when UserSearch UsernameAttribute is "dn"`)
 	}
@@ -517,20 +1546,22 @@ func (p *Provider) SearchForDefaultNamingContext(ctx context.Context) (string, e
 	t := trace.FromContext(ctx).Nest("slow ldap attempt when searching for default naming context", trace.Field{Key: "providerName", Value: p.GetName()})
 	defer t.LogIfLong(500 * time.Millisecond) // to help users debug slow LDAP searches
 
-	conn, err := p.dial(ctx)
+	conn, _, err := p.dial(ctx)
 	if err != nil {
 		p.traceSearchBaseDiscoveryFailure(t, err)
-		return "", fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
+		return "", classify(ErrConnectionFailed, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err))
 	}
 	defer conn.Close()
 
-	err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
+	err = p.bindAsBindUser(conn)
 	if err != nil {
 		p.traceSearchBaseDiscoveryFailure(t, err)
-		return "", fmt.Errorf(`error binding as %q before querying for defaultNamingContext: %w`, p.c.BindUsername, err)
+		return "", classifyBindError(fmt.Errorf(`error binding as %s before querying for defaultNamingContext: %w`, p.bindUserDescription(), err))
 	}
 
+	start := time.Now()
 	searchResult, err := conn.Search(p.defaultNamingContextRequest())
+	p.observeOperation(operationDefaultNamingContext, start, err)
 	if err != nil {
 		return "", fmt.Errorf(`error querying RootDSE for defaultNamingContext: %w`, err)
 	}
@@ -547,7 +1578,17 @@ func (p *Provider) SearchForDefaultNamingContext(ctx context.Context) (string, e
 }
 
 func (p *Provider) searchAndBindUser(conn Conn, username string, grantedScopes []string, bindFunc func(conn Conn, foundUserDN string) error) (*authenticators.Response, error) {
-	searchResult, err := conn.Search(p.userSearchRequest(username))
+	searchResult, err := p.searchForUser(conn, username)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		if !p.c.UserSearch.AllowMultipleMatches || searchResult == nil || len(searchResult.Entries) == 0 {
+			// The server found more entries than it was allowed to return, so do not pick any of the returned entries.
+			return nil, classify(ErrAmbiguousUser, fmt.Errorf(`searching for user %q resulted in more search results than the size limit, but expected 1 result`,
+				username,
+			))
+		}
+		// The returned entries are usable, and the first of them is picked below.
+		err = nil
+	}
 	if err != nil {
 		plog.All(`error searching for user`,
 			"upstreamName", p.GetName(),
@@ -571,8 +1612,15 @@ func (p *Provider) searchAndBindUser(conn Conn, username string, grantedScopes [
 	// At this point, we have matched at least one entry, so we can be confident that the username is not actually
 	// someone's password mistakenly entered into the username field, so we can log it without concern.
 	if len(searchResult.Entries) > 1 {
-		return nil, fmt.Errorf(`searching for user %q resulted in %d search results, but expected 1 result`,
-			username, len(searchResult.Entries),
+		if !p.c.UserSearch.AllowMultipleMatches {
+			return nil, classify(ErrAmbiguousUser, fmt.Errorf(`searching for user %q resulted in %d search results, but expected 1 result`,
+				username, len(searchResult.Entries),
+			))
+		}
+		plog.Warning("searching for user resulted in more than one search result, so using the first result because multiple matches are allowed",
+			"upstreamName", p.GetName(),
+			"username", username,
+			"userDN", searchResult.Entries[0].DN,
 		)
 	}
 	userEntry := searchResult.Entries[0]
@@ -580,7 +1628,7 @@ func (p *Provider) searchAndBindUser(conn Conn, username string, grantedScopes [
 		return nil, fmt.Errorf(`searching for user %q resulted in search result without DN`, username)
 	}
 
-	mappedUsername, err := p.getSearchResultAttributeValue(p.c.UserSearch.UsernameAttribute, userEntry, username)
+	mappedUsername, err := p.getSearchResultAttributeValue(p.c.UserSearch.UsernameAttribute, userEntry, username, p.c.UserSearch.UsernameAttributeMultiValuePolicy)
 	if err != nil {
 		return nil, err
 	}
@@ -594,7 +1642,12 @@ func (p *Provider) searchAndBindUser(conn Conn, username string, grantedScopes [
 
 	var mappedGroupNames []string
 	if slices.Contains(grantedScopes, oidcapi.ScopeGroups) {
-		mappedGroupNames, err = p.searchGroupsForUserDN(conn, userEntry.DN)
+		mappedGroupNames, err = p.searchGroupsForUser(conn, userEntry.DN, userEntry)
+		if err != nil && p.c.GroupSearch.FailurePolicy == GroupSearchFailOpen {
+			plog.WarningErr("group search failed, so continuing without groups because the group search failure policy is FailOpen",
+				err, "upstreamName", p.GetName(), "username", username, "dn", userEntry.DN)
+			mappedGroupNames, err = []string{}, nil
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -618,7 +1671,7 @@ func (p *Provider) searchAndBindUser(conn Conn, username string, grantedScopes [
 		if errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultInvalidCredentials {
 			return nil, nil
 		}
-		return nil, fmt.Errorf(`error binding for user %q using provided password against DN %q: %w`, username, userEntry.DN, err)
+		return nil, classifyBindError(fmt.Errorf(`error binding for user %q using provided password against DN %q: %w`, username, userEntry.DN, err))
 	}
 
 	if len(mappedUsername) == 0 || len(mappedUID) == 0 {
@@ -631,6 +1684,7 @@ func (p *Provider) searchAndBindUser(conn Conn, username string, grantedScopes [
 			Name:   mappedUsername,
 			UID:    mappedUID,
 			Groups: mappedGroupNames,
+			Extra:  p.mapExtraAttributes(userEntry),
 		},
 		DN:                     userEntry.DN,
 		ExtraRefreshAttributes: mappedRefreshAttributes,
@@ -639,13 +1693,50 @@ func (p *Provider) searchAndBindUser(conn Conn, username string, grantedScopes [
 	return response, nil
 }
 
+// mapExtraAttributes returns the user's extra information from the ExtraAttributes of the user's entry,
+// or nil when no extra attributes are configured.
+func (p *Provider) mapExtraAttributes(userEntry *ldap.Entry) map[string][]string {
+	if len(p.c.UserSearch.ExtraAttributes) == 0 {
+		return nil
+	}
+	extra := make(map[string][]string, len(p.c.UserSearch.ExtraAttributes))
+	for key, attributeName := range p.c.UserSearch.ExtraAttributes {
+		if attributeName == distinguishedNameAttributeName {
+			extra[key] = []string{userEntry.DN}
+			continue
+		}
+		if values := userEntry.GetAttributeValues(attributeName); len(values) > 0 {
+			extra[key] = values
+		}
+	}
+	return extra
+}
+
+// searchForUser returns the search result for the user's entry, which is found using either the user search
+// or the UserDNTemplate. A result without entries means that the user was not found.
+func (p *Provider) searchForUser(conn Conn, username string) (searchResult *ldap.SearchResult, err error) {
+	defer func(start time.Time) { p.observeOperation(operationUserSearch, start, err) }(time.Now())
+
+	if len(p.c.UserSearch.UserDNTemplate) == 0 {
+		return conn.Search(p.userSearchRequest(username))
+	}
+
+	searchResult, err = conn.Search(p.userDNTemplateRequest(username))
+	ldapErr := &ldap.Error{}
+	if errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultNoSuchObject {
+		// There is no entry at the DN, so the user does not exist.
+		return &ldap.SearchResult{}, nil
+	}
+	return searchResult, err
+}
+
 func (p *Provider) defaultNamingContextRequest() *ldap.SearchRequest {
 	return &ldap.SearchRequest{
 		BaseDN:       "",
 		Scope:        ldap.ScopeBaseObject,
 		DerefAliases: ldap.NeverDerefAliases,
 		SizeLimit:    2,
-		TimeLimit:    90,
+		TimeLimit:    p.searchTimeLimitSeconds(),
 		TypesOnly:    false,
 		Filter:       "(objectClass=*)",
 		Attributes:   []string{"defaultNamingContext"},
@@ -653,14 +1744,29 @@ func (p *Provider) defaultNamingContextRequest() *ldap.SearchRequest {
 	}
 }
 
+// searchBaseRequest reads only the entry at the search base, to check that it exists and can be read.
+func (p *Provider) searchBaseRequest(baseDN string) *ldap.SearchRequest {
+	return &ldap.SearchRequest{
+		BaseDN:       baseDN,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    1,
+		TimeLimit:    p.searchTimeLimitSeconds(),
+		TypesOnly:    true,
+		Filter:       "(objectClass=*)",
+		Attributes:   []string{"objectClass"},
+		Controls:     nil, // don't need paging because we set the SizeLimit so small
+	}
+}
+
 func (p *Provider) userSearchRequest(username string) *ldap.SearchRequest {
 	// See https://ldap.com/the-ldap-search-operation for general documentation of LDAP search options.
 	return &ldap.SearchRequest{
 		BaseDN:       p.c.UserSearch.Base,
-		Scope:        ldap.ScopeWholeSubtree,
+		Scope:        p.c.UserSearch.Scope.ldapScope(),
 		DerefAliases: ldap.NeverDerefAliases,
 		SizeLimit:    2,
-		TimeLimit:    90,
+		TimeLimit:    p.searchTimeLimitSeconds(),
 		TypesOnly:    false,
 		Filter:       p.userSearchFilter(username),
 		Attributes:   p.userSearchRequestedAttributes(),
@@ -668,14 +1774,30 @@ func (p *Provider) userSearchRequest(username string) *ldap.SearchRequest {
 	}
 }
 
+func (p *Provider) userDNTemplateRequest(username string) *ldap.SearchRequest {
+	// The username is end user input, so it should be escaped before being included in a DN to prevent
+	// DN injection.
+	return &ldap.SearchRequest{
+		BaseDN:       ExpandDNTemplate(p.c.UserSearch.UserDNTemplate, username),
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    2,
+		TimeLimit:    p.searchTimeLimitSeconds(),
+		TypesOnly:    false,
+		Filter:       "(objectClass=*)", // we already have the dn, so the filter doesn't matter
+		Attributes:   p.userSearchRequestedAttributes(),
+		Controls:     nil, // this could be used to enable paging, but we're already limiting the result max size
+	}
+}
+
 func (p *Provider) groupSearchRequest(userDN string) *ldap.SearchRequest {
 	// See https://ldap.com/the-ldap-search-operation for general documentation of LDAP search options.
 	return &ldap.SearchRequest{
 		BaseDN:       p.c.GroupSearch.Base,
-		Scope:        ldap.ScopeWholeSubtree,
+		Scope:        p.c.GroupSearch.Scope.ldapScope(),
 		DerefAliases: ldap.NeverDerefAliases,
-		SizeLimit:    0, // unlimited size because we will search with paging
-		TimeLimit:    90,
+		SizeLimit:    p.c.SearchSizeLimit, // the server's limit when zero, because we will search with paging
+		TimeLimit:    p.searchTimeLimitSeconds(),
 		TypesOnly:    false,
 		Filter:       p.groupSearchFilter(userDN),
 		Attributes:   p.groupSearchRequestedAttributes(),
@@ -683,6 +1805,36 @@ func (p *Provider) groupSearchRequest(userDN string) *ldap.SearchRequest {
 	}
 }
 
+// userGroupsAttributeRequest reads only the UserAttributeForGroups of the user's entry.
+func (p *Provider) userGroupsAttributeRequest(userDN string) *ldap.SearchRequest {
+	return &ldap.SearchRequest{
+		BaseDN:       userDN,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    2,
+		TimeLimit:    p.searchTimeLimitSeconds(),
+		TypesOnly:    false,
+		Filter:       "(objectClass=*)", // we already have the dn, so the filter doesn't matter
+		Attributes:   []string{p.userAttributeForGroups()},
+		Controls:     nil, // this could be used to enable paging, but we're already limiting the result max size
+	}
+}
+
+// groupEntryRequest reads the group name attribute of the group entry at the DN.
+func (p *Provider) groupEntryRequest(groupDN string) *ldap.SearchRequest {
+	return &ldap.SearchRequest{
+		BaseDN:       groupDN,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    2,
+		TimeLimit:    p.searchTimeLimitSeconds(),
+		TypesOnly:    false,
+		Filter:       "(objectClass=*)", // we already have the dn, so the filter doesn't matter
+		Attributes:   p.groupSearchRequestedAttributes(),
+		Controls:     nil, // this could be used to enable paging, but we're already limiting the result max size
+	}
+}
+
 func (p *Provider) refreshUserSearchRequest(dn string) *ldap.SearchRequest {
 	// See https://ldap.com/the-ldap-search-operation for general documentation of LDAP search options.
 	return &ldap.SearchRequest{
@@ -690,7 +1842,7 @@ func (p *Provider) refreshUserSearchRequest(dn string) *ldap.SearchRequest {
 		Scope:        ldap.ScopeBaseObject,
 		DerefAliases: ldap.NeverDerefAliases,
 		SizeLimit:    2,
-		TimeLimit:    90,
+		TimeLimit:    p.searchTimeLimitSeconds(),
 		TypesOnly:    false,
 		Filter:       "(objectClass=*)", // we already have the dn, so the filter doesn't matter
 		Attributes:   p.userSearchRequestedAttributes(),
@@ -709,28 +1861,98 @@ func (p *Provider) userSearchRequestedAttributes() []string {
 	for k := range p.c.RefreshAttributeChecks {
 		attributes = append(attributes, k)
 	}
+	extraAttributes := sets.NewString()
+	for _, attributeName := range p.c.UserSearch.ExtraAttributes {
+		if attributeName != distinguishedNameAttributeName {
+			extraAttributes.Insert(attributeName)
+		}
+	}
+	// Sort the extra attributes, and skip the duplicates, so that the requested attributes are predictable.
+	for _, attributeName := range extraAttributes.List() {
+		if !slices.Contains(attributes, attributeName) {
+			attributes = append(attributes, attributeName)
+		}
+	}
+	if p.c.GroupSearch.Mode == GroupSearchModeUserAttribute && !slices.Contains(attributes, p.userAttributeForGroups()) {
+		attributes = append(attributes, p.userAttributeForGroups())
+	}
 	return attributes
 }
 
+func (p *Provider) userAttributeForGroups() string {
+	if len(p.c.GroupSearch.UserAttributeForGroups) == 0 {
+		return defaultUserAttributeForGroups
+	}
+	return p.c.GroupSearch.UserAttributeForGroups
+}
+
+func (p *Provider) groupNameAttribute() string {
+	if len(p.c.GroupSearch.GroupNameAttribute) == 0 {
+		return distinguishedNameAttributeName
+	}
+	return p.c.GroupSearch.GroupNameAttribute
+}
+
+func (p *Provider) searchTimeLimitSeconds() int {
+	if p.c.SearchTimeLimit == 0 {
+		return int(DefaultSearchTimeLimit / time.Second)
+	}
+	return int(p.c.SearchTimeLimit / time.Second)
+}
+
+func (p *Provider) groupSearchPageSize() uint32 {
+	if p.c.GroupSearch.PageSize == 0 {
+		return defaultGroupSearchPageSize
+	}
+	return p.c.GroupSearch.PageSize
+}
+
 func (p *Provider) groupSearchRequestedAttributes() []string {
+	attributes := []string{}
 	switch p.c.GroupSearch.GroupNameAttribute {
-	case "":
-		return []string{}
-	case distinguishedNameAttributeName:
-		return []string{}
+	case "", distinguishedNameAttributeName:
 	default:
-		return []string{p.c.GroupSearch.GroupNameAttribute}
+		attributes = append(attributes, p.c.GroupSearch.GroupNameAttribute)
+	}
+	// The groups which contain a group are read from its entry when resolving nested groups using the user attribute.
+	if p.c.GroupSearch.NestedGroupsMaxDepth > 0 && p.c.GroupSearch.Mode == GroupSearchModeUserAttribute &&
+		!slices.Contains(attributes, p.userAttributeForGroups()) {
+		attributes = append(attributes, p.userAttributeForGroups())
+	}
+	return attributes
+}
+
+func (p *Provider) normalizeUsername(username string) string {
+	normalization := p.c.UserSearch.UsernameNormalization
+	if normalization.TrimWhitespace {
+		username = strings.TrimSpace(username)
+	}
+	if normalization.StripNetBIOSPrefix {
+		if i := strings.Index(username, `\`); i >= 0 {
+			username = username[i+1:]
+		}
 	}
+	if suffix := "@" + normalization.StripDomainSuffix; len(normalization.StripDomainSuffix) > 0 &&
+		len(username) > len(suffix) && strings.EqualFold(username[len(username)-len(suffix):], suffix) {
+		username = username[:len(username)-len(suffix)]
+	}
+	return username
 }
 
 func (p *Provider) userSearchFilter(username string) string {
 	// The username is end user input, so it should be escaped before being included in a search to prevent
 	// query injection.
 	safeUsername := p.escapeForSearchFilter(username)
-	if len(p.c.UserSearch.Filter) == 0 {
-		return fmt.Sprintf("(%s=%s)", p.c.UserSearch.UsernameAttribute, safeUsername)
+	filterFormat := p.c.UserSearch.Filter
+	if len(filterFormat) == 0 {
+		filterFormat = p.c.UserSearch.UsernameAttribute + "=" + searchFilterInterpolationLocationMarker
+	}
+	if p.c.UserSearch.UsernameCaseInsensitive {
+		// The LDAP server will decide which entry matches, and the username will be read from that entry,
+		// so the case of the username returned to the caller always comes from the directory.
+		filterFormat = equalityAssertionOfUsernameRegexp.ReplaceAllString(filterFormat, "${1}${2}:"+caseIgnoreMatchingRule+":=${3}")
 	}
-	return interpolateSearchFilter(p.c.UserSearch.Filter, safeUsername)
+	return interpolateSearchFilter(filterFormat, safeUsername)
 }
 
 func (p *Provider) groupSearchFilter(userDN string) string {
@@ -756,18 +1978,44 @@ func (p *Provider) escapeForSearchFilter(s string) string {
 	return ldap.EscapeFilter(s)
 }
 
-// Returns the (potentially) binary data of the attribute's value, base64 URL encoded.
+// ExpandDNTemplate replaces each "{}" placeholder in dnTemplate with value, after escaping value for use as an
+// attribute value in a DN.
+func ExpandDNTemplate(dnTemplate string, value string) string {
+	return strings.ReplaceAll(dnTemplate, searchFilterInterpolationLocationMarker, escapeForDN(value))
+}
+
+// escapeForDN escapes s for use as an attribute value in a DN, as described by RFC 4514 section 2.4.
+func escapeForDN(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\' || r == '"' || r == '+' || r == ',' || r == ';' || r == '<' || r == '>' || r == '=':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == 0:
+			b.WriteString(`\00`)
+		case (i == 0 && (r == ' ' || r == '#')) || (i == len(s)-1 && r == ' '):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Returns the (potentially) binary data of the attribute's value, encoded using the configured UIDAttributeEncoding.
 func (p *Provider) getSearchResultAttributeRawValueEncoded(attributeName string, entry *ldap.Entry, username string) (string, error) {
 	if attributeName == distinguishedNameAttributeName {
-		return base64.RawURLEncoding.EncodeToString([]byte(entry.DN)), nil
+		return p.encodeUID([]byte(entry.DN)), nil
 	}
 
 	attributeValues := entry.GetRawAttributeValues(attributeName)
 
 	if len(attributeValues) != 1 {
-		return "", fmt.Errorf(`found %d values for attribute %q while searching for user %q, but expected 1 result`,
+		return "", classifyAttributeValueCount(len(attributeValues), fmt.Errorf(`found %d values for attribute %q while searching for user %q, but expected 1 result`,
 			len(attributeValues), attributeName, username,
-		)
+		))
 	}
 
 	attributeValue := attributeValues[0]
@@ -781,20 +2029,38 @@ func (p *Provider) getSearchResultAttributeRawValueEncoded(attributeName string,
 		return overrideFunc(entry)
 	}
 
-	return base64.RawURLEncoding.EncodeToString(attributeValue), nil
+	return p.encodeUID(attributeValue), nil
 }
 
-func (p *Provider) getSearchResultAttributeValue(attributeName string, entry *ldap.Entry, username string) (string, error) {
+func (p *Provider) encodeUID(value []byte) string {
+	if p.c.UserSearch.UIDAttributeEncoding == UIDEncodingHex {
+		return hex.EncodeToString(value)
+	}
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+// Returns the value of the attribute, using the policy to choose one of its values when it has more than one.
+func (p *Provider) getSearchResultAttributeValue(attributeName string, entry *ldap.Entry, username string, policy MultiValuePolicy) (string, error) {
 	if attributeName == distinguishedNameAttributeName {
 		return entry.DN, nil
 	}
 
 	attributeValues := entry.GetAttributeValues(attributeName)
 
+	if len(attributeValues) > 1 {
+		switch policy {
+		case MultiValueFirst:
+			attributeValues = attributeValues[:1]
+		case MultiValueLast:
+			attributeValues = attributeValues[len(attributeValues)-1:]
+		case MultiValueError:
+		}
+	}
+
 	if len(attributeValues) != 1 {
-		return "", fmt.Errorf(`found %d values for attribute %q while searching for user %q, but expected 1 result`,
+		return "", classifyAttributeValueCount(len(attributeValues), fmt.Errorf(`found %d values for attribute %q while searching for user %q, but expected 1 result`,
 			len(attributeValues), attributeName, username,
-		)
+		))
 	}
 
 	attributeValue := attributeValues[0]
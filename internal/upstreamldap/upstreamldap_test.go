@@ -6,13 +6,18 @@ package upstreamldap
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -54,7 +59,7 @@ const (
 	testUserDNWithSpecialChars                    = `user DN with * \ special characters ()`
 	testUserDNWithSpecialCharsEscaped             = `user DN with \2a \5c special characters \28\29`
 
-	expectedGroupSearchPageSize = uint32(250)
+	expectedGroupSearchPageSize = uint32(1000)
 )
 
 var (
@@ -180,6 +185,7 @@ func TestEndUserAuthentication(t *testing.T) {
 		bindEndUserMocks           func(conn *mockldapconn.MockConn)
 		dialError                  error
 		wantError                  testutil.RequireErrorStringFunc
+		wantErrorIs                error
 		wantToSkipDial             bool
 		wantAuthResponse           *authenticators.Response
 		wantUnauthenticated        bool
@@ -202,6 +208,42 @@ func TestEndUserAuthentication(t *testing.T) {
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
+		{
+			name:     "when using an anonymous bind for searches",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.BindUsername = ""
+				p.BindPassword = ""
+				p.AnonymousBind = true
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().UnauthenticatedBind("").Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:     "when the anonymous bind for searches returns an error",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.BindUsername = ""
+				p.BindPassword = ""
+				p.AnonymousBind = true
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().UnauthenticatedBind("").Return(errors.New("some anonymous bind error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantExactErrorString(`error binding as anonymous user before user search: some anonymous bind error`),
+		},
 		{
 			name:     "when the user search filter is already wrapped by parenthesis then it is not wrapped again",
 			username: testUpstreamUsername,
@@ -287,6 +329,98 @@ func TestEndUserAuthentication(t *testing.T) {
 				info.Groups = []string{}
 			}),
 		},
+		{
+			name:     "when using the group DN for groups without the group name attribute, groups without either are skipped",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.UseDNWhenGroupNameIsMissing = true
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+								},
+							},
+							{
+								DN: testGroupSearchResultDNValue2,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute("unrelated attribute", []string{"anything"}),
+								},
+							},
+							{
+								DN: "cn=group-with-empty-name",
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{""}),
+								},
+							},
+							{
+								DN: "",
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue2}),
+								},
+							},
+							{
+								DN: "",
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute("unrelated attribute", []string{"anything"}),
+								},
+							},
+						},
+						Referrals: []string{}, // note that we are not following referrals at this time
+						Controls:  []ldap.Control{},
+					}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				info := r.User.(*user.DefaultInfo)
+				info.Groups = []string{
+					"cn=group-with-empty-name",
+					testGroupSearchResultDNValue2,
+					testGroupSearchResultGroupNameAttributeValue1,
+					testGroupSearchResultGroupNameAttributeValue2,
+				}
+			}),
+		},
+		{
+			name:     "when using the group DN for groups without the group name attribute, a group with too many values for the attribute is still an error",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.UseDNWhenGroupNameIsMissing = true
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{
+										testGroupSearchResultGroupNameAttributeValue1,
+										testGroupSearchResultGroupNameAttributeValue2,
+									}),
+								},
+							},
+						},
+					}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`error searching for group memberships for user with DN "%s": found 2 values for attribute "%s" while searching for user "%s", but expected 1 result`,
+				testUserSearchResultDNValue, testGroupSearchGroupNameAttribute, testUserSearchResultDNValue),
+		},
 		{
 			name:           "when groups scope isn't granted, don't do group search",
 			username:       testUpstreamUsername,
@@ -372,6 +506,76 @@ func TestEndUserAuthentication(t *testing.T) {
 				info.UID = base64.RawURLEncoding.EncodeToString([]byte(testUserSearchResultDNValue))
 			}),
 		},
+		{
+			name:     "when the UIDAttribute has a binary value and the UIDAttributeEncoding is Hex",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UIDAttributeEncoding = UIDEncodingHex
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+								{
+									Name:       testUserSearchUIDAttribute,
+									Values:     []string{"\x00\xff\x10\x80"},
+									ByteValues: [][]byte{{0x00, 0xff, 0x10, 0x80}},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				info := r.User.(*user.DefaultInfo)
+				info.UID = "00ff1080"
+			}),
+		},
+		{
+			name:     "when the UIDAttribute is dn and the UIDAttributeEncoding is Hex",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UIDAttribute = "dn"
+				p.UserSearch.UIDAttributeEncoding = UIDEncodingHex
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Attributes = []string{testUserSearchUsernameAttribute}
+				})).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				info := r.User.(*user.DefaultInfo)
+				info.UID = hex.EncodeToString([]byte(testUserSearchResultDNValue))
+			}),
+		},
 		{
 			name:     "when the GroupNameAttribute is empty then it defaults to dn",
 			username: testUpstreamUsername,
@@ -459,77 +663,182 @@ func TestEndUserAuthentication(t *testing.T) {
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:     "when user search Filter is blank it derives a search filter from the UsernameAttribute",
+			name:     "when AllowedGroups is configured then only the allowed groups are returned",
 			username: testUpstreamUsername,
 			password: testUpstreamPassword,
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.UserSearch.Filter = ""
+				p.GroupSearch.AllowedGroups = []string{"cn=admins,ou=groups,dc=example,dc=com"}
 			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
-					r.Filter = "(" + testUserSearchUsernameAttribute + "=" + testUpstreamUsername + ")"
-				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(exampleGroupSearchResult, nil).Times(1)
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: "cn=admins,ou=groups,dc=example,dc=com",
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"admins"}),
+								},
+							},
+							{
+								DN: "cn=developers,ou=groups,dc=example,dc=com",
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"developers"}),
+								},
+							},
+						},
+					}, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			wantAuthResponse: expectedAuthResponse(nil),
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				info := r.User.(*user.DefaultInfo)
+				info.Groups = []string{"admins"}
+			}),
 		},
 		{
-			name:     "when group search Filter is blank it uses a default search filter of member={}",
+			name:     "when the group search mode is userAttribute then the group DNs are read from memberOf of the user entry",
 			username: testUpstreamUsername,
 			password: testUpstreamPassword,
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.GroupSearch.Filter = ""
+				p.GroupSearch = GroupSearchConfig{Mode: GroupSearchModeUserAttribute}
 			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
-					r.Filter = "(member=" + testUserSearchResultDNValue + ")"
-				}), expectedGroupSearchPageSize).
-					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Attributes = append(r.Attributes, "memberOf")
+				})).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+								ldap.NewEntryAttribute("memberOf", []string{testGroupSearchResultDNValue2, "", testGroupSearchResultDNValue1}),
+							},
+						},
+					},
+				}, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			wantAuthResponse: expectedAuthResponse(nil),
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				info := r.User.(*user.DefaultInfo)
+				info.Groups = []string{testGroupSearchResultDNValue1, testGroupSearchResultDNValue2}
+			}),
 		},
 		{
-			name:           "when the username has special LDAP search filter characters then they must be properly escaped in the custom user search filter, because the username is end-user input",
-			username:       `a&b|c(d)e\f*g`,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when the group search mode is userAttribute with a group name attribute then each allowed group entry is read",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch = GroupSearchConfig{
+					Mode:                        GroupSearchModeUserAttribute,
+					UserAttributeForGroups:      "some-groups-attribute",
+					GroupNameAttribute:          testGroupSearchGroupNameAttribute,
+					UseDNWhenGroupNameIsMissing: true,
+					AllowedGroups:               []string{"cn=admins,dc=example,dc=com", "cn=deleted,dc=example,dc=com"},
+				}
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
-					r.Filter = fmt.Sprintf("(some-user-filter=%s-and-more-filter=%s)", `a&b|c\28d\29e\5cf\2ag`, `a&b|c\28d\29e\5cf\2ag`)
-				})).Return(exampleUserSearchResult, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(exampleGroupSearchResult, nil).Times(1)
+					r.Attributes = append(r.Attributes, "some-groups-attribute")
+				})).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+								ldap.NewEntryAttribute("some-groups-attribute", []string{
+									"cn=admins,dc=example,dc=com", "cn=developers,dc=example,dc=com", "cn=deleted,dc=example,dc=com",
+								}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				groupEntryRequest := func(groupDN string) *ldap.SearchRequest {
+					return &ldap.SearchRequest{
+						BaseDN:       groupDN,
+						Scope:        ldap.ScopeBaseObject,
+						DerefAliases: ldap.NeverDerefAliases,
+						SizeLimit:    2,
+						TimeLimit:    90,
+						TypesOnly:    false,
+						Filter:       "(objectClass=*)",
+						Attributes:   []string{testGroupSearchGroupNameAttribute},
+					}
+				}
+				conn.EXPECT().Search(groupEntryRequest("cn=admins,dc=example,dc=com")).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: "cn=admins,dc=example,dc=com",
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"admins"}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Search(groupEntryRequest("cn=deleted,dc=example,dc=com")).
+					Return(nil, ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object"))).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			wantAuthResponse: expectedAuthResponse(nil),
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				info := r.User.(*user.DefaultInfo)
+				info.Groups = []string{"admins", "cn=deleted,dc=example,dc=com"}
+			}),
 		},
 		{
-			name:     "when the username has special LDAP search filter characters then they must be properly escaped in the default user search filter, because the username is end-user input",
-			username: `a&b|c(d)e\f*g`,
+			name:     "when the group search mode is userAttribute and reading a group entry fails",
+			username: testUpstreamUsername,
 			password: testUpstreamPassword,
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.UserSearch.Filter = ""
+				p.GroupSearch = GroupSearchConfig{
+					Mode:               GroupSearchModeUserAttribute,
+					GroupNameAttribute: testGroupSearchGroupNameAttribute,
+				}
 			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
-					r.Filter = fmt.Sprintf("(some-upstream-username-attribute=%s)", `a&b|c\28d\29e\5cf\2ag`)
+					r.Attributes = append(r.Attributes, "memberOf")
+				})).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+								ldap.NewEntryAttribute("memberOf", []string{testGroupSearchResultDNValue1}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Search(gomock.Any()).Return(nil, errors.New("some group entry error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantExactErrorString(`error reading group entry "some-upstream-group-dn1" for user with DN "some-upstream-user-dn": some group entry error`),
+		},
+		{
+			name:     "when user search Filter is blank it derives a search filter from the UsernameAttribute",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.Filter = ""
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Filter = "(" + testUserSearchUsernameAttribute + "=" + testUpstreamUsername + ")"
 				})).Return(exampleUserSearchResult, nil).Times(1)
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
 					Return(exampleGroupSearchResult, nil).Times(1)
@@ -541,145 +850,91 @@ func TestEndUserAuthentication(t *testing.T) {
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:           "when the user search result DN has special LDAP search filter characters then they must be properly escaped in the custom group search filter",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when the username is configured to be case-insensitive and the user search Filter is blank it derives a search filter which uses the caseIgnoreMatch matching rule",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.Filter = ""
+				p.UserSearch.UsernameCaseInsensitive = true
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).
-					Return(&ldap.SearchResult{
-						Entries: []*ldap.Entry{
-							{
-								DN: testUserDNWithSpecialChars,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
-									ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
-								},
-							},
-						},
-					}, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
-					escapedDN := testUserDNWithSpecialCharsEscaped
-					r.Filter = fmt.Sprintf("(some-group-filter=%s-and-more-filter=%s)", escapedDN, escapedDN)
-				}), expectedGroupSearchPageSize).Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Filter = "(" + testUserSearchUsernameAttribute + ":caseIgnoreMatch:=" + testUpstreamUsername + ")"
+				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserDNWithSpecialChars, testUpstreamPassword).Times(1)
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
-				r.DN = testUserDNWithSpecialChars
-			}),
+			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:     "when the user search result DN has special LDAP search filter characters then they must be properly escaped in the default group search filter",
-			username: testUpstreamUsername,
+			name:     "when the username is configured to be case-insensitive it uses the caseIgnoreMatch matching rule for equality assertions on the username in the custom user search filter",
+			username: "Some-Mixed-Case-Username",
 			password: testUpstreamPassword,
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.GroupSearch.Filter = ""
+				p.UserSearch.Filter = "&(objectClass=person)(|(uid={})(mail={}))"
+				p.UserSearch.UsernameCaseInsensitive = true
 			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).
-					Return(&ldap.SearchResult{
-						Entries: []*ldap.Entry{
-							{
-								DN: testUserDNWithSpecialChars,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
-									ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
-								},
-							},
-						},
-					}, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
-					r.Filter = fmt.Sprintf("(member=%s)", testUserDNWithSpecialCharsEscaped)
-				}), expectedGroupSearchPageSize).Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Filter = "(&(objectClass=person)(|(uid:caseIgnoreMatch:=Some-Mixed-Case-Username)(mail:caseIgnoreMatch:=Some-Mixed-Case-Username)))"
+				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserDNWithSpecialChars, testUpstreamPassword).Times(1)
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
-				r.DN = testUserDNWithSpecialChars
-			}),
+			// The username returned is the value of the username attribute from the directory, not the
+			// username which was typed by the end user.
+			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:           "group names are sorted to make the result more stable/predictable",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when username normalization is configured it searches for the normalized username",
+			username: " EXAMPLE\\" + testUpstreamUsername + "@Example.COM ",
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UsernameNormalization = UsernameNormalizationConfig{
+					TrimWhitespace:     true,
+					StripNetBIOSPrefix: true,
+					StripDomainSuffix:  "example.com",
+				}
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(&ldap.SearchResult{
-						Entries: []*ldap.Entry{
-							{
-								DN: testGroupSearchResultDNValue1,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"c"}),
-								},
-							},
-							{
-								DN: testGroupSearchResultDNValue2,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"a"}),
-								},
-							},
-							{
-								DN: testGroupSearchResultDNValue2,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"b"}),
-								},
-							},
-						},
-						Referrals: []string{}, // note that we are not following referrals at this time
-						Controls:  []ldap.Control{},
-					}, nil).Times(1)
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			wantAuthResponse: &authenticators.Response{
-				User: &user.DefaultInfo{
-					Name:   testUserSearchResultUsernameAttributeValue,
-					UID:    base64.RawURLEncoding.EncodeToString([]byte(testUserSearchResultUIDAttributeValue)),
-					Groups: []string{"a", "b", "c"},
-				},
-				DN:                     testUserSearchResultDNValue,
-				ExtraRefreshAttributes: map[string]string{},
-			},
+			// The username returned is the value of the username attribute from the directory, not the
+			// normalized username.
+			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:     "requesting additional refresh related attributes",
-			username: testUpstreamUsername,
+			name:     "when username normalization and UserDNTemplate are configured it reads the entry at the DN of the normalized username",
+			username: testUpstreamUsername + "@example.com",
 			password: testUpstreamPassword,
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.RefreshAttributeChecks = map[string]func(entry *ldap.Entry, attributes provider.RefreshAttributes) error{
-					"some-attribute-to-check-during-refresh": func(entry *ldap.Entry, attributes provider.RefreshAttributes) error {
-						return nil
-					},
-				}
+				p.UserSearch.UserDNTemplate = "uid={},ou=people,dc=example,dc=com"
+				p.UserSearch.UsernameNormalization.StripDomainSuffix = "example.com"
 			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
-					r.Attributes = append(r.Attributes, "some-attribute-to-check-during-refresh")
-				})).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
-								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
-								ldap.NewEntryAttribute("some-attribute-to-check-during-refresh", []string{"some-attribute-value"}),
-							},
-						},
-					},
-				}, nil).Times(1)
+					r.BaseDN = "uid=" + testUpstreamUsername + ",ou=people,dc=example,dc=com"
+					r.Scope = ldap.ScopeBaseObject
+					r.Filter = "(objectClass=*)"
+				})).Return(exampleUserSearchResult, nil).Times(1)
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
 					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
@@ -687,193 +942,333 @@ func TestEndUserAuthentication(t *testing.T) {
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
-				r.ExtraRefreshAttributes = map[string]string{"some-attribute-to-check-during-refresh": "c29tZS1hdHRyaWJ1dGUtdmFsdWU"}
-			}),
+			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:     "requesting additional refresh related attributes, but they aren't returned",
+			name:     "when UserDNTemplate is configured it reads the entry at the user's DN instead of searching for the user, and ignores the Filter",
 			username: testUpstreamUsername,
 			password: testUpstreamPassword,
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.RefreshAttributeChecks = map[string]func(entry *ldap.Entry, attributes provider.RefreshAttributes) error{
-					"some-attribute-to-check-during-refresh": func(entry *ldap.Entry, attributes provider.RefreshAttributes) error {
-						return nil
-					},
-				}
+				p.UserSearch.UserDNTemplate = "uid={},ou=people,dc=example,dc=com"
 			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
-					r.Attributes = append(r.Attributes, "some-attribute-to-check-during-refresh")
+					r.BaseDN = "uid=" + testUpstreamUsername + ",ou=people,dc=example,dc=com"
+					r.Scope = ldap.ScopeBaseObject
+					r.Filter = "(objectClass=*)"
 				})).Return(exampleUserSearchResult, nil).Times(1)
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
 					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantExactErrorString("found 0 values for attribute \"some-attribute-to-check-during-refresh\" while searching for user \"some-upstream-username\", but expected 1 result"),
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:           "when dial fails",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
-			dialError:      errors.New("some dial error"),
-			wantError:      testutil.WantSprintfErrorString(`error dialing host "%s": some dial error`, testHost),
+			name:     "when UserDNTemplate is configured and the username has special DN characters then they must be properly escaped, because the username is end-user input",
+			username: ` #a,b+c"d\e<f>g;h=i `,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UserDNTemplate = "uid={},ou=people,dc=example,dc=com"
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.BaseDN = `uid=\ #a\,b\+c\"d\\e\<f\>g\;h\=i\ ,ou=people,dc=example,dc=com`
+					r.Scope = ldap.ScopeBaseObject
+					r.Filter = "(objectClass=*)"
+				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:     "when the UsernameAttribute is dn and there is not a user search filter provided",
+			name:     "when UserDNTemplate is configured and UsernameAttribute is dn then the Filter is not required",
 			username: testUpstreamUsername,
 			password: testUpstreamPassword,
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.UserSearch.UsernameAttribute = "dn"
+				p.UserSearch.UserDNTemplate = "uid={},ou=people,dc=example,dc=com"
 				p.UserSearch.Filter = ""
+				p.UserSearch.UsernameAttribute = "dn"
 			}),
-			wantToSkipDial: true,
-			wantError:      testutil.WantExactErrorString(`must specify UserSearch Filter when UserSearch UsernameAttribute is "dn"`),
-		},
-		{
-			name:           "when binding as the bind user returns an error",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
 			searchMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.BaseDN = "uid=" + testUpstreamUsername + ",ou=people,dc=example,dc=com"
+					r.Scope = ldap.ScopeBaseObject
+					r.Filter = "(objectClass=*)"
+					r.Attributes = []string{testUserSearchUIDAttribute}
+				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(`error binding as "%s" before user search: some bind error`, testBindUsername),
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				info := r.User.(*user.DefaultInfo)
+				info.Name = testUserSearchResultDNValue
+			}),
 		},
 		{
-			name:           "when searching for the user returns an error",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when UserDNTemplate is configured and there is no entry at the user's DN",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UserDNTemplate = "uid={},ou=people,dc=example,dc=com"
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(nil, errors.New("some user search error")).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.BaseDN = "uid=" + testUpstreamUsername + ",ou=people,dc=example,dc=com"
+					r.Scope = ldap.ScopeBaseObject
+					r.Filter = "(objectClass=*)"
+				})).Return(nil, &ldap.Error{Err: errors.New("some no such object error"), ResultCode: ldap.LDAPResultNoSuchObject}).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantExactErrorString(`error searching for user: some user search error`),
+			wantUnauthenticated: true,
 		},
 		{
-			name:           "when searching for the user's groups returns an error",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when UserDNTemplate is configured and reading the entry at the user's DN returns some other error",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UserDNTemplate = "uid={},ou=people,dc=example,dc=com"
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(nil, errors.New("some group search error")).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.BaseDN = "uid=" + testUpstreamUsername + ",ou=people,dc=example,dc=com"
+					r.Scope = ldap.ScopeBaseObject
+					r.Filter = "(objectClass=*)"
+				})).Return(nil, errors.New("some search error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(`error searching for group memberships for user with DN "%s": some group search error`, testUserSearchResultDNValue),
+			wantError: testutil.WantExactErrorString("error searching for user: some search error"),
 		},
 		{
-			name:           "when searching for the user returns no results",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when group search PageSize is configured it is used when searching for groups",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.PageSize = 42
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{},
-				}, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), uint32(42)).
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantUnauthenticated: true,
-		},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
 		{
-			name:           "when searching for the user returns multiple results",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when the search size and time limits are configured they are used when searching",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.SearchSizeLimit = 50
+				p.SearchTimeLimit = 30 * time.Second
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{DN: testUserSearchResultDNValue},
-						{DN: "some-other-dn"},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.TimeLimit = 30 // the user search still asks for at most two entries
+				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
+					r.SizeLimit = 50
+					r.TimeLimit = 30
+				}), expectedGroupSearchPageSize).Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(`searching for user "%s" resulted in 2 search results, but expected 1 result`, testUpstreamUsername),
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:           "when searching for the user returns a user without a DN",
-			username:       testUpstreamUsername,
+			name:     "when the user and group search scopes are configured they are used when searching",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.Scope = SearchScopeOne
+				p.GroupSearch.Scope = SearchScopeBase
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Scope = ldap.ScopeSingleLevel
+				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
+					r.Scope = ldap.ScopeBaseObject
+				}), expectedGroupSearchPageSize).Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:     "when the user and group search scopes are sub they search the whole subtree",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.Scope = SearchScopeSub
+				p.GroupSearch.Scope = SearchScopeSub
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:     "when group search Filter is blank it uses a default search filter of member={}",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.Filter = ""
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
+					r.Filter = "(member=" + testUserSearchResultDNValue + ")"
+				}), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:           "when the username has special LDAP search filter characters then they must be properly escaped in the custom user search filter, because the username is end-user input",
+			username:       `a&b|c(d)e\f*g`,
 			password:       testUpstreamPassword,
 			providerConfig: providerConfig(nil),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{DN: ""},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Filter = fmt.Sprintf("(some-user-filter=%s-and-more-filter=%s)", `a&b|c\28d\29e\5cf\2ag`, `a&b|c\28d\29e\5cf\2ag`)
+				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(`searching for user "%s" resulted in search result without DN`, testUpstreamUsername),
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
 		},
 		{
-			name:           "when searching for the user's groups returns a group without a DN",
+			name:     "when the username has special LDAP search filter characters then they must be properly escaped in the default user search filter, because the username is end-user input",
+			username: `a&b|c(d)e\f*g`,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.Filter = ""
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Filter = fmt.Sprintf("(some-upstream-username-attribute=%s)", `a&b|c\28d\29e\5cf\2ag`)
+				})).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:           "when the user search result DN has special LDAP search filter characters then they must be properly escaped in the custom group search filter",
 			username:       testUpstreamUsername,
 			password:       testUpstreamPassword,
 			providerConfig: providerConfig(nil),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+				conn.EXPECT().Search(expectedUserSearch(nil)).
 					Return(&ldap.SearchResult{
 						Entries: []*ldap.Entry{
 							{
-								DN: testGroupSearchResultDNValue1,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
-								},
-							},
-							{
-								DN: "",
+								DN: testUserDNWithSpecialChars,
 								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue2}),
+									ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+									ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
 								},
 							},
 						},
-						Referrals: []string{}, // note that we are not following referrals at this time
-						Controls:  []ldap.Control{},
 					}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
+					escapedDN := testUserDNWithSpecialCharsEscaped
+					r.Filter = fmt.Sprintf("(some-group-filter=%s-and-more-filter=%s)", escapedDN, escapedDN)
+				}), expectedGroupSearchPageSize).Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(
-				`searching for group memberships for user with DN "%s" resulted in search result without DN`,
-				testUserSearchResultDNValue),
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserDNWithSpecialChars, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				r.DN = testUserDNWithSpecialChars
+			}),
 		},
 		{
-			name:           "when searching for the user returns a user without an expected username attribute",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when the user search result DN has special LDAP search filter characters then they must be properly escaped in the default group search filter",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.Filter = ""
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+				conn.EXPECT().Search(expectedUserSearch(nil)).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testUserDNWithSpecialChars,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+									ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+								},
 							},
 						},
-					},
-				}, nil).Times(1)
+					}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
+					r.Filter = fmt.Sprintf("(member=%s)", testUserDNWithSpecialCharsEscaped)
+				}), expectedGroupSearchPageSize).Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(
-				`found 0 values for attribute "%s" while searching for user "%s", but expected 1 result`,
-				testUserSearchUsernameAttribute, testUpstreamUsername),
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserDNWithSpecialChars, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				r.DN = testUserDNWithSpecialChars
+			}),
 		},
 		{
-			name:           "when searching for the group memberships returns a group without an expected group name attribute",
+			name:           "group names are sorted to make the result more stable/predictable",
 			username:       testUpstreamUsername,
 			password:       testUpstreamPassword,
 			providerConfig: providerConfig(nil),
@@ -886,13 +1281,19 @@ func TestEndUserAuthentication(t *testing.T) {
 							{
 								DN: testGroupSearchResultDNValue1,
 								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"c"}),
 								},
 							},
 							{
-								DN: testGroupSearchResultDNValue1,
+								DN: testGroupSearchResultDNValue2,
 								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute("unrelated attribute", []string{"anything"}),
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"a"}),
+								},
+							},
+							{
+								DN: testGroupSearchResultDNValue2,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"b"}),
 								},
 							},
 						},
@@ -901,429 +1302,2300 @@ func TestEndUserAuthentication(t *testing.T) {
 					}, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(
-				`error searching for group memberships for user with DN "%s": found 0 values for attribute "%s" while searching for user "%s", but expected 1 result`,
-				testUserSearchResultDNValue, testGroupSearchGroupNameAttribute, testUserSearchResultDNValue),
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: &authenticators.Response{
+				User: &user.DefaultInfo{
+					Name:   testUserSearchResultUsernameAttributeValue,
+					UID:    base64.RawURLEncoding.EncodeToString([]byte(testUserSearchResultUIDAttributeValue)),
+					Groups: []string{"a", "b", "c"},
+				},
+				DN:                     testUserSearchResultDNValue,
+				ExtraRefreshAttributes: map[string]string{},
+			},
 		},
 		{
-			name:           "when searching for the user returns a user with too many values for the expected username attribute",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "requesting additional refresh related attributes",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.RefreshAttributeChecks = map[string]func(entry *ldap.Entry, attributes provider.RefreshAttributes) error{
+					"some-attribute-to-check-during-refresh": func(entry *ldap.Entry, attributes provider.RefreshAttributes) error {
+						return nil
+					},
+				}
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Attributes = append(r.Attributes, "some-attribute-to-check-during-refresh")
+				})).Return(&ldap.SearchResult{
 					Entries: []*ldap.Entry{
 						{
 							DN: testUserSearchResultDNValue,
 							Attributes: []*ldap.EntryAttribute{
-								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{
-									testUserSearchResultUsernameAttributeValue,
-									"unexpected-additional-value",
-								}),
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
 								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+								ldap.NewEntryAttribute("some-attribute-to-check-during-refresh", []string{"some-attribute-value"}),
 							},
 						},
 					},
 				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(
-				`found 2 values for attribute "%s" while searching for user "%s", but expected 1 result`,
-				testUserSearchUsernameAttribute, testUpstreamUsername),
-		},
-		{
-			name:           "when searching for the group memberships returns a group with too many values for the expected group name attribute",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
-			searchMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(&ldap.SearchResult{
-						Entries: []*ldap.Entry{
-							{
-								DN: testGroupSearchResultDNValue1,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
-								},
-							},
-							{
-								DN: testGroupSearchResultDNValue1,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{
-										testGroupSearchResultGroupNameAttributeValue1,
-										"unexpected-additional-value",
-									}),
-								},
-							},
-						},
-						Referrals: []string{}, // note that we are not following referrals at this time
-						Controls:  []ldap.Control{},
-					}, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(
-				`error searching for group memberships for user with DN "%s": found 2 values for attribute "%s" while searching for user "%s", but expected 1 result`,
-				testUserSearchResultDNValue, testGroupSearchGroupNameAttribute, testUserSearchResultDNValue),
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				r.ExtraRefreshAttributes = map[string]string{"some-attribute-to-check-during-refresh": "c29tZS1hdHRyaWJ1dGUtdmFsdWU"}
+			}),
 		},
 		{
-			name:           "when searching for the user returns a user with an empty value for the expected username attribute",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "mapping attributes to the user's extra information",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.ExtraAttributes = map[string]string{
+					"example.com/department":  "departmentNumber",
+					"example.com/employee-id": "employeeID",
+					"example.com/missing":     "missingAttribute",
+					"example.com/dn":          "dn",
+					"example.com/uid":         testUserSearchUIDAttribute,
+				}
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					// The extra attributes are requested in order, without duplicates, and without dn.
+					r.Attributes = append(r.Attributes, "departmentNumber", "employeeID", "missingAttribute")
+				})).Return(&ldap.SearchResult{
 					Entries: []*ldap.Entry{
 						{
 							DN: testUserSearchResultDNValue,
 							Attributes: []*ldap.EntryAttribute{
-								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{""}),
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
 								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+								ldap.NewEntryAttribute("departmentNumber", []string{"engineering", "research"}),
+								ldap.NewEntryAttribute("employeeID", []string{"12345"}),
 							},
 						},
 					},
 				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(
-				`found empty value for attribute "%s" while searching for user "%s", but expected value to be non-empty`,
-				testUserSearchUsernameAttribute, testUpstreamUsername),
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				info := r.User.(*user.DefaultInfo)
+				info.Extra = map[string][]string{
+					"example.com/department":  {"engineering", "research"},
+					"example.com/employee-id": {"12345"},
+					"example.com/dn":          {testUserSearchResultDNValue},
+					"example.com/uid":         {testUserSearchResultUIDAttributeValue},
+				}
+			}),
 		},
 		{
-			name:           "when searching for the group memberships returns a group with an empty value for for the expected group name attribute",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "requesting additional refresh related attributes, but they aren't returned",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.RefreshAttributeChecks = map[string]func(entry *ldap.Entry, attributes provider.RefreshAttributes) error{
+					"some-attribute-to-check-during-refresh": func(entry *ldap.Entry, attributes provider.RefreshAttributes) error {
+						return nil
+					},
+				}
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Attributes = append(r.Attributes, "some-attribute-to-check-during-refresh")
+				})).Return(exampleUserSearchResult, nil).Times(1)
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(&ldap.SearchResult{
-						Entries: []*ldap.Entry{
-							{
-								DN: testGroupSearchResultDNValue1,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
-								},
-							},
-							{
-								DN: testGroupSearchResultDNValue1,
-								Attributes: []*ldap.EntryAttribute{
-									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{""}),
-								},
-							},
-						},
-						Referrals: []string{}, // note that we are not following referrals at this time
-						Controls:  []ldap.Control{},
-					}, nil).Times(1)
+					Return(exampleGroupSearchResult, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(
-				`error searching for group memberships for user with DN "%s": found empty value for attribute "%s" while searching for user "%s", but expected value to be non-empty`,
-				testUserSearchResultDNValue, testGroupSearchGroupNameAttribute, testUserSearchResultDNValue),
+			wantError: testutil.WantExactErrorString("found 0 values for attribute \"some-attribute-to-check-during-refresh\" while searching for user \"some-upstream-username\", but expected 1 result"),
 		},
 		{
-			name:           "when searching for the user returns a user without an expected UID attribute",
+			name:           "when dial fails",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			dialError:      errors.New("some dial error"),
+			wantError:      testutil.WantSprintfErrorString(`error dialing host "%s": some dial error`, testHost),
+			wantErrorIs:    ErrConnectionFailed,
+		},
+		{
+			name:     "when the UsernameAttribute is dn and there is not a user search filter provided",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UsernameAttribute = "dn"
+				p.UserSearch.Filter = ""
+			}),
+			wantToSkipDial: true,
+			wantError:      testutil.WantExactErrorString(`must specify UserSearch Filter when UserSearch UsernameAttribute is "dn"`),
+		},
+		{
+			name:           "when binding as the bind user returns an error",
 			username:       testUpstreamUsername,
 			password:       testUpstreamPassword,
 			providerConfig: providerConfig(nil),
 			searchMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
-							},
-						},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(`found 0 values for attribute "%s" while searching for user "%s", but expected 1 result`, testUserSearchUIDAttribute, testUpstreamUsername),
+			wantError:   testutil.WantSprintfErrorString(`error binding as "%s" before user search: some bind error`, testBindUsername),
+			wantErrorIs: ErrBindFailed,
 		},
 		{
-			name:           "when searching for the user returns a user with too many values for the expected UID attribute",
+			name:           "when searching for the user returns an error",
 			username:       testUpstreamUsername,
 			password:       testUpstreamPassword,
 			providerConfig: providerConfig(nil),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
-								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{
-									testUserSearchResultUIDAttributeValue,
-									"unexpected-additional-value",
-								}),
-							},
-						},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(nil, errors.New("some user search error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(`found 2 values for attribute "%s" while searching for user "%s", but expected 1 result`, testUserSearchUIDAttribute, testUpstreamUsername),
+			wantError: testutil.WantExactErrorString(`error searching for user: some user search error`),
 		},
 		{
-			name:           "when searching for the user returns a user with an empty value for the expected UID attribute",
+			name:           "when searching for the user's groups returns an error",
 			username:       testUpstreamUsername,
 			password:       testUpstreamPassword,
 			providerConfig: providerConfig(nil),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
-								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{""}),
-							},
-						},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(nil, errors.New("some group search error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(`found empty value for attribute "%s" while searching for user "%s", but expected value to be non-empty`, testUserSearchUIDAttribute, testUpstreamUsername),
+			wantError: testutil.WantSprintfErrorString(`error searching for group memberships for user with DN "%s": some group search error`, testUserSearchResultDNValue),
 		},
 		{
-			name:     "when the group search has an override func that errors",
+			name:     "when searching for the user's groups returns an error and the group search failure policy is FailClosed",
 			username: testUpstreamUsername,
 			password: testUpstreamPassword,
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.GroupAttributeParsingOverrides = map[string]func(*ldap.Entry) (string, error){testGroupSearchGroupNameAttribute: func(entry *ldap.Entry) (string, error) {
-					return "", errors.New("some error")
-				}}
+				p.GroupSearch.FailurePolicy = GroupSearchFailClosed
 			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(exampleGroupSearchResult, nil).Times(1)
+					Return(nil, errors.New("some group search error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString("error finding groups for user %s: some error", testUserSearchResultDNValue),
+			wantError: testutil.WantSprintfErrorString(`error searching for group memberships for user with DN "%s": some group search error`, testUserSearchResultDNValue),
 		},
 		{
-			name:           "when binding as the found user returns an error",
-			username:       testUpstreamUsername,
-			password:       testUpstreamPassword,
-			providerConfig: providerConfig(nil),
+			name:     "when searching for the user's groups returns an error and the group search failure policy is FailOpen",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.FailurePolicy = GroupSearchFailOpen
+			}),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(exampleGroupSearchResult, nil).Times(1)
+					Return(nil, errors.New("some group search error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Return(errors.New("some bind error")).Times(1)
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
 			},
-			skipDryRunAuthenticateUser: true,
-			wantError:                  testutil.WantSprintfErrorString(`error binding for user "%s" using provided password against DN "%s": some bind error`, testUpstreamUsername, testUserSearchResultDNValue),
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				r.User.(*user.DefaultInfo).Groups = []string{}
+			}),
 		},
 		{
-			name:           "when binding as the found user returns a specific invalid credentials error",
+			name:           "when searching for the user's groups fails after some pages of results were already read",
 			username:       testUpstreamUsername,
 			password:       testUpstreamPassword,
 			providerConfig: providerConfig(nil),
 			searchMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
 				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				// ldap.Conn.SearchWithPaging returns the entries from the pages which it read before the error.
 				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
-					Return(exampleGroupSearchResult, nil).Times(1)
+					Return(exampleGroupSearchResult, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("some page error"))).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantUnauthenticated:        true,
-			skipDryRunAuthenticateUser: true,
-			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				err := &ldap.Error{
-					Err:        errors.New("some bind error"),
-					ResultCode: ldap.LDAPResultInvalidCredentials,
-				}
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Return(err).Times(1)
-			},
+			wantError: testutil.WantSprintfErrorString(`error searching for group memberships for user with DN "%s": LDAP Result Code 4 "Size Limit Exceeded": some page error`, testUserSearchResultDNValue),
 		},
 		{
-			name:                "when no username is specified",
-			username:            "",
-			password:            testUpstreamPassword,
-			providerConfig:      providerConfig(nil),
-			wantToSkipDial:      true,
+			name:           "when searching for the user returns no results",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
 			wantUnauthenticated: true,
 		},
-	}
-
-	for _, test := range tests {
-		tt := test
-		t.Run(tt.name, func(t *testing.T) {
-			ctrl := gomock.NewController(t)
-			t.Cleanup(ctrl.Finish)
-
-			conn := mockldapconn.NewMockConn(ctrl)
-			if tt.searchMocks != nil {
-				tt.searchMocks(conn)
-			}
-			if tt.bindEndUserMocks != nil {
-				tt.bindEndUserMocks(conn)
-			}
-
-			dialWasAttempted := false
-			tt.providerConfig.Dialer = LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
-				dialWasAttempted = true
-				require.Equal(t, tt.providerConfig.Host, addr.Endpoint())
-				if tt.dialError != nil {
-					return nil, tt.dialError
-				}
-				return conn, nil
-			})
-
-			ldapProvider := New(*tt.providerConfig)
-
-			if tt.grantedScopes == nil {
-				tt.grantedScopes = []string{"groups"}
-			}
-
+		{
+			name:           "when searching for the user returns multiple results",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{DN: testUserSearchResultDNValue},
+						{DN: "some-other-dn"},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError:   testutil.WantSprintfErrorString(`searching for user "%s" resulted in 2 search results, but expected 1 result`, testUpstreamUsername),
+			wantErrorIs: ErrAmbiguousUser,
+		},
+		{
+			name:           "when searching for the user returns a user without a DN",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{DN: ""},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`searching for user "%s" resulted in search result without DN`, testUpstreamUsername),
+		},
+		{
+			name:           "when searching for the user exceeds the size limit",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				// The server returns the entries which fit in the size limit along with the error.
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{DN: testUserSearchResultDNValue},
+						{DN: "some-other-dn"},
+					},
+				}, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("some size limit error"))).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError:   testutil.WantSprintfErrorString(`searching for user "%s" resulted in more search results than the size limit, but expected 1 result`, testUpstreamUsername),
+			wantErrorIs: ErrAmbiguousUser,
+		},
+		{
+			name:           "when the server's own size limit only lets searching for the user return one entry",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{exampleUserSearchResult.Entries[0]},
+				}, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("some size limit error"))).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError:   testutil.WantSprintfErrorString(`searching for user "%s" resulted in more search results than the size limit, but expected 1 result`, testUpstreamUsername),
+			wantErrorIs: ErrAmbiguousUser,
+		},
+		{
+			name:     "when searching for the user returns multiple results and multiple matches are allowed it uses the first result",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.AllowMultipleMatches = true
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						exampleUserSearchResult.Entries[0],
+						{DN: "some-other-dn"},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:     "when searching for the user exceeds the size limit and multiple matches are allowed it uses the first returned entry",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.AllowMultipleMatches = true
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						exampleUserSearchResult.Entries[0],
+						{DN: "some-other-dn"},
+					},
+				}, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("some size limit error"))).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:     "when searching for the user exceeds the size limit without returning entries and multiple matches are allowed",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.AllowMultipleMatches = true
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{},
+					ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("some size limit error"))).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError:   testutil.WantSprintfErrorString(`searching for user "%s" resulted in more search results than the size limit, but expected 1 result`, testUpstreamUsername),
+			wantErrorIs: ErrAmbiguousUser,
+		},
+		{
+			name:           "when searching for the user's groups returns a group without a DN",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+								},
+							},
+							{
+								DN: "",
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue2}),
+								},
+							},
+						},
+						Referrals: []string{}, // note that we are not following referrals at this time
+						Controls:  []ldap.Control{},
+					}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`searching for group memberships for user with DN "%s" resulted in search result without DN`,
+				testUserSearchResultDNValue),
+		},
+		{
+			name:           "when searching for the user returns a user without an expected username attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`found 0 values for attribute "%s" while searching for user "%s", but expected 1 result`,
+				testUserSearchUsernameAttribute, testUpstreamUsername),
+		},
+		{
+			name:           "when searching for the group memberships returns a group without an expected group name attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+								},
+							},
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute("unrelated attribute", []string{"anything"}),
+								},
+							},
+						},
+						Referrals: []string{}, // note that we are not following referrals at this time
+						Controls:  []ldap.Control{},
+					}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`error searching for group memberships for user with DN "%s": found 0 values for attribute "%s" while searching for user "%s", but expected 1 result`,
+				testUserSearchResultDNValue, testGroupSearchGroupNameAttribute, testUserSearchResultDNValue),
+		},
+		{
+			name:           "when searching for the user returns a user with too many values for the expected username attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{
+									testUserSearchResultUsernameAttributeValue,
+									"unexpected-additional-value",
+								}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`found 2 values for attribute "%s" while searching for user "%s", but expected 1 result`,
+				testUserSearchUsernameAttribute, testUpstreamUsername),
+			wantErrorIs: ErrMultiValuedAttribute,
+		},
+		{
+			name:     "when searching for the user returns a user with too many values for the expected username attribute and the first value should be used",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UsernameAttributeMultiValuePolicy = MultiValueFirst
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{
+									testUserSearchResultUsernameAttributeValue,
+									"unexpected-additional-value",
+								}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:     "when searching for the user returns a user with too many values for the expected username attribute and the last value should be used",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UsernameAttributeMultiValuePolicy = MultiValueLast
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{
+									"unexpected-additional-value",
+									testUserSearchResultUsernameAttributeValue,
+								}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(nil),
+		},
+		{
+			name:           "when searching for the group memberships returns a group with too many values for the expected group name attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+								},
+							},
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{
+										testGroupSearchResultGroupNameAttributeValue1,
+										"unexpected-additional-value",
+									}),
+								},
+							},
+						},
+						Referrals: []string{}, // note that we are not following referrals at this time
+						Controls:  []ldap.Control{},
+					}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`error searching for group memberships for user with DN "%s": found 2 values for attribute "%s" while searching for user "%s", but expected 1 result`,
+				testUserSearchResultDNValue, testGroupSearchGroupNameAttribute, testUserSearchResultDNValue),
+		},
+		{
+			name:           "when searching for the user returns a user with an empty value for the expected username attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{""}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`found empty value for attribute "%s" while searching for user "%s", but expected value to be non-empty`,
+				testUserSearchUsernameAttribute, testUpstreamUsername),
+		},
+		{
+			name:           "when searching for the group memberships returns a group with an empty value for for the expected group name attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+								},
+							},
+							{
+								DN: testGroupSearchResultDNValue1,
+								Attributes: []*ldap.EntryAttribute{
+									ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{""}),
+								},
+							},
+						},
+						Referrals: []string{}, // note that we are not following referrals at this time
+						Controls:  []ldap.Control{},
+					}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`error searching for group memberships for user with DN "%s": found empty value for attribute "%s" while searching for user "%s", but expected value to be non-empty`,
+				testUserSearchResultDNValue, testGroupSearchGroupNameAttribute, testUserSearchResultDNValue),
+		},
+		{
+			name:           "when searching for the user returns a user without an expected UID attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`found 0 values for attribute "%s" while searching for user "%s", but expected 1 result`, testUserSearchUIDAttribute, testUpstreamUsername),
+		},
+		{
+			name:           "when searching for the user returns a user with too many values for the expected UID attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{
+									testUserSearchResultUIDAttributeValue,
+									"unexpected-additional-value",
+								}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError:   testutil.WantSprintfErrorString(`found 2 values for attribute "%s" while searching for user "%s", but expected 1 result`, testUserSearchUIDAttribute, testUpstreamUsername),
+			wantErrorIs: ErrMultiValuedAttribute,
+		},
+		{
+			name:     "when searching for the user returns a user with too many values for the expected UID attribute then it is an error even when the username attribute allows them",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UsernameAttributeMultiValuePolicy = MultiValueFirst
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{
+									testUserSearchResultUIDAttributeValue,
+									"unexpected-additional-value",
+								}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError:   testutil.WantSprintfErrorString(`found 2 values for attribute "%s" while searching for user "%s", but expected 1 result`, testUserSearchUIDAttribute, testUpstreamUsername),
+			wantErrorIs: ErrMultiValuedAttribute,
+		},
+		{
+			name:           "when searching for the user returns a user with an empty value for the expected UID attribute",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{""}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`found empty value for attribute "%s" while searching for user "%s", but expected value to be non-empty`, testUserSearchUIDAttribute, testUpstreamUsername),
+		},
+		{
+			name:     "when the group search has an override func that errors",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupAttributeParsingOverrides = map[string]func(*ldap.Entry) (string, error){testGroupSearchGroupNameAttribute: func(entry *ldap.Entry) (string, error) {
+					return "", errors.New("some error")
+				}}
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString("error finding groups for user %s: some error", testUserSearchResultDNValue),
+		},
+		{
+			name:           "when binding as the found user returns an error",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Return(errors.New("some bind error")).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantError:                  testutil.WantSprintfErrorString(`error binding for user "%s" using provided password against DN "%s": some bind error`, testUpstreamUsername, testUserSearchResultDNValue),
+			wantErrorIs:                ErrBindFailed,
+		},
+		{
+			name:           "when binding as the found user returns a specific invalid credentials error",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantUnauthenticated:        true,
+			skipDryRunAuthenticateUser: true,
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				err := &ldap.Error{
+					Err:        errors.New("some bind error"),
+					ResultCode: ldap.LDAPResultInvalidCredentials,
+				}
+				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Return(err).Times(1)
+			},
+		},
+		{
+			name:                "when no username is specified",
+			username:            "",
+			password:            testUpstreamPassword,
+			providerConfig:      providerConfig(nil),
+			wantToSkipDial:      true,
+			wantUnauthenticated: true,
+		},
+		{
+			name:     "when the username is empty after it is normalized",
+			username: "  ",
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.UsernameNormalization.TrimWhitespace = true
+			}),
+			wantToSkipDial:      true,
+			wantUnauthenticated: true,
+		},
+	}
+
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			conn := mockldapconn.NewMockConn(ctrl)
+			if tt.searchMocks != nil {
+				tt.searchMocks(conn)
+			}
+			if tt.bindEndUserMocks != nil {
+				tt.bindEndUserMocks(conn)
+			}
+
+			dialWasAttempted := false
+			tt.providerConfig.Dialer = LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+				dialWasAttempted = true
+				require.Equal(t, tt.providerConfig.Host, addr.Endpoint())
+				if tt.dialError != nil {
+					return nil, tt.dialError
+				}
+				return conn, nil
+			})
+
+			ldapProvider := New(*tt.providerConfig)
+
+			if tt.grantedScopes == nil {
+				tt.grantedScopes = []string{"groups"}
+			}
+
 			authResponse, authenticated, err := ldapProvider.AuthenticateUser(context.Background(), tt.username, tt.password, tt.grantedScopes)
 			require.Equal(t, !tt.wantToSkipDial, dialWasAttempted)
 			switch {
 			case tt.wantError != nil:
 				testutil.RequireErrorStringFromErr(t, err, tt.wantError)
-				require.False(t, authenticated)
-				require.Nil(t, authResponse)
-			case tt.wantUnauthenticated:
-				require.NoError(t, err)
-				require.False(t, authenticated)
-				require.Nil(t, authResponse)
+				if tt.wantErrorIs != nil {
+					require.ErrorIs(t, err, tt.wantErrorIs)
+				}
+				require.False(t, authenticated)
+				require.Nil(t, authResponse)
+			case tt.wantUnauthenticated:
+				require.NoError(t, err)
+				require.False(t, authenticated)
+				require.Nil(t, authResponse)
+			default:
+				require.NoError(t, err)
+				require.True(t, authenticated)
+				require.Equal(t, tt.wantAuthResponse, authResponse)
+			}
+
+			// DryRunAuthenticateUser() should have the same behavior as AuthenticateUser() except that it does not bind
+			// as the end user to confirm their password. Since it should behave the same, all of the same test cases
+			// apply, except for those which are specifically testing what happens when the end user bind fails.
+			if tt.skipDryRunAuthenticateUser {
+				return // move on to the next test
+			}
+
+			// Reset some variables to get ready to call DryRunAuthenticateUser().
+			dialWasAttempted = false
+			conn = mockldapconn.NewMockConn(ctrl)
+			if tt.searchMocks != nil {
+				tt.searchMocks(conn)
+			}
+			// Skip tt.bindEndUserMocks since DryRunAuthenticateUser() never binds as the end user.
+
+			authResponse, authenticated, err = ldapProvider.DryRunAuthenticateUser(context.Background(), tt.username, tt.grantedScopes)
+			require.Equal(t, !tt.wantToSkipDial, dialWasAttempted)
+			switch {
+			case tt.wantError != nil:
+				testutil.RequireErrorStringFromErr(t, err, tt.wantError)
+				if tt.wantErrorIs != nil {
+					require.ErrorIs(t, err, tt.wantErrorIs)
+				}
+				require.False(t, authenticated)
+				require.Nil(t, authResponse)
+			case tt.wantUnauthenticated:
+				require.NoError(t, err)
+				require.False(t, authenticated)
+				require.Nil(t, authResponse)
+			default:
+				require.NoError(t, err)
+				require.True(t, authenticated)
+				require.Equal(t, tt.wantAuthResponse, authResponse)
+			}
+		})
+	}
+}
+
+func TestUpstreamRefresh(t *testing.T) {
+	pwdLastSetAttribute := "pwdLastSet"
+
+	expectedUserSearch := func(editFunc func(r *ldap.SearchRequest)) *ldap.SearchRequest {
+		request := &ldap.SearchRequest{
+			BaseDN:       testUserSearchResultDNValue,
+			Scope:        ldap.ScopeBaseObject,
+			DerefAliases: ldap.NeverDerefAliases,
+			SizeLimit:    2,
+			TimeLimit:    90,
+			TypesOnly:    false,
+			Filter:       "(objectClass=*)",
+			Attributes:   []string{testUserSearchUsernameAttribute, testUserSearchUIDAttribute, pwdLastSetAttribute},
+			Controls:     nil, // don't need paging because we set the SizeLimit so small
+		}
+		if editFunc != nil {
+			editFunc(request)
+		}
+		return request
+	}
+
+	expectedGroupSearch := func(editFunc func(r *ldap.SearchRequest)) *ldap.SearchRequest {
+		request := &ldap.SearchRequest{
+			BaseDN:       testGroupSearchBase,
+			Scope:        ldap.ScopeWholeSubtree,
+			DerefAliases: ldap.NeverDerefAliases,
+			SizeLimit:    0, // unlimited size because we will search with paging
+			TimeLimit:    90,
+			TypesOnly:    false,
+			Filter:       testGroupSearchFilterInterpolated,
+			Attributes:   []string{testGroupSearchGroupNameAttribute},
+			Controls:     nil, // nil because ldap.SearchWithPaging() will set the appropriate controls for us
+		}
+		if editFunc != nil {
+			editFunc(request)
+		}
+		return request
+	}
+
+	happyPathUserSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testUserSearchResultDNValue,
+				Attributes: []*ldap.EntryAttribute{
+					{
+						Name:   testUserSearchUsernameAttribute,
+						Values: []string{testUserSearchResultUsernameAttributeValue},
+					},
+					{
+						Name:       testUserSearchUIDAttribute,
+						ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
+					},
+					{
+						Name:       pwdLastSetAttribute,
+						Values:     []string{"132801740800000000"},
+						ByteValues: [][]byte{[]byte("132801740800000000")},
+					},
+				},
+			},
+		},
+	}
+
+	happyPathGroupSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testGroupSearchResultDNValue1,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+				},
+			},
+			{
+				DN: testGroupSearchResultDNValue2,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue2}),
+				},
+			},
+		},
+		Referrals: []string{}, // note that we are not following referrals at this time
+		Controls:  []ldap.Control{},
+	}
+
+	providerConfig := func(editFunc func(p *ProviderConfig)) *ProviderConfig {
+		config := &ProviderConfig{
+			Name:               "some-provider-name",
+			Host:               testHost,
+			CABundle:           nil, // this field is only used by the production dialer, which is replaced by a mock for this test
+			ConnectionProtocol: TLS,
+			BindUsername:       testBindUsername,
+			BindPassword:       testBindPassword,
+			UserSearch: UserSearchConfig{
+				Base:              testUserSearchBase,
+				UIDAttribute:      testUserSearchUIDAttribute,
+				UsernameAttribute: testUserSearchUsernameAttribute,
+			},
+			GroupSearch: GroupSearchConfig{
+				Base:               testGroupSearchBase,
+				Filter:             testGroupSearchFilter,
+				GroupNameAttribute: testGroupSearchGroupNameAttribute,
+			},
+			RefreshAttributeChecks: map[string]func(*ldap.Entry, provider.RefreshAttributes) error{
+				pwdLastSetAttribute: AttributeUnchangedSinceLogin(pwdLastSetAttribute),
+			},
+		}
+		if editFunc != nil {
+			editFunc(config)
+		}
+		return config
+	}
+
+	tests := []struct {
+		name           string
+		providerConfig *ProviderConfig
+		grantedScopes  []string
+		setupMocks     func(conn *mockldapconn.MockConn)
+		refreshUserDN  string
+		dialError      error
+		wantErr        string
+		wantGroups     []string
+	}{
+		{
+			name: "happy path without group search where searching the dn returns a single entry",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch = GroupSearchConfig{}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups: []string{},
+		},
+		{
+			name:           "happy path where group search returns groups",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).Return(happyPathGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups: []string{testGroupSearchResultGroupNameAttributeValue1, testGroupSearchResultGroupNameAttributeValue2},
+		},
+		{
+			name: "happy path where the group search mode is userAttribute",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch = GroupSearchConfig{Mode: GroupSearchModeUserAttribute}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				userEntry := *happyPathUserSearchResult.Entries[0]
+				userEntry.Attributes = append(userEntry.Attributes, ldap.NewEntryAttribute("memberOf", []string{testGroupSearchResultDNValue1}))
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Attributes = append(r.Attributes, "memberOf")
+				})).Return(&ldap.SearchResult{Entries: []*ldap.Entry{&userEntry}}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups: []string{testGroupSearchResultDNValue1},
+		},
+		{
+			name:           "happy path when the user DN has special LDAP search filter characters then they must be properly escaped in the custom group search filter",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.BaseDN = testUserDNWithSpecialChars
+				})).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testUserDNWithSpecialChars,
+								Attributes: []*ldap.EntryAttribute{
+									{
+										Name:   testUserSearchUsernameAttribute,
+										Values: []string{testUserSearchResultUsernameAttributeValue},
+									},
+									{
+										Name:       testUserSearchUIDAttribute,
+										ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
+									},
+									{
+										Name:       pwdLastSetAttribute,
+										Values:     []string{"132801740800000000"},
+										ByteValues: [][]byte{[]byte("132801740800000000")},
+									},
+								},
+							},
+						},
+					}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
+					r.Filter = fmt.Sprintf("(some-group-filter=%s-and-more-filter=%s)", testUserDNWithSpecialCharsEscaped, testUserDNWithSpecialCharsEscaped)
+				}), expectedGroupSearchPageSize).Return(happyPathGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			refreshUserDN: testUserDNWithSpecialChars,
+			wantGroups:    []string{testGroupSearchResultGroupNameAttributeValue1, testGroupSearchResultGroupNameAttributeValue2},
+		},
+		{
+			name: "when the user DN has special LDAP search filter characters then they must be properly escaped in the default group search filter",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.Filter = ""
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.BaseDN = testUserDNWithSpecialChars
+				})).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							{
+								DN: testUserDNWithSpecialChars,
+								Attributes: []*ldap.EntryAttribute{
+									{
+										Name:   testUserSearchUsernameAttribute,
+										Values: []string{testUserSearchResultUsernameAttributeValue},
+									},
+									{
+										Name:       testUserSearchUIDAttribute,
+										ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
+									},
+									{
+										Name:       pwdLastSetAttribute,
+										Values:     []string{"132801740800000000"},
+										ByteValues: [][]byte{[]byte("132801740800000000")},
+									},
+								},
+							},
+						},
+					}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
+					r.Filter = fmt.Sprintf("(member=%s)", testUserDNWithSpecialCharsEscaped)
+				}), expectedGroupSearchPageSize).Return(happyPathGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			refreshUserDN: testUserDNWithSpecialChars,
+			wantGroups:    []string{testGroupSearchResultGroupNameAttributeValue1, testGroupSearchResultGroupNameAttributeValue2},
+		},
+		{
+			name:           "happy path where group search returns no groups",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries:   []*ldap.Entry{},
+					Referrals: []string{}, // note that we are not following referrals at this time
+					Controls:  []ldap.Control{},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups: []string{},
+		},
+		{
+			name: "happy path where group search is configured but skipGroupRefresh is set",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.SkipGroupRefresh = true
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1) // note that group search is not expected
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups: nil, // do not update groups
+		},
+		{
+			name:           "happy path where group search is configured but groups scope isn't included",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			grantedScopes: []string{},
+			wantGroups:    nil,
+		},
+		{
+			name:           "error where dial fails",
+			providerConfig: providerConfig(nil),
+			dialError:      errors.New("some dial error"),
+			wantErr:        "error dialing host \"ldap.example.com:8443\": some dial error",
+		},
+		{
+			name:           "error binding",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "error binding as \"cn=some-bind-username,dc=pinniped,dc=dev\" before user search: some bind error",
+		},
+		{
+			name:           "search result returns no entries",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "searching for user \"some-upstream-user-dn\" resulted in 0 search results, but expected 1 result",
+		},
+		{
+			name:           "error searching",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(nil, errors.New("some search error"))
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "error searching for user \"some-upstream-user-dn\": some search error",
+		},
+		{
+			name:           "search result returns more than one entry",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN:         testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{},
+						},
+						{
+							DN:         "doesn't-matter",
+							Attributes: []*ldap.EntryAttribute{},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "searching for user \"some-upstream-user-dn\" resulted in 2 search results, but expected 1 result",
+		},
+		{
+			name:           "search result has wrong uid",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								{
+									Name:   testUserSearchUsernameAttribute,
+									Values: []string{testUserSearchResultUsernameAttributeValue},
+								},
+								{
+									Name:       testUserSearchUIDAttribute,
+									ByteValues: [][]byte{[]byte("wrong-uid")},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "searching for user \"some-upstream-user-dn\" produced a different subject than the previous value. expected: \"ldaps://ldap.example.com:8443?base=some-upstream-user-base-dn&sub=c29tZS11cHN0cmVhbS11aWQtdmFsdWU\", actual: \"ldaps://ldap.example.com:8443?base=some-upstream-user-base-dn&sub=d3JvbmctdWlk\"",
+		},
+		{
+			name:           "search result has wrong username",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								{
+									Name:   testUserSearchUsernameAttribute,
+									Values: []string{"wrong-username"},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "searching for user \"some-upstream-user-dn\" returned a different username than the previous value. expected: \"some-upstream-username-value\", actual: \"wrong-username\"",
+		},
+		{
+			name:           "search result has no dn",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							Attributes: []*ldap.EntryAttribute{
+								{
+									Name:   testUserSearchUsernameAttribute,
+									Values: []string{testUserSearchResultUsernameAttributeValue},
+								},
+								{
+									Name:       testUserSearchUIDAttribute,
+									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "searching for user with original DN \"some-upstream-user-dn\" resulted in search result without DN",
+		},
+		{
+			name:           "search result has 0 values for username attribute",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								{
+									Name:   testUserSearchUsernameAttribute,
+									Values: []string{},
+								},
+								{
+									Name:       testUserSearchUIDAttribute,
+									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "found 0 values for attribute \"some-upstream-username-attribute\" while searching for user \"some-upstream-user-dn\", but expected 1 result",
+		},
+		{
+			name:           "search result has more than one value for username attribute",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								{
+									Name:   testUserSearchUsernameAttribute,
+									Values: []string{testUserSearchResultUsernameAttributeValue, "something-else"},
+								},
+								{
+									Name:       testUserSearchUIDAttribute,
+									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "found 2 values for attribute \"some-upstream-username-attribute\" while searching for user \"some-upstream-user-dn\", but expected 1 result",
+		},
+		{
+			name:           "search result has 0 values for uid attribute",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								{
+									Name:   testUserSearchUsernameAttribute,
+									Values: []string{testUserSearchResultUsernameAttributeValue},
+								},
+								{
+									Name:       testUserSearchUIDAttribute,
+									ByteValues: [][]byte{},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "found 0 values for attribute \"some-upstream-uid-attribute\" while searching for user \"some-upstream-user-dn\", but expected 1 result",
+		},
+		{
+			name:           "search result has 2 values for uid attribute",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								{
+									Name:   testUserSearchUsernameAttribute,
+									Values: []string{testUserSearchResultUsernameAttributeValue},
+								},
+								{
+									Name:       testUserSearchUIDAttribute,
+									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue), []byte("other-uid-value")},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "found 2 values for attribute \"some-upstream-uid-attribute\" while searching for user \"some-upstream-user-dn\", but expected 1 result",
+		},
+		{
+			name:           "search result has a changed pwdLastSet value",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								{
+									Name:   testUserSearchUsernameAttribute,
+									Values: []string{testUserSearchResultUsernameAttributeValue},
+								},
+								{
+									Name:       testUserSearchUIDAttribute,
+									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
+								},
+								{
+									Name:       pwdLastSetAttribute,
+									ByteValues: [][]byte{[]byte("132801740800000001")},
+								},
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "validation for attribute \"pwdLastSet\" failed during upstream refresh: value for attribute \"pwdLastSet\" has changed since initial value at login",
+		},
+		{
+			name:           "group search returns an error",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).Return(nil, errors.New("some search error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr: "error searching for group memberships for user with DN \"some-upstream-user-dn\": some search error",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			conn := mockldapconn.NewMockConn(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(conn)
+			}
+
+			dialWasAttempted := false
+			tt.providerConfig.Dialer = LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+				dialWasAttempted = true
+				require.Equal(t, tt.providerConfig.Host, addr.Endpoint())
+				if tt.dialError != nil {
+					return nil, tt.dialError
+				}
+
+				return conn, nil
+			})
+
+			if tt.refreshUserDN == "" {
+				tt.refreshUserDN = testUserSearchResultDNValue // default for all tests
+			}
+
+			if tt.grantedScopes == nil {
+				tt.grantedScopes = []string{"groups"}
+			}
+			initialPwdLastSetEncoded := base64.RawURLEncoding.EncodeToString([]byte("132801740800000000"))
+			ldapProvider := New(*tt.providerConfig)
+			subject := "ldaps://ldap.example.com:8443?base=some-upstream-user-base-dn&sub=c29tZS11cHN0cmVhbS11aWQtdmFsdWU"
+			groups, err := ldapProvider.PerformRefresh(context.Background(), provider.RefreshAttributes{
+				Username:             testUserSearchResultUsernameAttributeValue,
+				Subject:              subject,
+				DN:                   tt.refreshUserDN,
+				AdditionalAttributes: map[string]string{pwdLastSetAttribute: initialPwdLastSetEncoded},
+				GrantedScopes:        tt.grantedScopes,
+			})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, true, dialWasAttempted)
+			require.Equal(t, tt.wantGroups, groups)
+		})
+	}
+}
+
+func TestTestConnection(t *testing.T) {
+	providerConfig := func(editFunc func(p *ProviderConfig)) *ProviderConfig {
+		config := &ProviderConfig{
+			Name:               "some-provider-name",
+			Host:               testHost,
+			CABundle:           nil, // this field is only used by the production dialer, which is replaced by a mock for this test
+			ConnectionProtocol: TLS,
+			BindUsername:       testBindUsername,
+			BindPassword:       testBindPassword,
+			UserSearch:         UserSearchConfig{}, // not used by TestConnection
+		}
+		if editFunc != nil {
+			editFunc(config)
+		}
+		return config
+	}
+
+	tests := []struct {
+		name           string
+		providerConfig *ProviderConfig
+		setupMocks     func(conn *mockldapconn.MockConn)
+		dialError      error
+		dialErrors     map[string]error
+		wantError      testutil.RequireErrorStringFunc
+		wantToSkipDial bool
+		wantDialed     []string
+		wantHost       string
+	}{
+		{
+			name:           "happy path",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantHost: testHost,
+		},
+		{
+			name: "when multiple hosts are configured and the first one is reachable",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.Host = "ldap1.example.com:8443, ldap2.example.com:8443"
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantDialed: []string{"ldap1.example.com:8443"},
+			wantHost:   "ldap1.example.com:8443",
+		},
+		{
+			name: "when multiple hosts are configured and only a later one is reachable",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.Host = "ldap1.example.com:8443,ldap2.example.com:8443,ldap3.example.com"
+			}),
+			dialErrors: map[string]error{
+				"ldap1.example.com:8443": errors.New("some dial error"),
+			},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantDialed: []string{"ldap1.example.com:8443", "ldap2.example.com:8443"},
+			wantHost:   "ldap2.example.com:8443",
+		},
+		{
+			name: "when multiple hosts are configured and none are reachable",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.Host = "ldap1.example.com:8443,ldap2.example.com"
+			}),
+			dialErrors: map[string]error{
+				"ldap1.example.com:8443": errors.New("some dial error"),
+				"ldap2.example.com:636":  errors.New("some other dial error"),
+			},
+			wantDialed: []string{"ldap1.example.com:8443", "ldap2.example.com:636"},
+			wantError: testutil.WantExactErrorString(`error dialing host "ldap1.example.com:8443,ldap2.example.com": ` +
+				`[host "ldap1.example.com:8443": some dial error, host "ldap2.example.com": some other dial error]`),
+		},
+		{
+			name:           "when dial fails",
+			providerConfig: providerConfig(nil),
+			dialError:      errors.New("some dial error"),
+			wantError:      testutil.WantSprintfErrorString(`error dialing host "%s": some dial error`, testHost),
+		},
+		{
+			name:           "when binding as the bind user returns an error",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`error binding as "%s": some bind error`, testBindUsername),
+		},
+		{
+			name:           "when the connection is reset while binding, then it tries again with a new connection",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				resetErr := ldap.NewError(ldap.ErrorNetwork, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET})
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(resetErr).Times(1)
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(2)
+			},
+			wantDialed: []string{testHost, testHost},
+			wantHost:   testHost,
+		},
+		{
+			name:           "when the connection is broken while binding every time, then it only tries again once",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				brokenPipeErr := &net.OpError{Op: "write", Net: "tcp", Err: syscall.EPIPE}
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(brokenPipeErr).Times(2)
+				conn.EXPECT().Close().Times(2)
+			},
+			wantDialed: []string{testHost, testHost},
+			wantError:  testutil.WantSprintfErrorString(`error binding as "%s": write tcp: broken pipe`, testBindUsername),
+		},
+		{
+			name: "when using an anonymous bind",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.BindUsername = ""
+				p.BindPassword = ""
+				p.AnonymousBind = true
+				p.UserSearch.Base = testUserSearchBase
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().UnauthenticatedBind("").Times(1)
+				conn.EXPECT().Search(&ldap.SearchRequest{
+					BaseDN:       testUserSearchBase,
+					Scope:        ldap.ScopeBaseObject,
+					DerefAliases: ldap.NeverDerefAliases,
+					SizeLimit:    1,
+					TimeLimit:    90,
+					TypesOnly:    true,
+					Filter:       "(objectClass=*)",
+					Attributes:   []string{"objectClass"},
+				}).Return(&ldap.SearchResult{}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantHost: testHost,
+		},
+		{
+			name: "when the anonymous bind returns an error",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.BindUsername = ""
+				p.BindPassword = ""
+				p.AnonymousBind = true
+				p.UserSearch.Base = testUserSearchBase
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().UnauthenticatedBind("").Return(errors.New("some bind error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantExactErrorString(`error binding as anonymous user: some bind error`),
+		},
+		{
+			name: "when the anonymous user is not allowed to search",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.BindUsername = ""
+				p.BindPassword = ""
+				p.AnonymousBind = true
+				p.UserSearch.Base = testUserSearchBase
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().UnauthenticatedBind("").Times(1)
+				conn.EXPECT().Search(gomock.Any()).Return(nil, errors.New("some search error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`error searching for user search base "%s" as anonymous user: some search error`, testUserSearchBase),
+		},
+		{
+			name: "when the config is invalid",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				// This particular combination of options is not allowed.
+				p.UserSearch.UsernameAttribute = "dn"
+				p.UserSearch.Filter = ""
+			}),
+			wantToSkipDial: true,
+			wantError:      testutil.WantExactErrorString(`must specify UserSearch Filter when UserSearch UsernameAttribute is "dn"`),
+		},
+	}
+
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			conn := mockldapconn.NewMockConn(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(conn)
+			}
+
+			var dialed []string
+			tt.providerConfig.Dialer = LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+				dialed = append(dialed, addr.Endpoint())
+				if !strings.Contains(tt.providerConfig.Host, ",") {
+					require.Equal(t, tt.providerConfig.Host, addr.Endpoint())
+				}
+				if err := tt.dialErrors[addr.Endpoint()]; err != nil {
+					return nil, err
+				}
+				if tt.dialError != nil {
+					return nil, tt.dialError
+				}
+				return conn, nil
+			})
+
+			provider := New(*tt.providerConfig)
+			host, err := provider.TestConnection(context.Background())
+
+			require.Equal(t, !tt.wantToSkipDial, len(dialed) > 0)
+			if tt.wantDialed != nil {
+				require.Equal(t, tt.wantDialed, dialed)
+			}
+			require.Equal(t, tt.wantHost, host)
+
+			switch {
+			case tt.wantError != nil:
+				testutil.RequireErrorStringFromErr(t, err, tt.wantError)
 			default:
 				require.NoError(t, err)
-				require.True(t, authenticated)
-				require.Equal(t, tt.wantAuthResponse, authResponse)
 			}
+		})
+	}
+}
+
+// connWithTLSState is a Conn which also describes its TLS connection state, like ldap.Conn does.
+type connWithTLSState struct {
+	*mockldapconn.MockConn
+	state tls.ConnectionState
+}
+
+func (c *connWithTLSState) TLSConnectionState() (tls.ConnectionState, bool) {
+	return c.state, true
+}
+
+func TestTestConnectionWithResultServerCertificateNotAfter(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		state        *tls.ConnectionState
+		wantNotAfter time.Time
+	}{
+		{
+			name:         "the connection does not describe its TLS connection state",
+			wantNotAfter: time.Time{},
+		},
+		{
+			name:         "the server presented no certificates",
+			state:        &tls.ConnectionState{},
+			wantNotAfter: time.Time{},
+		},
+		{
+			name: "the server presented a certificate chain",
+			state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{NotAfter: notAfter},
+				{NotAfter: notAfter.Add(24 * time.Hour)},
+			}},
+			wantNotAfter: notAfter,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+			mockConn := mockldapconn.NewMockConn(ctrl)
+			mockConn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+			mockConn.EXPECT().Close().Times(1)
+
+			var conn Conn = mockConn
+			if tt.state != nil {
+				conn = &connWithTLSState{MockConn: mockConn, state: *tt.state}
+			}
+
+			provider := New(ProviderConfig{
+				Host:               testHost,
+				ConnectionProtocol: TLS,
+				BindUsername:       testBindUsername,
+				BindPassword:       testBindPassword,
+				Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+					return conn, nil
+				}),
+			})
+			result, err := provider.TestConnectionWithResult(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, &TestConnectionResult{
+				Host:                      testHost,
+				Reachable:                 true,
+				Bound:                     true,
+				ServerCertificateNotAfter: tt.wantNotAfter,
+			}, result)
+		})
+	}
+}
+
+func TestDryRunGroupSearch(t *testing.T) {
+	providerConfig := func(editFunc func(p *ProviderConfig)) *ProviderConfig {
+		config := &ProviderConfig{
+			Name:               "some-provider-name",
+			Host:               testHost,
+			CABundle:           nil, // this field is only used by the production dialer, which is replaced by a mock for this test
+			ConnectionProtocol: TLS,
+			BindUsername:       testBindUsername,
+			BindPassword:       testBindPassword,
+			GroupSearch: GroupSearchConfig{
+				Base:               testGroupSearchBase,
+				Filter:             testGroupSearchFilter,
+				GroupNameAttribute: testGroupSearchGroupNameAttribute,
+			},
+		}
+		if editFunc != nil {
+			editFunc(config)
+		}
+		return config
+	}
+
+	expectedGroupSearchBaseSearch := &ldap.SearchRequest{
+		BaseDN:       testGroupSearchBase,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    1,
+		TimeLimit:    90,
+		TypesOnly:    true,
+		Filter:       "(objectClass=*)",
+		Attributes:   []string{"objectClass"},
+	}
+	groupSearchBaseEntry := &ldap.SearchResult{Entries: []*ldap.Entry{{DN: testGroupSearchBase}}}
+
+	expectedGroupSearch := &ldap.SearchRequest{
+		BaseDN:       testGroupSearchBase,
+		Scope:        ldap.ScopeWholeSubtree,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    0, // unlimited size because we will search with paging
+		TimeLimit:    90,
+		TypesOnly:    false,
+		Filter:       testGroupSearchFilterInterpolated,
+		Attributes:   []string{testGroupSearchGroupNameAttribute},
+		Controls:     nil, // nil because ldap.SearchWithPaging() will set the appropriate controls for us
+	}
+
+	expectedUserGroupsAttributeSearch := &ldap.SearchRequest{
+		BaseDN:       testUserSearchResultDNValue,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    2,
+		TimeLimit:    90,
+		TypesOnly:    false,
+		Filter:       "(objectClass=*)",
+		Attributes:   []string{"memberOf"},
+	}
+
+	expectedNestedGroupSearch := func(groupDN string) *ldap.SearchRequest {
+		search := *expectedGroupSearch
+		search.Filter = fmt.Sprintf("(some-group-filter=%s-and-more-filter=%s)", groupDN, groupDN)
+		return &search
+	}
+
+	expectedGroupEntryRead := func(groupDN string) *ldap.SearchRequest {
+		return &ldap.SearchRequest{
+			BaseDN:       groupDN,
+			Scope:        ldap.ScopeBaseObject,
+			DerefAliases: ldap.NeverDerefAliases,
+			SizeLimit:    2,
+			TimeLimit:    90,
+			TypesOnly:    false,
+			Filter:       "(objectClass=*)",
+			Attributes:   []string{"memberOf"},
+		}
+	}
+
+	groupEntry := func(dn, name string, memberOf ...string) *ldap.Entry {
+		entry := &ldap.Entry{DN: dn, Attributes: []*ldap.EntryAttribute{
+			ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{name}),
+		}}
+		if len(memberOf) > 0 {
+			entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute("memberOf", memberOf))
+		}
+		return entry
+	}
+
+	tests := []struct {
+		name           string
+		providerConfig *ProviderConfig
+		setupMocks     func(conn *mockldapconn.MockConn)
+		dialError      error
+		wantError      testutil.RequireErrorStringFunc
+		wantToSkipDial bool
+		wantGroups     []string
+
+		wantDisallowedGroupCount int
+		wantDirectGroupCount     int
+		wantNestedGroupCount     int
+	}{
+		{
+			name:           "happy path",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testGroupSearchResultDNValue2,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue2}),
+							},
+						},
+						{
+							DN: testGroupSearchResultDNValue1,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups:           []string{testGroupSearchResultGroupNameAttributeValue1, testGroupSearchResultGroupNameAttributeValue2},
+			wantDirectGroupCount: 2,
+		},
+		{
+			name: "when allowed groups are configured",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.AllowedGroups = []string{"CN=Admins, OU=Groups,DC=example,DC=com", "cn=unused,ou=groups,dc=example,dc=com"}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: "cn=admins,ou=groups,dc=example,dc=com",
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"admins"}),
+							},
+						},
+						{
+							DN: "cn=developers,ou=groups,dc=example,dc=com",
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{"developers"}),
+							},
+						},
+						{
+							DN: testGroupSearchResultDNValue1, // not a parsable DN, so it cannot match any allowed group
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups:               []string{"admins"},
+			wantDisallowedGroupCount: 2,
+			wantDirectGroupCount:     3,
+		},
+		{
+			name:           "when the group search finds no groups",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).Return(&ldap.SearchResult{}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups: []string{},
+		},
+		{
+			name: "when group search is not configured",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.Base = ""
+			}),
+			wantToSkipDial: true,
+			wantGroups:     []string{},
+		},
+		{
+			name:           "when dial fails",
+			providerConfig: providerConfig(nil),
+			dialError:      errors.New("some dial error"),
+			wantError:      testutil.WantSprintfErrorString(`error dialing host "%s": some dial error`, testHost),
+		},
+		{
+			name:           "when binding as the bind user returns an error",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`error binding as "%s" before group search: some bind error`, testBindUsername),
+		},
+		{
+			name:           "when the group search base does not exist",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(nil, ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object"))).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`group search base is unreadable: error searching for %q as "%s": LDAP Result Code 32 "No Such Object": no such object`, testGroupSearchBase, testBindUsername),
+		},
+		{
+			name:           "when the group search base cannot be read by the bind user",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(&ldap.SearchResult{}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`group search base is unreadable: %q was not found or could not be read as "%s"`, testGroupSearchBase, testBindUsername),
+		},
+		{
+			name:           "when the group search returns an error",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).Return(nil, errors.New("some search error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`error searching for group memberships for user with DN %q: some search error`, testUserSearchResultDNValue),
+		},
+		{
+			name: "when the group search mode is userAttribute then the group DNs are read from the user entry",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch = GroupSearchConfig{
+					Mode:          GroupSearchModeUserAttribute,
+					AllowedGroups: []string{"cn=admins,ou=groups,dc=example,dc=com"},
+				}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserGroupsAttributeSearch).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute("memberOf", []string{
+									"cn=admins,ou=groups,dc=example,dc=com", "cn=developers,ou=groups,dc=example,dc=com",
+								}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups:               []string{"cn=admins,ou=groups,dc=example,dc=com"},
+			wantDisallowedGroupCount: 1,
+			wantDirectGroupCount:     2,
+		},
+		{
+			name: "when nested groups are resolved then the groups which contain the user's groups are included, and cycles are ignored",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.NestedGroupsMaxDepth = 5
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=developers,ou=groups,dc=example,dc=com", "developers"),
+						groupEntry("cn=testers,ou=groups,dc=example,dc=com", "testers"),
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedNestedGroupSearch("cn=developers,ou=groups,dc=example,dc=com"), expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=engineering,ou=groups,dc=example,dc=com", "engineering"),
+						groupEntry("CN=Testers, OU=Groups,DC=example,DC=com", "testers"), // already found for the user
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedNestedGroupSearch("cn=testers,ou=groups,dc=example,dc=com"), expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=engineering,ou=groups,dc=example,dc=com", "engineering"), // already found at this level
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedNestedGroupSearch("cn=engineering,ou=groups,dc=example,dc=com"), expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=everyone,ou=groups,dc=example,dc=com", "everyone"),
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedNestedGroupSearch("cn=everyone,ou=groups,dc=example,dc=com"), expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=developers,ou=groups,dc=example,dc=com", "developers"), // a cycle back to the user's group
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups:           []string{"developers", "engineering", "everyone", "testers"},
+			wantDirectGroupCount: 2,
+			wantNestedGroupCount: 2,
+		},
+		{
+			name: "when nested groups are resolved then no more levels than the max depth are searched",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.NestedGroupsMaxDepth = 1
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=developers,ou=groups,dc=example,dc=com", "developers"),
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedNestedGroupSearch("cn=developers,ou=groups,dc=example,dc=com"), expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=engineering,ou=groups,dc=example,dc=com", "engineering"),
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups:           []string{"developers", "engineering"},
+			wantDirectGroupCount: 1,
+			wantNestedGroupCount: 1,
+		},
+		{
+			name: "when nested groups are resolved then the allowed groups are applied to the nested groups too",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.NestedGroupsMaxDepth = 1
+				p.GroupSearch.AllowedGroups = []string{"cn=engineering,ou=groups,dc=example,dc=com"}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=developers,ou=groups,dc=example,dc=com", "developers"),
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedNestedGroupSearch("cn=developers,ou=groups,dc=example,dc=com"), expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=engineering,ou=groups,dc=example,dc=com", "engineering"),
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups:               []string{"engineering"},
+			wantDisallowedGroupCount: 1,
+			wantDirectGroupCount:     1,
+			wantNestedGroupCount:     1,
+		},
+		{
+			name: "when nested groups are resolved and searching for the groups which contain a group returns an error",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.NestedGroupsMaxDepth = 5
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						groupEntry("cn=developers,ou=groups,dc=example,dc=com", "developers"),
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedNestedGroupSearch("cn=developers,ou=groups,dc=example,dc=com"), expectedGroupSearchPageSize).
+					Return(nil, errors.New("some search error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`error searching for the groups which contain group "cn=developers,ou=groups,dc=example,dc=com" for user with DN %q: some search error`, testUserSearchResultDNValue),
+		},
+		{
+			name: "when the group search mode is userAttribute and nested groups are resolved then they are read from the group entries",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch = GroupSearchConfig{
+					Mode:                 GroupSearchModeUserAttribute,
+					NestedGroupsMaxDepth: 5,
+				}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserGroupsAttributeSearch).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute("memberOf", []string{"cn=developers,ou=groups,dc=example,dc=com"}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Search(expectedGroupEntryRead("cn=developers,ou=groups,dc=example,dc=com")).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: "cn=developers,ou=groups,dc=example,dc=com",
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute("memberOf", []string{"cn=engineering,ou=groups,dc=example,dc=com"}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Search(expectedGroupEntryRead("cn=engineering,ou=groups,dc=example,dc=com")).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: "cn=engineering,ou=groups,dc=example,dc=com",
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute("memberOf", []string{"cn=developers,ou=groups,dc=example,dc=com"}), // a cycle
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantGroups:           []string{"cn=developers,ou=groups,dc=example,dc=com", "cn=engineering,ou=groups,dc=example,dc=com"},
+			wantDirectGroupCount: 1,
+			wantNestedGroupCount: 1,
+		},
+		{
+			name: "when the group search mode is userAttribute and the user entry is not found",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch = GroupSearchConfig{Mode: GroupSearchModeUserAttribute}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserGroupsAttributeSearch).Return(&ldap.SearchResult{}, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`reading the entry of user with DN %q resulted in 0 search results, but expected 1 result`, testUserSearchResultDNValue),
+		},
+		{
+			name: "when the group search mode is userAttribute and reading the user entry returns an error",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch = GroupSearchConfig{Mode: GroupSearchModeUserAttribute}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserGroupsAttributeSearch).Return(nil, errors.New("some search error")).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantError: testutil.WantSprintfErrorString(`error reading the entry of user with DN %q: some search error`, testUserSearchResultDNValue),
+		},
+	}
 
-			// DryRunAuthenticateUser() should have the same behavior as AuthenticateUser() except that it does not bind
-			// as the end user to confirm their password. Since it should behave the same, all of the same test cases
-			// apply, except for those which are specifically testing what happens when the end user bind fails.
-			if tt.skipDryRunAuthenticateUser {
-				return // move on to the next test
-			}
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
 
-			// Reset some variables to get ready to call DryRunAuthenticateUser().
-			dialWasAttempted = false
-			conn = mockldapconn.NewMockConn(ctrl)
-			if tt.searchMocks != nil {
-				tt.searchMocks(conn)
+			conn := mockldapconn.NewMockConn(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(conn)
 			}
-			// Skip tt.bindEndUserMocks since DryRunAuthenticateUser() never binds as the end user.
 
-			authResponse, authenticated, err = ldapProvider.DryRunAuthenticateUser(context.Background(), tt.username, tt.grantedScopes)
+			dialWasAttempted := false
+			tt.providerConfig.Dialer = LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+				dialWasAttempted = true
+				require.Equal(t, tt.providerConfig.Host, addr.Endpoint())
+				if tt.dialError != nil {
+					return nil, tt.dialError
+				}
+				return conn, nil
+			})
+
+			provider := New(*tt.providerConfig)
+			result, err := provider.DryRunGroupSearchWithResult(context.Background(), testUserSearchResultDNValue)
+
 			require.Equal(t, !tt.wantToSkipDial, dialWasAttempted)
+
 			switch {
 			case tt.wantError != nil:
 				testutil.RequireErrorStringFromErr(t, err, tt.wantError)
-				require.False(t, authenticated)
-				require.Nil(t, authResponse)
-			case tt.wantUnauthenticated:
-				require.NoError(t, err)
-				require.False(t, authenticated)
-				require.Nil(t, authResponse)
+				require.Nil(t, result)
 			default:
 				require.NoError(t, err)
-				require.True(t, authenticated)
-				require.Equal(t, tt.wantAuthResponse, authResponse)
+				require.Equal(t, &GroupSearchResult{
+					Groups:               tt.wantGroups,
+					DisallowedGroupCount: tt.wantDisallowedGroupCount,
+					DirectGroupCount:     tt.wantDirectGroupCount,
+					NestedGroupCount:     tt.wantNestedGroupCount,
+				}, result)
 			}
 		})
 	}
 }
 
-func TestUpstreamRefresh(t *testing.T) {
-	pwdLastSetAttribute := "pwdLastSet"
-
-	expectedUserSearch := func(editFunc func(r *ldap.SearchRequest)) *ldap.SearchRequest {
-		request := &ldap.SearchRequest{
-			BaseDN:       testUserSearchResultDNValue,
-			Scope:        ldap.ScopeBaseObject,
-			DerefAliases: ldap.NeverDerefAliases,
-			SizeLimit:    2,
-			TimeLimit:    90,
-			TypesOnly:    false,
-			Filter:       "(objectClass=*)",
-			Attributes:   []string{testUserSearchUsernameAttribute, testUserSearchUIDAttribute, pwdLastSetAttribute},
-			Controls:     nil, // don't need paging because we set the SizeLimit so small
-		}
-		if editFunc != nil {
-			editFunc(request)
-		}
-		return request
-	}
-
-	expectedGroupSearch := func(editFunc func(r *ldap.SearchRequest)) *ldap.SearchRequest {
-		request := &ldap.SearchRequest{
-			BaseDN:       testGroupSearchBase,
-			Scope:        ldap.ScopeWholeSubtree,
-			DerefAliases: ldap.NeverDerefAliases,
-			SizeLimit:    0, // unlimited size because we will search with paging
-			TimeLimit:    90,
-			TypesOnly:    false,
-			Filter:       testGroupSearchFilterInterpolated,
-			Attributes:   []string{testGroupSearchGroupNameAttribute},
-			Controls:     nil, // nil because ldap.SearchWithPaging() will set the appropriate controls for us
-		}
-		if editFunc != nil {
-			editFunc(request)
-		}
-		return request
-	}
-
-	happyPathUserSearchResult := &ldap.SearchResult{
-		Entries: []*ldap.Entry{
-			{
-				DN: testUserSearchResultDNValue,
-				Attributes: []*ldap.EntryAttribute{
-					{
-						Name:   testUserSearchUsernameAttribute,
-						Values: []string{testUserSearchResultUsernameAttributeValue},
-					},
-					{
-						Name:       testUserSearchUIDAttribute,
-						ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
-					},
-					{
-						Name:       pwdLastSetAttribute,
-						Values:     []string{"132801740800000000"},
-						ByteValues: [][]byte{[]byte("132801740800000000")},
-					},
-				},
-			},
-		},
-	}
-
-	happyPathGroupSearchResult := &ldap.SearchResult{
-		Entries: []*ldap.Entry{
-			{
-				DN: testGroupSearchResultDNValue1,
-				Attributes: []*ldap.EntryAttribute{
-					ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
-				},
-			},
-			{
-				DN: testGroupSearchResultDNValue2,
-				Attributes: []*ldap.EntryAttribute{
-					ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue2}),
-				},
-			},
-		},
-		Referrals: []string{}, // note that we are not following referrals at this time
-		Controls:  []ldap.Control{},
-	}
-
+func TestValidateUserSearchBase(t *testing.T) {
 	providerConfig := func(editFunc func(p *ProviderConfig)) *ProviderConfig {
 		config := &ProviderConfig{
 			Name:               "some-provider-name",
@@ -1333,17 +3605,7 @@ func TestUpstreamRefresh(t *testing.T) {
 			BindUsername:       testBindUsername,
 			BindPassword:       testBindPassword,
 			UserSearch: UserSearchConfig{
-				Base:              testUserSearchBase,
-				UIDAttribute:      testUserSearchUIDAttribute,
-				UsernameAttribute: testUserSearchUsernameAttribute,
-			},
-			GroupSearch: GroupSearchConfig{
-				Base:               testGroupSearchBase,
-				Filter:             testGroupSearchFilter,
-				GroupNameAttribute: testGroupSearchGroupNameAttribute,
-			},
-			RefreshAttributeChecks: map[string]func(*ldap.Entry, provider.RefreshAttributes) error{
-				pwdLastSetAttribute: AttributeUnchangedSinceLogin(pwdLastSetAttribute),
+				Base: testUserSearchBase,
 			},
 		}
 		if editFunc != nil {
@@ -1352,435 +3614,273 @@ func TestUpstreamRefresh(t *testing.T) {
 		return config
 	}
 
+	expectedUserSearchBaseSearch := &ldap.SearchRequest{
+		BaseDN:       testUserSearchBase,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    1,
+		TimeLimit:    90,
+		TypesOnly:    true,
+		Filter:       "(objectClass=*)",
+		Attributes:   []string{"objectClass"},
+	}
+	userSearchBaseEntry := &ldap.SearchResult{Entries: []*ldap.Entry{{DN: testUserSearchBase}}}
+
 	tests := []struct {
 		name           string
 		providerConfig *ProviderConfig
-		grantedScopes  []string
 		setupMocks     func(conn *mockldapconn.MockConn)
-		refreshUserDN  string
 		dialError      error
-		wantErr        string
-		wantGroups     []string
+		wantError      testutil.RequireErrorStringFunc
+		wantToSkipDial bool
 	}{
 		{
-			name: "happy path without group search where searching the dn returns a single entry",
-			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.GroupSearch = GroupSearchConfig{}
-			}),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
-			},
-			wantGroups: []string{},
-		},
-		{
-			name:           "happy path where group search returns groups",
-			providerConfig: providerConfig(nil),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).Return(happyPathGroupSearchResult, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
-			},
-			wantGroups: []string{testGroupSearchResultGroupNameAttributeValue1, testGroupSearchResultGroupNameAttributeValue2},
-		},
-		{
-			name:           "happy path when the user DN has special LDAP search filter characters then they must be properly escaped in the custom group search filter",
+			name:           "happy path",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
-					r.BaseDN = testUserDNWithSpecialChars
-				})).
-					Return(&ldap.SearchResult{
-						Entries: []*ldap.Entry{
-							{
-								DN: testUserDNWithSpecialChars,
-								Attributes: []*ldap.EntryAttribute{
-									{
-										Name:   testUserSearchUsernameAttribute,
-										Values: []string{testUserSearchResultUsernameAttributeValue},
-									},
-									{
-										Name:       testUserSearchUIDAttribute,
-										ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
-									},
-									{
-										Name:       pwdLastSetAttribute,
-										Values:     []string{"132801740800000000"},
-										ByteValues: [][]byte{[]byte("132801740800000000")},
-									},
-								},
-							},
-						},
-					}, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
-					r.Filter = fmt.Sprintf("(some-group-filter=%s-and-more-filter=%s)", testUserDNWithSpecialCharsEscaped, testUserDNWithSpecialCharsEscaped)
-				}), expectedGroupSearchPageSize).Return(happyPathGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(userSearchBaseEntry, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			refreshUserDN: testUserDNWithSpecialChars,
-			wantGroups:    []string{testGroupSearchResultGroupNameAttributeValue1, testGroupSearchResultGroupNameAttributeValue2},
 		},
 		{
-			name: "when the user DN has special LDAP search filter characters then they must be properly escaped in the default group search filter",
+			name: "happy path when using anonymous bind",
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.GroupSearch.Filter = ""
+				p.BindUsername = ""
+				p.BindPassword = ""
+				p.AnonymousBind = true
 			}),
 			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
-					r.BaseDN = testUserDNWithSpecialChars
-				})).
-					Return(&ldap.SearchResult{
-						Entries: []*ldap.Entry{
-							{
-								DN: testUserDNWithSpecialChars,
-								Attributes: []*ldap.EntryAttribute{
-									{
-										Name:   testUserSearchUsernameAttribute,
-										Values: []string{testUserSearchResultUsernameAttributeValue},
-									},
-									{
-										Name:       testUserSearchUIDAttribute,
-										ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
-									},
-									{
-										Name:       pwdLastSetAttribute,
-										Values:     []string{"132801740800000000"},
-										ByteValues: [][]byte{[]byte("132801740800000000")},
-									},
-								},
-							},
-						},
-					}, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(func(r *ldap.SearchRequest) {
-					r.Filter = fmt.Sprintf("(member=%s)", testUserDNWithSpecialCharsEscaped)
-				}), expectedGroupSearchPageSize).Return(happyPathGroupSearchResult, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
-			},
-			refreshUserDN: testUserDNWithSpecialChars,
-			wantGroups:    []string{testGroupSearchResultGroupNameAttributeValue1, testGroupSearchResultGroupNameAttributeValue2},
-		},
-		{
-			name:           "happy path where group search returns no groups",
-			providerConfig: providerConfig(nil),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).Return(&ldap.SearchResult{
-					Entries:   []*ldap.Entry{},
-					Referrals: []string{}, // note that we are not following referrals at this time
-					Controls:  []ldap.Control{},
-				}, nil).Times(1)
+				conn.EXPECT().UnauthenticatedBind("").Times(1)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(userSearchBaseEntry, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantGroups: []string{},
 		},
 		{
-			name: "happy path where group search is configured but skipGroupRefresh is set",
+			name: "when the user search base is not configured",
 			providerConfig: providerConfig(func(p *ProviderConfig) {
-				p.GroupSearch.SkipGroupRefresh = true
+				p.UserSearch.Base = ""
 			}),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1) // note that group search is not expected
-				conn.EXPECT().Close().Times(1)
-			},
-			wantGroups: nil, // do not update groups
-		},
-		{
-			name:           "happy path where group search is configured but groups scope isn't included",
-			providerConfig: providerConfig(nil),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
-			},
-			grantedScopes: []string{},
-			wantGroups:    nil,
+			wantToSkipDial: true,
 		},
 		{
-			name:           "error where dial fails",
+			name:           "when dial fails",
 			providerConfig: providerConfig(nil),
 			dialError:      errors.New("some dial error"),
-			wantErr:        "error dialing host \"ldap.example.com:8443\": some dial error",
+			wantError:      testutil.WantSprintfErrorString(`error dialing host "%s": some dial error`, testHost),
 		},
 		{
-			name:           "error binding",
+			name:           "when binding as the bind user returns an error",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantErr: "error binding as \"cn=some-bind-username,dc=pinniped,dc=dev\" before user search: some bind error",
-		},
-		{
-			name:           "search result returns no entries",
-			providerConfig: providerConfig(nil),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{},
-				}, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
-			},
-			wantErr: "searching for user \"some-upstream-user-dn\" resulted in 0 search results, but expected 1 result",
+			wantError: testutil.WantSprintfErrorString(`error binding as "%s" before searching for user search base: some bind error`, testBindUsername),
 		},
 		{
-			name:           "error searching",
+			name:           "when the user search base does not exist",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(nil, errors.New("some search error"))
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).
+					Return(nil, ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object"))).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantErr: "error searching for user \"some-upstream-user-dn\": some search error",
+			wantError: testutil.WantSprintfErrorString(
+				`error searching for user search base "%s" as "%s": LDAP Result Code 32 "No Such Object": no such object`,
+				testUserSearchBase, testBindUsername),
 		},
 		{
-			name:           "search result returns more than one entry",
+			name:           "when the user search base cannot be read by the bind user",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN:         testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{},
-						},
-						{
-							DN:         "doesn't-matter",
-							Attributes: []*ldap.EntryAttribute{},
-						},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(&ldap.SearchResult{}, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantErr: "searching for user \"some-upstream-user-dn\" resulted in 2 search results, but expected 1 result",
+			wantError: testutil.WantSprintfErrorString(
+				`user search base "%s" was not found or could not be read as "%s"`, testUserSearchBase, testBindUsername),
 		},
-		{
-			name:           "search result has wrong uid",
-			providerConfig: providerConfig(nil),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								{
-									Name:   testUserSearchUsernameAttribute,
-									Values: []string{testUserSearchResultUsernameAttributeValue},
-								},
-								{
-									Name:       testUserSearchUIDAttribute,
-									ByteValues: [][]byte{[]byte("wrong-uid")},
-								},
-							},
-						},
-					},
-				}, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
+	}
+
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			conn := mockldapconn.NewMockConn(ctrl)
+			if tt.setupMocks != nil {
+				tt.setupMocks(conn)
+			}
+
+			dialWasAttempted := false
+			tt.providerConfig.Dialer = LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+				dialWasAttempted = true
+				require.Equal(t, tt.providerConfig.Host, addr.Endpoint())
+				if tt.dialError != nil {
+					return nil, tt.dialError
+				}
+				return conn, nil
+			})
+
+			err := New(*tt.providerConfig).ValidateUserSearchBase(context.Background())
+
+			require.Equal(t, !tt.wantToSkipDial, dialWasAttempted)
+			switch {
+			case tt.wantError != nil:
+				testutil.RequireErrorStringFromErr(t, err, tt.wantError)
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateGroupSearch(t *testing.T) {
+	providerConfig := func(editFunc func(p *ProviderConfig)) *ProviderConfig {
+		config := &ProviderConfig{
+			Name:               "some-provider-name",
+			Host:               testHost,
+			CABundle:           nil, // this field is only used by the production dialer, which is replaced by a mock for this test
+			ConnectionProtocol: TLS,
+			BindUsername:       testBindUsername,
+			BindPassword:       testBindPassword,
+			GroupSearch: GroupSearchConfig{
+				Base:               testGroupSearchBase,
+				Filter:             testGroupSearchFilter,
+				GroupNameAttribute: testGroupSearchGroupNameAttribute,
 			},
-			wantErr: "searching for user \"some-upstream-user-dn\" produced a different subject than the previous value. expected: \"ldaps://ldap.example.com:8443?base=some-upstream-user-base-dn&sub=c29tZS11cHN0cmVhbS11aWQtdmFsdWU\", actual: \"ldaps://ldap.example.com:8443?base=some-upstream-user-base-dn&sub=d3JvbmctdWlk\"",
-		},
+		}
+		if editFunc != nil {
+			editFunc(config)
+		}
+		return config
+	}
+
+	expectedGroupSearchBaseSearch := &ldap.SearchRequest{
+		BaseDN:       testGroupSearchBase,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    1,
+		TimeLimit:    90,
+		TypesOnly:    true,
+		Filter:       "(objectClass=*)",
+		Attributes:   []string{"objectClass"},
+	}
+	expectedGroupSearch := &ldap.SearchRequest{
+		BaseDN:       testGroupSearchBase,
+		Scope:        ldap.ScopeWholeSubtree,
+		DerefAliases: ldap.NeverDerefAliases,
+		TimeLimit:    90,
+		Filter: "(some-group-filter=cn=pinniped-group-search-validation" +
+			"-and-more-filter=cn=pinniped-group-search-validation)",
+		Attributes: []string{testGroupSearchGroupNameAttribute},
+	}
+	groupSearchBaseEntry := &ldap.SearchResult{Entries: []*ldap.Entry{{DN: testGroupSearchBase}}}
+
+	tests := []struct {
+		name           string
+		providerConfig *ProviderConfig
+		setupMocks     func(conn *mockldapconn.MockConn)
+		dialError      error
+		wantError      testutil.RequireErrorStringFunc
+		wantToSkipDial bool
+	}{
 		{
-			name:           "search result has wrong username",
+			name:           "happy path ignores the groups which are found",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								{
-									Name:   testUserSearchUsernameAttribute,
-									Values: []string{"wrong-username"},
-								},
-							},
-						},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).
+					Return(&ldap.SearchResult{Entries: []*ldap.Entry{{DN: testGroupSearchResultDNValue1}}}, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantErr: "searching for user \"some-upstream-user-dn\" returned a different username than the previous value. expected: \"some-upstream-username-value\", actual: \"wrong-username\"",
 		},
 		{
-			name:           "search result has no dn",
-			providerConfig: providerConfig(nil),
+			name: "happy path when using anonymous bind",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.BindUsername = ""
+				p.BindPassword = ""
+				p.AnonymousBind = true
+			}),
 			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							Attributes: []*ldap.EntryAttribute{
-								{
-									Name:   testUserSearchUsernameAttribute,
-									Values: []string{testUserSearchResultUsernameAttributeValue},
-								},
-								{
-									Name:       testUserSearchUIDAttribute,
-									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
-								},
-							},
-						},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().UnauthenticatedBind("").Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).
+					Return(&ldap.SearchResult{}, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantErr: "searching for user with original DN \"some-upstream-user-dn\" resulted in search result without DN",
 		},
 		{
-			name:           "search result has 0 values for username attribute",
-			providerConfig: providerConfig(nil),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								{
-									Name:   testUserSearchUsernameAttribute,
-									Values: []string{},
-								},
-								{
-									Name:       testUserSearchUIDAttribute,
-									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
-								},
-							},
-						},
-					},
-				}, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
-			},
-			wantErr: "found 0 values for attribute \"some-upstream-username-attribute\" while searching for user \"some-upstream-user-dn\", but expected 1 result",
+			name: "when the group search base is not configured",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.Base = ""
+			}),
+			wantToSkipDial: true,
 		},
 		{
-			name:           "search result has more than one value for username attribute",
-			providerConfig: providerConfig(nil),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								{
-									Name:   testUserSearchUsernameAttribute,
-									Values: []string{testUserSearchResultUsernameAttributeValue, "something-else"},
-								},
-								{
-									Name:       testUserSearchUIDAttribute,
-									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
-								},
-							},
-						},
-					},
-				}, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
-			},
-			wantErr: "found 2 values for attribute \"some-upstream-username-attribute\" while searching for user \"some-upstream-user-dn\", but expected 1 result",
+			name: "when the groups are read from the user's entry",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.Mode = GroupSearchModeUserAttribute
+			}),
+			wantToSkipDial: true,
 		},
 		{
-			name:           "search result has 0 values for uid attribute",
+			name: "when the group search filter does not parse",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.Filter = "(&(member={})"
+			}),
+			wantError: testutil.WantExactErrorString(
+				`group search filter "(&(member=cn=pinniped-group-search-validation)" is invalid: ` +
+					`LDAP Result Code 201 "Filter Compile Error": ldap: unexpected end of filter`),
+			wantToSkipDial: true,
+		},
+		{
+			name:           "when dial fails",
 			providerConfig: providerConfig(nil),
-			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								{
-									Name:   testUserSearchUsernameAttribute,
-									Values: []string{testUserSearchResultUsernameAttributeValue},
-								},
-								{
-									Name:       testUserSearchUIDAttribute,
-									ByteValues: [][]byte{},
-								},
-							},
-						},
-					},
-				}, nil).Times(1)
-				conn.EXPECT().Close().Times(1)
-			},
-			wantErr: "found 0 values for attribute \"some-upstream-uid-attribute\" while searching for user \"some-upstream-user-dn\", but expected 1 result",
+			dialError:      errors.New("some dial error"),
+			wantError:      testutil.WantSprintfErrorString(`error dialing host "%s": some dial error`, testHost),
 		},
 		{
-			name:           "search result has 2 values for uid attribute",
+			name:           "when binding as the bind user returns an error",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								{
-									Name:   testUserSearchUsernameAttribute,
-									Values: []string{testUserSearchResultUsernameAttributeValue},
-								},
-								{
-									Name:       testUserSearchUIDAttribute,
-									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue), []byte("other-uid-value")},
-								},
-							},
-						},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantErr: "found 2 values for attribute \"some-upstream-uid-attribute\" while searching for user \"some-upstream-user-dn\", but expected 1 result",
+			wantError: testutil.WantSprintfErrorString(`error binding as "%s" before group search: some bind error`, testBindUsername),
 		},
 		{
-			name:           "search result has a changed pwdLastSet value",
+			name:           "when the group search base cannot be read by the bind user",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(&ldap.SearchResult{
-					Entries: []*ldap.Entry{
-						{
-							DN: testUserSearchResultDNValue,
-							Attributes: []*ldap.EntryAttribute{
-								{
-									Name:   testUserSearchUsernameAttribute,
-									Values: []string{testUserSearchResultUsernameAttributeValue},
-								},
-								{
-									Name:       testUserSearchUIDAttribute,
-									ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)},
-								},
-								{
-									Name:       pwdLastSetAttribute,
-									ByteValues: [][]byte{[]byte("132801740800000001")},
-								},
-							},
-						},
-					},
-				}, nil).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(&ldap.SearchResult{}, nil).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantErr: "validation for attribute \"pwdLastSet\" failed during upstream refresh: value for attribute \"pwdLastSet\" has changed since initial value at login",
+			wantError: testutil.WantSprintfErrorString(
+				`group search base is unreadable: "%s" was not found or could not be read as "%s"`, testGroupSearchBase, testBindUsername),
 		},
 		{
-			name:           "group search returns an error",
+			name:           "when the server rejects the group search",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Search(expectedUserSearch(nil)).Return(happyPathUserSearchResult, nil).Times(1)
-				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).Return(nil, errors.New("some search error")).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch, expectedGroupSearchPageSize).
+					Return(nil, ldap.NewError(ldap.LDAPResultUndefinedAttributeType, errors.New("some search error"))).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantErr: "error searching for group memberships for user with DN \"some-upstream-user-dn\": some search error",
+			wantError: testutil.WantExactErrorString(
+				`error running the group search: LDAP Result Code 17 "Undefined Attribute Type": some search error`),
 		},
 	}
 
-	for _, tt := range tests {
-		tt := tt
+	for _, test := range tests {
+		tt := test
 		t.Run(tt.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			t.Cleanup(ctrl.Finish)
@@ -1797,40 +3897,23 @@ func TestUpstreamRefresh(t *testing.T) {
 				if tt.dialError != nil {
 					return nil, tt.dialError
 				}
-
 				return conn, nil
 			})
 
-			if tt.refreshUserDN == "" {
-				tt.refreshUserDN = testUserSearchResultDNValue // default for all tests
-			}
+			err := New(*tt.providerConfig).ValidateGroupSearch(context.Background())
 
-			if tt.grantedScopes == nil {
-				tt.grantedScopes = []string{"groups"}
-			}
-			initialPwdLastSetEncoded := base64.RawURLEncoding.EncodeToString([]byte("132801740800000000"))
-			ldapProvider := New(*tt.providerConfig)
-			subject := "ldaps://ldap.example.com:8443?base=some-upstream-user-base-dn&sub=c29tZS11cHN0cmVhbS11aWQtdmFsdWU"
-			groups, err := ldapProvider.PerformRefresh(context.Background(), provider.RefreshAttributes{
-				Username:             testUserSearchResultUsernameAttributeValue,
-				Subject:              subject,
-				DN:                   tt.refreshUserDN,
-				AdditionalAttributes: map[string]string{pwdLastSetAttribute: initialPwdLastSetEncoded},
-				GrantedScopes:        tt.grantedScopes,
-			})
-			if tt.wantErr != "" {
-				require.Error(t, err)
-				require.Equal(t, tt.wantErr, err.Error())
-			} else {
+			require.Equal(t, !tt.wantToSkipDial, dialWasAttempted)
+			switch {
+			case tt.wantError != nil:
+				testutil.RequireErrorStringFromErr(t, err, tt.wantError)
+			default:
 				require.NoError(t, err)
 			}
-			require.Equal(t, true, dialWasAttempted)
-			require.Equal(t, tt.wantGroups, groups)
 		})
 	}
 }
 
-func TestTestConnection(t *testing.T) {
+func TestDryRun(t *testing.T) {
 	providerConfig := func(editFunc func(p *ProviderConfig)) *ProviderConfig {
 		config := &ProviderConfig{
 			Name:               "some-provider-name",
@@ -1839,7 +3922,17 @@ func TestTestConnection(t *testing.T) {
 			ConnectionProtocol: TLS,
 			BindUsername:       testBindUsername,
 			BindPassword:       testBindPassword,
-			UserSearch:         UserSearchConfig{}, // not used by TestConnection
+			UserSearch: UserSearchConfig{
+				Base:              testUserSearchBase,
+				Filter:            testUserSearchFilter,
+				UsernameAttribute: testUserSearchUsernameAttribute,
+				UIDAttribute:      testUserSearchUIDAttribute,
+			},
+			GroupSearch: GroupSearchConfig{
+				Base:               testGroupSearchBase,
+				Filter:             testGroupSearchFilter,
+				GroupNameAttribute: testGroupSearchGroupNameAttribute,
+			},
 		}
 		if editFunc != nil {
 			editFunc(config)
@@ -1847,46 +3940,195 @@ func TestTestConnection(t *testing.T) {
 		return config
 	}
 
+	expectedUserSearchBaseSearch := &ldap.SearchRequest{
+		BaseDN:       testUserSearchBase,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    1,
+		TimeLimit:    90,
+		TypesOnly:    true,
+		Filter:       "(objectClass=*)",
+		Attributes:   []string{"objectClass"},
+	}
+	expectedGroupSearchBaseSearch := &ldap.SearchRequest{
+		BaseDN:       testGroupSearchBase,
+		Scope:        ldap.ScopeBaseObject,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    1,
+		TimeLimit:    90,
+		TypesOnly:    true,
+		Filter:       "(objectClass=*)",
+		Attributes:   []string{"objectClass"},
+	}
+	expectedUserSearch := &ldap.SearchRequest{
+		BaseDN:       testUserSearchBase,
+		Scope:        ldap.ScopeWholeSubtree,
+		DerefAliases: ldap.NeverDerefAliases,
+		SizeLimit:    2,
+		TimeLimit:    90,
+		Filter:       testUserSearchFilterInterpolated,
+		Attributes:   []string{testUserSearchUsernameAttribute, testUserSearchUIDAttribute},
+	}
+	groupSearchResult := func(dns ...string) *ldap.SearchResult {
+		result := &ldap.SearchResult{}
+		for _, dn := range dns {
+			result.Entries = append(result.Entries, &ldap.Entry{
+				DN:         dn,
+				Attributes: []*ldap.EntryAttribute{ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{dn})},
+			})
+		}
+		return result
+	}
+	userSearchBaseEntry := &ldap.SearchResult{Entries: []*ldap.Entry{{DN: testUserSearchBase}}}
+	groupSearchBaseEntry := &ldap.SearchResult{Entries: []*ldap.Entry{{DN: testGroupSearchBase}}}
+	userSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{{
+			DN: testUserSearchResultDNValue,
+			Attributes: []*ldap.EntryAttribute{
+				ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+				ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+			},
+		}},
+	}
+
 	tests := []struct {
 		name           string
 		providerConfig *ProviderConfig
+		sampleUsername string
 		setupMocks     func(conn *mockldapconn.MockConn)
 		dialError      error
 		wantError      testutil.RequireErrorStringFunc
-		wantToSkipDial bool
+		wantErrorIs    error
+		wantResult     *DryRunResult
 	}{
 		{
-			name:           "happy path",
+			name:           "happy path without a sample user searches for the groups of the bind user",
+			providerConfig: providerConfig(nil),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(3)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(userSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(gomock.Any(), expectedGroupSearchPageSize).
+					Return(groupSearchResult(testGroupSearchResultDNValue1, testGroupSearchResultDNValue2), nil).Times(1)
+				conn.EXPECT().Close().Times(3)
+			},
+			wantResult: &DryRunResult{
+				TestConnectionResult: TestConnectionResult{Host: testHost, Reachable: true, Bound: true},
+				UserSearchBaseValid:  true,
+				Groups:               []string{testGroupSearchResultDNValue1, testGroupSearchResultDNValue2},
+			},
+		},
+		{
+			name: "happy path without a sample user counts the disallowed groups of the bind user",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.GroupSearch.AllowedGroups = []string{"cn=group2,dc=pinniped,dc=dev"}
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(3)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(userSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().Search(expectedGroupSearchBaseSearch).Return(groupSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(gomock.Any(), expectedGroupSearchPageSize).
+					Return(groupSearchResult("cn=group1,dc=pinniped,dc=dev", "cn=group2,dc=pinniped,dc=dev"), nil).Times(1)
+				conn.EXPECT().Close().Times(3)
+			},
+			wantResult: &DryRunResult{
+				TestConnectionResult: TestConnectionResult{Host: testHost, Reachable: true, Bound: true},
+				UserSearchBaseValid:  true,
+				Groups:               []string{"cn=group2,dc=pinniped,dc=dev"},
+				DisallowedGroupCount: 1,
+			},
+		},
+		{
+			name:           "happy path with a sample user finds the user and their groups",
+			providerConfig: providerConfig(nil),
+			sampleUsername: testUpstreamUsername,
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(3)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(userSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch).Return(userSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(gomock.Any(), expectedGroupSearchPageSize).
+					Return(groupSearchResult(testGroupSearchResultDNValue1), nil).Times(1)
+				conn.EXPECT().Close().Times(3)
+			},
+			wantResult: &DryRunResult{
+				TestConnectionResult: TestConnectionResult{Host: testHost, Reachable: true, Bound: true},
+				UserSearchBaseValid:  true,
+				User: &user.DefaultInfo{
+					Name:   testUserSearchResultUsernameAttributeValue,
+					UID:    base64.RawURLEncoding.EncodeToString([]byte(testUserSearchResultUIDAttributeValue)),
+					Groups: []string{testGroupSearchResultDNValue1},
+				},
+				Groups: []string{testGroupSearchResultDNValue1},
+			},
+		},
+		{
+			name: "happy path with anonymous bind and without a sample user skips the group search",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.BindUsername = ""
+				p.BindPassword = ""
+				p.AnonymousBind = true
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().UnauthenticatedBind("").Times(2)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(userSearchBaseEntry, nil).Times(2)
+				conn.EXPECT().Close().Times(2)
+			},
+			wantResult: &DryRunResult{
+				TestConnectionResult: TestConnectionResult{Host: testHost, Reachable: true, Bound: true},
+				UserSearchBaseValid:  true,
+			},
+		},
+		{
+			name:           "when the sample user is not found",
 			providerConfig: providerConfig(nil),
+			sampleUsername: testUpstreamUsername,
 			setupMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
-				conn.EXPECT().Close().Times(1)
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(3)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(userSearchBaseEntry, nil).Times(1)
+				conn.EXPECT().Search(expectedUserSearch).Return(&ldap.SearchResult{}, nil).Times(1)
+				conn.EXPECT().Close().Times(3)
+			},
+			wantError:   testutil.WantExactErrorString(`sample user "some-upstream-username" was not found`),
+			wantErrorIs: ErrUserNotFound,
+			wantResult: &DryRunResult{
+				TestConnectionResult: TestConnectionResult{Host: testHost, Reachable: true, Bound: true},
+				UserSearchBaseValid:  true,
 			},
 		},
 		{
-			name:           "when dial fails",
+			name:           "when the user search base cannot be read",
 			providerConfig: providerConfig(nil),
-			dialError:      errors.New("some dial error"),
-			wantError:      testutil.WantSprintfErrorString(`error dialing host "%s": some dial error`, testHost),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(2)
+				conn.EXPECT().Search(expectedUserSearchBaseSearch).Return(&ldap.SearchResult{}, nil).Times(1)
+				conn.EXPECT().Close().Times(2)
+			},
+			wantError: testutil.WantSprintfErrorString(
+				`user search base "%s" was not found or could not be read as "%s"`, testUserSearchBase, testBindUsername),
+			wantResult: &DryRunResult{
+				TestConnectionResult: TestConnectionResult{Host: testHost, Reachable: true, Bound: true},
+			},
 		},
 		{
-			name:           "when binding as the bind user returns an error",
+			name:           "when the bind fails",
 			providerConfig: providerConfig(nil),
 			setupMocks: func(conn *mockldapconn.MockConn) {
 				conn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
 				conn.EXPECT().Close().Times(1)
 			},
-			wantError: testutil.WantSprintfErrorString(`error binding as "%s": some bind error`, testBindUsername),
+			wantError:   testutil.WantSprintfErrorString(`error binding as "%s": some bind error`, testBindUsername),
+			wantErrorIs: ErrBindFailed,
+			wantResult: &DryRunResult{
+				TestConnectionResult: TestConnectionResult{Host: testHost, Reachable: true},
+			},
 		},
 		{
-			name: "when the config is invalid",
-			providerConfig: providerConfig(func(p *ProviderConfig) {
-				// This particular combination of options is not allowed.
-				p.UserSearch.UsernameAttribute = "dn"
-				p.UserSearch.Filter = ""
-			}),
-			wantToSkipDial: true,
-			wantError:      testutil.WantExactErrorString(`must specify UserSearch Filter when UserSearch UsernameAttribute is "dn"`),
+			name:           "when the dial fails",
+			providerConfig: providerConfig(nil),
+			dialError:      errors.New("some dial error"),
+			wantError:      testutil.WantSprintfErrorString(`error dialing host "%s": some dial error`, testHost),
+			wantErrorIs:    ErrConnectionFailed,
+			wantResult:     &DryRunResult{},
 		},
 	}
 
@@ -1901,9 +4143,7 @@ func TestTestConnection(t *testing.T) {
 				tt.setupMocks(conn)
 			}
 
-			dialWasAttempted := false
 			tt.providerConfig.Dialer = LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
-				dialWasAttempted = true
 				require.Equal(t, tt.providerConfig.Host, addr.Endpoint())
 				if tt.dialError != nil {
 					return nil, tt.dialError
@@ -1911,14 +4151,15 @@ func TestTestConnection(t *testing.T) {
 				return conn, nil
 			})
 
-			provider := New(*tt.providerConfig)
-			err := provider.TestConnection(context.Background())
-
-			require.Equal(t, !tt.wantToSkipDial, dialWasAttempted)
+			result, err := New(*tt.providerConfig).DryRun(context.Background(), tt.sampleUsername)
 
+			require.Equal(t, tt.wantResult, result)
 			switch {
 			case tt.wantError != nil:
 				testutil.RequireErrorStringFromErr(t, err, tt.wantError)
+				if tt.wantErrorIs != nil {
+					require.ErrorIs(t, err, tt.wantErrorIs)
+				}
 			default:
 				require.NoError(t, err)
 			}
@@ -1926,6 +4167,381 @@ func TestTestConnection(t *testing.T) {
 	}
 }
 
+func TestConnectionPooling(t *testing.T) {
+	userSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testUserSearchResultDNValue,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+					ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		connectionPool ConnectionPoolConfig
+		setupMocks     func(conn1, conn2 *mockldapconn.MockConn)
+		wantErrs       []string
+		wantDials      int
+	}{
+		{
+			name:           "when pooling is enabled, an idle connection is reused by the next call",
+			connectionPool: ConnectionPoolConfig{MaxIdleConnections: 1, IdleTimeout: time.Hour},
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				conn1.EXPECT().Bind(testBindUsername, testBindPassword).Times(2)
+				conn1.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(2)
+				// Not closed, because it is still idle in the pool until the idle timeout.
+			},
+			wantErrs:  []string{"", ""},
+			wantDials: 1,
+		},
+		{
+			name:           "when pooling is enabled, an idle connection which cannot be bound is replaced by a new connection",
+			connectionPool: ConnectionPoolConfig{MaxIdleConnections: 1, IdleTimeout: time.Hour},
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				gomock.InOrder(
+					conn1.EXPECT().Bind(testBindUsername, testBindPassword),
+					conn1.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil),
+					conn1.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("connection closed by server")),
+					conn1.EXPECT().Close(),
+				)
+				conn2.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn2.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(1)
+			},
+			wantErrs:  []string{"", ""},
+			wantDials: 2,
+		},
+		{
+			name:           "when pooling is enabled, a connection which returned an error is closed instead of being reused",
+			connectionPool: ConnectionPoolConfig{MaxIdleConnections: 1, IdleTimeout: time.Hour},
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				conn1.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn1.EXPECT().Search(gomock.Any()).Return(nil, errors.New("some search error")).Times(1)
+				conn1.EXPECT().Close().Times(1)
+				conn2.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn2.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(1)
+			},
+			wantErrs:  []string{`error searching for user: some search error`, ""},
+			wantDials: 2,
+		},
+		{
+			name: "when pooling is disabled, every call dials a new connection and closes it",
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				for _, conn := range []*mockldapconn.MockConn{conn1, conn2} {
+					conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+					conn.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(1)
+					conn.EXPECT().Close().Times(1)
+				}
+			},
+			wantErrs:  []string{"", ""},
+			wantDials: 2,
+		},
+	}
+
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			conns := []*mockldapconn.MockConn{mockldapconn.NewMockConn(ctrl), mockldapconn.NewMockConn(ctrl)}
+			tt.setupMocks(conns[0], conns[1])
+
+			dials := 0
+			ldapProvider := New(ProviderConfig{
+				Name:               "some-provider-name",
+				Host:               testHost,
+				ConnectionProtocol: TLS,
+				BindUsername:       testBindUsername,
+				BindPassword:       testBindPassword,
+				UserSearch: UserSearchConfig{
+					Base:              testUserSearchBase,
+					Filter:            testUserSearchFilter,
+					UsernameAttribute: testUserSearchUsernameAttribute,
+					UIDAttribute:      testUserSearchUIDAttribute,
+				},
+				ConnectionPool: tt.connectionPool,
+				Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+					require.Less(t, dials, len(conns), "too many dials")
+					dials++
+					return conns[dials-1], nil
+				}),
+			})
+
+			for _, wantErr := range tt.wantErrs {
+				response, authenticated, err := ldapProvider.DryRunAuthenticateUser(context.Background(), testUpstreamUsername, []string{"groups"})
+				if wantErr != "" {
+					require.EqualError(t, err, wantErr)
+					continue
+				}
+				require.NoError(t, err)
+				require.True(t, authenticated)
+				require.Equal(t, testUserSearchResultUsernameAttributeValue, response.User.GetName())
+			}
+			require.Equal(t, tt.wantDials, dials)
+		})
+	}
+}
+
+func TestConnectionPoolingMaxConnections(t *testing.T) {
+	userSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testUserSearchResultDNValue,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+					ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+				},
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	var mu sync.Mutex
+	dials, inUse, maxInUse := 0, 0, 0
+	newConn := func() *mockldapconn.MockConn {
+		conn := mockldapconn.NewMockConn(ctrl)
+		conn.EXPECT().Bind(testBindUsername, testBindPassword).AnyTimes()
+		conn.EXPECT().Search(gomock.Any()).AnyTimes().DoAndReturn(func(*ldap.SearchRequest) (*ldap.SearchResult, error) {
+			mu.Lock()
+			inUse++
+			if inUse > maxInUse {
+				maxInUse = inUse
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			inUse--
+			mu.Unlock()
+			return userSearchResult, nil
+		})
+		// Not closed, because the pool keeps as many idle connections as may be open.
+		return conn
+	}
+
+	ldapProvider := New(ProviderConfig{
+		Name:               "some-provider-name",
+		Host:               testHost,
+		ConnectionProtocol: TLS,
+		BindUsername:       testBindUsername,
+		BindPassword:       testBindPassword,
+		UserSearch: UserSearchConfig{
+			Base:              testUserSearchBase,
+			Filter:            testUserSearchFilter,
+			UsernameAttribute: testUserSearchUsernameAttribute,
+			UIDAttribute:      testUserSearchUIDAttribute,
+		},
+		ConnectionPool: ConnectionPoolConfig{MaxConnections: 2, MaxIdleConnections: 2, IdleTimeout: time.Hour},
+		Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dials++
+			return newConn(), nil
+		}),
+	})
+
+	// Many concurrent logins share the connections instead of each opening one.
+	const logins = 10
+	errs := make(chan error, logins)
+	for i := 0; i < logins; i++ {
+		go func() {
+			_, authenticated, err := ldapProvider.DryRunAuthenticateUser(context.Background(), testUpstreamUsername, []string{"groups"})
+			if err == nil && !authenticated {
+				err = errors.New("not authenticated")
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < logins; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	require.LessOrEqual(t, dials, 2)
+	require.LessOrEqual(t, maxInUse, 2)
+
+	// When the context is done while waiting for a connection, the call fails instead of opening another one.
+	conn1, _, err := ldapProvider.connectAsBindUser(context.Background())
+	require.NoError(t, err)
+	conn2, _, err := ldapProvider.connectAsBindUser(context.Background())
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = ldapProvider.DryRunAuthenticateUser(ctx, testUpstreamUsername, []string{"groups"})
+	require.EqualError(t, err, fmt.Sprintf(`error dialing host %q: context deadline exceeded`, testHost))
+	require.LessOrEqual(t, dials, 2)
+	ldapProvider.releaseConn(conn1, testHost, true)
+	ldapProvider.releaseConn(conn2, testHost, true)
+}
+
+func TestAuthenticationCaching(t *testing.T) {
+	userSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testUserSearchResultDNValue,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+					ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+				},
+			},
+		},
+	}
+
+	type login struct {
+		password          string
+		grantedScopes     []string
+		wantAuthenticated bool
+		wantErr           string
+	}
+
+	// expectLogin sets up the mock calls for a login which contacts the LDAP server.
+	expectLogin := func(conn *mockldapconn.MockConn, password string, bindErr error) {
+		conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+		conn.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(1)
+		conn.EXPECT().Bind(testUserSearchResultDNValue, password).Return(bindErr).Times(1)
+		conn.EXPECT().Close().Times(1)
+	}
+
+	tests := []struct {
+		name                string
+		authenticationCache AuthenticationCacheConfig
+		setupMocks          func(conn1, conn2 *mockldapconn.MockConn)
+		logins              []login
+		wantDials           int
+	}{
+		{
+			name:                "when caching is enabled, a successful login is reused by the next login with the same credentials",
+			authenticationCache: AuthenticationCacheConfig{TTL: time.Hour, MaxEntries: 10},
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				expectLogin(conn1, testUpstreamPassword, nil)
+			},
+			logins: []login{
+				{password: testUpstreamPassword, wantAuthenticated: true},
+				{password: testUpstreamPassword, wantAuthenticated: true},
+				{password: testUpstreamPassword, wantAuthenticated: true},
+			},
+			wantDials: 1,
+		},
+		{
+			name:                "when caching is enabled, a login with a different password is not answered from the cache",
+			authenticationCache: AuthenticationCacheConfig{TTL: time.Hour, MaxEntries: 10},
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				expectLogin(conn1, testUpstreamPassword, nil)
+				expectLogin(conn2, "wrong-password", ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("some bind error")))
+			},
+			logins: []login{
+				{password: testUpstreamPassword, wantAuthenticated: true},
+				{password: "wrong-password", wantAuthenticated: false},
+			},
+			wantDials: 2,
+		},
+		{
+			name:                "when caching is enabled, a login with different granted scopes is not answered from the cache",
+			authenticationCache: AuthenticationCacheConfig{TTL: time.Hour, MaxEntries: 10},
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				expectLogin(conn1, testUpstreamPassword, nil)
+				expectLogin(conn2, testUpstreamPassword, nil)
+			},
+			logins: []login{
+				{password: testUpstreamPassword, wantAuthenticated: true},
+				{password: testUpstreamPassword, grantedScopes: []string{"openid"}, wantAuthenticated: true},
+			},
+			wantDials: 2,
+		},
+		{
+			name:                "when caching is enabled, failed logins are not cached",
+			authenticationCache: AuthenticationCacheConfig{TTL: time.Hour, MaxEntries: 10},
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				expectLogin(conn1, testUpstreamPassword, ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("some bind error")))
+				expectLogin(conn2, testUpstreamPassword, nil)
+			},
+			logins: []login{
+				{password: testUpstreamPassword, wantAuthenticated: false},
+				{password: testUpstreamPassword, wantAuthenticated: true},
+			},
+			wantDials: 2,
+		},
+		{
+			name:                "when caching is enabled, logins which returned an error are not cached",
+			authenticationCache: AuthenticationCacheConfig{TTL: time.Hour, MaxEntries: 10},
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				conn1.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn1.EXPECT().Search(gomock.Any()).Return(nil, errors.New("some search error")).Times(1)
+				conn1.EXPECT().Close().Times(1)
+				expectLogin(conn2, testUpstreamPassword, nil)
+			},
+			logins: []login{
+				{password: testUpstreamPassword, wantErr: "error searching for user: some search error"},
+				{password: testUpstreamPassword, wantAuthenticated: true},
+			},
+			wantDials: 2,
+		},
+		{
+			name: "when caching is disabled, every login contacts the LDAP server",
+			setupMocks: func(conn1, conn2 *mockldapconn.MockConn) {
+				expectLogin(conn1, testUpstreamPassword, nil)
+				expectLogin(conn2, testUpstreamPassword, nil)
+			},
+			logins: []login{
+				{password: testUpstreamPassword, wantAuthenticated: true},
+				{password: testUpstreamPassword, wantAuthenticated: true},
+			},
+			wantDials: 2,
+		},
+	}
+
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			conns := []*mockldapconn.MockConn{mockldapconn.NewMockConn(ctrl), mockldapconn.NewMockConn(ctrl)}
+			tt.setupMocks(conns[0], conns[1])
+
+			dials := 0
+			ldapProvider := New(ProviderConfig{
+				Name:               "some-provider-name",
+				Host:               testHost,
+				ConnectionProtocol: TLS,
+				BindUsername:       testBindUsername,
+				BindPassword:       testBindPassword,
+				UserSearch: UserSearchConfig{
+					Base:              testUserSearchBase,
+					Filter:            testUserSearchFilter,
+					UsernameAttribute: testUserSearchUsernameAttribute,
+					UIDAttribute:      testUserSearchUIDAttribute,
+				},
+				AuthenticationCache: tt.authenticationCache,
+				Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+					require.Less(t, dials, len(conns), "too many dials")
+					dials++
+					return conns[dials-1], nil
+				}),
+			})
+
+			for _, l := range tt.logins {
+				response, authenticated, err := ldapProvider.AuthenticateUser(context.Background(), testUpstreamUsername, l.password, l.grantedScopes)
+				if l.wantErr != "" {
+					require.EqualError(t, err, l.wantErr)
+					continue
+				}
+				require.NoError(t, err)
+				require.Equal(t, l.wantAuthenticated, authenticated)
+				if authenticated {
+					require.Equal(t, testUserSearchResultUsernameAttributeValue, response.User.GetName())
+					require.Equal(t, testUserSearchResultDNValue, response.DN)
+				}
+			}
+			require.Equal(t, tt.wantDials, dials)
+		})
+	}
+}
+
 func TestGetConfig(t *testing.T) {
 	c := ProviderConfig{
 		Name:         "original-provider-name",
@@ -1968,9 +4584,205 @@ func TestGetURL(t *testing.T) {
 			Host:       "ldap.example.com",
 			UserSearch: UserSearchConfig{Base: "ou=users,dc=pinniped,dc=dev"},
 		}).GetURL().String())
+
+	require.Equal(t,
+		"ldaps://ldap1.example.com:1234?base=ou%3Dusers%2Cdc%3Dpinniped%2Cdc%3Ddev",
+		New(ProviderConfig{
+			Host:       "ldap1.example.com:1234, ldap2.example.com:1234",
+			UserSearch: UserSearchConfig{Base: "ou=users,dc=pinniped,dc=dev"},
+		}).GetURL().String())
+}
+
+func TestNetDialerTimeout(t *testing.T) {
+	require.Equal(t, DefaultConnectionTimeout, New(ProviderConfig{}).netDialer().Timeout)
+	require.Equal(t, 5*time.Second, New(ProviderConfig{ConnectionTimeout: 5 * time.Second}).netDialer().Timeout)
+}
+
+func TestNetDialerKeepAlive(t *testing.T) {
+	require.Equal(t, DefaultKeepAlive, New(ProviderConfig{}).netDialer().KeepAlive)
+	require.Equal(t, 5*time.Second, New(ProviderConfig{KeepAlive: 5 * time.Second}).netDialer().KeepAlive)
+	require.Negative(t, New(ProviderConfig{KeepAlive: -1}).netDialer().KeepAlive)
 }
 
 // Testing of host parsing, TLS negotiation, and CA bundle, etc. for the production code's dialer.
+func TestSearchFilterAndDNTemplateEscaping(t *testing.T) {
+	tests := []struct {
+		name       string
+		username   string
+		wantFilter string
+		wantBaseDN string
+	}{
+		{
+			name:       "asterisk",
+			username:   `a*b`,
+			wantFilter: `(uid=a\2ab)`,
+			wantBaseDN: `uid=a*b,ou=people,dc=example,dc=com`,
+		},
+		{
+			name:       "closing parenthesis",
+			username:   `x)y`,
+			wantFilter: `(uid=x\29y)`,
+			wantBaseDN: `uid=x)y,ou=people,dc=example,dc=com`,
+		},
+		{
+			name:       "backslash",
+			username:   `c\d`,
+			wantFilter: `(uid=c\5cd)`,
+			wantBaseDN: `uid=c\\d,ou=people,dc=example,dc=com`,
+		},
+		{
+			name:       "DN special characters",
+			username:   `a,b=c+d`,
+			wantFilter: `(uid=a,b=c+d)`,
+			wantBaseDN: `uid=a\,b\=c\+d,ou=people,dc=example,dc=com`,
+		},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			p := New(ProviderConfig{UserSearch: UserSearchConfig{UsernameAttribute: "uid"}})
+
+			filter := p.userSearchFilter(test.username)
+			require.Equal(t, test.wantFilter, filter)
+			// The escaped filter must be well-formed, and it must only match the username which was given.
+			compiled, err := ldap.CompileFilter(filter)
+			require.NoError(t, err)
+			require.Equal(t, ldap.FilterEqualityMatch, int(compiled.Tag))
+			require.Len(t, compiled.Children, 2)
+			require.Equal(t, "uid", compiled.Children[0].Data.String())
+			require.Equal(t, test.username, compiled.Children[1].Data.String())
+
+			baseDN := ExpandDNTemplate("uid={},ou=people,dc=example,dc=com", test.username)
+			require.Equal(t, test.wantBaseDN, baseDN)
+			// The escaped value must stay within the first RDN of the template.
+			parsedDN, err := ldap.ParseDN(baseDN)
+			require.NoError(t, err)
+			require.Len(t, parsedDN.RDNs, 4)
+			require.Len(t, parsedDN.RDNs[0].Attributes, 1)
+			require.Equal(t, "uid", parsedDN.RDNs[0].Attributes[0].Type)
+			require.Equal(t, test.username, parsedDN.RDNs[0].Attributes[0].Value)
+		})
+	}
+}
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name          string
+		normalization UsernameNormalizationConfig
+		username      string
+		wantUsername  string
+	}{
+		{
+			name:         "no normalization",
+			username:     ` EXAMPLE\jdoe@example.com `,
+			wantUsername: ` EXAMPLE\jdoe@example.com `,
+		},
+		{
+			name:          "trim whitespace",
+			normalization: UsernameNormalizationConfig{TrimWhitespace: true},
+			username:      " \tjdoe\n ",
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "trim whitespace keeps whitespace inside the username",
+			normalization: UsernameNormalizationConfig{TrimWhitespace: true},
+			username:      " jane doe ",
+			wantUsername:  "jane doe",
+		},
+		{
+			name:          "strip NetBIOS prefix",
+			normalization: UsernameNormalizationConfig{StripNetBIOSPrefix: true},
+			username:      `EXAMPLE\jdoe`,
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "strip NetBIOS prefix only strips up to the first backslash",
+			normalization: UsernameNormalizationConfig{StripNetBIOSPrefix: true},
+			username:      `EXAMPLE\j\doe`,
+			wantUsername:  `j\doe`,
+		},
+		{
+			name:          "strip NetBIOS prefix when there is no prefix",
+			normalization: UsernameNormalizationConfig{StripNetBIOSPrefix: true},
+			username:      "jdoe",
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "strip domain suffix",
+			normalization: UsernameNormalizationConfig{StripDomainSuffix: "example.com"},
+			username:      "jdoe@example.com",
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "strip domain suffix compares the domain case-insensitively",
+			normalization: UsernameNormalizationConfig{StripDomainSuffix: "example.com"},
+			username:      "jdoe@Example.COM",
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "strip domain suffix leaves other domains",
+			normalization: UsernameNormalizationConfig{StripDomainSuffix: "example.com"},
+			username:      "jdoe@other.example.com",
+			wantUsername:  "jdoe@other.example.com",
+		},
+		{
+			name:          "strip domain suffix requires the @ before the domain",
+			normalization: UsernameNormalizationConfig{StripDomainSuffix: "example.com"},
+			username:      "jdoe.example.com",
+			wantUsername:  "jdoe.example.com",
+		},
+		{
+			name:          "strip domain suffix leaves a username which is only the domain",
+			normalization: UsernameNormalizationConfig{StripDomainSuffix: "example.com"},
+			username:      "@example.com",
+			wantUsername:  "@example.com",
+		},
+		{
+			name:          "strip domain suffix without trimming whitespace",
+			normalization: UsernameNormalizationConfig{StripDomainSuffix: "example.com"},
+			username:      "jdoe@example.com ",
+			wantUsername:  "jdoe@example.com ",
+		},
+		{
+			name:          "trim whitespace and strip domain suffix",
+			normalization: UsernameNormalizationConfig{TrimWhitespace: true, StripDomainSuffix: "example.com"},
+			username:      " jdoe@example.com ",
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "trim whitespace and strip NetBIOS prefix",
+			normalization: UsernameNormalizationConfig{TrimWhitespace: true, StripNetBIOSPrefix: true},
+			username:      ` EXAMPLE\jdoe `,
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "strip NetBIOS prefix and domain suffix",
+			normalization: UsernameNormalizationConfig{StripNetBIOSPrefix: true, StripDomainSuffix: "example.com"},
+			username:      `EXAMPLE\jdoe@example.com`,
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "all normalizations",
+			normalization: UsernameNormalizationConfig{TrimWhitespace: true, StripNetBIOSPrefix: true, StripDomainSuffix: "example.com"},
+			username:      ` EXAMPLE\jdoe@EXAMPLE.com `,
+			wantUsername:  "jdoe",
+		},
+		{
+			name:          "all normalizations when the username is already normalized",
+			normalization: UsernameNormalizationConfig{TrimWhitespace: true, StripNetBIOSPrefix: true, StripDomainSuffix: "example.com"},
+			username:      "jdoe",
+			wantUsername:  "jdoe",
+		},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			p := New(ProviderConfig{UserSearch: UserSearchConfig{UsernameNormalization: test.normalization}})
+			require.Equal(t, test.wantUsername, p.normalizeUsername(test.username))
+		})
+	}
+}
+
 func TestRealTLSDialing(t *testing.T) {
 	testServer := tlsserver.TLSTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
 		func(server *httptest.Server) {
@@ -2076,6 +4888,24 @@ func TestRealTLSDialing(t *testing.T) {
 			context:   context.Background(),
 			wantError: testutil.WantSprintfErrorString(`LDAP Result Code 200 "Network Error": dial tcp %s: connect: connection refused`, recentlyClaimedHostAndPort),
 		},
+		{
+			name:      "fails over to the next host when the first host cannot be reached",
+			host:      recentlyClaimedHostAndPort + "," + testServerHostAndPort,
+			caBundle:  testServerCABundle,
+			connProto: TLS,
+			context:   context.Background(),
+		},
+		{
+			name:      "cannot connect to any of multiple hosts",
+			host:      recentlyClaimedHostAndPort + ",this:is:not:a:valid:hostname",
+			caBundle:  testServerCABundle,
+			connProto: TLS,
+			context:   context.Background(),
+			wantError: testutil.WantSprintfErrorString(
+				`[host "%s": LDAP Result Code 200 "Network Error": dial tcp %s: connect: connection refused, `+
+					`host "this:is:not:a:valid:hostname": LDAP Result Code 200 "Network Error": host "this:is:not:a:valid:hostname" is not a valid hostname or IP address]`,
+				recentlyClaimedHostAndPort, recentlyClaimedHostAndPort),
+		},
 		{
 			name:      "pays attention to the passed context",
 			host:      testServerHostAndPort,
@@ -2102,7 +4932,7 @@ func TestRealTLSDialing(t *testing.T) {
 				ConnectionProtocol: tt.connProto,
 				Dialer:             nil, // this test is for the default (production) TLS dialer
 			})
-			conn, err := provider.dial(tt.context)
+			conn, _, err := provider.dial(tt.context)
 			if conn != nil {
 				defer conn.Close()
 			}
@@ -2115,11 +4945,11 @@ func TestRealTLSDialing(t *testing.T) {
 
 				// Should be an instance of the real production LDAP client type.
 				// Can't test its methods here because we are not dialed to a real LDAP server.
-				require.IsType(t, &ldap.Conn{}, conn)
+				require.IsType(t, &operationTimeoutConn{}, conn)
 
 				// Indirectly checking that the Dialer method constructed the ldap.Conn with isTLS set to true,
 				// since this is always the correct behavior unless/until we want to support StartTLS.
-				err := conn.(*ldap.Conn).StartTLS(ptls.DefaultLDAP(nil))
+				err := conn.(*operationTimeoutConn).StartTLS(ptls.DefaultLDAP(nil))
 				require.EqualError(t, err, `LDAP Result Code 200 "Network Error": ldap: already encrypted`)
 			}
 		})
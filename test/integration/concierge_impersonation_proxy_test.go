@@ -1402,6 +1402,17 @@ func TestImpersonationProxy(t *testing.T) { //nolint:gocyclo // yeah, it's compl
 				})
 			})
 
+			// - hit the impersonation proxy's own health check endpoint
+			//   - should succeed 200 whether or not anonymous auth is enabled, since it is not proxied
+			//   - should respond with a small JSON body
+			t.Run("impersonation proxy health check", func(t *testing.T) {
+				parallelIfNotEKS(t)
+
+				healthz, errHealth := impersonationProxyAnonymousRestClient.Get().AbsPath("/healthz/impersonation-proxy").DoRaw(ctx)
+				require.NoError(t, errHealth, testlib.Sdump(errHealth))
+				require.Equal(t, `{"status":"ok"}`+"\n", string(healthz))
+			})
+
 			t.Run("anonymous authentication enabled", func(t *testing.T) {
 				testlib.IntegrationEnv(t).WithCapability(testlib.AnonymousAuthenticationSupported)
 				parallelIfNotEKS(t)
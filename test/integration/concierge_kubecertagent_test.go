@@ -43,6 +43,11 @@ func TestKubeCertAgent(t *testing.T) {
 
 		for _, p := range agentPods.Items {
 			if p.Status.Phase == corev1.PodRunning {
+				// The agent pods should always have resource requests and limits, either the defaults or the configured ones.
+				resources := p.Spec.Containers[0].Resources
+				if len(resources.Requests) == 0 || len(resources.Limits) == 0 {
+					return false, fmt.Errorf("agent pod %s/%s does not have resource requests and limits: %#v", p.Namespace, p.Name, resources)
+				}
 				return true, nil
 			}
 		}
@@ -1786,7 +1786,7 @@ func wantGroupsInAdditionalClaimsIfGroupsExist(additionalClaims map[string]inter
 }
 
 func requireSuccessfulLDAPIdentityProviderConditions(t *testing.T, ldapIDP *idpv1alpha1.LDAPIdentityProvider, expectedLDAPConnectionValidMessage string) {
-	require.Len(t, ldapIDP.Status.Conditions, 3)
+	require.Len(t, ldapIDP.Status.Conditions, 6)
 
 	conditionsSummary := [][]string{}
 	for _, condition := range ldapIDP.Status.Conditions {
@@ -1795,11 +1795,13 @@ func requireSuccessfulLDAPIdentityProviderConditions(t *testing.T, ldapIDP *idpv
 			condition.Type, string(condition.Status), condition.Reason, condition.Message)
 		switch condition.Type {
 		case "BindSecretValid":
-			require.Equal(t, "loaded bind secret", condition.Message)
+			require.Equal(t, `loaded bind secret of type "kubernetes.io/basic-auth"`, condition.Message)
 		case "TLSConfigurationValid":
 			require.Equal(t, "loaded TLS configuration", condition.Message)
 		case "LDAPConnectionValid":
 			require.Equal(t, expectedLDAPConnectionValidMessage, condition.Message)
+		case "UserSearchBaseValid":
+			require.Equal(t, fmt.Sprintf(`userSearch.base %q was found`, ldapIDP.Spec.UserSearch.Base), condition.Message)
 		}
 	}
 
@@ -1807,6 +1809,9 @@ func requireSuccessfulLDAPIdentityProviderConditions(t *testing.T, ldapIDP *idpv
 		{"BindSecretValid", "True", "Success"},
 		{"TLSConfigurationValid", "True", "Success"},
 		{"LDAPConnectionValid", "True", "Success"},
+		{"SearchConfigurationValid", "True", "Success"},
+		{"UserSearchBaseValid", "True", "Success"},
+		{"GroupSearchValid", "True", "Success"},
 	}, conditionsSummary)
 }
 
@@ -1820,7 +1825,7 @@ func requireSuccessfulActiveDirectoryIdentityProviderConditions(t *testing.T, ad
 			condition.Type, string(condition.Status), condition.Reason, condition.Message)
 		switch condition.Type {
 		case "BindSecretValid":
-			require.Equal(t, "loaded bind secret", condition.Message)
+			require.Equal(t, `loaded bind secret of type "kubernetes.io/basic-auth"`, condition.Message)
 		case "TLSConfigurationValid":
 			require.Equal(t, "loaded TLS configuration", condition.Message)
 		case "LDAPConnectionValid":
@@ -1845,7 +1850,7 @@ func requireSuccessfulActiveDirectoryIdentityProviderConditions(t *testing.T, ad
 
 func requireEventuallySuccessfulLDAPIdentityProviderConditions(t *testing.T, requireEventually *require.Assertions, ldapIDP *idpv1alpha1.LDAPIdentityProvider, expectedLDAPConnectionValidMessage string) {
 	t.Helper()
-	requireEventually.Len(ldapIDP.Status.Conditions, 3)
+	requireEventually.Len(ldapIDP.Status.Conditions, 6)
 
 	conditionsSummary := [][]string{}
 	for _, condition := range ldapIDP.Status.Conditions {
@@ -1854,11 +1859,13 @@ func requireEventuallySuccessfulLDAPIdentityProviderConditions(t *testing.T, req
 			condition.Type, string(condition.Status), condition.Reason, condition.Message)
 		switch condition.Type {
 		case "BindSecretValid":
-			requireEventually.Equal("loaded bind secret", condition.Message)
+			requireEventually.Equal(`loaded bind secret of type "kubernetes.io/basic-auth"`, condition.Message)
 		case "TLSConfigurationValid":
 			requireEventually.Equal("loaded TLS configuration", condition.Message)
 		case "LDAPConnectionValid":
 			requireEventually.Equal(expectedLDAPConnectionValidMessage, condition.Message)
+		case "UserSearchBaseValid":
+			requireEventually.Equal(fmt.Sprintf(`userSearch.base %q was found`, ldapIDP.Spec.UserSearch.Base), condition.Message)
 		}
 	}
 
@@ -1866,6 +1873,9 @@ func requireEventuallySuccessfulLDAPIdentityProviderConditions(t *testing.T, req
 		{"BindSecretValid", "True", "Success"},
 		{"TLSConfigurationValid", "True", "Success"},
 		{"LDAPConnectionValid", "True", "Success"},
+		{"SearchConfigurationValid", "True", "Success"},
+		{"UserSearchBaseValid", "True", "Success"},
+		{"GroupSearchValid", "True", "Success"},
 	}, conditionsSummary)
 }
 
@@ -1880,7 +1890,7 @@ func requireEventuallySuccessfulActiveDirectoryIdentityProviderConditions(t *tes
 			condition.Type, string(condition.Status), condition.Reason, condition.Message)
 		switch condition.Type {
 		case "BindSecretValid":
-			requireEventually.Equal("loaded bind secret", condition.Message)
+			requireEventually.Equal(`loaded bind secret of type "kubernetes.io/basic-auth"`, condition.Message)
 		case "TLSConfigurationValid":
 			requireEventually.Equal("loaded TLS configuration", condition.Message)
 		case "LDAPConnectionValid":